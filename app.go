@@ -3,17 +3,34 @@ package main
 import (
 	"bytes"
 	"compress/gzip"
+	"container/list"
 	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/tls"
 	"database/sql"
 	"encoding/csv"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"hash"
+	"hash/fnv"
 	"io"
+	"math"
+	"math/rand"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
 	pathpkg "path"
 	"path/filepath"
+	"reflect"
+	"regexp"
+	goruntime "runtime"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -28,9 +45,13 @@ import (
 	"claude-code-codex-companion/internal/database"
 	"claude-code-codex-companion/internal/endpoint"
 	"claude-code-codex-companion/internal/health"
+	"claude-code-codex-companion/internal/jsonpath"
 	logger "claude-code-codex-companion/internal/logger"
 	"claude-code-codex-companion/internal/modelrewrite"
+	"claude-code-codex-companion/internal/oauth"
+	"claude-code-codex-companion/internal/proxyclient"
 	"claude-code-codex-companion/internal/utils"
+	"claude-code-codex-companion/internal/validator"
 )
 
 const (
@@ -64,11 +85,64 @@ type App struct {
 	db            *sql.DB
 	configPath    string
 	config        map[string]interface{} // 配置缓存
-	logs          []LogEntry             // 内存日志存储
+	configError   string                 // 配置文件解析失败时的错误信息（非空表示处于安全模式）
 	requestLogger *logger.Logger
 	modelRewriter *modelrewrite.Rewriter
 	healthChecker *health.Checker
 
+	logsMu sync.Mutex
+	logs   []LogEntry // 内存日志存储；代理请求会并发写入，读写都需持有 logsMu
+
+	unknownPromptTokens sync.Map // 已记录过的未知系统提示词模板变量，避免重复日志
+	runtimeEndpoints    sync.Map // 端点ID -> *endpoint.Endpoint，保存 TestEndpoint/能力探测与代理 4xx 错误学习到的端点能力（不参与代理请求构建）
+
+	upstreamClientsMu sync.Mutex
+	upstreamClients   map[string]*upstreamClientEntry // 端点名称 -> 复用的上游HTTP客户端
+
+	requestQueuesMu sync.Mutex
+	requestQueues   map[string]*requestQueue // 端点名称 -> 转发前的有界FIFO请求队列
+
+	endpointOutcomesMu sync.Mutex
+	endpointOutcomes   map[string]*endpointOutcomeWindow // 端点名称 -> 最近请求结果，用于按成功率加权选择
+
+	endpointLatencyMu sync.Mutex
+	endpointLatency   map[endpointLatencyKey]*endpointLatencyWindow // 端点名称 + 是否流式 -> 最近的响应头耗时，用于自适应超时
+
+	requestBudgetsMu sync.Mutex
+	requestBudgets   map[string][]time.Time // 端点名称 -> 最近一分钟内的转发时间，用于 max_requests_per_minute
+
+	businessErrorsMu sync.Mutex
+	businessErrors   map[string]*businessErrorTracker // 端点名称 -> 时间窗口内的业务错误情况，用于 server.business_error_demotion_threshold
+
+	requestCounters requestCounters // GetServerStatus 展示的代理请求计数
+
+	requestLogWriterMu sync.Mutex
+	requestLogWriter   *logger.AsyncRequestWriter // 异步批量写入 request_logs，避免请求路径同步写 SQLite
+
+	accessLogMu      sync.Mutex
+	accessLogger     *logger.AccessLogger // logging.access_log 对应的访问日志，配置变化时重建
+	accessLogConfig  logger.AccessLogConfig
+	accessLogApplied bool // accessLogConfig 是否已应用（初始化失败时也为 true）
+
+	unhealthySinceMu sync.Mutex
+	unhealthySince   map[string]time.Time // 端点名称 -> 持续不健康的起始时间，用于 server.auto_disable_after_minutes
+
+	healthGateMu           sync.Mutex
+	healthGateSweepDone    bool // 启动健康检测是否已完成至少一轮
+	healthGateSweepRunning bool // 健康闸门检测是否正在运行
+	healthGateOpen         bool // 健康端点数已达到 server.min_healthy_endpoints
+
+	deadLetterMu       sync.Mutex
+	deadLetterAttempts map[string][]deadLetterAttempt // 请求ID -> 尚未成功的各次尝试，全部失败时写入死信表
+
+	consolidatedMu   sync.Mutex
+	consolidatedLogs map[string]*consolidatedRequestLog // 请求ID -> 暂存的失败尝试（logging.consolidate_attempts）
+
+	modelsCacheMu sync.Mutex
+	modelsCache   map[string]*endpointModelsEntry // 端点名称 -> 上游 /models 拉取结果，用于 server.models_refresh_minutes
+
+	warmUpFunc func(id string) // 端点新建/重新启用后的预热检测，为空时使用 warmUpEndpoint
+
 	proxyHost      string
 	proxyPort      int
 	configuredHost string
@@ -189,7 +263,9 @@ func (a *App) startup(ctx context.Context) {
 		a.configPath = "./config.json" // 回退到默认路径
 	}
 
+	a.logsMu.Lock()
 	a.logs = []LogEntry{} // 初始化日志存储
+	a.logsMu.Unlock()
 
 	// 初始化绑定管理器 - 使用Wails自动生成的代码
 	if err := a.InitializeBindingManager(); err != nil {
@@ -205,6 +281,9 @@ func (a *App) startup(ctx context.Context) {
 	a.addLog("info", "统一路由架构已启动")
 	a.addLog("info", "前端通过Go API与后端通信")
 
+	// 加载配置：解析失败时进入安全模式，代理返回503而不是静默使用空配置
+	a.LoadConfig()
+
 	// 初始化统一数据库管理器
 	if err := a.initDatabaseManager(); err != nil {
 		runtime.LogError(a.ctx, fmt.Sprintf("Failed to initialize database manager: %v", err))
@@ -241,6 +320,9 @@ func (a *App) startup(ctx context.Context) {
 		a.addLog("info", "健康检查器初始化成功")
 	}
 
+	a.warnInsecureEndpoints()
+	a.warnEndpointConfigProblems()
+
 	runtime.LogInfo(a.ctx, "CCCC Desktop App startup completed")
 	runtime.LogInfo(a.ctx, "✅ 统一路由架构已启用 - 无HTTP服务器冲突")
 	runtime.LogInfo(a.ctx, "✅ 前端将通过Go API与后端通信")
@@ -258,6 +340,9 @@ func (a *App) addLog(level, message string) {
 		Message:   message,
 	}
 
+	a.logsMu.Lock()
+	defer a.logsMu.Unlock()
+
 	a.logs = append(a.logs, entry)
 
 	// 保持日志数量在合理范围内（最多1000条）
@@ -329,6 +414,15 @@ func (a *App) initModelRewriterAndHealthChecker() error {
 		}
 
 		a.healthChecker = health.NewChecker(timeoutCfg.ToHealthCheckTimeoutConfig(), a.modelRewriter, defaultModel)
+
+		// 多格式探测：server.health_check_formats 指定必须通过的格式，未配置时沿用单格式检查
+		if a.config != nil {
+			if server, ok := a.config["server"].(map[string]interface{}); ok {
+				if formats, err := parseStringSlice(server["health_check_formats"]); err == nil {
+					a.healthChecker.SetProbeFormats(formats)
+				}
+			}
+		}
 	}
 
 	return nil
@@ -394,6 +488,21 @@ func (a *App) initDatabase() error {
 		return fmt.Errorf("failed to ensure request logs schema: %w", err)
 	}
 
+	// 确保端点表包含最新字段（端点表可能尚未创建，失败时仅记录警告）
+	if err := a.ensureEndpointSchema(db); err != nil {
+		runtime.LogWarning(a.ctx, fmt.Sprintf("Failed to ensure endpoint schema: %v", err))
+	}
+
+	// 健康检查历史表（用于识别状态抖动的端点）
+	if err := ensureHealthHistorySchema(db); err != nil {
+		runtime.LogWarning(a.ctx, fmt.Sprintf("Failed to ensure health history schema: %v", err))
+	}
+
+	// 死信表（所有端点都失败的请求，便于排查与重放）
+	if err := ensureDeadLetterSchema(db); err != nil {
+		runtime.LogWarning(a.ctx, fmt.Sprintf("Failed to ensure dead letter schema: %v", err))
+	}
+
 	// 打印数据库路径信息
 	mainDBPath := a.dbManager.GetMainDBPath()
 	runtime.LogInfo(a.ctx, fmt.Sprintf("Main database path: %s", mainDBPath))
@@ -415,6 +524,15 @@ func (a *App) OnDomReady(ctx context.Context) {
 	// 启动HTTP代理服务器供Claude Code使用
 	go a.startProxyServer()
 
+	// 配置了 server.min_healthy_endpoints 时，先完成一轮健康检测再放行代理请求
+	go a.runStartupHealthSweep()
+
+	// 配置了 server.models_refresh_minutes 时，周期性拉取上游 /models 供 /v1/models 聚合使用
+	go a.runModelsRefreshLoop()
+
+	// OAuth 端点：启动时及之后定期预刷新即将过期的 access_token，避免首个请求才因 401 触发刷新
+	go a.runOAuthPreflightLoop()
+
 	a.running = true
 }
 
@@ -470,11 +588,17 @@ func (a *App) startProxyServer() {
 		}
 
 		// 处理API请求
-		if r.URL.Path == "/v1/messages" || r.URL.Path == "/chat/completions" || r.URL.Path == "/responses" {
+		if r.URL.Path == "/v1/messages" || r.URL.Path == "/chat/completions" || r.URL.Path == "/responses" || utils.IsAnthropicBatchPath(r.URL.Path) || utils.IsAnthropicCompletePath(r.URL.Path) {
 			a.handleProxyRequest(w, r)
 			return
 		}
 
+		// 聚合模型列表
+		if r.URL.Path == "/v1/models" || r.URL.Path == "/models" {
+			a.handleModelsList(w, r)
+			return
+		}
+
 		// 健康检查端点
 		if r.URL.Path == "/health" || r.URL.Path == "/" {
 			w.Header().Set("Content-Type", "application/json")
@@ -507,6 +631,37 @@ func (a *App) startProxyServer() {
 func (a *App) handleProxyRequest(w http.ResponseWriter, r *http.Request) {
 	startTime := time.Now()
 
+	// 请求结束时按最终状态码计入运行状态的请求计数；开启 logging.access_log 时同时输出一行访问日志，独立于数据库日志
+	recorder := &accessLogRecorder{ResponseWriter: w}
+	w = recorder
+	accessLogger := a.getAccessLogger()
+
+	// server.server_timing：写出响应头前附加 Server-Timing（端点选择、转换、上游首字节、总耗时），便于客户端定位延迟
+	if a.isServerTimingEnabled() {
+		recorder.timings = &serverTimings{start: startTime}
+	}
+	timings := recorder.timings
+	defer func() {
+		a.requestCounters.record(recorder.status)
+		if accessLogger != nil {
+			accessLogger.Log(recorder.entry(r, startTime))
+		}
+	}()
+
+	// 安全模式：配置文件损坏时暂停代理，明确告知客户端原因
+	if configError := a.getConfigError(); configError != "" {
+		writeJSONError(w, http.StatusServiceUnavailable, "config_safe_mode",
+			"Proxy is paused in safe mode because config.json could not be parsed: "+configError)
+		return
+	}
+
+	// 启动健康闸门：健康端点数达到 server.min_healthy_endpoints 前暂停代理
+	if open, _, _, reason := a.healthGateStatus(); !open {
+		writeJSONError(w, http.StatusServiceUnavailable, "insufficient_healthy_endpoints",
+			"Proxy is paused until enough endpoints are healthy: "+reason)
+		return
+	}
+
 	// 读取请求体
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
@@ -517,8 +672,18 @@ func (a *App) handleProxyRequest(w http.ResponseWriter, r *http.Request) {
 
 	runtime.LogInfo(a.ctx, fmt.Sprintf("收到代理请求: %s %s", r.Method, r.URL.Path))
 
-	// 获取可用的端点
-	endpoints, err := a.getAvailableEndpoints()
+	// 获取可用的端点；重放请求固定端点时只使用该端点（不受启用/健康状态限制，便于修复后验证）
+	var endpoints []config.EndpointConfig
+	if pinned := replayEndpointFromContext(r.Context()); pinned != "" {
+		endpoints, err = a.queryPinnedEndpoint(pinned)
+		if err != nil {
+			runtime.LogError(a.ctx, fmt.Sprintf("查找重放端点失败: %v", err))
+			writeJSONError(w, http.StatusNotFound, "replay_endpoint_not_found", err.Error())
+			return
+		}
+	} else {
+		endpoints, err = a.getAvailableEndpoints()
+	}
 	if err != nil {
 		runtime.LogError(a.ctx, fmt.Sprintf("获取端点失败: %v", err))
 		writeJSONError(w, http.StatusInternalServerError, "endpoint_query_failed", "Failed to get endpoints")
@@ -531,7 +696,63 @@ func (a *App) handleProxyRequest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	formatDetection := utils.DetectRequestFormat(r.URL.Path, body)
+	// 业务错误率过高的端点临时降级到正常端点之后（不禁用）
+	endpoints = a.demoteBusinessErrorEndpoints(endpoints)
+
+	// 同优先级端点按近期成功率加权随机排序，失败中的端点分到更少流量
+	endpoints = a.orderEndpointsBySuccessRate(endpoints)
+
+	// X-CCCC-Tags 请求标签：与请求标签有交集的端点优先尝试，其余端点作为回退；标签同时写入请求日志
+	requestTags := utils.ParseRequestTags(r.Header.Get(utils.RequestTagsHeader))
+
+	// server.routing_rules：按工具数/消息数/估算 token 数匹配第一条规则，规则标签与请求标签合并参与端点优先级
+	routingRule, routingRuleMatched := a.matchRoutingRule(body)
+	if routingRuleMatched && strings.TrimSpace(routingRule.Tag) != "" {
+		requestTags = utils.MergeTags(requestTags, []string{routingRule.Tag})
+	}
+	if len(requestTags) > 0 {
+		endpoints = preferTaggedEndpoints(endpoints, requestTags)
+	}
+
+	// Anthropic 批处理 API 无法转换为 OpenAI 格式，只路由到配置了 Anthropic URL 的端点
+	batchRequest := utils.IsAnthropicBatchPath(r.URL.Path)
+	if batchRequest {
+		endpoints = filterAnthropicEndpoints(endpoints)
+		if len(endpoints) == 0 {
+			runtime.LogWarning(a.ctx, fmt.Sprintf("批处理请求 %s 没有可用的 Anthropic 端点", r.URL.Path))
+			writeJSONError(w, http.StatusServiceUnavailable, "no_batch_capable_endpoints", "No available endpoint with an Anthropic URL for the batch API")
+			return
+		}
+	}
+
+	// Anthropic 旧版 /v1/complete：OpenAI-only 端点通过 prompt <-> chat 转换支持非流式请求，流式请求只能走 Anthropic 端点
+	legacyComplete := utils.IsAnthropicCompletePath(r.URL.Path)
+	if legacyComplete {
+		endpoints = filterLegacyCompleteEndpoints(endpoints, streamRequested(body))
+		if len(endpoints) == 0 {
+			runtime.LogWarning(a.ctx, fmt.Sprintf("旧版补全请求 %s 没有兼容的端点", r.URL.Path))
+			writeJSONError(w, http.StatusServiceUnavailable, "no_legacy_complete_endpoints", "No available endpoint supports the legacy /v1/complete API (streaming requires an Anthropic URL)")
+			return
+		}
+	}
+
+	// 音频请求（modalities 含 audio 或 input_audio 内容块）无法转换，只路由到开启 supports_audio 的 OpenAI 端点并原样透传；跳过不计入健康惩罚
+	if utils.RequestNeedsAudio(body) {
+		var skipped []string
+		endpoints, skipped = filterAudioEndpoints(endpoints)
+		for _, name := range skipped {
+			reason := fmt.Sprintf("端点 %s 不支持音频输入（supports_audio 未开启或无 OpenAI URL），已跳过", name)
+			runtime.LogInfo(a.ctx, reason)
+			a.addLog("info", reason)
+		}
+		if len(endpoints) == 0 {
+			runtime.LogWarning(a.ctx, fmt.Sprintf("音频请求 %s 没有支持音频的端点", r.URL.Path))
+			writeJSONError(w, http.StatusServiceUnavailable, "no_audio_capable_endpoints", "No available endpoint supports audio requests (enable supports_audio on an endpoint with an OpenAI URL)")
+			return
+		}
+	}
+
+	formatDetection := a.detectRequestFormat(r, body)
 	if a.requestLogger == nil {
 		if err := a.initRequestLogger(); err != nil {
 			runtime.LogError(a.ctx, fmt.Sprintf("初始化日志记录器失败: %v", err))
@@ -539,10 +760,11 @@ func (a *App) handleProxyRequest(w http.ResponseWriter, r *http.Request) {
 	}
 
 	requestID := fmt.Sprintf("req_%d", time.Now().UnixNano())
+	defer a.takeDeadLetterAttempts(requestID)
+	defer a.flushConsolidatedAttempts(requestID)
 	originalRequestHeaders := headersToMap(r.Header, true)
 	originalRequestURL := r.URL.String()
 	originalRequestBody := string(body)
-	originalRequestBodyPreview, originalRequestBodyTruncated := truncateStringForLog(originalRequestBody, healthLogPreviewLimit)
 	requestBodySize := len(body)
 
 	clientToken := a.extractClientToken(r)
@@ -562,9 +784,57 @@ func (a *App) handleProxyRequest(w http.ResponseWriter, r *http.Request) {
 		detectionConfidence = formatDetection.Confidence
 	}
 
+	// logging.per_client：按客户端类型决定请求/响应体的记录长度（none 在写日志时清除）
+	bodyPolicy := a.getLogBodyPolicy(clientType)
+	requestBodyLimit := logBodyLimit(bodyPolicy.request)
+	responseBodyLimit := logBodyLimit(bodyPolicy.response)
+	originalRequestBodyPreview, originalRequestBodyTruncated := truncateStringForLog(originalRequestBody, requestBodyLimit)
+
 	attemptNumber := 1
+	// retry.retriable_statuses / retry.terminal_statuses：终止状态码直接返回给客户端，不再尝试其他端点
+	retryPolicy := a.getRetryStatusPolicy()
+
+	// 当前持有的端点队列槽位，切换端点或请求结束时归还
+	releaseQueueSlot := func() {}
+	defer func() { releaseQueueSlot() }()
+
+	// X-CCCC-Model：开启 server.allow_model_override_header 时临时覆盖请求模型，之后仍经过别名归一化与模型重写
+	clientModel := ""
+	modelOverridden := false
+	overrideModel := strings.TrimSpace(r.Header.Get(utils.ModelOverrideHeader))
+	if overrideModel != "" {
+		if a.isModelOverrideHeaderEnabled() {
+			var overriddenBody []byte
+			overriddenBody, clientModel, modelOverridden = modelrewrite.OverrideRequestModel(body, overrideModel)
+			if modelOverridden {
+				body = overriddenBody
+				runtime.LogInfo(a.ctx, fmt.Sprintf("请求头覆盖模型: %s -> %s", clientModel, overrideModel))
+			}
+		} else {
+			runtime.LogWarning(a.ctx, fmt.Sprintf("忽略 %s 请求头：server.allow_model_override_header 未开启", utils.ModelOverrideHeader))
+		}
+	}
+	// 路由规则的模型改写按请求头覆盖处理（同样经过别名归一化与模型重写），X-CCCC-Model 已生效时以请求头为准
+	if routingRuleMatched && !modelOverridden && strings.TrimSpace(routingRule.Model) != "" {
+		var overriddenBody []byte
+		overriddenBody, clientModel, modelOverridden = modelrewrite.OverrideRequestModel(body, routingRule.Model)
+		if modelOverridden {
+			body = overriddenBody
+			overrideModel = strings.TrimSpace(routingRule.Model)
+			runtime.LogInfo(a.ctx, fmt.Sprintf("路由规则 %s 改写模型: %s -> %s", routingRule.Name, clientModel, overrideModel))
+		}
+	}
+
+	// 在模型重写之前按 server.model_aliases 归一化模型名
+	body, rawModel, canonicalModel, aliasApplied := modelrewrite.CanonicalizeRequestModel(body, a.getModelAliases())
+	if aliasApplied {
+		runtime.LogInfo(a.ctx, fmt.Sprintf("模型别名归一化: %s -> %s", rawModel, canonicalModel))
+	}
 
+	timings.markSelection()
 	for _, endpoint := range endpoints {
+		releaseQueueSlot()
+		releaseQueueSlot = func() {}
 		attemptStart := time.Now()
 
 		targetURL, err := a.buildTargetURL(&endpoint, r.URL.Path, r.URL.RawQuery)
@@ -599,7 +869,7 @@ func (a *App) handleProxyRequest(w http.ResponseWriter, r *http.Request) {
 				RequestFormat:          requestFormat,
 				DetectionConfidence:    detectionConfidence,
 				DetectedBy:             detectedBy,
-				Tags:                   append([]string{}, endpoint.Tags...),
+				Tags:                   utils.MergeTags(requestTags, endpoint.Tags),
 				EndpointResponseTime:   time.Since(attemptStart).Milliseconds(),
 			})
 			lastError = err
@@ -612,7 +882,90 @@ func (a *App) handleProxyRequest(w http.ResponseWriter, r *http.Request) {
 		if rewriteErr != nil {
 			runtime.LogError(a.ctx, fmt.Sprintf("模型重写失败 (%s): %v", endpoint.Name, rewriteErr))
 		}
-		finalRequestBodyPreview, _ := truncateStringForLog(string(bodyForEndpoint), healthLogPreviewLimit)
+		if aliasApplied {
+			// 日志与响应回写均以客户端发送的原始模型名为准
+			if !rewriteApplied {
+				rewrittenModel = canonicalModel
+				rewriteApplied = true
+			}
+			originalModel = rawModel
+		}
+		if modelOverridden {
+			// 日志记录客户端请求体中的原始模型，实际发送的是请求头指定（并经别名/重写处理）的模型
+			if !rewriteApplied {
+				rewrittenModel = overrideModel
+				if aliasApplied {
+					rewrittenModel = canonicalModel
+				}
+				rewriteApplied = true
+			}
+			originalModel = clientModel
+		}
+		// 端点 allowed_models：最终发送的模型（重写后）不在允许列表中时跳过该端点，不计入健康惩罚
+		if finalModel := utils.ExtractModelFromRequestBody(string(bodyForEndpoint)); !endpointAllowsModel(endpoint.AllowedModels, finalModel) {
+			runtime.LogWarning(a.ctx, fmt.Sprintf("端点 %s 的 allowed_models 不包含模型 %s，尝试下一端点", endpoint.Name, finalModel))
+			a.addLog("warn", fmt.Sprintf("端点 %s 不允许模型 %s，已跳过", endpoint.Name, finalModel))
+			lastError = fmt.Errorf("endpoint %s: model %s not in allowed_models", endpoint.Name, finalModel)
+			lastStatus = http.StatusBadRequest
+			attemptNumber++
+			continue
+		}
+		if !batchRequest && (strings.Contains(r.URL.Path, "/chat/completions") || strings.Contains(r.URL.Path, "/responses")) {
+			if transformed, modified, err := utils.ApplyUserFieldMode(bodyForEndpoint, endpoint.UserFieldMode); err != nil {
+				runtime.LogWarning(a.ctx, fmt.Sprintf("user 字段处理失败 (%s): %v", endpoint.Name, err))
+			} else if modified {
+				bodyForEndpoint = transformed
+				runtime.LogDebug(a.ctx, fmt.Sprintf("user 字段已按 %s 模式处理 (%s)", utils.NormalizeUserFieldMode(endpoint.UserFieldMode), endpoint.Name))
+			}
+		}
+		var thinkingEnabled bool
+		var thinkingBudget int
+		if !batchRequest {
+			// 批处理请求体是 requests 数组，单条消息的改写不适用
+			bodyForEndpoint, _ = applyParameterOverrides(bodyForEndpoint, endpoint.ParameterOverrides)
+			bodyForEndpoint = a.applyExtraSystemPrompt(bodyForEndpoint, &endpoint, r.URL.Path, clientType)
+			if !legacyComplete {
+				// 旧版补全使用 max_tokens_to_sample，不在此处理
+				defaultMax, ceiling := a.getMaxTokensLimits(&endpoint)
+				var note string
+				bodyForEndpoint, note = applyMaxTokensPolicy(bodyForEndpoint, resolveMaxTokensField(&endpoint, r.URL.Path), defaultMax, ceiling)
+				if note != "" {
+					runtime.LogInfo(a.ctx, fmt.Sprintf("max_tokens 策略已应用 (%s): %s", endpoint.Name, note))
+					a.addLog("info", fmt.Sprintf("端点 %s: %s", endpoint.Name, note))
+				}
+			}
+			bodyForEndpoint, thinkingEnabled, thinkingBudget = applyThinkingPolicy(bodyForEndpoint, &endpoint, r.URL.Path)
+		}
+		legacyCompleteConverted := false
+		if legacyComplete && endpoint.URLAnthropic == "" {
+			converted, convErr := conversion.ConvertLegacyCompleteRequestToChat(bodyForEndpoint)
+			if convErr != nil {
+				runtime.LogWarning(a.ctx, fmt.Sprintf("旧版补全请求转换失败 (%s): %v", endpoint.Name, convErr))
+				lastError = fmt.Errorf("endpoint %s: legacy completion conversion failed: %w", endpoint.Name, convErr)
+				lastStatus = http.StatusBadRequest
+				attemptNumber++
+				continue
+			}
+			bodyForEndpoint = converted
+			legacyCompleteConverted = true
+		}
+		if !batchRequest && strings.Contains(targetURL, "/chat/completions") {
+			// 端点 stream_include_usage：流式请求补充 stream_options.include_usage，使上游返回 usage 统计
+			bodyForEndpoint, _ = applyStreamIncludeUsage(bodyForEndpoint, endpoint.StreamIncludeUsage == nil || *endpoint.StreamIncludeUsage)
+		}
+		if len(endpoint.BodyTransforms) > 0 {
+			// 端点 body_transforms：在全部改写与格式转换之后按顺序执行 JSONPath set/delete
+			var fired []string
+			bodyForEndpoint, fired = applyBodyTransforms(bodyForEndpoint, endpoint.BodyTransforms)
+			if len(fired) > 0 {
+				msg := fmt.Sprintf("端点 %s 请求体改写: %s", endpoint.Name, strings.Join(fired, ", "))
+				runtime.LogInfo(a.ctx, msg)
+				a.addLog("info", msg)
+			}
+		}
+		conversionStages := requestConversionStages(r.URL.Path, targetURL, legacyCompleteConverted, originalModel, rewrittenModel, rewriteApplied)
+		finalRequestBodyPreview, _ := truncateStringForLog(string(bodyForEndpoint), requestBodyLimit)
+		timings.markRequestConversion(time.Since(attemptStart))
 
 		mappedToken, ok := a.validateAndMapToken(clientToken, &endpoint)
 		if !ok {
@@ -628,15 +981,93 @@ func (a *App) handleProxyRequest(w http.ResponseWriter, r *http.Request) {
 			runtime.LogInfo(a.ctx, fmt.Sprintf("Token validation passed for endpoint %s (no credential forwarding required)", endpoint.Name))
 		}
 
-		finalRequestHeaders := buildFinalRequestHeaders(r.Header, &endpoint, mappedToken)
+		finalRequestHeaders := buildFinalRequestHeaders(filterForwardHeaders(r.Header, a.getHeaderForwardFilter()), &endpoint, mappedToken)
+
+		release, queueErr := a.acquireRequestQueueSlot(r.Context(), endpoint.Name)
+		if queueErr != nil {
+			runtime.LogWarning(a.ctx, fmt.Sprintf("端点请求队列拒绝 (%s): %v", endpoint.Name, queueErr))
+			a.addLog("warn", fmt.Sprintf("端点 %s 请求排队失败: %v", endpoint.Name, queueErr))
+			lastError = fmt.Errorf("endpoint %s: %w", endpoint.Name, queueErr)
+			lastStatus = http.StatusServiceUnavailable
+			attemptNumber++
+			continue
+		}
+
+		// 端点 max_requests_per_minute：预算用尽时跳过该端点，尝试下一个
+		if !a.reserveRequestBudget(endpoint.Name, endpoint.MaxRequestsPerMinute, time.Now()) {
+			release()
+			runtime.LogWarning(a.ctx, fmt.Sprintf("端点 %s 已达到每分钟 %d 次请求上限，尝试下一端点", endpoint.Name, endpoint.MaxRequestsPerMinute))
+			a.addLog("warn", fmt.Sprintf("端点 %s 已达到每分钟 %d 次请求上限，已跳过", endpoint.Name, endpoint.MaxRequestsPerMinute))
+			lastError = fmt.Errorf("endpoint %s: max_requests_per_minute (%d) reached", endpoint.Name, endpoint.MaxRequestsPerMinute)
+			lastStatus = http.StatusTooManyRequests
+			attemptNumber++
+			continue
+		}
+		releaseQueueSlot = release
+
+		// 认证方式按 forwardRequest 将设置的认证头判断，转发失败或发生重定向时同样准确
+		authHeader := http.Header{}
+		applyEndpointAuth(authHeader, endpoint, mappedToken)
+		authMethodUsed := upstreamAuthMethod(endpoint.AuthType, authHeader)
+
+		// logClientCanceled 客户端断开时仍写入请求日志，但标记为 client_canceled，不计入端点成功率与最近错误
+		logClientCanceled := func(streaming bool) {
+			a.logProxyRequest(&logger.RequestLog{
+				Timestamp:              time.Now(),
+				RequestID:              requestID,
+				Endpoint:               endpoint.Name,
+				AuthMethodUsed:         authMethodUsed,
+				Method:                 r.Method,
+				Path:                   r.URL.Path,
+				StatusCode:             statusClientClosedRequest,
+				DurationMs:             time.Since(attemptStart).Milliseconds(),
+				AttemptNumber:          attemptNumber,
+				RequestHeaders:         cloneStringMap(originalRequestHeaders),
+				RequestBody:            originalRequestBodyPreview,
+				RequestBodyTruncated:   originalRequestBodyTruncated,
+				RequestBodySize:        requestBodySize,
+				ResponseHeaders:        map[string]string{},
+				IsStreaming:            streaming,
+				Error:                  "client canceled",
+				ErrorCategory:          errorCategoryClientCanceled,
+				Model:                  chooseLoggedModel(originalModel, rewrittenModel),
+				OriginalModel:          originalModel,
+				RewrittenModel:         rewrittenModel,
+				ModelRewriteApplied:    rewriteApplied,
+				Tags:                   utils.MergeTags(requestTags, endpoint.Tags),
+				OriginalRequestURL:     originalRequestURL,
+				OriginalRequestHeaders: cloneStringMap(originalRequestHeaders),
+				OriginalRequestBody:    originalRequestBodyPreview,
+				FinalRequestURL:        targetURL,
+				FinalRequestHeaders:    cloneStringMap(finalRequestHeaders),
+				FinalRequestBody:       finalRequestBodyPreview,
+				ThinkingEnabled:        thinkingEnabled,
+				ThinkingBudgetTokens:   thinkingBudget,
+				ClientType:             clientType,
+				RequestFormat:          requestFormat,
+				DetectionConfidence:    detectionConfidence,
+				DetectedBy:             detectedBy,
+				FormatConverted:        rewriteApplied,
+				ConversionPath:         strings.Join(conversionStages, conversionStageSeparator),
+				EndpointResponseTime:   time.Since(attemptStart).Milliseconds(),
+			})
+		}
 
+		upstreamStart := time.Now()
 		resp, err := a.forwardRequest(r, bodyForEndpoint, targetURL, endpoint, mappedToken)
+		timings.markUpstream(time.Since(upstreamStart))
+		if err != nil && clientCanceled(r) {
+			a.addLog("info", fmt.Sprintf("客户端已取消请求，中止上游调用: %s (%s)", r.URL.Path, endpoint.Name))
+			logClientCanceled(false)
+			return
+		}
 		if err != nil {
 			runtime.LogError(a.ctx, fmt.Sprintf("请求发送失败: %s -> %s (%s): %v", r.URL.Path, targetURL, endpoint.Name, err))
 			a.logProxyRequest(&logger.RequestLog{
 				Timestamp:              time.Now(),
 				RequestID:              requestID,
 				Endpoint:               endpoint.Name,
+				AuthMethodUsed:         authMethodUsed,
 				Method:                 r.Method,
 				Path:                   r.URL.Path,
 				StatusCode:             http.StatusBadGateway,
@@ -656,18 +1087,21 @@ func (a *App) handleProxyRequest(w http.ResponseWriter, r *http.Request) {
 				OriginalModel:          originalModel,
 				RewrittenModel:         rewrittenModel,
 				ModelRewriteApplied:    rewriteApplied,
-				Tags:                   append([]string{}, endpoint.Tags...),
+				Tags:                   utils.MergeTags(requestTags, endpoint.Tags),
 				OriginalRequestURL:     originalRequestURL,
 				OriginalRequestHeaders: cloneStringMap(originalRequestHeaders),
 				OriginalRequestBody:    originalRequestBodyPreview,
 				FinalRequestURL:        targetURL,
 				FinalRequestHeaders:    cloneStringMap(finalRequestHeaders),
 				FinalRequestBody:       finalRequestBodyPreview,
+				ThinkingEnabled:        thinkingEnabled,
+				ThinkingBudgetTokens:   thinkingBudget,
 				ClientType:             clientType,
 				RequestFormat:          requestFormat,
 				DetectionConfidence:    detectionConfidence,
 				DetectedBy:             detectedBy,
 				FormatConverted:        rewriteApplied,
+				ConversionPath:         strings.Join(conversionStages, conversionStageSeparator),
 				EndpointResponseTime:   time.Since(attemptStart).Milliseconds(),
 			})
 			lastError = err
@@ -683,6 +1117,7 @@ func (a *App) handleProxyRequest(w http.ResponseWriter, r *http.Request) {
 				Timestamp:              time.Now(),
 				RequestID:              requestID,
 				Endpoint:               endpoint.Name,
+				AuthMethodUsed:         authMethodUsed,
 				Method:                 r.Method,
 				Path:                   r.URL.Path,
 				StatusCode:             http.StatusBadGateway,
@@ -702,18 +1137,21 @@ func (a *App) handleProxyRequest(w http.ResponseWriter, r *http.Request) {
 				OriginalModel:          originalModel,
 				RewrittenModel:         rewrittenModel,
 				ModelRewriteApplied:    rewriteApplied,
-				Tags:                   append([]string{}, endpoint.Tags...),
+				Tags:                   utils.MergeTags(requestTags, endpoint.Tags),
 				OriginalRequestURL:     originalRequestURL,
 				OriginalRequestHeaders: cloneStringMap(originalRequestHeaders),
 				OriginalRequestBody:    originalRequestBodyPreview,
 				FinalRequestURL:        targetURL,
 				FinalRequestHeaders:    cloneStringMap(finalRequestHeaders),
 				FinalRequestBody:       finalRequestBodyPreview,
+				ThinkingEnabled:        thinkingEnabled,
+				ThinkingBudgetTokens:   thinkingBudget,
 				ClientType:             clientType,
 				RequestFormat:          requestFormat,
 				DetectionConfidence:    detectionConfidence,
 				DetectedBy:             detectedBy,
 				FormatConverted:        rewriteApplied,
+				ConversionPath:         strings.Join(conversionStages, conversionStageSeparator),
 				EndpointResponseTime:   time.Since(attemptStart).Milliseconds(),
 			})
 			attemptNumber++
@@ -729,11 +1167,12 @@ func (a *App) handleProxyRequest(w http.ResponseWriter, r *http.Request) {
 			lastStatus = resp.StatusCode
 			lastBody = bodyCopy
 
-			responseBodyPreview, responseBodyTruncated := truncateStringForLog(string(bodyCopy), healthLogPreviewLimit)
+			responseBodyPreview, responseBodyTruncated := truncateStringForLog(string(bodyCopy), responseBodyLimit)
 			a.logProxyRequest(&logger.RequestLog{
 				Timestamp:              time.Now(),
 				RequestID:              requestID,
 				Endpoint:               endpoint.Name,
+				AuthMethodUsed:         authMethodUsed,
 				Method:                 r.Method,
 				Path:                   r.URL.Path,
 				StatusCode:             resp.StatusCode,
@@ -753,13 +1192,15 @@ func (a *App) handleProxyRequest(w http.ResponseWriter, r *http.Request) {
 				OriginalModel:          originalModel,
 				RewrittenModel:         rewrittenModel,
 				ModelRewriteApplied:    rewriteApplied,
-				Tags:                   append([]string{}, endpoint.Tags...),
+				Tags:                   utils.MergeTags(requestTags, endpoint.Tags),
 				OriginalRequestURL:     originalRequestURL,
 				OriginalRequestHeaders: cloneStringMap(originalRequestHeaders),
 				OriginalRequestBody:    originalRequestBodyPreview,
 				FinalRequestURL:        targetURL,
 				FinalRequestHeaders:    cloneStringMap(finalRequestHeaders),
 				FinalRequestBody:       finalRequestBodyPreview,
+				ThinkingEnabled:        thinkingEnabled,
+				ThinkingBudgetTokens:   thinkingBudget,
 				FinalResponseHeaders:   cloneStringMap(responseHeadersMap),
 				FinalResponseBody:      responseBodyPreview,
 				ClientType:             clientType,
@@ -767,9 +1208,14 @@ func (a *App) handleProxyRequest(w http.ResponseWriter, r *http.Request) {
 				DetectionConfidence:    detectionConfidence,
 				DetectedBy:             detectedBy,
 				FormatConverted:        rewriteApplied,
+				ConversionPath:         strings.Join(conversionStages, conversionStageSeparator),
 				EndpointResponseTime:   time.Since(attemptStart).Milliseconds(),
 			})
 
+			if retryPolicy.IsTerminalStatus(resp.StatusCode) {
+				a.writeTerminalUpstreamError(w, resp, bodyCopy, endpoint.Name)
+				return
+			}
 			attemptNumber++
 			continue
 		}
@@ -778,16 +1224,24 @@ func (a *App) handleProxyRequest(w http.ResponseWriter, r *http.Request) {
         if resp.StatusCode >= http.StatusBadRequest && resp.StatusCode < http.StatusInternalServerError {
             bodyCopy, _ := io.ReadAll(resp.Body)
             resp.Body.Close()
-            runtime.LogWarning(a.ctx, fmt.Sprintf("端点 %s 返回客户端错误 %d，尝试下一端点", endpoint.Name, resp.StatusCode))
+            if resp.StatusCode == http.StatusBadRequest || resp.StatusCode == http.StatusUnprocessableEntity {
+                a.learnUnsupportedParams(endpoint, bodyCopy, bodyForEndpoint)
+            }
+            // 端点 fallback_on_4xx 优先于全局 server.fallback_on_4xx；关闭时直接把 4xx 返回给客户端
+            fallback := a.shouldFallbackOn4xx(&endpoint)
+            if fallback {
+                runtime.LogWarning(a.ctx, fmt.Sprintf("端点 %s 返回客户端错误 %d，尝试下一端点", endpoint.Name, resp.StatusCode))
+            }
             lastStatus = resp.StatusCode
             lastBody = bodyCopy
 
             responseHeadersMap := headersToMap(resp.Header, false)
-            responseBodyPreview, responseBodyTruncated := truncateStringForLog(string(bodyCopy), healthLogPreviewLimit)
+            responseBodyPreview, responseBodyTruncated := truncateStringForLog(string(bodyCopy), responseBodyLimit)
             a.logProxyRequest(&logger.RequestLog{
                 Timestamp:              time.Now(),
                 RequestID:              requestID,
                 Endpoint:               endpoint.Name,
+                AuthMethodUsed:         authMethodUsed,
                 Method:                 r.Method,
                 Path:                   r.URL.Path,
                 StatusCode:             resp.StatusCode,
@@ -807,13 +1261,15 @@ func (a *App) handleProxyRequest(w http.ResponseWriter, r *http.Request) {
                 OriginalModel:          originalModel,
                 RewrittenModel:         rewrittenModel,
                 ModelRewriteApplied:    rewriteApplied,
-                Tags:                   append([]string{}, endpoint.Tags...),
+                Tags:                   utils.MergeTags(requestTags, endpoint.Tags),
                 OriginalRequestURL:     originalRequestURL,
                 OriginalRequestHeaders: cloneStringMap(originalRequestHeaders),
                 OriginalRequestBody:    originalRequestBodyPreview,
                 FinalRequestURL:        targetURL,
                 FinalRequestHeaders:    cloneStringMap(finalRequestHeaders),
                 FinalRequestBody:       finalRequestBodyPreview,
+                ThinkingEnabled:        thinkingEnabled,
+                ThinkingBudgetTokens:   thinkingBudget,
                 FinalResponseHeaders:   cloneStringMap(responseHeadersMap),
                 FinalResponseBody:      responseBodyPreview,
                 ClientType:             clientType,
@@ -821,20 +1277,40 @@ func (a *App) handleProxyRequest(w http.ResponseWriter, r *http.Request) {
                 DetectionConfidence:    detectionConfidence,
                 DetectedBy:             detectedBy,
                 FormatConverted:        rewriteApplied,
+                ConversionPath:         strings.Join(conversionStages, conversionStageSeparator),
                 EndpointResponseTime:   time.Since(attemptStart).Milliseconds(),
             })
 
+            if !fallback || retryPolicy.IsTerminalStatus(resp.StatusCode) {
+                a.writeTerminalUpstreamError(w, resp, bodyCopy, endpoint.Name)
+                return
+            }
             attemptNumber++
             continue
         }
 
 		isStreaming := strings.Contains(strings.ToLower(resp.Header.Get("Content-Type")), "text/event-stream")
+		maxResponseBytes := a.getMaxResponseBodyBytes()
 
 		if isStreaming {
-			// 读取流式响应体（用于模型重写）
-			streamBody, readErr := io.ReadAll(resp.Body)
+			// 读取流式响应体（用于模型重写），超过 server.max_response_body_bytes 时截断
+			streamBody, readErr := readResponseBodyCapped(resp.Body, maxResponseBytes)
 			resp.Body.Close()
-			if readErr != nil {
+
+			// 🔥 GZIP DECOMPRESSION: 检查并解压 gzip；解压失败（如连接中断导致的截断）视为端点错误，尝试下一个端点
+			if readErr == nil {
+				streamBody, readErr = decodeGzipBody(streamBody, maxResponseBytes)
+			}
+			if errors.Is(readErr, errGzipDecompressFailed) {
+				runtime.LogWarning(a.ctx, fmt.Sprintf("流式响应 gzip 解压失败，尝试下一个端点: %s -> %s (%s): %v", r.URL.Path, targetURL, endpoint.Name, readErr))
+				a.addLog("warn", fmt.Sprintf("端点 %s 返回的 gzip 流式响应不完整或已损坏: %v", endpoint.Name, readErr))
+			}
+			if readErr != nil && clientCanceled(r) {
+				a.addLog("info", fmt.Sprintf("客户端已取消流式请求，中止上游读取: %s (%s)", r.URL.Path, endpoint.Name))
+				logClientCanceled(true)
+				return
+			}
+			if readErr != nil && !errors.Is(readErr, errResponseBodyTooLarge) {
 				runtime.LogError(a.ctx, fmt.Sprintf("读取流式响应失败: %s -> %s (%s): %v", r.URL.Path, targetURL, endpoint.Name, readErr))
 				lastError = readErr
 				lastStatus = http.StatusBadGateway
@@ -842,22 +1318,37 @@ func (a *App) handleProxyRequest(w http.ResponseWriter, r *http.Request) {
 				continue
 			}
 
-			// 🔥 GZIP DECOMPRESSION: 检查并解压 gzip
-			if len(streamBody) > 2 && streamBody[0] == 0x1f && streamBody[1] == 0x8b {
-				runtime.LogInfo(a.ctx, "Detected gzip compressed response, decompressing...")
-				gzReader, gzErr := gzip.NewReader(bytes.NewReader(streamBody))
-				if gzErr == nil {
-					decompressed, gzErr := io.ReadAll(gzReader)
-					gzReader.Close()
-					if gzErr == nil {
-						streamBody = decompressed
-						runtime.LogInfo(a.ctx, "✅ Gzip decompression successful")
-					}
+			timings.beginResponseConversion()
+
+			// 响应体超限：丢弃最后一个不完整事件，后续补发终止事件
+			oversizedStream := errors.Is(readErr, errResponseBodyTooLarge)
+			if oversizedStream {
+				streamBody = trimToLastSSEEvent(streamBody)
+				runtime.LogError(a.ctx, fmt.Sprintf("流式响应超过 %d 字节上限，已中止: %s (%s)", maxResponseBytes, r.URL.Path, endpoint.Name))
+				a.addLog("error", fmt.Sprintf("端点 %s 的流式响应超过 %d 字节上限，已截断并补发终止事件", endpoint.Name, maxResponseBytes))
+			}
+
+			// 在格式转换前检查上游原始流是否正常结束：转换会补齐 message_delta/message_stop，转换后再检查会把截断的流误判为完整
+			needsFormatConversion := endpoint.URLAnthropic == "" && endpoint.URLOpenAI != "" && requestFormat == "anthropic"
+			upstreamFormat, upstreamPath := requestFormat, r.URL.Path
+			if needsFormatConversion {
+				upstreamFormat, upstreamPath = "openai", "/chat/completions"
+			}
+			upstreamTerminated := !oversizedStream && sseStreamTerminated(streamBody, upstreamFormat, upstreamPath)
+
+			// 端点 sse_event_filter：丢弃上游的噪声事件（如 ping、厂商私有 x_*），协议必需事件始终保留
+			if len(endpoint.SSEEventFilter) > 0 {
+				if filtered, dropped := filterSSEEvents(streamBody, endpoint.SSEEventFilter); dropped > 0 {
+					streamBody = filtered
+					conversionStages = append(conversionStages, "response:sse_event_filter")
+					runtime.LogDebug(a.ctx, fmt.Sprintf("已过滤 %d 个 SSE 事件 (%s)", dropped, endpoint.Name))
 				}
 			}
 
+			// 记录上游实际使用的 service_tier（转换前提取）
+			upstreamServiceTier := extractServiceTier(streamBody)
+
 			// 🔥 FORMAT CONVERSION (SSE): OpenAI SSE → Anthropic SSE
-			needsFormatConversion := endpoint.URLAnthropic == "" && endpoint.URLOpenAI != "" && requestFormat == "anthropic"
 			runtime.LogInfo(a.ctx, fmt.Sprintf("🔍 SSE Conv check: URLAnthropic=%q URLOpenAI=%q requestFormat=%q needs=%v", 
 				endpoint.URLAnthropic, endpoint.URLOpenAI, requestFormat, needsFormatConversion))
 			
@@ -870,12 +1361,30 @@ func (a *App) handleProxyRequest(w http.ResponseWriter, r *http.Request) {
 				convErr := conversion.StreamOpenAISSEToAnthropic(reader, &buf)
 				if convErr == nil {
 					streamBody = buf.Bytes()
+					conversionStages = append(conversionStages, "response:openai_sse->anthropic_sse")
 					runtime.LogInfo(a.ctx, fmt.Sprintf("✅ SSE format conversion successful, new length: %d", len(streamBody)))
 				} else {
 					runtime.LogError(a.ctx, fmt.Sprintf("❌ SSE format conversion failed: %v", convErr))
 				}
 			}
 
+			if endpoint.StripReasoning {
+				if stripped, removed := conversion.StripReasoningFromResponse(streamBody); removed {
+					streamBody = stripped
+					conversionStages = append(conversionStages, "response:strip_reasoning")
+					runtime.LogInfo(a.ctx, fmt.Sprintf("已移除流式响应中的推理内容 (%s)", endpoint.Name))
+				}
+			}
+
+			// thinking_stream_mode：为不支持 thinking 事件的旧客户端将思考内容改写为普通文本或丢弃，正文保持不变
+			if mode := a.resolveThinkingStreamMode(&endpoint); mode != conversion.ThinkingStreamModePassthrough {
+				if rewritten, changed := conversion.ApplyThinkingStreamMode(streamBody, mode); changed {
+					streamBody = rewritten
+					conversionStages = append(conversionStages, "response:thinking_"+mode)
+					runtime.LogDebug(a.ctx, fmt.Sprintf("流式响应 thinking 内容已按 %s 模式处理 (%s)", mode, endpoint.Name))
+				}
+			}
+
 			// 应用模型重写（SSE 格式）
 			if rewriteApplied && a.modelRewriter != nil && originalModel != "" && rewrittenModel != "" {
 				if rewrittenBody, err := a.modelRewriter.RewriteResponse(streamBody, originalModel, rewrittenModel); err == nil {
@@ -884,16 +1393,33 @@ func (a *App) handleProxyRequest(w http.ResponseWriter, r *http.Request) {
 				} else {
 					runtime.LogWarning(a.ctx, fmt.Sprintf("流式响应模型重写失败: %v", err))
 				}
+				// 部分上游不返回 model 字段，回写会失效；默认强制使用客户端原始模型名
+				if a.isPreserveOriginalModelEnabled() {
+					streamBody = a.modelRewriter.ForceResponseModel(streamBody, originalModel)
+				}
 			}
 
 			// SSE格式中空text是正常的（在content_block_start中），不需要修复
 
+			// 上游流中途断开时补发终止事件，避免客户端一直等待
+			streamError := ""
+			if !upstreamTerminated {
+				streamBody = appendSSETerminalError(streamBody, requestFormat, r.URL.Path, needsFormatConversion)
+				streamError = "upstream stream ended without a terminal event"
+				runtime.LogWarning(a.ctx, fmt.Sprintf("流式响应未正常结束，已补发终止事件: %s (%s)", r.URL.Path, endpoint.Name))
+			}
+			if oversizedStream {
+				streamError = fmt.Sprintf("response body exceeded max_response_body_bytes (%d)", maxResponseBytes)
+			}
+
 			// 发送响应
 			for key, values := range resp.Header {
 				for _, value := range values {
 					w.Header().Add(key, value)
 				}
 			}
+			contentTypeOverride := a.correctContentType(w, streamBody, resp, endpoint.Name)
+			applyResponseHeaderOverrides(w.Header(), endpoint.ResponseHeaderOverrides)
 			w.Header().Set("Content-Length", strconv.Itoa(len(streamBody)))
 			w.WriteHeader(resp.StatusCode)
 			w.Write(streamBody)
@@ -902,6 +1428,7 @@ func (a *App) handleProxyRequest(w http.ResponseWriter, r *http.Request) {
 				Timestamp:              time.Now(),
 				RequestID:              requestID,
 				Endpoint:               endpoint.Name,
+				AuthMethodUsed:         authMethodUsed,
 				Method:                 r.Method,
 				Path:                   r.URL.Path,
 				StatusCode:             resp.StatusCode,
@@ -916,17 +1443,22 @@ func (a *App) handleProxyRequest(w http.ResponseWriter, r *http.Request) {
 				ResponseBodyTruncated:  false,
 				ResponseBodySize:       0,
 				IsStreaming:            true,
+				Error:                  streamError,
+				ServiceTier:            upstreamServiceTier,
+				ContentTypeOverride:    contentTypeOverride,
 				Model:                  chooseLoggedModel(originalModel, rewrittenModel),
 				OriginalModel:          originalModel,
 				RewrittenModel:         rewrittenModel,
 				ModelRewriteApplied:    rewriteApplied,
-				Tags:                   append([]string{}, endpoint.Tags...),
+				Tags:                   utils.MergeTags(requestTags, endpoint.Tags),
 				OriginalRequestURL:     originalRequestURL,
 				OriginalRequestHeaders: cloneStringMap(originalRequestHeaders),
 				OriginalRequestBody:    originalRequestBodyPreview,
 				FinalRequestURL:        targetURL,
 				FinalRequestHeaders:    cloneStringMap(finalRequestHeaders),
 				FinalRequestBody:       finalRequestBodyPreview,
+				ThinkingEnabled:        thinkingEnabled,
+				ThinkingBudgetTokens:   thinkingBudget,
 				FinalResponseHeaders:   cloneStringMap(responseHeadersMap),
 				FinalResponseBody:      "",
 				ClientType:             clientType,
@@ -934,23 +1466,77 @@ func (a *App) handleProxyRequest(w http.ResponseWriter, r *http.Request) {
 				DetectionConfidence:    detectionConfidence,
 				DetectedBy:             detectedBy,
 				FormatConverted:        rewriteApplied,
+				ConversionPath:         strings.Join(conversionStages, conversionStageSeparator),
 				EndpointResponseTime:   time.Since(attemptStart).Milliseconds(),
 			})
 
+			noteAccessLogUpstream(w, endpoint.Name, chooseLoggedModel(originalModel, rewrittenModel))
 			duration := time.Since(startTime).Milliseconds()
 			runtime.LogInfo(a.ctx, fmt.Sprintf("请求成功: %s -> %s (%dms)", r.URL.Path, targetURL, duration))
 			return
 		}
 
-		respBody, readErr := io.ReadAll(resp.Body)
+		respBody, readErr := readResponseBodyCapped(resp.Body, maxResponseBytes)
 		resp.Body.Close()
+
+		// 响应体被截断（连接中途断开）属于网络抖动而非格式错误：立即重试同一端点一次，仍截断时切换端点且不计入端点健康统计
+		if reason := truncatedResponseReason(respBody, resp.ContentLength, readErr); reason != "" && !clientCanceled(r) {
+			msg := fmt.Sprintf("端点 %s 的响应被截断（%s），判定为网络中断而非格式错误，重试同一端点", endpoint.Name, reason)
+			runtime.LogWarning(a.ctx, msg)
+			a.addLog("warn", msg)
+			// 重试同样占用端点的每分钟请求预算；预算用尽时不再重试，直接按截断处理
+			if !a.reserveRequestBudget(endpoint.Name, endpoint.MaxRequestsPerMinute, time.Now()) {
+				a.addLog("warn", fmt.Sprintf("端点 %s 已达到每分钟 %d 次请求上限，跳过截断重试", endpoint.Name, endpoint.MaxRequestsPerMinute))
+			} else if retryResp, retryErr := a.forwardRequest(r, bodyForEndpoint, targetURL, endpoint, mappedToken); retryErr == nil {
+				if retryResp.StatusCode == resp.StatusCode && !strings.Contains(strings.ToLower(retryResp.Header.Get("Content-Type")), "text/event-stream") {
+					resp = retryResp
+					responseHeadersMap = headersToMap(resp.Header, false)
+					respBody, readErr = readResponseBodyCapped(resp.Body, maxResponseBytes)
+				}
+				retryResp.Body.Close()
+			}
+			if reason := truncatedResponseReason(respBody, resp.ContentLength, readErr); reason != "" {
+				readErr = fmt.Errorf("%w: %s", errResponseTruncated, reason)
+			} else {
+				a.addLog("info", fmt.Sprintf("端点 %s 重试后响应完整", endpoint.Name))
+			}
+		}
+
+		// 🔥 GZIP DECOMPRESSION: 检查并解压 gzip；解压失败时不转发仍为压缩数据的响应体，按端点错误处理
+		if readErr == nil {
+			respBody, readErr = decodeGzipBody(respBody, maxResponseBytes)
+			if errors.Is(readErr, errGzipDecompressFailed) {
+				runtime.LogWarning(a.ctx, fmt.Sprintf("响应 gzip 解压失败，尝试下一个端点: %s -> %s (%s): %v", r.URL.Path, targetURL, endpoint.Name, readErr))
+				a.addLog("warn", fmt.Sprintf("端点 %s 返回的 gzip 响应不完整或已损坏: %v", endpoint.Name, readErr))
+			}
+		}
+
+		if readErr != nil && clientCanceled(r) {
+			a.addLog("info", fmt.Sprintf("客户端已取消请求，中止上游读取: %s (%s)", r.URL.Path, endpoint.Name))
+			logClientCanceled(false)
+			return
+		}
+		timings.beginResponseConversion()
 		if readErr != nil {
 			lastError = readErr
 			lastStatus = http.StatusBadGateway
+			if errors.Is(readErr, errResponseBodyTooLarge) {
+				// 不把被截断的 JSON 转发给客户端，改为明确的错误
+				runtime.LogError(a.ctx, fmt.Sprintf("响应超过 %d 字节上限，已中止: %s (%s)", maxResponseBytes, r.URL.Path, endpoint.Name))
+				a.addLog("error", fmt.Sprintf("端点 %s 的响应超过 %d 字节上限，已中止", endpoint.Name, maxResponseBytes))
+				lastBody, _ = json.Marshal(map[string]interface{}{
+					"success": false,
+					"error": map[string]interface{}{
+						"code":    "response_too_large",
+						"message": fmt.Sprintf("Upstream response exceeded %d bytes", maxResponseBytes),
+					},
+				})
+			}
 			a.logProxyRequest(&logger.RequestLog{
 				Timestamp:              time.Now(),
 				RequestID:              requestID,
 				Endpoint:               endpoint.Name,
+				AuthMethodUsed:         authMethodUsed,
 				Method:                 r.Method,
 				Path:                   r.URL.Path,
 				StatusCode:             http.StatusBadGateway,
@@ -966,53 +1552,110 @@ func (a *App) handleProxyRequest(w http.ResponseWriter, r *http.Request) {
 				ResponseBodySize:       0,
 				IsStreaming:            false,
 				Error:                  readErr.Error(),
+				ErrorCategory:          readErrorCategory(readErr),
 				Model:                  chooseLoggedModel(originalModel, rewrittenModel),
 				OriginalModel:          originalModel,
 				RewrittenModel:         rewrittenModel,
 				ModelRewriteApplied:    rewriteApplied,
-				Tags:                   append([]string{}, endpoint.Tags...),
+				Tags:                   utils.MergeTags(requestTags, endpoint.Tags),
 				OriginalRequestURL:     originalRequestURL,
 				OriginalRequestHeaders: cloneStringMap(originalRequestHeaders),
 				OriginalRequestBody:    originalRequestBodyPreview,
 				FinalRequestURL:        targetURL,
 				FinalRequestHeaders:    cloneStringMap(finalRequestHeaders),
 				FinalRequestBody:       finalRequestBodyPreview,
+				ThinkingEnabled:        thinkingEnabled,
+				ThinkingBudgetTokens:   thinkingBudget,
 				ClientType:             clientType,
 				RequestFormat:          requestFormat,
 				DetectionConfidence:    detectionConfidence,
 				DetectedBy:             detectedBy,
 				FormatConverted:        rewriteApplied,
+				ConversionPath:         strings.Join(conversionStages, conversionStageSeparator),
 				EndpointResponseTime:   time.Since(attemptStart).Milliseconds(),
 			})
 			attemptNumber++
 			continue
 		}
 
-		// 🔥 GZIP DECOMPRESSION: 检查并解压 gzip
-		if len(respBody) > 2 && respBody[0] == 0x1f && respBody[1] == 0x8b {
-			runtime.LogInfo(a.ctx, "Detected gzip compressed response, decompressing...")
-			gzReader, gzErr := gzip.NewReader(bytes.NewReader(respBody))
-			if gzErr == nil {
-				decompressed, gzErr := io.ReadAll(gzReader)
-				gzReader.Close()
-				if gzErr == nil {
-					respBody = decompressed
-					runtime.LogInfo(a.ctx, "✅ Gzip decompression successful")
-				}
+		// server.detect_error_in_2xx：部分代理以 200 返回 {"error": ...}，按失败处理并尝试下一个端点
+		if !batchRequest && resp.StatusCode >= http.StatusOK && resp.StatusCode < http.StatusMultipleChoices && a.isErrorIn2xxDetectionEnabled() {
+			if errMessage, isError := detectErrorBodyIn2xx(respBody, upstreamResponseFormat(targetURL)); isError {
+				runtime.LogWarning(a.ctx, fmt.Sprintf("端点 %s 返回 %d 但响应体为错误对象，尝试下一个端点: %s", endpoint.Name, resp.StatusCode, errMessage))
+				a.addLog("warn", fmt.Sprintf("端点 %s 返回 %d 但响应体为错误: %s", endpoint.Name, resp.StatusCode, errMessage))
+				lastError = fmt.Errorf("endpoint %s returned error body with status %d: %s", endpoint.Name, resp.StatusCode, errMessage)
+				lastStatus = http.StatusBadGateway
+				lastBody = respBody
+				responseBodyPreview, responseBodyTruncated := truncateStringForLog(string(respBody), responseBodyLimit)
+				a.logProxyRequest(&logger.RequestLog{
+					Timestamp:              time.Now(),
+					RequestID:              requestID,
+					Endpoint:               endpoint.Name,
+					AuthMethodUsed:         authMethodUsed,
+					Method:                 r.Method,
+					Path:                   r.URL.Path,
+					StatusCode:             http.StatusBadGateway,
+					DurationMs:             time.Since(attemptStart).Milliseconds(),
+					AttemptNumber:          attemptNumber,
+					RequestHeaders:         cloneStringMap(originalRequestHeaders),
+					RequestBody:            originalRequestBodyPreview,
+					RequestBodyTruncated:   originalRequestBodyTruncated,
+					RequestBodySize:        requestBodySize,
+					ResponseHeaders:        cloneStringMap(responseHeadersMap),
+					ResponseBody:           responseBodyPreview,
+					ResponseBodyTruncated:  responseBodyTruncated,
+					ResponseBodySize:       len(respBody),
+					IsStreaming:            false,
+					Error:                  lastError.Error(),
+					Model:                  chooseLoggedModel(originalModel, rewrittenModel),
+					OriginalModel:          originalModel,
+					RewrittenModel:         rewrittenModel,
+					ModelRewriteApplied:    rewriteApplied,
+					Tags:                   utils.MergeTags(requestTags, endpoint.Tags),
+					OriginalRequestURL:     originalRequestURL,
+					OriginalRequestHeaders: cloneStringMap(originalRequestHeaders),
+					OriginalRequestBody:    originalRequestBodyPreview,
+					FinalRequestURL:        targetURL,
+					FinalRequestHeaders:    cloneStringMap(finalRequestHeaders),
+					FinalRequestBody:       finalRequestBodyPreview,
+					ThinkingEnabled:        thinkingEnabled,
+					ThinkingBudgetTokens:   thinkingBudget,
+					ClientType:             clientType,
+					RequestFormat:          requestFormat,
+					DetectionConfidence:    detectionConfidence,
+					DetectedBy:             detectedBy,
+					FormatConverted:        rewriteApplied,
+					ConversionPath:         strings.Join(conversionStages, conversionStageSeparator),
+					EndpointResponseTime:   time.Since(attemptStart).Milliseconds(),
+				})
+				attemptNumber++
+				continue
 			}
 		}
 
+		upstreamServiceTier := extractServiceTier(respBody)
+
 		if rewriteApplied && a.modelRewriter != nil && originalModel != "" && rewrittenModel != "" {
 			if rewrittenBody, err := a.modelRewriter.RewriteResponse(respBody, originalModel, rewrittenModel); err == nil {
 				respBody = rewrittenBody
 			}
+			if a.isPreserveOriginalModelEnabled() {
+				respBody = a.modelRewriter.ForceResponseModel(respBody, originalModel)
+			}
 		}
 
 		// 🔥 FORMAT CONVERSION: OpenAI → Anthropic
 		runtime.LogInfo(a.ctx, fmt.Sprintf("🔍 Non-streaming format check: endpoint=%s, requestFormat=%q, URLAnth=%q, URLOpenAI=%q", 
 			endpoint.Name, requestFormat, endpoint.URLAnthropic, endpoint.URLOpenAI))
 		
-		if requestFormat == "anthropic" && endpoint.URLAnthropic == "" && endpoint.URLOpenAI != "" {
+		if legacyCompleteConverted {
+			if convertedBody, convErr := conversion.ConvertChatResponseToLegacyComplete(respBody); convErr == nil {
+				respBody = convertedBody
+				conversionStages = append(conversionStages, "response:openai->anthropic_complete")
+			} else {
+				runtime.LogError(a.ctx, fmt.Sprintf("❌ Legacy completion response conversion failed: %v", convErr))
+			}
+		} else if requestFormat == "anthropic" && endpoint.URLAnthropic == "" && endpoint.URLOpenAI != "" {
 			// 检测响应格式
 			var testResp map[string]interface{}
 			if json.Unmarshal(respBody, &testResp) == nil {
@@ -1023,6 +1666,7 @@ func (a *App) handleProxyRequest(w http.ResponseWriter, r *http.Request) {
 					convertedBody, convErr := conversion.ConvertChatResponseJSONToAnthropic(respBody)
 					if convErr == nil {
 						respBody = convertedBody
+						conversionStages = append(conversionStages, "response:openai->anthropic")
 						runtime.LogInfo(a.ctx, "✅ Response format conversion successful")
 					} else {
 						runtime.LogError(a.ctx, fmt.Sprintf("❌ Response format conversion failed: %v", convErr))
@@ -1035,37 +1679,73 @@ func (a *App) handleProxyRequest(w http.ResponseWriter, r *http.Request) {
 			runtime.LogInfo(a.ctx, "ℹ️ Format conversion skipped (conditions not met)")
 		}
 
-		// 🔥 RESPONSE VALIDATION: 修复不完整的 Anthropic 响应
-		if requestFormat == "anthropic" {
-			var anthResp map[string]interface{}
-			if err := json.Unmarshal(respBody, &anthResp); err == nil {
-				// 检查是否是 Anthropic 格式
-				if anthResp["type"] == "message" {
-					if content, ok := anthResp["content"].([]interface{}); ok {
-						fixed := false
-						for i, block := range content {
-							if blockMap, ok := block.(map[string]interface{}); ok {
-								if blockMap["type"] == "text" {
-									// 检查 text 字段是否存在且非空
-									textVal, hasText := blockMap["text"]
-									if !hasText || textVal == "" {
-										// 修复：添加占位符文本，避免 Claude Code 报错
-										blockMap["text"] = "[Empty response from upstream]"
-										content[i] = blockMap
-										fixed = true
-										runtime.LogInfo(a.ctx, fmt.Sprintf("🔧 Fixed empty text field in content block %d", i))
-									}
-								}
-							}
-						}
-						if fixed {
-							anthResp["content"] = content
-							if fixedBody, err := json.Marshal(anthResp); err == nil {
-								respBody = fixedBody
-								runtime.LogInfo(a.ctx, "✅ Response validation: fixed empty Anthropic response")
-							}
-						}
-					}
+		if endpoint.StripReasoning {
+			if stripped, removed := conversion.StripReasoningFromResponse(respBody); removed {
+				respBody = stripped
+				conversionStages = append(conversionStages, "response:strip_reasoning")
+				runtime.LogInfo(a.ctx, fmt.Sprintf("已移除响应中的推理内容 (%s)", endpoint.Name))
+			}
+		}
+
+		// 🔥 RESPONSE VALIDATION: 处理空文本块的 Anthropic 响应（批处理响应是 message_batch 或 JSONL 结果，不做处理）
+		// server.empty_response_action: patch（默认，填充占位符）| retry（完全为空时尝试下一个端点）| passthrough（原样返回）
+		if requestFormat == "anthropic" && !batchRequest {
+			emptyAction, placeholder := a.getEmptyResponseSettings()
+			if emptyAction == emptyResponseActionRetry && isEmptyAnthropicResponse(respBody) {
+				runtime.LogWarning(a.ctx, fmt.Sprintf("上游返回空响应，尝试下一个端点: %s (%s)", r.URL.Path, endpoint.Name))
+				a.addLog("warn", fmt.Sprintf("端点 %s 返回空响应，尝试下一个端点", endpoint.Name))
+				lastError = fmt.Errorf("empty response from endpoint %s", endpoint.Name)
+				lastStatus = http.StatusBadGateway
+				responseBodyPreview, responseBodyTruncated := truncateStringForLog(string(respBody), responseBodyLimit)
+				a.logProxyRequest(&logger.RequestLog{
+					Timestamp:              time.Now(),
+					RequestID:              requestID,
+					Endpoint:               endpoint.Name,
+					AuthMethodUsed:         authMethodUsed,
+					Method:                 r.Method,
+					Path:                   r.URL.Path,
+					StatusCode:             http.StatusBadGateway,
+					DurationMs:             time.Since(attemptStart).Milliseconds(),
+					AttemptNumber:          attemptNumber,
+					RequestHeaders:         cloneStringMap(originalRequestHeaders),
+					RequestBody:            originalRequestBodyPreview,
+					RequestBodyTruncated:   originalRequestBodyTruncated,
+					RequestBodySize:        requestBodySize,
+					ResponseHeaders:        cloneStringMap(responseHeadersMap),
+					ResponseBody:           responseBodyPreview,
+					ResponseBodyTruncated:  responseBodyTruncated,
+					ResponseBodySize:       len(respBody),
+					IsStreaming:            false,
+					Error:                  lastError.Error(),
+					Model:                  chooseLoggedModel(originalModel, rewrittenModel),
+					OriginalModel:          originalModel,
+					RewrittenModel:         rewrittenModel,
+					ModelRewriteApplied:    rewriteApplied,
+					Tags:                   utils.MergeTags(requestTags, endpoint.Tags),
+					OriginalRequestURL:     originalRequestURL,
+					OriginalRequestHeaders: cloneStringMap(originalRequestHeaders),
+					OriginalRequestBody:    originalRequestBodyPreview,
+					FinalRequestURL:        targetURL,
+					FinalRequestHeaders:    cloneStringMap(finalRequestHeaders),
+					FinalRequestBody:       finalRequestBodyPreview,
+					ThinkingEnabled:        thinkingEnabled,
+					ThinkingBudgetTokens:   thinkingBudget,
+					ClientType:             clientType,
+					RequestFormat:          requestFormat,
+					DetectionConfidence:    detectionConfidence,
+					DetectedBy:             detectedBy,
+					FormatConverted:        rewriteApplied,
+					ConversionPath:         strings.Join(conversionStages, conversionStageSeparator),
+					EndpointResponseTime:   time.Since(attemptStart).Milliseconds(),
+				})
+				attemptNumber++
+				continue
+			}
+			// retry 模式下部分文本块为空（如伴随 tool_use）时仍按占位符修复，避免 Claude Code 报错
+			if emptyAction != emptyResponseActionPassthrough {
+				if fixedBody, fixed := patchEmptyAnthropicText(respBody, placeholder); fixed {
+					respBody = fixedBody
+					runtime.LogInfo(a.ctx, "✅ Response validation: fixed empty Anthropic response")
 				}
 			}
 		}
@@ -1078,15 +1758,18 @@ func (a *App) handleProxyRequest(w http.ResponseWriter, r *http.Request) {
 				w.Header().Add(key, value)
 			}
 		}
+		contentTypeOverride := a.correctContentType(w, respBody, resp, endpoint.Name)
+		applyResponseHeaderOverrides(w.Header(), endpoint.ResponseHeaderOverrides)
 		w.Header().Set("Content-Length", strconv.Itoa(len(respBody)))
 		w.WriteHeader(resp.StatusCode)
 		w.Write(respBody)
 
-		responseBodyPreview, responseBodyTruncated := truncateStringForLog(string(respBody), healthLogPreviewLimit)
+		responseBodyPreview, responseBodyTruncated := truncateStringForLog(string(respBody), responseBodyLimit)
 		a.logProxyRequest(&logger.RequestLog{
 			Timestamp:              time.Now(),
 			RequestID:              requestID,
 			Endpoint:               endpoint.Name,
+			AuthMethodUsed:         authMethodUsed,
 			Method:                 r.Method,
 			Path:                   r.URL.Path,
 			StatusCode:             resp.StatusCode,
@@ -1101,17 +1784,21 @@ func (a *App) handleProxyRequest(w http.ResponseWriter, r *http.Request) {
 			ResponseBodyTruncated:  responseBodyTruncated,
 			ResponseBodySize:       len(respBody),
 			IsStreaming:            false,
+			ServiceTier:            upstreamServiceTier,
+			ContentTypeOverride:    contentTypeOverride,
 			Model:                  chooseLoggedModel(originalModel, rewrittenModel),
 			OriginalModel:          originalModel,
 			RewrittenModel:         rewrittenModel,
 			ModelRewriteApplied:    rewriteApplied,
-			Tags:                   append([]string{}, endpoint.Tags...),
+			Tags:                   utils.MergeTags(requestTags, endpoint.Tags),
 			OriginalRequestURL:     originalRequestURL,
 			OriginalRequestHeaders: cloneStringMap(originalRequestHeaders),
 			OriginalRequestBody:    originalRequestBodyPreview,
 			FinalRequestURL:        targetURL,
 			FinalRequestHeaders:    cloneStringMap(finalRequestHeaders),
 			FinalRequestBody:       finalRequestBodyPreview,
+			ThinkingEnabled:        thinkingEnabled,
+			ThinkingBudgetTokens:   thinkingBudget,
 			FinalResponseHeaders:   cloneStringMap(responseHeadersMap),
 			FinalResponseBody:      responseBodyPreview,
 			ClientType:             clientType,
@@ -1119,9 +1806,11 @@ func (a *App) handleProxyRequest(w http.ResponseWriter, r *http.Request) {
 			DetectionConfidence:    detectionConfidence,
 			DetectedBy:             detectedBy,
 			FormatConverted:        rewriteApplied,
+			ConversionPath:         strings.Join(conversionStages, conversionStageSeparator),
 			EndpointResponseTime:   time.Since(attemptStart).Milliseconds(),
 		})
 
+		noteAccessLogUpstream(w, endpoint.Name, chooseLoggedModel(originalModel, rewrittenModel))
 		duration := time.Since(startTime).Milliseconds()
 		runtime.LogInfo(a.ctx, fmt.Sprintf("请求成功: %s -> %s (%dms)", r.URL.Path, targetURL, duration))
 		return
@@ -1167,10 +1856,17 @@ func (a *App) handleProxyRequest(w http.ResponseWriter, r *http.Request) {
 
 	if lastStatus != 0 {
 		runtime.LogError(a.ctx, fmt.Sprintf("所有端点返回服务器错误，最后状态码: %d", lastStatus))
+		a.recordDeadLetter(requestID, r, originalRequestBody, originalRequestHeaders, clientType, requestFormat, lastStatus, fmt.Sprintf("All endpoints returned %d", lastStatus))
 		if len(lastBody) > 0 {
-			w.WriteHeader(lastStatus)
-			w.Write(lastBody)
-			responseBodyPreview, responseBodyTruncated := truncateStringForLog(string(lastBody), healthLogPreviewLimit)
+			// Responses API 客户端无法解析其他格式的上游错误体，保留上游错误信息改写为 Responses 错误
+			if strings.Contains(r.URL.Path, "/responses") {
+				writeProxyError(w, r, body, lastStatus, "upstream_error", upstreamErrorMessage(lastBody))
+			} else {
+				w.Header().Set("Content-Type", errorBodyContentType(lastBody))
+				w.WriteHeader(lastStatus)
+				w.Write(lastBody)
+			}
+			responseBodyPreview, responseBodyTruncated := truncateStringForLog(string(lastBody), responseBodyLimit)
 			a.logProxyRequest(&logger.RequestLog{
 				Timestamp:              time.Now(),
 				RequestID:              requestID,
@@ -1204,7 +1900,7 @@ func (a *App) handleProxyRequest(w http.ResponseWriter, r *http.Request) {
 				EndpointResponseTime:   time.Since(startTime).Milliseconds(),
 			})
 		} else {
-			writeJSONError(w, lastStatus, "upstream_error", "All upstream endpoints returned errors")
+			writeProxyError(w, r, body, lastStatus, "upstream_error", "All upstream endpoints returned errors")
 			a.logProxyRequest(&logger.RequestLog{
 				Timestamp:              time.Now(),
 				RequestID:              requestID,
@@ -1243,6 +1939,7 @@ func (a *App) handleProxyRequest(w http.ResponseWriter, r *http.Request) {
 
 	if lastError != nil {
 		runtime.LogError(a.ctx, fmt.Sprintf("所有端点请求失败: %v", lastError))
+		a.recordDeadLetter(requestID, r, originalRequestBody, originalRequestHeaders, clientType, requestFormat, http.StatusBadGateway, lastError.Error())
 		a.logProxyRequest(&logger.RequestLog{
 			Timestamp:              time.Now(),
 			RequestID:              requestID,
@@ -1275,11 +1972,12 @@ func (a *App) handleProxyRequest(w http.ResponseWriter, r *http.Request) {
 			FormatConverted:        false,
 			EndpointResponseTime:   time.Since(startTime).Milliseconds(),
 		})
-		writeJSONError(w, http.StatusBadGateway, "proxy_forward_failed", lastError.Error())
+		writeProxyError(w, r, body, http.StatusBadGateway, "proxy_forward_failed", lastError.Error())
 		return
 	}
 
 	runtime.LogError(a.ctx, "没有可用端点处理请求")
+	a.recordDeadLetter(requestID, r, originalRequestBody, originalRequestHeaders, clientType, requestFormat, http.StatusServiceUnavailable, "No available endpoints")
 	a.logProxyRequest(&logger.RequestLog{
 		Timestamp:              time.Now(),
 		RequestID:              requestID,
@@ -1312,7 +2010,7 @@ func (a *App) handleProxyRequest(w http.ResponseWriter, r *http.Request) {
 		FormatConverted:        false,
 		EndpointResponseTime:   time.Since(startTime).Milliseconds(),
 	})
-	writeJSONError(w, http.StatusServiceUnavailable, "no_available_endpoints", "No available endpoints")
+	writeProxyError(w, r, body, http.StatusServiceUnavailable, "no_available_endpoints", "No available endpoints")
 }
 
 // buildTargetURL 根据请求路径选择端点基础URL并拼接完整目标URL
@@ -1331,6 +2029,20 @@ func (a *App) buildTargetURL(endpoint *config.EndpointConfig, requestPath string
 
 	var base string
 	switch {
+	case utils.IsAnthropicBatchPath(reqPath):
+		// 批处理 API 没有 OpenAI 等价路径，不做路径转换
+		if endpoint.URLAnthropic == "" {
+			return "", fmt.Errorf("endpoint %s has no Anthropic URL for batch path %s", endpoint.Name, reqPath)
+		}
+		base = endpoint.URLAnthropic
+	case utils.IsAnthropicCompletePath(reqPath):
+		if endpoint.URLAnthropic != "" {
+			base = endpoint.URLAnthropic
+		} else {
+			// 旧版补全请求体已转换为 chat 格式
+			base = endpoint.URLOpenAI
+			reqPath = strings.TrimSuffix(strings.TrimSuffix(reqPath, "/"), "/v1/complete") + "/v1/chat/completions"
+		}
 	case strings.HasPrefix(reqPath, "/v1/messages"):
 		if endpoint.URLAnthropic != "" {
 			base = endpoint.URLAnthropic
@@ -1390,43 +2102,509 @@ func (a *App) buildTargetURL(endpoint *config.EndpointConfig, requestPath string
 	return baseURL.String(), nil
 }
 
-// getAvailableEndpoints 获取可用的端点
-func (a *App) getAvailableEndpoints() ([]config.EndpointConfig, error) {
-	query := `
-		SELECT name,
-		       url_anthropic,
-			   url_openai,
-			   endpoint_type,
-			   auth_type,
-			   auth_value,
-			   enabled,
-			   priority,
-			   tags,
-			   model_rewrite_enabled,
-			   target_model,
-			   model_rewrite_rules
-		FROM endpoints
-		WHERE enabled = 1
-		ORDER BY priority DESC, created_at ASC
-	`
+// preferTaggedEndpoints 将标签与请求标签有交集的端点稳定地排到前面，保持各自原有顺序
+func preferTaggedEndpoints(endpoints []config.EndpointConfig, requestTags []string) []config.EndpointConfig {
+	matched := make([]config.EndpointConfig, 0, len(endpoints))
+	var others []config.EndpointConfig
+	for _, ep := range endpoints {
+		if utils.HasCommonTag(ep.Tags, requestTags) {
+			matched = append(matched, ep)
+		} else {
+			others = append(others, ep)
+		}
+	}
+	return append(matched, others...)
+}
 
-	rows, err := a.db.Query(query)
+// PreviewRoutingOrder 预览给定客户端类型、模型与标签的请求将依次尝试的端点（不发送任何请求），并列出被跳过的端点及原因
+func (a *App) PreviewRoutingOrder(clientType string, model string, tags []string) map[string]interface{} {
+	body, _ := json.Marshal(map[string]interface{}{"model": strings.TrimSpace(model)})
+	return a.previewRoutingOrder(clientType, "", body, tags)
+}
+
+// PreviewRoutingOrderForRequest 按完整的示例请求（路径与请求体，如从请求日志复制）预览端点尝试顺序，
+// 额外应用 server.routing_rules 的工具数/消息数/token 条件以及批处理、旧版补全、音频请求的端点过滤
+func (a *App) PreviewRoutingOrderForRequest(clientType string, path string, body string, tags []string) map[string]interface{} {
+	return a.previewRoutingOrder(clientType, strings.TrimSpace(path), []byte(body), tags)
+}
+
+// previewRoutingOrder 按 handleProxyRequest 的端点选择步骤计算尝试顺序，不发送请求也不记录路由日志
+func (a *App) previewRoutingOrder(clientType string, path string, body []byte, tags []string) map[string]interface{} {
+	a.mutex.RLock()
+	db := a.db
+	a.mutex.RUnlock()
+	if db == nil {
+		return map[string]interface{}{
+			"success": false,
+			"message": "数据库不可用",
+		}
+	}
+
+	endpoints, err := a.queryEndpointConfigs("", false)
 	if err != nil {
-		return nil, err
+		return map[string]interface{}{
+			"success": false,
+			"message": fmt.Sprintf("读取端点失败: %v", err),
+		}
 	}
-	defer rows.Close()
 
-	var endpoints []config.EndpointConfig
-	for rows.Next() {
-		var (
-			name, urlAnthropic, urlOpenai, endpointType, authType, authValue sql.NullString
-			enabled                                                          sql.NullBool
-			priority                                                         sql.NullInt64
-			tagsJSON                                                         sql.NullString
-			modelRewriteEnabled                                              sql.NullBool
-			targetModel                                                      sql.NullString
-			modelRewriteRules                                                sql.NullString
-		)
+	clientType = strings.ToLower(strings.TrimSpace(clientType))
+	now := time.Now()
+	skipped := []map[string]interface{}{}
+	enabled := make([]config.EndpointConfig, 0, len(endpoints))
+	for _, ep := range endpoints {
+		if !ep.Enabled {
+			skipped = append(skipped, map[string]interface{}{"name": ep.Name, "reason": "端点未启用"})
+			continue
+		}
+		enabled = append(enabled, ep)
+	}
+
+	// 与 handleProxyRequest 相同的排序步骤；同优先级的加权随机改为按权重降序展示期望顺序
+	ordered := a.demoteBusinessErrorEndpoints(enabled)
+	weighted := a.getSuccessRateWindow() > 0
+	if weighted {
+		ordered = a.orderEndpointsByExpectedWeight(ordered)
+	}
+	requestTags := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			requestTags = append(requestTags, tag)
+		}
+	}
+	routingRule, routingRuleMatched := utils.MatchRoutingRule(a.getRoutingRules(), utils.MeasureRequest(body))
+	if routingRuleMatched && strings.TrimSpace(routingRule.Tag) != "" {
+		requestTags = utils.MergeTags(requestTags, []string{routingRule.Tag})
+	}
+	if len(requestTags) > 0 {
+		ordered = preferTaggedEndpoints(ordered, requestTags)
+	}
+
+	// 与代理相同的端点过滤：批处理与流式旧版补全只能走 Anthropic URL，音频请求只能走开启 supports_audio 的 OpenAI 端点
+	if utils.IsAnthropicBatchPath(path) {
+		filtered := filterAnthropicEndpoints(ordered)
+		skipped = appendFilteredEndpoints(skipped, ordered, filtered, "批处理请求需要 Anthropic URL")
+		ordered = filtered
+	}
+	if utils.IsAnthropicCompletePath(path) {
+		filtered := filterLegacyCompleteEndpoints(ordered, streamRequested(body))
+		skipped = appendFilteredEndpoints(skipped, ordered, filtered, "流式旧版补全请求需要 Anthropic URL")
+		ordered = filtered
+	}
+	if utils.RequestNeedsAudio(body) {
+		filtered, _ := filterAudioEndpoints(ordered)
+		skipped = appendFilteredEndpoints(skipped, ordered, filtered, "不支持音频输入（supports_audio 未开启或无 OpenAI URL）")
+		ordered = filtered
+	}
+
+	// 路由规则的模型改写先于别名归一化，之后按端点计算模型重写结果
+	if routingRuleMatched && strings.TrimSpace(routingRule.Model) != "" {
+		if overridden, _, ok := modelrewrite.OverrideRequestModel(body, routingRule.Model); ok {
+			body = overridden
+		}
+	}
+	var payload struct {
+		Model string `json:"model"`
+	}
+	json.Unmarshal(body, &payload)
+	model := strings.TrimSpace(payload.Model)
+	body, _, canonicalModel, aliasApplied := modelrewrite.CanonicalizeRequestModel(body, a.getModelAliases())
+	if !aliasApplied {
+		canonicalModel = model
+	}
+
+	attempts := []map[string]interface{}{}
+	for _, ep := range ordered {
+		upstreamURL := previewUpstreamURL(ep, clientType)
+		if upstreamURL == "" {
+			skipped = append(skipped, map[string]interface{}{"name": ep.Name, "reason": "未配置该客户端类型可用的上游 URL"})
+			continue
+		}
+		if ep.MaxRequestsPerMinute > 0 {
+			if remaining, resetIn := a.requestBudgetStatus(ep.Name, ep.MaxRequestsPerMinute, now); remaining == 0 {
+				skipped = append(skipped, map[string]interface{}{
+					"name":   ep.Name,
+					"reason": fmt.Sprintf("已达到每分钟 %d 次请求上限，%s 后恢复", ep.MaxRequestsPerMinute, resetIn.Round(time.Second)),
+				})
+				continue
+			}
+		}
+
+		notes := []string{}
+		if a.isEndpointDemoted(ep.Name, now) {
+			notes = append(notes, "业务错误率过高，已临时降级")
+		}
+		if len(requestTags) > 0 && utils.HasCommonTag(ep.Tags, requestTags) {
+			notes = append(notes, "匹配请求标签，优先尝试")
+		}
+		targetModel := canonicalModel
+		if model != "" {
+			epCopy := ep
+			if _, _, rewritten, applied, err := a.applyModelRewrite(body, &epCopy, clientType, nil); err == nil && applied {
+				targetModel = rewritten
+				notes = append(notes, "模型重写: "+canonicalModel+" -> "+rewritten)
+			}
+		}
+		if !endpointAllowsModel(ep.AllowedModels, targetModel) {
+			skipped = append(skipped, map[string]interface{}{
+				"name":   ep.Name,
+				"reason": fmt.Sprintf("allowed_models 不包含模型 %s", targetModel),
+			})
+			continue
+		}
+
+		entry := map[string]interface{}{
+			"position":     len(attempts) + 1,
+			"name":         ep.Name,
+			"priority":     ep.Priority,
+			"upstream_url": upstreamURL,
+			"model":        targetModel,
+			"notes":        notes,
+		}
+		if weighted {
+			entry["weight"] = a.endpointSelectionWeight(ep.Name)
+		}
+		attempts = append(attempts, entry)
+	}
+
+	message := fmt.Sprintf("将依次尝试 %d 个端点，跳过 %d 个", len(attempts), len(skipped))
+	if weighted {
+		message += "（同优先级端点按成功率加权随机，实际顺序可能不同）"
+	}
+	result := map[string]interface{}{
+		"success":         true,
+		"data":            attempts,
+		"skipped":         skipped,
+		"canonical_model": canonicalModel,
+		"message":         message,
+	}
+	if routingRuleMatched {
+		result["routing_rule"] = routingRule.Name
+	}
+	return result
+}
+
+// appendFilteredEndpoints 将被过滤掉的端点按原顺序记入跳过列表
+func appendFilteredEndpoints(skipped []map[string]interface{}, before, after []config.EndpointConfig, reason string) []map[string]interface{} {
+	kept := make(map[string]bool, len(after))
+	for _, ep := range after {
+		kept[ep.Name] = true
+	}
+	for _, ep := range before {
+		if !kept[ep.Name] {
+			skipped = append(skipped, map[string]interface{}{"name": ep.Name, "reason": reason})
+		}
+	}
+	return skipped
+}
+
+// orderEndpointsByExpectedWeight 同优先级端点按成功率权重降序排列（orderEndpointsBySuccessRate 的确定性版本，用于预览）
+func (a *App) orderEndpointsByExpectedWeight(endpoints []config.EndpointConfig) []config.EndpointConfig {
+	ordered := append([]config.EndpointConfig(nil), endpoints...)
+	weights := make(map[string]float64, len(ordered))
+	for _, ep := range ordered {
+		weights[ep.Name] = a.endpointSelectionWeight(ep.Name)
+	}
+	sort.SliceStable(ordered, func(i, j int) bool {
+		if ordered[i].Priority != ordered[j].Priority {
+			return false
+		}
+		return weights[ordered[i].Name] > weights[ordered[j].Name]
+	})
+	return ordered
+}
+
+// previewUpstreamURL 按客户端类型返回端点将使用的上游基础 URL：Claude Code 优先 Anthropic URL，其余优先 OpenAI URL
+func previewUpstreamURL(ep config.EndpointConfig, clientType string) string {
+	anthropicURL := strings.TrimSpace(ep.URLAnthropic)
+	openaiURL := strings.TrimSpace(ep.URLOpenAI)
+	if clientType == "claude_code" || clientType == "anthropic" {
+		if anthropicURL != "" {
+			return anthropicURL
+		}
+		return openaiURL
+	}
+	if openaiURL != "" {
+		return openaiURL
+	}
+	return anthropicURL
+}
+
+// filterAnthropicEndpoints 只保留配置了 Anthropic URL 的端点（保持原有顺序）
+func filterAnthropicEndpoints(endpoints []config.EndpointConfig) []config.EndpointConfig {
+	filtered := make([]config.EndpointConfig, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		if strings.TrimSpace(endpoint.URLAnthropic) != "" {
+			filtered = append(filtered, endpoint)
+		}
+	}
+	return filtered
+}
+
+// filterAudioEndpoints 只保留开启 supports_audio 且配置了 OpenAI URL 的端点（保持原有顺序），同时返回被跳过的端点名
+func filterAudioEndpoints(endpoints []config.EndpointConfig) ([]config.EndpointConfig, []string) {
+	capable := make([]config.EndpointConfig, 0, len(endpoints))
+	var skipped []string
+	for _, endpoint := range endpoints {
+		if endpoint.SupportsAudio && strings.TrimSpace(endpoint.URLOpenAI) != "" {
+			capable = append(capable, endpoint)
+			continue
+		}
+		skipped = append(skipped, endpoint.Name)
+	}
+	return capable, skipped
+}
+
+// conversionStageSeparator 与 internal/proxy 的 conversion_path 分隔符保持一致
+const conversionStageSeparator = "|"
+
+// requestConversionStages 返回请求发往端点前实际应用的转换阶段（路径改写、请求体转换、模型重写）
+func requestConversionStages(requestPath, targetURL string, legacyCompleteConverted bool, originalModel, rewrittenModel string, rewriteApplied bool) []string {
+	stages := []string{}
+	if parsed, err := url.Parse(targetURL); err == nil && !strings.HasSuffix(parsed.Path, requestPath) {
+		stages = append(stages, fmt.Sprintf("path:%s->%s", requestPath, parsed.Path))
+	}
+	if legacyCompleteConverted {
+		stages = append(stages, "request:anthropic_complete->openai")
+	}
+	if rewriteApplied && originalModel != "" && rewrittenModel != "" && originalModel != rewrittenModel {
+		stages = append(stages, fmt.Sprintf("model:%s->%s", originalModel, rewrittenModel))
+	}
+	return stages
+}
+
+// normalizePreviewFormat 归一化转换预览的格式名，与端点 target_format 的取值保持一致
+func normalizePreviewFormat(format string) string {
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "anthropic", "claude":
+		return "anthropic"
+	case "anthropic_complete", "complete", "legacy_complete":
+		return "anthropic_complete"
+	case "openai_chat", "openai", "chat", "chat_completions":
+		return "openai_chat"
+	case "openai_responses", "responses", "codex":
+		return "openai_responses"
+	case "gemini":
+		return "gemini"
+	}
+	return ""
+}
+
+// previewConsumedFields 各源格式中会被转换器改写为其它字段（而非丢弃）的顶层字段
+var previewConsumedFields = map[string][]string{
+	"anthropic":          {"system", "messages", "max_tokens", "stop_sequences", "thinking", "metadata", "tools", "tool_choice", "service_tier"},
+	"anthropic_complete": {"prompt", "max_tokens_to_sample", "stop_sequences", "metadata"},
+	"openai_chat":        {"messages", "max_tokens", "max_completion_tokens", "reasoning_effort", "response_format", "tools", "tool_choice", "stop"},
+	"openai_responses":   {"input", "instructions", "max_output_tokens", "reasoning", "text", "tools", "tool_choice"},
+}
+
+// ConvertRequestPreview 对粘贴的请求体执行一次请求转换（不发送任何请求），返回转换结果与警告，
+// 用于交互式核对转换是否保真；endpointType 影响 Anthropic -> OpenAI 时的 max_tokens 字段选择，
+// 传入端点名称时使用该端点的类型、max_tokens_field_name 与 user_field_mode
+func (a *App) ConvertRequestPreview(body string, fromFormat string, toFormat string, endpointType string) map[string]interface{} {
+	from, to := normalizePreviewFormat(fromFormat), normalizePreviewFormat(toFormat)
+	if from == "" || to == "" {
+		return map[string]interface{}{
+			"success": false,
+			"message": fmt.Sprintf("不支持的格式: %s -> %s (支持: anthropic, anthropic_complete, openai_chat, openai_responses)", fromFormat, toFormat),
+		}
+	}
+
+	raw := []byte(strings.TrimSpace(body))
+	var source map[string]interface{}
+	if err := json.Unmarshal(raw, &source); err != nil {
+		return map[string]interface{}{
+			"success": false,
+			"message": "请求体不是有效的JSON对象: " + err.Error(),
+		}
+	}
+
+	warnings := []string{}
+	var converted []byte
+	var err error
+	switch {
+	case from == to:
+		converted = raw
+		warnings = append(warnings, "source and target formats are identical; the body is forwarded unchanged")
+	case from == "anthropic" && to == "openai_chat":
+		converted, _, err = conversion.NewRequestConverter(nil).Convert(raw, a.previewEndpointInfo(endpointType))
+	case from == "anthropic_complete" && to == "openai_chat":
+		converted, err = conversion.ConvertLegacyCompleteRequestToChat(raw)
+	case from == "openai_responses" && to == "openai_chat":
+		converted, err = conversion.ConvertResponsesRequestJSONToChat(raw)
+	case from == "openai_chat" && to == "openai_responses":
+		converted, err = conversion.ConvertChatRequestJSONToResponses(raw)
+	default:
+		return map[string]interface{}{
+			"success": false,
+			"message": fmt.Sprintf("没有 %s -> %s 的请求转换器", from, to),
+		}
+	}
+	if err != nil {
+		return map[string]interface{}{
+			"success":         false,
+			"message":         "转换失败: " + err.Error(),
+			"conversion_path": from + "->" + to,
+		}
+	}
+
+	var target map[string]interface{}
+	if err := json.Unmarshal(converted, &target); err == nil && from != to {
+		consumed := map[string]bool{}
+		for _, field := range previewConsumedFields[from] {
+			consumed[field] = true
+		}
+		dropped := []string{}
+		for field := range source {
+			if _, kept := target[field]; !kept && !consumed[field] {
+				dropped = append(dropped, field)
+			}
+		}
+		sort.Strings(dropped)
+		for _, field := range dropped {
+			warnings = append(warnings, fmt.Sprintf("field %q was dropped by the conversion", field))
+		}
+	}
+
+	// 预览面向人阅读，格式化输出
+	pretty := string(converted)
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, converted, "", "  "); err == nil {
+		pretty = indented.String()
+	}
+
+	return map[string]interface{}{
+		"success":         true,
+		"conversion_path": from + "->" + to,
+		"converted_body":  pretty,
+		"warnings":        warnings,
+	}
+}
+
+// previewEndpointInfo 将 ConvertRequestPreview 的 endpointType 解析为 Anthropic -> OpenAI 转换参数：
+// 与端点名称完全一致时使用该端点配置，否则视为端点类型
+func (a *App) previewEndpointInfo(endpointType string) *conversion.EndpointInfo {
+	endpointType = strings.TrimSpace(endpointType)
+	info := &conversion.EndpointInfo{Type: endpointType}
+	if endpointType == "" || a.db == nil {
+		return info
+	}
+	configs, err := a.queryEndpointConfigs("WHERE name = ?", false, endpointType)
+	if err != nil || len(configs) == 0 {
+		return info
+	}
+	endpoint := configs[0]
+	info.Type = "openai"
+	info.MaxTokensFieldName = endpoint.MaxTokensFieldName
+	info.UserFieldMode = endpoint.UserFieldMode
+	return info
+}
+
+// filterLegacyCompleteEndpoints 保留能处理旧版 /v1/complete 的端点：流式请求只能透传到 Anthropic URL
+func filterLegacyCompleteEndpoints(endpoints []config.EndpointConfig, stream bool) []config.EndpointConfig {
+	if stream {
+		return filterAnthropicEndpoints(endpoints)
+	}
+	filtered := make([]config.EndpointConfig, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		if strings.TrimSpace(endpoint.URLAnthropic) != "" || strings.TrimSpace(endpoint.URLOpenAI) != "" {
+			filtered = append(filtered, endpoint)
+		}
+	}
+	return filtered
+}
+
+// streamRequested 读取请求体中的 stream 标记
+func streamRequested(body []byte) bool {
+	var payload struct {
+		Stream bool `json:"stream"`
+	}
+	return json.Unmarshal(body, &payload) == nil && payload.Stream
+}
+
+// getAvailableEndpoints 获取可用的端点
+func (a *App) getAvailableEndpoints() ([]config.EndpointConfig, error) {
+	return a.queryEndpointConfigs("WHERE enabled = 1", true)
+}
+
+// queryEndpointConfigs 按过滤条件读取端点的路由配置，enabledOnly 为 true 时跳过未启用的端点
+func (a *App) queryEndpointConfigs(filter string, enabledOnly bool, args ...interface{}) ([]config.EndpointConfig, error) {
+	query := `
+		SELECT name,
+		       url_anthropic,
+			   url_openai,
+			   endpoint_type,
+			   auth_type,
+			   auth_value,
+			   enabled,
+			   priority,
+			   tags,
+			   model_rewrite_enabled,
+			   target_model,
+			   model_rewrite_rules,
+			   parameter_overrides,
+			   extra_system_prompt,
+			   force_thinking,
+			   disable_thinking,
+			   user_field_mode,
+			   anthropic_version,
+			   user_agent,
+			   strip_reasoning_in_response,
+			   insecure_skip_verify,
+			   default_max_tokens,
+			   max_tokens_ceiling,
+			   max_tokens_field_name,
+			   response_header_overrides,
+			   sse_event_filter,
+			   max_requests_per_minute,
+			   hmac_header,
+			   hmac_secret,
+			   hmac_algo,
+			   stream_include_usage,
+			   fallback_on_4xx,
+			   oauth_config,
+			   body_transforms,
+			   openai_organization,
+			   openai_project,
+			   supports_audio,
+			   thinking_stream_mode,
+			   allowed_models
+		FROM endpoints
+		` + filter + `
+		ORDER BY priority DESC, created_at ASC
+	`
+
+	rows, err := a.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var endpoints []config.EndpointConfig
+	for rows.Next() {
+		var (
+			name, urlAnthropic, urlOpenai, endpointType, authType, authValue sql.NullString
+			enabled                                                          sql.NullBool
+			priority                                                         sql.NullInt64
+			tagsJSON                                                         sql.NullString
+			modelRewriteEnabled                                              sql.NullBool
+			targetModel                                                      sql.NullString
+			modelRewriteRules                                                sql.NullString
+			parameterOverrides                                               sql.NullString
+			extraSystemPrompt                                                sql.NullString
+			forceThinking, disableThinking, stripReasoning, insecureSkip     sql.NullBool
+			userFieldMode, anthropicVersion, userAgent                       sql.NullString
+			defaultMaxTokens, maxTokensCeiling                               sql.NullInt64
+			maxTokensFieldName, responseHeaderOverrides                      sql.NullString
+			sseEventFilter                                                   sql.NullString
+			maxRequestsPerMinute                                             sql.NullInt64
+			hmacHeader, hmacSecret, hmacAlgo                                 sql.NullString
+			streamIncludeUsage, fallbackOn4xx                                sql.NullBool
+			oauthConfigJSON, bodyTransformsJSON                              sql.NullString
+			openAIOrganization, openAIProject                                sql.NullString
+			supportsAudio                                                    sql.NullBool
+			thinkingStreamMode, allowedModels                                sql.NullString
+		)
 
 		if err := rows.Scan(
 			&name,
@@ -1441,11 +2619,38 @@ func (a *App) getAvailableEndpoints() ([]config.EndpointConfig, error) {
 			&modelRewriteEnabled,
 			&targetModel,
 			&modelRewriteRules,
+			&parameterOverrides,
+			&extraSystemPrompt,
+			&forceThinking,
+			&disableThinking,
+			&userFieldMode,
+			&anthropicVersion,
+			&userAgent,
+			&stripReasoning,
+			&insecureSkip,
+			&defaultMaxTokens,
+			&maxTokensCeiling,
+			&maxTokensFieldName,
+			&responseHeaderOverrides,
+			&sseEventFilter,
+			&maxRequestsPerMinute,
+			&hmacHeader,
+			&hmacSecret,
+			&hmacAlgo,
+			&streamIncludeUsage,
+			&fallbackOn4xx,
+			&oauthConfigJSON,
+			&bodyTransformsJSON,
+			&openAIOrganization,
+			&openAIProject,
+			&supportsAudio,
+			&thinkingStreamMode,
+			&allowedModels,
 		); err != nil {
 			continue
 		}
 
-		if !enabled.Valid || !enabled.Bool {
+		if enabledOnly && (!enabled.Valid || !enabled.Bool) {
 			continue
 		}
 
@@ -1457,6 +2662,41 @@ func (a *App) getAvailableEndpoints() ([]config.EndpointConfig, error) {
 			AuthValue:    authValue.String,
 			Enabled:      enabled.Bool,
 			Priority:     int(priority.Int64),
+
+			ExtraSystemPrompt:  extraSystemPrompt.String,
+			ParameterOverrides: encodeParameterOverrides(decodeParameterOverrides(parameterOverrides)),
+			ForceThinking:      forceThinking.Valid && forceThinking.Bool,
+			DisableThinking:    disableThinking.Valid && disableThinking.Bool,
+			UserFieldMode:      utils.NormalizeUserFieldMode(userFieldMode.String),
+			AnthropicVersion:   strings.TrimSpace(anthropicVersion.String),
+			UserAgent:          strings.TrimSpace(userAgent.String),
+			StripReasoning:     stripReasoning.Valid && stripReasoning.Bool,
+			InsecureSkipVerify: insecureSkip.Valid && insecureSkip.Bool,
+			DefaultMaxTokens:   int(defaultMaxTokens.Int64),
+			MaxTokensCeiling:   int(maxTokensCeiling.Int64),
+			MaxTokensFieldName: strings.TrimSpace(maxTokensFieldName.String),
+
+			ResponseHeaderOverrides: decodeHeaderOverrides(responseHeaderOverrides),
+			SSEEventFilter:          decodeStringSlice(sseEventFilter),
+			MaxRequestsPerMinute:    int(maxRequestsPerMinute.Int64),
+			HMACHeader:              strings.TrimSpace(hmacHeader.String),
+			HMACSecret:              strings.TrimSpace(hmacSecret.String),
+			HMACAlgo:                strings.TrimSpace(hmacAlgo.String),
+			OAuthConfig:             decodeOAuthConfig(oauthConfigJSON),
+			BodyTransforms:          decodeBodyTransforms(bodyTransformsJSON),
+			OpenAIOrganization:      strings.TrimSpace(openAIOrganization.String),
+			OpenAIProject:           strings.TrimSpace(openAIProject.String),
+			SupportsAudio:           supportsAudio.Valid && supportsAudio.Bool,
+			ThinkingStreamMode:      conversion.NormalizeThinkingStreamMode(thinkingStreamMode.String),
+			AllowedModels:           decodeStringSlice(allowedModels),
+		}
+		if streamIncludeUsage.Valid {
+			include := streamIncludeUsage.Bool
+			endpoint.StreamIncludeUsage = &include
+		}
+		if fallbackOn4xx.Valid {
+			fallback := fallbackOn4xx.Bool
+			endpoint.FallbackOn4xx = &fallback
 		}
 
 		if tagsJSON.Valid && strings.TrimSpace(tagsJSON.String) != "" {
@@ -1625,6 +2865,100 @@ func (a *App) isArbitraryTokenModeEnabled() bool {
 	return os.Getenv("ARBITRARY_TOKEN_MODE") == "true"
 }
 
+// getRetryStatusPolicy 读取 retry.retriable_statuses / retry.terminal_statuses；
+// 未配置或配置无效时返回空策略（所有 4xx/5xx 都切换端点重试，与原有行为一致）
+func (a *App) getRetryStatusPolicy() config.RetryConfig {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	if a.config == nil {
+		return config.RetryConfig{}
+	}
+	retry, ok := a.config["retry"].(map[string]interface{})
+	if !ok {
+		return config.RetryConfig{}
+	}
+
+	policy := config.RetryConfig{
+		RetriableStatuses: parseStatusCodeList(retry["retriable_statuses"]),
+		TerminalStatuses:  parseStatusCodeList(retry["terminal_statuses"]),
+	}
+	if err := config.ValidateRetryStatuses(policy.RetriableStatuses, policy.TerminalStatuses); err != nil {
+		return config.RetryConfig{}
+	}
+	return policy
+}
+
+// parseStatusCodeList 解析配置中的状态码列表，兼容 JSON 数字与字符串形式
+func parseStatusCodeList(raw interface{}) []int {
+	items, ok := raw.([]interface{})
+	if !ok {
+		if codes, ok := raw.([]int); ok {
+			return codes
+		}
+		return nil
+	}
+
+	codes := make([]int, 0, len(items))
+	for _, item := range items {
+		switch v := item.(type) {
+		case float64:
+			codes = append(codes, int(v))
+		case int:
+			codes = append(codes, v)
+		case string:
+			if parsed, err := strconv.Atoi(strings.TrimSpace(v)); err == nil {
+				codes = append(codes, parsed)
+			}
+		}
+	}
+	return codes
+}
+
+// writeTerminalUpstreamError 将终止状态码的上游响应原样返回给客户端
+func (a *App) writeTerminalUpstreamError(w http.ResponseWriter, resp *http.Response, body []byte, endpointName string) {
+	runtime.LogWarning(a.ctx, fmt.Sprintf("端点 %s 返回终止状态码 %d，不再尝试其他端点", endpointName, resp.StatusCode))
+	if decoded, err := decodeGzipBody(body, 0); err == nil {
+		body = decoded
+	}
+	if contentType := resp.Header.Get("Content-Type"); contentType != "" {
+		w.Header().Set("Content-Type", contentType)
+	}
+	w.WriteHeader(resp.StatusCode)
+	w.Write(body)
+}
+
+// getDefaultUserAgent 读取全局默认 User-Agent（server.user_agent），未配置或格式无效时返回空字符串
+func (a *App) getDefaultUserAgent() string {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	if a.config != nil {
+		if server, ok := a.config["server"].(map[string]interface{}); ok {
+			if userAgent, ok := server["user_agent"].(string); ok && config.ValidateUserAgent(userAgent) == nil {
+				return userAgent
+			}
+		}
+	}
+	return ""
+}
+
+// isPreserveOriginalModelEnabled 检查模型重写后是否强制将响应 model 设回客户端原始模型名
+// （server.preserve_original_model，默认开启；调试时可关闭以查看上游真实返回）
+func (a *App) isPreserveOriginalModelEnabled() bool {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	if a.config != nil {
+		if server, ok := a.config["server"].(map[string]interface{}); ok {
+			if enabled, ok := server["preserve_original_model"].(bool); ok {
+				return enabled
+			}
+		}
+	}
+	return true
+}
+
 // setClaudeCodeAuthToken 设置Claude Code认证token
 func (a *App) setClaudeCodeAuthToken(token string) error {
 	a.mutex.Lock()
@@ -1695,1154 +3029,6665 @@ func (a *App) applyModelRewrite(body []byte, endpoint *config.EndpointConfig, cl
 	return rewrittenBody, originalModel, rewrittenModel, true, nil
 }
 
-// chooseLoggedModel 返回应记录的模型名称
-func chooseLoggedModel(originalModel, rewrittenModel string) string {
-	if strings.TrimSpace(rewrittenModel) != "" {
-		return rewrittenModel
-	}
-	return originalModel
+// systemPromptTokenPattern 匹配 {{name}} 形式的模板变量
+var systemPromptTokenPattern = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_]+)\s*\}\}`)
+
+// resolveSystemPromptTemplate 替换提示词中的模板变量，未知变量保持原样并返回其名称
+func resolveSystemPromptTemplate(prompt string, vars map[string]string) (string, []string) {
+	var unknown []string
+	resolved := systemPromptTokenPattern.ReplaceAllStringFunc(prompt, func(token string) string {
+		name := systemPromptTokenPattern.FindStringSubmatch(token)[1]
+		if value, ok := vars[name]; ok {
+			return value
+		}
+		unknown = append(unknown, name)
+		return token
+	})
+	return resolved, unknown
 }
 
-// logProxyRequest 统一写入请求日志
-func (a *App) logProxyRequest(entry *logger.RequestLog) {
-	if entry == nil {
-		return
+// applyExtraSystemPrompt 将端点配置的额外系统提示词注入请求体
+// Anthropic /messages 写入 system 字段，OpenAI /chat/completions 插入首条 system 消息，/responses 写入 instructions
+func (a *App) applyExtraSystemPrompt(body []byte, endpoint *config.EndpointConfig, path, clientType string) []byte {
+	if endpoint == nil || strings.TrimSpace(endpoint.ExtraSystemPrompt) == "" {
+		return body
 	}
 
-	if a.requestLogger == nil {
-		if err := a.initRequestLogger(); err != nil {
-			runtime.LogError(a.ctx, fmt.Sprintf("无法初始化请求日志记录器: %v", err))
-			return
+	var payload map[string]interface{}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return body
+	}
+
+	model, _ := payload["model"].(string)
+	prompt, unknown := resolveSystemPromptTemplate(endpoint.ExtraSystemPrompt, map[string]string{
+		"date":        time.Now().Format("2006-01-02"),
+		"client_type": clientType,
+		"model":       model,
+	})
+	for _, name := range unknown {
+		if _, logged := a.unknownPromptTokens.LoadOrStore(endpoint.Name+"|"+name, true); !logged {
+			runtime.LogWarning(a.ctx, fmt.Sprintf("Unknown template variable {{%s}} in extra_system_prompt of endpoint %s, left as-is", name, endpoint.Name))
+			a.addLog("warn", fmt.Sprintf("端点 %s 的额外系统提示词包含未知模板变量 {{%s}}，已保留原文", endpoint.Name, name))
 		}
 	}
 
-	if a.requestLogger != nil {
-		a.requestLogger.LogRequest(entry)
+	switch {
+	case strings.Contains(path, "/responses"):
+		if existing, ok := payload["instructions"].(string); ok && strings.TrimSpace(existing) != "" {
+			payload["instructions"] = prompt + "\n\n" + existing
+		} else {
+			payload["instructions"] = prompt
+		}
+	case strings.Contains(path, "/chat/completions"):
+		messages, _ := payload["messages"].([]interface{})
+		systemMessage := map[string]interface{}{"role": "system", "content": prompt}
+		payload["messages"] = append([]interface{}{systemMessage}, messages...)
+	case strings.Contains(path, "/messages"):
+		switch existing := payload["system"].(type) {
+		case string:
+			if strings.TrimSpace(existing) != "" {
+				payload["system"] = prompt + "\n\n" + existing
+			} else {
+				payload["system"] = prompt
+			}
+		case []interface{}:
+			block := map[string]interface{}{"type": "text", "text": prompt}
+			payload["system"] = append([]interface{}{block}, existing...)
+		default:
+			payload["system"] = prompt
+		}
+	default:
+		return body
 	}
-}
 
-// headersToMap 将HTTP头转换为map
-func headersToMap(h http.Header, maskSensitive bool) map[string]string {
-	if len(h) == 0 {
-		return map[string]string{}
+	updated, err := json.Marshal(payload)
+	if err != nil {
+		return body
 	}
+	return updated
+}
 
-	result := make(map[string]string, len(h))
-	for key, values := range h {
-		joined := strings.Join(values, ",")
-		if maskSensitive {
-			joined = maskHeaderValue(key, joined)
-		}
-		result[key] = joined
+const streamTruncatedMessage = "Upstream stream ended unexpectedly before completion"
+
+// sseTerminalSpec 返回 SSE 流格式对应的终止条件，以及流中断时补发给客户端的错误/终止事件；未知格式返回 ok=false
+// Anthropic: event: error；OpenAI Chat: error + [DONE]；OpenAI Responses: response.failed
+func sseTerminalSpec(requestFormat, path string) (utils.SSETerminalSpec, string, bool) {
+	var terminal utils.SSETerminalSpec
+	var synthetic string
+
+	switch {
+	case requestFormat == "anthropic":
+		terminal.EventTypes = map[string]bool{"message_stop": true, "error": true}
+		payload, _ := json.Marshal(map[string]interface{}{
+			"type": "error",
+			"error": map[string]interface{}{
+				"type":    "api_error",
+				"message": streamTruncatedMessage,
+			},
+		})
+		synthetic = "event: error\ndata: " + string(payload) + "\n\n"
+	case requestFormat == "openai" && strings.Contains(path, "/responses"):
+		terminal.EventTypes = map[string]bool{"response.completed": true, "response.failed": true, "response.incomplete": true, "error": true}
+		payload, _ := json.Marshal(map[string]interface{}{
+			"type": "response.failed",
+			"response": map[string]interface{}{
+				"status": "failed",
+				"error": map[string]interface{}{
+					"code":    "stream_truncated",
+					"message": streamTruncatedMessage,
+				},
+			},
+		})
+		synthetic = "event: response.failed\ndata: " + string(payload) + "\n\n"
+	case requestFormat == "openai":
+		// 部分上游在给出 finish_reason 后不发送 [DONE]，同样视为正常结束
+		terminal.Done = true
+		terminal.FinishReason = true
+		payload, _ := json.Marshal(map[string]interface{}{
+			"error": map[string]interface{}{
+				"type":    "stream_truncated",
+				"message": streamTruncatedMessage,
+			},
+		})
+		synthetic = "data: " + string(payload) + "\n\ndata: [DONE]\n\n"
+	default:
+		return terminal, "", false
 	}
-	return result
+	return terminal, synthetic, true
 }
 
-// buildFinalRequestHeaders 构建发往上游的请求头（敏感字段已脱敏）
-func buildFinalRequestHeaders(original http.Header, endpoint *config.EndpointConfig, mappedToken string) map[string]string {
-	headers := headersToMap(original, true)
+// sseStreamTerminated 检查上游原始 SSE 流是否正常结束；未知格式无法判断，视为已结束
+func sseStreamTerminated(body []byte, format, path string) bool {
+	terminal, _, ok := sseTerminalSpec(format, path)
+	return !ok || utils.SSEStreamTerminated(body, terminal)
+}
 
-	// 移除原有认证信息
-	for key := range headers {
-		lower := strings.ToLower(key)
-		if lower == "authorization" || lower == "x-api-key" {
-			delete(headers, key)
-		}
+// ensureSSETerminalEvent 检查SSE流是否包含客户端格式对应的终止事件，缺失时补发错误/终止事件
+func ensureSSETerminalEvent(body []byte, requestFormat, path string) ([]byte, bool) {
+	if sseStreamTerminated(body, requestFormat, path) {
+		return body, false
 	}
+	return appendSSETerminalError(body, requestFormat, path, false), true
+}
 
-	token := strings.TrimSpace(mappedToken)
-	if token == "" && endpoint != nil {
-		token = strings.TrimSpace(endpoint.AuthValue)
+// appendSSETerminalError 在流末尾补发客户端格式对应的错误终止事件。
+// dropConverted 为 true 时先移除格式转换补齐的 message_delta/message_stop，避免客户端把截断的流当作正常结束
+func appendSSETerminalError(body []byte, requestFormat, path string, dropConverted bool) []byte {
+	_, synthetic, ok := sseTerminalSpec(requestFormat, path)
+	if !ok {
+		return body
 	}
 
-	if endpoint != nil {
-		switch strings.ToLower(strings.TrimSpace(endpoint.AuthType)) {
-		case "api_key":
-			if token != "" {
-				headers["X-API-Key"] = maskToken(token)
-			}
-		case "auth_token", "auto":
-			if token != "" {
-				headers["Authorization"] = maskHeaderValue("Authorization", "Bearer "+token)
-			}
+	completed := append([]byte(nil), body...)
+	if dropConverted && requestFormat == "anthropic" {
+		completed = trimTrailingSSEEvents(completed, map[string]bool{"message_delta": true, "message_stop": true})
+	}
+	if len(completed) > 0 && !bytes.HasSuffix(completed, []byte("\n\n")) {
+		if bytes.HasSuffix(completed, []byte("\n")) {
+			completed = append(completed, '\n')
+		} else {
+			completed = append(completed, '\n', '\n')
 		}
 	}
+	return append(completed, synthetic...)
+}
 
-	return headers
+// trimTrailingSSEEvents 从流末尾移除事件名属于 names 的连续事件
+func trimTrailingSSEEvents(body []byte, names map[string]bool) []byte {
+	for {
+		trimmed := bytes.TrimRight(body, "\n")
+		start := bytes.LastIndex(trimmed, []byte("\n\n"))
+		name := ""
+		for _, line := range strings.Split(string(trimmed[start+1:]), "\n") {
+			if value, ok := strings.CutPrefix(strings.TrimSpace(line), "event:"); ok {
+				name = strings.TrimSpace(value)
+				break
+			}
+		}
+		if len(trimmed) == 0 || !names[name] {
+			return body
+		}
+		if start < 0 {
+			return body[:0]
+		}
+		body = trimmed[:start+2]
+	}
 }
 
-// maskHeaderValue 对敏感头部进行脱敏
-func maskHeaderValue(key, value string) string {
-	switch strings.ToLower(key) {
-	case "authorization":
-		parts := strings.Fields(value)
-		if len(parts) == 2 && strings.EqualFold(parts[0], "Bearer") {
-			return parts[0] + " " + maskToken(parts[1])
+// defaultForcedThinkingBudget force_thinking 注入的默认思考预算（对应 reasoning_effort=medium）
+const (
+	defaultForcedThinkingBudget = 8192
+	defaultForcedReasoningLevel = "medium"
+)
+
+// maxTokensFieldNames 各请求格式中表示最大输出 token 数的字段
+var maxTokensFieldNames = []string{"max_tokens", "max_completion_tokens", "max_output_tokens"}
+
+func isValidMaxTokensFieldName(field string) bool {
+	if field == "" {
+		return true
+	}
+	for _, name := range maxTokensFieldNames {
+		if field == name {
+			return true
 		}
-		return maskToken(value)
-	case "x-api-key":
-		return maskToken(value)
-	default:
-		return value
 	}
+	return false
 }
 
-// normalizeClientType 将内部客户端类型转换为统一字符串
-func normalizeClientType(ct utils.ClientType) string {
-	switch ct {
-	case utils.ClientClaudeCode:
-		return "claude_code"
-	case utils.ClientCodex:
-		return "codex"
-	case utils.ClientGemini:
-		return "gemini"
-	default:
-		return "unknown"
+// isValidHMACAlgo 校验端点 hmac_algo，空值表示使用默认 sha256
+func isValidHMACAlgo(algo string) bool {
+	switch strings.ToLower(strings.TrimSpace(algo)) {
+	case "", "sha256", "sha512", "sha1":
+		return true
 	}
+	return false
 }
 
-// normalizeRequestFormat 统一请求格式标识
-func normalizeRequestFormat(f utils.RequestFormat) string {
-	switch f {
-	case utils.FormatAnthropic:
-		return "anthropic"
-	case utils.FormatOpenAI:
-		return "openai"
+// computeHMACSignature 计算请求体的 HMAC 签名（十六进制小写）
+func computeHMACSignature(body []byte, secret, algo string) (string, error) {
+	var newHash func() hash.Hash
+	switch strings.ToLower(strings.TrimSpace(algo)) {
+	case "", "sha256":
+		newHash = sha256.New
+	case "sha512":
+		newHash = sha512.New
+	case "sha1":
+		newHash = sha1.New
 	default:
-		return "unknown"
+		return "", fmt.Errorf("unsupported hmac_algo: %s", algo)
 	}
+	mac := hmac.New(newHash, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil)), nil
 }
 
-// forwardRequest 转发请求到目标端点
-func (a *App) forwardRequest(originalReq *http.Request, body []byte, targetURL string, endpoint config.EndpointConfig, upstreamToken string) (*http.Response, error) {
-	// 解析目标URL
-	parsedURL, err := url.Parse(targetURL)
-	if err != nil {
-		runtime.LogError(a.ctx, fmt.Sprintf("解析目标URL失败: %v", err))
-		return nil, err
+// resolveSecretValue 解析密钥配置：env:NAME 或 ${NAME} 从环境变量读取，其余原样返回
+func resolveSecretValue(value string) string {
+	value = strings.TrimSpace(value)
+	switch {
+	case strings.HasPrefix(value, "env:"):
+		return strings.TrimSpace(os.Getenv(strings.TrimSpace(strings.TrimPrefix(value, "env:"))))
+	case strings.HasPrefix(value, "${") && strings.HasSuffix(value, "}"):
+		return strings.TrimSpace(os.Getenv(strings.TrimSpace(value[2 : len(value)-1])))
 	}
+	return value
+}
 
-	// 创建新请求
-	req, err := http.NewRequest(originalReq.Method, parsedURL.String(), bytes.NewReader(body))
-	if err != nil {
-		runtime.LogError(a.ctx, fmt.Sprintf("创建新请求失败: %v", err))
-		return nil, err
+// resolveMaxTokensField 端点配置的 max_tokens_field_name 优先，否则按请求路径选择（/responses 使用 max_output_tokens）
+func resolveMaxTokensField(endpoint *config.EndpointConfig, path string) string {
+	if endpoint != nil && endpoint.MaxTokensFieldName != "" {
+		return endpoint.MaxTokensFieldName
+	}
+	if strings.Contains(path, "/responses") {
+		return "max_output_tokens"
+	}
+	return "max_tokens"
+}
+
+// getMaxTokensLimits 返回默认 max_tokens 与上限：端点的 default_max_tokens/max_tokens_ceiling 优先，
+// 未配置（0）时使用 server.default_max_tokens/server.max_tokens_ceiling
+func (a *App) getMaxTokensLimits(endpoint *config.EndpointConfig) (int, int) {
+	defaultMax, ceiling := 0, 0
+	if endpoint != nil {
+		defaultMax, ceiling = endpoint.DefaultMaxTokens, endpoint.MaxTokensCeiling
+	}
+	if defaultMax > 0 && ceiling > 0 {
+		return defaultMax, ceiling
 	}
 
-	// 复制所有请求头，跳过认证相关字段，后续将使用经过验证的凭据
-	for key, values := range originalReq.Header {
-		if strings.EqualFold(key, "Authorization") || strings.EqualFold(key, "X-API-Key") {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+	if a.config == nil {
+		return defaultMax, ceiling
+	}
+	server, ok := a.config["server"].(map[string]interface{})
+	if !ok {
+		return defaultMax, ceiling
+	}
+	if defaultMax <= 0 {
+		defaultMax = extractNonNegativeInt(server["default_max_tokens"])
+	}
+	if ceiling <= 0 {
+		ceiling = extractNonNegativeInt(server["max_tokens_ceiling"])
+	}
+	return defaultMax, ceiling
+}
+
+// applyMaxTokensPolicy 请求未携带任何 max_tokens 类字段时按 field 注入 defaultMax，
+// 已携带的值超过 ceiling 时截断到 ceiling；返回的说明用于日志，未修改时为空
+func applyMaxTokensPolicy(body []byte, field string, defaultMax, ceiling int) ([]byte, string) {
+	if defaultMax <= 0 && ceiling <= 0 {
+		return body, ""
+	}
+	var payload map[string]interface{}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return body, ""
+	}
+
+	var notes []string
+	present := false
+	for _, name := range maxTokensFieldNames {
+		raw, exists := payload[name]
+		if !exists || raw == nil {
 			continue
 		}
-		for _, value := range values {
-			req.Header.Add(key, value)
+		present = true
+		if value, ok := raw.(float64); ok && ceiling > 0 && value > float64(ceiling) {
+			payload[name] = ceiling
+			notes = append(notes, fmt.Sprintf("%s %d 超过上限，已截断为 %d", name, int(value), ceiling))
+		}
+	}
+	if !present && defaultMax > 0 {
+		if ceiling > 0 && defaultMax > ceiling {
+			defaultMax = ceiling
 		}
+		payload[field] = defaultMax
+		notes = append(notes, fmt.Sprintf("请求未携带 max_tokens，已注入 %s=%d", field, defaultMax))
+	}
+	if len(notes) == 0 {
+		return body, ""
 	}
 
-	effectiveToken := strings.TrimSpace(upstreamToken)
-	if effectiveToken == "" {
-		effectiveToken = strings.TrimSpace(endpoint.AuthValue)
+	updated, err := json.Marshal(payload)
+	if err != nil {
+		return body, ""
 	}
+	return updated, strings.Join(notes, "; ")
+}
 
-	switch strings.ToLower(strings.TrimSpace(endpoint.AuthType)) {
-	case "api_key":
-		if effectiveToken != "" {
-			req.Header.Set("x-api-key", effectiveToken)
-			req.Header.Del("Authorization")
-			runtime.LogInfo(a.ctx, fmt.Sprintf("使用端点API Key认证: %s", maskToken(effectiveToken)))
-		} else {
-			runtime.LogInfo(a.ctx, "端点API Key未配置，请求将使用原始头部")
+// applyStreamIncludeUsage 流式 Chat Completions 请求：include 为 true 时在客户端未指定的情况下注入
+// stream_options.include_usage=true；为 false 时移除 stream_options（上游不支持该字段）
+func applyStreamIncludeUsage(body []byte, include bool) ([]byte, bool) {
+	var payload map[string]interface{}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return body, false
+	}
+	if stream, _ := payload["stream"].(bool); !stream {
+		return body, false
+	}
+
+	if include {
+		options, _ := payload["stream_options"].(map[string]interface{})
+		if _, exists := options["include_usage"]; exists {
+			return body, false
 		}
-	case "auth_token", "auto":
-		if effectiveToken != "" {
-			req.Header.Set("Authorization", "Bearer "+effectiveToken)
-			req.Header.Del("x-api-key")
-			runtime.LogInfo(a.ctx, fmt.Sprintf("使用端点Bearer Token认证: %s", maskToken(effectiveToken)))
-		} else {
-			runtime.LogInfo(a.ctx, "端点Bearer Token未配置，请求将使用原始头部")
+		if options == nil {
+			options = map[string]interface{}{}
 		}
-	default:
-		if effectiveToken != "" {
-			req.Header.Set("Authorization", effectiveToken)
-			runtime.LogInfo(a.ctx, fmt.Sprintf("使用端点自定义认证: %s", maskToken(effectiveToken)))
-		} else {
-			runtime.LogInfo(a.ctx, "端点未配置认证信息，使用原始请求头")
+		options["include_usage"] = true
+		payload["stream_options"] = options
+	} else {
+		if _, exists := payload["stream_options"]; !exists {
+			return body, false
 		}
+		delete(payload, "stream_options")
 	}
 
-	// 发送请求
-	client := &http.Client{
-		Timeout: 15 * time.Second,
-	}
-
-	resp, err := client.Do(req)
+	updated, err := json.Marshal(payload)
 	if err != nil {
-		runtime.LogError(a.ctx, fmt.Sprintf("发送请求失败: %v", err))
-		return nil, err
+		return body, false
 	}
-
-	return resp, nil
+	return updated, true
 }
 
-// getKeys 获取map的所有key
-func getKeys(m map[string]interface{}) []string {
-	keys := make([]string, 0, len(m))
-	for k := range m {
-		keys = append(keys, k)
+// applyThinkingPolicy 按端点的 force_thinking/disable_thinking 调整请求体，返回最终的思考状态（用于日志）
+// disable 优先：移除 thinking/reasoning_effort/reasoning；force：请求未携带时注入默认预算
+func applyThinkingPolicy(body []byte, endpoint *config.EndpointConfig, path string) ([]byte, bool, int) {
+	var payload map[string]interface{}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return body, false, 0
 	}
-	return keys
-}
 
-// writeJSONError 输出统一的JSON错误响应
-func writeJSONError(w http.ResponseWriter, status int, code string, message string) {
-	if message == "" {
-		message = http.StatusText(status)
+	modified := false
+	if endpoint != nil && endpoint.DisableThinking {
+		for _, field := range []string{"thinking", "reasoning_effort", "reasoning"} {
+			if _, exists := payload[field]; exists {
+				delete(payload, field)
+				modified = true
+			}
+		}
+	} else if endpoint != nil && endpoint.ForceThinking {
+		enabled, _ := thinkingStateFromPayload(payload)
+		if !enabled {
+			switch {
+			case strings.Contains(path, "/responses"):
+				payload["reasoning"] = map[string]interface{}{"effort": defaultForcedReasoningLevel}
+			case strings.Contains(path, "/chat/completions"):
+				payload["reasoning_effort"] = defaultForcedReasoningLevel
+			case strings.Contains(path, "/messages"):
+				payload["thinking"] = map[string]interface{}{
+					"type":          "enabled",
+					"budget_tokens": float64(defaultForcedThinkingBudget),
+				}
+				// Anthropic 要求 max_tokens 大于 budget_tokens（缺失时同样补齐）
+				if maxTokens, ok := payload["max_tokens"].(float64); !ok || int(maxTokens) <= defaultForcedThinkingBudget {
+					payload["max_tokens"] = defaultForcedThinkingBudget * 2
+				}
+				// 扩展思考不接受 top_k，且要求 temperature 为 1、top_p 不低于 0.95；移除这些采样参数使用上游默认值
+				for _, field := range []string{"temperature", "top_k", "top_p"} {
+					delete(payload, field)
+				}
+			}
+			modified = true
+		}
 	}
-	payload := map[string]interface{}{
-		"success": false,
-		"error": map[string]interface{}{
-			"code":    code,
-			"message": message,
-		},
+
+	enabled, budget := thinkingStateFromPayload(payload)
+	if !modified {
+		return body, enabled, budget
 	}
 
-	respBytes, err := json.Marshal(payload)
+	updated, err := json.Marshal(payload)
 	if err != nil {
-		http.Error(w, message, status)
-		return
+		return body, enabled, budget
 	}
+	return updated, enabled, budget
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
-	w.Write(respBytes)
+// thinkingStateFromPayload 解析请求体中的思考配置（Anthropic thinking 或 OpenAI reasoning_effort/reasoning）
+func thinkingStateFromPayload(payload map[string]interface{}) (bool, int) {
+	if thinking, ok := payload["thinking"].(map[string]interface{}); ok {
+		if thinkingType, _ := thinking["type"].(string); thinkingType == "enabled" {
+			budget, _ := thinking["budget_tokens"].(float64)
+			return true, int(budget)
+		}
+	}
+
+	effort, _ := payload["reasoning_effort"].(string)
+	if reasoning, ok := payload["reasoning"].(map[string]interface{}); ok && effort == "" {
+		effort, _ = reasoning["effort"].(string)
+	}
+	if effort == "" || effort == "none" {
+		return false, 0
+	}
+	return true, conversion.NewThinkingBudgetMapper(nil, nil).OpenAIReasoningEffortToAnthropicTokens(effort)
 }
 
-// cleanup 清理资源
-func (a *App) cleanup() {
-	if a.dbManager != nil {
-		a.dbManager.Close()
+// chooseLoggedModel 返回应记录的模型名称
+func chooseLoggedModel(originalModel, rewrittenModel string) string {
+	if strings.TrimSpace(rewrittenModel) != "" {
+		return rewrittenModel
 	}
-	runtime.LogInfo(a.ctx, "✅ 统一路由架构已关闭")
+	return originalModel
 }
 
-// GetServerStatus 获取服务器状态
-func (a *App) GetServerStatus() map[string]interface{} {
-	a.mutex.RLock()
-	defer a.mutex.RUnlock()
+// contentTypeDetector 复用 validator 的内容类型嗅探逻辑（无状态，可全局共享）
+var contentTypeDetector = validator.NewResponseValidator()
 
-	host := strings.TrimSpace(a.proxyHost)
-	if host == "" {
-		host = defaultProxyHost
+// correctContentType 上游Content-Type与实际内容不符时（如 text/plain 的 JSON、标成 JSON 的 SSE）修正响应头，返回覆盖说明
+func (a *App) correctContentType(w http.ResponseWriter, body []byte, resp *http.Response, endpointName string) string {
+	currentContentType := resp.Header.Get("Content-Type")
+	newContentType, overrideInfo := applyContentTypeCorrection(w.Header(), body, resp)
+	if newContentType == "" {
+		return ""
 	}
+	runtime.LogInfo(a.ctx, fmt.Sprintf("Content-Type 已修正 (%s): %q -> %q [%s]", endpointName, currentContentType, newContentType, overrideInfo))
+	return overrideInfo
+}
 
-	port := a.proxyPort
-	if port <= 0 || port > 65535 {
-		port = defaultProxyPort
+// applyContentTypeCorrection 嗅探响应体的实际类型，与上游 Content-Type 不符时写入修正后的响应头；无需修正时返回空
+func applyContentTypeCorrection(header http.Header, body []byte, resp *http.Response) (string, string) {
+	newContentType, overrideInfo := contentTypeDetector.SmartDetectContentType(body, resp.Header.Get("Content-Type"), resp.StatusCode)
+	if newContentType == "" {
+		return "", ""
 	}
+	header.Set("Content-Type", newContentType)
+	return newContentType, overrideInfo
+}
 
-	configuredHost := strings.TrimSpace(a.configuredHost)
-	if configuredHost == "" {
-		configuredHost = defaultProxyHost
+// extractServiceTier 从上游响应（JSON 或 SSE）中提取实际生效的 service_tier
+func extractServiceTier(body []byte) string {
+	if len(body) == 0 || !bytes.Contains(body, []byte(`"service_tier"`)) {
+		return ""
 	}
 
-	configuredPort := a.configuredPort
-	if configuredPort <= 0 || configuredPort > 65535 {
-		configuredPort = defaultProxyPort
+	fromPayload := func(payload map[string]interface{}) string {
+		if tier, ok := payload["service_tier"].(string); ok && tier != "" {
+			return tier
+		}
+		for _, key := range []string{"usage", "response", "message"} {
+			if nested, ok := payload[key].(map[string]interface{}); ok {
+				if tier, ok := nested["service_tier"].(string); ok && tier != "" {
+					return tier
+				}
+				if usage, ok := nested["usage"].(map[string]interface{}); ok {
+					if tier, ok := usage["service_tier"].(string); ok && tier != "" {
+						return tier
+					}
+				}
+			}
+		}
+		return ""
 	}
 
-	status := map[string]interface{}{
-		"running":           a.running,
-		"host":              host,
-		"port":              port,
-		"configured_host":   configuredHost,
-		"configured_port":   configuredPort,
-		"endpoints_total":   0,
-		"endpoints_healthy": 0,
-		"mode":              "desktop (统一路由)",
-		"architecture":      "unified_wails",
-		"http_server":       "embedded",
-		"api_communication": "go_methods_only",
-		"config_path":       a.configPath,
+	var payload map[string]interface{}
+	if err := json.Unmarshal(body, &payload); err == nil {
+		return fromPayload(payload)
 	}
 
-	if a.running {
-		status["uptime"] = "运行中 (统一架构)"
+	// SSE：取最后一个携带 service_tier 的事件
+	tier := ""
+	for _, line := range strings.Split(string(body), "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "data:") || !strings.Contains(line, `"service_tier"`) {
+			continue
+		}
+		var event map[string]interface{}
+		if err := json.Unmarshal([]byte(strings.TrimSpace(strings.TrimPrefix(line, "data:"))), &event); err == nil {
+			if found := fromPayload(event); found != "" {
+				tier = found
+			}
+		}
 	}
+	return tier
+}
 
-	return status
+// upstreamAuthMethod 根据实际发往上游的请求头判断所用的认证方式：api_key / authorization / oauth，未携带凭据时为空
+func upstreamAuthMethod(authType string, header http.Header) string {
+	if header.Get("x-api-key") != "" {
+		return "api_key"
+	}
+	if header.Get("Authorization") != "" {
+		if strings.EqualFold(strings.TrimSpace(authType), "oauth") {
+			return "oauth"
+		}
+		return "authorization"
+	}
+	return ""
 }
 
-// RestartServer 重启服务
-func (a *App) RestartServer() string {
-	runtime.LogInfo(a.ctx, "Restarting unified architecture services")
+// logProxyRequest 统一写入请求日志
+func (a *App) logProxyRequest(entry *logger.RequestLog) {
+	if entry == nil {
+		return
+	}
 
-	a.mutex.Lock()
-	a.running = false
-	a.mutex.Unlock()
+	// 响应截断视为网络抖动，不计入端点成功率与业务错误率
+	// 客户端取消与端点无关，同样跳过
+	if entry.Endpoint != "authorization" && entry.Endpoint != "fallback" && !skipsEndpointOutcome(entry.ErrorCategory) {
+		a.recordEndpointOutcome(entry.Endpoint, entry.StatusCode)
+		if demoted, changed, rate := a.recordBusinessErrorOutcome(entry.Endpoint, entry.StatusCode, time.Now()); changed {
+			if demoted {
+				msg := fmt.Sprintf("端点 %s 业务错误率 %.0f%% 超过阈值，临时降低优先级", entry.Endpoint, rate*100)
+				runtime.LogWarning(a.ctx, msg)
+				a.addLog("warn", msg)
+			} else {
+				msg := fmt.Sprintf("端点 %s 业务错误率回落至 %.0f%%，恢复原优先级", entry.Endpoint, rate*100)
+				runtime.LogInfo(a.ctx, msg)
+				a.addLog("info", msg)
+			}
+		}
+		a.trackDeadLetterAttempt(entry)
+		if entry.StatusCode >= http.StatusBadRequest || entry.Error != "" {
+			a.mutex.RLock()
+			db := a.db
+			a.mutex.RUnlock()
+			a.recordEndpointLastError(db, entry.Endpoint, summarizeEndpointError(entry.StatusCode, entry.Error))
+		}
+	}
 
-	time.Sleep(100 * time.Millisecond)
+	// logging.body_sample_rate：未被抽中的请求只保留元数据（状态、耗时、模型、大小）
+	if !bodySampled(entry.RequestID, a.getBodySampleRate()) {
+		stripLoggedBodies(entry)
+	}
+	if policy := a.getLogBodyPolicy(entry.ClientType); policy.request == logBodyNone || policy.response == logBodyNone {
+		if policy.request == logBodyNone {
+			stripLoggedRequestBodies(entry)
+		}
+		if policy.response == logBodyNone {
+			stripLoggedResponseBodies(entry)
+		}
+	}
+
+	// logging.consolidate_attempts：失败尝试先暂存，请求结束时与最终结果合并为一条日志
+	if a.isConsolidateAttemptsEnabled() {
+		if entry = a.consolidateAttempt(entry); entry == nil {
+			return
+		}
+	}
+
+	a.writeRequestLog(entry)
+}
+
+// writeRequestLog 将请求日志写入日志存储
+func (a *App) writeRequestLog(entry *logger.RequestLog) {
+	if a.requestLogger == nil {
+		if err := a.initRequestLogger(); err != nil {
+			runtime.LogError(a.ctx, fmt.Sprintf("无法初始化请求日志记录器: %v", err))
+			return
+		}
+	}
+
+	if writer := a.getRequestLogWriter(); writer != nil {
+		writer.Enqueue(entry)
+	}
+}
+
+// getRequestLogWriter 返回异步请求日志写入器，首次使用时创建
+func (a *App) getRequestLogWriter() *logger.AsyncRequestWriter {
+	a.requestLogWriterMu.Lock()
+	defer a.requestLogWriterMu.Unlock()
+
+	if a.requestLogWriter == nil && a.requestLogger != nil {
+		a.requestLogWriter = logger.NewAsyncRequestWriter(a.requestLogger, logger.DefaultAsyncLogBufferSize)
+	}
+	return a.requestLogWriter
+}
+
+// closeRequestLogWriter 写完队列中剩余的请求日志并停止后台写入协程
+func (a *App) closeRequestLogWriter() {
+	a.requestLogWriterMu.Lock()
+	writer := a.requestLogWriter
+	a.requestLogWriterMu.Unlock()
+
+	if writer == nil {
+		return
+	}
+	writer.Close()
+	if dropped := writer.Dropped(); dropped > 0 {
+		runtime.LogWarning(a.ctx, fmt.Sprintf("日志写入队列繁忙，共丢弃 %d 条请求日志", dropped))
+	}
+}
+
+// flushRequestLogs 等待写入队列中已有的请求日志落库，读取日志或统计前调用以免漏掉仍在队列中的记录
+func (a *App) flushRequestLogs() {
+	a.requestLogWriterMu.Lock()
+	writer := a.requestLogWriter
+	a.requestLogWriterMu.Unlock()
+
+	writer.Flush()
+}
+
+// droppedRequestLogs 返回因写入队列已满被丢弃的请求日志条数
+func (a *App) droppedRequestLogs() int64 {
+	a.requestLogWriterMu.Lock()
+	defer a.requestLogWriterMu.Unlock()
+	return a.requestLogWriter.Dropped()
+}
+
+// consolidatedRequestLog 合并日志模式下单个请求暂存的失败尝试
+type consolidatedRequestLog struct {
+	last     *logger.RequestLog
+	attempts []logger.RequestAttempt
+}
+
+// isConsolidateAttemptsEnabled 读取 logging.consolidate_attempts，默认关闭（每次尝试单独记录一条日志）
+func (a *App) isConsolidateAttemptsEnabled() bool {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	if a.config == nil {
+		return false
+	}
+	logging, ok := a.config["logging"].(map[string]interface{})
+	if !ok {
+		return false
+	}
+	return extractBool(logging["consolidate_attempts"], false)
+}
+
+// requestAttemptFromLog 提取单次尝试的结果摘要（保留失败尝试的错误响应体）
+func requestAttemptFromLog(entry *logger.RequestLog) logger.RequestAttempt {
+	return logger.RequestAttempt{
+		Endpoint:      entry.Endpoint,
+		AttemptNumber: entry.AttemptNumber,
+		StatusCode:    entry.StatusCode,
+		DurationMs:    entry.DurationMs,
+		Error:         entry.Error,
+		ResponseBody:  entry.ResponseBody,
+	}
+}
+
+// consolidateAttempt 暂存失败的端点尝试并返回 nil；成功或最终结果时附带此前的全部尝试返回待写入的日志
+func (a *App) consolidateAttempt(entry *logger.RequestLog) *logger.RequestLog {
+	if entry.RequestID == "" {
+		return entry
+	}
+	final := entry.Endpoint == "authorization" || entry.Endpoint == "fallback" ||
+		(entry.StatusCode > 0 && entry.StatusCode < http.StatusBadRequest && entry.Error == "")
+
+	a.consolidatedMu.Lock()
+	defer a.consolidatedMu.Unlock()
+	pending := a.consolidatedLogs[entry.RequestID]
+	if !final {
+		if a.consolidatedLogs == nil {
+			a.consolidatedLogs = make(map[string]*consolidatedRequestLog)
+		}
+		if pending == nil {
+			pending = &consolidatedRequestLog{}
+			a.consolidatedLogs[entry.RequestID] = pending
+		}
+		pending.last = entry
+		pending.attempts = append(pending.attempts, requestAttemptFromLog(entry))
+		return nil
+	}
+
+	if pending == nil {
+		return entry
+	}
+	delete(a.consolidatedLogs, entry.RequestID)
+	attempts := pending.attempts
+	if entry.Endpoint != "authorization" && entry.Endpoint != "fallback" {
+		attempts = append(attempts, requestAttemptFromLog(entry))
+	}
+	entry.Attempts = attempts
+	return entry
+}
+
+// flushConsolidatedAttempts 请求结束时写出仍暂存的失败尝试（如终止状态码直接返回、没有最终日志的情况），以最后一次尝试为主记录
+func (a *App) flushConsolidatedAttempts(requestID string) {
+	a.consolidatedMu.Lock()
+	pending := a.consolidatedLogs[requestID]
+	delete(a.consolidatedLogs, requestID)
+	a.consolidatedMu.Unlock()
+
+	if pending == nil || pending.last == nil {
+		return
+	}
+	entry := pending.last
+	entry.Attempts = pending.attempts
+	a.writeRequestLog(entry)
+}
+
+// getBodySampleRate 读取 logging.body_sample_rate（0.0-1.0），默认 1.0 即记录全部请求体
+func (a *App) getBodySampleRate() float64 {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	if a.config == nil {
+		return 1
+	}
+	logging, ok := a.config["logging"].(map[string]interface{})
+	if !ok {
+		return 1
+	}
+
+	rate := 1.0
+	switch v := logging["body_sample_rate"].(type) {
+	case float64:
+		rate = v
+	case int:
+		rate = float64(v)
+	case string:
+		if parsed, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+			rate = parsed
+		}
+	}
+	if rate < 0 {
+		rate = 0
+	}
+	if rate > 1 {
+		rate = 1
+	}
+	return rate
+}
+
+// bodySampled 按请求ID哈希决定是否记录请求体，同一请求的所有尝试结果一致
+func bodySampled(requestID string, rate float64) bool {
+	if rate >= 1 {
+		return true
+	}
+	if rate <= 0 {
+		return false
+	}
+	hasher := fnv.New64a()
+	hasher.Write([]byte(requestID))
+	return float64(hasher.Sum64()%10000) < rate*10000
+}
+
+// stripLoggedBodies 清除日志条目中的请求/响应体，保留大小等元数据
+func stripLoggedBodies(entry *logger.RequestLog) {
+	stripLoggedRequestBodies(entry)
+	stripLoggedResponseBodies(entry)
+}
+
+// stripLoggedRequestBodies 仅清除请求体
+func stripLoggedRequestBodies(entry *logger.RequestLog) {
+	entry.RequestBody = ""
+	entry.OriginalRequestBody = ""
+	entry.FinalRequestBody = ""
+	entry.RequestBodyTruncated = false
+}
+
+// stripLoggedResponseBodies 仅清除响应体
+func stripLoggedResponseBodies(entry *logger.RequestLog) {
+	entry.ResponseBody = ""
+	entry.OriginalResponseBody = ""
+	entry.FinalResponseBody = ""
+	entry.ResponseBodyTruncated = false
+}
+
+// 请求/响应体记录模式（与 logging.log_request_body / log_response_body 取值一致）
+const (
+	logBodyFull      = "full"
+	logBodyTruncated = "truncated"
+	logBodyNone      = "none"
+)
+
+// logBodyPolicy 某类客户端的请求/响应体记录模式
+type logBodyPolicy struct {
+	request  string
+	response string
+}
+
+// normalizeLogBodyMode 规范化记录模式，无效值返回空串
+func normalizeLogBodyMode(value interface{}) string {
+	mode, _ := value.(string)
+	switch mode = strings.ToLower(strings.TrimSpace(mode)); mode {
+	case logBodyFull, logBodyTruncated, logBodyNone:
+		return mode
+	}
+	return ""
+}
+
+// logBodyLimit 返回记录模式对应的截断长度，full 不截断
+func logBodyLimit(mode string) int {
+	if mode == logBodyFull {
+		return 0
+	}
+	return healthLogPreviewLimit
+}
+
+// getLogBodyPolicy 读取 logging.log_request_body / log_response_body（默认 truncated），
+// 并按 logging.per_client.<client_type> 覆盖：body 同时作用于请求与响应，request_body / response_body 单独覆盖
+func (a *App) getLogBodyPolicy(clientType string) logBodyPolicy {
+	policy := logBodyPolicy{request: logBodyTruncated, response: logBodyTruncated}
+
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	if a.config == nil {
+		return policy
+	}
+	logging, ok := a.config["logging"].(map[string]interface{})
+	if !ok {
+		return policy
+	}
+	if mode := normalizeLogBodyMode(logging["log_request_body"]); mode != "" {
+		policy.request = mode
+	}
+	if mode := normalizeLogBodyMode(logging["log_response_body"]); mode != "" {
+		policy.response = mode
+	}
+
+	perClient, ok := logging["per_client"].(map[string]interface{})
+	if !ok {
+		return policy
+	}
+	override, ok := perClient[strings.TrimSpace(clientType)].(map[string]interface{})
+	if !ok {
+		return policy
+	}
+	if mode := normalizeLogBodyMode(override["body"]); mode != "" {
+		policy.request = mode
+		policy.response = mode
+	}
+	if mode := normalizeLogBodyMode(override["request_body"]); mode != "" {
+		policy.request = mode
+	}
+	if mode := normalizeLogBodyMode(override["response_body"]); mode != "" {
+		policy.response = mode
+	}
+	return policy
+}
+
+// accessLogRecorder 记录写给客户端的状态码、字节数以及最终服务的端点，用于访问日志
+type accessLogRecorder struct {
+	http.ResponseWriter
+	status   int
+	bytes    int64
+	endpoint string
+	model    string
+	timings  *serverTimings // 非空时在写出响应头前附加 Server-Timing
+}
+
+func (rec *accessLogRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+		rec.timings.apply(rec.Header(), time.Now())
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *accessLogRecorder) Write(p []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+		rec.timings.apply(rec.Header(), time.Now())
+	}
+	n, err := rec.ResponseWriter.Write(p)
+	rec.bytes += int64(n)
+	return n, err
+}
+
+func (rec *accessLogRecorder) entry(r *http.Request, startTime time.Time) logger.AccessLogEntry {
+	return logger.AccessLogEntry{
+		Time:       startTime,
+		RemoteAddr: r.RemoteAddr,
+		Method:     r.Method,
+		Path:       r.URL.Path,
+		Proto:      r.Proto,
+		Status:     rec.status,
+		Bytes:      rec.bytes,
+		Duration:   time.Since(startTime),
+		Endpoint:   rec.endpoint,
+		Model:      rec.model,
+		UserAgent:  r.UserAgent(),
+	}
+}
+
+// serverTimings handleProxyRequest 各阶段耗时；多次尝试时转换与上游耗时取最后一次尝试（即实际返回的响应）
+type serverTimings struct {
+	start                   time.Time
+	selection               time.Duration // 收到请求到开始尝试端点：读取请求体、查询与排序过滤端点、格式检测
+	requestConversion       time.Duration // 单次尝试中请求体改写与格式转换
+	upstream                time.Duration // 上游响应头返回耗时（首字节）
+	responseConversionStart time.Time     // 读完上游响应体、开始响应转换的时间
+}
+
+// markSelection 记录端点选择阶段结束
+func (t *serverTimings) markSelection() {
+	if t != nil {
+		t.selection = time.Since(t.start)
+	}
+}
+
+// markRequestConversion 记录当前尝试的请求转换耗时
+func (t *serverTimings) markRequestConversion(d time.Duration) {
+	if t != nil {
+		t.requestConversion = d
+	}
+}
+
+// markUpstream 记录当前尝试的上游首字节耗时，并清除上一尝试遗留的响应转换起点
+func (t *serverTimings) markUpstream(d time.Duration) {
+	if t != nil {
+		t.upstream = d
+		t.responseConversionStart = time.Time{}
+	}
+}
+
+// beginResponseConversion 标记上游响应体已读完，之后到写出响应头的时间计入转换耗时
+func (t *serverTimings) beginResponseConversion() {
+	if t != nil {
+		t.responseConversionStart = time.Now()
+	}
+}
+
+// header 生成 Server-Timing 头的值（毫秒，保留一位小数）
+func (t *serverTimings) header(now time.Time) string {
+	conversion := t.requestConversion
+	if !t.responseConversionStart.IsZero() {
+		conversion += now.Sub(t.responseConversionStart)
+	}
+	metric := func(name, desc string, d time.Duration) string {
+		return fmt.Sprintf("%s;desc=%q;dur=%.1f", name, desc, float64(d.Microseconds())/1000)
+	}
+	return strings.Join([]string{
+		metric("select", "endpoint selection", t.selection),
+		metric("convert", "conversion", conversion),
+		metric("upstream", "upstream TTFB", t.upstream),
+		metric("total", "total", now.Sub(t.start)),
+	}, ", ")
+}
+
+// apply 写入 Server-Timing 响应头；与代理的 CORS 设置一致允许任意来源读取计时
+func (t *serverTimings) apply(h http.Header, now time.Time) {
+	if t != nil {
+		h.Set("Server-Timing", t.header(now))
+		h.Set("Timing-Allow-Origin", "*")
+	}
+}
+
+// noteAccessLogUpstream 在访问日志中记录成功服务请求的端点与模型
+func noteAccessLogUpstream(w http.ResponseWriter, endpointName, model string) {
+	if rec, ok := w.(*accessLogRecorder); ok {
+		rec.endpoint = endpointName
+		rec.model = model
+	}
+}
+
+// getAccessLogConfig 读取 logging.access_log（enabled/format/destination/max_size_mb/max_backups），未启用时返回 false
+func (a *App) getAccessLogConfig() (logger.AccessLogConfig, bool) {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	if a.config == nil {
+		return logger.AccessLogConfig{}, false
+	}
+	logging, ok := a.config["logging"].(map[string]interface{})
+	if !ok {
+		return logger.AccessLogConfig{}, false
+	}
+	raw, ok := logging["access_log"].(map[string]interface{})
+	if !ok {
+		return logger.AccessLogConfig{}, false
+	}
+	if enabled, ok := raw["enabled"].(bool); ok && !enabled {
+		return logger.AccessLogConfig{}, false
+	}
+
+	cfg := logger.AccessLogConfig{
+		Format:      getStringFromMap(raw, "format"),
+		Destination: getStringFromMap(raw, "destination"),
+	}
+	if size, ok := raw["max_size_mb"].(float64); ok {
+		cfg.MaxSizeMB = int(size)
+	}
+	if backups, ok := raw["max_backups"].(float64); ok {
+		cfg.MaxBackups = int(backups)
+	}
+	return cfg, true
+}
+
+// getAccessLogger 返回当前配置对应的访问日志记录器，配置变化时关闭旧文件并重建
+func (a *App) getAccessLogger() *logger.AccessLogger {
+	cfg, enabled := a.getAccessLogConfig()
+
+	a.accessLogMu.Lock()
+	defer a.accessLogMu.Unlock()
+
+	if !enabled {
+		if a.accessLogger != nil {
+			a.accessLogger.Close()
+			a.accessLogger = nil
+		}
+		a.accessLogApplied = false
+		return nil
+	}
+	// 配置未变化时复用（包括初始化失败的情况，避免每个请求重复尝试）
+	if a.accessLogApplied && cfg == a.accessLogConfig {
+		return a.accessLogger
+	}
+
+	if a.accessLogger != nil {
+		a.accessLogger.Close()
+		a.accessLogger = nil
+	}
+	a.accessLogConfig = cfg
+	a.accessLogApplied = true
+
+	accessLogger, err := logger.NewAccessLogger(cfg)
+	if err != nil {
+		a.addLog("error", fmt.Sprintf("访问日志初始化失败: %v", err))
+		return nil
+	}
+	a.accessLogger = accessLogger
+	return accessLogger
+}
+
+// headersToMap 将HTTP头转换为map
+func headersToMap(h http.Header, maskSensitive bool) map[string]string {
+	if len(h) == 0 {
+		return map[string]string{}
+	}
+
+	result := make(map[string]string, len(h))
+	for key, values := range h {
+		joined := strings.Join(values, ",")
+		if maskSensitive {
+			joined = maskHeaderValue(key, joined)
+		}
+		result[key] = joined
+	}
+	return result
+}
+
+// buildFinalRequestHeaders 构建发往上游的请求头（敏感字段已脱敏）
+func buildFinalRequestHeaders(original http.Header, endpoint *config.EndpointConfig, mappedToken string) map[string]string {
+	headers := headersToMap(original, true)
+
+	// 移除原有认证信息
+	for key := range headers {
+		lower := strings.ToLower(key)
+		if lower == "authorization" || lower == "x-api-key" {
+			delete(headers, key)
+		}
+	}
+
+	token := strings.TrimSpace(mappedToken)
+	if token == "" && endpoint != nil {
+		token = strings.TrimSpace(endpoint.AuthValue)
+	}
+
+	if endpoint != nil {
+		switch strings.ToLower(strings.TrimSpace(endpoint.AuthType)) {
+		case "api_key":
+			if token != "" {
+				headers["X-API-Key"] = maskToken(token)
+			}
+		case "auth_token", "auto":
+			if token != "" {
+				headers["Authorization"] = maskHeaderValue("Authorization", "Bearer "+token)
+			}
+		}
+
+		injected := http.Header{}
+		applyOrganizationHeaders(injected, *endpoint)
+		for key, values := range injected {
+			for existing := range headers {
+				if strings.EqualFold(existing, key) {
+					delete(headers, existing)
+				}
+			}
+			headers[key] = maskHeaderValue(key, strings.Join(values, ","))
+		}
+	}
+
+	return headers
+}
+
+// applyOrganizationHeaders 写入端点配置的 OpenAI-Organization / OpenAI-Project 请求头，未配置时保留原值
+func applyOrganizationHeaders(h http.Header, endpoint config.EndpointConfig) {
+	if organization := strings.TrimSpace(endpoint.OpenAIOrganization); organization != "" {
+		h.Set("OpenAI-Organization", organization)
+	}
+	if project := strings.TrimSpace(endpoint.OpenAIProject); project != "" {
+		h.Set("OpenAI-Project", project)
+	}
+}
+
+// maskHeaderValue 对敏感头部进行脱敏
+func maskHeaderValue(key, value string) string {
+	switch strings.ToLower(key) {
+	case "authorization":
+		parts := strings.Fields(value)
+		if len(parts) == 2 && strings.EqualFold(parts[0], "Bearer") {
+			return parts[0] + " " + maskToken(parts[1])
+		}
+		return maskToken(value)
+	case "x-api-key":
+		return maskToken(value)
+	case "openai-organization", "openai-project", "anthropic-organization-id":
+		// 组织/项目 ID 可定位到具体账户，日志中只保留首尾几位
+		return maskToken(value)
+	default:
+		return value
+	}
+}
+
+// normalizeClientType 将内部客户端类型转换为统一字符串
+func normalizeClientType(ct utils.ClientType) string {
+	switch ct {
+	case utils.ClientClaudeCode:
+		return "claude_code"
+	case utils.ClientCodex:
+		return "codex"
+	case utils.ClientGemini:
+		return "gemini"
+	default:
+		return "unknown"
+	}
+}
+
+// detectRequestFormat 检测请求格式，X-CCCC-Request-Format 请求头存在且有效时跳过自动检测
+func (a *App) detectRequestFormat(r *http.Request, body []byte) *utils.FormatDetectionResult {
+	if override := strings.TrimSpace(r.Header.Get(utils.RequestFormatOverrideHeader)); override != "" {
+		if forced := utils.DetectRequestFormatFromHeader(override); forced != nil {
+			return forced
+		}
+		runtime.LogWarning(a.ctx, fmt.Sprintf("Ignoring unsupported %s value %q, falling back to auto-detection", utils.RequestFormatOverrideHeader, override))
+	}
+	return utils.DetectRequestFormat(r.URL.Path, body)
+}
+
+// normalizeRequestFormat 统一请求格式标识
+func normalizeRequestFormat(f utils.RequestFormat) string {
+	switch f {
+	case utils.FormatAnthropic:
+		return "anthropic"
+	case utils.FormatOpenAI:
+		return "openai"
+	case utils.FormatGemini:
+		return "gemini"
+	default:
+		return "unknown"
+	}
+}
+
+// forwardRequest 转发请求到目标端点
+func (a *App) forwardRequest(originalReq *http.Request, body []byte, targetURL string, endpoint config.EndpointConfig, upstreamToken string) (*http.Response, error) {
+	// 解析目标URL
+	parsedURL, err := url.Parse(targetURL)
+	if err != nil {
+		runtime.LogError(a.ctx, fmt.Sprintf("解析目标URL失败: %v", err))
+		return nil, err
+	}
+
+	// 创建新请求，沿用客户端请求的上下文：客户端断开时上游连接与流式读取一并中止
+	req, err := http.NewRequestWithContext(originalReq.Context(), originalReq.Method, parsedURL.String(), bytes.NewReader(body))
+	if err != nil {
+		runtime.LogError(a.ctx, fmt.Sprintf("创建新请求失败: %v", err))
+		return nil, err
+	}
+
+	// 复制请求头，跳过认证相关字段（后续将使用经过验证的凭据）以及被转发规则过滤的头部
+	headerFilter := a.getHeaderForwardFilter()
+	for key, values := range originalReq.Header {
+		if strings.EqualFold(key, "Authorization") || strings.EqualFold(key, "X-API-Key") {
+			continue
+		}
+		if !headerFilter.allows(key) {
+			continue
+		}
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+
+	// server.forward_client_ip：向上游告知原始客户端地址
+	if forwarding := a.getClientIPForwarding(); forwarding.enabled {
+		existingXFF := strings.Join(originalReq.Header.Values("X-Forwarded-For"), ", ")
+		if xff, realIP := forwarding.resolve(originalReq.RemoteAddr, existingXFF); xff != "" {
+			req.Header.Set("X-Forwarded-For", xff)
+			req.Header.Set("X-Real-IP", realIP)
+		}
+	}
+
+	// 端点 user_agent 优先，其次全局 server.user_agent；均未配置时保留客户端的 User-Agent
+	if userAgent := config.ResolveUserAgent(endpoint.UserAgent, a.getDefaultUserAgent()); userAgent != "" {
+		req.Header.Set("User-Agent", userAgent)
+	}
+
+	// Anthropic 端点：端点显式配置的 anthropic_version 优先，其次保留客户端发送的值，最后使用默认版本
+	if endpoint.URLAnthropic != "" && strings.HasPrefix(targetURL, strings.TrimRight(endpoint.URLAnthropic, "/")) {
+		req.Header.Set("anthropic-version", config.ResolveAnthropicVersion(endpoint.AnthropicVersion, req.Header.Get("anthropic-version")))
+	}
+
+	runtime.LogInfo(a.ctx, applyEndpointAuth(req.Header, endpoint, upstreamToken))
+
+	// 组织/项目受限的 API Key：端点配置的 openai_organization / openai_project 覆盖客户端发送的值
+	applyOrganizationHeaders(req.Header, endpoint)
+
+	// 端点请求签名：body 已是全部改写之后的最终请求体
+	if header := strings.TrimSpace(endpoint.HMACHeader); header != "" {
+		secret := resolveSecretValue(endpoint.HMACSecret)
+		if secret == "" {
+			runtime.LogWarning(a.ctx, fmt.Sprintf("端点 %s 配置了 hmac_header 但签名密钥为空，跳过签名", endpoint.Name))
+		} else if signature, err := computeHMACSignature(body, secret, endpoint.HMACAlgo); err != nil {
+			runtime.LogWarning(a.ctx, fmt.Sprintf("端点 %s 请求签名失败: %v", endpoint.Name, err))
+		} else {
+			req.Header.Set(header, signature)
+		}
+	}
+
+	// 发送请求（复用端点的连接池）
+	client, err := a.getUpstreamClient(endpoint)
+	if err != nil {
+		runtime.LogError(a.ctx, fmt.Sprintf("创建上游客户端失败 (%s): %v", endpoint.Name, err))
+		return nil, err
+	}
+
+	// server.adaptive_timeout_*：按端点近期 P95 响应时间限制等待响应头的时间，超时直接换下一端点；
+	// 流式与非流式请求的首包耗时差异较大，分别统计
+	stream := streamRequested(body)
+	headerTimeout := a.adaptiveTimeoutFor(endpoint.Name, stream)
+
+	// 网络层错误（DNS、连接重置等）在同一端点内重试，不计为新的端点尝试；HTTP状态错误不在此重试
+	maxRetries := a.getNetworkRetryCount()
+	resp, sentAt, err := doWithNetworkRetry(client, req, body, headerTimeout, maxRetries, func(retry int, delay time.Duration, err error) {
+		runtime.LogWarning(a.ctx, fmt.Sprintf("网络错误，端点内重试 %d/%d (%s) 将在 %v 后进行: %v", retry+1, maxRetries, endpoint.Name, delay, err))
+	})
+	if err != nil {
+		if errors.Is(err, errAdaptiveTimeout) {
+			// 超时的尝试按超时值计入样本，否则端点变慢后 P95 不会上升，超时会一直卡在旧值
+			a.recordEndpointLatency(endpoint.Name, stream, headerTimeout)
+			runtime.LogWarning(a.ctx, fmt.Sprintf("端点 %s 超过自适应超时 %v 未返回响应头，尝试下一端点", endpoint.Name, headerTimeout))
+		} else {
+			runtime.LogError(a.ctx, fmt.Sprintf("发送请求失败: %v", err))
+		}
+		return nil, err
+	}
+	if resp.StatusCode < http.StatusInternalServerError {
+		a.recordEndpointLatency(endpoint.Name, stream, time.Since(sentAt))
+	}
+	return resp, nil
+}
+
+// applyEndpointAuth 按端点 auth_type 设置上游认证头，upstreamToken 为空时使用端点 auth_value；
+// 转发请求与拉取 /models 共用，返回描述所用认证方式的日志文本（凭据已脱敏）
+func applyEndpointAuth(header http.Header, endpoint config.EndpointConfig, upstreamToken string) string {
+	effectiveToken := strings.TrimSpace(upstreamToken)
+	if effectiveToken == "" {
+		effectiveToken = strings.TrimSpace(endpoint.AuthValue)
+	}
+
+	switch strings.ToLower(strings.TrimSpace(endpoint.AuthType)) {
+	case "api_key":
+		if effectiveToken == "" {
+			return "端点API Key未配置，请求将使用原始头部"
+		}
+		header.Set("x-api-key", effectiveToken)
+		header.Del("Authorization")
+		return fmt.Sprintf("使用端点API Key认证: %s", maskToken(effectiveToken))
+	case "auth_token", "auto":
+		if effectiveToken == "" {
+			return "端点Bearer Token未配置，请求将使用原始头部"
+		}
+		header.Set("Authorization", "Bearer "+effectiveToken)
+		header.Del("x-api-key")
+		return fmt.Sprintf("使用端点Bearer Token认证: %s", maskToken(effectiveToken))
+	case "oauth":
+		// 优先使用 oauth_config 中（可能已被预刷新的）access_token，未配置时沿用 auth_value
+		if authorization := oauth.GetAuthorizationHeader(endpoint.OAuthConfig); authorization != "" {
+			header.Set("Authorization", authorization)
+			header.Del("x-api-key")
+			return fmt.Sprintf("使用端点OAuth认证: %s", maskHeaderValue("Authorization", authorization))
+		}
+	}
+
+	if effectiveToken == "" {
+		return "端点未配置认证信息，使用原始请求头"
+	}
+	header.Set("Authorization", effectiveToken)
+	return fmt.Sprintf("使用端点自定义认证: %s", maskToken(effectiveToken))
+}
+
+// doWithNetworkRetry 发送请求，网络层错误时按退避在同一端点内最多重试 maxRetries 次；
+// 自适应超时和客户端取消不重试。onRetry 在每次重试等待前调用，返回最后一次发送的时间用于统计耗时
+func doWithNetworkRetry(client *http.Client, req *http.Request, body []byte, headerTimeout time.Duration, maxRetries int, onRetry func(retry int, delay time.Duration, err error)) (*http.Response, time.Time, error) {
+	for retry := 0; ; retry++ {
+		sentAt := time.Now()
+		resp, err := doWithHeaderTimeout(client, req, headerTimeout)
+		if err == nil {
+			return resp, sentAt, nil
+		}
+		if errors.Is(err, errAdaptiveTimeout) || retry >= maxRetries || errors.Is(err, context.Canceled) {
+			return nil, sentAt, err
+		}
+
+		delay := networkRetryDelay(retry)
+		if onRetry != nil {
+			onRetry(retry, delay, err)
+		}
+		select {
+		case <-time.After(delay):
+		case <-req.Context().Done():
+			return nil, sentAt, req.Context().Err()
+		}
+
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+}
+
+// clientCanceled 客户端已断开（请求上下文被取消），此时不再尝试其他端点，也不计入端点失败
+func clientCanceled(r *http.Request) bool {
+	return r.Context().Err() != nil
+}
+
+// errAdaptiveTimeout 上游在自适应超时内没有返回响应头
+var errAdaptiveTimeout = errors.New("adaptive timeout exceeded")
+
+const (
+	// defaultAdaptiveTimeoutMin 自适应超时的默认下限
+	defaultAdaptiveTimeoutMin = 3 * time.Second
+	// adaptiveTimeoutSampleSize 每个端点保留的最近耗时样本数
+	adaptiveTimeoutSampleSize = 50
+	// adaptiveTimeoutMinSamples 样本不足时不启用自适应超时
+	adaptiveTimeoutMinSamples = 5
+)
+
+// adaptiveTimeoutSettings server.adaptive_timeout_multiplier / adaptive_timeout_min_ms / adaptive_timeout_max_ms
+type adaptiveTimeoutSettings struct {
+	Multiplier float64 // 0 表示关闭
+	Min        time.Duration
+	Max        time.Duration
+}
+
+// getAdaptiveTimeoutSettings 读取自适应超时配置：倍数未配置或不大于 0 时关闭，上限默认为转发整体超时
+func (a *App) getAdaptiveTimeoutSettings() adaptiveTimeoutSettings {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	settings := adaptiveTimeoutSettings{Min: defaultAdaptiveTimeoutMin, Max: forwardRequestTimeout}
+	if a.config == nil {
+		return settings
+	}
+	server, ok := a.config["server"].(map[string]interface{})
+	if !ok {
+		return settings
+	}
+
+	switch v := server["adaptive_timeout_multiplier"].(type) {
+	case float64:
+		settings.Multiplier = v
+	case int:
+		settings.Multiplier = float64(v)
+	case string:
+		if parsed, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+			settings.Multiplier = parsed
+		}
+	}
+	if settings.Multiplier < 0 {
+		settings.Multiplier = 0
+	}
+	if v := extractNonNegativeInt(server["adaptive_timeout_min_ms"]); v > 0 {
+		settings.Min = time.Duration(v) * time.Millisecond
+	}
+	if v := extractNonNegativeInt(server["adaptive_timeout_max_ms"]); v > 0 {
+		settings.Max = time.Duration(v) * time.Millisecond
+	}
+	if settings.Max < settings.Min {
+		settings.Max = settings.Min
+	}
+	return settings
+}
+
+// endpointLatencyKey 自适应超时的统计维度：端点名称与是否流式请求
+type endpointLatencyKey struct {
+	name   string
+	stream bool
+}
+
+// endpointLatencyWindow 端点最近的响应头耗时（环形缓冲）以及最近一次计算出的自适应超时
+type endpointLatencyWindow struct {
+	samples []time.Duration
+	next    int
+	timeout time.Duration
+	p95     time.Duration
+}
+
+func (w *endpointLatencyWindow) record(d time.Duration) {
+	if len(w.samples) < adaptiveTimeoutSampleSize {
+		w.samples = append(w.samples, d)
+		return
+	}
+	w.samples[w.next] = d
+	w.next = (w.next + 1) % adaptiveTimeoutSampleSize
+}
+
+// percentileDuration 最近秩法计算百分位数，samples 为空时返回 0
+func percentileDuration(samples []time.Duration, percentile float64) time.Duration {
+	if len(samples) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration(nil), samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	rank := int(math.Ceil(percentile * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// recordEndpointLatency 记录一次拿到响应头的耗时（5xx 不计入，避免故障期间拉高超时；超时按超时值计入）
+func (a *App) recordEndpointLatency(endpointName string, stream bool, d time.Duration) {
+	if endpointName == "" {
+		return
+	}
+	a.endpointLatencyMu.Lock()
+	defer a.endpointLatencyMu.Unlock()
+	if a.endpointLatency == nil {
+		a.endpointLatency = make(map[endpointLatencyKey]*endpointLatencyWindow)
+	}
+	key := endpointLatencyKey{name: endpointName, stream: stream}
+	window, ok := a.endpointLatency[key]
+	if !ok {
+		window = &endpointLatencyWindow{}
+		a.endpointLatency[key] = window
+	}
+	window.record(d)
+}
+
+// adaptiveTimeoutFor 返回端点当前的自适应超时（P95 × 倍数，限制在上下限之间）并记录下来供观察；
+// 未开启或样本不足时返回 0，表示只受转发整体超时限制
+func (a *App) adaptiveTimeoutFor(endpointName string, stream bool) time.Duration {
+	settings := a.getAdaptiveTimeoutSettings()
+
+	a.endpointLatencyMu.Lock()
+	defer a.endpointLatencyMu.Unlock()
+	window, ok := a.endpointLatency[endpointLatencyKey{name: endpointName, stream: stream}]
+	if !ok {
+		return 0
+	}
+	if settings.Multiplier <= 0 || len(window.samples) < adaptiveTimeoutMinSamples {
+		window.timeout = 0
+		return 0
+	}
+
+	window.p95 = percentileDuration(window.samples, 0.95)
+	timeout := time.Duration(float64(window.p95) * settings.Multiplier)
+	if timeout < settings.Min {
+		timeout = settings.Min
+	}
+	if timeout > settings.Max {
+		timeout = settings.Max
+	}
+	window.timeout = timeout
+	return timeout
+}
+
+// endpointAdaptiveTimeout 返回最近一次为端点（流式或非流式）计算的自适应超时与 P95 耗时（未生效时为 0）
+func (a *App) endpointAdaptiveTimeout(endpointName string, stream bool) (time.Duration, time.Duration) {
+	a.endpointLatencyMu.Lock()
+	defer a.endpointLatencyMu.Unlock()
+	if window, ok := a.endpointLatency[endpointLatencyKey{name: endpointName, stream: stream}]; ok {
+		return window.timeout, window.p95
+	}
+	return 0, 0
+}
+
+// cancelOnCloseBody 响应体关闭时释放自适应超时使用的 context
+type cancelOnCloseBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnCloseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// doWithHeaderTimeout 发送请求，timeout > 0 时只限制等待响应头的时间；响应头到达后流式读取不受影响
+func doWithHeaderTimeout(client *http.Client, req *http.Request, timeout time.Duration) (*http.Response, error) {
+	if timeout <= 0 {
+		return client.Do(req)
+	}
+
+	ctx, cancel := context.WithCancel(req.Context())
+	timer := time.AfterFunc(timeout, cancel)
+	resp, err := client.Do(req.WithContext(ctx))
+	if !timer.Stop() && req.Context().Err() == nil {
+		// 计时器已触发：无论请求是否恰好返回，都按超时处理
+		if err == nil {
+			resp.Body.Close()
+		}
+		cancel()
+		return nil, fmt.Errorf("%w (%v)", errAdaptiveTimeout, timeout)
+	}
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelOnCloseBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// forwardRequestTimeout 转发请求的整体超时
+const forwardRequestTimeout = 15 * time.Second
+
+// upstreamClientEntry 缓存的上游客户端，key 记录创建时的代理/超时/连接池配置
+type upstreamClientEntry struct {
+	key    string
+	client *http.Client
+}
+
+// upstreamClientKey 生成客户端缓存键，代理、超时或连接池配置变化时键随之变化
+func upstreamClientKey(endpoint config.EndpointConfig, timeout time.Duration, maxIdlePerHost int) string {
+	proxyKey := "direct"
+	if endpoint.Proxy != nil {
+		proxyKey = strings.Join([]string{endpoint.Proxy.Type, endpoint.Proxy.Address, endpoint.Proxy.Username, endpoint.Proxy.Password}, "|")
+	}
+	return fmt.Sprintf("%s|%s|%s|%d|%t", endpoint.Name, proxyKey, timeout, maxIdlePerHost, endpoint.InsecureSkipVerify)
+}
+
+// getUpstreamClient 返回端点复用的HTTP客户端（保持连接池与keep-alive），配置变化时重建
+func (a *App) getUpstreamClient(endpoint config.EndpointConfig) (*http.Client, error) {
+	maxIdlePerHost := a.getUpstreamMaxIdleConnsPerHost()
+	key := upstreamClientKey(endpoint, forwardRequestTimeout, maxIdlePerHost)
+
+	a.upstreamClientsMu.Lock()
+	defer a.upstreamClientsMu.Unlock()
+
+	if entry, ok := a.upstreamClients[endpoint.Name]; ok {
+		if entry.key == key {
+			return entry.client, nil
+		}
+		entry.client.CloseIdleConnections()
+	}
+
+	client, err := proxyclient.CreateHTTPClient(endpoint.Proxy, config.ProxyTimeoutConfig{
+		OverallRequest: forwardRequestTimeout.String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if transport, ok := client.Transport.(*http.Transport); ok {
+		transport.MaxIdleConnsPerHost = maxIdlePerHost
+		if transport.MaxIdleConns < maxIdlePerHost {
+			transport.MaxIdleConns = maxIdlePerHost
+		}
+		// insecure_skip_verify：自签名证书的内网端点跳过 TLS 校验
+		if endpoint.InsecureSkipVerify {
+			if transport.TLSClientConfig == nil {
+				transport.TLSClientConfig = &tls.Config{}
+			}
+			transport.TLSClientConfig.InsecureSkipVerify = true
+		}
+	}
+
+	if a.upstreamClients == nil {
+		a.upstreamClients = make(map[string]*upstreamClientEntry)
+	}
+	a.upstreamClients[endpoint.Name] = &upstreamClientEntry{key: key, client: client}
+	return client, nil
+}
+
+// warnInsecureEndpoints 启动时醒目提示启用了 insecure_skip_verify 的端点（跳过 TLS 校验存在中间人风险）
+func (a *App) warnInsecureEndpoints() {
+	a.mutex.RLock()
+	db := a.db
+	a.mutex.RUnlock()
+	if db == nil {
+		return
+	}
+
+	endpoints, err := a.queryEndpointConfigs("WHERE insecure_skip_verify = 1", false)
+	if err != nil {
+		return
+	}
+	for _, endpoint := range endpoints {
+		message := fmt.Sprintf("⚠️ 端点 '%s' 已启用 insecure_skip_verify，TLS 证书校验已关闭，仅应用于受信任的内网自签名端点", endpoint.Name)
+		runtime.LogWarning(a.ctx, message)
+		a.addLog("warn", message)
+	}
+}
+
+// invalidateUpstreamClients 丢弃所有缓存的上游客户端（端点配置被修改或删除后调用）
+func (a *App) invalidateUpstreamClients() {
+	a.upstreamClientsMu.Lock()
+	defer a.upstreamClientsMu.Unlock()
+
+	for name, entry := range a.upstreamClients {
+		entry.client.CloseIdleConnections()
+		delete(a.upstreamClients, name)
+	}
+}
+
+// getUpstreamMaxIdleConnsPerHost 读取 server.upstream_max_idle_conns_per_host
+func (a *App) getUpstreamMaxIdleConnsPerHost() int {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	value := config.Default.HTTPClient.MaxIdlePerHost
+	if a.config == nil {
+		return value
+	}
+	server, ok := a.config["server"].(map[string]interface{})
+	if !ok {
+		return value
+	}
+
+	switch v := server["upstream_max_idle_conns_per_host"].(type) {
+	case float64:
+		value = int(v)
+	case int:
+		value = v
+	case int64:
+		value = int(v)
+	}
+	if value <= 0 {
+		value = config.Default.HTTPClient.MaxIdlePerHost
+	}
+	return value
+}
+
+const (
+	defaultNetworkRetryCount = 1
+	maxNetworkRetryCount     = 5
+	networkRetryBaseDelay    = 200 * time.Millisecond
+)
+
+// networkRetryDelay 计算第 retry 次网络重试的退避时间（指数退避 + 随机抖动）
+func networkRetryDelay(retry int) time.Duration {
+	base := networkRetryBaseDelay << uint(retry)
+	return base/2 + time.Duration(rand.Int63n(int64(base)))
+}
+
+// getNetworkRetryCount 读取 server.network_retry_count，默认1次，最多5次
+// getFallbackOn4xx 读取 server.fallback_on_4xx，默认 true（上游 4xx 时尝试下一端点）
+func (a *App) getFallbackOn4xx() bool {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	if a.config == nil {
+		return true
+	}
+	server, ok := a.config["server"].(map[string]interface{})
+	if !ok {
+		return true
+	}
+	return extractBool(server["fallback_on_4xx"], true)
+}
+
+// shouldFallbackOn4xx 端点 fallback_on_4xx 已设置时优先，否则使用全局配置
+func (a *App) shouldFallbackOn4xx(endpoint *config.EndpointConfig) bool {
+	if endpoint != nil && endpoint.FallbackOn4xx != nil {
+		return *endpoint.FallbackOn4xx
+	}
+	return a.getFallbackOn4xx()
+}
+
+func (a *App) getNetworkRetryCount() int {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	if a.config == nil {
+		return defaultNetworkRetryCount
+	}
+	server, ok := a.config["server"].(map[string]interface{})
+	if !ok {
+		return defaultNetworkRetryCount
+	}
+
+	count := defaultNetworkRetryCount
+	switch v := server["network_retry_count"].(type) {
+	case float64:
+		count = int(v)
+	case int:
+		count = v
+	case int64:
+		count = int(v)
+	case string:
+		if parsed, err := strconv.Atoi(strings.TrimSpace(v)); err == nil {
+			count = parsed
+		}
+	}
+
+	if count < 0 {
+		count = 0
+	}
+	if count > maxNetworkRetryCount {
+		count = maxNetworkRetryCount
+	}
+	return count
+}
+
+const (
+	defaultRequestQueueDepth   = 16
+	defaultRequestQueueMaxWait = 10 * time.Second
+)
+
+var (
+	errRequestQueueFull    = errors.New("request queue is full")
+	errRequestQueueTimeout = errors.New("request queue wait timed out")
+)
+
+// requestQueueSettings 端点前置请求队列配置，Concurrency 为 0 表示关闭
+type requestQueueSettings struct {
+	Concurrency int
+	Depth       int
+	MaxWait     time.Duration
+}
+
+// requestQueue 有界 FIFO 队列：同一端点最多 limit 个并发转发，超出部分按到达顺序排队等待
+type requestQueue struct {
+	mu      sync.Mutex
+	active  int
+	limit   int
+	depth   int
+	waiters *list.List // 元素为 chan struct{}，槽位释放时按顺序直接移交
+}
+
+func newRequestQueue(limit, depth int) *requestQueue {
+	return &requestQueue{limit: limit, depth: depth, waiters: list.New()}
+}
+
+// resize 在配置变化时原地更新并发上限与队列深度，已排队的请求不受影响
+func (q *requestQueue) resize(limit, depth int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.limit = limit
+	q.depth = depth
+	for q.active < q.limit && q.waiters.Len() > 0 {
+		q.grantFrontLocked()
+	}
+}
+
+// acquire 获取转发槽位；队列已满立即返回 errRequestQueueFull，等待超时返回 errRequestQueueTimeout
+func (q *requestQueue) acquire(ctx context.Context, maxWait time.Duration) error {
+	q.mu.Lock()
+	if q.active < q.limit && q.waiters.Len() == 0 {
+		q.active++
+		q.mu.Unlock()
+		return nil
+	}
+	if q.waiters.Len() >= q.depth {
+		q.mu.Unlock()
+		return errRequestQueueFull
+	}
+	ready := make(chan struct{})
+	elem := q.waiters.PushBack(ready)
+	q.mu.Unlock()
+
+	timer := time.NewTimer(maxWait)
+	defer timer.Stop()
+
+	var waitErr error
+	select {
+	case <-ready:
+		return nil
+	case <-timer.C:
+		waitErr = errRequestQueueTimeout
+	case <-ctx.Done():
+		waitErr = ctx.Err()
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	select {
+	case <-ready:
+		// 超时的同时槽位已移交过来，转交给下一个等待者
+		q.releaseLocked()
+	default:
+		q.waiters.Remove(elem)
+	}
+	return waitErr
+}
+
+// release 归还槽位，有等待者时直接移交给队首
+func (q *requestQueue) release() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.releaseLocked()
+}
+
+func (q *requestQueue) releaseLocked() {
+	q.active--
+	if q.active < q.limit && q.waiters.Len() > 0 {
+		q.grantFrontLocked()
+	}
+}
+
+func (q *requestQueue) grantFrontLocked() {
+	front := q.waiters.Front()
+	q.waiters.Remove(front)
+	q.active++
+	close(front.Value.(chan struct{}))
+}
+
+// getRequestQueueSettings 读取 server.request_queue_concurrency / request_queue_depth / request_queue_max_wait_ms
+func (a *App) getRequestQueueSettings() requestQueueSettings {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	settings := requestQueueSettings{Depth: defaultRequestQueueDepth, MaxWait: defaultRequestQueueMaxWait}
+	if a.config == nil {
+		return settings
+	}
+	server, ok := a.config["server"].(map[string]interface{})
+	if !ok {
+		return settings
+	}
+
+	readInt := func(key string) (int, bool) {
+		switch v := server[key].(type) {
+		case float64:
+			return int(v), true
+		case int:
+			return v, true
+		case int64:
+			return int(v), true
+		case string:
+			if parsed, err := strconv.Atoi(strings.TrimSpace(v)); err == nil {
+				return parsed, true
+			}
+		}
+		return 0, false
+	}
+
+	if v, ok := readInt("request_queue_concurrency"); ok && v > 0 {
+		settings.Concurrency = v
+	}
+	if v, ok := readInt("request_queue_depth"); ok && v >= 0 {
+		settings.Depth = v
+	}
+	if v, ok := readInt("request_queue_max_wait_ms"); ok && v > 0 {
+		settings.MaxWait = time.Duration(v) * time.Millisecond
+	}
+	return settings
+}
+
+// acquireRequestQueueSlot 在转发前为端点获取队列槽位；队列未启用时返回空操作的 release
+func (a *App) acquireRequestQueueSlot(ctx context.Context, endpointName string) (func(), error) {
+	settings := a.getRequestQueueSettings()
+	if settings.Concurrency <= 0 {
+		return func() {}, nil
+	}
+
+	a.requestQueuesMu.Lock()
+	if a.requestQueues == nil {
+		a.requestQueues = make(map[string]*requestQueue)
+	}
+	queue, ok := a.requestQueues[endpointName]
+	if !ok {
+		queue = newRequestQueue(settings.Concurrency, settings.Depth)
+		a.requestQueues[endpointName] = queue
+	}
+	a.requestQueuesMu.Unlock()
+	if ok {
+		queue.resize(settings.Concurrency, settings.Depth)
+	}
+
+	if err := queue.acquire(ctx, settings.MaxWait); err != nil {
+		return nil, err
+	}
+	var once sync.Once
+	return func() { once.Do(queue.release) }, nil
+}
+
+// requestBudgetWindow max_requests_per_minute 的滑动窗口长度
+const requestBudgetWindow = time.Minute
+
+// pruneRequestBudget 丢弃滑动窗口之外的请求时间，调用方需持有 a.requestBudgetsMu
+func (a *App) pruneRequestBudget(endpointName string, now time.Time) []time.Time {
+	sent := a.requestBudgets[endpointName]
+	cutoff := now.Add(-requestBudgetWindow)
+	keep := 0
+	for keep < len(sent) && !sent[keep].After(cutoff) {
+		keep++
+	}
+	sent = sent[keep:]
+	if len(sent) == 0 {
+		delete(a.requestBudgets, endpointName)
+		return nil
+	}
+	a.requestBudgets[endpointName] = sent
+	return sent
+}
+
+// reserveRequestBudget 在端点的每分钟请求预算内占用一次额度；limit 为 0 表示不限制，额度用尽时返回 false
+func (a *App) reserveRequestBudget(endpointName string, limit int, now time.Time) bool {
+	if limit <= 0 {
+		return true
+	}
+
+	a.requestBudgetsMu.Lock()
+	defer a.requestBudgetsMu.Unlock()
+	if a.requestBudgets == nil {
+		a.requestBudgets = make(map[string][]time.Time)
+	}
+	sent := a.pruneRequestBudget(endpointName, now)
+	if len(sent) >= limit {
+		return false
+	}
+	a.requestBudgets[endpointName] = append(sent, now)
+	return true
+}
+
+// requestBudgetStatus 返回端点当前剩余的每分钟请求额度，以及最早一次请求滑出窗口（额度恢复）前的时间
+func (a *App) requestBudgetStatus(endpointName string, limit int, now time.Time) (int, time.Duration) {
+	if limit <= 0 {
+		return 0, 0
+	}
+
+	a.requestBudgetsMu.Lock()
+	defer a.requestBudgetsMu.Unlock()
+	if a.requestBudgets == nil {
+		return limit, 0
+	}
+	sent := a.pruneRequestBudget(endpointName, now)
+	remaining := limit - len(sent)
+	if remaining < 0 {
+		remaining = 0
+	}
+	var resetIn time.Duration
+	if len(sent) > 0 {
+		resetIn = sent[0].Add(requestBudgetWindow).Sub(now)
+	}
+	return remaining, resetIn
+}
+
+// server.empty_response_action 可选值
+const (
+	emptyResponseActionPatch       = "patch"
+	emptyResponseActionRetry       = "retry"
+	emptyResponseActionPassthrough = "passthrough"
+)
+
+// defaultEmptyResponsePlaceholder 空文本块的默认占位符（server.empty_response_placeholder）
+const defaultEmptyResponsePlaceholder = "[Empty response from upstream]"
+
+// getEmptyResponseSettings 读取 server.empty_response_action（默认 patch，未知值按 patch 处理）与 server.empty_response_placeholder
+func (a *App) getEmptyResponseSettings() (string, string) {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	action, placeholder := emptyResponseActionPatch, defaultEmptyResponsePlaceholder
+	if a.config == nil {
+		return action, placeholder
+	}
+	server, ok := a.config["server"].(map[string]interface{})
+	if !ok {
+		return action, placeholder
+	}
+
+	if value, ok := server["empty_response_action"].(string); ok {
+		switch normalized := strings.ToLower(strings.TrimSpace(value)); normalized {
+		case emptyResponseActionRetry, emptyResponseActionPassthrough:
+			action = normalized
+		}
+	}
+	if value, ok := server["empty_response_placeholder"].(string); ok && value != "" {
+		placeholder = value
+	}
+	return action, placeholder
+}
+
+// isErrorIn2xxDetectionEnabled 读取 server.detect_error_in_2xx，默认开启
+func (a *App) isErrorIn2xxDetectionEnabled() bool {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	if a.config == nil {
+		return true
+	}
+	server, ok := a.config["server"].(map[string]interface{})
+	if !ok {
+		return true
+	}
+	return extractBool(server["detect_error_in_2xx"], true)
+}
+
+// getRoutingRules 读取 server.routing_rules；缺少条件或动作（model/tag）的规则被忽略
+func (a *App) getRoutingRules() []utils.RoutingRule {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	server, ok := a.config["server"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	rawRules, ok := server["routing_rules"].([]interface{})
+	if !ok {
+		return nil
+	}
+
+	rules := make([]utils.RoutingRule, 0, len(rawRules))
+	for i, raw := range rawRules {
+		item, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		rule := utils.RoutingRule{
+			Name:        strings.TrimSpace(getStringFromMap(item, "name")),
+			MinTools:    extractNonNegativeInt(item["min_tools"]),
+			MinMessages: extractNonNegativeInt(item["min_messages"]),
+			MinTokens:   extractNonNegativeInt(item["min_tokens"]),
+			Model:       strings.TrimSpace(getStringFromMap(item, "model")),
+			Tag:         strings.TrimSpace(getStringFromMap(item, "tag")),
+		}
+		if !rule.HasCondition() || !rule.HasAction() {
+			continue
+		}
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("#%d", i+1)
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// matchRoutingRule 按 server.routing_rules 匹配请求，命中时记录规则名、条件与实际请求特征
+func (a *App) matchRoutingRule(body []byte) (utils.RoutingRule, bool) {
+	rules := a.getRoutingRules()
+	if len(rules) == 0 {
+		return utils.RoutingRule{}, false
+	}
+
+	characteristics := utils.MeasureRequest(body)
+	rule, matched := utils.MatchRoutingRule(rules, characteristics)
+	if !matched {
+		return rule, false
+	}
+
+	var actions []string
+	if rule.Model != "" {
+		actions = append(actions, "model="+rule.Model)
+	}
+	if rule.Tag != "" {
+		actions = append(actions, "tag="+rule.Tag)
+	}
+	msg := fmt.Sprintf("路由规则 %s 命中 (%s; tools=%d messages=%d tokens≈%d) -> %s",
+		rule.Name, rule.Conditions(), characteristics.Tools, characteristics.Messages, characteristics.ApproxTokens, strings.Join(actions, ", "))
+	runtime.LogInfo(a.ctx, msg)
+	a.addLog("info", msg)
+	return rule, true
+}
+
+// isServerTimingEnabled 读取 server.server_timing，默认关闭（开启后代理响应附加 Server-Timing 头）
+func (a *App) isServerTimingEnabled() bool {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	server, ok := a.config["server"].(map[string]interface{})
+	if !ok {
+		return false
+	}
+	return extractBool(server["server_timing"], false)
+}
+
+// isModelOverrideHeaderEnabled 读取 server.allow_model_override_header，默认关闭（开启后允许 X-CCCC-Model 请求头覆盖模型）
+func (a *App) isModelOverrideHeaderEnabled() bool {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	if a.config == nil {
+		return false
+	}
+	server, ok := a.config["server"].(map[string]interface{})
+	if !ok {
+		return false
+	}
+	return extractBool(server["allow_model_override_header"], false)
+}
+
+// upstreamResponseFormat 按上游 URL 推断响应格式：anthropic | openai | responses | gemini，无法判断时返回空串
+func upstreamResponseFormat(targetURL string) string {
+	path := targetURL
+	if parsed, err := url.Parse(targetURL); err == nil {
+		path = parsed.Path
+	}
+	switch {
+	case strings.HasSuffix(path, "/messages") || strings.HasSuffix(path, "/complete"):
+		return "anthropic"
+	case strings.HasSuffix(path, "/responses"):
+		return "responses"
+	case strings.HasSuffix(path, "/chat/completions") || strings.HasSuffix(path, "/completions"):
+		return "openai"
+	case strings.Contains(path, ":generateContent"):
+		return "gemini"
+	}
+	return ""
+}
+
+// detectErrorBodyIn2xx 判断 2xx 响应体是否实为顶层 error 对象；
+// 按格式排除正常响应（Anthropic message、Chat choices、Responses response 对象、Gemini candidates），返回错误信息
+func detectErrorBodyIn2xx(body []byte, format string) (string, bool) {
+	var payload map[string]interface{}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return "", false
+	}
+
+	var message string
+	switch errField := payload["error"].(type) {
+	case map[string]interface{}:
+		if msg, ok := errField["message"].(string); ok {
+			message = msg
+		} else if errType, ok := errField["type"].(string); ok {
+			message = errType
+		}
+	case string:
+		if strings.TrimSpace(errField) == "" {
+			return "", false
+		}
+		message = errField
+	default:
+		return "", false
+	}
+
+	_, hasChoices := payload["choices"]
+	_, hasCandidates := payload["candidates"]
+	isMessage := payload["type"] == "message"
+	isResponse := payload["object"] == "response"
+	switch format {
+	case "anthropic":
+		if isMessage {
+			return "", false
+		}
+	case "openai":
+		if hasChoices {
+			return "", false
+		}
+	case "responses":
+		// Responses 对象自带 error 字段（失败时由 status 表示），不视为代理错误
+		if isResponse {
+			return "", false
+		}
+	case "gemini":
+		if hasCandidates {
+			return "", false
+		}
+	default:
+		if isMessage || hasChoices || isResponse || hasCandidates {
+			return "", false
+		}
+	}
+
+	if strings.TrimSpace(message) == "" {
+		message = "upstream returned error object"
+	}
+	return message, true
+}
+
+// isEmptyAnthropicResponse 判断 Anthropic message 响应是否完全为空：content 为空，或所有块都是空文本块
+func isEmptyAnthropicResponse(body []byte) bool {
+	var resp map[string]interface{}
+	if err := json.Unmarshal(body, &resp); err != nil || resp["type"] != "message" {
+		return false
+	}
+	content, ok := resp["content"].([]interface{})
+	if !ok {
+		return resp["content"] == nil
+	}
+	for _, block := range content {
+		blockMap, ok := block.(map[string]interface{})
+		if !ok || blockMap["type"] != "text" {
+			return false
+		}
+		if text, _ := blockMap["text"].(string); text != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// patchEmptyAnthropicText 为 Anthropic message 响应中缺失或为空的 text 字段填充占位符，避免 Claude Code 报错
+func patchEmptyAnthropicText(body []byte, placeholder string) ([]byte, bool) {
+	var resp map[string]interface{}
+	if err := json.Unmarshal(body, &resp); err != nil || resp["type"] != "message" {
+		return body, false
+	}
+	content, ok := resp["content"].([]interface{})
+	if !ok {
+		return body, false
+	}
+
+	fixed := false
+	for _, block := range content {
+		if blockMap, ok := block.(map[string]interface{}); ok && blockMap["type"] == "text" {
+			if textVal, hasText := blockMap["text"]; !hasText || textVal == "" {
+				blockMap["text"] = placeholder
+				fixed = true
+			}
+		}
+	}
+	if !fixed {
+		return body, false
+	}
+	fixedBody, err := json.Marshal(resp)
+	if err != nil {
+		return body, false
+	}
+	return fixedBody, true
+}
+
+// errResponseBodyTooLarge 上游响应体超过 server.max_response_body_bytes
+var errResponseBodyTooLarge = errors.New("response body exceeds max_response_body_bytes")
+
+// getMaxResponseBodyBytes 读取 server.max_response_body_bytes，0 表示不限制
+func (a *App) getMaxResponseBodyBytes() int64 {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	if a.config == nil {
+		return 0
+	}
+	server, ok := a.config["server"].(map[string]interface{})
+	if !ok {
+		return 0
+	}
+
+	var limit int64
+	switch v := server["max_response_body_bytes"].(type) {
+	case float64:
+		limit = int64(v)
+	case int:
+		limit = int64(v)
+	case int64:
+		limit = v
+	case string:
+		if parsed, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64); err == nil {
+			limit = parsed
+		}
+	}
+	if limit < 0 {
+		limit = 0
+	}
+	return limit
+}
+
+// readResponseBodyCapped 读取响应体，超过 limit 字节时返回前 limit 字节与 errResponseBodyTooLarge（limit<=0 不限制）
+func readResponseBodyCapped(reader io.Reader, limit int64) ([]byte, error) {
+	if limit <= 0 {
+		return io.ReadAll(reader)
+	}
+	data, err := io.ReadAll(io.LimitReader(reader, limit+1))
+	if err != nil {
+		return data, err
+	}
+	if int64(len(data)) > limit {
+		return data[:limit], errResponseBodyTooLarge
+	}
+	return data, nil
+}
+
+// decompressGzipCapped 解压 gzip 响应体，解压后的大小同样受 limit 约束，防止压缩炸弹
+func decompressGzipCapped(body []byte, limit int64) ([]byte, error) {
+	gzReader, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer gzReader.Close()
+	return readResponseBodyCapped(gzReader, limit)
+}
+
+// errResponseTruncated 非流式响应体在读取完成前被截断（重试后仍然截断）
+var errResponseTruncated = errors.New("upstream response truncated")
+
+// errorCategoryTruncated 请求日志 ErrorCategory：响应被截断，不计入端点健康统计
+const errorCategoryTruncated = "truncated"
+
+// errorCategoryClientCanceled 请求日志 ErrorCategory：客户端在响应完成前断开，不计入端点健康统计
+const errorCategoryClientCanceled = "client_canceled"
+
+// statusClientClosedRequest 客户端取消请求时记录的状态码（沿用 nginx 的 499 Client Closed Request）
+const statusClientClosedRequest = 499
+
+// skipsEndpointOutcome 判断该类别的请求日志是否跳过端点成功率、业务错误率与最近错误等统计
+func skipsEndpointOutcome(category string) bool {
+	return category == errorCategoryTruncated || category == errorCategoryClientCanceled
+}
+
+// truncatedResponseReason 判断非流式响应体是否因连接中断被截断，返回原因；未截断或属于其他错误时返回空串
+func truncatedResponseReason(body []byte, contentLength int64, readErr error) string {
+	if errors.Is(readErr, io.ErrUnexpectedEOF) {
+		return "连接在响应体读取完成前断开"
+	}
+	if readErr != nil {
+		return ""
+	}
+	if !validator.IsTruncatedJSON(body, contentLength) {
+		return ""
+	}
+	if contentLength > 0 && int64(len(body)) < contentLength {
+		return fmt.Sprintf("读取 %d 字节，Content-Length 为 %d", len(body), contentLength)
+	}
+	return "JSON 未以 } 或 ] 结尾"
+}
+
+// readErrorCategory 返回读取响应体错误对应的日志 ErrorCategory
+func readErrorCategory(readErr error) string {
+	if errors.Is(readErr, errResponseTruncated) {
+		return errorCategoryTruncated
+	}
+	return ""
+}
+
+// errGzipDecompressFailed 上游声明/看起来是 gzip 但无法完整解压（常见于连接中断导致的截断）
+var errGzipDecompressFailed = errors.New("gzip decompression failed")
+
+// decodeGzipBody 检测 gzip 魔数并解压；非 gzip 数据原样返回。
+// 解压失败时返回包装了 errGzipDecompressFailed 的错误，超过 limit 时返回已解压部分与 errResponseBodyTooLarge
+func decodeGzipBody(body []byte, limit int64) ([]byte, error) {
+	if len(body) <= 2 || body[0] != 0x1f || body[1] != 0x8b {
+		return body, nil
+	}
+	decompressed, err := decompressGzipCapped(body, limit)
+	if err == nil || errors.Is(err, errResponseBodyTooLarge) {
+		return decompressed, err
+	}
+	return body, fmt.Errorf("%w: %v", errGzipDecompressFailed, err)
+}
+
+// trimToLastSSEEvent 截断到最后一个完整的 SSE 事件（以空行结尾）
+func trimToLastSSEEvent(body []byte) []byte {
+	if idx := bytes.LastIndex(body, []byte("\n\n")); idx >= 0 {
+		return body[:idx+2]
+	}
+	return body[:0]
+}
+
+// requiredSSEEvents 客户端依赖的协议事件，即使匹配 sse_event_filter 也不会被丢弃
+var requiredSSEEvents = map[string]bool{
+	"message_start":                          true,
+	"content_block_start":                    true,
+	"content_block_delta":                    true,
+	"content_block_stop":                     true,
+	"message_delta":                          true,
+	"message_stop":                           true,
+	"error":                                  true,
+	"response.created":                       true,
+	"response.in_progress":                   true,
+	"response.output_item.added":             true,
+	"response.output_item.done":              true,
+	"response.content_part.added":            true,
+	"response.content_part.done":             true,
+	"response.output_text.delta":             true,
+	"response.output_text.done":              true,
+	"response.function_call_arguments.delta": true,
+	"response.function_call_arguments.done":  true,
+	"response.completed":                     true,
+	"response.failed":                        true,
+	"response.incomplete":                    true,
+}
+
+// sseEventType 返回 SSE 事件的类型：优先取 event 行，否则取 data JSON 中的 type 字段
+func sseEventType(event string) string {
+	data := ""
+	for _, line := range strings.Split(event, "\n") {
+		line = strings.TrimRight(line, "\r")
+		switch {
+		case strings.HasPrefix(line, "event:"):
+			return strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:") && data == "":
+			data = strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		}
+	}
+	var payload struct {
+		Type string `json:"type"`
+	}
+	if data != "" && json.Unmarshal([]byte(data), &payload) == nil {
+		return payload.Type
+	}
+	return ""
+}
+
+// matchSSEEventPattern 判断事件类型是否匹配过滤规则，规则以 * 结尾时按前缀匹配
+func matchSSEEventPattern(pattern, eventType string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+		return strings.HasPrefix(eventType, prefix)
+	}
+	return pattern == eventType
+}
+
+// filterSSEEvents 丢弃类型匹配 patterns 的 SSE 事件（requiredSSEEvents 除外），返回过滤后的流与丢弃数量
+func filterSSEEvents(body []byte, patterns []string) ([]byte, int) {
+	if len(patterns) == 0 || len(body) == 0 {
+		return body, 0
+	}
+
+	events := strings.Split(strings.ReplaceAll(string(body), "\r\n", "\n"), "\n\n")
+	kept := make([]string, 0, len(events))
+	dropped := 0
+	for _, event := range events {
+		eventType := sseEventType(event)
+		drop := false
+		if eventType != "" && !requiredSSEEvents[eventType] {
+			for _, pattern := range patterns {
+				if matchSSEEventPattern(pattern, eventType) {
+					drop = true
+					break
+				}
+			}
+		}
+		if drop {
+			dropped++
+			continue
+		}
+		kept = append(kept, event)
+	}
+	if dropped == 0 {
+		return body, 0
+	}
+	return []byte(strings.Join(kept, "\n\n")), dropped
+}
+
+// serialiseSSEEventFilter 校验并序列化端点 sse_event_filter（事件类型列表，支持 prefix* 通配）
+func serialiseSSEEventFilter(raw interface{}) (string, error) {
+	patterns, err := parseStringSlice(raw)
+	if err != nil {
+		return "", err
+	}
+	for _, pattern := range patterns {
+		if pattern == "*" || strings.ContainsAny(pattern, " \t\r\n") {
+			return "", fmt.Errorf("invalid event type pattern %q", pattern)
+		}
+	}
+	return serialiseStringSlice(patterns, "[]")
+}
+
+const (
+	// successRateMinSamples 样本不足时成功率按 1.0 处理，避免冷启动误伤
+	successRateMinSamples = 5
+	// successRateMinWeight 权重下限，保证失败中的端点仍有少量流量以便恢复
+	successRateMinWeight = 0.05
+	maxSuccessRateWindow = 1000
+)
+
+// endpointOutcomeWindow 记录端点最近 N 次请求结果的滑动窗口
+type endpointOutcomeWindow struct {
+	outcomes []bool
+	next     int
+	filled   bool
+}
+
+func (w *endpointOutcomeWindow) record(success bool, size int) {
+	if len(w.outcomes) != size {
+		// 窗口大小变化时保留最近的结果
+		recent := w.snapshot()
+		if len(recent) > size {
+			recent = recent[len(recent)-size:]
+		}
+		w.outcomes = make([]bool, size)
+		copy(w.outcomes, recent)
+		w.next = len(recent) % size
+		w.filled = len(recent) == size
+	}
+	w.outcomes[w.next] = success
+	w.next = (w.next + 1) % size
+	if w.next == 0 {
+		w.filled = true
+	}
+}
+
+// snapshot 按时间顺序返回窗口内的结果
+func (w *endpointOutcomeWindow) snapshot() []bool {
+	if !w.filled {
+		return append([]bool(nil), w.outcomes[:w.next]...)
+	}
+	return append(append([]bool(nil), w.outcomes[w.next:]...), w.outcomes[:w.next]...)
+}
+
+// successRate 返回窗口内成功率与样本数
+func (w *endpointOutcomeWindow) successRate() (float64, int) {
+	samples := w.snapshot()
+	if len(samples) == 0 {
+		return 1, 0
+	}
+	success := 0
+	for _, ok := range samples {
+		if ok {
+			success++
+		}
+	}
+	return float64(success) / float64(len(samples)), len(samples)
+}
+
+// getSuccessRateWindow 读取 server.success_rate_window（最近请求数），0 表示不按成功率调整端点顺序
+func (a *App) getSuccessRateWindow() int {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	if a.config == nil {
+		return 0
+	}
+	server, ok := a.config["server"].(map[string]interface{})
+	if !ok {
+		return 0
+	}
+
+	window := 0
+	switch v := server["success_rate_window"].(type) {
+	case float64:
+		window = int(v)
+	case int:
+		window = v
+	case int64:
+		window = int(v)
+	case string:
+		if parsed, err := strconv.Atoi(strings.TrimSpace(v)); err == nil {
+			window = parsed
+		}
+	}
+	if window < 0 {
+		window = 0
+	}
+	if window > maxSuccessRateWindow {
+		window = maxSuccessRateWindow
+	}
+	return window
+}
+
+// recordEndpointOutcome 将一次端点请求结果写入滑动窗口（5xx、429 与网络错误计为失败）
+func (a *App) recordEndpointOutcome(endpointName string, statusCode int) {
+	if endpointName == "" {
+		return
+	}
+	success := statusCode > 0 && statusCode < http.StatusInternalServerError && statusCode != http.StatusTooManyRequests
+
+	disableAfter, _ := a.getAutoDisableSettings()
+	a.mutex.RLock()
+	db := a.db
+	a.mutex.RUnlock()
+	a.trackEndpointHealth(db, endpointName, success, disableAfter)
+
+	window := a.getSuccessRateWindow()
+	if window <= 0 {
+		return
+	}
+
+	a.endpointOutcomesMu.Lock()
+	defer a.endpointOutcomesMu.Unlock()
+	if a.endpointOutcomes == nil {
+		a.endpointOutcomes = make(map[string]*endpointOutcomeWindow)
+	}
+	outcomes, ok := a.endpointOutcomes[endpointName]
+	if !ok {
+		outcomes = &endpointOutcomeWindow{}
+		a.endpointOutcomes[endpointName] = outcomes
+	}
+	outcomes.record(success, window)
+}
+
+// endpointSelectionWeight 返回端点的选择权重：近期成功率，样本不足时为 1，且不低于 successRateMinWeight
+func (a *App) endpointSelectionWeight(endpointName string) float64 {
+	a.endpointOutcomesMu.Lock()
+	outcomes, ok := a.endpointOutcomes[endpointName]
+	var rate float64 = 1
+	samples := 0
+	if ok {
+		rate, samples = outcomes.successRate()
+	}
+	a.endpointOutcomesMu.Unlock()
+
+	if samples < successRateMinSamples {
+		return 1
+	}
+	if rate < successRateMinWeight {
+		return successRateMinWeight
+	}
+	return rate
+}
+
+// orderEndpointsBySuccessRate 在同优先级端点之间按近期成功率加权随机排序（优先级顺序保持不变）
+func (a *App) orderEndpointsBySuccessRate(endpoints []config.EndpointConfig) []config.EndpointConfig {
+	if a.getSuccessRateWindow() <= 0 || len(endpoints) < 2 {
+		return endpoints
+	}
+
+	ordered := append([]config.EndpointConfig(nil), endpoints...)
+	for start := 0; start < len(ordered); {
+		end := start + 1
+		for end < len(ordered) && ordered[end].Priority == ordered[start].Priority {
+			end++
+		}
+		if end-start > 1 {
+			// 加权随机排列（Efraimidis-Spirakis）：key = u^(1/w)，按 key 降序
+			group := ordered[start:end]
+			keys := make(map[string]float64, len(group))
+			for _, ep := range group {
+				keys[ep.Name] = math.Pow(rand.Float64(), 1/a.endpointSelectionWeight(ep.Name))
+			}
+			sort.SliceStable(group, func(i, j int) bool {
+				return keys[group[i].Name] > keys[group[j].Name]
+			})
+		}
+		start = end
+	}
+	return ordered
+}
+
+const (
+	// businessErrorDemotionMinSamples 时间窗口内样本不足时不降级，避免偶发业务错误误伤
+	businessErrorDemotionMinSamples    = 5
+	defaultBusinessErrorDemotionWindow = 10 * time.Minute
+)
+
+// businessErrorOutcome 一次端点响应是否为业务错误
+type businessErrorOutcome struct {
+	at       time.Time
+	business bool
+}
+
+// businessErrorTracker 记录端点在时间窗口内的业务错误情况，以及当前是否处于降级状态
+type businessErrorTracker struct {
+	outcomes []businessErrorOutcome
+	demoted  bool
+}
+
+// prune 丢弃窗口之外的结果
+func (t *businessErrorTracker) prune(now time.Time, window time.Duration) {
+	cutoff := now.Add(-window)
+	keep := 0
+	for keep < len(t.outcomes) && t.outcomes[keep].at.Before(cutoff) {
+		keep++
+	}
+	t.outcomes = t.outcomes[keep:]
+}
+
+// rate 返回窗口内业务错误率与样本数
+func (t *businessErrorTracker) rate() (float64, int) {
+	if len(t.outcomes) == 0 {
+		return 0, 0
+	}
+	business := 0
+	for _, outcome := range t.outcomes {
+		if outcome.business {
+			business++
+		}
+	}
+	return float64(business) / float64(len(t.outcomes)), len(t.outcomes)
+}
+
+// isBusinessErrorStatus 判断上游状态码是否属于业务错误：端点可达且工作正常，但拒绝了请求内容
+// （如 400 上下文过长、413、422）；鉴权、限流与超时类 4xx 不计入
+func isBusinessErrorStatus(statusCode int) bool {
+	if statusCode < http.StatusBadRequest || statusCode >= http.StatusInternalServerError {
+		return false
+	}
+	switch statusCode {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusProxyAuthRequired,
+		http.StatusRequestTimeout, http.StatusTooManyRequests:
+		return false
+	}
+	return true
+}
+
+// getBusinessErrorDemotionSettings 读取 server.business_error_demotion_threshold（业务错误率阈值 0~1，0 表示关闭）
+// 与 server.business_error_demotion_window_minutes（统计窗口，默认 10 分钟）
+func (a *App) getBusinessErrorDemotionSettings() (float64, time.Duration) {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	if a.config == nil {
+		return 0, 0
+	}
+	server, ok := a.config["server"].(map[string]interface{})
+	if !ok {
+		return 0, 0
+	}
+
+	threshold := 0.0
+	switch v := server["business_error_demotion_threshold"].(type) {
+	case float64:
+		threshold = v
+	case int:
+		threshold = float64(v)
+	case int64:
+		threshold = float64(v)
+	case string:
+		if parsed, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+			threshold = parsed
+		}
+	}
+	if threshold <= 0 {
+		return 0, 0
+	}
+	if threshold > 1 {
+		threshold = 1
+	}
+
+	window := defaultBusinessErrorDemotionWindow
+	switch v := server["business_error_demotion_window_minutes"].(type) {
+	case float64:
+		if v > 0 {
+			window = time.Duration(v * float64(time.Minute))
+		}
+	case int:
+		if v > 0 {
+			window = time.Duration(v) * time.Minute
+		}
+	case int64:
+		if v > 0 {
+			window = time.Duration(v) * time.Minute
+		}
+	case string:
+		if parsed, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil && parsed > 0 {
+			window = time.Duration(parsed * float64(time.Minute))
+		}
+	}
+	return threshold, window
+}
+
+// recordBusinessErrorOutcome 记录一次端点响应并重新评估降级状态；返回评估后的降级状态、
+// 状态是否发生变化以及当前业务错误率。未开启降级时不记录
+func (a *App) recordBusinessErrorOutcome(endpointName string, statusCode int, now time.Time) (bool, bool, float64) {
+	threshold, window := a.getBusinessErrorDemotionSettings()
+	if endpointName == "" || threshold <= 0 {
+		return false, false, 0
+	}
+
+	a.businessErrorsMu.Lock()
+	defer a.businessErrorsMu.Unlock()
+	if a.businessErrors == nil {
+		a.businessErrors = make(map[string]*businessErrorTracker)
+	}
+	tracker, ok := a.businessErrors[endpointName]
+	if !ok {
+		tracker = &businessErrorTracker{}
+		a.businessErrors[endpointName] = tracker
+	}
+
+	tracker.outcomes = append(tracker.outcomes, businessErrorOutcome{at: now, business: isBusinessErrorStatus(statusCode)})
+	tracker.prune(now, window)
+	rate, samples := tracker.rate()
+
+	demoted := samples >= businessErrorDemotionMinSamples && rate >= threshold
+	changed := demoted != tracker.demoted
+	tracker.demoted = demoted
+	return demoted, changed, rate
+}
+
+// isEndpointDemoted 判断端点当前是否因业务错误过多而降级（窗口过期后自动恢复）
+func (a *App) isEndpointDemoted(endpointName string, now time.Time) bool {
+	threshold, window := a.getBusinessErrorDemotionSettings()
+	if threshold <= 0 {
+		return false
+	}
+
+	a.businessErrorsMu.Lock()
+	defer a.businessErrorsMu.Unlock()
+	tracker, ok := a.businessErrors[endpointName]
+	if !ok || !tracker.demoted {
+		return false
+	}
+	tracker.prune(now, window)
+	rate, samples := tracker.rate()
+	if samples < businessErrorDemotionMinSamples || rate < threshold {
+		tracker.demoted = false
+	}
+	return tracker.demoted
+}
+
+// demoteBusinessErrorEndpoints 将降级端点的有效优先级整体下移到所有正常端点之后（不禁用端点），
+// 降级端点之间保持原有的相对优先级顺序
+func (a *App) demoteBusinessErrorEndpoints(endpoints []config.EndpointConfig) []config.EndpointConfig {
+	if len(endpoints) < 2 {
+		return endpoints
+	}
+	if threshold, _ := a.getBusinessErrorDemotionSettings(); threshold <= 0 {
+		return endpoints
+	}
+
+	now := time.Now()
+	minPriority, maxPriority := endpoints[0].Priority, endpoints[0].Priority
+	demoted := make(map[string]bool)
+	for _, ep := range endpoints {
+		if ep.Priority < minPriority {
+			minPriority = ep.Priority
+		}
+		if ep.Priority > maxPriority {
+			maxPriority = ep.Priority
+		}
+		if a.isEndpointDemoted(ep.Name, now) {
+			demoted[ep.Name] = true
+		}
+	}
+	if len(demoted) == 0 || len(demoted) == len(endpoints) {
+		return endpoints
+	}
+
+	shift := maxPriority - minPriority + 1
+	ordered := append([]config.EndpointConfig(nil), endpoints...)
+	for i := range ordered {
+		if demoted[ordered[i].Name] {
+			ordered[i].Priority -= shift
+		}
+	}
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ordered[i].Priority > ordered[j].Priority
+	})
+	return ordered
+}
+
+// getAutoDisableSettingsNoLock 读取 server.auto_disable_after_minutes（0 表示不自动禁用）与
+// server.auto_reenable_on_health_check（默认关闭），调用方需持有 a.mutex
+func (a *App) getAutoDisableSettingsNoLock() (time.Duration, bool) {
+	if a.config == nil {
+		return 0, false
+	}
+	server, ok := a.config["server"].(map[string]interface{})
+	if !ok {
+		return 0, false
+	}
+
+	minutes := 0.0
+	switch v := server["auto_disable_after_minutes"].(type) {
+	case float64:
+		minutes = v
+	case int:
+		minutes = float64(v)
+	case int64:
+		minutes = float64(v)
+	case string:
+		if parsed, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+			minutes = parsed
+		}
+	}
+	if minutes < 0 {
+		minutes = 0
+	}
+	reenable, _ := server["auto_reenable_on_health_check"].(bool)
+	return time.Duration(minutes * float64(time.Minute)), reenable
+}
+
+func (a *App) getAutoDisableSettings() (time.Duration, bool) {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+	return a.getAutoDisableSettingsNoLock()
+}
+
+// trackEndpointHealth 记录端点连续不健康的起始时间，持续超过 disableAfter 时在数据库中禁用该端点
+func (a *App) trackEndpointHealth(db *sql.DB, endpointName string, healthy bool, disableAfter time.Duration) {
+	a.unhealthySinceMu.Lock()
+	if a.unhealthySince == nil {
+		a.unhealthySince = make(map[string]time.Time)
+	}
+	if healthy || disableAfter <= 0 {
+		delete(a.unhealthySince, endpointName)
+		a.unhealthySinceMu.Unlock()
+		return
+	}
+	since, tracked := a.unhealthySince[endpointName]
+	if !tracked {
+		a.unhealthySince[endpointName] = time.Now()
+		a.unhealthySinceMu.Unlock()
+		return
+	}
+	if time.Since(since) < disableAfter {
+		a.unhealthySinceMu.Unlock()
+		return
+	}
+	delete(a.unhealthySince, endpointName)
+	a.unhealthySinceMu.Unlock()
+
+	if db == nil {
+		return
+	}
+	now := getCurrentTimestamp()
+	result, err := db.Exec(`
+		UPDATE endpoints
+		SET enabled = 0, auto_disabled = 1, status = 'unhealthy', updated_at = ?
+		WHERE name = ? AND enabled = 1
+	`, now, endpointName)
+	if err != nil {
+		a.addLog("error", fmt.Sprintf("自动禁用端点 %s 失败: %v", endpointName, err))
+		return
+	}
+	if affected, _ := result.RowsAffected(); affected > 0 {
+		a.addLog("warn", fmt.Sprintf("端点 %s 自 %s 起持续不健康超过 %v，已自动禁用", endpointName, since.Format(time.RFC3339), disableAfter))
+	}
+}
+
+// maxEndpointLastErrorLength last_error 的最大长度（字符数）
+const maxEndpointLastErrorLength = 200
+
+// summarizeEndpointError 生成适合在端点列表中直接展示的失败原因：
+// 有具体错误信息时取最内层原因（如 "connection refused"），否则使用状态码（如 "401 Unauthorized"）
+func summarizeEndpointError(statusCode int, errMsg string) string {
+	errMsg = strings.TrimSpace(errMsg)
+	if errMsg == "" || strings.HasPrefix(errMsg, "upstream returned") {
+		if statusCode <= 0 {
+			return errMsg
+		}
+		return strings.TrimSpace(fmt.Sprintf("%d %s", statusCode, http.StatusText(statusCode)))
+	}
+
+	if idx := strings.LastIndex(errMsg, ": "); idx >= 0 && strings.TrimSpace(errMsg[idx+2:]) != "" {
+		errMsg = strings.TrimSpace(errMsg[idx+2:])
+	}
+	if runes := []rune(errMsg); len(runes) > maxEndpointLastErrorLength {
+		errMsg = string(runes[:maxEndpointLastErrorLength]) + "..."
+	}
+	return errMsg
+}
+
+// recordEndpointLastError 将最近一次失败原因写入端点行（健康检查与代理失败共用）
+func (a *App) recordEndpointLastError(db *sql.DB, endpointName, summary string) {
+	if db == nil || endpointName == "" || summary == "" {
+		return
+	}
+	if _, err := db.Exec(`
+		UPDATE endpoints
+		SET last_error = ?, last_error_at = ?
+		WHERE name = ?
+	`, summary, getCurrentTimestamp(), endpointName); err != nil {
+		a.addLog("warn", fmt.Sprintf("记录端点 %s 最近错误失败: %v", endpointName, err))
+	}
+}
+
+// reenableAutoDisabledEndpoint 健康检查成功后重新启用被自动禁用的端点，手动禁用的端点不受影响
+func (a *App) reenableAutoDisabledEndpoint(db *sql.DB, id, endpointName string) {
+	if db == nil {
+		return
+	}
+	result, err := db.Exec(`
+		UPDATE endpoints
+		SET enabled = 1, auto_disabled = 0, updated_at = ?
+		WHERE id = ? AND auto_disabled = 1
+	`, getCurrentTimestamp(), id)
+	if err != nil {
+		a.addLog("error", fmt.Sprintf("重新启用端点 %s 失败: %v", endpointName, err))
+		return
+	}
+	if affected, _ := result.RowsAffected(); affected > 0 {
+		a.addLog("info", fmt.Sprintf("端点 %s 健康检查通过，已自动重新启用", endpointName))
+	}
+}
+
+// healthGateRecheckInterval 启动健康闸门未满足时重新检测端点的间隔
+const healthGateRecheckInterval = 30 * time.Second
+
+// getMinHealthyEndpoints 读取 server.min_healthy_endpoints，默认0（不启用启动健康闸门）
+func (a *App) getMinHealthyEndpoints() int {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	if a.config == nil {
+		return 0
+	}
+	server, ok := a.config["server"].(map[string]interface{})
+	if !ok {
+		return 0
+	}
+
+	required := 0
+	switch v := server["min_healthy_endpoints"].(type) {
+	case float64:
+		required = int(v)
+	case int:
+		required = v
+	case int64:
+		required = int(v)
+	case string:
+		if parsed, err := strconv.Atoi(strings.TrimSpace(v)); err == nil {
+			required = parsed
+		}
+	}
+	if required < 0 {
+		required = 0
+	}
+	return required
+}
+
+// countHealthyEndpoints 统计已启用且状态为 healthy 的端点数量
+func countHealthyEndpoints(db *sql.DB) (int, error) {
+	if db == nil {
+		return 0, fmt.Errorf("database not available")
+	}
+	var count int
+	err := db.QueryRow(`SELECT COUNT(*) FROM endpoints WHERE enabled = 1 AND status = 'healthy'`).Scan(&count)
+	return count, err
+}
+
+// healthGateStatus 返回启动健康闸门状态；闸门一旦满足即保持打开，不会因后续端点故障再次暂停代理
+func (a *App) healthGateStatus() (open bool, healthy int, required int, reason string) {
+	required = a.getMinHealthyEndpoints()
+	// 未启用闸门时每个请求都会调用，跳过端点计数查询
+	if required <= 0 {
+		return true, 0, required, ""
+	}
+
+	// 闸门已打开后不再查询端点计数（healthy 返回 0），避免每个代理请求都访问数据库
+	a.healthGateMu.Lock()
+	gateOpen := a.healthGateOpen
+	a.healthGateMu.Unlock()
+	if gateOpen {
+		return true, 0, required, ""
+	}
+
+	a.mutex.RLock()
+	db := a.db
+	a.mutex.RUnlock()
+	healthy, countErr := countHealthyEndpoints(db)
+
+	a.healthGateMu.Lock()
+	defer a.healthGateMu.Unlock()
+	if a.healthGateOpen {
+		return true, healthy, required, ""
+	}
+	if !a.healthGateSweepDone {
+		return false, healthy, required, "startup health check is still running"
+	}
+	if countErr != nil {
+		return false, healthy, required, fmt.Sprintf("failed to count healthy endpoints: %v", countErr)
+	}
+	if healthy >= required {
+		a.healthGateOpen = true
+		return true, healthy, required, ""
+	}
+	return false, healthy, required, fmt.Sprintf("only %d of the required %d endpoints are healthy", healthy, required)
+}
+
+// runStartupHealthSweep 启动时（或运行中启用 server.min_healthy_endpoints 后）检测所有端点，
+// 健康端点数未达到要求时定期重测直到满足；同一时间只运行一轮
+func (a *App) runStartupHealthSweep() {
+	if a.getMinHealthyEndpoints() <= 0 {
+		return
+	}
+
+	a.healthGateMu.Lock()
+	if a.healthGateSweepRunning || a.healthGateOpen {
+		a.healthGateMu.Unlock()
+		return
+	}
+	a.healthGateSweepRunning = true
+	a.healthGateMu.Unlock()
+	defer func() {
+		a.healthGateMu.Lock()
+		a.healthGateSweepRunning = false
+		a.healthGateMu.Unlock()
+	}()
+
+	for {
+		// 运行中关闭了闸门设置时停止重测
+		if a.getMinHealthyEndpoints() <= 0 {
+			return
+		}
+		a.TestAllEndpoints()
+
+		a.healthGateMu.Lock()
+		a.healthGateSweepDone = true
+		a.healthGateMu.Unlock()
+
+		open, healthy, required, _ := a.healthGateStatus()
+		if open {
+			runtime.LogInfo(a.ctx, fmt.Sprintf("启动健康闸门已打开: %d/%d 个端点健康", healthy, required))
+			a.addLog("info", fmt.Sprintf("启动健康检查通过（%d/%d 个端点健康），代理开始服务", healthy, required))
+			return
+		}
+
+		runtime.LogWarning(a.ctx, fmt.Sprintf("健康端点不足 (%d/%d)，代理保持暂停，%v 后重新检测", healthy, required, healthGateRecheckInterval))
+		a.addLog("warn", fmt.Sprintf("健康端点不足（%d/%d），代理保持暂停", healthy, required))
+		time.Sleep(healthGateRecheckInterval)
+	}
+}
+
+const (
+	// modelsRefreshIdleInterval 未启用 server.models_refresh_minutes 时重新读取配置的间隔
+	modelsRefreshIdleInterval = time.Minute
+	// modelsFetchTimeout 单个端点拉取 /models 的超时
+	modelsFetchTimeout = 15 * time.Second
+)
+
+// endpointModelsEntry 端点上游 /models 的最近一次拉取结果
+type endpointModelsEntry struct {
+	models    []string
+	fetchedAt time.Time
+	err       string
+}
+
+// getModelsRefreshInterval 读取 server.models_refresh_minutes，默认0（不拉取上游模型列表，仅使用配置推断的模型）
+func (a *App) getModelsRefreshInterval() time.Duration {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	if a.config == nil {
+		return 0
+	}
+	server, ok := a.config["server"].(map[string]interface{})
+	if !ok {
+		return 0
+	}
+
+	minutes := 0.0
+	switch v := server["models_refresh_minutes"].(type) {
+	case float64:
+		minutes = v
+	case int:
+		minutes = float64(v)
+	case int64:
+		minutes = float64(v)
+	case string:
+		if parsed, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+			minutes = parsed
+		}
+	}
+	if minutes <= 0 {
+		return 0
+	}
+	return time.Duration(minutes * float64(time.Minute))
+}
+
+// runModelsRefreshLoop 按 server.models_refresh_minutes 周期性拉取各启用端点的 /models 并缓存
+func (a *App) runModelsRefreshLoop() {
+	for {
+		interval := a.getModelsRefreshInterval()
+		if interval <= 0 {
+			time.Sleep(modelsRefreshIdleInterval)
+			continue
+		}
+		a.refreshEndpointModels()
+		time.Sleep(interval)
+	}
+}
+
+// oauthPreflightInterval OAuth 预刷新检查间隔，需小于 oauth.ShouldRefreshToken 的 5 分钟提前量
+const oauthPreflightInterval = time.Minute
+
+// runOAuthPreflightLoop 启动时立即检查一次，之后按 oauthPreflightInterval 周期性预刷新 OAuth token
+func (a *App) runOAuthPreflightLoop() {
+	for {
+		a.refreshExpiringOAuthTokens()
+		time.Sleep(oauthPreflightInterval)
+	}
+}
+
+// needsOAuthPreflight 判断端点的 OAuth token 是否需要预刷新：开启 auto_refresh、可刷新且按存储的过期时间即将过期
+func needsOAuthPreflight(endpoint config.EndpointConfig) bool {
+	if !strings.EqualFold(strings.TrimSpace(endpoint.AuthType), "oauth") {
+		return false
+	}
+	cfg := endpoint.OAuthConfig
+	if cfg == nil || !cfg.AutoRefresh || cfg.RefreshToken == "" || cfg.TokenURL == "" {
+		return false
+	}
+	return oauth.ShouldRefreshToken(cfg)
+}
+
+// refreshExpiringOAuthTokens 刷新启用端点中即将过期的 OAuth token，并将新 token 写回数据库
+func (a *App) refreshExpiringOAuthTokens() {
+	a.mutex.RLock()
+	db := a.db
+	a.mutex.RUnlock()
+	if db == nil {
+		return
+	}
+
+	endpoints, err := a.getAvailableEndpoints()
+	if err != nil {
+		return
+	}
+	for _, endpoint := range endpoints {
+		if !needsOAuthPreflight(endpoint) {
+			continue
+		}
+		client, err := a.getUpstreamClient(endpoint)
+		if err != nil {
+			a.addLog("warn", fmt.Sprintf("端点 %s OAuth 预刷新失败: %v", endpoint.Name, err))
+			continue
+		}
+		name := endpoint.Name
+		refreshed, err := oauth.RefreshTokenWithCallback(endpoint.OAuthConfig, client, func(cfg *config.OAuthConfig) error {
+			return persistOAuthConfig(db, name, cfg)
+		})
+		if err != nil {
+			a.addLog("warn", fmt.Sprintf("端点 %s OAuth 预刷新失败: %v", name, err))
+			continue
+		}
+		a.addLog("info", fmt.Sprintf("端点 %s OAuth token 已预刷新，新的过期时间 %s", name, time.UnixMilli(refreshed.ExpiresAt).Format("2006-01-02 15:04:05")))
+	}
+}
+
+// persistOAuthConfig 将刷新后的 OAuth 配置写回端点的 oauth_config 字段
+func persistOAuthConfig(db *sql.DB, endpointName string, cfg *config.OAuthConfig) error {
+	encoded, err := json.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(`UPDATE endpoints SET oauth_config = ?, updated_at = ? WHERE name = ?`, string(encoded), getCurrentTimestamp(), endpointName)
+	return err
+}
+
+// refreshEndpointModels 拉取所有启用端点的模型列表；失败的端点记录错误，聚合时回退到配置推断的模型
+func (a *App) refreshEndpointModels() {
+	endpoints, err := a.getAvailableEndpoints()
+	if err != nil {
+		a.addLog("warn", fmt.Sprintf("刷新模型列表失败: %v", err))
+		return
+	}
+
+	entries := make(map[string]*endpointModelsEntry, len(endpoints))
+	failed := 0
+	for _, endpoint := range endpoints {
+		entry := &endpointModelsEntry{fetchedAt: time.Now()}
+		if models, err := a.fetchEndpointModels(endpoint); err != nil {
+			entry.err = err.Error()
+			failed++
+		} else {
+			entry.models = models
+		}
+		entries[endpoint.Name] = entry
+	}
+
+	a.modelsCacheMu.Lock()
+	a.modelsCache = entries
+	a.modelsCacheMu.Unlock()
+
+	if failed > 0 {
+		a.addLog("warn", fmt.Sprintf("模型列表已刷新: %d 个端点中 %d 个拉取失败，已回退到配置推断的模型", len(endpoints), failed))
+	} else {
+		a.addLog("info", fmt.Sprintf("模型列表已刷新: %d 个端点", len(endpoints)))
+	}
+}
+
+// fetchEndpointModels 请求端点上游的 /v1/models，兼容 OpenAI/Anthropic（data[].id）与 Gemini（models[].name）响应
+func (a *App) fetchEndpointModels(endpoint config.EndpointConfig) ([]string, error) {
+	targetURL, err := a.buildTargetURL(&endpoint, "/v1/models", "")
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), modelsFetchTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, targetURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if userAgent := config.ResolveUserAgent(endpoint.UserAgent, a.getDefaultUserAgent()); userAgent != "" {
+		req.Header.Set("User-Agent", userAgent)
+	}
+	if endpoint.URLAnthropic != "" && strings.HasPrefix(targetURL, strings.TrimRight(endpoint.URLAnthropic, "/")) {
+		req.Header.Set("anthropic-version", config.ResolveAnthropicVersion(endpoint.AnthropicVersion, ""))
+	}
+	applyOrganizationHeaders(req.Header, endpoint)
+	applyEndpointAuth(req.Header, endpoint, "")
+
+	client, err := a.getUpstreamClient(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("upstream /models returned HTTP %d", resp.StatusCode)
+	}
+	return parseUpstreamModelList(body)
+}
+
+// parseUpstreamModelList 提取模型列表响应中的模型ID
+func parseUpstreamModelList(body []byte) ([]string, error) {
+	var payload map[string]interface{}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("invalid models response: %w", err)
+	}
+
+	var models []string
+	appendItems := func(items []interface{}, keys ...string) {
+		for _, raw := range items {
+			switch item := raw.(type) {
+			case string:
+				models = append(models, item)
+			case map[string]interface{}:
+				for _, key := range keys {
+					if id, ok := item[key].(string); ok && id != "" {
+						models = append(models, strings.TrimPrefix(id, "models/"))
+						break
+					}
+				}
+			}
+		}
+	}
+	if data, ok := payload["data"].([]interface{}); ok {
+		appendItems(data, "id", "name")
+	} else if list, ok := payload["models"].([]interface{}); ok {
+		appendItems(list, "name", "id")
+	} else {
+		return nil, fmt.Errorf("models response has neither data nor models")
+	}
+	return dedupeModelIDs(models), nil
+}
+
+// configDerivedModels 从端点已启用的模型重写配置推断可用模型（重写目标与健康检查模型）
+func configDerivedModels(endpoint config.EndpointConfig) []string {
+	if endpoint.ModelRewrite == nil || !endpoint.ModelRewrite.Enabled {
+		return nil
+	}
+	var models []string
+	for _, rule := range endpoint.ModelRewrite.Rules {
+		models = append(models, rule.TargetModel)
+	}
+	models = append(models, endpoint.ModelRewrite.TargetModel)
+	return dedupeModelIDs(models)
+}
+
+func dedupeModelIDs(models []string) []string {
+	seen := make(map[string]bool, len(models))
+	result := make([]string, 0, len(models))
+	for _, model := range models {
+		model = strings.TrimSpace(model)
+		if model == "" || seen[model] {
+			continue
+		}
+		seen[model] = true
+		result = append(result, model)
+	}
+	return result
+}
+
+// cachedEndpointModels 返回端点的有效模型列表：缓存未过期（两个刷新周期内）且拉取成功时使用上游结果，否则回退到配置推断
+func (a *App) cachedEndpointModels(endpoint config.EndpointConfig, interval time.Duration) ([]string, string) {
+	if interval > 0 {
+		a.modelsCacheMu.Lock()
+		entry := a.modelsCache[endpoint.Name]
+		a.modelsCacheMu.Unlock()
+		if entry != nil && entry.err == "" && time.Since(entry.fetchedAt) <= 2*interval {
+			return entry.models, "upstream"
+		}
+	}
+	return configDerivedModels(endpoint), "config"
+}
+
+// aggregateModels 合并所有启用端点的模型列表，按端点优先级顺序去重，owned_by 为首个提供该模型的端点
+func (a *App) aggregateModels() ([]map[string]interface{}, error) {
+	endpoints, err := a.getAvailableEndpoints()
+	if err != nil {
+		return nil, err
+	}
+	interval := a.getModelsRefreshInterval()
+
+	data := make([]map[string]interface{}, 0)
+	seen := map[string]bool{}
+	for _, endpoint := range endpoints {
+		models, _ := a.cachedEndpointModels(endpoint, interval)
+		for _, model := range models {
+			if seen[model] {
+				continue
+			}
+			seen[model] = true
+			data = append(data, map[string]interface{}{
+				"id":       model,
+				"object":   "model",
+				"owned_by": endpoint.Name,
+			})
+		}
+	}
+	return data, nil
+}
+
+// handleModelsList 返回聚合后的 OpenAI 格式模型列表（GET /v1/models）
+func (a *App) handleModelsList(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Only GET is supported for the models list")
+		return
+	}
+
+	data, err := a.aggregateModels()
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "endpoint_query_failed", "Failed to get endpoints")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"object": "list",
+		"data":   data,
+	})
+}
+// getModelAliases 读取 server.model_aliases（别名 -> 规范模型名）
+func (a *App) getModelAliases() map[string]string {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	if a.config == nil {
+		return nil
+	}
+	server, ok := a.config["server"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	aliases := map[string]string{}
+	switch raw := server["model_aliases"].(type) {
+	case map[string]interface{}:
+		for alias, value := range raw {
+			if canonical, ok := value.(string); ok && strings.TrimSpace(canonical) != "" {
+				aliases[strings.TrimSpace(alias)] = strings.TrimSpace(canonical)
+			}
+		}
+	case map[string]string:
+		for alias, canonical := range raw {
+			if strings.TrimSpace(canonical) != "" {
+				aliases[strings.TrimSpace(alias)] = strings.TrimSpace(canonical)
+			}
+		}
+	}
+	return aliases
+}
+
+// defaultForwardHeaderBlocklist 默认不转发的客户端请求头（部分上游会因未知头部直接返回400）
+var defaultForwardHeaderBlocklist = []string{
+	"x-stainless-*",
+	"anthropic-dangerous-direct-browser-access",
+}
+
+// headerForwardFilter 请求头转发规则，使用大小写不敏感的glob匹配
+type headerForwardFilter struct {
+	allowlist []string // 非空时仅转发匹配的头部
+	blocklist []string // 匹配的头部始终不转发
+}
+
+// allows 判断请求头是否允许转发给上游
+func (f headerForwardFilter) allows(key string) bool {
+	lower := strings.ToLower(key)
+	if lower == "content-type" {
+		return true
+	}
+	// X-CCCC-* 为代理自身的控制头，不转发给上游
+	if strings.HasPrefix(lower, "x-cccc-") {
+		return false
+	}
+	if len(f.allowlist) > 0 && !matchHeaderGlob(f.allowlist, lower) {
+		return false
+	}
+	return !matchHeaderGlob(f.blocklist, lower)
+}
+
+func matchHeaderGlob(patterns []string, lowerKey string) bool {
+	for _, pattern := range patterns {
+		if matched, err := pathpkg.Match(strings.ToLower(pattern), lowerKey); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+// filterForwardHeaders 返回按转发规则过滤后的请求头副本
+func filterForwardHeaders(h http.Header, filter headerForwardFilter) http.Header {
+	filtered := make(http.Header, len(h))
+	for key, values := range h {
+		if filter.allows(key) {
+			filtered[key] = append([]string(nil), values...)
+		}
+	}
+	return filtered
+}
+
+// getHeaderForwardFilter 读取 server.forward_header_allowlist / forward_header_blocklist，未配置黑名单时使用默认列表
+func (a *App) getHeaderForwardFilter() headerForwardFilter {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	filter := headerForwardFilter{
+		blocklist: defaultForwardHeaderBlocklist,
+	}
+
+	if a.config == nil {
+		return filter
+	}
+	server, ok := a.config["server"].(map[string]interface{})
+	if !ok {
+		return filter
+	}
+
+	if raw, exists := server["forward_header_allowlist"]; exists {
+		if allowlist, err := parseStringSlice(raw); err == nil {
+			filter.allowlist = allowlist
+		}
+	}
+	if raw, exists := server["forward_header_blocklist"]; exists {
+		if blocklist, err := parseStringSlice(raw); err == nil {
+			filter.blocklist = blocklist
+		}
+	}
+
+	return filter
+}
+
+// clientIPForwarding server.forward_client_ip 与 server.trusted_proxies 的解析结果
+type clientIPForwarding struct {
+	enabled bool
+	trusted []*net.IPNet
+}
+
+// getClientIPForwarding 读取 server.forward_client_ip（默认关闭）与 server.trusted_proxies（IP 或 CIDR 列表）
+func (a *App) getClientIPForwarding() clientIPForwarding {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	var forwarding clientIPForwarding
+	if a.config == nil {
+		return forwarding
+	}
+	server, ok := a.config["server"].(map[string]interface{})
+	if !ok {
+		return forwarding
+	}
+
+	forwarding.enabled, _ = server["forward_client_ip"].(bool)
+	if raw, exists := server["trusted_proxies"]; exists {
+		if entries, err := parseStringSlice(raw); err == nil {
+			forwarding.trusted = parseTrustedProxies(entries)
+		}
+	}
+	return forwarding
+}
+
+// parseTrustedProxies 将 IP 或 CIDR 列表解析为网段，单个 IP 视为 /32（IPv6 为 /128），无效项忽略
+func parseTrustedProxies(entries []string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(entries))
+	for _, entry := range entries {
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			networks = append(networks, network)
+			continue
+		}
+		if ip := net.ParseIP(entry); ip != nil {
+			bits := 128
+			if ip.To4() != nil {
+				ip = ip.To4()
+				bits = 32
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+		}
+	}
+	return networks
+}
+
+func (f clientIPForwarding) isTrusted(ip net.IP) bool {
+	for _, network := range f.trusted {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolve 计算转发给上游的 X-Forwarded-For 与 X-Real-IP。只有直连方是可信代理时才保留并追加客户端发来的
+// X-Forwarded-For，并从右向左跳过可信代理确定真实客户端；否则丢弃（可能伪造）并只使用直连地址
+func (f clientIPForwarding) resolve(remoteAddr, existingXFF string) (string, string) {
+	host := remoteAddr
+	if h, _, err := net.SplitHostPort(remoteAddr); err == nil {
+		host = h
+	}
+	remoteIP := net.ParseIP(strings.TrimSpace(host))
+	if remoteIP == nil {
+		return "", ""
+	}
+	remote := remoteIP.String()
+
+	if !f.isTrusted(remoteIP) || strings.TrimSpace(existingXFF) == "" {
+		return remote, remote
+	}
+
+	chain := make([]string, 0, 4)
+	for _, part := range strings.Split(existingXFF, ",") {
+		if trimmed := strings.TrimSpace(part); trimmed != "" {
+			chain = append(chain, trimmed)
+		}
+	}
+	chain = append(chain, remote)
+
+	realIP := chain[0]
+	for i := len(chain) - 1; i >= 0; i-- {
+		ip := net.ParseIP(chain[i])
+		if ip == nil {
+			// 无法解析的条目不可信，停止回溯，使用最后一个可验证的地址
+			realIP = chain[i+1]
+			break
+		}
+		if !f.isTrusted(ip) {
+			realIP = ip.String()
+			break
+		}
+	}
+	return strings.Join(chain, ", "), realIP
+}
+
+// getKeys 获取map的所有key
+func getKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// writeJSONError 输出统一的JSON错误响应
+func writeJSONError(w http.ResponseWriter, status int, code string, message string) {
+	if message == "" {
+		message = http.StatusText(status)
+	}
+	payload := map[string]interface{}{
+		"success": false,
+		"error": map[string]interface{}{
+			"code":    code,
+			"message": message,
+		},
+	}
+
+	respBytes, err := json.Marshal(payload)
+	if err != nil {
+		http.Error(w, message, status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(respBytes)
+}
+
+// writeProxyError 写出所有端点失败时的代理错误；Responses API 请求使用 Codex 可解析的 OpenAI 错误格式
+// 仅按路径判断：格式检测会把所有 OpenAI Chat 请求标记为 codex，不能据此切换错误格式
+func writeProxyError(w http.ResponseWriter, r *http.Request, body []byte, status int, code string, message string) {
+	if strings.Contains(r.URL.Path, "/responses") {
+		writeResponsesError(w, status, code, message, streamRequested(body))
+		return
+	}
+	writeJSONError(w, status, code, message)
+}
+
+// upstreamErrorMessage 从上游错误体中提取错误信息（兼容 OpenAI/Anthropic 的 error.message、writeJSONError 格式及纯文本）
+func upstreamErrorMessage(body []byte) string {
+	var payload map[string]interface{}
+	if err := json.Unmarshal(body, &payload); err == nil {
+		if errObj, ok := payload["error"].(map[string]interface{}); ok {
+			if message, ok := errObj["message"].(string); ok && strings.TrimSpace(message) != "" {
+				return strings.TrimSpace(message)
+			}
+		}
+		if message, ok := payload["error"].(string); ok && strings.TrimSpace(message) != "" {
+			return strings.TrimSpace(message)
+		}
+		if message, ok := payload["message"].(string); ok && strings.TrimSpace(message) != "" {
+			return strings.TrimSpace(message)
+		}
+	}
+
+	message := strings.TrimSpace(string(body))
+	if runes := []rune(message); len(runes) > maxEndpointLastErrorLength {
+		message = string(runes[:maxEndpointLastErrorLength]) + "..."
+	}
+	return message
+}
+
+// errorBodyContentType 按内容推断原样透传的上游错误体的 Content-Type
+func errorBodyContentType(body []byte) string {
+	if json.Valid(body) {
+		return "application/json"
+	}
+	return "text/plain; charset=utf-8"
+}
+
+// writeResponsesError 以 Responses API 格式返回错误：非流式为 {"error": {...}}，流式为单个 response.failed 事件
+func writeResponsesError(w http.ResponseWriter, status int, code string, message string, stream bool) {
+	if message == "" {
+		message = http.StatusText(status)
+	}
+	errorType := "invalid_request_error"
+	if status >= http.StatusInternalServerError {
+		errorType = "server_error"
+	}
+
+	if !stream {
+		respBytes, err := json.Marshal(map[string]interface{}{
+			"error": map[string]interface{}{
+				"type":    errorType,
+				"code":    code,
+				"message": message,
+				"param":   nil,
+			},
+		})
+		if err != nil {
+			http.Error(w, message, status)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write(respBytes)
+		return
+	}
+
+	// 流式客户端只解析 SSE 事件，HTTP 状态码保持 200，失败信息放在 response.failed 事件中
+	eventBytes, err := json.Marshal(map[string]interface{}{
+		"type":            "response.failed",
+		"sequence_number": 0,
+		"response": map[string]interface{}{
+			"id":         fmt.Sprintf("resp_%d", time.Now().UnixNano()),
+			"object":     "response",
+			"created_at": time.Now().Unix(),
+			"status":     "failed",
+			"output":     []interface{}{},
+			"error": map[string]interface{}{
+				"code":    code,
+				"message": message,
+			},
+		},
+	})
+	if err != nil {
+		http.Error(w, message, status)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "event: response.failed\ndata: %s\n\n", eventBytes)
+}
+
+// cleanup 清理资源
+func (a *App) cleanup() {
+	a.closeRequestLogWriter()
+	if a.dbManager != nil {
+		a.dbManager.Close()
+	}
+	a.accessLogMu.Lock()
+	if a.accessLogger != nil {
+		a.accessLogger.Close()
+		a.accessLogger = nil
+	}
+	a.accessLogMu.Unlock()
+	runtime.LogInfo(a.ctx, "✅ 统一路由架构已关闭")
+}
+
+// GetServerStatus 获取服务器状态
+func (a *App) GetServerStatus() map[string]interface{} {
+	gateOpen, healthyCount, requiredHealthy, gateReason := a.healthGateStatus()
+
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	host := strings.TrimSpace(a.proxyHost)
+	if host == "" {
+		host = defaultProxyHost
+	}
+
+	port := a.proxyPort
+	if port <= 0 || port > 65535 {
+		port = defaultProxyPort
+	}
+
+	configuredHost := strings.TrimSpace(a.configuredHost)
+	if configuredHost == "" {
+		configuredHost = defaultProxyHost
+	}
+
+	configuredPort := a.configuredPort
+	if configuredPort <= 0 || configuredPort > 65535 {
+		configuredPort = defaultProxyPort
+	}
+
+	// 端点总数与健康数直接查询数据库；查询失败时保留健康闸门统计的健康数
+	endpointsTotal := 0
+	if a.db != nil {
+		if total, healthy, err := queryEndpointCounts(a.db); err == nil {
+			endpointsTotal = total
+			healthyCount = healthy
+		}
+	}
+	counters := a.requestCounters.snapshot()
+
+	status := map[string]interface{}{
+		"running":           a.running,
+		"host":              host,
+		"port":              port,
+		"configured_host":   configuredHost,
+		"configured_port":   configuredPort,
+		"endpoints_total":   endpointsTotal,
+		"endpoints_healthy": healthyCount,
+		"mode":              "desktop (统一路由)",
+		"architecture":      "unified_wails",
+		"http_server":       "embedded",
+		"api_communication": "go_methods_only",
+		"config_path":       a.configPath,
+		"safe_mode":         a.configError != "",
+
+		"min_healthy_endpoints": requiredHealthy,
+		"health_gate_open":      gateOpen,
+
+		"requests_total":                counters.lifetimeTotal,
+		"requests_failed":               counters.lifetimeFailed,
+		"requests_since_restart":        counters.sinceResetTotal,
+		"requests_failed_since_restart": counters.sinceResetFailed,
+		"counters_reset_at":             counters.resetAt.Format(time.RFC3339),
+	}
+
+	if a.running {
+		status["uptime"] = "运行中 (统一架构)"
+	}
+	if a.configError != "" {
+		status["config_error"] = a.configError
+		status["uptime"] = "安全模式 (配置文件解析失败)"
+	} else if !gateOpen {
+		status["health_gate_reason"] = gateReason
+		status["uptime"] = "已暂停 (健康端点不足)"
+	}
+
+	return status
+}
+
+// requestCounts 代理请求计数：lifetime 自进程启动累计，since_restart 在 RestartServer / ResetRequestCounters 时清零
+type requestCounts struct {
+	lifetimeTotal    int64
+	lifetimeFailed   int64
+	sinceResetTotal  int64
+	sinceResetFailed int64
+	resetAt          time.Time
+}
+
+// requestCounters 内存中维护的 requestCounts
+type requestCounters struct {
+	mu     sync.Mutex
+	counts requestCounts
+}
+
+// record 计入一次客户端请求；状态码 >= 400 或未写出响应（如客户端取消）视为失败
+func (c *requestCounters) record(status int) {
+	failed := status == 0 || status >= http.StatusBadRequest
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.counts.lifetimeTotal++
+	c.counts.sinceResetTotal++
+	if failed {
+		c.counts.lifetimeFailed++
+		c.counts.sinceResetFailed++
+	}
+}
+
+// reset 清零 since_restart 计数，lifetime 计数保持不变
+func (c *requestCounters) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.counts.sinceResetTotal = 0
+	c.counts.sinceResetFailed = 0
+	c.counts.resetAt = time.Now()
+}
+
+// snapshot 返回计数副本；从未重置时 resetAt 为进程启动时间
+func (c *requestCounters) snapshot() requestCounts {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	counts := c.counts
+	if counts.resetAt.IsZero() {
+		counts.resetAt = appStartedAt
+	}
+	return counts
+}
+
+// ResetRequestCounters 清零运行状态中的 since_restart 请求计数（lifetime 计数不受影响）
+func (a *App) ResetRequestCounters() map[string]interface{} {
+	a.requestCounters.reset()
+	runtime.LogInfo(a.ctx, "请求计数已重置")
+	a.addLog("info", "请求计数已重置")
+	return map[string]interface{}{
+		"success": true,
+		"message": "请求计数已重置",
+	}
+}
+
+// RestartServer 重启服务
+func (a *App) RestartServer() string {
+	runtime.LogInfo(a.ctx, "Restarting unified architecture services")
+
+	a.mutex.Lock()
+	a.running = false
+	a.mutex.Unlock()
+
+	time.Sleep(100 * time.Millisecond)
+
+	a.mutex.Lock()
+	a.running = true
+	a.mutex.Unlock()
+	a.requestCounters.reset()
+
+	runtime.LogInfo(a.ctx, "✅ 统一架构服务重启成功")
+	return "统一架构服务重启成功 (无HTTP服务器冲突)"
+}
+
+// GetVersionInfo 获取版本信息
+func (a *App) GetVersionInfo() string {
+	return fmt.Sprintf("1.0.0 - %s", getCurrentTimestamp())
+}
+
+// Greet 返回问候语
+func (a *App) Greet(name string) string {
+	return fmt.Sprintf("Hello %s, Welcome to CCCC Proxy Desktop with Unified Architecture!", name)
+}
+
+// GetEndpoints 返回端点列表
+func (a *App) GetEndpoints() map[string]interface{} {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	runtime.LogInfo(a.ctx, "GetEndpoints: 开始获取端点列表")
+
+	if a.db == nil {
+		runtime.LogError(a.ctx, "GetEndpoints: 数据库为空")
+		return map[string]interface{}{
+			"success": false,
+			"error":   "数据库不可用",
+			"data":    []interface{}{},
+		}
+	}
+
+	query := `
+		SELECT id, name, url_anthropic, url_openai, endpoint_type, auth_type, auth_value,
+			   enabled, priority, tags, status, response_time, last_check, created_at, updated_at,
+			   model_rewrite_enabled, target_model, parameter_overrides, model_rewrite_rules,
+			   extra_system_prompt, force_thinking, disable_thinking, user_field_mode, anthropic_version,
+			   notes, user_agent, auto_disabled, strip_reasoning_in_response, insecure_skip_verify,
+			   default_max_tokens, max_tokens_ceiling, max_tokens_field_name, last_error, last_error_at,
+			   response_header_overrides, sse_event_filter, max_requests_per_minute,
+			   hmac_header, hmac_secret, hmac_algo, stream_include_usage, fallback_on_4xx, oauth_config,
+			   body_transforms, provider, region, openai_organization, openai_project, supports_audio,
+			   thinking_stream_mode, allowed_models
+		FROM endpoints
+		ORDER BY priority DESC, created_at ASC
+	`
+
+	rows, err := a.db.Query(query)
+	if err != nil {
+		runtime.LogError(a.ctx, fmt.Sprintf("Failed to query endpoints: %v", err))
+		return map[string]interface{}{
+			"success": false,
+			"error":   fmt.Sprintf("查询端点失败: %v", err),
+			"data":    []interface{}{},
+		}
+	}
+	defer rows.Close()
+
+	var endpoints []interface{}
+	for rows.Next() {
+		var (
+			id, name, urlAnthropic, urlOpenai, endpointType, authType, authValue sql.NullString
+			enabled                                                              sql.NullBool
+			priority                                                             sql.NullInt64
+			tagsJSON, status, lastCheck, createdAt, updatedAt                    sql.NullString
+			targetModel, parameterOverridesJSON, modelRewriteRulesJSON           sql.NullString
+			extraSystemPrompt, userFieldMode, anthropicVersion, notes            sql.NullString
+			userAgent                                                            sql.NullString
+			forceThinking, disableThinking, autoDisabled, stripReasoning         sql.NullBool
+			insecureSkip                                                         sql.NullBool
+			responseTime, defaultMaxTokens, maxTokensCeiling                     sql.NullInt64
+			maxRequestsPerMinute                                                 sql.NullInt64
+			maxTokensFieldName, lastError, lastErrorAt                           sql.NullString
+			responseHeaderOverridesJSON, sseEventFilterJSON                      sql.NullString
+			hmacHeader, hmacSecret, hmacAlgo                                     sql.NullString
+			streamIncludeUsage, fallbackOn4xx                                    sql.NullBool
+			modelRewriteEnabled                                                  sql.NullBool
+			oauthConfigJSON, bodyTransformsJSON                                  sql.NullString
+			provider, region                                                     sql.NullString
+			openAIOrganization, openAIProject                                    sql.NullString
+			supportsAudio                                                        sql.NullBool
+			thinkingStreamMode, allowedModelsJSON                                sql.NullString
+		)
+
+		if err := rows.Scan(
+			&id,
+			&name,
+			&urlAnthropic,
+			&urlOpenai,
+			&endpointType,
+			&authType,
+			&authValue,
+			&enabled,
+			&priority,
+			&tagsJSON,
+			&status,
+			&responseTime,
+			&lastCheck,
+			&createdAt,
+			&updatedAt,
+			&modelRewriteEnabled,
+			&targetModel,
+			&parameterOverridesJSON,
+			&modelRewriteRulesJSON,
+			&extraSystemPrompt,
+			&forceThinking,
+			&disableThinking,
+			&userFieldMode,
+			&anthropicVersion,
+			&notes,
+			&userAgent,
+			&autoDisabled,
+			&stripReasoning,
+			&insecureSkip,
+			&defaultMaxTokens,
+			&maxTokensCeiling,
+			&maxTokensFieldName,
+			&lastError,
+			&lastErrorAt,
+			&responseHeaderOverridesJSON,
+			&sseEventFilterJSON,
+			&maxRequestsPerMinute,
+			&hmacHeader,
+			&hmacSecret,
+			&hmacAlgo,
+			&streamIncludeUsage,
+			&fallbackOn4xx,
+			&oauthConfigJSON,
+			&bodyTransformsJSON,
+			&provider,
+			&region,
+			&openAIOrganization,
+			&openAIProject,
+			&supportsAudio,
+			&thinkingStreamMode,
+			&allowedModelsJSON,
+		); err != nil {
+			runtime.LogError(a.ctx, fmt.Sprintf("Failed to scan endpoint row: %v", err))
+			continue
+		}
+
+		enabledValue := true
+		if enabled.Valid {
+			enabledValue = enabled.Bool
+		}
+
+		tags := decodeStringSlice(tagsJSON)
+		parameterOverrides := decodeParameterOverrides(parameterOverridesJSON)
+		modelRewrite := buildModelRewriteMap(modelRewriteEnabled, targetModel, modelRewriteRulesJSON)
+
+		endpoint := map[string]interface{}{
+			"id":            id.String,
+			"name":          name.String,
+			"url_anthropic": urlAnthropic.String,
+			"url_openai":    urlOpenai.String,
+			"endpoint_type": endpointType.String,
+			"auth_type":     authType.String,
+			"auth_value":    authValue.String,
+			"enabled":       enabledValue,
+			"priority":      int(priority.Int64),
+			"tags":          tags,
+			"status":        status.String,
+			"response_time": int(responseTime.Int64),
+			"last_check":    lastCheck.String,
+			"created_at":    createdAt.String,
+			"updated_at":    updatedAt.String,
+
+			"force_thinking":   forceThinking.Valid && forceThinking.Bool,
+			"disable_thinking": disableThinking.Valid && disableThinking.Bool,
+			"user_field_mode":  utils.NormalizeUserFieldMode(userFieldMode.String),
+			"notes":            notes.String,
+			"auto_disabled":    autoDisabled.Valid && autoDisabled.Bool,
+
+			"provider": strings.TrimSpace(provider.String),
+			"region":   strings.TrimSpace(region.String),
+
+			"openai_organization": strings.TrimSpace(openAIOrganization.String),
+			"openai_project":      strings.TrimSpace(openAIProject.String),
+			"supports_audio":      supportsAudio.Valid && supportsAudio.Bool,
+
+			"thinking_stream_mode": conversion.NormalizeThinkingStreamMode(thinkingStreamMode.String),
+
+			"strip_reasoning_in_response": stripReasoning.Valid && stripReasoning.Bool,
+			"insecure_skip_verify":        insecureSkip.Valid && insecureSkip.Bool,
+
+			"default_max_tokens":    int(defaultMaxTokens.Int64),
+			"max_tokens_ceiling":    int(maxTokensCeiling.Int64),
+			"max_tokens_field_name": strings.TrimSpace(maxTokensFieldName.String),
+
+			"max_requests_per_minute": int(maxRequestsPerMinute.Int64),
+
+			"hmac_header": strings.TrimSpace(hmacHeader.String),
+			"hmac_secret": strings.TrimSpace(hmacSecret.String),
+			"hmac_algo":   strings.TrimSpace(hmacAlgo.String),
+
+			"stream_include_usage": !streamIncludeUsage.Valid || streamIncludeUsage.Bool,
+			"fallback_on_4xx":      nullableBool(fallbackOn4xx),
+			"body_transforms":      bodyTransformsForResponse(decodeBodyTransforms(bodyTransformsJSON)),
+
+			"last_error":    lastError.String,
+			"last_error_at": lastErrorAt.String,
+		}
+		if oauthConfig := decodeOAuthConfig(oauthConfigJSON); oauthConfig != nil {
+			endpoint["oauth_config"] = oauthConfig
+		}
+		if version := strings.TrimSpace(anthropicVersion.String); version != "" {
+			endpoint["anthropic_version"] = version
+		}
+		if ua := strings.TrimSpace(userAgent.String); ua != "" {
+			endpoint["user_agent"] = ua
+		}
+
+		if len(parameterOverrides) > 0 {
+			endpoint["parameter_overrides"] = parameterOverrides
+		}
+		if headerOverrides := decodeHeaderOverrides(responseHeaderOverridesJSON); len(headerOverrides) > 0 {
+			endpoint["response_header_overrides"] = headerOverrides
+		}
+		if sseFilter := decodeStringSlice(sseEventFilterJSON); len(sseFilter) > 0 {
+			endpoint["sse_event_filter"] = sseFilter
+		}
+		if allowedModels := decodeStringSlice(allowedModelsJSON); len(allowedModels) > 0 {
+			endpoint["allowed_models"] = allowedModels
+		}
+		if modelRewrite != nil {
+			endpoint["model_rewrite"] = modelRewrite
+		}
+		if target := strings.TrimSpace(targetModel.String); target != "" {
+			endpoint["target_model"] = target
+		}
+		if prompt := strings.TrimSpace(extraSystemPrompt.String); prompt != "" {
+			endpoint["extra_system_prompt"] = prompt
+		}
+
+		endpoints = append(endpoints, endpoint)
+	}
+
+	runtime.LogInfo(a.ctx, fmt.Sprintf("GetEndpoints: 完成，获取到 %d 个端点", len(endpoints)))
+
+	// 添加详细的端点信息日志
+	for i, endpoint := range endpoints {
+		if ep, ok := endpoint.(map[string]interface{}); ok {
+			runtime.LogInfo(a.ctx, fmt.Sprintf("Endpoint[%d]: ID=%s, Name=%s, TargetModel=%v",
+				i, ep["id"], ep["name"], ep["target_model"]))
+		}
+	}
+
+	return map[string]interface{}{
+		"success": true,
+		"data":    endpoints,
+	}
+}
+
+// endpointInsert 校验通过、待写入 endpoints 表的端点定义
+type endpointInsert struct {
+	id      string
+	name    string
+	enabled bool
+	args    []interface{}
+}
+
+// endpointInsertSQL 与 endpointInsert.args 顺序一致的插入语句
+const endpointInsertSQL = `
+	INSERT INTO endpoints (
+		id, name, url_anthropic, url_openai, endpoint_type, auth_type, auth_value,
+		enabled, priority, tags, status, response_time, last_check, created_at, updated_at,
+		model_rewrite_enabled, target_model, parameter_overrides, model_rewrite_rules,
+		extra_system_prompt, force_thinking, disable_thinking, user_field_mode, anthropic_version,
+		notes, user_agent, strip_reasoning_in_response, insecure_skip_verify,
+		default_max_tokens, max_tokens_ceiling, max_tokens_field_name, response_header_overrides,
+		sse_event_filter, max_requests_per_minute, hmac_header, hmac_secret, hmac_algo,
+		stream_include_usage, fallback_on_4xx, oauth_config, body_transforms, provider, region,
+		openai_organization, openai_project, supports_audio, thinking_stream_mode, allowed_models
+	)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+`
+
+// prepareEndpointInsert 解析并校验端点定义，失败时返回面向用户的错误信息（CreateEndpoint 与 ImportEndpoints 共用）
+func (a *App) prepareEndpointInsert(endpointData map[string]interface{}) (*endpointInsert, string) {
+	// 生成端点ID（使用UUID避免冲突）
+	endpointID := fmt.Sprintf("endpoint_%s", uuid.NewString())
+
+	name := strings.TrimSpace(getStringFromMap(endpointData, "name"))
+	if name == "" {
+		return nil, "端点名称不能为空"
+	}
+
+	urlAnthropic := strings.TrimSpace(getStringFromMap(endpointData, "url_anthropic"))
+	urlOpenai := strings.TrimSpace(getStringFromMap(endpointData, "url_openai"))
+
+	if urlAnthropic == "" && urlOpenai == "" {
+		return nil, "至少需要配置一个URL"
+	}
+
+	endpointType := strings.TrimSpace(getStringFromMap(endpointData, "endpoint_type"))
+	if endpointType == "" {
+		endpointType = deduceEndpointType(urlAnthropic, urlOpenai)
+	}
+
+	authType := strings.TrimSpace(getStringFromMap(endpointData, "auth_type"))
+	if authType == "" {
+		authType = "none"
+	}
+
+	authValue := strings.TrimSpace(getStringFromMap(endpointData, "auth_value"))
+
+	enabled := extractBool(endpointData["enabled"], true)
+	priority := extractPriority(endpointData["priority"])
+
+	tagsJSON := "[]"
+	if rawTags, exists := endpointData["tags"]; exists {
+		if serialised, err := serialiseStringSlice(rawTags, "[]"); err == nil {
+			tagsJSON = serialised
+		} else {
+			runtime.LogWarning(a.ctx, fmt.Sprintf("Invalid tags value for endpoint %s: %v", name, err))
+		}
+	}
+
+	parameterOverridesJSON := "{}"
+	if rawOverrides, exists := endpointData["parameter_overrides"]; exists {
+		if serialised, err := serialiseParameterOverrides(rawOverrides, "{}"); err == nil {
+			parameterOverridesJSON = serialised
+		} else {
+			runtime.LogWarning(a.ctx, fmt.Sprintf("Invalid parameter_overrides for endpoint %s: %v", name, err))
+		}
+	}
+
+	responseHeaderOverridesJSON := "{}"
+	if rawHeaders, exists := endpointData["response_header_overrides"]; exists {
+		serialised, err := serialiseHeaderOverrides(rawHeaders, "{}")
+		if err != nil {
+			return nil, "无效的 response_header_overrides: " + err.Error()
+		}
+		responseHeaderOverridesJSON = serialised
+	}
+
+	sseEventFilterJSON := "[]"
+	if rawFilter, exists := endpointData["sse_event_filter"]; exists {
+		serialised, err := serialiseSSEEventFilter(rawFilter)
+		if err != nil {
+			return nil, "无效的 sse_event_filter: " + err.Error()
+		}
+		sseEventFilterJSON = serialised
+	}
+
+	allowedModelsJSON := "[]"
+	if rawAllowed, exists := endpointData["allowed_models"]; exists {
+		serialised, err := serialiseStringSlice(rawAllowed, "[]")
+		if err != nil {
+			return nil, "无效的 allowed_models: " + err.Error()
+		}
+		allowedModelsJSON = serialised
+	}
+
+	extraSystemPrompt := strings.TrimSpace(getStringFromMap(endpointData, "extra_system_prompt"))
+	forceThinking := extractBool(endpointData["force_thinking"], false)
+	disableThinking := extractBool(endpointData["disable_thinking"], false)
+	stripReasoning := extractBool(endpointData["strip_reasoning_in_response"], false)
+	insecureSkipVerify := extractBool(endpointData["insecure_skip_verify"], false)
+	supportsAudio := extractBool(endpointData["supports_audio"], false)
+	defaultMaxTokens := extractNonNegativeInt(endpointData["default_max_tokens"])
+	maxTokensCeiling := extractNonNegativeInt(endpointData["max_tokens_ceiling"])
+	maxTokensFieldName := strings.TrimSpace(getStringFromMap(endpointData, "max_tokens_field_name"))
+	maxRequestsPerMinute := extractNonNegativeInt(endpointData["max_requests_per_minute"])
+	hmacHeader := strings.TrimSpace(getStringFromMap(endpointData, "hmac_header"))
+	hmacSecret := strings.TrimSpace(getStringFromMap(endpointData, "hmac_secret"))
+	hmacAlgo := strings.ToLower(strings.TrimSpace(getStringFromMap(endpointData, "hmac_algo")))
+	streamIncludeUsage := extractBool(endpointData["stream_include_usage"], true)
+	fallbackOn4xx := extractOptionalBool(endpointData["fallback_on_4xx"])
+	oauthConfigJSON, err := serialiseOAuthConfig(endpointData["oauth_config"], name)
+	if err != nil {
+		return nil, "无效的 oauth_config: " + err.Error()
+	}
+	bodyTransformsJSON, err := serialiseBodyTransforms(endpointData["body_transforms"])
+	if err != nil {
+		return nil, "无效的 body_transforms: " + err.Error()
+	}
+	if !isValidHMACAlgo(hmacAlgo) {
+		return nil, "无效的 hmac_algo: " + hmacAlgo + " (支持: sha256, sha512, sha1)"
+	}
+	if !isValidMaxTokensFieldName(maxTokensFieldName) {
+		return nil, "无效的 max_tokens_field_name: " + maxTokensFieldName + " (支持: max_tokens, max_completion_tokens, max_output_tokens)"
+	}
+	userFieldMode := utils.NormalizeUserFieldMode(getStringFromMap(endpointData, "user_field_mode"))
+	thinkingStreamMode := getStringFromMap(endpointData, "thinking_stream_mode")
+	if !conversion.IsValidThinkingStreamMode(thinkingStreamMode) {
+		return nil, "无效的 thinking_stream_mode: " + thinkingStreamMode + " (支持: passthrough, text, drop)"
+	}
+	anthropicVersion := strings.TrimSpace(getStringFromMap(endpointData, "anthropic_version"))
+	notes := strings.TrimSpace(getStringFromMap(endpointData, "notes"))
+	provider := strings.TrimSpace(getStringFromMap(endpointData, "provider"))
+	region := strings.TrimSpace(getStringFromMap(endpointData, "region"))
+	openAIOrganization := strings.TrimSpace(getStringFromMap(endpointData, "openai_organization"))
+	openAIProject := strings.TrimSpace(getStringFromMap(endpointData, "openai_project"))
+	userAgent := getStringFromMap(endpointData, "user_agent")
+	if err := config.ValidateUserAgent(userAgent); err != nil {
+		return nil, "无效的 user_agent: " + err.Error()
+	}
+
+	modelRewritePayload, err := extractModelRewritePayload(endpointData["model_rewrite"])
+	if err != nil {
+		runtime.LogWarning(a.ctx, fmt.Sprintf("Invalid model_rewrite for endpoint %s: %v", name, err))
+		modelRewritePayload = defaultModelRewritePayload()
+	}
+
+	createdAt := getCurrentTimestamp()
+
+	return &endpointInsert{
+		id:      endpointID,
+		name:    name,
+		enabled: enabled,
+		args: []interface{}{
+			endpointID,
+			name,
+			urlAnthropic,
+			urlOpenai,
+			endpointType,
+			authType,
+			authValue,
+			enabled,
+			priority,
+			tagsJSON,
+			"healthy",
+			0,
+			"",
+			createdAt,
+			createdAt,
+			modelRewritePayload.Enabled,
+			modelRewritePayload.TargetModel,
+			parameterOverridesJSON,
+			modelRewritePayload.RulesJSON,
+			extraSystemPrompt,
+			forceThinking,
+			disableThinking,
+			userFieldMode,
+			anthropicVersion,
+			notes,
+			userAgent,
+			stripReasoning,
+			insecureSkipVerify,
+			defaultMaxTokens,
+			maxTokensCeiling,
+			maxTokensFieldName,
+			responseHeaderOverridesJSON,
+			sseEventFilterJSON,
+			maxRequestsPerMinute,
+			hmacHeader,
+			hmacSecret,
+			hmacAlgo,
+			streamIncludeUsage,
+			fallbackOn4xx,
+			oauthConfigJSON,
+			bodyTransformsJSON,
+			provider,
+			region,
+			openAIOrganization,
+			openAIProject,
+			supportsAudio,
+			conversion.NormalizeThinkingStreamMode(thinkingStreamMode),
+			allowedModelsJSON,
+		},
+	}, ""
+}
+
+// CreateEndpoint 创建新端点
+func (a *App) CreateEndpoint(endpointData map[string]interface{}) map[string]interface{} {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if a.db == nil {
+		runtime.LogError(a.ctx, "Database not available")
+		return map[string]interface{}{
+			"success": false,
+			"message": "数据库不可用",
+		}
+	}
+
+	insert, message := a.prepareEndpointInsert(endpointData)
+	if insert == nil {
+		return map[string]interface{}{
+			"success": false,
+			"message": message,
+		}
+	}
+	endpointID, name := insert.id, insert.name
+
+	result, err := a.db.Exec(endpointInsertSQL, insert.args...)
+
+	if err != nil {
+		runtime.LogError(a.ctx, fmt.Sprintf("Failed to create endpoint %s: %v", name, err))
+		return map[string]interface{}{
+			"success": false,
+			"message": fmt.Sprintf("创建端点失败: %v", err),
+		}
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return map[string]interface{}{
+			"success": false,
+			"message": "创建端点后验证失败",
+		}
+	}
+
+	if rowsAffected == 0 {
+		return map[string]interface{}{
+			"success": false,
+			"message": "端点创建失败：没有插入任何记录",
+		}
+	}
+
+	a.addLog("info", fmt.Sprintf("端点 '%s' (ID: %s) 已成功创建", name, endpointID))
+	if insert.enabled && a.isWarmUpOnEnableEnabledNoLock() {
+		a.startWarmUp(endpointID)
+	}
+
+	return map[string]interface{}{
+		"success":       true,
+		"message":       fmt.Sprintf("端点 '%s' 创建成功", name),
+		"id":            endpointID,
+		"endpoint_name": name,
+		"rows_affected": rowsAffected,
+	}
+}
+
+// isWarmUpOnEnableEnabledNoLock 读取 server.warm_up_on_enable（默认开启），调用方需持有 a.mutex
+func (a *App) isWarmUpOnEnableEnabledNoLock() bool {
+	if a.config != nil {
+		if server, ok := a.config["server"].(map[string]interface{}); ok {
+			if enabled, ok := server["warm_up_on_enable"].(bool); ok {
+				return enabled
+			}
+		}
+	}
+	return true
+}
+
+// startWarmUp 异步执行端点预热检测
+func (a *App) startWarmUp(id string) {
+	warmUp := a.warmUpFunc
+	if warmUp == nil {
+		warmUp = a.warmUpEndpoint
+	}
+	go warmUp(id)
+}
+
+// warmUpEndpoint 在端点新建或重新启用后异步执行一次健康检测，使状态在真实流量到达前得到验证
+func (a *App) warmUpEndpoint(id string) {
+	result := a.TestEndpoint(id)
+	name, _ := result["endpoint_name"].(string)
+	if name == "" {
+		name = id
+	}
+	if success, _ := result["success"].(bool); success {
+		a.addLog("info", fmt.Sprintf("端点 %s 预热检测通过", name))
+		return
+	}
+	message, _ := result["error"].(string)
+	if message == "" {
+		message, _ = result["message"].(string)
+	}
+	a.addLog("warn", fmt.Sprintf("端点 %s 预热检测失败: %s", name, message))
+}
+
+// UpdateEndpoint 更新端点
+func (a *App) UpdateEndpoint(id string, endpointData map[string]interface{}) map[string]interface{} {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if a.db == nil {
+		runtime.LogError(a.ctx, "Database not available")
+		return map[string]interface{}{
+			"success": false,
+			"message": "数据库不可用",
+		}
+	}
+
+	// 构建动态更新SQL
+	setParts := []string{}
+	args := []interface{}{}
+
+	if rawName, exists := endpointData["name"]; exists {
+		if name, ok := rawName.(string); ok && strings.TrimSpace(name) != "" {
+			setParts = append(setParts, "name = ?")
+			args = append(args, strings.TrimSpace(name))
+		}
+	}
+
+	if rawURL, exists := endpointData["url_anthropic"]; exists {
+		if value, ok := rawURL.(string); ok {
+			setParts = append(setParts, "url_anthropic = ?")
+			args = append(args, strings.TrimSpace(value))
+		}
+	}
+
+	if rawURL, exists := endpointData["url_openai"]; exists {
+		if value, ok := rawURL.(string); ok {
+			setParts = append(setParts, "url_openai = ?")
+			args = append(args, strings.TrimSpace(value))
+		}
+	}
+
+	if rawType, exists := endpointData["endpoint_type"]; exists {
+		if value, ok := rawType.(string); ok && strings.TrimSpace(value) != "" {
+			setParts = append(setParts, "endpoint_type = ?")
+			args = append(args, strings.TrimSpace(value))
+		}
+	}
+
+	if rawAuthType, exists := endpointData["auth_type"]; exists {
+		if value, ok := rawAuthType.(string); ok && strings.TrimSpace(value) != "" {
+			setParts = append(setParts, "auth_type = ?")
+			args = append(args, strings.TrimSpace(value))
+		}
+	}
+
+	if rawAuthValue, exists := endpointData["auth_value"]; exists {
+		if value, ok := rawAuthValue.(string); ok {
+			setParts = append(setParts, "auth_value = ?")
+			args = append(args, strings.TrimSpace(value))
+		}
+	}
+
+	// 仅在端点由禁用变为启用时预热，避免普通编辑触发额外请求
+	warmUp := false
+	if rawEnabled, exists := endpointData["enabled"]; exists && extractBool(rawEnabled, true) && a.isWarmUpOnEnableEnabledNoLock() {
+		var wasEnabled sql.NullBool
+		if err := a.db.QueryRow("SELECT enabled FROM endpoints WHERE id = ?", id).Scan(&wasEnabled); err == nil {
+			warmUp = wasEnabled.Valid && !wasEnabled.Bool
+		}
+	}
+
+	if rawEnabled, exists := endpointData["enabled"]; exists {
+		// 手动启用/禁用后不再视为自动禁用
+		setParts = append(setParts, "enabled = ?", "auto_disabled = ?")
+		args = append(args, extractBool(rawEnabled, true), false)
+	}
+
+	if rawPriority, exists := endpointData["priority"]; exists {
+		setParts = append(setParts, "priority = ?")
+		args = append(args, extractPriority(rawPriority))
+	}
+
+	if rawTags, exists := endpointData["tags"]; exists {
+		if serialised, err := serialiseStringSlice(rawTags, "[]"); err == nil {
+			setParts = append(setParts, "tags = ?")
+			args = append(args, serialised)
+		} else {
+			runtime.LogWarning(a.ctx, fmt.Sprintf("Invalid tags update for endpoint %s: %v", id, err))
+		}
+	}
+
+	if rawOverrides, exists := endpointData["parameter_overrides"]; exists {
+		if serialised, err := serialiseParameterOverrides(rawOverrides, "{}"); err == nil {
+			setParts = append(setParts, "parameter_overrides = ?")
+			args = append(args, serialised)
+		} else {
+			runtime.LogWarning(a.ctx, fmt.Sprintf("Invalid parameter_overrides update for endpoint %s: %v", id, err))
+		}
+	}
+
+	if rawHeaders, exists := endpointData["response_header_overrides"]; exists {
+		serialised, err := serialiseHeaderOverrides(rawHeaders, "{}")
+		if err != nil {
+			return map[string]interface{}{
+				"success": false,
+				"message": "无效的 response_header_overrides: " + err.Error(),
+			}
+		}
+		setParts = append(setParts, "response_header_overrides = ?")
+		args = append(args, serialised)
+	}
+
+	if rawFilter, exists := endpointData["sse_event_filter"]; exists {
+		serialised, err := serialiseSSEEventFilter(rawFilter)
+		if err != nil {
+			return map[string]interface{}{
+				"success": false,
+				"message": "无效的 sse_event_filter: " + err.Error(),
+			}
+		}
+		setParts = append(setParts, "sse_event_filter = ?")
+		args = append(args, serialised)
+	}
+
+	if rawPrompt, exists := endpointData["extra_system_prompt"]; exists {
+		if value, ok := rawPrompt.(string); ok {
+			setParts = append(setParts, "extra_system_prompt = ?")
+			args = append(args, strings.TrimSpace(value))
+		}
+	}
+
+	if rawForce, exists := endpointData["force_thinking"]; exists {
+		setParts = append(setParts, "force_thinking = ?")
+		args = append(args, extractBool(rawForce, false))
+	}
+
+	if rawDisable, exists := endpointData["disable_thinking"]; exists {
+		setParts = append(setParts, "disable_thinking = ?")
+		args = append(args, extractBool(rawDisable, false))
+	}
+
+	if rawStrip, exists := endpointData["strip_reasoning_in_response"]; exists {
+		setParts = append(setParts, "strip_reasoning_in_response = ?")
+		args = append(args, extractBool(rawStrip, false))
+	}
+
+	if rawInsecure, exists := endpointData["insecure_skip_verify"]; exists {
+		setParts = append(setParts, "insecure_skip_verify = ?")
+		args = append(args, extractBool(rawInsecure, false))
+	}
+
+	if rawAudio, exists := endpointData["supports_audio"]; exists {
+		setParts = append(setParts, "supports_audio = ?")
+		args = append(args, extractBool(rawAudio, false))
+	}
+
+	if rawMode, exists := endpointData["thinking_stream_mode"]; exists {
+		if mode, ok := rawMode.(string); ok {
+			if !conversion.IsValidThinkingStreamMode(mode) {
+				return map[string]interface{}{
+					"success": false,
+					"message": "无效的 thinking_stream_mode: " + mode + " (支持: passthrough, text, drop)",
+				}
+			}
+			setParts = append(setParts, "thinking_stream_mode = ?")
+			args = append(args, conversion.NormalizeThinkingStreamMode(mode))
+		}
+	}
+
+	if rawAllowed, exists := endpointData["allowed_models"]; exists {
+		serialised, err := serialiseStringSlice(rawAllowed, "[]")
+		if err != nil {
+			return map[string]interface{}{
+				"success": false,
+				"message": "无效的 allowed_models: " + err.Error(),
+			}
+		}
+		setParts = append(setParts, "allowed_models = ?")
+		args = append(args, serialised)
+	}
+
+	if rawDefault, exists := endpointData["default_max_tokens"]; exists {
+		setParts = append(setParts, "default_max_tokens = ?")
+		args = append(args, extractNonNegativeInt(rawDefault))
+	}
+
+	if rawCeiling, exists := endpointData["max_tokens_ceiling"]; exists {
+		setParts = append(setParts, "max_tokens_ceiling = ?")
+		args = append(args, extractNonNegativeInt(rawCeiling))
+	}
+
+	if rawBudget, exists := endpointData["max_requests_per_minute"]; exists {
+		setParts = append(setParts, "max_requests_per_minute = ?")
+		args = append(args, extractNonNegativeInt(rawBudget))
+	}
+
+	for _, field := range []string{"hmac_header", "hmac_secret"} {
+		if raw, exists := endpointData[field]; exists {
+			if value, ok := raw.(string); ok {
+				setParts = append(setParts, field+" = ?")
+				args = append(args, strings.TrimSpace(value))
+			}
+		}
+	}
+
+	if rawAlgo, exists := endpointData["hmac_algo"]; exists {
+		if algo, ok := rawAlgo.(string); ok {
+			algo = strings.ToLower(strings.TrimSpace(algo))
+			if !isValidHMACAlgo(algo) {
+				return map[string]interface{}{
+					"success": false,
+					"message": "无效的 hmac_algo: " + algo + " (支持: sha256, sha512, sha1)",
+				}
+			}
+			setParts = append(setParts, "hmac_algo = ?")
+			args = append(args, algo)
+		}
+	}
+
+	if rawIncludeUsage, exists := endpointData["stream_include_usage"]; exists {
+		setParts = append(setParts, "stream_include_usage = ?")
+		args = append(args, extractBool(rawIncludeUsage, true))
+	}
+
+	if rawFallback, exists := endpointData["fallback_on_4xx"]; exists {
+		// null / 空字符串表示清除覆盖，沿用全局 server.fallback_on_4xx
+		setParts = append(setParts, "fallback_on_4xx = ?")
+		args = append(args, extractOptionalBool(rawFallback))
+	}
+
+	if rawOAuth, exists := endpointData["oauth_config"]; exists {
+		oauthConfigJSON, err := serialiseOAuthConfig(rawOAuth, id)
+		if err != nil {
+			return map[string]interface{}{
+				"success": false,
+				"message": "无效的 oauth_config: " + err.Error(),
+			}
+		}
+		setParts = append(setParts, "oauth_config = ?")
+		args = append(args, oauthConfigJSON)
+	}
+
+	if rawTransforms, exists := endpointData["body_transforms"]; exists {
+		bodyTransformsJSON, err := serialiseBodyTransforms(rawTransforms)
+		if err != nil {
+			return map[string]interface{}{
+				"success": false,
+				"message": "无效的 body_transforms: " + err.Error(),
+			}
+		}
+		setParts = append(setParts, "body_transforms = ?")
+		args = append(args, bodyTransformsJSON)
+	}
+
+	if rawField, exists := endpointData["max_tokens_field_name"]; exists {
+		if field, ok := rawField.(string); ok {
+			field = strings.TrimSpace(field)
+			if !isValidMaxTokensFieldName(field) {
+				return map[string]interface{}{
+					"success": false,
+					"message": "无效的 max_tokens_field_name: " + field + " (支持: max_tokens, max_completion_tokens, max_output_tokens)",
+				}
+			}
+			setParts = append(setParts, "max_tokens_field_name = ?")
+			args = append(args, field)
+		}
+	}
+
+	if rawMode, exists := endpointData["user_field_mode"]; exists {
+		if mode, ok := rawMode.(string); ok {
+			if strings.TrimSpace(mode) != "" && !utils.IsValidUserFieldMode(mode) {
+				return map[string]interface{}{
+					"success": false,
+					"message": "无效的 user_field_mode: " + mode + " (支持: passthrough, strip, hash, truncate)",
+				}
+			}
+			setParts = append(setParts, "user_field_mode = ?")
+			args = append(args, utils.NormalizeUserFieldMode(mode))
+		}
+	}
+
+	if rawVersion, exists := endpointData["anthropic_version"]; exists {
+		if version, ok := rawVersion.(string); ok {
+			setParts = append(setParts, "anthropic_version = ?")
+			args = append(args, strings.TrimSpace(version))
+		}
+	}
+
+	if rawUserAgent, exists := endpointData["user_agent"]; exists {
+		if userAgent, ok := rawUserAgent.(string); ok {
+			if err := config.ValidateUserAgent(userAgent); err != nil {
+				return map[string]interface{}{
+					"success": false,
+					"message": "无效的 user_agent: " + err.Error(),
+				}
+			}
+			setParts = append(setParts, "user_agent = ?")
+			args = append(args, userAgent)
+		}
+	}
+
+	// notes 仅用于备注说明，不参与路由
+	if rawNotes, exists := endpointData["notes"]; exists {
+		if notes, ok := rawNotes.(string); ok {
+			setParts = append(setParts, "notes = ?")
+			args = append(args, strings.TrimSpace(notes))
+		}
+	}
+
+	// provider / region 仅用于分组展示与统计过滤，不参与路由；openai_organization / openai_project 为转发时注入的组织/项目头
+	for _, field := range []string{"provider", "region", "openai_organization", "openai_project"} {
+		if rawValue, exists := endpointData[field]; exists {
+			if value, ok := rawValue.(string); ok {
+				setParts = append(setParts, field+" = ?")
+				args = append(args, strings.TrimSpace(value))
+			}
+		}
+	}
+
+	// 检查是否有model_rewrite更新，如果有，target_model更新应该在model_rewrite处理中
+	hasModelRewriteUpdate := false
+	if rawModelRewrite, exists := endpointData["model_rewrite"]; exists {
+		payload, err := extractModelRewritePayload(rawModelRewrite)
+		if err != nil {
+			runtime.LogWarning(a.ctx, fmt.Sprintf("Invalid model_rewrite update for endpoint %s: %v", id, err))
+		} else {
+			hasModelRewriteUpdate = true
+			setParts = append(setParts, "model_rewrite_enabled = ?")
+			args = append(args, payload.Enabled)
+			setParts = append(setParts, "target_model = ?")
+			args = append(args, payload.TargetModel)
+			setParts = append(setParts, "model_rewrite_rules = ?")
+			args = append(args, payload.RulesJSON)
+			runtime.LogInfo(a.ctx, fmt.Sprintf("Adding model_rewrite update with target_model: '%s'", payload.TargetModel))
+		}
+	}
+
+	// 只有在没有model_rewrite更新时才处理单独的target_model更新
+	if !hasModelRewriteUpdate {
+		if rawTargetModel, exists := endpointData["target_model"]; exists {
+			if value, ok := rawTargetModel.(string); ok {
+				setParts = append(setParts, "target_model = ?")
+				args = append(args, strings.TrimSpace(value))
+				runtime.LogInfo(a.ctx, fmt.Sprintf("Adding standalone target_model update: '%s'", strings.TrimSpace(value)))
+			}
+		}
+	}
+
+	if len(setParts) == 0 {
+		return map[string]interface{}{
+			"success": false,
+			"message": "没有提供要更新的字段",
+		}
+	}
+
+	// 添加更新时间和ID
+	setParts = append(setParts, "updated_at = ?")
+	args = append(args, getCurrentTimestamp())
+	args = append(args, id)
+
+	sql := fmt.Sprintf("UPDATE endpoints SET %s WHERE id = ?", strings.Join(setParts, ", "))
+
+	result, err := a.db.Exec(sql, args...)
+	if err != nil {
+		runtime.LogError(a.ctx, fmt.Sprintf("Failed to update endpoint %s: %v", id, err))
+		return map[string]interface{}{
+			"success": false,
+			"message": fmt.Sprintf("更新端点失败: %v", err),
+		}
+	}
+
+	// 检查影响的行数
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		runtime.LogWarning(a.ctx, fmt.Sprintf("No rows affected when updating endpoint %s", id))
+		return map[string]interface{}{
+			"success": false,
+			"message": "没有找到要更新的端点",
+		}
+	}
+
+	a.addLog("info", fmt.Sprintf("端点 %s 已更新", id))
+	a.invalidateUpstreamClients()
+	if warmUp {
+		a.startWarmUp(id)
+	}
+
+	return map[string]interface{}{
+		"success": true,
+		"message": "端点更新成功 (通过Go API)",
+	}
+}
+
+// DeleteEndpoint 删除端点
+func (a *App) DeleteEndpoint(id string) map[string]interface{} {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	runtime.LogInfo(a.ctx, fmt.Sprintf("DeleteEndpoint called with ID: %s", id))
+
+	if a.db == nil {
+		runtime.LogError(a.ctx, "Database not available")
+		return map[string]interface{}{
+			"success": false,
+			"message": "数据库不可用",
+		}
+	}
+
+	// 检查ID是否为空
+	if strings.TrimSpace(id) == "" {
+		runtime.LogError(a.ctx, "Empty endpoint ID provided")
+		return map[string]interface{}{
+			"success": false,
+			"message": "端点ID不能为空",
+		}
+	}
+
+	// 先检查端点是否存在，同时获取端点名称用于日志
+	var existingID, endpointName sql.NullString
+	err := a.db.QueryRow("SELECT id, name FROM endpoints WHERE id = ?", id).Scan(&existingID, &endpointName)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			runtime.LogError(a.ctx, fmt.Sprintf("Endpoint not found with ID: %s", id))
+			return map[string]interface{}{
+				"success": false,
+				"message": fmt.Sprintf("端点不存在: %s", id),
+			}
+		}
+		runtime.LogError(a.ctx, fmt.Sprintf("Error checking endpoint existence: %v", err))
+		return map[string]interface{}{
+			"success": false,
+			"message": fmt.Sprintf("检查端点失败: %v", err),
+		}
+	}
+
+	// 执行删除操作
+	result, err := a.db.Exec("DELETE FROM endpoints WHERE id = ?", id)
+	if err != nil {
+		runtime.LogError(a.ctx, fmt.Sprintf("Failed to delete endpoint %s: %v", id, err))
+		return map[string]interface{}{
+			"success": false,
+			"message": fmt.Sprintf("删除端点失败: %v", err),
+		}
+	}
+
+	// 检查是否真的删除了记录
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		runtime.LogError(a.ctx, fmt.Sprintf("Error getting rows affected for endpoint %s: %v", id, err))
+		return map[string]interface{}{
+			"success": false,
+			"message": "获取删除结果失败",
+		}
+	}
+
+	if rowsAffected == 0 {
+		runtime.LogError(a.ctx, fmt.Sprintf("No rows deleted for endpoint ID: %s", id))
+		return map[string]interface{}{
+			"success": false,
+			"message": fmt.Sprintf("没有删除任何记录，端点ID: %s", id),
+		}
+	}
+
+	endpointNameStr := "未知端点"
+	if endpointName.Valid && endpointName.String != "" {
+		endpointNameStr = endpointName.String
+	}
+
+	runtime.LogInfo(a.ctx, fmt.Sprintf("Successfully deleted endpoint '%s' with ID: %s (rows affected: %d)", endpointNameStr, id, rowsAffected))
+
+	// 添加删除操作的日志记录
+	a.addLog("info", fmt.Sprintf("端点 '%s' (ID: %s) 已成功删除", endpointNameStr, id))
+	if _, err := a.db.Exec("DELETE FROM endpoint_health_history WHERE endpoint_id = ?", id); err != nil {
+		runtime.LogWarning(a.ctx, fmt.Sprintf("Failed to delete health history for endpoint %s: %v", id, err))
+	}
+	a.invalidateUpstreamClients()
+	a.runtimeEndpoints.Delete(id)
+
+	return map[string]interface{}{
+		"success":       true,
+		"message":       fmt.Sprintf("端点 '%s' 删除成功", endpointNameStr),
+		"endpoint_name": endpointNameStr,
+		"rows_affected": rowsAffected,
+	}
+}
+
+// TestEndpoint 测试端点
+func (a *App) TestEndpoint(id string) map[string]interface{} {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if a.db == nil {
+		return map[string]interface{}{
+			"success":     false,
+			"message":     "数据库不可用",
+			"endpoint_id": id,
+		}
+	}
+
+	// 重新加载配置以确保获取最新的默认模型设置
+	if a.config == nil {
+		a.LoadConfig()
+	}
+
+	// 重置健康检查器以使用最新的默认模型
+	a.healthChecker = nil
+	if err := a.initModelRewriterAndHealthChecker(); err != nil {
+		runtime.LogError(a.ctx, fmt.Sprintf("Failed to initialize health checker: %v", err))
+		return map[string]interface{}{
+			"success":     false,
+			"message":     fmt.Sprintf("初始化健康检查器失败: %v", err),
+			"endpoint_id": id,
+		}
+	}
+
+	var (
+		name, urlAnthropic, urlOpenai, endpointType, authType, authValue, tagsJSON sql.NullString
+		enabled                                                                    sql.NullBool
+		priority                                                                   sql.NullInt64
+		modelRewriteEnabled, insecureSkipVerify                                    sql.NullBool
+		targetModel, parameterOverridesJSON, modelRewriteRulesJSON                 sql.NullString
+	)
+
+	err := a.db.QueryRow(`
+		SELECT name, url_anthropic, url_openai, endpoint_type, auth_type, auth_value,
+		       enabled, priority, tags, model_rewrite_enabled, target_model,
+		       parameter_overrides, model_rewrite_rules, insecure_skip_verify
+		FROM endpoints
+		WHERE id = ?
+	`, id).Scan(
+		&name,
+		&urlAnthropic,
+		&urlOpenai,
+		&endpointType,
+		&authType,
+		&authValue,
+		&enabled,
+		&priority,
+		&tagsJSON,
+		&modelRewriteEnabled,
+		&targetModel,
+		&parameterOverridesJSON,
+		&modelRewriteRulesJSON,
+		&insecureSkipVerify,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return map[string]interface{}{
+				"success":     false,
+				"message":     fmt.Sprintf("端点 %s 不存在", id),
+				"endpoint_id": id,
+			}
+		}
+		return map[string]interface{}{
+			"success":     false,
+			"message":     fmt.Sprintf("查询端点失败: %v", err),
+			"endpoint_id": id,
+		}
+	}
+
+	nameStr := strings.TrimSpace(name.String)
+	if nameStr == "" {
+		nameStr = id
+	}
+
+	enabledValue := true
+	if enabled.Valid {
+		enabledValue = enabled.Bool
+	}
+
+	priorityValue := int(priority.Int64)
+	if !priority.Valid || priorityValue <= 0 {
+		priorityValue = 10
+	}
+
+	endpointTags := decodeStringSlice(tagsJSON)
+
+	modelRewriteCfg, mrErr := buildModelRewriteConfigFromRow(modelRewriteEnabled, targetModel, modelRewriteRulesJSON)
+	if mrErr != nil {
+		runtime.LogWarning(a.ctx, fmt.Sprintf("Failed to parse model rewrite config for endpoint %s: %v", id, mrErr))
+	}
+
+	cfg := config.EndpointConfig{
+		Name:         nameStr,
+		URLAnthropic: strings.TrimSpace(urlAnthropic.String),
+		URLOpenAI:    strings.TrimSpace(urlOpenai.String),
+		AuthType:     normalizeAuthType(authType.String),
+		AuthValue:    strings.TrimSpace(authValue.String),
+		Enabled:      enabledValue,
+		Priority:     priorityValue,
+		Tags:         endpointTags,
+
+		InsecureSkipVerify: insecureSkipVerify.Valid && insecureSkipVerify.Bool,
+	}
+
+	if modelRewriteCfg != nil {
+		cfg.ModelRewrite = modelRewriteCfg
+	}
+
+	testEndpoint := endpoint.NewEndpoint(cfg)
+	testEndpoint.ID = id
+	testEndpoint.Enabled = enabledValue
+	testEndpoint.Tags = endpointTags
+	testEndpoint.AuthValue = cfg.AuthValue
+	testEndpoint.AuthType = cfg.AuthType
+
+	if endpointTypeStr := strings.TrimSpace(endpointType.String); endpointTypeStr != "" {
+		testEndpoint.EndpointType = endpointTypeStr
+	}
+
+	if modelRewriteCfg != nil {
+		testEndpoint.ModelRewrite = modelRewriteCfg
+	}
+
+	if parameterOverrides := decodeParameterOverrides(parameterOverridesJSON); len(parameterOverrides) > 0 {
+		testEndpoint.ParameterOverrides = encodeParameterOverrides(parameterOverrides)
+	}
+
+	// 多格式探测时全部通过才算成功，耗时取最慢的一次，状态码与预览取第一个失败的探测
+	probes, checkErr := a.healthChecker.CheckEndpointFormats(testEndpoint)
+	result, slowest := summarizeFormatProbes(probes)
+
+	testURLUsed := strings.TrimSpace(result.URL)
+	if testURLUsed == "" {
+		testURLUsed = firstNonEmpty(cfg.URLAnthropic, cfg.URLOpenAI)
+	}
+
+	responseTime := int(slowest.Milliseconds())
+	if responseTime < 0 {
+		responseTime = 0
+	}
+
+	statusValue := "healthy"
+	message := fmt.Sprintf("端点 %s 测试成功", nameStr)
+	errorMessage := ""
+	if checkErr != nil {
+		statusValue = "unhealthy"
+		message = fmt.Sprintf("端点 %s 测试失败", nameStr)
+		errorMessage = checkErr.Error()
+	} else if cfg.AuthValue != "" {
+		// 测试成功说明当前认证头有效，记录到端点运行时学习结果
+		authHeader := "Authorization"
+		if cfg.AuthType == "api_key" {
+			authHeader = "x-api-key"
+		}
+		runtimeEp := a.runtimeEndpoint(id, cfg)
+		runtimeEp.AuthHeaderMutex.Lock()
+		runtimeEp.DetectedAuthHeader = authHeader
+		runtimeEp.AuthHeaderMutex.Unlock()
+	}
+
+	now := getCurrentTimestamp()
+	if _, updateErr := a.db.Exec(`
+		UPDATE endpoints
+		SET status = ?, response_time = ?, last_check = ?, updated_at = ?
+		WHERE id = ?
+	`, statusValue, responseTime, now, now, id); updateErr != nil {
+		runtime.LogWarning(a.ctx, fmt.Sprintf("Failed to update endpoint status for %s: %v", id, updateErr))
+	}
+	if historyErr := recordHealthHistory(a.db, id, nameStr, statusValue, responseTime, errorMessage, now); historyErr != nil {
+		runtime.LogWarning(a.ctx, fmt.Sprintf("Failed to record health history for %s: %v", id, historyErr))
+	}
+	if checkErr != nil {
+		// 上游返回了错误状态码时以状态码描述失败，否则使用网络错误的根因
+		cause := errorMessage
+		if result.StatusCode >= http.StatusBadRequest {
+			cause = ""
+		}
+		a.recordEndpointLastError(a.db, nameStr, summarizeEndpointError(result.StatusCode, cause))
+	}
+	disableAfter, reenable := a.getAutoDisableSettingsNoLock()
+	a.trackEndpointHealth(a.db, nameStr, checkErr == nil, disableAfter)
+	if checkErr == nil && reenable {
+		a.reenableAutoDisabledEndpoint(a.db, id, nameStr)
+	}
+
+	requestID := ""
+	formatResults := make([]map[string]interface{}, 0, len(probes))
+	for _, probe := range probes {
+		probeResult := probe.Result
+		if probeResult == nil {
+			probeResult = &health.HealthCheckResult{}
+		}
+		probeURL := firstNonEmpty(probeResult.URL, testURLUsed)
+		probeRequestID, _ := a.logEndpointTestResult(testEndpoint, probeResult, probe.Err, probeURL)
+		if requestID == "" {
+			requestID = probeRequestID
+		}
+		if probe.Format == "" {
+			continue
+		}
+
+		formatResult := map[string]interface{}{
+			"format":        probe.Format,
+			"success":       probe.Err == nil,
+			"status_code":   probeResult.StatusCode,
+			"url":           probeURL,
+			"response_time": int(probeResult.Duration.Milliseconds()),
+		}
+		if probeRequestID != "" {
+			formatResult["request_id"] = probeRequestID
+		}
+		if probe.Err != nil {
+			formatResult["error"] = probe.Err.Error()
+		}
+		formatResults = append(formatResults, formatResult)
+	}
+
+	requestPreview := truncateForResponse(result.RequestBody)
+	responsePreview := truncateForResponse(result.ResponseBody)
+
+	responseData := map[string]interface{}{
+		"success":          checkErr == nil,
+		"message":          message,
+		"endpoint_id":      id,
+		"endpoint_name":    nameStr,
+		"status":           statusValue,
+		"response_time":    responseTime,
+		"status_code":      result.StatusCode,
+		"url":              testURLUsed,
+		"request_preview":  requestPreview,
+		"response_preview": responsePreview,
+		"timestamp":        now,
+	}
+
+	if requestID != "" {
+		responseData["request_id"] = requestID
+	}
+	if len(formatResults) > 0 {
+		responseData["format_results"] = formatResults
+	}
+	if len(result.RequestHeaders) > 0 {
+		responseData["request_headers"] = result.RequestHeaders
+	}
+	if len(result.ResponseHeaders) > 0 {
+		responseData["response_headers"] = result.ResponseHeaders
+	}
+	if result.Model != "" {
+		responseData["model"] = result.Model
+	}
+	if checkErr != nil {
+		responseData["error"] = errorMessage
+		a.addLog("warn", fmt.Sprintf("端点 '%s' (ID: %s) 测试失败: %s，响应时间: %dms", nameStr, id, errorMessage, responseTime))
+	} else {
+		a.addLog("info", fmt.Sprintf("端点 '%s' (ID: %s) 测试成功，响应时间: %dms", nameStr, id, responseTime))
+	}
+
+	return responseData
+}
+
+// TestAllEndpoints 测试所有端点
+func (a *App) TestAllEndpoints() map[string]interface{} {
+	runtime.LogInfo(a.ctx, "=== TestAllEndpoints 函数开始执行 ===")
+	runtime.LogInfo(a.ctx, "Testing all endpoints via Go API (统一架构)")
+
+	if a.db == nil {
+		runtime.LogError(a.ctx, "TestAllEndpoints: 数据库不可用")
+		return map[string]interface{}{
+			"results":       []interface{}{},
+			"total":         0,
+			"success_count": 0,
+			"message":       "批量测试失败：数据库不可用",
+			"success":       false,
+		}
+	}
+
+	rows, err := a.db.Query(`
+		SELECT id, name
+		FROM endpoints
+		ORDER BY priority DESC, created_at ASC
+	`)
+	if err != nil {
+		runtime.LogError(a.ctx, fmt.Sprintf("TestAllEndpoints: 查询端点列表失败: %v", err))
+		return map[string]interface{}{
+			"results":       []interface{}{},
+			"total":         0,
+			"success_count": 0,
+			"message":       fmt.Sprintf("批量测试失败：查询端点列表失败(%v)", err),
+			"success":       false,
+		}
+	}
+	defer rows.Close()
+
+	type endpointRef struct {
+		ID   string
+		Name string
+	}
+
+	var endpointRefs []endpointRef
+	for rows.Next() {
+		var ref endpointRef
+		if err := rows.Scan(&ref.ID, &ref.Name); err != nil {
+			runtime.LogError(a.ctx, fmt.Sprintf("TestAllEndpoints: 读取端点信息失败: %v", err))
+			continue
+		}
+		endpointRefs = append(endpointRefs, ref)
+	}
 
-	a.mutex.Lock()
-	a.running = true
-	a.mutex.Unlock()
+	if err := rows.Err(); err != nil {
+		runtime.LogError(a.ctx, fmt.Sprintf("TestAllEndpoints: 遍历端点行失败: %v", err))
+	}
 
-	runtime.LogInfo(a.ctx, "✅ 统一架构服务重启成功")
-	return "统一架构服务重启成功 (无HTTP服务器冲突)"
-}
+	// 添加批量测试开始的日志记录
+	a.addLog("info", fmt.Sprintf("开始批量测试 %d 个端点", len(endpointRefs)))
 
-// GetVersionInfo 获取版本信息
-func (a *App) GetVersionInfo() string {
-	return fmt.Sprintf("1.0.0 - %s", getCurrentTimestamp())
-}
+	results := make([]interface{}, 0, len(endpointRefs))
+	successCount := 0
 
-// Greet 返回问候语
-func (a *App) Greet(name string) string {
-	return fmt.Sprintf("Hello %s, Welcome to CCCC Proxy Desktop with Unified Architecture!", name)
+	for idx, ref := range endpointRefs {
+		if ref.Name == "" {
+			runtime.LogInfo(a.ctx, fmt.Sprintf("Testing endpoint %d: ID=%s", idx, ref.ID))
+		} else {
+			runtime.LogInfo(a.ctx, fmt.Sprintf("Testing endpoint %d: ID=%s, Name=%s", idx, ref.ID, ref.Name))
+		}
+
+		result := a.TestEndpoint(ref.ID)
+		results = append(results, result)
+
+		if success, ok := result["success"].(bool); ok && success {
+			successCount++
+		}
+
+		runtime.LogInfo(a.ctx, fmt.Sprintf("Endpoint %d test result: success=%v", idx, result["success"]))
+	}
+
+	a.addLog("info", fmt.Sprintf("批量测试完成，成功: %d/%d", successCount, len(results)))
+	runtime.LogInfo(a.ctx, fmt.Sprintf("TestAllEndpoints completed: success_count=%d, total=%d", successCount, len(results)))
+
+	return map[string]interface{}{
+		"results":       results,
+		"total":         len(results),
+		"success_count": successCount,
+		"message":       fmt.Sprintf("批量测试完成，成功: %d/%d", successCount, len(results)),
+		"success":       true,
+	}
 }
 
-// GetEndpoints 返回端点列表
-func (a *App) GetEndpoints() map[string]interface{} {
+const pingEndpointTimeout = 5 * time.Second
+
+// PingEndpoint 仅测试端点主机的网络连通性（不携带认证与请求体），用于区分网络问题与认证/模型问题
+func (a *App) PingEndpoint(id string) map[string]interface{} {
 	a.mutex.RLock()
-	defer a.mutex.RUnlock()
+	db := a.db
+	a.mutex.RUnlock()
 
-	runtime.LogInfo(a.ctx, "GetEndpoints: 开始获取端点列表")
+	if db == nil {
+		return map[string]interface{}{
+			"success":     false,
+			"message":     "数据库不可用",
+			"endpoint_id": id,
+		}
+	}
 
-	if a.db == nil {
-		runtime.LogError(a.ctx, "GetEndpoints: 数据库为空")
+	var name, urlAnthropic, urlOpenai sql.NullString
+	err := db.QueryRow("SELECT name, url_anthropic, url_openai FROM endpoints WHERE id = ?", id).Scan(&name, &urlAnthropic, &urlOpenai)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return map[string]interface{}{
+				"success":     false,
+				"message":     fmt.Sprintf("端点 %s 不存在", id),
+				"endpoint_id": id,
+			}
+		}
 		return map[string]interface{}{
-			"success": false,
-			"error":   "数据库不可用",
-			"data":    []interface{}{},
+			"success":     false,
+			"message":     fmt.Sprintf("查询端点失败: %v", err),
+			"endpoint_id": id,
 		}
 	}
 
-	query := `
-		SELECT id, name, url_anthropic, url_openai, endpoint_type, auth_type, auth_value,
-			   enabled, priority, tags, status, response_time, last_check, created_at, updated_at,
-			   model_rewrite_enabled, target_model, parameter_overrides, model_rewrite_rules
-		FROM endpoints
-		ORDER BY priority DESC, created_at ASC
-	`
+	// 同一主机只探测一次
+	var hosts []string
+	seen := map[string]bool{}
+	for _, raw := range []string{urlAnthropic.String, urlOpenai.String} {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+		parsed, err := url.Parse(raw)
+		if err != nil || parsed.Host == "" {
+			continue
+		}
+		base := parsed.Scheme + "://" + parsed.Host
+		if !seen[base] {
+			seen[base] = true
+			hosts = append(hosts, base)
+		}
+	}
 
-	rows, err := a.db.Query(query)
-	if err != nil {
-		runtime.LogError(a.ctx, fmt.Sprintf("Failed to query endpoints: %v", err))
+	if len(hosts) == 0 {
 		return map[string]interface{}{
-			"success": false,
-			"error":   fmt.Sprintf("查询端点失败: %v", err),
-			"data":    []interface{}{},
+			"success":     false,
+			"message":     "端点未配置有效的URL",
+			"endpoint_id": id,
 		}
 	}
-	defer rows.Close()
 
-	var endpoints []interface{}
-	for rows.Next() {
-		var (
-			id, name, urlAnthropic, urlOpenai, endpointType, authType, authValue sql.NullString
-			enabled                                                              sql.NullBool
-			priority                                                             sql.NullInt64
-			tagsJSON, status, lastCheck, createdAt, updatedAt                    sql.NullString
-			targetModel, parameterOverridesJSON, modelRewriteRulesJSON           sql.NullString
-			responseTime                                                         sql.NullInt64
-			modelRewriteEnabled                                                  sql.NullBool
-		)
+	// 与转发请求使用相同的代理与 TLS 设置
+	var endpointCfg config.EndpointConfig
+	if configs, err := a.queryEndpointConfigs("WHERE id = ?", false, id); err == nil && len(configs) > 0 {
+		endpointCfg = configs[0]
+	}
 
-		if err := rows.Scan(
-			&id,
-			&name,
-			&urlAnthropic,
-			&urlOpenai,
-			&endpointType,
-			&authType,
-			&authValue,
-			&enabled,
-			&priority,
-			&tagsJSON,
-			&status,
-			&responseTime,
-			&lastCheck,
-			&createdAt,
-			&updatedAt,
-			&modelRewriteEnabled,
-			&targetModel,
-			&parameterOverridesJSON,
-			&modelRewriteRulesJSON,
-		); err != nil {
-			runtime.LogError(a.ctx, fmt.Sprintf("Failed to scan endpoint row: %v", err))
-			continue
+	results := make([]map[string]interface{}, 0, len(hosts))
+	allReachable := true
+	for _, host := range hosts {
+		result := pingHost(host, endpointCfg, pingEndpointTimeout)
+		if reachable, _ := result["reachable"].(bool); !reachable {
+			allReachable = false
 		}
+		results = append(results, result)
+	}
 
-		enabledValue := true
-		if enabled.Valid {
-			enabledValue = enabled.Bool
+	nameStr := firstNonEmpty(name.String, id)
+	message := fmt.Sprintf("端点 %s 网络连通", nameStr)
+	if !allReachable {
+		message = fmt.Sprintf("端点 %s 网络不可达", nameStr)
+		a.addLog("warn", fmt.Sprintf("端点 '%s' (ID: %s) 连通性测试失败", nameStr, id))
+	}
+
+	return map[string]interface{}{
+		"success":       allReachable,
+		"message":       message,
+		"endpoint_id":   id,
+		"endpoint_name": nameStr,
+		"results":       results,
+		"timestamp":     getCurrentTimestamp(),
+	}
+}
+
+// pingHost 经端点代理对主机发送不带认证的 HEAD 请求（失败时回退 GET），任何HTTP响应都视为可达
+func pingHost(baseURL string, endpoint config.EndpointConfig, timeout time.Duration) map[string]interface{} {
+	result := map[string]interface{}{
+		"url":          baseURL,
+		"reachable":    false,
+		"dns_resolved": false,
+		"latency_ms":   0,
+	}
+
+	parsed, err := url.Parse(baseURL)
+	if err != nil {
+		result["error"] = err.Error()
+		return result
+	}
+
+	// 配置了代理时由代理解析目标主机，本地DNS解析结果没有意义
+	if endpoint.Proxy == nil {
+		if _, err := net.LookupHost(parsed.Hostname()); err != nil {
+			result["error"] = fmt.Sprintf("DNS解析失败: %v", err)
+			return result
 		}
+		result["dns_resolved"] = true
+	} else {
+		result["via_proxy"] = endpoint.Proxy.Type + "://" + endpoint.Proxy.Address
+	}
 
-		tags := decodeStringSlice(tagsJSON)
-		parameterOverrides := decodeStringMap(parameterOverridesJSON)
-		modelRewrite := buildModelRewriteMap(modelRewriteEnabled, targetModel, modelRewriteRulesJSON)
+	client, err := proxyclient.CreateHTTPClient(endpoint.Proxy, config.ProxyTimeoutConfig{
+		OverallRequest: timeout.String(),
+	})
+	if err != nil {
+		result["error"] = fmt.Sprintf("创建客户端失败: %v", err)
+		return result
+	}
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	defer client.CloseIdleConnections()
 
-		endpoint := map[string]interface{}{
-			"id":            id.String,
-			"name":          name.String,
-			"url_anthropic": urlAnthropic.String,
-			"url_openai":    urlOpenai.String,
-			"endpoint_type": endpointType.String,
-			"auth_type":     authType.String,
-			"auth_value":    authValue.String,
-			"enabled":       enabledValue,
-			"priority":      int(priority.Int64),
-			"tags":          tags,
-			"status":        status.String,
-			"response_time": int(responseTime.Int64),
-			"last_check":    lastCheck.String,
-			"created_at":    createdAt.String,
-			"updated_at":    updatedAt.String,
+	start := time.Now()
+	resp, err := client.Head(baseURL)
+	if err != nil {
+		var certErr *tls.CertificateVerificationError
+		if errors.As(err, &certErr) {
+			result["tls_valid"] = false
 		}
+		start = time.Now()
+		resp, err = client.Get(baseURL)
+	}
+	result["latency_ms"] = time.Since(start).Milliseconds()
 
-		if len(parameterOverrides) > 0 {
-			endpoint["parameter_overrides"] = parameterOverrides
+	if err != nil {
+		var certErr *tls.CertificateVerificationError
+		if errors.As(err, &certErr) {
+			// TLS握手阶段已经建立TCP连接，主机可达但证书无效
+			result["reachable"] = true
+			result["tls_valid"] = false
 		}
-		if modelRewrite != nil {
-			endpoint["model_rewrite"] = modelRewrite
+		result["error"] = err.Error()
+		return result
+	}
+	defer resp.Body.Close()
+
+	result["reachable"] = true
+	result["status_code"] = resp.StatusCode
+	if resp.TLS != nil {
+		result["tls_valid"] = true
+		result["tls_version"] = tls.VersionName(resp.TLS.Version)
+	}
+
+	return result
+}
+
+// summarizeFormatProbes 汇总各格式的探测结果：返回第一个失败的探测（全部成功时为第一个探测）与最长耗时
+func summarizeFormatProbes(probes []health.FormatProbeResult) (*health.HealthCheckResult, time.Duration) {
+	var representative *health.HealthCheckResult
+	var slowest time.Duration
+	representativeFailed := false
+	for _, probe := range probes {
+		if probe.Result == nil {
+			continue
 		}
-		if target := strings.TrimSpace(targetModel.String); target != "" {
-			endpoint["target_model"] = target
+		if representative == nil || (probe.Err != nil && !representativeFailed) {
+			representative = probe.Result
+			representativeFailed = probe.Err != nil
+		}
+		if probe.Result.Duration > slowest {
+			slowest = probe.Result.Duration
 		}
+	}
+	if representative == nil {
+		representative = &health.HealthCheckResult{}
+	}
+	return representative, slowest
+}
 
-		endpoints = append(endpoints, endpoint)
+// runtimeEndpoint 返回端点的探测结果对象（首次访问时按配置创建）；由 TestEndpoint、ProbeEndpointCapabilities 写入，
+// 桌面代理转发只在上游返回参数错误时记录不支持的参数，不读取这里的学习结果
+func (a *App) runtimeEndpoint(id string, cfg config.EndpointConfig) *endpoint.Endpoint {
+	if existing, ok := a.runtimeEndpoints.Load(id); ok {
+		return existing.(*endpoint.Endpoint)
 	}
+	ep := endpoint.NewEndpoint(cfg)
+	ep.ID = id
+	actual, _ := a.runtimeEndpoints.LoadOrStore(id, ep)
+	return actual.(*endpoint.Endpoint)
+}
 
-	runtime.LogInfo(a.ctx, fmt.Sprintf("GetEndpoints: 完成，获取到 %d 个端点", len(endpoints)))
+// learnUnsupportedParams 从代理请求的上游 4xx 错误中学习不支持的参数，供 GetEndpointLearning 查看
+func (a *App) learnUnsupportedParams(cfg config.EndpointConfig, errorBody, requestBody []byte) {
+	a.mutex.RLock()
+	db := a.db
+	a.mutex.RUnlock()
+	if db == nil {
+		return
+	}
 
-	// 添加详细的端点信息日志
-	for i, endpoint := range endpoints {
-		if ep, ok := endpoint.(map[string]interface{}); ok {
-			runtime.LogInfo(a.ctx, fmt.Sprintf("Endpoint[%d]: ID=%s, Name=%s, TargetModel=%v",
-				i, ep["id"], ep["name"], ep["target_model"]))
+	var id string
+	if err := db.QueryRow("SELECT id FROM endpoints WHERE name = ?", cfg.Name).Scan(&id); err != nil {
+		return
+	}
+	if learned := a.runtimeEndpoint(id, cfg).LearnUnsupportedParamsFromError(errorBody, requestBody); len(learned) > 0 {
+		a.addLog("info", fmt.Sprintf("端点 %s 不支持参数: %s", cfg.Name, strings.Join(learned, ", ")))
+	}
+}
+
+// lookupEndpointName 查询端点名称，端点不存在时返回错误结果
+func (a *App) lookupEndpointName(id string) (string, map[string]interface{}) {
+	a.mutex.RLock()
+	db := a.db
+	a.mutex.RUnlock()
+
+	if db == nil {
+		return "", map[string]interface{}{
+			"success":     false,
+			"message":     "数据库不可用",
+			"endpoint_id": id,
 		}
 	}
 
-	return map[string]interface{}{
-		"success": true,
-		"data":    endpoints,
+	var name sql.NullString
+	if err := db.QueryRow("SELECT name FROM endpoints WHERE id = ?", id).Scan(&name); err != nil {
+		if err == sql.ErrNoRows {
+			return "", map[string]interface{}{
+				"success":     false,
+				"message":     fmt.Sprintf("端点 %s 不存在", id),
+				"endpoint_id": id,
+			}
+		}
+		return "", map[string]interface{}{
+			"success":     false,
+			"message":     fmt.Sprintf("查询端点失败: %v", err),
+			"endpoint_id": id,
+		}
 	}
+
+	return firstNonEmpty(strings.TrimSpace(name.String), id), nil
 }
 
-// CreateEndpoint 创建新端点
-func (a *App) CreateEndpoint(endpointData map[string]interface{}) map[string]interface{} {
-	a.mutex.Lock()
-	defer a.mutex.Unlock()
+// TestModelRewriteRule 在保存前测试重写规则是否匹配示例模型名
+// pattern 支持 "源模式 -> 目标模型" 写法（与规则字符串格式一致），matchType 为 glob（默认）/regex/exact
+func (a *App) TestModelRewriteRule(pattern, matchType, sampleModel string) map[string]interface{} {
+	sourcePattern := strings.TrimSpace(pattern)
+	targetModel := ""
+	if parts := strings.SplitN(sourcePattern, "->", 2); len(parts) == 2 {
+		sourcePattern = strings.TrimSpace(parts[0])
+		targetModel = strings.TrimSpace(parts[1])
+	}
+	sampleModel = strings.TrimSpace(sampleModel)
+
+	if sourcePattern == "" {
+		return map[string]interface{}{
+			"success": false,
+			"message": "匹配模式不能为空",
+		}
+	}
+
+	normalizedType, err := modelrewrite.NormalizeMatchType(matchType)
+	if err != nil {
+		return map[string]interface{}{
+			"success":    false,
+			"message":    fmt.Sprintf("无效的匹配方式: %v", err),
+			"pattern":    sourcePattern,
+			"match_type": matchType,
+		}
+	}
+	matched, err := modelrewrite.MatchModelPattern(sourcePattern, normalizedType, sampleModel)
+	if err != nil {
+		return map[string]interface{}{
+			"success":    false,
+			"message":    fmt.Sprintf("无效的匹配模式: %v", err),
+			"pattern":    sourcePattern,
+			"match_type": normalizedType,
+		}
+	}
+
+	resultModel := sampleModel
+	if matched && targetModel != "" {
+		resultModel = targetModel
+	}
+
+	message := fmt.Sprintf("模式 %s 未匹配 %s", sourcePattern, sampleModel)
+	if matched {
+		message = fmt.Sprintf("模式 %s 匹配 %s", sourcePattern, sampleModel)
+	}
+
+	return map[string]interface{}{
+		"success":      true,
+		"message":      message,
+		"matched":      matched,
+		"pattern":      sourcePattern,
+		"match_type":   normalizedType,
+		"sample_model": sampleModel,
+		"target_model": targetModel,
+		"result_model": resultModel,
+	}
+}
 
-	runtime.LogInfo(a.ctx, "CreateEndpoint called")
+// GetEndpointHealthHistory 查询端点的健康检查历史（最新在前），并统计状态切换次数与可用率
+func (a *App) GetEndpointHealthHistory(id string, limit int) map[string]interface{} {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
 
 	if a.db == nil {
-		runtime.LogError(a.ctx, "Database not available")
 		return map[string]interface{}{
 			"success": false,
 			"message": "数据库不可用",
 		}
 	}
-
-	// 生成端点ID（使用UUID避免冲突）
-	endpointID := fmt.Sprintf("endpoint_%s", uuid.NewString())
-
-	name := strings.TrimSpace(getStringFromMap(endpointData, "name"))
-	if name == "" {
+	if strings.TrimSpace(id) == "" {
 		return map[string]interface{}{
 			"success": false,
-			"message": "端点名称不能为空",
+			"message": "端点ID不能为空",
 		}
 	}
+	if limit <= 0 || limit > healthHistoryLimitPerEndpoint {
+		limit = healthHistoryLimitPerEndpoint
+	}
 
-	urlAnthropic := strings.TrimSpace(getStringFromMap(endpointData, "url_anthropic"))
-	urlOpenai := strings.TrimSpace(getStringFromMap(endpointData, "url_openai"))
-
-	if urlAnthropic == "" && urlOpenai == "" {
+	entries, err := queryHealthHistory(a.db, id, limit)
+	if err != nil {
 		return map[string]interface{}{
 			"success": false,
-			"message": "至少需要配置一个URL",
+			"message": fmt.Sprintf("查询健康历史失败: %v", err),
 		}
 	}
 
-	endpointType := strings.TrimSpace(getStringFromMap(endpointData, "endpoint_type"))
-	if endpointType == "" {
-		endpointType = deduceEndpointType(urlAnthropic, urlOpenai)
+	history := make([]map[string]interface{}, 0, len(entries))
+	healthyCount := 0
+	transitions := 0
+	for i, entry := range entries {
+		if entry.Status == "healthy" {
+			healthyCount++
+		}
+		if i > 0 && entries[i-1].Status != entry.Status {
+			transitions++
+		}
+		item := map[string]interface{}{
+			"timestamp":     entry.Timestamp,
+			"status":        entry.Status,
+			"response_time": entry.ResponseTime,
+		}
+		if entry.Error != "" {
+			item["error"] = entry.Error
+		}
+		history = append(history, item)
 	}
 
-	authType := strings.TrimSpace(getStringFromMap(endpointData, "auth_type"))
-	if authType == "" {
-		authType = "none"
+	uptime := 0.0
+	if len(entries) > 0 {
+		uptime = float64(healthyCount) * 100 / float64(len(entries))
 	}
 
-	authValue := strings.TrimSpace(getStringFromMap(endpointData, "auth_value"))
+	return map[string]interface{}{
+		"success":        true,
+		"endpoint_id":    id,
+		"data":           history,
+		"count":          len(entries),
+		"transitions":    transitions,
+		"uptime_percent": uptime,
+	}
+}
 
-	enabled := extractBool(endpointData["enabled"], true)
-	priority := extractPriority(endpointData["priority"])
+// GetDeadLetters 查询死信队列（所有端点都失败的请求，最新在前）
+func (a *App) GetDeadLetters(limit int) map[string]interface{} {
+	a.mutex.RLock()
+	db := a.db
+	a.mutex.RUnlock()
 
-	tagsJSON := "[]"
-	if rawTags, exists := endpointData["tags"]; exists {
-		if serialised, err := serialiseStringSlice(rawTags, "[]"); err == nil {
-			tagsJSON = serialised
-		} else {
-			runtime.LogWarning(a.ctx, fmt.Sprintf("Invalid tags value for endpoint %s: %v", name, err))
+	if db == nil {
+		return map[string]interface{}{
+			"success": false,
+			"message": "数据库不可用",
 		}
 	}
-
-	parameterOverridesJSON := "{}"
-	if rawOverrides, exists := endpointData["parameter_overrides"]; exists {
-		if serialised, err := serialiseStringMap(rawOverrides, "{}"); err == nil {
-			parameterOverridesJSON = serialised
-		} else {
-			runtime.LogWarning(a.ctx, fmt.Sprintf("Invalid parameter_overrides for endpoint %s: %v", name, err))
-		}
+	if limit <= 0 || limit > deadLetterLimit {
+		limit = deadLetterLimit
 	}
 
-	modelRewritePayload, err := extractModelRewritePayload(endpointData["model_rewrite"])
+	entries, err := queryDeadLetters(db, 0, limit)
 	if err != nil {
-		runtime.LogWarning(a.ctx, fmt.Sprintf("Invalid model_rewrite for endpoint %s: %v", name, err))
-		modelRewritePayload = defaultModelRewritePayload()
+		return map[string]interface{}{
+			"success": false,
+			"message": fmt.Sprintf("查询死信队列失败: %v", err),
+		}
+	}
+
+	items := make([]map[string]interface{}, 0, len(entries))
+	for _, entry := range entries {
+		items = append(items, map[string]interface{}{
+			"id":                 entry.ID,
+			"request_id":         entry.RequestID,
+			"timestamp":          entry.Timestamp,
+			"method":             entry.Method,
+			"path":               entry.Path,
+			"raw_query":          entry.RawQuery,
+			"request_headers":    entry.RequestHeaders,
+			"request_body":       entry.RequestBody,
+			"client_type":        entry.ClientType,
+			"request_format":     entry.RequestFormat,
+			"final_status":       entry.FinalStatus,
+			"final_error":        entry.FinalError,
+			"attempts":           entry.Attempts,
+			"replay_count":       entry.ReplayCount,
+			"last_replay_at":     entry.LastReplayAt,
+			"last_replay_status": entry.LastReplayStatus,
+		})
 	}
 
-	createdAt := getCurrentTimestamp()
-
-	runtime.LogInfo(a.ctx, fmt.Sprintf(
-		"Creating endpoint: ID=%s, Name=%s, AnthropicURL=%s, OpenAIURL=%s, Type=%s",
-		endpointID, name, urlAnthropic, urlOpenai, endpointType,
-	))
+	return map[string]interface{}{
+		"success": true,
+		"data":    items,
+		"total":   len(items),
+	}
+}
 
-	result, err := a.db.Exec(`
-		INSERT INTO endpoints (
-			id, name, url_anthropic, url_openai, endpoint_type, auth_type, auth_value,
-			enabled, priority, tags, status, response_time, last_check, created_at, updated_at,
-			model_rewrite_enabled, target_model, parameter_overrides, model_rewrite_rules
-		)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-	`,
-		endpointID,
-		name,
-		urlAnthropic,
-		urlOpenai,
-		endpointType,
-		authType,
-		authValue,
-		enabled,
-		priority,
-		tagsJSON,
-		"healthy",
-		0,
-		"",
-		createdAt,
-		createdAt,
-		modelRewritePayload.Enabled,
-		modelRewritePayload.TargetModel,
-		parameterOverridesJSON,
-		modelRewritePayload.RulesJSON,
-	)
+// ReplayDeadLetter 通过代理重新发送一条死信请求。
+// 原始客户端凭证不会落库，重放时使用全局 Claude Code 认证token（未配置时为 hello 占位令牌）。
+func (a *App) ReplayDeadLetter(id int64) map[string]interface{} {
+	a.mutex.RLock()
+	db := a.db
+	a.mutex.RUnlock()
 
-	if err != nil {
-		runtime.LogError(a.ctx, fmt.Sprintf("Failed to create endpoint %s: %v", name, err))
+	if db == nil {
 		return map[string]interface{}{
 			"success": false,
-			"message": fmt.Sprintf("创建端点失败: %v", err),
+			"message": "数据库不可用",
 		}
 	}
 
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
+	entries, err := queryDeadLetters(db, id, 1)
+	if err != nil || len(entries) == 0 {
+		message := fmt.Sprintf("死信记录不存在: %d", id)
+		if err != nil {
+			message = fmt.Sprintf("查询死信记录失败: %v", err)
+		}
 		return map[string]interface{}{
 			"success": false,
-			"message": "创建端点后验证失败",
+			"message": message,
 		}
 	}
-
-	if rowsAffected == 0 {
+	entry := entries[0]
+	if entry.RequestBody == "" && entry.Method != http.MethodGet {
 		return map[string]interface{}{
 			"success": false,
-			"message": "端点创建失败：没有插入任何记录",
+			"message": "该死信未保存请求体（受日志记录策略限制），无法重放",
 		}
 	}
 
-	a.addLog("info", fmt.Sprintf("端点 '%s' (ID: %s) 已成功创建", name, endpointID))
-
-	return map[string]interface{}{
-		"success":       true,
-		"message":       fmt.Sprintf("端点 '%s' 创建成功", name),
-		"id":            endpointID,
-		"endpoint_name": name,
-		"rows_affected": rowsAffected,
+	target := entry.Path
+	if entry.RawQuery != "" {
+		target += "?" + entry.RawQuery
 	}
-}
-
-// UpdateEndpoint 更新端点
-func (a *App) UpdateEndpoint(id string, endpointData map[string]interface{}) map[string]interface{} {
-	a.mutex.Lock()
-	defer a.mutex.Unlock()
-
-	if a.db == nil {
-		runtime.LogError(a.ctx, "Database not available")
+	recorder, err := a.replayProxyRequest(entry.Method, target, entry.RequestHeaders, entry.RequestBody, "")
+	if err != nil {
 		return map[string]interface{}{
 			"success": false,
-			"message": "数据库不可用",
+			"message": fmt.Sprintf("构建重放请求失败: %v", err),
 		}
 	}
 
-	// 构建动态更新SQL
-	setParts := []string{}
-	args := []interface{}{}
-
-	if rawName, exists := endpointData["name"]; exists {
-		if name, ok := rawName.(string); ok && strings.TrimSpace(name) != "" {
-			setParts = append(setParts, "name = ?")
-			args = append(args, strings.TrimSpace(name))
-		}
+	replayedAt := time.Now().Format(time.RFC3339)
+	if _, err := db.Exec(`
+		UPDATE dead_letters
+		SET replay_count = replay_count + 1, last_replay_at = ?, last_replay_status = ?
+		WHERE id = ?
+	`, replayedAt, recorder.Code, entry.ID); err != nil {
+		runtime.LogWarning(a.ctx, fmt.Sprintf("更新死信重放状态失败 (%d): %v", entry.ID, err))
 	}
 
-	if rawURL, exists := endpointData["url_anthropic"]; exists {
-		if value, ok := rawURL.(string); ok {
-			setParts = append(setParts, "url_anthropic = ?")
-			args = append(args, strings.TrimSpace(value))
-		}
+	succeeded := recorder.Code >= 200 && recorder.Code < 300
+	level := "info"
+	if !succeeded {
+		level = "warn"
 	}
+	a.addLog(level, fmt.Sprintf("重放死信请求 %s，状态码 %d", entry.RequestID, recorder.Code))
 
-	if rawURL, exists := endpointData["url_openai"]; exists {
-		if value, ok := rawURL.(string); ok {
-			setParts = append(setParts, "url_openai = ?")
-			args = append(args, strings.TrimSpace(value))
-		}
+	responseBody, truncated := truncateStringForLog(recorder.Body.String(), healthLogPreviewLimit)
+	return map[string]interface{}{
+		"success":                 succeeded,
+		"message":                 fmt.Sprintf("重放完成，状态码 %d", recorder.Code),
+		"status_code":             recorder.Code,
+		"response_body":           responseBody,
+		"response_body_truncated": truncated,
+		"replayed_at":             replayedAt,
 	}
+}
 
-	if rawType, exists := endpointData["endpoint_type"]; exists {
-		if value, ok := rawType.(string); ok && strings.TrimSpace(value) != "" {
-			setParts = append(setParts, "endpoint_type = ?")
-			args = append(args, strings.TrimSpace(value))
-		}
-	}
+// replayEndpointKey 重放请求固定路由端点的 context key，仅供内部重放使用，客户端无法通过请求头指定
+type replayEndpointKey struct{}
 
-	if rawAuthType, exists := endpointData["auth_type"]; exists {
-		if value, ok := rawAuthType.(string); ok && strings.TrimSpace(value) != "" {
-			setParts = append(setParts, "auth_type = ?")
-			args = append(args, strings.TrimSpace(value))
-		}
-	}
+// replayEndpointFromContext 返回重放请求固定的端点名称，未固定时为空
+func replayEndpointFromContext(ctx context.Context) string {
+	name, _ := ctx.Value(replayEndpointKey{}).(string)
+	return name
+}
 
-	if rawAuthValue, exists := endpointData["auth_value"]; exists {
-		if value, ok := rawAuthValue.(string); ok {
-			setParts = append(setParts, "auth_value = ?")
-			args = append(args, strings.TrimSpace(value))
-		}
-	}
+// replaySkippedHeaders 重放时不沿用的请求头：凭证与长度重新生成，组织/项目头在日志中已脱敏
+var replaySkippedHeaders = map[string]bool{
+	"authorization":             true,
+	"x-api-key":                 true,
+	"content-length":            true,
+	"openai-organization":       true,
+	"openai-project":            true,
+	"anthropic-organization-id": true,
+}
 
-	if rawEnabled, exists := endpointData["enabled"]; exists {
-		setParts = append(setParts, "enabled = ?")
-		args = append(args, extractBool(rawEnabled, true))
+// queryPinnedEndpoint 按名称查找重放固定的端点，使用 utils.MatchEndpointName 匹配：精确匹配优先，
+// 其次忽略大小写、唯一前缀、唯一子串，日志中记录的旧名称与当前端点名大小写或后缀不同时仍能重放；不唯一时返回候选列表
+func (a *App) queryPinnedEndpoint(name string) ([]config.EndpointConfig, error) {
+	all, err := a.queryEndpointConfigs("", false)
+	if err != nil {
+		return nil, err
 	}
-
-	if rawPriority, exists := endpointData["priority"]; exists {
-		setParts = append(setParts, "priority = ?")
-		args = append(args, extractPriority(rawPriority))
+	names := make([]string, 0, len(all))
+	for _, endpoint := range all {
+		names = append(names, endpoint.Name)
 	}
-
-	if rawTags, exists := endpointData["tags"]; exists {
-		if serialised, err := serialiseStringSlice(rawTags, "[]"); err == nil {
-			setParts = append(setParts, "tags = ?")
-			args = append(args, serialised)
-		} else {
-			runtime.LogWarning(a.ctx, fmt.Sprintf("Invalid tags update for endpoint %s: %v", id, err))
-		}
+	matched, err := utils.MatchEndpointName(names, name)
+	if err != nil {
+		return nil, err
 	}
-
-	if rawOverrides, exists := endpointData["parameter_overrides"]; exists {
-		if serialised, err := serialiseStringMap(rawOverrides, "{}"); err == nil {
-			setParts = append(setParts, "parameter_overrides = ?")
-			args = append(args, serialised)
-		} else {
-			runtime.LogWarning(a.ctx, fmt.Sprintf("Invalid parameter_overrides update for endpoint %s: %v", id, err))
+	for _, endpoint := range all {
+		if endpoint.Name == matched {
+			return []config.EndpointConfig{endpoint}, nil
 		}
 	}
+	return nil, nil
+}
 
-	// 检查是否有model_rewrite更新，如果有，target_model更新应该在model_rewrite处理中
-	hasModelRewriteUpdate := false
-	if rawModelRewrite, exists := endpointData["model_rewrite"]; exists {
-		payload, err := extractModelRewritePayload(rawModelRewrite)
-		if err != nil {
-			runtime.LogWarning(a.ctx, fmt.Sprintf("Invalid model_rewrite update for endpoint %s: %v", id, err))
-		} else {
-			hasModelRewriteUpdate = true
-			setParts = append(setParts, "model_rewrite_enabled = ?")
-			args = append(args, payload.Enabled)
-			setParts = append(setParts, "target_model = ?")
-			args = append(args, payload.TargetModel)
-			setParts = append(setParts, "model_rewrite_rules = ?")
-			args = append(args, payload.RulesJSON)
-			runtime.LogInfo(a.ctx, fmt.Sprintf("Adding model_rewrite update with target_model: '%s'", payload.TargetModel))
-		}
+// replayProxyRequest 通过代理重新发送一条已记录的请求，pinnedEndpoint 非空时只路由到该端点（不受启用/健康状态限制）。
+// 原始客户端凭证不会落库，重放时使用全局 Claude Code 认证token（未配置时为 hello 占位令牌）
+func (a *App) replayProxyRequest(method, target string, headers map[string]string, body, pinnedEndpoint string) (*replayResponseRecorder, error) {
+	ctx := context.Background()
+	if pinnedEndpoint != "" {
+		ctx = context.WithValue(ctx, replayEndpointKey{}, pinnedEndpoint)
 	}
-
-	// 只有在没有model_rewrite更新时才处理单独的target_model更新
-	if !hasModelRewriteUpdate {
-		if rawTargetModel, exists := endpointData["target_model"]; exists {
-			if value, ok := rawTargetModel.(string); ok {
-				setParts = append(setParts, "target_model = ?")
-				args = append(args, strings.TrimSpace(value))
-				runtime.LogInfo(a.ctx, fmt.Sprintf("Adding standalone target_model update: '%s'", strings.TrimSpace(value)))
-			}
-		}
+	req, err := http.NewRequestWithContext(ctx, method, target, strings.NewReader(body))
+	if err != nil {
+		return nil, err
 	}
-
-	if len(setParts) == 0 {
-		return map[string]interface{}{
-			"success": false,
-			"message": "没有提供要更新的字段",
+	for key, value := range headers {
+		if replaySkippedHeaders[strings.ToLower(key)] {
+			continue
 		}
+		req.Header.Set(key, value)
 	}
+	token := strings.TrimSpace(a.getClaudeCodeAuthToken())
+	if token == "" {
+		token = "hello"
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
 
-	// 添加更新时间和ID
-	setParts = append(setParts, "updated_at = ?")
-	args = append(args, getCurrentTimestamp())
-	args = append(args, id)
+	recorder := newReplayResponseRecorder()
+	a.handleProxyRequest(recorder, req)
+	return recorder, nil
+}
 
-	sql := fmt.Sprintf("UPDATE endpoints SET %s WHERE id = ?", strings.Join(setParts, ", "))
+// replayResponseRecorder 在内存中收集重放请求的响应状态码、响应头与响应体
+type replayResponseRecorder struct {
+	Code        int
+	Body        bytes.Buffer
+	header      http.Header
+	wroteHeader bool
+}
 
-	// 添加详细的调试信息
-	runtime.LogInfo(a.ctx, fmt.Sprintf("Update SQL: %s", sql))
-	runtime.LogInfo(a.ctx, fmt.Sprintf("Update Args: %v", args))
+func newReplayResponseRecorder() *replayResponseRecorder {
+	return &replayResponseRecorder{Code: http.StatusOK, header: make(http.Header)}
+}
 
-	result, err := a.db.Exec(sql, args...)
-	if err != nil {
-		runtime.LogError(a.ctx, fmt.Sprintf("Failed to update endpoint %s: %v", id, err))
-		return map[string]interface{}{
-			"success": false,
-			"message": fmt.Sprintf("更新端点失败: %v", err),
-		}
+func (rec *replayResponseRecorder) Header() http.Header {
+	return rec.header
+}
+
+func (rec *replayResponseRecorder) WriteHeader(status int) {
+	if rec.wroteHeader {
+		return
 	}
+	rec.wroteHeader = true
+	rec.Code = status
+}
 
-	// 检查影响的行数
-	rowsAffected, _ := result.RowsAffected()
-	runtime.LogInfo(a.ctx, fmt.Sprintf("Update affected %d rows", rowsAffected))
+func (rec *replayResponseRecorder) Write(p []byte) (int, error) {
+	rec.WriteHeader(http.StatusOK)
+	return rec.Body.Write(p)
+}
 
-	if rowsAffected == 0 {
-		runtime.LogWarning(a.ctx, fmt.Sprintf("No rows affected when updating endpoint %s", id))
-		return map[string]interface{}{
-			"success": false,
-			"message": "没有找到要更新的端点",
-		}
+// replayCandidateLimit ReplayLastFailed 向前查找的失败日志条数
+const replayCandidateLimit = 50
+
+// findLastFailedRequest 返回最近一条最终失败且请求体完整落库的请求日志（同一请求后续尝试成功的不算失败）
+func findLastFailedRequest(requestLogger *logger.Logger) (*logger.RequestLog, error) {
+	failedLogs, _, err := requestLogger.GetLogs(replayCandidateLimit, 0, true)
+	if err != nil {
+		return nil, err
 	}
 
-	runtime.LogInfo(a.ctx, fmt.Sprintf("Successfully updated endpoint: %s", id))
-	a.addLog("info", fmt.Sprintf("端点 %s 已更新", id))
+	checked := map[string]bool{}
+	for _, log := range failedLogs {
+		if log.RequestID == "" || checked[log.RequestID] {
+			continue
+		}
+		checked[log.RequestID] = true
+		if log.OriginalRequestBody == "" || len(log.OriginalRequestBody) < log.RequestBodySize {
+			continue // 请求体未落库或被截断，无法原样重放
+		}
 
-	return map[string]interface{}{
-		"success": true,
-		"message": "端点更新成功 (通过Go API)",
+		attempts, err := requestLogger.GetAllLogsByRequestID(log.RequestID)
+		if err != nil {
+			return nil, err
+		}
+		succeeded := false
+		for _, attempt := range attempts {
+			if attempt.StatusCode >= 200 && attempt.StatusCode < 400 && attempt.Error == "" {
+				succeeded = true
+				break
+			}
+		}
+		if !succeeded {
+			return log, nil
+		}
 	}
+	return nil, nil
 }
 
-// DeleteEndpoint 删除端点
-func (a *App) DeleteEndpoint(id string) map[string]interface{} {
-	a.mutex.Lock()
-	defer a.mutex.Unlock()
-
-	runtime.LogInfo(a.ctx, fmt.Sprintf("DeleteEndpoint called with ID: %s", id))
+// ReplayLastFailed 找到最近一次失败的请求并重新发送：endpointID 非空时固定发往该端点，
+// 否则发往原请求最后尝试的端点，便于修复端点后立即验证
+func (a *App) ReplayLastFailed(endpointID string) map[string]interface{} {
+	a.mutex.RLock()
+	requestLogger := a.requestLogger
+	a.mutex.RUnlock()
 
-	if a.db == nil {
-		runtime.LogError(a.ctx, "Database not available")
+	if requestLogger == nil {
 		return map[string]interface{}{
 			"success": false,
-			"message": "数据库不可用",
+			"message": "请求日志不可用",
 		}
 	}
 
-	// 检查ID是否为空
-	if strings.TrimSpace(id) == "" {
-		runtime.LogError(a.ctx, "Empty endpoint ID provided")
+	a.flushRequestLogs()
+	failed, err := findLastFailedRequest(requestLogger)
+	if err != nil {
 		return map[string]interface{}{
 			"success": false,
-			"message": "端点ID不能为空",
+			"message": fmt.Sprintf("查询失败请求失败: %v", err),
 		}
 	}
-
-	// 先检查端点是否存在，同时获取端点名称用于日志
-	var existingID, endpointName sql.NullString
-	err := a.db.QueryRow("SELECT id, name FROM endpoints WHERE id = ?", id).Scan(&existingID, &endpointName)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			runtime.LogError(a.ctx, fmt.Sprintf("Endpoint not found with ID: %s", id))
-			return map[string]interface{}{
-				"success": false,
-				"message": fmt.Sprintf("端点不存在: %s", id),
-			}
-		}
-		runtime.LogError(a.ctx, fmt.Sprintf("Error checking endpoint existence: %v", err))
+	if failed == nil {
 		return map[string]interface{}{
 			"success": false,
-			"message": fmt.Sprintf("检查端点失败: %v", err),
+			"message": "没有可重放的失败请求（请求体未记录或已截断的请求无法重放）",
 		}
 	}
 
-	// 执行删除操作
-	result, err := a.db.Exec("DELETE FROM endpoints WHERE id = ?", id)
-	if err != nil {
-		runtime.LogError(a.ctx, fmt.Sprintf("Failed to delete endpoint %s: %v", id, err))
+	endpointName := strings.TrimSpace(failed.Endpoint)
+	if id := strings.TrimSpace(endpointID); id != "" {
+		name, failure := a.lookupEndpointName(id)
+		if failure != nil {
+			return failure
+		}
+		endpointName = name
+	}
+	if endpointName == "" || endpointName == "fallback" || endpointName == "authorization" {
 		return map[string]interface{}{
-			"success": false,
-			"message": fmt.Sprintf("删除端点失败: %v", err),
+			"success":    false,
+			"message":    fmt.Sprintf("请求 %s 未记录具体端点，请指定要重放的端点", failed.RequestID),
+			"request_id": failed.RequestID,
 		}
 	}
 
-	// 检查是否真的删除了记录
-	rowsAffected, err := result.RowsAffected()
+	target := failed.OriginalRequestURL
+	if target == "" {
+		target = failed.Path
+	}
+	recorder, err := a.replayProxyRequest(failed.Method, target, failed.OriginalRequestHeaders, failed.OriginalRequestBody, endpointName)
 	if err != nil {
-		runtime.LogError(a.ctx, fmt.Sprintf("Error getting rows affected for endpoint %s: %v", id, err))
 		return map[string]interface{}{
 			"success": false,
-			"message": "获取删除结果失败",
+			"message": fmt.Sprintf("构建重放请求失败: %v", err),
 		}
 	}
 
-	if rowsAffected == 0 {
-		runtime.LogError(a.ctx, fmt.Sprintf("No rows deleted for endpoint ID: %s", id))
-		return map[string]interface{}{
-			"success": false,
-			"message": fmt.Sprintf("没有删除任何记录，端点ID: %s", id),
-		}
+	succeeded := recorder.Code >= 200 && recorder.Code < 300
+	level := "info"
+	if !succeeded {
+		level = "warn"
 	}
+	a.addLog(level, fmt.Sprintf("重放失败请求 %s 到端点 %s，状态码 %d", failed.RequestID, endpointName, recorder.Code))
 
-	endpointNameStr := "未知端点"
-	if endpointName.Valid && endpointName.String != "" {
-		endpointNameStr = endpointName.String
+	responseBody, truncated := truncateStringForLog(recorder.Body.String(), healthLogPreviewLimit)
+	return map[string]interface{}{
+		"success":                 succeeded,
+		"message":                 fmt.Sprintf("重放完成，状态码 %d", recorder.Code),
+		"request_id":              failed.RequestID,
+		"original_status":         failed.StatusCode,
+		"original_error":          failed.Error,
+		"endpoint":                endpointName,
+		"status_code":             recorder.Code,
+		"response_body":           responseBody,
+		"response_body_truncated": truncated,
+		"replayed_at":             time.Now().Format(time.RFC3339),
 	}
+}
 
-	runtime.LogInfo(a.ctx, fmt.Sprintf("Successfully deleted endpoint '%s' with ID: %s (rows affected: %d)", endpointNameStr, id, rowsAffected))
+// GetEndpointLearning 查看端点测试与能力探测得到的能力（不支持的参数、原生Codex格式、认证头、count_tokens支持）；
+// 不支持的参数同时来自代理请求的上游参数错误，其余结果仅来自 TestEndpoint/ProbeEndpointCapabilities；学习结果不会改变代理请求
+func (a *App) GetEndpointLearning(id string) map[string]interface{} {
+	name, failure := a.lookupEndpointName(id)
+	if failure != nil {
+		return failure
+	}
 
-	// 添加删除操作的日志记录
-	a.addLog("info", fmt.Sprintf("端点 '%s' (ID: %s) 已成功删除", endpointNameStr, id))
+	var snapshot endpoint.LearningSnapshot
+	if existing, ok := a.runtimeEndpoints.Load(id); ok {
+		snapshot = existing.(*endpoint.Endpoint).GetLearningSnapshot()
+	}
+	if snapshot.UnsupportedParams == nil {
+		snapshot.UnsupportedParams = []string{}
+	}
 
 	return map[string]interface{}{
-		"success":       true,
-		"message":       fmt.Sprintf("端点 '%s' 删除成功", endpointNameStr),
-		"endpoint_name": endpointNameStr,
-		"rows_affected": rowsAffected,
+		"success":              true,
+		"endpoint_id":          id,
+		"endpoint_name":        name,
+		"source":               "probe",
+		"unsupported_params":   snapshot.UnsupportedParams,
+		"native_codex_format":  snapshot.NativeCodexFormat,
+		"detected_auth_header": snapshot.DetectedAuthHeader,
+		"count_tokens_support": snapshot.CountTokensSupport,
+		"streaming_support":    snapshot.StreamingSupport,
+		"vision_support":       snapshot.VisionSupport,
+		"max_tokens_field":     snapshot.MaxTokensField,
 	}
 }
 
-// TestEndpoint 测试端点
-func (a *App) TestEndpoint(id string) map[string]interface{} {
-	a.mutex.Lock()
-	defer a.mutex.Unlock()
+// ResetEndpointLearning 清除端点的探测结果，端点能力变化后可重新测试或探测
+func (a *App) ResetEndpointLearning(id string) map[string]interface{} {
+	name, failure := a.lookupEndpointName(id)
+	if failure != nil {
+		return failure
+	}
 
-	if a.db == nil {
+	if existing, ok := a.runtimeEndpoints.Load(id); ok {
+		existing.(*endpoint.Endpoint).ResetLearning()
+	}
+
+	runtime.LogInfo(a.ctx, fmt.Sprintf("Endpoint learning reset: %s (%s)", name, id))
+	a.addLog("info", fmt.Sprintf("端点 '%s' 的学习结果已重置", name))
+
+	return map[string]interface{}{
+		"success":     true,
+		"message":     fmt.Sprintf("端点 '%s' 的学习结果已重置", name),
+		"endpoint_id": id,
+	}
+}
+
+// ProbeEndpointCapabilities 主动发送少量探测请求，确定端点是否支持流式、工具调用、/responses、图片输入，
+// 以及可用的 max_tokens 参数名和认证头；结果写入端点运行时学习字段，并以能力矩阵返回
+func (a *App) ProbeEndpointCapabilities(id string) map[string]interface{} {
+	if _, failure := a.lookupEndpointName(id); failure != nil {
+		return failure
+	}
+
+	configs, err := a.queryEndpointConfigs("WHERE id = ?", false, id)
+	if err != nil || len(configs) == 0 {
+		message := "端点不存在"
+		if err != nil {
+			message = fmt.Sprintf("查询端点失败: %v", err)
+		}
 		return map[string]interface{}{
 			"success":     false,
-			"message":     "数据库不可用",
+			"message":     message,
 			"endpoint_id": id,
 		}
 	}
+	cfg := configs[0]
 
-	// 重新加载配置以确保获取最新的默认模型设置
-	if a.config == nil {
-		a.LoadConfig()
-	}
-
-	// 重置健康检查器以使用最新的默认模型
-	a.healthChecker = nil
-	if err := a.initModelRewriterAndHealthChecker(); err != nil {
-		runtime.LogError(a.ctx, fmt.Sprintf("Failed to initialize health checker: %v", err))
+	a.mutex.Lock()
+	initErr := a.initModelRewriterAndHealthChecker()
+	checker := a.healthChecker
+	a.mutex.Unlock()
+	if initErr != nil || checker == nil {
 		return map[string]interface{}{
 			"success":     false,
-			"message":     fmt.Sprintf("初始化健康检查器失败: %v", err),
+			"message":     fmt.Sprintf("初始化健康检查器失败: %v", initErr),
 			"endpoint_id": id,
 		}
 	}
 
-	var (
-		name, urlAnthropic, urlOpenai, endpointType, authType, authValue, tagsJSON sql.NullString
-		enabled                                                                    sql.NullBool
-		priority                                                                   sql.NullInt64
-		modelRewriteEnabled                                                        sql.NullBool
-		targetModel, parameterOverridesJSON, modelRewriteRulesJSON                 sql.NullString
-	)
-
-	err := a.db.QueryRow(`
-		SELECT name, url_anthropic, url_openai, endpoint_type, auth_type, auth_value,
-		       enabled, priority, tags, model_rewrite_enabled, target_model,
-		       parameter_overrides, model_rewrite_rules
-		FROM endpoints
-		WHERE id = ?
-	`, id).Scan(
-		&name,
-		&urlAnthropic,
-		&urlOpenai,
-		&endpointType,
-		&authType,
-		&authValue,
-		&enabled,
-		&priority,
-		&tagsJSON,
-		&modelRewriteEnabled,
-		&targetModel,
-		&parameterOverridesJSON,
-		&modelRewriteRulesJSON,
-	)
-
+	probeEndpoint := endpoint.NewEndpoint(cfg)
+	probeEndpoint.ID = id
+	report, err := checker.ProbeCapabilities(probeEndpoint)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return map[string]interface{}{
-				"success":     false,
-				"message":     fmt.Sprintf("端点 %s 不存在", id),
-				"endpoint_id": id,
-			}
-		}
+		runtime.LogWarning(a.ctx, fmt.Sprintf("端点能力探测失败 (%s): %v", cfg.Name, err))
 		return map[string]interface{}{
 			"success":     false,
-			"message":     fmt.Sprintf("查询端点失败: %v", err),
+			"message":     fmt.Sprintf("端点 '%s' 能力探测失败: %v", cfg.Name, err),
 			"endpoint_id": id,
 		}
 	}
 
-	nameStr := strings.TrimSpace(name.String)
-	if nameStr == "" {
-		nameStr = id
-	}
+	a.applyCapabilityReport(a.runtimeEndpoint(id, cfg), cfg, report)
 
-	enabledValue := true
-	if enabled.Valid {
-		enabledValue = enabled.Bool
+	capabilities := make(map[string]interface{}, len(report.Results))
+	for _, result := range report.Results {
+		capabilities[result.Capability] = map[string]interface{}{
+			"supported":   result.Supported,
+			"status_code": result.StatusCode,
+			"detail":      result.Detail,
+		}
 	}
 
-	priorityValue := int(priority.Int64)
-	if !priority.Valid || priorityValue <= 0 {
-		priorityValue = 10
+	runtime.LogInfo(a.ctx, fmt.Sprintf("Endpoint capabilities probed: %s (%s)", cfg.Name, id))
+	a.addLog("info", fmt.Sprintf("端点 '%s' 能力探测完成", cfg.Name))
+
+	return map[string]interface{}{
+		"success":          true,
+		"message":          fmt.Sprintf("端点 '%s' 能力探测完成", cfg.Name),
+		"endpoint_id":      id,
+		"endpoint_name":    cfg.Name,
+		"format":           report.Format,
+		"model":            report.Model,
+		"auth_header":      report.AuthHeader,
+		"max_tokens_field": report.MaxTokensField,
+		"capabilities":     capabilities,
 	}
+}
 
-	endpointTags := decodeStringSlice(tagsJSON)
+// applyCapabilityReport 将能力探测结果写入端点运行时学习字段；无法判断的能力保留原有学习结果，显式配置的 supports_responses 优先
+func (a *App) applyCapabilityReport(ep *endpoint.Endpoint, cfg config.EndpointConfig, report *health.CapabilityReport) {
+	if report.AuthHeader != "" {
+		ep.AuthHeaderMutex.Lock()
+		ep.DetectedAuthHeader = report.AuthHeader
+		ep.AuthHeaderMutex.Unlock()
+	}
 
-	modelRewriteCfg, mrErr := buildModelRewriteConfigFromRow(modelRewriteEnabled, targetModel, modelRewriteRulesJSON)
-	if mrErr != nil {
-		runtime.LogWarning(a.ctx, fmt.Sprintf("Failed to parse model rewrite config for endpoint %s: %v", id, mrErr))
+	if tools := report.Result(health.CapabilityTools); tools != nil && tools.Supported != nil && !*tools.Supported {
+		ep.LearnUnsupportedParam("tools")
+		ep.LearnUnsupportedParam("tool_choice")
 	}
 
-	cfg := config.EndpointConfig{
-		Name:         nameStr,
-		URLAnthropic: strings.TrimSpace(urlAnthropic.String),
-		URLOpenAI:    strings.TrimSpace(urlOpenai.String),
-		AuthType:     normalizeAuthType(authType.String),
-		AuthValue:    strings.TrimSpace(authValue.String),
-		Enabled:      enabledValue,
-		Priority:     priorityValue,
-		Tags:         endpointTags,
+	if responses := report.Result(health.CapabilityResponses); responses != nil && responses.Supported != nil && cfg.SupportsResponses == nil {
+		ep.SetNativeCodexSupport(*responses.Supported)
 	}
 
-	if modelRewriteCfg != nil {
-		cfg.ModelRewrite = modelRewriteCfg
+	var streaming, vision *bool
+	if result := report.Result(health.CapabilityStreaming); result != nil {
+		streaming = result.Supported
+	}
+	if result := report.Result(health.CapabilityVision); result != nil {
+		vision = result.Supported
 	}
+	ep.MarkCapabilities(streaming, vision, report.MaxTokensField)
+}
 
-	testEndpoint := endpoint.NewEndpoint(cfg)
-	testEndpoint.ID = id
-	testEndpoint.Enabled = enabledValue
-	testEndpoint.Tags = endpointTags
-	testEndpoint.AuthValue = cfg.AuthValue
-	testEndpoint.AuthType = cfg.AuthType
+// ValidateConfig 检查所有端点的模型重写配置（规则完整性、模式语法、重复模式），供界面在请求失败前提示配置问题
+func (a *App) ValidateConfig() map[string]interface{} {
+	a.mutex.RLock()
+	db := a.db
+	a.mutex.RUnlock()
 
-	if endpointTypeStr := strings.TrimSpace(endpointType.String); endpointTypeStr != "" {
-		testEndpoint.EndpointType = endpointTypeStr
+	if db == nil {
+		return map[string]interface{}{
+			"success": false,
+			"message": "数据库不可用",
+		}
 	}
 
-	if modelRewriteCfg != nil {
-		testEndpoint.ModelRewrite = modelRewriteCfg
+	endpoints, err := a.queryEndpointConfigs("", false)
+	if err != nil {
+		return map[string]interface{}{
+			"success": false,
+			"message": fmt.Sprintf("读取端点失败: %v", err),
+		}
 	}
 
-	if parameterOverrides := decodeStringMap(parameterOverridesJSON); len(parameterOverrides) > 0 {
-		testEndpoint.ParameterOverrides = parameterOverrides
+	problems := validateEndpointConfigs(endpoints)
+	return map[string]interface{}{
+		"success":  true,
+		"valid":    len(problems) == 0,
+		"problems": problems,
+		"message":  fmt.Sprintf("检查了 %d 个端点，发现 %d 个配置问题", len(endpoints), len(problems)),
 	}
+}
 
-	result, checkErr := a.healthChecker.CheckEndpointWithDetails(testEndpoint)
-	if result == nil {
-		result = &health.HealthCheckResult{}
+// validateEndpointConfigs 返回端点配置问题列表（endpoint/severity/message）：
+// 无效的模型重写规则，以及重写目标不在端点 allowed_models 中（请求必然被该端点跳过）
+func validateEndpointConfigs(endpoints []config.EndpointConfig) []map[string]interface{} {
+	problems := []map[string]interface{}{}
+	for _, endpoint := range endpoints {
+		if endpoint.ModelRewrite == nil {
+			continue
+		}
+		if err := config.ValidateModelRewriteConfig(endpoint.ModelRewrite, fmt.Sprintf("endpoint '%s'", endpoint.Name)); err != nil {
+			problems = append(problems, map[string]interface{}{
+				"endpoint": endpoint.Name,
+				"severity": "error",
+				"message":  err.Error(),
+			})
+		}
+		if !endpoint.ModelRewrite.Enabled || len(endpoint.AllowedModels) == 0 {
+			continue
+		}
+		for _, rule := range endpoint.ModelRewrite.Rules {
+			target := strings.TrimSpace(rule.TargetModel)
+			if target == "" || endpointAllowsModel(endpoint.AllowedModels, target) {
+				continue
+			}
+			problems = append(problems, map[string]interface{}{
+				"endpoint": endpoint.Name,
+				"severity": "warning",
+				"message":  fmt.Sprintf("模型重写规则 %s -> %s 的目标模型不在 allowed_models 中，匹配的请求将跳过该端点", rule.SourcePattern, target),
+			})
+		}
 	}
+	return problems
+}
 
-	testURLUsed := strings.TrimSpace(result.URL)
-	if testURLUsed == "" {
-		testURLUsed = firstNonEmpty(cfg.URLAnthropic, cfg.URLOpenAI)
+// endpointAllowsModel 判断模型是否在端点 allowed_models 中（支持 glob 通配）；未设置列表或请求未携带模型时不限制
+func endpointAllowsModel(allowed []string, model string) bool {
+	if len(allowed) == 0 || model == "" {
+		return true
 	}
-
-	responseTime := int(result.Duration.Milliseconds())
-	if responseTime < 0 {
-		responseTime = 0
+	for _, pattern := range allowed {
+		if matched, err := modelrewrite.MatchModelPattern(pattern, modelrewrite.MatchTypeGlob, model); err == nil && matched {
+			return true
+		}
 	}
+	return false
+}
 
-	statusValue := "healthy"
-	message := fmt.Sprintf("端点 %s 测试成功", nameStr)
-	errorMessage := ""
-	if checkErr != nil {
-		statusValue = "unhealthy"
-		message = fmt.Sprintf("端点 %s 测试失败", nameStr)
-		errorMessage = checkErr.Error()
+// warnEndpointConfigProblems 启动时记录端点配置问题（与 ValidateConfig 相同的检查）
+func (a *App) warnEndpointConfigProblems() {
+	a.mutex.RLock()
+	db := a.db
+	a.mutex.RUnlock()
+	if db == nil {
+		return
 	}
 
-	now := getCurrentTimestamp()
-	if _, updateErr := a.db.Exec(`
-		UPDATE endpoints
-		SET status = ?, response_time = ?, last_check = ?, updated_at = ?
-		WHERE id = ?
-	`, statusValue, responseTime, now, now, id); updateErr != nil {
-		runtime.LogWarning(a.ctx, fmt.Sprintf("Failed to update endpoint status for %s: %v", id, updateErr))
+	endpoints, err := a.queryEndpointConfigs("", false)
+	if err != nil {
+		return
 	}
+	for _, problem := range validateEndpointConfigs(endpoints) {
+		message := fmt.Sprintf("⚠️ 端点 '%s' 配置问题: %s", problem["endpoint"], problem["message"])
+		runtime.LogWarning(a.ctx, message)
+		a.addLog("warn", message)
+	}
+}
 
-	requestID, _ := a.logEndpointTestResult(testEndpoint, result, checkErr, testURLUsed)
-
-	requestPreview := truncateForResponse(result.RequestBody)
-	responsePreview := truncateForResponse(result.ResponseBody)
+// GetEffectiveEndpointConfig 返回端点最终生效的配置视图（默认值、全局设置与端点覆盖合并后的结果），用于排查路由决策
+func (a *App) GetEffectiveEndpointConfig(id string) map[string]interface{} {
+	if _, failure := a.lookupEndpointName(id); failure != nil {
+		return failure
+	}
 
-	responseData := map[string]interface{}{
-		"success":          checkErr == nil,
-		"message":          message,
-		"endpoint_id":      id,
-		"endpoint_name":    nameStr,
-		"status":           statusValue,
-		"response_time":    responseTime,
-		"status_code":      result.StatusCode,
-		"url":              testURLUsed,
-		"request_preview":  requestPreview,
-		"response_preview": responsePreview,
-		"timestamp":        now,
+	configs, err := a.queryEndpointConfigs("WHERE id = ?", false, id)
+	if err != nil || len(configs) == 0 {
+		message := "端点不存在"
+		if err != nil {
+			message = fmt.Sprintf("查询端点失败: %v", err)
+		}
+		return map[string]interface{}{
+			"success":     false,
+			"message":     message,
+			"endpoint_id": id,
+		}
 	}
+	cfg := configs[0]
 
-	if requestID != "" {
-		responseData["request_id"] = requestID
+	var snapshot endpoint.LearningSnapshot
+	if existing, ok := a.runtimeEndpoints.Load(id); ok {
+		snapshot = existing.(*endpoint.Endpoint).GetLearningSnapshot()
 	}
-	if len(result.RequestHeaders) > 0 {
-		responseData["request_headers"] = result.RequestHeaders
+	if snapshot.UnsupportedParams == nil {
+		snapshot.UnsupportedParams = []string{}
 	}
-	if len(result.ResponseHeaders) > 0 {
-		responseData["response_headers"] = result.ResponseHeaders
+
+	return map[string]interface{}{
+		"success":       true,
+		"endpoint_id":   id,
+		"endpoint_name": cfg.Name,
+		"enabled":       cfg.Enabled,
+		"priority":      cfg.Priority,
+		"tags":          cfg.Tags,
+		"timeouts":      a.effectiveTimeouts(cfg.Name),
+		"auth":          effectiveAuth(cfg, snapshot.DetectedAuthHeader),
+		"model_rewrite": effectiveModelRewrite(cfg, a.getModelAliases()),
+		"overrides": map[string]interface{}{
+			"parameter_overrides": decodeEncodedParameterOverrides(cfg.ParameterOverrides),
+			"response_headers":    cfg.ResponseHeaderOverrides,
+			"sse_event_filter":    cfg.SSEEventFilter,
+			"header_forwarding":   a.effectiveHeaderForwarding(),
+			"anthropic_version":   config.ResolveAnthropicVersion(cfg.AnthropicVersion, ""),
+			"user_agent":          config.ResolveUserAgent(cfg.UserAgent, a.getDefaultUserAgent()),
+			"user_field_mode":     utils.NormalizeUserFieldMode(cfg.UserFieldMode),
+			"extra_system_prompt": cfg.ExtraSystemPrompt != "",
+			"force_thinking":      cfg.ForceThinking,
+			"disable_thinking":    cfg.DisableThinking,
+			"strip_reasoning":     cfg.StripReasoning,
+			"thinking_stream":     a.resolveThinkingStreamMode(&cfg),
+			"max_tokens":          a.effectiveMaxTokens(&cfg),
+		},
+		"tls":     map[string]interface{}{"insecure_skip_verify": cfg.InsecureSkipVerify},
+		"routing": a.effectiveRouting(&cfg),
+		"learned": map[string]interface{}{
+			"unsupported_params":   snapshot.UnsupportedParams,
+			"native_codex_format":  snapshot.NativeCodexFormat,
+			"detected_auth_header": snapshot.DetectedAuthHeader,
+			"count_tokens_support": snapshot.CountTokensSupport,
+			"streaming_support":    snapshot.StreamingSupport,
+			"vision_support":       snapshot.VisionSupport,
+			"max_tokens_field":     snapshot.MaxTokensField,
+		},
 	}
-	if result.Model != "" {
-		responseData["model"] = result.Model
+}
+
+// effectiveTimeouts 汇总转发超时、自适应超时、端点内网络重试与请求队列设置
+func (a *App) effectiveTimeouts(endpointName string) map[string]interface{} {
+	queue := a.getRequestQueueSettings()
+	adaptive := a.getAdaptiveTimeoutSettings()
+	adaptiveTimeout, p95 := a.endpointAdaptiveTimeout(endpointName, false)
+	streamTimeout, streamP95 := a.endpointAdaptiveTimeout(endpointName, true)
+	adaptiveInfo := map[string]interface{}{
+		"multiplier":            adaptive.Multiplier,
+		"min_ms":                adaptive.Min.Milliseconds(),
+		"max_ms":                adaptive.Max.Milliseconds(),
+		"current_ms":            adaptiveTimeout.Milliseconds(),
+		"p95_latency_ms":        p95.Milliseconds(),
+		"stream_current_ms":     streamTimeout.Milliseconds(),
+		"stream_p95_latency_ms": streamP95.Milliseconds(),
+	}
+	return map[string]interface{}{
+		"forward_timeout_ms":        forwardRequestTimeout.Milliseconds(),
+		"adaptive_timeout":          adaptiveInfo,
+		"network_retry_count":       a.getNetworkRetryCount(),
+		"upstream_max_idle_conns":   a.getUpstreamMaxIdleConnsPerHost(),
+		"request_queue_concurrency": queue.Concurrency,
+		"request_queue_depth":       queue.Depth,
+		"request_queue_max_wait_ms": queue.MaxWait.Milliseconds(),
+		"max_response_body_bytes":   a.getMaxResponseBodyBytes(),
 	}
-	if checkErr != nil {
-		responseData["error"] = errorMessage
-		a.addLog("warn", fmt.Sprintf("端点 '%s' (ID: %s) 测试失败: %s，响应时间: %dms", nameStr, id, errorMessage, responseTime))
-	} else {
-		a.addLog("info", fmt.Sprintf("端点 '%s' (ID: %s) 测试成功，响应时间: %dms", nameStr, id, responseTime))
+}
+
+// effectiveAuth 描述转发时实际使用的认证头（与 forwardRequest 的逻辑保持一致）
+func effectiveAuth(cfg config.EndpointConfig, detectedHeader string) map[string]interface{} {
+	authType := strings.ToLower(strings.TrimSpace(cfg.AuthType))
+	header := "Authorization"
+	scheme := "raw"
+	credential := cfg.AuthValue
+	switch authType {
+	case "api_key":
+		header = "x-api-key"
+		scheme = "api_key"
+	case "auth_token", "auto":
+		scheme = "bearer"
+	case "oauth":
+		// 配置了 access_token 时使用 oauth_config，否则沿用 auth_value 原样作为 Authorization
+		if cfg.OAuthConfig != nil && strings.TrimSpace(cfg.OAuthConfig.AccessToken) != "" {
+			scheme = "oauth"
+			credential = cfg.OAuthConfig.AccessToken
+		}
 	}
 
-	return responseData
+	result := map[string]interface{}{
+		"auth_type":       cfg.AuthType,
+		"header":          header,
+		"scheme":          scheme,
+		"has_credential":  strings.TrimSpace(credential) != "",
+		"credential":      maskToken(credential),
+		"detected_header": detectedHeader,
+	}
+	if scheme == "oauth" {
+		result["oauth_expires_at"] = cfg.OAuthConfig.ExpiresAt
+		result["oauth_auto_refresh"] = cfg.OAuthConfig.AutoRefresh
+	}
+	return result
 }
 
-// TestAllEndpoints 测试所有端点
-func (a *App) TestAllEndpoints() map[string]interface{} {
-	runtime.LogInfo(a.ctx, "=== TestAllEndpoints 函数开始执行 ===")
-	runtime.LogInfo(a.ctx, "Testing all endpoints via Go API (统一架构)")
+// effectiveMaxTokens 描述缺省 max_tokens 注入与上限截断的实际取值（端点配置优先于全局配置）
+func (a *App) effectiveMaxTokens(cfg *config.EndpointConfig) map[string]interface{} {
+	defaultMax, ceiling := a.getMaxTokensLimits(cfg)
+	return map[string]interface{}{
+		"default":    defaultMax,
+		"ceiling":    ceiling,
+		"field_name": cfg.MaxTokensFieldName,
+	}
+}
 
-	if a.db == nil {
-		runtime.LogError(a.ctx, "TestAllEndpoints: 数据库不可用")
-		return map[string]interface{}{
-			"results":       []interface{}{},
-			"total":         0,
-			"success_count": 0,
-			"message":       "批量测试失败：数据库不可用",
-			"success":       false,
+// effectiveModelRewrite 返回端点重写规则与全局模型别名
+func effectiveModelRewrite(cfg config.EndpointConfig, aliases map[string]string) map[string]interface{} {
+	result := map[string]interface{}{
+		"enabled":       false,
+		"rules":         []config.ModelRewriteRule{},
+		"model_aliases": aliases,
+	}
+	if cfg.ModelRewrite != nil {
+		result["enabled"] = cfg.ModelRewrite.Enabled
+		if len(cfg.ModelRewrite.Rules) > 0 {
+			result["rules"] = cfg.ModelRewrite.Rules
 		}
+		if cfg.ModelRewrite.TargetModel != "" {
+			result["target_model"] = cfg.ModelRewrite.TargetModel
+		}
+	}
+	return result
+}
+
+// effectiveHeaderForwarding 返回全局请求头转发规则
+func (a *App) effectiveHeaderForwarding() map[string]interface{} {
+	filter := a.getHeaderForwardFilter()
+	return map[string]interface{}{
+		"allowlist": append([]string{}, filter.allowlist...),
+		"blocklist": append([]string{}, filter.blocklist...),
 	}
+}
 
-	rows, err := a.db.Query(`
-		SELECT id, name
-		FROM endpoints
-		ORDER BY priority DESC, created_at ASC
-	`)
-	if err != nil {
-		runtime.LogError(a.ctx, fmt.Sprintf("TestAllEndpoints: 查询端点列表失败: %v", err))
-		return map[string]interface{}{
-			"results":       []interface{}{},
-			"total":         0,
-			"success_count": 0,
-			"message":       fmt.Sprintf("批量测试失败：查询端点列表失败(%v)", err),
-			"success":       false,
+// effectiveRouting 计算各客户端路径实际转发的上游URL以及是否需要格式转换
+func (a *App) effectiveRouting(cfg *config.EndpointConfig) map[string]interface{} {
+	routing := map[string]interface{}{}
+	for _, path := range []string{"/v1/messages", "/chat/completions", "/responses", "/v1/messages/batches"} {
+		entry := map[string]interface{}{}
+		if target, err := a.buildTargetURL(cfg, path, ""); err != nil {
+			entry["error"] = err.Error()
+		} else {
+			entry["target_url"] = target
+			entry["format_conversion"] = path == "/v1/messages" && cfg.URLAnthropic == "" && cfg.URLOpenAI != ""
 		}
+		routing[path] = entry
 	}
-	defer rows.Close()
+	return routing
+}
 
-	type endpointRef struct {
-		ID   string
-		Name string
-	}
+// appStartedAt 进程启动时间，用于计算运行时长
+var appStartedAt = time.Now()
 
-	var endpointRefs []endpointRef
-	for rows.Next() {
-		var ref endpointRef
-		if err := rows.Scan(&ref.ID, &ref.Name); err != nil {
-			runtime.LogError(a.ctx, fmt.Sprintf("TestAllEndpoints: 读取端点信息失败: %v", err))
-			continue
+// defaultStatsWindow GetStats 未配置 server.stats_window 时的统计窗口
+const defaultStatsWindow = "24h"
+
+// statsWindowDurations GetStats 支持的统计窗口，与 GetRequestTrends 的 timeRange 取值一致
+var statsWindowDurations = map[string]time.Duration{
+	"1h":  time.Hour,
+	"24h": 24 * time.Hour,
+	"7d":  7 * 24 * time.Hour,
+	"30d": 30 * 24 * time.Hour,
+}
+
+// getStatsWindow 读取 server.stats_window，未配置或取值不受支持时回退到 24h
+func (a *App) getStatsWindow() (string, time.Duration) {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	if serverConfig, ok := a.config["server"].(map[string]interface{}); ok {
+		window := strings.TrimSpace(getStringFromMap(serverConfig, "stats_window"))
+		if duration, ok := statsWindowDurations[window]; ok {
+			return window, duration
 		}
-		endpointRefs = append(endpointRefs, ref)
 	}
+	return defaultStatsWindow, statsWindowDurations[defaultStatsWindow]
+}
 
-	if err := rows.Err(); err != nil {
-		runtime.LogError(a.ctx, fmt.Sprintf("TestAllEndpoints: 遍历端点行失败: %v", err))
+// resolveThinkingStreamMode 返回流式响应中 thinking 内容的处理方式：端点 thinking_stream_mode 优先，
+// 未配置时使用 server.thinking_stream_mode，默认 passthrough
+func (a *App) resolveThinkingStreamMode(endpoint *config.EndpointConfig) string {
+	if endpoint != nil {
+		if mode := conversion.NormalizeThinkingStreamMode(endpoint.ThinkingStreamMode); mode != "" {
+			return mode
+		}
 	}
 
-	// 添加批量测试开始的日志记录
-	a.addLog("info", fmt.Sprintf("开始批量测试 %d 个端点", len(endpointRefs)))
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
 
-	results := make([]interface{}, 0, len(endpointRefs))
-	successCount := 0
+	if serverConfig, ok := a.config["server"].(map[string]interface{}); ok {
+		if mode := conversion.NormalizeThinkingStreamMode(getStringFromMap(serverConfig, "thinking_stream_mode")); mode != "" {
+			return mode
+		}
+	}
+	return conversion.ThinkingStreamModePassthrough
+}
 
-	for idx, ref := range endpointRefs {
-		if ref.Name == "" {
-			runtime.LogInfo(a.ctx, fmt.Sprintf("Testing endpoint %d: ID=%s", idx, ref.ID))
+// GetStats 返回概览卡片使用的统计信息：统计窗口（server.stats_window）内的请求总数、成功/失败数、
+// 成功率、平均与 P95 响应时间均来自请求日志，字段命名与 GetRequestTrends / 仪表盘一致
+func (a *App) GetStats() map[string]interface{} {
+	a.mutex.RLock()
+	db := a.db
+	requestLogger := a.requestLogger
+	running := a.running
+	a.mutex.RUnlock()
+
+	a.flushRequestLogs()
+	window, windowDuration := a.getStatsWindow()
+	since := time.Now().Add(-windowDuration)
+
+	result := map[string]interface{}{
+		"success":           true,
+		"timeRange":         window,
+		"totalRequests":     int64(0),
+		"successRequests":   int64(0),
+		"errorsCount":       int64(0),
+		"successRate":       0.0,
+		"avgResponseTime":   int64(0),
+		"p95ResponseTime":   int64(0),
+		"requestsPerSecond": 0.0,
+		"systemUptime":      int64(time.Since(appStartedAt).Seconds()),
+		"activeEndpoints":   0,
+		"totalEndpoints":    0,
+		"running":           running,
+		"droppedLogs":       a.droppedRequestLogs(),
+		"lastUpdated":       getCurrentTimestamp(),
+	}
+
+	var failures []string
+	if requestLogger != nil {
+		if counts, err := requestLogger.CountRequestsSince(since); err != nil {
+			failures = append(failures, fmt.Sprintf("统计请求数失败: %v", err))
 		} else {
-			runtime.LogInfo(a.ctx, fmt.Sprintf("Testing endpoint %d: ID=%s, Name=%s", idx, ref.ID, ref.Name))
+			result["totalRequests"] = counts.Total
+			result["successRequests"] = counts.Succeeded
+			result["errorsCount"] = counts.Total - counts.Succeeded
+			if counts.Total > 0 {
+				result["successRate"] = float64(counts.Succeeded) / float64(counts.Total) * 100
+			}
+			result["requestsPerSecond"] = float64(counts.Total) / windowDuration.Seconds()
 		}
 
-		result := a.TestEndpoint(ref.ID)
-		results = append(results, result)
-
-		if success, ok := result["success"].(bool); ok && success {
-			successCount++
+		if durations, err := requestLogger.DurationStatsSince(since); err != nil {
+			failures = append(failures, fmt.Sprintf("统计响应时间失败: %v", err))
+		} else {
+			result["avgResponseTime"] = int64(math.Round(durations.AvgMs))
+			result["p95ResponseTime"] = durations.P95Ms
 		}
+	}
 
-		runtime.LogInfo(a.ctx, fmt.Sprintf("Endpoint %d test result: success=%v", idx, result["success"]))
+	if db != nil {
+		total, active, err := queryEndpointCounts(db)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("查询端点状态失败: %v", err))
+		} else {
+			result["totalEndpoints"] = total
+			result["activeEndpoints"] = active
+		}
 	}
 
-	a.addLog("info", fmt.Sprintf("批量测试完成，成功: %d/%d", successCount, len(results)))
-	runtime.LogInfo(a.ctx, fmt.Sprintf("TestAllEndpoints completed: success_count=%d, total=%d", successCount, len(results)))
+	if len(failures) > 0 {
+		result["success"] = false
+		result["message"] = strings.Join(failures, "; ")
+	}
+	return result
+}
 
-	return map[string]interface{}{
-		"results":       results,
-		"total":         len(results),
-		"success_count": successCount,
-		"message":       fmt.Sprintf("批量测试完成，成功: %d/%d", successCount, len(results)),
-		"success":       true,
+// queryEndpointCounts 返回端点总数与已启用且健康的端点数量
+func queryEndpointCounts(db *sql.DB) (int, int, error) {
+	var total, active int
+	err := db.QueryRow(`
+		SELECT
+			COUNT(*),
+			COALESCE(SUM(CASE WHEN enabled = 1 AND status = 'healthy' THEN 1 ELSE 0 END), 0)
+		FROM endpoints
+	`).Scan(&total, &active)
+	if err != nil {
+		return 0, 0, err
 	}
+	return total, active, nil
 }
 
-// GetStats 返回统计信息
-func (a *App) GetStats() map[string]interface{} {
-	endpoints := a.GetEndpoints()
-	endpointsTotal := len(endpoints)
-	endpointsHealthy := 0
+// quickStatsWindow GetQuickStats 统计请求数与成功率的时间窗口
+const quickStatsWindow = time.Hour
 
-	for _, epInterface := range endpoints {
-		if ep, ok := epInterface.(map[string]interface{}); ok {
-			if enabled, ok := ep["enabled"].(bool); ok && enabled {
-				if status, ok := ep["status"].(string); ok && status == "healthy" {
-					endpointsHealthy++
-				}
+// GetQuickStats 返回托盘/菜单栏使用的精简概览：近一小时请求数与成功率、最快的健康端点、不健康端点数量；
+// 日志库与端点库各一条聚合查询，可每隔几秒轮询
+func (a *App) GetQuickStats() map[string]interface{} {
+	a.mutex.RLock()
+	db := a.db
+	requestLogger := a.requestLogger
+	a.mutex.RUnlock()
+
+	result := map[string]interface{}{
+		"success":             true,
+		"window_minutes":      int(quickStatsWindow.Minutes()),
+		"requests_last_hour":  int64(0),
+		"success_rate":        nil,
+		"fastest_endpoint":    "",
+		"fastest_response_ms": int64(0),
+		"unhealthy_endpoints": 0,
+		"updated_at":          getCurrentTimestamp(),
+	}
+
+	if requestLogger != nil {
+		if counts, err := requestLogger.CountRequestsSince(time.Now().Add(-quickStatsWindow)); err == nil {
+			result["requests_last_hour"] = counts.Total
+			if counts.Total > 0 {
+				result["success_rate"] = float64(counts.Succeeded) / float64(counts.Total) * 100
 			}
 		}
 	}
 
-	return map[string]interface{}{
-		"uptime":              "运行中 (统一架构)",
-		"requests_total":      0,
-		"requests_successful": 0,
-		"requests_failed":     0,
-		"endpoints_total":     endpointsTotal,
-		"endpoints_healthy":   endpointsHealthy,
-		"running":             a.running,
-		"last_updated":        getCurrentTimestamp(),
-		"architecture":        "unified_wails_no_http_server",
+	if db != nil {
+		fastest, fastestMs, unhealthy, err := queryEndpointQuickStats(db)
+		if err != nil {
+			result["success"] = false
+			result["message"] = fmt.Sprintf("查询端点状态失败: %v", err)
+			return result
+		}
+		result["fastest_endpoint"] = fastest
+		result["fastest_response_ms"] = fastestMs
+		result["unhealthy_endpoints"] = unhealthy
+	}
+	return result
+}
+
+// queryEndpointQuickStats 一次查询取得响应最快的已启用健康端点与不健康端点数量（含被自动禁用的端点）
+func queryEndpointQuickStats(db *sql.DB) (string, int64, int, error) {
+	var (
+		fastest   sql.NullString
+		fastestMs sql.NullInt64
+		unhealthy int
+	)
+	err := db.QueryRow(`
+		SELECT
+			(SELECT name FROM endpoints WHERE enabled = 1 AND status = 'healthy' AND response_time > 0 ORDER BY response_time ASC LIMIT 1),
+			(SELECT response_time FROM endpoints WHERE enabled = 1 AND status = 'healthy' AND response_time > 0 ORDER BY response_time ASC LIMIT 1),
+			(SELECT COUNT(*) FROM endpoints WHERE status = 'unhealthy' AND (enabled = 1 OR auto_disabled = 1))
+	`).Scan(&fastest, &fastestMs, &unhealthy)
+	if err != nil {
+		return "", 0, 0, err
 	}
+	return fastest.String, fastestMs.Int64, unhealthy, nil
 }
 
 // GetConfigPath 获取配置文件路径
@@ -2878,6 +9723,33 @@ func (a *App) OpenURL(url string) map[string]interface{} {
 	}
 }
 
+// enterSafeModeNoLock 配置解析失败时进入安全模式，并备份损坏的配置文件以免被默认配置覆盖（调用方需持有写锁）
+func (a *App) enterSafeModeNoLock(raw []byte, parseErr error) {
+	if a.configError == parseErr.Error() {
+		return // 已处于安全模式，避免重复备份与日志
+	}
+	a.configError = parseErr.Error()
+
+	backupPath := fmt.Sprintf("%s.corrupt-%s", a.configPath, time.Now().Format("20060102-150405"))
+	if err := os.WriteFile(backupPath, raw, 0644); err != nil {
+		runtime.LogWarning(a.ctx, fmt.Sprintf("Failed to back up corrupt config: %v", err))
+		backupPath = ""
+	}
+
+	message := fmt.Sprintf("配置文件解析失败，代理已进入安全模式（所有请求返回503）: %v", parseErr)
+	if backupPath != "" {
+		message += fmt.Sprintf("；损坏的配置已备份到 %s", backupPath)
+	}
+	a.addLog("error", message)
+}
+
+// getConfigError 返回当前的配置错误（为空表示未处于安全模式）
+func (a *App) getConfigError() string {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+	return a.configError
+}
+
 // LoadConfig 加载配置
 func (a *App) LoadConfig() map[string]interface{} {
 	a.mutex.Lock()
@@ -2941,6 +9813,7 @@ func (a *App) LoadConfig() map[string]interface{} {
 	var configData map[string]interface{}
 	if err := json.Unmarshal(jsonData, &configData); err != nil {
 		runtime.LogError(a.ctx, fmt.Sprintf("Failed to parse config file: %v", err))
+		a.enterSafeModeNoLock(jsonData, err)
 		defaultConfig["endpoints"] = []interface{}{}
 		if server, ok := defaultConfig["server"].(map[string]interface{}); ok {
 			a.applyServerAddressNoLock(server)
@@ -2954,6 +9827,12 @@ func (a *App) LoadConfig() map[string]interface{} {
 		return defaultConfig
 	}
 
+	if a.configError != "" {
+		a.configError = ""
+		runtime.LogInfo(a.ctx, "Config parsed successfully, leaving safe mode")
+		a.addLog("info", "配置文件已恢复，退出安全模式")
+	}
+
 	// 合并默认配置和加载的配置，确保所有必要字段都存在
 	if server, ok := configData["server"].(map[string]interface{}); ok {
 		if defaultServer, ok := defaultConfig["server"].(map[string]interface{}); ok {
@@ -2973,19 +9852,309 @@ func (a *App) LoadConfig() map[string]interface{} {
 		}
 	}
 
-	if !a.running {
-		a.syncActualAddressNoLock()
+	if !a.running {
+		a.syncActualAddressNoLock()
+	}
+
+	// 暂时不加载端点数据，避免死锁问题
+	configData["endpoints"] = []interface{}{}
+
+	// 将配置保存到App结构体中
+	a.config = configData
+
+	runtime.LogInfo(a.ctx, fmt.Sprintf("Configuration loaded successfully from: %s", a.configPath))
+
+	return configData
+}
+
+// DiffConfig 预览 newConfig 与当前配置之间的差异（不落盘），并标记高风险变更
+func (a *App) DiffConfig(newConfig map[string]interface{}) map[string]interface{} {
+	if newConfig == nil {
+		return map[string]interface{}{
+			"success": false,
+			"message": "配置不能为空",
+		}
+	}
+
+	a.mutex.RLock()
+	oldConfig, err := normalizeConfigForDiff(a.config)
+	db := a.db
+	a.mutex.RUnlock()
+	if err != nil {
+		return map[string]interface{}{
+			"success": false,
+			"message": fmt.Sprintf("读取当前配置失败: %v", err),
+		}
+	}
+	candidate, err := normalizeConfigForDiff(newConfig)
+	if err != nil {
+		return map[string]interface{}{
+			"success": false,
+			"message": fmt.Sprintf("新配置无法序列化: %v", err),
+		}
+	}
+
+	// 端点实际存储在数据库中，配置里的 endpoints 字段单独按名称比较
+	oldEndpoints, _ := oldConfig["endpoints"].([]interface{})
+	newEndpoints, hasEndpoints := candidate["endpoints"].([]interface{})
+	delete(oldConfig, "endpoints")
+	delete(candidate, "endpoints")
+	if hasEndpoints && len(newEndpoints) > 0 && len(oldEndpoints) == 0 && db != nil {
+		if stored, err := a.queryEndpointConfigs("", false); err == nil {
+			oldEndpoints = endpointConfigsForDiff(stored)
+		}
+	}
+
+	changes := diffConfigValues("", oldConfig, candidate)
+	endpointChanges := map[string]interface{}{
+		"added":   []string{},
+		"removed": []string{},
+		"changed": []map[string]interface{}{},
+	}
+	if hasEndpoints && len(newEndpoints) > 0 {
+		endpointChanges = diffEndpointLists(oldEndpoints, newEndpoints)
+	}
+	warnings := configDiffWarnings(oldConfig, candidate, newEndpoints, hasEndpoints && len(newEndpoints) > 0)
+
+	endpointTotal := len(endpointChanges["added"].([]string)) +
+		len(endpointChanges["removed"].([]string)) +
+		len(endpointChanges["changed"].([]map[string]interface{}))
+
+	return map[string]interface{}{
+		"success":          true,
+		"has_changes":      len(changes) > 0 || endpointTotal > 0,
+		"changes":          changes,
+		"endpoint_changes": endpointChanges,
+		"warnings":         warnings,
+		"message":          fmt.Sprintf("共 %d 项配置变更，%d 项端点变更，%d 条风险提示", len(changes), endpointTotal, len(warnings)),
+	}
+}
+
+// normalizeConfigForDiff 通过 JSON 往返统一数值类型（int/float64 等），便于比较
+func normalizeConfigForDiff(cfg map[string]interface{}) (map[string]interface{}, error) {
+	result := map[string]interface{}{}
+	if cfg == nil {
+		return result, nil
+	}
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// endpointConfigsForDiff 将数据库中的端点配置转换为与前端提交一致的 map 结构
+func endpointConfigsForDiff(endpoints []config.EndpointConfig) []interface{} {
+	data, err := json.Marshal(endpoints)
+	if err != nil {
+		return nil
+	}
+	var result []interface{}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil
+	}
+	return result
+}
+
+// isSensitiveConfigKey 判断配置键是否包含令牌等敏感信息，差异中需掩码展示
+func isSensitiveConfigKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, marker := range []string{"token", "secret", "password", "auth_value", "api_key"} {
+		if strings.Contains(key, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// maskConfigValue 递归掩码敏感值中的字符串
+func maskConfigValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		return maskToken(v)
+	case []interface{}:
+		masked := make([]interface{}, len(v))
+		for i, item := range v {
+			masked[i] = maskConfigValue(item)
+		}
+		return masked
+	case map[string]interface{}:
+		masked := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			masked[key] = maskConfigValue(item)
+		}
+		return masked
+	default:
+		return value
+	}
+}
+
+// diffConfigValues 递归比较两份配置，返回按路径排序的 added/removed/changed 列表
+func diffConfigValues(path string, oldValue, newValue interface{}) []map[string]interface{} {
+	oldMap, oldIsMap := oldValue.(map[string]interface{})
+	newMap, newIsMap := newValue.(map[string]interface{})
+	if oldIsMap && newIsMap {
+		keys := make(map[string]struct{}, len(oldMap)+len(newMap))
+		for key := range oldMap {
+			keys[key] = struct{}{}
+		}
+		for key := range newMap {
+			keys[key] = struct{}{}
+		}
+		sortedKeys := make([]string, 0, len(keys))
+		for key := range keys {
+			sortedKeys = append(sortedKeys, key)
+		}
+		sort.Strings(sortedKeys)
+
+		var changes []map[string]interface{}
+		for _, key := range sortedKeys {
+			childPath := key
+			if path != "" {
+				childPath = path + "." + key
+			}
+			oldChild, inOld := oldMap[key]
+			newChild, inNew := newMap[key]
+			sensitive := isSensitiveConfigKey(key)
+			switch {
+			case !inOld:
+				if sensitive {
+					newChild = maskConfigValue(newChild)
+				}
+				changes = append(changes, map[string]interface{}{"path": childPath, "type": "added", "new": newChild})
+			case !inNew:
+				if sensitive {
+					oldChild = maskConfigValue(oldChild)
+				}
+				changes = append(changes, map[string]interface{}{"path": childPath, "type": "removed", "old": oldChild})
+			default:
+				childChanges := diffConfigValues(childPath, oldChild, newChild)
+				if sensitive {
+					for _, change := range childChanges {
+						for _, field := range []string{"old", "new"} {
+							if value, ok := change[field]; ok {
+								change[field] = maskConfigValue(value)
+							}
+						}
+					}
+				}
+				changes = append(changes, childChanges...)
+			}
+		}
+		return changes
+	}
+
+	if reflect.DeepEqual(oldValue, newValue) {
+		return nil
+	}
+	return []map[string]interface{}{{"path": path, "type": "changed", "old": oldValue, "new": newValue}}
+}
+
+// diffEndpointLists 按端点名称比较端点列表，返回新增、删除以及字段变化的端点
+func diffEndpointLists(oldEndpoints, newEndpoints []interface{}) map[string]interface{} {
+	index := func(list []interface{}) (map[string]map[string]interface{}, []string) {
+		byName := make(map[string]map[string]interface{}, len(list))
+		var order []string
+		for _, item := range list {
+			endpoint, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			name, _ := endpoint["name"].(string)
+			name = strings.TrimSpace(name)
+			if name == "" {
+				continue
+			}
+			if _, exists := byName[name]; !exists {
+				order = append(order, name)
+			}
+			byName[name] = endpoint
+		}
+		return byName, order
+	}
+
+	oldByName, oldOrder := index(oldEndpoints)
+	newByName, newOrder := index(newEndpoints)
+
+	added := []string{}
+	changed := []map[string]interface{}{}
+	for _, name := range newOrder {
+		oldEndpoint, exists := oldByName[name]
+		if !exists {
+			added = append(added, name)
+			continue
+		}
+		var fields []string
+		for _, change := range diffConfigValues("", oldEndpoint, newByName[name]) {
+			fields = append(fields, change["path"].(string))
+		}
+		if len(fields) > 0 {
+			changed = append(changed, map[string]interface{}{"name": name, "fields": fields})
+		}
+	}
+
+	removed := []string{}
+	for _, name := range oldOrder {
+		if _, exists := newByName[name]; !exists {
+			removed = append(removed, name)
+		}
+	}
+
+	return map[string]interface{}{
+		"added":   added,
+		"removed": removed,
+		"changed": changed,
+	}
+}
+
+// configDiffWarnings 识别高风险变更：清空鉴权令牌、清空令牌映射、禁用全部端点、修改监听端口等
+func configDiffWarnings(oldConfig, newConfig map[string]interface{}, newEndpoints []interface{}, checkEndpoints bool) []string {
+	warnings := []string{}
+	oldServer, _ := oldConfig["server"].(map[string]interface{})
+	newServer, _ := newConfig["server"].(map[string]interface{})
+
+	if oldServer != nil && newServer == nil {
+		warnings = append(warnings, "server 配置段被移除")
 	}
 
-	// 暂时不加载端点数据，避免死锁问题
-	configData["endpoints"] = []interface{}{}
+	oldToken, _ := oldServer["claude_code_auth_token"].(string)
+	newToken, _ := newServer["claude_code_auth_token"].(string)
+	if strings.TrimSpace(oldToken) != "" && strings.TrimSpace(newToken) == "" {
+		warnings = append(warnings, "claude_code_auth_token 将被清空，代理将不再校验客户端令牌")
+	}
 
-	// 将配置保存到App结构体中
-	a.config = configData
+	oldMappings, _ := oldServer["token_mappings"].([]interface{})
+	newMappings, _ := newServer["token_mappings"].([]interface{})
+	if len(oldMappings) > 0 && len(newMappings) == 0 {
+		warnings = append(warnings, fmt.Sprintf("token_mappings 将被清空（当前 %d 条）", len(oldMappings)))
+	}
 
-	runtime.LogInfo(a.ctx, fmt.Sprintf("Configuration loaded successfully from: %s", a.configPath))
+	if oldServer != nil && newServer != nil {
+		if oldPort, ok := oldServer["port"]; ok && !reflect.DeepEqual(oldPort, newServer["port"]) {
+			warnings = append(warnings, fmt.Sprintf("监听端口将从 %v 变更为 %v，需重启代理服务", oldPort, newServer["port"]))
+		}
+	}
 
-	return configData
+	if checkEndpoints {
+		enabledCount := 0
+		for _, item := range newEndpoints {
+			endpoint, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if enabled, ok := endpoint["enabled"].(bool); !ok || enabled {
+				enabledCount++
+			}
+		}
+		if enabledCount == 0 {
+			warnings = append(warnings, "所有端点都将被禁用，代理请求将全部失败")
+		}
+	}
+
+	return warnings
 }
 
 // SaveConfig 保存配置
@@ -3035,9 +10204,18 @@ func (a *App) SaveConfig(configData map[string]interface{}) map[string]interface
 
 	// 更新App结构体中的配置缓存
 	a.config = configData
+	if a.configError != "" {
+		a.configError = ""
+		a.addLog("info", "已写入新的配置文件，退出安全模式")
+	}
 
 	runtime.LogInfo(a.ctx, fmt.Sprintf("Configuration saved successfully to: %s", a.configPath))
 
+	// 运行中启用 server.min_healthy_endpoints 时需要重新检测，否则闸门等不到启动检测会一直返回 503
+	if a.running {
+		go a.runStartupHealthSweep()
+	}
+
 	return map[string]interface{}{
 		"success": true,
 		"message": "配置保存成功 (通过Go API)",
@@ -3064,6 +10242,7 @@ func (a *App) GetLogs(params map[string]interface{}) map[string]interface{} {
 	page := 1
 	limit := 20
 	search := ""
+	searchBodies := false // 同时检索请求/响应体（LIKE 全表扫描，需显式开启）
 	clientType := ""
 	statusRange := ""
 	streamingOnly := false
@@ -3091,6 +10270,10 @@ func (a *App) GetLogs(params map[string]interface{}) map[string]interface{} {
 		search = s
 	}
 
+	if sb, ok := params["search_bodies"].(bool); ok {
+		searchBodies = sb
+	}
+
 	if ct, ok := params["client_type"].(string); ok {
 		clientType = ct
 	}
@@ -3129,6 +10312,8 @@ func (a *App) GetLogs(params map[string]interface{}) map[string]interface{} {
 
 	// 处理清理请求 - 从数据库清理旧日志
 	if cleanup >= 0 { // 只有明确提供cleanup参数时才执行清理
+		// 先让队列中的日志落库，否则清理后仍会有旧日志写入
+		a.flushRequestLogs()
 
 		if cleanup == 0 {
 			// 清除所有日志 - 使用logger的存储来清理
@@ -3187,59 +10372,19 @@ func (a *App) GetLogs(params map[string]interface{}) map[string]interface{} {
 		}
 	}
 
-	// 构建SQL查询条件
-	whereConditions := []string{}
-	args := []interface{}{}
-
-	// 搜索条件
-	if search != "" {
-		whereConditions = append(whereConditions, "(request_id LIKE ? OR endpoint LIKE ? OR model LIKE ? OR path LIKE ?)")
-		searchPattern := "%" + search + "%"
-		args = append(args, searchPattern, searchPattern, searchPattern, searchPattern)
-	}
-
-	// 客户端类型过滤
-	if clientType != "" && clientType != "all" {
-		whereConditions = append(whereConditions, "client_type = ?")
-		args = append(args, clientType)
-	}
-
-	// 状态码范围过滤
-	if statusRange != "" && statusRange != "all" {
-		switch statusRange {
-		case "2xx":
-			whereConditions = append(whereConditions, "status_code >= 200 AND status_code < 300")
-		case "4xx":
-			whereConditions = append(whereConditions, "status_code >= 400 AND status_code < 500")
-		case "5xx":
-			whereConditions = append(whereConditions, "status_code >= 500")
-		case "error":
-			whereConditions = append(whereConditions, "status_code >= 400")
-		}
-	}
-
-	// 流式响应过滤
-	if streamingOnly {
-		whereConditions = append(whereConditions, "is_streaming = 1")
-	}
-
-	// 模型重写过滤
-	if model == "any" {
-		whereConditions = append(whereConditions, "model_rewrite_applied = 1")
-	}
-
-	// 错误过滤
-	if failedOnly || hasError {
-		whereConditions = append(whereConditions, "(status_code >= 400 OR error != '')")
-	}
-
-	// 思考模式过滤
-	if withThinking {
-		whereConditions = append(whereConditions, "thinking_enabled = 1")
+	// 全部过滤条件在数据库层应用，分页与总数都基于过滤后的结果
+	filter := logger.LogQueryFilter{
+		Search:        strings.TrimSpace(search),
+		SearchBodies:  searchBodies,
+		FailedOnly:    failedOnly || hasError,
+		StatusRange:   statusRange,
+		ClientType:    clientType,
+		StreamingOnly: streamingOnly,
+		RewrittenOnly: model == "any",
+		ThinkingOnly:  withThinking,
 	}
-
-	// 使用日志记录器获取数据
-	logs, total, err := a.requestLogger.GetLogs(limit, (page-1)*limit, failedOnly)
+	a.flushRequestLogs()
+	logs, total, err := a.requestLogger.QueryLogs(filter, limit, (page-1)*limit)
 	if err != nil {
 		return map[string]interface{}{
 			"success": false,
@@ -3247,83 +10392,9 @@ func (a *App) GetLogs(params map[string]interface{}) map[string]interface{} {
 		}
 	}
 
-	// 应用过滤条件（由于GetLogs方法只支持基本的failedOnly过滤，我们需要在这里应用其他过滤条件）
-	var filteredLogs []*logger.RequestLog
-	if search != "" || clientType != "" || statusRange != "" || streamingOnly || hasError || model != "" || withThinking {
-		filteredLogs = make([]*logger.RequestLog, 0)
-		for _, log := range logs {
-			// 搜索过滤
-			if search != "" {
-				searchLower := strings.ToLower(search)
-				if !strings.Contains(strings.ToLower(log.RequestID), searchLower) &&
-					!strings.Contains(strings.ToLower(log.Endpoint), searchLower) &&
-					!strings.Contains(strings.ToLower(log.Path), searchLower) &&
-					!strings.Contains(strings.ToLower(log.Model), searchLower) {
-					continue
-				}
-			}
-
-			// 客户端类型过滤
-			if clientType != "" && clientType != "all" && log.ClientType != clientType {
-				continue
-			}
-
-			// 状态码范围过滤
-			if statusRange != "" && statusRange != "all" {
-				switch statusRange {
-				case "2xx":
-					if log.StatusCode < 200 || log.StatusCode >= 300 {
-						continue
-					}
-				case "4xx":
-					if log.StatusCode < 400 || log.StatusCode >= 500 {
-						continue
-					}
-				case "5xx":
-					if log.StatusCode < 500 {
-						continue
-					}
-				case "error":
-					if log.StatusCode < 400 && log.Error == "" {
-						continue
-					}
-				}
-			}
-
-			// 流式响应过滤
-			if streamingOnly && !log.IsStreaming {
-				continue
-			}
-
-			// 模型重写过滤
-			if model == "any" && !log.ModelRewriteApplied {
-				continue
-			}
-
-			// 错误过滤
-			if hasError && log.StatusCode < 400 && log.Error == "" {
-				continue
-			}
-
-			// 思考模式过滤
-			if withThinking && !log.ThinkingEnabled {
-				continue
-			}
-
-			filteredLogs = append(filteredLogs, log)
-		}
-	} else {
-		filteredLogs = logs
-	}
-
-	// 🔴 CRITICAL: total 必须保持为数据库返回的真实总数（包含 failedOnly 等 DB 层过滤）
-	// 禁止用页内过滤结果覆盖，否则前端分页总数会错误地显示为当前页大小（20）
-	// 如果需要精确的过滤后总数，应该将过滤逻辑下沉到数据库层（logger.GetLogs）
-	// 当前实现：DB 层过滤 failedOnly，内存层过滤其他条件，total 反映 DB 层结果
-
 	// 转换日志数据为前端格式
 	logEntries := []map[string]interface{}{}
-	for _, log := range filteredLogs {
+	for _, log := range logs {
 		logMap := map[string]interface{}{
 			"id":                        strconv.Itoa(int(log.Timestamp.Unix())),
 			"timestamp":                 a.formatTimestamp(log.Timestamp),
@@ -3344,6 +10415,7 @@ func (a *App) GetLogs(params map[string]interface{}) map[string]interface{} {
 			"thinking_enabled":          log.ThinkingEnabled,
 			"thinking_budget_tokens":    log.ThinkingBudgetTokens,
 			"format_converted":          log.FormatConverted,
+			"conversion_path":           log.ConversionPath,
 			"request_headers":           cloneStringMap(log.RequestHeaders),
 			"response_headers":          cloneStringMap(log.ResponseHeaders),
 			"request_body":              log.RequestBody,
@@ -3387,6 +10459,18 @@ func (a *App) GetLogs(params map[string]interface{}) map[string]interface{} {
 		if log.SessionID != "" {
 			logMap["session_id"] = log.SessionID
 		}
+		if log.ServiceTier != "" {
+			logMap["service_tier"] = log.ServiceTier
+		}
+		if log.AuthMethodUsed != "" {
+			logMap["auth_method_used"] = log.AuthMethodUsed
+		}
+		if len(log.Attempts) > 0 {
+			logMap["attempts"] = log.Attempts
+		}
+		if log.ContentTypeOverride != "" {
+			logMap["content_type_override"] = log.ContentTypeOverride
+		}
 
 		logEntries = append(logEntries, logMap)
 	}
@@ -3407,6 +10491,7 @@ func (a *App) GetLogs(params map[string]interface{}) map[string]interface{} {
 		"page":    page,
 		"limit":   limit,
 		"message": fmt.Sprintf("获取到 %d 条日志，第 %d 页，共 %d 条", len(logEntries), page, total),
+		"search_bodies": filter.SearchBodies && filter.Search != "",
 	}
 }
 
@@ -3425,16 +10510,22 @@ func (a *App) GetSystemInfo() map[string]interface{} {
 	}
 }
 
-// GetEndpointStats 获取端点统计
-func (a *App) GetEndpointStats() []interface{} {
-	endpoints := a.GetEndpoints()
+// GetEndpointStats 获取端点统计，provider / region 非空时只返回匹配的端点（不区分大小写）
+func (a *App) GetEndpointStats(provider, region string) []interface{} {
+	endpoints, _ := a.GetEndpoints()["data"].([]interface{})
 	result := make([]interface{}, 0, len(endpoints))
+	now := time.Now()
 
 	for _, epInterface := range endpoints {
 		ep, ok := epInterface.(map[string]interface{})
 		if !ok {
 			continue
 		}
+		epProvider, _ := ep["provider"].(string)
+		epRegion, _ := ep["region"].(string)
+		if !matchesEndpointGroup(epProvider, epRegion, provider, region) {
+			continue
+		}
 
 		stat := map[string]interface{}{
 			"name":              ep["name"],
@@ -3443,18 +10534,75 @@ func (a *App) GetEndpointStats() []interface{} {
 			"avg_response_time": 0,
 			"status":            ep["status"],
 			"enabled":           ep["enabled"],
+			"provider":          epProvider,
+			"region":            epRegion,
 			"api_type":          "Go Methods (统一架构)",
 		}
+		name, _ := ep["name"].(string)
+		if adaptiveTimeout, p95 := a.endpointAdaptiveTimeout(name, false); adaptiveTimeout > 0 {
+			stat["adaptive_timeout_ms"] = adaptiveTimeout.Milliseconds()
+			stat["p95_latency_ms"] = p95.Milliseconds()
+		}
+		if adaptiveTimeout, p95 := a.endpointAdaptiveTimeout(name, true); adaptiveTimeout > 0 {
+			stat["stream_adaptive_timeout_ms"] = adaptiveTimeout.Milliseconds()
+			stat["stream_p95_latency_ms"] = p95.Milliseconds()
+		}
+		if limit, _ := ep["max_requests_per_minute"].(int); limit > 0 {
+			remaining, resetIn := a.requestBudgetStatus(name, limit, now)
+			stat["max_requests_per_minute"] = limit
+			stat["requests_remaining"] = remaining
+			stat["budget_resets_in_ms"] = resetIn.Milliseconds()
+		}
 		result = append(result, stat)
 	}
 
 	return result
 }
 
-// GetRequestTrends 获取请求趋势
-func (a *App) GetRequestTrends(timeRange string) map[string]interface{} {
+// matchesEndpointGroup 判断端点的 provider / region 是否匹配过滤条件，空条件视为不过滤
+func matchesEndpointGroup(provider, region, wantProvider, wantRegion string) bool {
+	wantProvider = strings.TrimSpace(wantProvider)
+	wantRegion = strings.TrimSpace(wantRegion)
+	if wantProvider != "" && !strings.EqualFold(strings.TrimSpace(provider), wantProvider) {
+		return false
+	}
+	if wantRegion != "" && !strings.EqualFold(strings.TrimSpace(region), wantRegion) {
+		return false
+	}
+	return true
+}
+
+// queryEndpointNamesInGroup 返回 provider / region 匹配的端点名称；两个条件都为空时返回 nil 表示不过滤
+func queryEndpointNamesInGroup(db *sql.DB, provider, region string) ([]string, error) {
+	if strings.TrimSpace(provider) == "" && strings.TrimSpace(region) == "" {
+		return nil, nil
+	}
+
+	rows, err := db.Query("SELECT name, provider, region FROM endpoints")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	names := []string{}
+	for rows.Next() {
+		var name, epProvider, epRegion sql.NullString
+		if err := rows.Scan(&name, &epProvider, &epRegion); err != nil {
+			return nil, err
+		}
+		if matchesEndpointGroup(epProvider.String, epRegion.String, provider, region) {
+			names = append(names, name.String)
+		}
+	}
+	return names, rows.Err()
+}
+
+// GetRequestTrends 获取请求趋势；provider / region 非空时只统计匹配端点的请求，便于按服务商/地区查看成功率
+func (a *App) GetRequestTrends(timeRange, provider, region string) map[string]interface{} {
 	a.mutex.RLock()
-	defer a.mutex.RUnlock()
+	db := a.db
+	requestLogger := a.requestLogger
+	a.mutex.RUnlock()
 
 	// 根据时间范围确定数据点数量和间隔
 	var dataPoints int
@@ -3478,18 +10626,61 @@ func (a *App) GetRequestTrends(timeRange string) map[string]interface{} {
 		interval = 1 * time.Hour
 	}
 
-	// 生成趋势数据
-	data := make([]interface{}, 0, dataPoints)
 	now := time.Now()
+	start := now.Add(-time.Duration(dataPoints-1) * interval)
+	requests := make([]int, dataPoints)
+	successes := make([]int, dataPoints)
+	failures := make([]int, dataPoints)
 
-	for i := dataPoints - 1; i >= 0; i-- {
-		timePoint := now.Add(-time.Duration(i) * interval)
+	// bucketOf 返回时间所在的数据点下标，超出范围时返回 -1
+	bucketOf := func(t time.Time) int {
+		if t.Before(start) {
+			return -1
+		}
+		index := int(t.Sub(start) / interval)
+		if index >= dataPoints {
+			index = dataPoints - 1
+		}
+		return index
+	}
 
-		// 从日志中统计这个时间段的请求
-		requests := 0
-		successes := 0
-		failures := 0
+	filtered := strings.TrimSpace(provider) != "" || strings.TrimSpace(region) != ""
+	if requestLogger != nil {
+		var endpointNames []string
+		if filtered && db != nil {
+			names, err := queryEndpointNamesInGroup(db, provider, region)
+			if err != nil {
+				return map[string]interface{}{
+					"success": false,
+					"message": fmt.Sprintf("查询端点分组失败: %v", err),
+				}
+			}
+			endpointNames = names
+		}
 
+		a.flushRequestLogs()
+		outcomes, err := requestLogger.RequestOutcomesSince(start, endpointNames)
+		if err != nil {
+			return map[string]interface{}{
+				"success": false,
+				"message": fmt.Sprintf("查询请求趋势失败: %v", err),
+			}
+		}
+		for _, outcome := range outcomes {
+			index := bucketOf(outcome.Timestamp)
+			if index < 0 {
+				continue
+			}
+			requests[index]++
+			if outcome.Succeeded {
+				successes[index]++
+			} else {
+				failures[index]++
+			}
+		}
+	} else if !filtered {
+		// 未启用请求日志时退回内存日志（无端点信息，无法按分组过滤）
+		a.logsMu.Lock()
 		for _, log := range a.logs {
 			// 只统计包含请求信息的日志
 			if log.RequestID == "" {
@@ -3497,48 +10688,42 @@ func (a *App) GetRequestTrends(timeRange string) map[string]interface{} {
 			}
 
 			// 解析日志时间
-			logTime, err := time.Parse("2006-01-02 15:04:05", log.Timestamp)
+			logTime, err := time.ParseInLocation("2006-01-02 15:04:05", log.Timestamp, time.Local)
 			if err != nil {
 				continue
 			}
 
-			// 检查是否在当前时间窗口
-			if (logTime.Equal(timePoint) || logTime.After(timePoint)) &&
-				(logTime.Before(timePoint.Add(interval)) || logTime.Equal(timePoint.Add(interval))) {
-				requests++
-				if log.Level == "error" {
-					failures++
-				} else {
-					successes++
-				}
+			index := bucketOf(logTime)
+			if index < 0 {
+				continue
+			}
+			requests[index]++
+			if log.Level == "error" {
+				failures[index]++
+			} else {
+				successes[index]++
 			}
 		}
-
-		data = append(data, map[string]interface{}{
-			"time":      timePoint.Format("2006-01-02T15:04:05Z"),
-			"requests":  requests,
-			"successes": successes,
-			"failures":  failures,
-		})
+		a.logsMu.Unlock()
 	}
 
-	// 计算总计
+	// 生成趋势数据并计算总计
+	data := make([]interface{}, 0, dataPoints)
 	totalRequests := 0
 	totalSuccesses := 0
 	totalFailures := 0
 
-	for _, point := range data {
-		if pointMap, ok := point.(map[string]interface{}); ok {
-			if req, ok := pointMap["requests"].(int); ok {
-				totalRequests += req
-			}
-			if succ, ok := pointMap["successes"].(int); ok {
-				totalSuccesses += succ
-			}
-			if fail, ok := pointMap["failures"].(int); ok {
-				totalFailures += fail
-			}
-		}
+	for i := 0; i < dataPoints; i++ {
+		timePoint := start.Add(time.Duration(i) * interval)
+		data = append(data, map[string]interface{}{
+			"time":      timePoint.Format("2006-01-02T15:04:05Z"),
+			"requests":  requests[i],
+			"successes": successes[i],
+			"failures":  failures[i],
+		})
+		totalRequests += requests[i]
+		totalSuccesses += successes[i]
+		totalFailures += failures[i]
 	}
 
 	successRate := 0.0
@@ -3547,7 +10732,10 @@ func (a *App) GetRequestTrends(timeRange string) map[string]interface{} {
 	}
 
 	return map[string]interface{}{
+		"success":        true,
 		"timeRange":      timeRange,
+		"provider":       strings.TrimSpace(provider),
+		"region":         strings.TrimSpace(region),
 		"data":           data,
 		"totalRequests":  totalRequests,
 		"totalSuccesses": totalSuccesses,
@@ -3567,48 +10755,374 @@ func (a *App) ensureEndpointSchema(db *sql.DB) error {
 	columns := map[string]bool{}
 	for rows.Next() {
 		var (
-			cid       int
-			name      string
-			colType   string
-			notNull   int
-			dfltValue sql.NullString
-			pk        int
+			cid       int
+			name      string
+			colType   string
+			notNull   int
+			dfltValue sql.NullString
+			pk        int
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dfltValue, &pk); err != nil {
+			return err
+		}
+		columns[name] = true
+	}
+
+	migrations := []struct {
+		name string
+		sql  string
+	}{
+		{"tags", "ALTER TABLE endpoints ADD COLUMN tags TEXT"},
+		{"status", "ALTER TABLE endpoints ADD COLUMN status TEXT DEFAULT 'healthy'"},
+		{"response_time", "ALTER TABLE endpoints ADD COLUMN response_time INTEGER DEFAULT 0"},
+		{"last_check", "ALTER TABLE endpoints ADD COLUMN last_check TEXT"},
+		{"created_at", "ALTER TABLE endpoints ADD COLUMN created_at TEXT"},
+		{"updated_at", "ALTER TABLE endpoints ADD COLUMN updated_at TEXT"},
+		{"model_rewrite_enabled", "ALTER TABLE endpoints ADD COLUMN model_rewrite_enabled BOOLEAN DEFAULT FALSE"},
+		{"target_model", "ALTER TABLE endpoints ADD COLUMN target_model TEXT"},
+		{"parameter_overrides", "ALTER TABLE endpoints ADD COLUMN parameter_overrides TEXT"},
+		{"model_rewrite_rules", "ALTER TABLE endpoints ADD COLUMN model_rewrite_rules TEXT"},
+		{"extra_system_prompt", "ALTER TABLE endpoints ADD COLUMN extra_system_prompt TEXT"},
+		{"force_thinking", "ALTER TABLE endpoints ADD COLUMN force_thinking BOOLEAN DEFAULT FALSE"},
+		{"disable_thinking", "ALTER TABLE endpoints ADD COLUMN disable_thinking BOOLEAN DEFAULT FALSE"},
+		{"user_field_mode", "ALTER TABLE endpoints ADD COLUMN user_field_mode TEXT DEFAULT 'truncate'"},
+		{"anthropic_version", "ALTER TABLE endpoints ADD COLUMN anthropic_version TEXT DEFAULT ''"},
+		{"notes", "ALTER TABLE endpoints ADD COLUMN notes TEXT DEFAULT ''"},
+		{"user_agent", "ALTER TABLE endpoints ADD COLUMN user_agent TEXT DEFAULT ''"},
+		{"auto_disabled", "ALTER TABLE endpoints ADD COLUMN auto_disabled BOOLEAN DEFAULT FALSE"},
+		{"strip_reasoning_in_response", "ALTER TABLE endpoints ADD COLUMN strip_reasoning_in_response BOOLEAN DEFAULT FALSE"},
+		{"insecure_skip_verify", "ALTER TABLE endpoints ADD COLUMN insecure_skip_verify BOOLEAN DEFAULT FALSE"},
+		{"default_max_tokens", "ALTER TABLE endpoints ADD COLUMN default_max_tokens INTEGER DEFAULT 0"},
+		{"max_tokens_ceiling", "ALTER TABLE endpoints ADD COLUMN max_tokens_ceiling INTEGER DEFAULT 0"},
+		{"max_tokens_field_name", "ALTER TABLE endpoints ADD COLUMN max_tokens_field_name TEXT DEFAULT ''"},
+		{"last_error", "ALTER TABLE endpoints ADD COLUMN last_error TEXT DEFAULT ''"},
+		{"last_error_at", "ALTER TABLE endpoints ADD COLUMN last_error_at TEXT DEFAULT ''"},
+		{"response_header_overrides", "ALTER TABLE endpoints ADD COLUMN response_header_overrides TEXT DEFAULT '{}'"},
+		{"sse_event_filter", "ALTER TABLE endpoints ADD COLUMN sse_event_filter TEXT DEFAULT '[]'"},
+		{"max_requests_per_minute", "ALTER TABLE endpoints ADD COLUMN max_requests_per_minute INTEGER DEFAULT 0"},
+		{"hmac_header", "ALTER TABLE endpoints ADD COLUMN hmac_header TEXT DEFAULT ''"},
+		{"hmac_secret", "ALTER TABLE endpoints ADD COLUMN hmac_secret TEXT DEFAULT ''"},
+		{"hmac_algo", "ALTER TABLE endpoints ADD COLUMN hmac_algo TEXT DEFAULT ''"},
+		{"stream_include_usage", "ALTER TABLE endpoints ADD COLUMN stream_include_usage BOOLEAN DEFAULT 1"},
+		{"fallback_on_4xx", "ALTER TABLE endpoints ADD COLUMN fallback_on_4xx BOOLEAN DEFAULT NULL"},
+		{"oauth_config", "ALTER TABLE endpoints ADD COLUMN oauth_config TEXT DEFAULT ''"},
+		{"body_transforms", "ALTER TABLE endpoints ADD COLUMN body_transforms TEXT DEFAULT '[]'"},
+		{"provider", "ALTER TABLE endpoints ADD COLUMN provider TEXT DEFAULT ''"},
+		{"region", "ALTER TABLE endpoints ADD COLUMN region TEXT DEFAULT ''"},
+		{"openai_organization", "ALTER TABLE endpoints ADD COLUMN openai_organization TEXT DEFAULT ''"},
+		{"openai_project", "ALTER TABLE endpoints ADD COLUMN openai_project TEXT DEFAULT ''"},
+		{"supports_audio", "ALTER TABLE endpoints ADD COLUMN supports_audio BOOLEAN DEFAULT FALSE"},
+		{"thinking_stream_mode", "ALTER TABLE endpoints ADD COLUMN thinking_stream_mode TEXT DEFAULT ''"},
+		{"allowed_models", "ALTER TABLE endpoints ADD COLUMN allowed_models TEXT DEFAULT '[]'"},
+	}
+
+	for _, migration := range migrations {
+		if !columns[migration.name] {
+			if _, err := db.Exec(migration.sql); err != nil {
+				lowerErr := strings.ToLower(err.Error())
+				if strings.Contains(lowerErr, "duplicate") || strings.Contains(lowerErr, "exists") {
+					continue
+				}
+				return fmt.Errorf("failed to add column %s: %w", migration.name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// healthHistoryLimitPerEndpoint 每个端点保留的健康检查历史条数
+const healthHistoryLimitPerEndpoint = 200
+
+// healthHistoryEntry 单条健康检查历史
+type healthHistoryEntry struct {
+	Timestamp    string
+	Status       string
+	ResponseTime int
+	Error        string
+}
+
+// ensureHealthHistorySchema 确保endpoint_health_history表存在
+func ensureHealthHistorySchema(db *sql.DB) error {
+	if _, err := db.Exec(`
+	CREATE TABLE IF NOT EXISTS endpoint_health_history (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		endpoint_id TEXT NOT NULL,
+		endpoint_name TEXT DEFAULT '',
+		timestamp TEXT DEFAULT '',
+		status TEXT DEFAULT '',
+		response_time INTEGER DEFAULT 0,
+		error TEXT DEFAULT ''
+	);`); err != nil {
+		return fmt.Errorf("failed to create endpoint_health_history table: %w", err)
+	}
+	if _, err := db.Exec("CREATE INDEX IF NOT EXISTS idx_health_history_endpoint ON endpoint_health_history(endpoint_id, id)"); err != nil {
+		return fmt.Errorf("failed to create endpoint_health_history index: %w", err)
+	}
+	return nil
+}
+
+// recordHealthHistory 写入一条健康检查结果，并裁剪该端点超出上限的旧记录
+func recordHealthHistory(db *sql.DB, endpointID, endpointName, status string, responseTime int, errMsg, timestamp string) error {
+	if _, err := db.Exec(`
+		INSERT INTO endpoint_health_history (endpoint_id, endpoint_name, timestamp, status, response_time, error)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, endpointID, endpointName, timestamp, status, responseTime, errMsg); err != nil {
+		return err
+	}
+
+	_, err := db.Exec(`
+		DELETE FROM endpoint_health_history
+		WHERE endpoint_id = ? AND id NOT IN (
+			SELECT id FROM endpoint_health_history WHERE endpoint_id = ? ORDER BY id DESC LIMIT ?
+		)
+	`, endpointID, endpointID, healthHistoryLimitPerEndpoint)
+	return err
+}
+
+// queryHealthHistory 按时间倒序读取端点的健康检查历史
+func queryHealthHistory(db *sql.DB, endpointID string, limit int) ([]healthHistoryEntry, error) {
+	rows, err := db.Query(`
+		SELECT timestamp, status, response_time, error
+		FROM endpoint_health_history
+		WHERE endpoint_id = ?
+		ORDER BY id DESC
+		LIMIT ?
+	`, endpointID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []healthHistoryEntry
+	for rows.Next() {
+		var (
+			timestamp, status, errMsg sql.NullString
+			responseTime              sql.NullInt64
+		)
+		if err := rows.Scan(&timestamp, &status, &responseTime, &errMsg); err != nil {
+			return nil, err
+		}
+		entries = append(entries, healthHistoryEntry{
+			Timestamp:    timestamp.String,
+			Status:       status.String,
+			ResponseTime: int(responseTime.Int64),
+			Error:        errMsg.String,
+		})
+	}
+	return entries, rows.Err()
+}
+
+// deadLetterLimit 死信表保留的最大记录数
+const deadLetterLimit = 500
+
+// deadLetterAttempt 死信请求中单个端点的尝试结果
+type deadLetterAttempt struct {
+	Endpoint      string `json:"endpoint"`
+	AttemptNumber int    `json:"attempt_number"`
+	StatusCode    int    `json:"status_code"`
+	Error         string `json:"error,omitempty"`
+	DurationMs    int64  `json:"duration_ms"`
+}
+
+// deadLetterEntry 死信表中的一条记录
+type deadLetterEntry struct {
+	ID               int64
+	RequestID        string
+	Timestamp        string
+	Method           string
+	Path             string
+	RawQuery         string
+	RequestHeaders   map[string]string
+	RequestBody      string
+	ClientType       string
+	RequestFormat    string
+	FinalStatus      int
+	FinalError       string
+	Attempts         []deadLetterAttempt
+	ReplayCount      int
+	LastReplayAt     string
+	LastReplayStatus int
+}
+
+// ensureDeadLetterSchema 确保dead_letters表存在
+func ensureDeadLetterSchema(db *sql.DB) error {
+	if _, err := db.Exec(`
+	CREATE TABLE IF NOT EXISTS dead_letters (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		request_id TEXT DEFAULT '',
+		timestamp TEXT DEFAULT '',
+		method TEXT DEFAULT '',
+		path TEXT DEFAULT '',
+		raw_query TEXT DEFAULT '',
+		request_headers TEXT DEFAULT '{}',
+		request_body TEXT DEFAULT '',
+		client_type TEXT DEFAULT '',
+		request_format TEXT DEFAULT '',
+		final_status INTEGER DEFAULT 0,
+		final_error TEXT DEFAULT '',
+		attempts TEXT DEFAULT '[]',
+		replay_count INTEGER DEFAULT 0,
+		last_replay_at TEXT DEFAULT '',
+		last_replay_status INTEGER DEFAULT 0
+	);`); err != nil {
+		return fmt.Errorf("failed to create dead_letters table: %w", err)
+	}
+	return nil
+}
+
+// insertDeadLetter 写入一条死信记录，并裁剪超出上限的旧记录
+func insertDeadLetter(db *sql.DB, entry deadLetterEntry) error {
+	headersJSON, err := json.Marshal(entry.RequestHeaders)
+	if err != nil {
+		return err
+	}
+	if entry.Attempts == nil {
+		entry.Attempts = []deadLetterAttempt{}
+	}
+	attemptsJSON, err := json.Marshal(entry.Attempts)
+	if err != nil {
+		return err
+	}
+
+	if _, err := db.Exec(`
+		INSERT INTO dead_letters (request_id, timestamp, method, path, raw_query, request_headers, request_body,
+			client_type, request_format, final_status, final_error, attempts)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, entry.RequestID, entry.Timestamp, entry.Method, entry.Path, entry.RawQuery, string(headersJSON), entry.RequestBody,
+		entry.ClientType, entry.RequestFormat, entry.FinalStatus, entry.FinalError, string(attemptsJSON)); err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+		DELETE FROM dead_letters
+		WHERE id NOT IN (SELECT id FROM dead_letters ORDER BY id DESC LIMIT ?)
+	`, deadLetterLimit)
+	return err
+}
+
+// queryDeadLetters 按时间倒序读取死信记录；id > 0 时只读取指定记录
+func queryDeadLetters(db *sql.DB, id int64, limit int) ([]deadLetterEntry, error) {
+	query := `
+		SELECT id, request_id, timestamp, method, path, raw_query, request_headers, request_body,
+			client_type, request_format, final_status, final_error, attempts,
+			replay_count, last_replay_at, last_replay_status
+		FROM dead_letters`
+	args := []interface{}{}
+	if id > 0 {
+		query += " WHERE id = ?"
+		args = append(args, id)
+	}
+	query += " ORDER BY id DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []deadLetterEntry
+	for rows.Next() {
+		var (
+			entry                                        deadLetterEntry
+			requestID, timestamp, method, path, rawQuery sql.NullString
+			headersJSON, body, clientType, requestFormat sql.NullString
+			finalError, attemptsJSON, lastReplayAt       sql.NullString
+			finalStatus, replayCount, lastReplayStatus   sql.NullInt64
 		)
-		if err := rows.Scan(&cid, &name, &colType, &notNull, &dfltValue, &pk); err != nil {
-			return err
+		if err := rows.Scan(&entry.ID, &requestID, &timestamp, &method, &path, &rawQuery, &headersJSON, &body,
+			&clientType, &requestFormat, &finalStatus, &finalError, &attemptsJSON,
+			&replayCount, &lastReplayAt, &lastReplayStatus); err != nil {
+			return nil, err
 		}
-		columns[name] = true
+		entry.RequestID = requestID.String
+		entry.Timestamp = timestamp.String
+		entry.Method = method.String
+		entry.Path = path.String
+		entry.RawQuery = rawQuery.String
+		entry.RequestBody = body.String
+		entry.ClientType = clientType.String
+		entry.RequestFormat = requestFormat.String
+		entry.FinalStatus = int(finalStatus.Int64)
+		entry.FinalError = finalError.String
+		entry.ReplayCount = int(replayCount.Int64)
+		entry.LastReplayAt = lastReplayAt.String
+		entry.LastReplayStatus = int(lastReplayStatus.Int64)
+		entry.RequestHeaders = map[string]string{}
+		if headersJSON.Valid && headersJSON.String != "" {
+			_ = json.Unmarshal([]byte(headersJSON.String), &entry.RequestHeaders)
+		}
+		entry.Attempts = []deadLetterAttempt{}
+		if attemptsJSON.Valid && attemptsJSON.String != "" {
+			_ = json.Unmarshal([]byte(attemptsJSON.String), &entry.Attempts)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// trackDeadLetterAttempt 暂存失败的端点尝试，请求最终全部失败时随死信一起保存
+func (a *App) trackDeadLetterAttempt(entry *logger.RequestLog) {
+	if entry.RequestID == "" || (entry.StatusCode > 0 && entry.StatusCode < 400 && entry.Error == "") {
+		return
 	}
+	a.deadLetterMu.Lock()
+	defer a.deadLetterMu.Unlock()
+	if a.deadLetterAttempts == nil {
+		a.deadLetterAttempts = make(map[string][]deadLetterAttempt)
+	}
+	a.deadLetterAttempts[entry.RequestID] = append(a.deadLetterAttempts[entry.RequestID], deadLetterAttempt{
+		Endpoint:      entry.Endpoint,
+		AttemptNumber: entry.AttemptNumber,
+		StatusCode:    entry.StatusCode,
+		Error:         entry.Error,
+		DurationMs:    entry.DurationMs,
+	})
+}
 
-	migrations := []struct {
-		name string
-		sql  string
-	}{
-		{"tags", "ALTER TABLE endpoints ADD COLUMN tags TEXT"},
-		{"status", "ALTER TABLE endpoints ADD COLUMN status TEXT DEFAULT 'healthy'"},
-		{"response_time", "ALTER TABLE endpoints ADD COLUMN response_time INTEGER DEFAULT 0"},
-		{"last_check", "ALTER TABLE endpoints ADD COLUMN last_check TEXT"},
-		{"created_at", "ALTER TABLE endpoints ADD COLUMN created_at TEXT"},
-		{"updated_at", "ALTER TABLE endpoints ADD COLUMN updated_at TEXT"},
-		{"model_rewrite_enabled", "ALTER TABLE endpoints ADD COLUMN model_rewrite_enabled BOOLEAN DEFAULT FALSE"},
-		{"target_model", "ALTER TABLE endpoints ADD COLUMN target_model TEXT"},
-		{"parameter_overrides", "ALTER TABLE endpoints ADD COLUMN parameter_overrides TEXT"},
-		{"model_rewrite_rules", "ALTER TABLE endpoints ADD COLUMN model_rewrite_rules TEXT"},
+// takeDeadLetterAttempts 取出并清除请求暂存的尝试记录
+func (a *App) takeDeadLetterAttempts(requestID string) []deadLetterAttempt {
+	a.deadLetterMu.Lock()
+	defer a.deadLetterMu.Unlock()
+	attempts := a.deadLetterAttempts[requestID]
+	delete(a.deadLetterAttempts, requestID)
+	return attempts
+}
+
+// recordDeadLetter 将所有端点都失败的请求写入死信表（原始请求体按日志记录策略保存，请求头已脱敏）
+func (a *App) recordDeadLetter(requestID string, r *http.Request, body string, headers map[string]string, clientType, requestFormat string, status int, errMsg string) {
+	a.mutex.RLock()
+	db := a.db
+	a.mutex.RUnlock()
+	if db == nil {
+		return
 	}
 
-	for _, migration := range migrations {
-		if !columns[migration.name] {
-			if _, err := db.Exec(migration.sql); err != nil {
-				lowerErr := strings.ToLower(err.Error())
-				if strings.Contains(lowerErr, "duplicate") || strings.Contains(lowerErr, "exists") {
-					continue
-				}
-				return fmt.Errorf("failed to add column %s: %w", migration.name, err)
-			}
-		}
+	// 与请求日志使用相同的记录策略：logging.body_sample_rate 未抽中或 logging.per_client 为 none 时不保存请求体
+	if !bodySampled(requestID, a.getBodySampleRate()) || a.getLogBodyPolicy(clientType).request == logBodyNone {
+		body = ""
 	}
 
-	return nil
+	entry := deadLetterEntry{
+		RequestID:      requestID,
+		Timestamp:      time.Now().Format(time.RFC3339),
+		Method:         r.Method,
+		Path:           r.URL.Path,
+		RawQuery:       r.URL.RawQuery,
+		RequestHeaders: headers,
+		RequestBody:    body,
+		ClientType:     clientType,
+		RequestFormat:  requestFormat,
+		FinalStatus:    status,
+		FinalError:     errMsg,
+		Attempts:       a.takeDeadLetterAttempts(requestID),
+	}
+	if err := insertDeadLetter(db, entry); err != nil {
+		runtime.LogWarning(a.ctx, fmt.Sprintf("写入死信记录失败 (%s): %v", requestID, err))
+		return
+	}
+	a.addLog("warn", fmt.Sprintf("请求 %s 所有端点均失败，已写入死信队列", requestID))
 }
 
 // ensureRequestLogsSchema 确保request_logs表存在并包含所有必要字段
@@ -3638,6 +11152,9 @@ func (a *App) ensureRequestLogsSchema(db *sql.DB) error {
 		tags TEXT DEFAULT '[]',
 		content_type_override TEXT DEFAULT '',
 		session_id TEXT DEFAULT '',
+		service_tier TEXT DEFAULT '',
+		auth_method_used TEXT DEFAULT '',
+		attempts TEXT DEFAULT '[]',
 		original_model TEXT DEFAULT '',
 		rewritten_model TEXT DEFAULT '',
 		model_rewrite_applied INTEGER DEFAULT 0,
@@ -3717,6 +11234,9 @@ func (a *App) ensureRequestLogsSchema(db *sql.DB) error {
 		"tags":                          "TEXT DEFAULT '[]'",
 		"content_type_override":         "TEXT DEFAULT ''",
 		"session_id":                    "TEXT DEFAULT ''",
+		"service_tier":                  "TEXT DEFAULT ''",
+		"auth_method_used":              "TEXT DEFAULT ''",
+		"attempts":                      "TEXT DEFAULT '[]'",
 		"original_model":                "TEXT DEFAULT ''",
 		"rewritten_model":               "TEXT DEFAULT ''",
 		"model_rewrite_applied":         "INTEGER DEFAULT 0",
@@ -4226,6 +11746,7 @@ func getCurrentTimestamp() string {
 type modelRewriteRule struct {
 	SourcePattern string `json:"source_pattern"`
 	TargetModel   string `json:"target_model"`
+	MatchType     string `json:"match_type,omitempty"`
 }
 
 type modelRewritePayload struct {
@@ -4299,6 +11820,56 @@ func extractBool(raw interface{}, defaultValue bool) bool {
 	return defaultValue
 }
 
+// extractOptionalBool 解析三态布尔值：nil、空字符串或无法解析时返回 nil（写入数据库为 NULL）
+func extractOptionalBool(raw interface{}) interface{} {
+	switch v := raw.(type) {
+	case bool, float64, float32, int, int32, int64:
+		return extractBool(v, false)
+	case string:
+		trimmed := strings.TrimSpace(v)
+		if _, err := strconv.ParseBool(trimmed); err == nil {
+			return extractBool(trimmed, false)
+		}
+		if _, err := strconv.Atoi(trimmed); err == nil {
+			return extractBool(trimmed, false)
+		}
+	}
+	return nil
+}
+
+// nullableBool 将可空布尔列转换为 JSON 友好的值（NULL 返回 nil）
+func nullableBool(value sql.NullBool) interface{} {
+	if !value.Valid {
+		return nil
+	}
+	return value.Bool
+}
+
+// extractNonNegativeInt 解析前端或配置传入的整数，无法解析或为负数时返回0
+func extractNonNegativeInt(raw interface{}) int {
+	value := 0
+	switch v := raw.(type) {
+	case float64:
+		value = int(v)
+	case float32:
+		value = int(v)
+	case int:
+		value = v
+	case int32:
+		value = int(v)
+	case int64:
+		value = int(v)
+	case string:
+		if parsed, err := strconv.Atoi(strings.TrimSpace(v)); err == nil {
+			value = parsed
+		}
+	}
+	if value < 0 {
+		return 0
+	}
+	return value
+}
+
 func extractPriority(raw interface{}) int {
 	priority := 1
 
@@ -4407,17 +11978,17 @@ func decodeStringSlice(value sql.NullString) []string {
 	return result
 }
 
-func parseStringMap(raw interface{}) (map[string]string, error) {
-	result := map[string]string{}
+// parseParameterOverrides 解析端点参数覆盖配置：字符串值沿用旧规则（空串删除，其余按JSON解析、失败则作为字符串），
+// 非字符串JSON值按原类型写入，null 表示删除该字段
+func parseParameterOverrides(raw interface{}) (map[string]interface{}, error) {
+	result := map[string]interface{}{}
 
 	switch v := raw.(type) {
 	case map[string]string:
 		for key, value := range v {
-			trimmedKey := strings.TrimSpace(key)
-			if trimmedKey == "" {
-				continue
+			if trimmedKey := strings.TrimSpace(key); trimmedKey != "" {
+				result[trimmedKey] = strings.TrimSpace(value)
 			}
-			result[trimmedKey] = strings.TrimSpace(value)
 		}
 	case map[string]interface{}:
 		for key, value := range v {
@@ -4425,15 +11996,13 @@ func parseStringMap(raw interface{}) (map[string]string, error) {
 			if trimmedKey == "" {
 				continue
 			}
-			switch cast := value.(type) {
+			switch typed := value.(type) {
 			case string:
-				result[trimmedKey] = strings.TrimSpace(cast)
-			case fmt.Stringer:
-				result[trimmedKey] = strings.TrimSpace(cast.String())
-			case nil:
-				result[trimmedKey] = ""
+				result[trimmedKey] = strings.TrimSpace(typed)
+			case nil, bool, float64, float32, int, int64, json.Number, []interface{}, map[string]interface{}:
+				result[trimmedKey] = value
 			default:
-				return nil, fmt.Errorf("parameter value for %s must be string", key)
+				return nil, fmt.Errorf("unsupported parameter value type %T for %s", value, key)
 			}
 		}
 	case string:
@@ -4441,52 +12010,387 @@ func parseStringMap(raw interface{}) (map[string]string, error) {
 		if trimmed == "" {
 			return result, nil
 		}
-		if err := json.Unmarshal([]byte(trimmed), &result); err != nil {
+		var decoded map[string]interface{}
+		if err := json.Unmarshal([]byte(trimmed), &decoded); err != nil {
 			return nil, err
 		}
+		return parseParameterOverrides(decoded)
 	case nil:
 		return result, nil
 	default:
 		return nil, fmt.Errorf("unsupported parameter type %T", raw)
 	}
-
-	return result, nil
+
+	return result, nil
+}
+
+// parseHeaderOverrides 解析端点响应头覆盖配置（头部名 -> 值），空值或 null 表示删除该头部
+func parseHeaderOverrides(raw interface{}) (map[string]string, error) {
+	result := map[string]string{}
+
+	switch v := raw.(type) {
+	case map[string]string:
+		for key, value := range v {
+			result[key] = value
+		}
+	case map[string]interface{}:
+		for key, value := range v {
+			switch typed := value.(type) {
+			case nil:
+				result[key] = ""
+			case string:
+				result[key] = typed
+			default:
+				return nil, fmt.Errorf("header value for %s must be a string, got %T", key, value)
+			}
+		}
+	case string:
+		trimmed := strings.TrimSpace(v)
+		if trimmed == "" {
+			return result, nil
+		}
+		var decoded map[string]interface{}
+		if err := json.Unmarshal([]byte(trimmed), &decoded); err != nil {
+			return nil, err
+		}
+		return parseHeaderOverrides(decoded)
+	case nil:
+		return result, nil
+	default:
+		return nil, fmt.Errorf("unsupported header overrides type %T", raw)
+	}
+
+	normalised := make(map[string]string, len(result))
+	for key, value := range result {
+		name := strings.TrimSpace(key)
+		if name == "" {
+			continue
+		}
+		if strings.ContainsAny(name, " \t\r\n:") {
+			return nil, fmt.Errorf("invalid header name %q", key)
+		}
+		if strings.ContainsAny(value, "\r\n") {
+			return nil, fmt.Errorf("header value for %s must not contain line breaks", name)
+		}
+		normalised[http.CanonicalHeaderKey(name)] = strings.TrimSpace(value)
+	}
+	return normalised, nil
+}
+
+func serialiseHeaderOverrides(raw interface{}, emptyFallback string) (string, error) {
+	overrides, err := parseHeaderOverrides(raw)
+	if err != nil {
+		return "", err
+	}
+	if len(overrides) == 0 {
+		return emptyFallback, nil
+	}
+	payload, err := json.Marshal(overrides)
+	if err != nil {
+		return "", err
+	}
+	return string(payload), nil
+}
+
+func decodeHeaderOverrides(value sql.NullString) map[string]string {
+	if !value.Valid {
+		return map[string]string{}
+	}
+	overrides, err := parseHeaderOverrides(value.String)
+	if err != nil {
+		return map[string]string{}
+	}
+	return overrides
+}
+
+// protectedResponseHeaders 由代理根据实际写出的响应体计算，不允许被 response_header_overrides 修改
+var protectedResponseHeaders = map[string]bool{
+	"Content-Length":    true,
+	"Transfer-Encoding": true,
+}
+
+// applyResponseHeaderOverrides 按端点 response_header_overrides 修改返回给客户端的响应头：空值删除，非空值覆盖
+func applyResponseHeaderOverrides(header http.Header, overrides map[string]string) {
+	for name, value := range overrides {
+		if protectedResponseHeaders[http.CanonicalHeaderKey(name)] {
+			continue
+		}
+		if value == "" {
+			header.Del(name)
+			continue
+		}
+		header.Set(name, value)
+	}
+}
+
+func serialiseParameterOverrides(raw interface{}, emptyFallback string) (string, error) {
+	overrides, err := parseParameterOverrides(raw)
+	if err != nil {
+		return "", err
+	}
+	if len(overrides) == 0 {
+		return emptyFallback, nil
+	}
+	payload, err := json.Marshal(overrides)
+	if err != nil {
+		return "", err
+	}
+	return string(payload), nil
+}
+
+func decodeParameterOverrides(value sql.NullString) map[string]interface{} {
+	if !value.Valid {
+		return map[string]interface{}{}
+	}
+	overrides, err := parseParameterOverrides(value.String)
+	if err != nil {
+		return map[string]interface{}{}
+	}
+	return overrides
+}
+
+// parseBodyTransforms 解析并校验端点 body_transforms（数组或 JSON 字符串）：op 仅支持 set/delete，path 必须是合法 JSONPath
+func parseBodyTransforms(raw interface{}) ([]config.BodyTransform, error) {
+	if raw == nil {
+		return nil, nil
+	}
+	var data []byte
+	switch v := raw.(type) {
+	case string:
+		if strings.TrimSpace(v) == "" {
+			return nil, nil
+		}
+		data = []byte(v)
+	default:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		data = encoded
+	}
+
+	var transforms []config.BodyTransform
+	if err := json.Unmarshal(data, &transforms); err != nil {
+		return nil, fmt.Errorf("必须是 {op, path, value} 数组: %w", err)
+	}
+	for i := range transforms {
+		transforms[i].Op = strings.ToLower(strings.TrimSpace(transforms[i].Op))
+		transforms[i].Path = strings.TrimSpace(transforms[i].Path)
+		if transforms[i].Op != "set" && transforms[i].Op != "delete" {
+			return nil, fmt.Errorf("第 %d 项: 不支持的 op %q (支持: set, delete)", i+1, transforms[i].Op)
+		}
+		if _, err := jsonpath.Compile(transforms[i].Path); err != nil {
+			return nil, fmt.Errorf("第 %d 项: %v", i+1, err)
+		}
+		if transforms[i].Op == "delete" {
+			transforms[i].Value = nil
+		}
+	}
+	return transforms, nil
+}
+
+// serialiseBodyTransforms 校验后序列化 body_transforms，空值保存为 []
+func serialiseBodyTransforms(raw interface{}) (string, error) {
+	transforms, err := parseBodyTransforms(raw)
+	if err != nil {
+		return "", err
+	}
+	if len(transforms) == 0 {
+		return "[]", nil
+	}
+	encoded, err := json.Marshal(transforms)
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
+
+// decodeBodyTransforms 解析数据库中的 body_transforms，无效时返回 nil
+func decodeBodyTransforms(value sql.NullString) []config.BodyTransform {
+	if !value.Valid {
+		return nil
+	}
+	transforms, err := parseBodyTransforms(value.String)
+	if err != nil {
+		return nil
+	}
+	return transforms
+}
+
+// bodyTransformsForResponse 返回给前端的 body_transforms，未配置时为空数组
+func bodyTransformsForResponse(transforms []config.BodyTransform) []config.BodyTransform {
+	if transforms == nil {
+		return []config.BodyTransform{}
+	}
+	return transforms
+}
+
+// applyBodyTransforms 按顺序对请求体执行 JSONPath 改写，返回新请求体与实际生效的操作描述
+func applyBodyTransforms(body []byte, transforms []config.BodyTransform) ([]byte, []string) {
+	if len(transforms) == 0 || len(body) == 0 {
+		return body, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var payload interface{}
+	if err := decoder.Decode(&payload); err != nil {
+		return body, nil
+	}
+
+	var fired []string
+	for _, transform := range transforms {
+		path, err := jsonpath.Compile(transform.Path)
+		if err != nil {
+			continue
+		}
+		var count int
+		if transform.Op == "delete" {
+			payload, count = path.Delete(payload)
+		} else {
+			payload, count = path.Set(payload, transform.Value)
+		}
+		if count > 0 {
+			fired = append(fired, fmt.Sprintf("%s %s (%d)", transform.Op, path, count))
+		}
+	}
+	if len(fired) == 0 {
+		return body, nil
+	}
+
+	updated, err := json.Marshal(payload)
+	if err != nil {
+		return body, nil
+	}
+	return updated, fired
+}
+
+// decodeOAuthConfig 解析数据库中的 oauth_config JSON，空值或无效时返回 nil
+func decodeOAuthConfig(value sql.NullString) *config.OAuthConfig {
+	if !value.Valid || strings.TrimSpace(value.String) == "" {
+		return nil
+	}
+	var cfg config.OAuthConfig
+	if err := json.Unmarshal([]byte(value.String), &cfg); err != nil {
+		return nil
+	}
+	return &cfg
+}
+
+// serialiseOAuthConfig 校验并序列化端点 oauth_config（对象或 JSON 字符串），null/空值表示清除
+func serialiseOAuthConfig(raw interface{}, context string) (string, error) {
+	if raw == nil {
+		return "", nil
+	}
+	var data []byte
+	switch v := raw.(type) {
+	case string:
+		if strings.TrimSpace(v) == "" {
+			return "", nil
+		}
+		data = []byte(v)
+	default:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		data = encoded
+	}
+
+	var cfg config.OAuthConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return "", err
+	}
+	if err := config.ValidateOAuthConfig(&cfg, context); err != nil {
+		return "", err
+	}
+	encoded, err := json.Marshal(&cfg)
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
+
+// encodeParameterOverrides 转换为 EndpointConfig 使用的字符串形式：
+// 空字符串表示删除，字符串值原样保留，其余值为JSON编码（与 internal/proxy 的 applyParameterOverrides 语义一致）
+func encodeParameterOverrides(overrides map[string]interface{}) map[string]string {
+	if len(overrides) == 0 {
+		return nil
+	}
+	encoded := make(map[string]string, len(overrides))
+	for key, value := range overrides {
+		switch typed := value.(type) {
+		case nil:
+			encoded[key] = ""
+			continue
+		case string:
+			// 前端以 Record<string,string> 提交：字符串在应用时再按JSON解析，空串删除字段
+			encoded[key] = typed
+			continue
+		}
+		payload, err := json.Marshal(value)
+		if err != nil {
+			continue
+		}
+		encoded[key] = string(payload)
+	}
+	return encoded
+}
+
+// decodeEncodedParameterOverrides 是 encodeParameterOverrides 的逆过程，空字符串还原为 nil（删除）
+func decodeEncodedParameterOverrides(encoded map[string]string) map[string]interface{} {
+	decoded := make(map[string]interface{}, len(encoded))
+	for key, raw := range encoded {
+		if raw == "" {
+			decoded[key] = nil
+			continue
+		}
+		var value interface{}
+		if err := json.Unmarshal([]byte(raw), &value); err != nil {
+			value = raw
+		}
+		decoded[key] = value
+	}
+	return decoded
 }
 
-func serialiseStringMap(raw interface{}, emptyFallback string) (string, error) {
-	mapping, err := parseStringMap(raw)
-	if err != nil {
-		return "", err
-	}
-	if len(mapping) == 0 {
-		return emptyFallback, nil
-	}
-	payload, err := json.Marshal(mapping)
-	if err != nil {
-		return "", err
+// applyParameterOverrides 按端点配置修改请求体字段：空值删除字段，其余值按JSON解析后写入
+func applyParameterOverrides(body []byte, overrides map[string]string) ([]byte, bool) {
+	if len(overrides) == 0 {
+		return body, false
 	}
-	return string(payload), nil
-}
 
-func decodeStringMap(value sql.NullString) map[string]string {
-	result := map[string]string{}
-	if !value.Valid {
-		return result
+	var payload map[string]interface{}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return body, false
 	}
-	raw := strings.TrimSpace(value.String)
-	if raw == "" {
-		return result
+
+	modified := false
+	for key, raw := range overrides {
+		if raw == "" {
+			if _, exists := payload[key]; exists {
+				delete(payload, key)
+				modified = true
+			}
+			continue
+		}
+		var value interface{}
+		if err := json.Unmarshal([]byte(raw), &value); err != nil {
+			value = raw
+		}
+		payload[key] = value
+		modified = true
 	}
-	if err := json.Unmarshal([]byte(raw), &result); err != nil {
-		return map[string]string{}
+
+	if !modified {
+		return body, false
 	}
-	cleaned := make(map[string]string, len(result))
-	for key, val := range result {
-		if trimmedKey := strings.TrimSpace(key); trimmedKey != "" {
-			cleaned[trimmedKey] = strings.TrimSpace(val)
-		}
+
+	updated, err := json.Marshal(payload)
+	if err != nil {
+		return body, false
 	}
-	return cleaned
+	return updated, true
 }
 
 func parseModelRewriteRules(raw interface{}) ([]modelRewriteRule, error) {
@@ -4498,6 +12402,9 @@ func parseModelRewriteRules(raw interface{}) ([]modelRewriteRule, error) {
 			if err != nil {
 				return nil, err
 			}
+			if _, err := modelrewrite.MatchModelPattern(rule.SourcePattern, rule.MatchType, ""); err != nil {
+				return nil, err
+			}
 			rules = append(rules, rule)
 		}
 		return rules, nil
@@ -4535,6 +12442,13 @@ func modelRewriteRuleFromInterface(raw interface{}) (modelRewriteRule, error) {
 		if tgt, ok := v["target_model"].(string); ok {
 			rule.TargetModel = strings.TrimSpace(tgt)
 		}
+		if matchType, ok := v["match_type"].(string); ok && strings.TrimSpace(matchType) != "" {
+			normalized, err := modelrewrite.NormalizeMatchType(matchType)
+			if err != nil {
+				return rule, err
+			}
+			rule.MatchType = normalized
+		}
 		if rule.TargetModel == "" {
 			return rule, fmt.Errorf("model_rewrite 规则缺少 target_model")
 		}
@@ -4550,6 +12464,13 @@ func modelRewriteRuleFromInterface(raw interface{}) (modelRewriteRule, error) {
 		if tgt, ok := v["target_model"]; ok {
 			rule.TargetModel = strings.TrimSpace(tgt)
 		}
+		if matchType, ok := v["match_type"]; ok && strings.TrimSpace(matchType) != "" {
+			normalized, err := modelrewrite.NormalizeMatchType(matchType)
+			if err != nil {
+				return rule, err
+			}
+			rule.MatchType = normalized
+		}
 		if rule.TargetModel == "" {
 			return rule, fmt.Errorf("model_rewrite 规则缺少 target_model")
 		}
@@ -4654,10 +12575,14 @@ func buildModelRewriteMap(enabled sql.NullBool, target sql.NullString, rules sql
 	if len(parsedRules) > 0 {
 		ruleList := make([]map[string]string, 0, len(parsedRules))
 		for _, rule := range parsedRules {
-			ruleList = append(ruleList, map[string]string{
+			ruleMap := map[string]string{
 				"source_pattern": rule.SourcePattern,
 				"target_model":   rule.TargetModel,
-			})
+			}
+			if rule.MatchType != "" {
+				ruleMap["match_type"] = rule.MatchType
+			}
+			ruleList = append(ruleList, ruleMap)
 		}
 		payload["rules"] = ruleList
 	}
@@ -4671,6 +12596,77 @@ func (a *App) formatTimestamp(t time.Time) string {
 
 // GetBindingInfo - 使用Wails自动生成的代码
 
+// RebuildLogIndexes 删除并重建日志库（logs.db）的 request_logs 索引，随后执行 ANALYZE（用于旧版本迁移的大库）
+func (a *App) RebuildLogIndexes() map[string]interface{} {
+	a.mutex.RLock()
+	dbManager := a.dbManager
+	a.mutex.RUnlock()
+
+	if dbManager == nil {
+		return map[string]interface{}{
+			"success": false,
+			"message": "数据库不可用",
+		}
+	}
+	logsDB, err := dbManager.GetLogsDB()
+	if err != nil {
+		return map[string]interface{}{
+			"success": false,
+			"message": fmt.Sprintf("日志数据库不可用: %v", err),
+		}
+	}
+
+	// 先写入排队中的日志，避免重建期间与批量写入争用
+	a.flushRequestLogs()
+	return a.rebuildRequestLogIndexes(logsDB)
+}
+
+// rebuildRequestLogIndexes 按 logger.RequestLogIndexes 逐个删除并重建索引
+func (a *App) rebuildRequestLogIndexes(db *sql.DB) map[string]interface{} {
+	definitions := logger.RequestLogIndexes()
+	start := time.Now()
+	indexes := make([]map[string]interface{}, 0, len(definitions))
+	failed := 0
+	for _, index := range definitions {
+		indexStart := time.Now()
+		entry := map[string]interface{}{"name": index.Name}
+		if _, err := db.Exec("DROP INDEX IF EXISTS " + index.Name); err != nil {
+			entry["error"] = fmt.Sprintf("删除失败: %v", err)
+		} else if _, err := db.Exec(index.SQL); err != nil {
+			entry["error"] = fmt.Sprintf("创建失败: %v", err)
+		}
+		if _, hasErr := entry["error"]; hasErr {
+			failed++
+		}
+		entry["duration_ms"] = time.Since(indexStart).Milliseconds()
+		indexes = append(indexes, entry)
+	}
+	analyzeStart := time.Now()
+	if _, err := db.Exec("ANALYZE request_logs"); err != nil {
+		return map[string]interface{}{
+			"success": false,
+			"message": fmt.Sprintf("ANALYZE 执行失败: %v", err),
+			"indexes": indexes,
+		}
+	}
+	analyzeMs := time.Since(analyzeStart).Milliseconds()
+	totalMs := time.Since(start).Milliseconds()
+
+	if failed > 0 {
+		a.addLog("warn", fmt.Sprintf("重建日志索引完成，但有 %d 个索引失败 (耗时 %dms)", failed, totalMs))
+	} else {
+		a.addLog("info", fmt.Sprintf("已重建 %d 个日志索引并完成 ANALYZE (耗时 %dms)", len(indexes), totalMs))
+	}
+
+	return map[string]interface{}{
+		"success":     failed == 0,
+		"message":     fmt.Sprintf("重建 %d 个索引（失败 %d 个），耗时 %dms", len(indexes), failed, totalMs),
+		"indexes":     indexes,
+		"analyze_ms":  analyzeMs,
+		"duration_ms": totalMs,
+	}
+}
+
 // ClearLogs 清除旧日志
 func (a *App) ClearLogs(daysToKeep interface{}) map[string]interface{} {
 	a.mutex.Lock()
@@ -4706,6 +12702,7 @@ func (a *App) ClearLogs(daysToKeep interface{}) map[string]interface{} {
 	cutoffDate := time.Now().AddDate(0, 0, -days)
 
 	// 清除内存日志
+	a.logsMu.Lock()
 	newLogs := make([]LogEntry, 0)
 	for _, log := range a.logs {
 		if logTime, err := time.Parse("2006-01-02 15:04:05", log.Timestamp); err == nil {
@@ -4715,8 +12712,10 @@ func (a *App) ClearLogs(daysToKeep interface{}) map[string]interface{} {
 		}
 	}
 	a.logs = newLogs
+	a.logsMu.Unlock()
 
-	// 清除数据库日志
+	// 清除数据库日志；先让队列中的日志落库，避免清理后再被写入
+	a.flushRequestLogs()
 	result, err := a.db.Exec(`
 		DELETE FROM request_logs
 		WHERE timestamp < ?
@@ -4739,126 +12738,481 @@ func (a *App) ClearLogs(daysToKeep interface{}) map[string]interface{} {
 	}
 }
 
-// ExportData 导出数据
-func (a *App) ExportData(format string) map[string]interface{} {
-	a.mutex.RLock()
-	defer a.mutex.RUnlock()
+// ExportData 导出数据
+func (a *App) ExportData(format string) map[string]interface{} {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	if a.db == nil {
+		return map[string]interface{}{
+			"success": false,
+			"message": "数据库不可用",
+		}
+	}
+
+	switch strings.ToLower(format) {
+	case "json":
+		return a.exportToJSON()
+	case "csv":
+		return a.exportToCSV()
+	default:
+		return map[string]interface{}{
+			"success": false,
+			"message": "不支持的导出格式: " + format + " (支持: json, csv)",
+		}
+	}
+}
+
+// exportToJSON 导出为JSON格式
+func (a *App) exportToJSON() map[string]interface{} {
+	// 导出端点数据
+	endpoints := a.GetEndpoints()
+	endpointData, ok := endpoints["data"]
+	if !ok {
+		endpointData = []interface{}{}
+	}
+
+	// 导出日志数据（最近1000条）
+	logs := a.GetLogs(map[string]interface{}{
+		"page":  "1",
+		"limit": "1000",
+	})
+
+	exportData := map[string]interface{}{
+		"version":     "1.0",
+		"export_time": time.Now().Format("2006-01-02 15:04:05"),
+		"endpoints":   endpointData,
+		"logs":        logs["logs"],
+	}
+
+	jsonData, err := json.MarshalIndent(exportData, "", "  ")
+	if err != nil {
+		return map[string]interface{}{
+			"success": false,
+			"message": fmt.Sprintf("JSON序列化失败: %v", err),
+		}
+	}
+
+	return map[string]interface{}{
+		"success": true,
+		"message": "数据导出成功 (JSON格式)",
+		"data":    string(jsonData),
+		"format":  "json",
+	}
+}
+
+// exportToCSV 导出为CSV格式
+func (a *App) exportToCSV() map[string]interface{} {
+	var csvData strings.Builder
+
+	// CSV写入器
+	writer := csv.NewWriter(&csvData)
+
+	// 写入端点数据
+	writer.Write([]string{"Type", "ID", "Name", "Anthropic URL", "OpenAI URL", "Auth Type", "Enabled", "Priority", "Status", "Notes"})
+	endpoints := a.GetEndpoints()
+	if endpointList, ok := endpoints["data"].([]interface{}); ok {
+		for _, ep := range endpointList {
+			if epMap, ok := ep.(map[string]interface{}); ok {
+				writer.Write([]string{
+					"endpoint",
+					getStringValue(epMap["id"]),
+					getStringValue(epMap["name"]),
+					getStringValue(epMap["url_anthropic"]),
+					getStringValue(epMap["url_openai"]),
+					getStringValue(epMap["auth_type"]),
+					fmt.Sprintf("%v", epMap["enabled"]),
+					fmt.Sprintf("%v", epMap["priority"]),
+					getStringValue(epMap["status"]),
+					getStringValue(epMap["notes"]),
+				})
+			}
+		}
+	}
+
+	// 写入日志数据
+	writer.Write([]string{"Type", "Timestamp", "Level", "Message", "Endpoint ID", "Response Time"})
+	logs := a.GetLogs(map[string]interface{}{
+		"page":  "1",
+		"limit": "1000",
+	})
+	if logList, ok := logs["logs"].([]interface{}); ok {
+		for _, log := range logList {
+			if logMap, ok := log.(map[string]interface{}); ok {
+				writer.Write([]string{
+					"log",
+					getStringValue(logMap["timestamp"]),
+					getStringValue(logMap["level"]),
+					getStringValue(logMap["message"]),
+					getStringValue(logMap["endpointId"]),
+					fmt.Sprintf("%v", logMap["responseTime"]),
+				})
+			}
+		}
+	}
+
+	writer.Flush()
+
+	return map[string]interface{}{
+		"success": true,
+		"message": "数据导出成功 (CSV格式)",
+		"data":    csvData.String(),
+		"format":  "csv",
+	}
+}
+
+// logExportCSVHeader 日志导出CSV列
+var logExportCSVHeader = []string{
+	"timestamp", "request_id", "endpoint", "method", "path", "status_code", "duration_ms",
+	"attempt_number", "client_type", "request_format", "model", "original_model", "rewritten_model",
+	"is_streaming", "conversion_path", "error", "request_body_size", "response_body_size",
+}
+
+// parseExportTime 解析导出时间参数，支持 RFC3339、"2006-01-02 15:04:05" 和 "2006-01-02"
+func parseExportTime(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("无法解析时间: %s", value)
+}
+
+// ExportLogs 按时间范围和过滤条件导出请求日志（CSV / JSONL）
+// params: format, start_time, end_time, status_range, client_type, endpoint, output_path(可选，写入文件；未指定时内容随结果返回，大小受限)
+func (a *App) ExportLogs(params map[string]interface{}) map[string]interface{} {
+	a.mutex.RLock()
+	requestLogger := a.requestLogger
+	a.mutex.RUnlock()
+
+	if requestLogger == nil {
+		return map[string]interface{}{
+			"success": false,
+			"message": "日志记录器未初始化",
+		}
+	}
+
+	format := strings.ToLower(getStringFromMap(params, "format"))
+	if format == "" {
+		format = "csv"
+	}
+	if format != "csv" && format != "jsonl" {
+		return map[string]interface{}{
+			"success": false,
+			"message": "不支持的导出格式: " + format + " (支持: csv, jsonl)",
+		}
+	}
+
+	since, err := parseExportTime(getStringFromMap(params, "start_time"))
+	if err != nil {
+		return map[string]interface{}{"success": false, "message": err.Error()}
+	}
+	until, err := parseExportTime(getStringFromMap(params, "end_time"))
+	if err != nil {
+		return map[string]interface{}{"success": false, "message": err.Error()}
+	}
+	if !since.IsZero() && !until.IsZero() && until.Before(since) {
+		return map[string]interface{}{"success": false, "message": "结束时间不能早于开始时间"}
+	}
+
+	filter := logger.LogExportFilter{
+		Since:       since,
+		Until:       until,
+		StatusRange: getStringFromMap(params, "status_range"),
+		ClientType:  getStringFromMap(params, "client_type"),
+		Endpoint:    getStringFromMap(params, "endpoint"),
+	}
+
+	var out io.Writer
+	buffer := &cappedExportBuffer{limit: maxInMemoryLogExportBytes}
+	outputPath := strings.TrimSpace(getStringFromMap(params, "output_path"))
+	if outputPath != "" {
+		file, err := os.Create(outputPath)
+		if err != nil {
+			return map[string]interface{}{
+				"success": false,
+				"message": fmt.Sprintf("创建导出文件失败: %v", err),
+			}
+		}
+		defer file.Close()
+		out = file
+	} else {
+		out = buffer
+	}
+
+	a.flushRequestLogs()
+	count, err := writeLogExport(out, format, func(fn func(*logger.RequestLog) error) error {
+		return requestLogger.StreamLogs(filter, fn)
+	})
+	if errors.Is(err, errLogExportTooLarge) {
+		return map[string]interface{}{
+			"success": false,
+			"message": fmt.Sprintf("导出内容超过 %d MB，请缩小时间范围或指定 output_path 写入文件", maxInMemoryLogExportBytes>>20),
+		}
+	}
+	if err != nil {
+		return map[string]interface{}{
+			"success": false,
+			"message": fmt.Sprintf("导出日志失败: %v", err),
+		}
+	}
+
+	a.addLog("info", fmt.Sprintf("导出请求日志 %d 条 (%s)", count, format))
+	result := map[string]interface{}{
+		"success": true,
+		"message": fmt.Sprintf("已导出 %d 条日志 (%s格式)", count, strings.ToUpper(format)),
+		"format":  format,
+		"count":   count,
+	}
+	if outputPath != "" {
+		result["path"] = outputPath
+	} else {
+		result["data"] = buffer.String()
+	}
+	return result
+}
+
+// maxInMemoryLogExportBytes 未指定 output_path 时导出内容随结果返回，超过该大小需写入文件
+const maxInMemoryLogExportBytes = 16 << 20
+
+// errLogExportTooLarge 内存导出超过 maxInMemoryLogExportBytes
+var errLogExportTooLarge = errors.New("log export exceeds the in-memory limit")
+
+// cappedExportBuffer 内存导出缓冲，累计写入超过 limit 字节时返回 errLogExportTooLarge 终止导出
+type cappedExportBuffer struct {
+	strings.Builder
+	limit int
+}
+
+func (b *cappedExportBuffer) Write(p []byte) (int, error) {
+	if b.Len()+len(p) > b.limit {
+		return 0, errLogExportTooLarge
+	}
+	return b.Builder.Write(p)
+}
+
+// writeLogExport 将流式读取的日志逐行写出，返回写出的条数
+func writeLogExport(out io.Writer, format string, stream func(func(*logger.RequestLog) error) error) (int, error) {
+	count := 0
+	if format == "jsonl" {
+		encoder := json.NewEncoder(out)
+		err := stream(func(log *logger.RequestLog) error {
+			count++
+			return encoder.Encode(log)
+		})
+		return count, err
+	}
+
+	writer := csv.NewWriter(out)
+	if err := writer.Write(logExportCSVHeader); err != nil {
+		return 0, err
+	}
+	err := stream(func(log *logger.RequestLog) error {
+		count++
+		return writer.Write([]string{
+			log.Timestamp.Format(time.RFC3339),
+			log.RequestID,
+			log.Endpoint,
+			log.Method,
+			log.Path,
+			strconv.Itoa(log.StatusCode),
+			strconv.FormatInt(log.DurationMs, 10),
+			strconv.Itoa(log.AttemptNumber),
+			log.ClientType,
+			log.RequestFormat,
+			log.Model,
+			log.OriginalModel,
+			log.RewrittenModel,
+			strconv.FormatBool(log.IsStreaming),
+			log.ConversionPath,
+			log.Error,
+			strconv.Itoa(log.RequestBodySize),
+			strconv.Itoa(log.ResponseBodySize),
+		})
+	})
+	writer.Flush()
+	if err == nil {
+		err = writer.Error()
+	}
+	return count, err
+}
+
+// diagnosticFailedLogLimit 诊断包中包含的最近失败请求数量
+const diagnosticFailedLogLimit = 50
 
-	if a.db == nil {
-		return map[string]interface{}{
-			"success": false,
-			"message": "数据库不可用",
-		}
-	}
+// diagnosticSecretKeyPattern 匹配需要脱敏的配置/头部字段名
+var diagnosticSecretKeyPattern = regexp.MustCompile(`(?i)(token|secret|password|passwd|api[_-]?key|auth_value|authorization|cookie|credential)`)
 
-	switch strings.ToLower(format) {
-	case "json":
-		return a.exportToJSON()
-	case "csv":
-		return a.exportToCSV()
-	default:
-		return map[string]interface{}{
-			"success": false,
-			"message": "不支持的导出格式: " + format + " (支持: json, csv)",
-		}
+// ExportDiagnostics 导出诊断包（JSON），包含脱敏配置、端点状态、最近失败请求、版本信息与数据库文件大小，便于提交问题报告
+func (a *App) ExportDiagnostics() map[string]interface{} {
+	a.mutex.RLock()
+	configCopy := sanitizeDiagnosticValue("", a.config)
+	configError := a.configError
+	dbManager := a.dbManager
+	a.mutex.RUnlock()
+
+	bundle := map[string]interface{}{
+		"generated_at": time.Now().Format(time.RFC3339),
+		"version": map[string]interface{}{
+			"app":        a.GetVersionInfo(),
+			"go_version": goruntime.Version(),
+			"os":         goruntime.GOOS,
+			"arch":       goruntime.GOARCH,
+		},
+		"server_status": a.GetServerStatus(),
+		"config":        configCopy,
+	}
+	if configError != "" {
+		bundle["config_error"] = configError
 	}
-}
 
-// exportToJSON 导出为JSON格式
-func (a *App) exportToJSON() map[string]interface{} {
-	// 导出端点数据
 	endpoints := a.GetEndpoints()
-	endpointData, ok := endpoints["data"]
-	if !ok {
-		endpointData = []interface{}{}
+	if data, ok := endpoints["data"]; ok {
+		bundle["endpoints"] = sanitizeDiagnosticValue("", data)
+	} else {
+		bundle["endpoints_error"] = endpoints["message"]
 	}
 
-	// 导出日志数据（最近1000条）
 	logs := a.GetLogs(map[string]interface{}{
-		"page":  "1",
-		"limit": "1000",
+		"page":        "1",
+		"limit":       strconv.Itoa(diagnosticFailedLogLimit),
+		"failed_only": true,
 	})
-
-	exportData := map[string]interface{}{
-		"version":     "1.0",
-		"export_time": time.Now().Format("2006-01-02 15:04:05"),
-		"endpoints":   endpointData,
-		"logs":        logs["logs"],
+	failed := []map[string]interface{}{}
+	if entries, ok := logs["logs"].([]map[string]interface{}); ok {
+		for _, entry := range entries {
+			failed = append(failed, sanitizeDiagnosticLog(entry))
+		}
+	} else if logErr, ok := logs["error"]; ok {
+		bundle["failed_requests_error"] = logErr
+	}
+	bundle["failed_requests"] = failed
+
+	databases := map[string]interface{}{}
+	if dbManager != nil {
+		for name, path := range map[string]string{
+			"main":       dbManager.GetMainDBPath(),
+			"logs":       dbManager.GetLogsDBPath(),
+			"statistics": dbManager.GetStatisticsDBPath(),
+		} {
+			info := map[string]interface{}{"path": path}
+			if stat, err := os.Stat(path); err == nil {
+				info["size_bytes"] = stat.Size()
+			} else {
+				info["error"] = err.Error()
+			}
+			databases[name] = info
+		}
 	}
+	bundle["databases"] = databases
 
-	jsonData, err := json.MarshalIndent(exportData, "", "  ")
+	jsonData, err := json.MarshalIndent(bundle, "", "  ")
 	if err != nil {
 		return map[string]interface{}{
 			"success": false,
-			"message": fmt.Sprintf("JSON序列化失败: %v", err),
+			"message": fmt.Sprintf("诊断包序列化失败: %v", err),
 		}
 	}
 
+	a.addLog("info", fmt.Sprintf("已生成诊断包（%d 条失败请求）", len(failed)))
 	return map[string]interface{}{
-		"success": true,
-		"message": "数据导出成功 (JSON格式)",
-		"data":    string(jsonData),
-		"format":  "json",
+		"success":  true,
+		"message":  "诊断包导出成功",
+		"data":     string(jsonData),
+		"format":   "json",
+		"filename": fmt.Sprintf("cccc-diagnostics-%s.json", time.Now().Format("20060102-150405")),
 	}
 }
 
-// exportToCSV 导出为CSV格式
-func (a *App) exportToCSV() map[string]interface{} {
-	var csvData strings.Builder
-
-	// CSV写入器
-	writer := csv.NewWriter(&csvData)
-
-	// 写入端点数据
-	writer.Write([]string{"Type", "ID", "Name", "Anthropic URL", "OpenAI URL", "Auth Type", "Enabled", "Priority", "Status"})
-	endpoints := a.GetEndpoints()
-	if endpointList, ok := endpoints["data"].([]interface{}); ok {
-		for _, ep := range endpointList {
-			if epMap, ok := ep.(map[string]interface{}); ok {
-				writer.Write([]string{
-					"endpoint",
-					getStringValue(epMap["id"]),
-					getStringValue(epMap["name"]),
-					getStringValue(epMap["url_anthropic"]),
-					getStringValue(epMap["url_openai"]),
-					getStringValue(epMap["auth_type"]),
-					fmt.Sprintf("%v", epMap["enabled"]),
-					fmt.Sprintf("%v", epMap["priority"]),
-					getStringValue(epMap["status"]),
-				})
-			}
+// sanitizeDiagnosticValue 深拷贝配置值，字段名匹配敏感模式的字符串值使用 maskToken 脱敏
+func sanitizeDiagnosticValue(key string, value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			copied[k] = sanitizeDiagnosticValue(k, item)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(v))
+		for i, item := range v {
+			copied[i] = sanitizeDiagnosticValue(key, item)
+		}
+		return copied
+	case []map[string]interface{}:
+		copied := make([]interface{}, len(v))
+		for i, item := range v {
+			copied[i] = sanitizeDiagnosticValue(key, item)
+		}
+		return copied
+	case map[string]string:
+		return sanitizeDiagnosticHeaders(v)
+	case *config.OAuthConfig:
+		// GetEndpoints 返回的 oauth_config 为结构体指针，转为 map 后按字段名脱敏 access_token / refresh_token
+		if v == nil {
+			return nil
+		}
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return nil
+		}
+		var fields map[string]interface{}
+		if err := json.Unmarshal(encoded, &fields); err != nil {
+			return nil
+		}
+		return sanitizeDiagnosticValue(key, fields)
+	case string:
+		if key != "" && diagnosticSecretKeyPattern.MatchString(key) {
+			return maskToken(v)
 		}
+		return v
+	default:
+		return v
 	}
+}
 
-	// 写入日志数据
-	writer.Write([]string{"Type", "Timestamp", "Level", "Message", "Endpoint ID", "Response Time"})
-	logs := a.GetLogs(map[string]interface{}{
-		"page":  "1",
-		"limit": "1000",
-	})
-	if logList, ok := logs["logs"].([]interface{}); ok {
-		for _, log := range logList {
-			if logMap, ok := log.(map[string]interface{}); ok {
-				writer.Write([]string{
-					"log",
-					getStringValue(logMap["timestamp"]),
-					getStringValue(logMap["level"]),
-					getStringValue(logMap["message"]),
-					getStringValue(logMap["endpointId"]),
-					fmt.Sprintf("%v", logMap["responseTime"]),
-				})
-			}
+// sanitizeDiagnosticHeaders 复用 maskHeaderValue，并对其它敏感头部做兜底脱敏
+func sanitizeDiagnosticHeaders(headers map[string]string) map[string]string {
+	masked := make(map[string]string, len(headers))
+	for key, value := range headers {
+		maskedValue := maskHeaderValue(key, value)
+		if maskedValue == value && diagnosticSecretKeyPattern.MatchString(key) {
+			maskedValue = maskToken(value)
 		}
+		masked[key] = maskedValue
 	}
+	return masked
+}
 
-	writer.Flush()
-
-	return map[string]interface{}{
-		"success": true,
-		"message": "数据导出成功 (CSV格式)",
-		"data":    csvData.String(),
-		"format":  "csv",
+// sanitizeDiagnosticLog 脱敏请求日志：头部按 sanitizeDiagnosticHeaders 处理，请求/响应体只保留长度
+func sanitizeDiagnosticLog(entry map[string]interface{}) map[string]interface{} {
+	sanitized := make(map[string]interface{}, len(entry))
+	for key, value := range entry {
+		switch v := value.(type) {
+		case map[string]string:
+			sanitized[key] = sanitizeDiagnosticHeaders(v)
+		case string:
+			if strings.HasSuffix(key, "_body") {
+				if v != "" {
+					sanitized[key] = fmt.Sprintf("[masked %d bytes]", len(v))
+				} else {
+					sanitized[key] = ""
+				}
+				continue
+			}
+			sanitized[key] = v
+		default:
+			sanitized[key] = value
+		}
 	}
+	return sanitized
 }
 
 // ImportData 导入数据
@@ -4923,6 +13277,331 @@ func (a *App) ImportData(data string) map[string]interface{} {
 	}
 }
 
+// endpointExportRuntimeFields 端点运行时状态，不属于端点定义，导出时去除
+var endpointExportRuntimeFields = []string{
+	"id", "status", "response_time", "last_check", "created_at", "updated_at",
+	"last_error", "last_error_at", "auto_disabled",
+}
+
+// endpointExportSecretFields 导出时可选去除的凭据字段
+var endpointExportSecretFields = []string{"auth_value", "hmac_secret"}
+
+// isSecretReference 判断值是否为 env:NAME / ${NAME} 形式的环境变量引用（不含密钥本身，可安全导出）
+func isSecretReference(value string) bool {
+	value = strings.TrimSpace(value)
+	return strings.HasPrefix(value, "env:") || (strings.HasPrefix(value, "${") && strings.HasSuffix(value, "}"))
+}
+
+// buildEndpointExport 将 GetEndpoints 返回的端点转换为可移植的端点定义：去除运行时状态；
+// includeSecrets 为 false 时去除凭据与 oauth_config（环境变量引用保留），并附带脱敏值供对照
+func buildEndpointExport(endpoints []interface{}, includeSecrets bool) []map[string]interface{} {
+	exported := make([]map[string]interface{}, 0, len(endpoints))
+	for _, raw := range endpoints {
+		ep, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		definition := make(map[string]interface{}, len(ep))
+		for key, value := range ep {
+			definition[key] = value
+		}
+		for _, key := range endpointExportRuntimeFields {
+			delete(definition, key)
+		}
+		if !includeSecrets {
+			for _, key := range endpointExportSecretFields {
+				value, _ := definition[key].(string)
+				if value == "" || isSecretReference(value) {
+					continue
+				}
+				delete(definition, key)
+				definition[key+"_masked"] = maskToken(value)
+			}
+			delete(definition, "oauth_config")
+		}
+		exported = append(exported, definition)
+	}
+	return exported
+}
+
+// ExportEndpoints 仅导出端点定义（URL、认证、标签、重写规则、覆盖参数、备注等），不包含日志，用于分享配置；
+// includeSecrets 为 false 时不导出 auth_value / hmac_secret / oauth_config
+func (a *App) ExportEndpoints(includeSecrets bool) map[string]interface{} {
+	endpoints := a.GetEndpoints()
+	if success, _ := endpoints["success"].(bool); !success {
+		return map[string]interface{}{
+			"success": false,
+			"message": fmt.Sprintf("读取端点失败: %v", endpoints["error"]),
+		}
+	}
+	list, _ := endpoints["data"].([]interface{})
+	definitions := buildEndpointExport(list, includeSecrets)
+
+	jsonData, err := json.MarshalIndent(map[string]interface{}{
+		"version":          "1.0",
+		"kind":             "endpoints",
+		"export_time":      time.Now().Format("2006-01-02 15:04:05"),
+		"secrets_included": includeSecrets,
+		"endpoints":        definitions,
+	}, "", "  ")
+	if err != nil {
+		return map[string]interface{}{
+			"success": false,
+			"message": fmt.Sprintf("JSON序列化失败: %v", err),
+		}
+	}
+
+	a.addLog("info", fmt.Sprintf("已导出 %d 个端点定义（包含凭据: %t）", len(definitions), includeSecrets))
+	return map[string]interface{}{
+		"success":          true,
+		"message":          fmt.Sprintf("已导出 %d 个端点", len(definitions)),
+		"data":             string(jsonData),
+		"format":           "json",
+		"count":            len(definitions),
+		"secrets_included": includeSecrets,
+		"filename":         fmt.Sprintf("cccc-endpoints-%s.json", time.Now().Format("20060102-150405")),
+	}
+}
+
+// parseEndpointImport 解析端点导入数据：支持 ExportEndpoints / ExportData 导出的对象，或直接的端点数组；
+// 去除运行时状态与 *_masked 对照字段，缺少名称的条目视为无效
+func parseEndpointImport(data string) ([]map[string]interface{}, error) {
+	if strings.TrimSpace(data) == "" {
+		return nil, fmt.Errorf("导入数据不能为空")
+	}
+
+	var rawList []interface{}
+	var document interface{}
+	if err := json.Unmarshal([]byte(data), &document); err != nil {
+		return nil, fmt.Errorf("数据格式错误，不是有效的JSON: %v", err)
+	}
+	switch v := document.(type) {
+	case []interface{}:
+		rawList = v
+	case map[string]interface{}:
+		list, ok := v["endpoints"].([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("导入数据中没有 endpoints 数组")
+		}
+		rawList = list
+	default:
+		return nil, fmt.Errorf("导入数据必须是对象或数组")
+	}
+
+	definitions := make([]map[string]interface{}, 0, len(rawList))
+	for i, raw := range rawList {
+		ep, ok := raw.(map[string]interface{})
+		if !ok || strings.TrimSpace(getStringFromMap(ep, "name")) == "" {
+			return nil, fmt.Errorf("第 %d 个端点无效：缺少名称", i+1)
+		}
+		for _, key := range endpointExportRuntimeFields {
+			delete(ep, key)
+		}
+		for key := range ep {
+			if strings.HasSuffix(key, "_masked") {
+				delete(ep, key)
+			}
+		}
+		definitions = append(definitions, ep)
+	}
+	return definitions, nil
+}
+
+// ImportEndpoints 导入端点定义。mode：
+// merge（默认）按名称更新已有端点、创建新端点，导入数据未包含的凭据保持不变；
+// append 全部作为新端点创建；replace 先删除所有现有端点再创建（删除与创建在同一事务中）。
+// 任一定义无效或新建端点缺少 auth_type 所需的凭据时整体拒绝导入
+func (a *App) ImportEndpoints(data string, mode string) map[string]interface{} {
+	mode = strings.ToLower(strings.TrimSpace(mode))
+	if mode == "" {
+		mode = "merge"
+	}
+	if mode != "merge" && mode != "append" && mode != "replace" {
+		return map[string]interface{}{
+			"success": false,
+			"message": "不支持的导入模式: " + mode + " (支持: merge, append, replace)",
+		}
+	}
+
+	definitions, err := parseEndpointImport(data)
+	if err != nil {
+		return map[string]interface{}{
+			"success": false,
+			"message": err.Error(),
+		}
+	}
+
+	a.mutex.RLock()
+	db := a.db
+	a.mutex.RUnlock()
+	if db == nil {
+		return map[string]interface{}{
+			"success": false,
+			"message": "数据库不可用",
+		}
+	}
+
+	existing := map[string]string{} // 端点名称 -> ID
+	rows, err := db.Query("SELECT id, name FROM endpoints")
+	if err != nil {
+		return map[string]interface{}{
+			"success": false,
+			"message": fmt.Sprintf("查询端点失败: %v", err),
+		}
+	}
+	for rows.Next() {
+		var id, name sql.NullString
+		if err := rows.Scan(&id, &name); err == nil {
+			existing[name.String] = id.String
+		}
+	}
+	rows.Close()
+
+	// 写入前先校验全部定义：任何一个无效或缺少凭据都不修改现有端点
+	type endpointUpdate struct {
+		id         string
+		definition map[string]interface{}
+	}
+	updates := []endpointUpdate{} // merge 模式下按名称匹配到的已有端点，未提供的凭据保持不变
+	inserts := []*endpointInsert{}
+	failures := []string{}
+	for _, definition := range definitions {
+		name := strings.TrimSpace(getStringFromMap(definition, "name"))
+		id, found := existing[name]
+		update := found && mode == "merge"
+		if missing := missingEndpointCredential(definition); missing != "" && !update {
+			failures = append(failures, fmt.Sprintf("%s: 缺少 %s（导出时未包含密钥，请补全后导入，或使用 merge 模式更新已有端点）", name, missing))
+			continue
+		}
+		insert, message := a.prepareEndpointInsert(definition)
+		if insert == nil {
+			failures = append(failures, fmt.Sprintf("%s: %s", name, message))
+			continue
+		}
+		if update {
+			updates = append(updates, endpointUpdate{id: id, definition: definition})
+		} else {
+			inserts = append(inserts, insert)
+		}
+	}
+	if len(failures) > 0 {
+		return map[string]interface{}{
+			"success":  false,
+			"message":  fmt.Sprintf("端点导入已取消：%d 个端点定义无效，现有端点未做任何修改", len(failures)),
+			"mode":     mode,
+			"failures": failures,
+		}
+	}
+
+	// replace 的删除与所有新建在同一事务中完成，失败时整体回滚
+	deletedIDs, err := a.applyEndpointImport(db, mode == "replace", inserts)
+	if err != nil {
+		return map[string]interface{}{
+			"success": false,
+			"message": fmt.Sprintf("端点导入失败，已回滚: %v", err),
+			"mode":    mode,
+		}
+	}
+	if len(deletedIDs) > 0 {
+		for _, id := range deletedIDs {
+			if _, err := db.Exec("DELETE FROM endpoint_health_history WHERE endpoint_id = ?", id); err != nil {
+				a.addLog("warn", fmt.Sprintf("删除端点 %s 的健康历史失败: %v", id, err))
+			}
+			a.runtimeEndpoints.Delete(id)
+		}
+		a.invalidateUpstreamClients()
+	}
+
+	updated := 0
+	for _, update := range updates {
+		if result := a.UpdateEndpoint(update.id, update.definition); result["success"] == true {
+			updated++
+		} else {
+			failures = append(failures, fmt.Sprintf("%s: %v", getStringFromMap(update.definition, "name"), result["message"]))
+		}
+	}
+
+	a.mutex.RLock()
+	warmUp := a.isWarmUpOnEnableEnabledNoLock()
+	a.mutex.RUnlock()
+	for _, insert := range inserts {
+		if insert.enabled && warmUp {
+			a.startWarmUp(insert.id)
+		}
+	}
+
+	created, deleted := len(inserts), len(deletedIDs)
+	a.addLog("info", fmt.Sprintf("端点导入完成（%s）：新建 %d，更新 %d，删除 %d，失败 %d", mode, created, updated, deleted, len(failures)))
+	return map[string]interface{}{
+		"success":  len(failures) == 0,
+		"message":  fmt.Sprintf("端点导入完成：新建 %d 个，更新 %d 个，失败 %d 个", created, updated, len(failures)),
+		"mode":     mode,
+		"created":  created,
+		"updated":  updated,
+		"deleted":  deleted,
+		"failures": failures,
+	}
+}
+
+// applyEndpointImport 在一个事务中（replace 时先删除全部现有端点）写入新端点，返回被删除的端点ID
+func (a *App) applyEndpointImport(db *sql.DB, replace bool, inserts []*endpointInsert) ([]string, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var deletedIDs []string
+	if replace {
+		rows, err := tx.Query("SELECT id FROM endpoints")
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var id string
+			if err := rows.Scan(&id); err == nil {
+				deletedIDs = append(deletedIDs, id)
+			}
+		}
+		rows.Close()
+		if _, err := tx.Exec("DELETE FROM endpoints"); err != nil {
+			return nil, fmt.Errorf("删除现有端点失败: %w", err)
+		}
+	}
+	for _, insert := range inserts {
+		if _, err := tx.Exec(endpointInsertSQL, insert.args...); err != nil {
+			return nil, fmt.Errorf("创建端点 %s 失败: %w", insert.name, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return deletedIDs, nil
+}
+
+// missingEndpointCredential 返回端点定义按 auth_type / hmac_header 所需但缺失的凭据字段，齐全时返回空串；
+// auto 可沿用客户端凭据，不要求 auth_value
+func missingEndpointCredential(definition map[string]interface{}) string {
+	authValue := strings.TrimSpace(getStringFromMap(definition, "auth_value"))
+	switch strings.ToLower(strings.TrimSpace(getStringFromMap(definition, "auth_type"))) {
+	case "api_key", "auth_token":
+		if authValue == "" {
+			return "auth_value"
+		}
+	case "oauth":
+		if authValue == "" && definition["oauth_config"] == nil {
+			return "oauth_config"
+		}
+	}
+	if strings.TrimSpace(getStringFromMap(definition, "hmac_header")) != "" && strings.TrimSpace(getStringFromMap(definition, "hmac_secret")) == "" {
+		return "hmac_secret"
+	}
+	return ""
+}
+
 // 辅助函数：获取字符串值
 func getStringValue(v interface{}) string {
 	if v == nil {
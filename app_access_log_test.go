@@ -0,0 +1,48 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAccessLogWrittenPerRequest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+	app := &App{config: map[string]interface{}{
+		// 健康闸门未打开时请求会直接返回 503，足以验证访问日志
+		"server": map[string]interface{}{"min_healthy_endpoints": float64(1)},
+		"logging": map[string]interface{}{
+			"access_log": map[string]interface{}{"format": "combined", "destination": path},
+		},
+	}}
+	defer func() {
+		if app.accessLogger != nil {
+			app.accessLogger.Close()
+		}
+	}()
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/messages", strings.NewReader(`{}`))
+	req.Header.Set("User-Agent", "claude-cli/1.0")
+	rec := httptest.NewRecorder()
+	app.handleProxyRequest(rec, req)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 from the closed health gate, got %d", rec.Code)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read access log: %v", err)
+	}
+	line := strings.TrimSpace(string(data))
+	if strings.Count(line, "\n") != 0 || !strings.Contains(line, `"POST /v1/messages HTTP/1.1" 503`) || !strings.Contains(line, `"claude-cli/1.0"`) {
+		t.Fatalf("unexpected access log line: %q", line)
+	}
+
+	app.config["logging"] = map[string]interface{}{}
+	if app.getAccessLogger() != nil {
+		t.Fatal("expected access log to be disabled after removing the config")
+	}
+}
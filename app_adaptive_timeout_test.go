@@ -0,0 +1,123 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAdaptiveTimeoutFromP95(t *testing.T) {
+	app := &App{}
+	for i := 1; i <= 20; i++ {
+		app.recordEndpointLatency("primary", false, time.Duration(i)*100*time.Millisecond)
+	}
+	if got := app.adaptiveTimeoutFor("primary", false); got != 0 {
+		t.Fatalf("expected adaptive timeout to be off by default, got %v", got)
+	}
+
+	app.config = map[string]interface{}{
+		"server": map[string]interface{}{
+			"adaptive_timeout_multiplier": 2.0,
+			"adaptive_timeout_min_ms":     1000,
+			"adaptive_timeout_max_ms":     10000,
+		},
+	}
+	// P95 = 1900ms，×2 = 3800ms
+	if got := app.adaptiveTimeoutFor("primary", false); got != 3800*time.Millisecond {
+		t.Fatalf("expected 3.8s adaptive timeout, got %v", got)
+	}
+	if timeout, p95 := app.endpointAdaptiveTimeout("primary", false); timeout != 3800*time.Millisecond || p95 != 1900*time.Millisecond {
+		t.Fatalf("expected computed timeout to be stored, got %v (p95 %v)", timeout, p95)
+	}
+
+	app.config["server"].(map[string]interface{})["adaptive_timeout_max_ms"] = 2500
+	if got := app.adaptiveTimeoutFor("primary", false); got != 2500*time.Millisecond {
+		t.Fatalf("expected timeout capped at max, got %v", got)
+	}
+
+	for i := 0; i < adaptiveTimeoutMinSamples-1; i++ {
+		app.recordEndpointLatency("fresh", false, 10*time.Millisecond)
+	}
+	if got := app.adaptiveTimeoutFor("fresh", false); got != 0 {
+		t.Fatalf("expected no adaptive timeout with too few samples, got %v", got)
+	}
+	app.recordEndpointLatency("fresh", false, 10*time.Millisecond)
+	if got := app.adaptiveTimeoutFor("fresh", false); got != time.Second {
+		t.Fatalf("expected timeout raised to min, got %v", got)
+	}
+
+	// 流式请求单独统计，不受非流式样本影响
+	if got := app.adaptiveTimeoutFor("primary", true); got != 0 {
+		t.Fatalf("expected no stream timeout without stream samples, got %v", got)
+	}
+	for i := 0; i < adaptiveTimeoutMinSamples; i++ {
+		app.recordEndpointLatency("primary", true, 200*time.Millisecond)
+	}
+	if got := app.adaptiveTimeoutFor("primary", true); got != time.Second {
+		t.Fatalf("expected stream timeout from stream samples only, got %v", got)
+	}
+}
+
+func TestAdaptiveTimeoutGrowsAfterTimeouts(t *testing.T) {
+	app := &App{config: map[string]interface{}{
+		"server": map[string]interface{}{
+			"adaptive_timeout_multiplier": 2.0,
+			"adaptive_timeout_min_ms":     100,
+			"adaptive_timeout_max_ms":     60000,
+		},
+	}}
+	for i := 0; i < adaptiveTimeoutMinSamples; i++ {
+		app.recordEndpointLatency("slow", false, 100*time.Millisecond)
+	}
+	timeout := app.adaptiveTimeoutFor("slow", false)
+	if timeout != 200*time.Millisecond {
+		t.Fatalf("expected 200ms timeout, got %v", timeout)
+	}
+	// 端点变慢后每次尝试都超时：超时值计入样本，超时随之上调而不是卡在旧值
+	for i := 0; i < adaptiveTimeoutSampleSize; i++ {
+		app.recordEndpointLatency("slow", false, timeout)
+		timeout = app.adaptiveTimeoutFor("slow", false)
+	}
+	if timeout <= 200*time.Millisecond {
+		t.Fatalf("expected timeout to grow after repeated timeouts, got %v", timeout)
+	}
+}
+
+func TestDoWithHeaderTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			select {
+			case <-release:
+			case <-r.Context().Done():
+			}
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		// 响应头到达后继续输出，不受等待响应头的超时限制
+		time.Sleep(80 * time.Millisecond)
+		io.WriteString(w, "done")
+	}))
+	defer server.Close()
+	defer close(release)
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/slow", nil)
+	if _, err := doWithHeaderTimeout(server.Client(), req, 50*time.Millisecond); !errors.Is(err, errAdaptiveTimeout) {
+		t.Fatalf("expected adaptive timeout error, got %v", err)
+	}
+
+	req, _ = http.NewRequest(http.MethodGet, server.URL+"/stream", nil)
+	resp, err := doWithHeaderTimeout(server.Client(), req, 50*time.Millisecond)
+	if err != nil {
+		t.Fatalf("expected headers within timeout, got %v", err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil || string(body) != "done" {
+		t.Fatalf("expected full body after headers, got %q (%v)", body, err)
+	}
+}
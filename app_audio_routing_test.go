@@ -0,0 +1,44 @@
+package main
+
+import (
+	"testing"
+
+	"claude-code-codex-companion/internal/config"
+)
+
+func TestFilterAudioEndpoints(t *testing.T) {
+	endpoints := []config.EndpointConfig{
+		{Name: "text-only", URLOpenAI: "https://a.example.com/v1"},
+		{Name: "anthropic-audio", URLAnthropic: "https://b.example.com", SupportsAudio: true},
+		{Name: "openai-audio", URLOpenAI: "https://c.example.com/v1", SupportsAudio: true},
+	}
+
+	capable, skipped := filterAudioEndpoints(endpoints)
+	if len(capable) != 1 || capable[0].Name != "openai-audio" {
+		t.Fatalf("expected only openai-audio to remain, got %+v", capable)
+	}
+	if len(skipped) != 2 || skipped[0] != "text-only" || skipped[1] != "anthropic-audio" {
+		t.Fatalf("expected skipped endpoints in original order, got %v", skipped)
+	}
+}
+
+func TestSupportsAudioColumnLoadedIntoEndpointConfig(t *testing.T) {
+	db := newEndpointTestDB(t)
+	app := &App{db: db}
+	if _, err := db.Exec(`INSERT INTO endpoints (id, name, url_openai, enabled, priority, supports_audio) VALUES
+		('1', 'audio', 'https://a.example.com/v1', 1, 1, 1), ('2', 'legacy', 'https://b.example.com/v1', 1, 2, NULL)`); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	endpoints, err := app.queryEndpointConfigs("", false)
+	if err != nil {
+		t.Fatalf("queryEndpointConfigs: %v", err)
+	}
+	supports := map[string]bool{}
+	for _, endpoint := range endpoints {
+		supports[endpoint.Name] = endpoint.SupportsAudio
+	}
+	if len(supports) != 2 || !supports["audio"] || supports["legacy"] {
+		t.Fatalf("unexpected supports_audio values: %v", supports)
+	}
+}
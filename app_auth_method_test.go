@@ -0,0 +1,46 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"claude-code-codex-companion/internal/config"
+)
+
+func TestUpstreamAuthMethod(t *testing.T) {
+	cases := []struct {
+		authType string
+		header   http.Header
+		expected string
+	}{
+		{"api_key", http.Header{"X-Api-Key": {"sk-1"}}, "api_key"},
+		{"auth_token", http.Header{"Authorization": {"Bearer sk-1"}}, "authorization"},
+		{"oauth", http.Header{"Authorization": {"Bearer at-1"}}, "oauth"},
+		{"none", http.Header{}, ""},
+	}
+	for _, c := range cases {
+		if got := upstreamAuthMethod(c.authType, c.header); got != c.expected {
+			t.Errorf("upstreamAuthMethod(%q) = %q, want %q", c.authType, got, c.expected)
+		}
+	}
+}
+
+func TestUpstreamAuthMethodFromEndpointAuth(t *testing.T) {
+	cases := []struct {
+		endpoint    config.EndpointConfig
+		mappedToken string
+		expected    string
+	}{
+		{config.EndpointConfig{AuthType: "api_key", AuthValue: "sk-1"}, "", "api_key"},
+		{config.EndpointConfig{AuthType: "api_key"}, "mapped", "api_key"},
+		{config.EndpointConfig{AuthType: "auth_token"}, "", ""},
+		{config.EndpointConfig{AuthType: "oauth", OAuthConfig: &config.OAuthConfig{AccessToken: "at-1"}}, "", "oauth"},
+	}
+	for _, c := range cases {
+		header := http.Header{}
+		applyEndpointAuth(header, c.endpoint, c.mappedToken)
+		if got := upstreamAuthMethod(c.endpoint.AuthType, header); got != c.expected {
+			t.Errorf("auth method for %q (mapped %q) = %q, want %q", c.endpoint.AuthType, c.mappedToken, got, c.expected)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAutoDisableAndReenableEndpoint(t *testing.T) {
+	db := newEndpointTestDB(t)
+	if _, err := db.Exec(`INSERT INTO endpoints (id, name, enabled, status) VALUES ('1', 'flaky', 1, 'healthy'), ('2', 'manual', 0, 'healthy')`); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	app := &App{db: db, config: map[string]interface{}{
+		"server": map[string]interface{}{"auto_disable_after_minutes": float64(5), "auto_reenable_on_health_check": true},
+	}}
+	disableAfter, reenable := app.getAutoDisableSettings()
+	if disableAfter != 5*time.Minute || !reenable {
+		t.Fatalf("unexpected settings: %v %v", disableAfter, reenable)
+	}
+
+	readState := func(id string) (bool, bool) {
+		var enabled, autoDisabled bool
+		if err := db.QueryRow(`SELECT enabled, auto_disabled FROM endpoints WHERE id = ?`, id).Scan(&enabled, &autoDisabled); err != nil {
+			t.Fatalf("query: %v", err)
+		}
+		return enabled, autoDisabled
+	}
+
+	app.recordEndpointOutcome("flaky", 502)
+	if enabled, _ := readState("1"); !enabled {
+		t.Fatal("expected endpoint to stay enabled on the first failure")
+	}
+
+	// 一次成功会重置连续不健康计时
+	app.recordEndpointOutcome("flaky", 200)
+	if _, tracked := app.unhealthySince["flaky"]; tracked {
+		t.Fatal("expected success to clear the unhealthy timer")
+	}
+
+	app.recordEndpointOutcome("flaky", 503)
+	app.unhealthySince["flaky"] = time.Now().Add(-6 * time.Minute)
+	app.recordEndpointOutcome("flaky", 503)
+	if enabled, autoDisabled := readState("1"); enabled || !autoDisabled {
+		t.Fatalf("expected endpoint to be auto-disabled, got enabled=%v auto_disabled=%v", enabled, autoDisabled)
+	}
+
+	app.reenableAutoDisabledEndpoint(db, "1", "flaky")
+	app.reenableAutoDisabledEndpoint(db, "2", "manual")
+	if enabled, autoDisabled := readState("1"); !enabled || autoDisabled {
+		t.Fatalf("expected endpoint to be re-enabled, got enabled=%v auto_disabled=%v", enabled, autoDisabled)
+	}
+	if enabled, _ := readState("2"); enabled {
+		t.Fatal("expected manually disabled endpoint to stay disabled")
+	}
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"testing"
+
+	"claude-code-codex-companion/internal/config"
+)
+
+func TestBatchPathRouting(t *testing.T) {
+	app := &App{}
+	endpoints := []config.EndpointConfig{
+		{Name: "openai-only", URLOpenAI: "https://api.openai.example"},
+		{Name: "anthropic", URLAnthropic: "https://api.anthropic.example", URLOpenAI: "https://api.openai.example"},
+	}
+
+	filtered := filterAnthropicEndpoints(endpoints)
+	if len(filtered) != 1 || filtered[0].Name != "anthropic" {
+		t.Fatalf("expected only the anthropic endpoint, got %v", filtered)
+	}
+
+	target, err := app.buildTargetURL(&filtered[0], "/v1/messages/batches/msgbatch_1/results", "")
+	if err != nil || target != "https://api.anthropic.example/v1/messages/batches/msgbatch_1/results" {
+		t.Fatalf("unexpected batch target %q (err=%v)", target, err)
+	}
+
+	if _, err := app.buildTargetURL(&endpoints[0], "/v1/messages/batches", ""); err == nil {
+		t.Fatal("expected batch path to be rejected for an OpenAI-only endpoint")
+	}
+}
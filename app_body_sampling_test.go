@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"claude-code-codex-companion/internal/logger"
+)
+
+func TestBodySampling(t *testing.T) {
+	app := &App{}
+	if rate := app.getBodySampleRate(); rate != 1 {
+		t.Fatalf("expected default sample rate 1, got %v", rate)
+	}
+	app.config = map[string]interface{}{
+		"logging": map[string]interface{}{"body_sample_rate": 0.25},
+	}
+	rate := app.getBodySampleRate()
+
+	sampled := 0
+	for i := 0; i < 4000; i++ {
+		id := fmt.Sprintf("req_%d", i)
+		first := bodySampled(id, rate)
+		if first != bodySampled(id, rate) {
+			t.Fatalf("expected deterministic sampling for %s", id)
+		}
+		if first {
+			sampled++
+		}
+	}
+	if sampled < 800 || sampled > 1200 {
+		t.Fatalf("expected roughly 25%% of requests sampled, got %d/4000", sampled)
+	}
+	if bodySampled("req_1", 0) || !bodySampled("req_1", 1) {
+		t.Fatal("expected rates 0 and 1 to drop or keep every body")
+	}
+
+	entry := &logger.RequestLog{RequestBody: "{}", FinalResponseBody: "{}", RequestBodySize: 2, StatusCode: 200, Model: "gpt-4o"}
+	stripLoggedBodies(entry)
+	if entry.RequestBody != "" || entry.FinalResponseBody != "" || entry.RequestBodySize != 2 || entry.Model != "gpt-4o" {
+		t.Fatalf("expected bodies stripped and metadata kept, got %+v", entry)
+	}
+}
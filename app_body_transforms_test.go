@@ -0,0 +1,49 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestParseBodyTransformsValidates(t *testing.T) {
+	if _, err := parseBodyTransforms([]interface{}{
+		map[string]interface{}{"op": "set", "path": "$.metadata.source", "value": "cccc"},
+		map[string]interface{}{"op": "DELETE", "path": "messages[0].name"},
+	}); err != nil {
+		t.Fatalf("expected valid transforms, got %v", err)
+	}
+	if _, err := parseBodyTransforms([]interface{}{map[string]interface{}{"op": "rename", "path": "$.a"}}); err == nil {
+		t.Error("expected unsupported op to be rejected")
+	}
+	if _, err := parseBodyTransforms(`[{"op":"delete","path":"$.messages[x]"}]`); err == nil {
+		t.Error("expected invalid path to be rejected")
+	}
+	if encoded, err := serialiseBodyTransforms(nil); err != nil || encoded != "[]" {
+		t.Errorf("expected empty transforms to serialise as [], got %q, %v", encoded, err)
+	}
+}
+
+func TestApplyBodyTransforms(t *testing.T) {
+	transforms, err := parseBodyTransforms(`[
+		{"op":"delete","path":"$.messages[0].name"},
+		{"op":"set","path":"$.metadata.source","value":"cccc"},
+		{"op":"delete","path":"$.not_present"}
+	]`)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	body := []byte(`{"model":"m","max_tokens":12345678901234567,"messages":[{"role":"user","name":"alice","content":"hi"}]}`)
+	updated, fired := applyBodyTransforms(body, transforms)
+	if len(fired) != 2 {
+		t.Fatalf("expected 2 transforms to fire, got %v", fired)
+	}
+	want := `{"max_tokens":12345678901234567,"messages":[{"content":"hi","role":"user"}],"metadata":{"source":"cccc"},"model":"m"}`
+	if string(updated) != want {
+		t.Fatalf("unexpected body:\n got %s\nwant %s", updated, want)
+	}
+
+	untouched := []byte(`{"model":"m"}`)
+	if got, fired := applyBodyTransforms(untouched, transforms[:1]); string(got) != string(untouched) || len(fired) != 0 {
+		t.Fatalf("expected body unchanged when nothing matches, got %s %v", got, fired)
+	}
+}
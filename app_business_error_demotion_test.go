@@ -0,0 +1,92 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"claude-code-codex-companion/internal/config"
+)
+
+func newDemotionTestApp(threshold float64, windowMinutes float64) *App {
+	return &App{config: map[string]interface{}{
+		"server": map[string]interface{}{
+			"business_error_demotion_threshold":      threshold,
+			"business_error_demotion_window_minutes": windowMinutes,
+		},
+	}}
+}
+
+func TestIsBusinessErrorStatus(t *testing.T) {
+	for _, status := range []int{http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusUnprocessableEntity} {
+		if !isBusinessErrorStatus(status) {
+			t.Fatalf("expected %d to be a business error", status)
+		}
+	}
+	for _, status := range []int{0, http.StatusOK, http.StatusUnauthorized, http.StatusForbidden, http.StatusTooManyRequests, http.StatusBadGateway} {
+		if isBusinessErrorStatus(status) {
+			t.Fatalf("expected %d not to be a business error", status)
+		}
+	}
+}
+
+func TestRecordBusinessErrorOutcome_DemotesAndRecovers(t *testing.T) {
+	app := newDemotionTestApp(0.5, 5)
+	now := time.Now()
+
+	for i := 0; i < businessErrorDemotionMinSamples-1; i++ {
+		if demoted, _, _ := app.recordBusinessErrorOutcome("primary", http.StatusBadRequest, now); demoted {
+			t.Fatalf("should not demote before reaching the minimum sample count")
+		}
+	}
+	demoted, changed, rate := app.recordBusinessErrorOutcome("primary", http.StatusBadRequest, now)
+	if !demoted || !changed || rate != 1 {
+		t.Fatalf("expected demotion, got demoted=%v changed=%v rate=%v", demoted, changed, rate)
+	}
+	if !app.isEndpointDemoted("primary", now) {
+		t.Fatalf("expected endpoint to be demoted")
+	}
+
+	// 窗口过期后自动恢复
+	if app.isEndpointDemoted("primary", now.Add(6*time.Minute)) {
+		t.Fatalf("expected demotion to expire with the window")
+	}
+}
+
+func TestRecordBusinessErrorOutcome_Disabled(t *testing.T) {
+	app := newDemotionTestApp(0, 5)
+	for i := 0; i < 10; i++ {
+		if demoted, _, _ := app.recordBusinessErrorOutcome("primary", http.StatusBadRequest, time.Now()); demoted {
+			t.Fatalf("demotion should be disabled when threshold is 0")
+		}
+	}
+	if len(app.businessErrors) != 0 {
+		t.Fatalf("expected nothing to be tracked when disabled")
+	}
+}
+
+func TestDemoteBusinessErrorEndpoints(t *testing.T) {
+	app := newDemotionTestApp(0.5, 5)
+	for i := 0; i < businessErrorDemotionMinSamples; i++ {
+		app.recordBusinessErrorOutcome("top", http.StatusBadRequest, time.Now())
+		app.recordBusinessErrorOutcome("mid", http.StatusOK, time.Now())
+	}
+
+	endpoints := []config.EndpointConfig{
+		{Name: "top", Priority: 10},
+		{Name: "mid", Priority: 5},
+		{Name: "low", Priority: 1},
+	}
+	ordered := app.demoteBusinessErrorEndpoints(endpoints)
+
+	names := []string{ordered[0].Name, ordered[1].Name, ordered[2].Name}
+	if names[0] != "mid" || names[1] != "low" || names[2] != "top" {
+		t.Fatalf("unexpected order: %v", names)
+	}
+	if ordered[2].Priority >= ordered[1].Priority {
+		t.Fatalf("demoted endpoint priority should fall below the rest, got %d", ordered[2].Priority)
+	}
+	if endpoints[0].Name != "top" || endpoints[0].Priority != 10 {
+		t.Fatalf("input slice must not be modified")
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"claude-code-codex-companion/internal/config"
+	"claude-code-codex-companion/internal/logger"
+)
+
+func TestClientCancellationAbortsStreamingRead(t *testing.T) {
+	upstreamDone := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer close(upstreamDone)
+		w.Header().Set("Content-Type", "text/event-stream")
+		for i := 0; ; i++ {
+			if _, err := fmt.Fprintf(w, "data: {\"chunk\":%d}\n\n", i); err != nil {
+				return
+			}
+			w.(http.Flusher).Flush()
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	}))
+	defer upstream.Close()
+
+	app := &App{}
+	client, err := app.getUpstreamClient(config.EndpointConfig{Name: "stream", URLOpenAI: upstream.URL})
+	if err != nil {
+		t.Fatalf("getUpstreamClient failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	clientReq := httptest.NewRequest(http.MethodPost, "/v1/messages", nil).WithContext(ctx)
+	req, _ := http.NewRequestWithContext(clientReq.Context(), http.MethodPost, upstream.URL, nil)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("upstream request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	time.AfterFunc(50*time.Millisecond, cancel)
+	readDone := make(chan error, 1)
+	go func() {
+		_, readErr := readResponseBodyCapped(resp.Body, 1<<20)
+		readDone <- readErr
+	}()
+
+	select {
+	case readErr := <-readDone:
+		if readErr == nil {
+			t.Fatal("expected the streaming read to fail after the client cancelled")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("streaming read was not aborted by client cancellation")
+	}
+	if !clientCanceled(clientReq) {
+		t.Fatal("expected clientCanceled to report the cancelled request")
+	}
+
+	select {
+	case <-upstreamDone:
+	case <-time.After(2 * time.Second):
+		t.Fatal("upstream handler did not observe the cancellation")
+	}
+}
+
+func TestClientCanceledActiveRequest(t *testing.T) {
+	if clientCanceled(httptest.NewRequest(http.MethodPost, "/v1/messages", nil)) {
+		t.Fatal("an active request must not be reported as cancelled")
+	}
+}
+
+func TestClientCanceledRequestIsLoggedWithoutEndpointOutcome(t *testing.T) {
+	requestLogger, err := logger.NewLogger(logger.LogConfig{Level: "info", LogRequestTypes: "all", LogDirectory: t.TempDir()})
+	if err != nil {
+		t.Fatalf("create logger: %v", err)
+	}
+	defer requestLogger.Close()
+	app := &App{
+		requestLogger: requestLogger,
+		config:        map[string]interface{}{"server": map[string]interface{}{"success_rate_window": float64(10)}},
+	}
+	defer app.closeRequestLogWriter()
+
+	app.logProxyRequest(&logger.RequestLog{
+		RequestID:     "req_canceled",
+		Endpoint:      "ep",
+		Method:        http.MethodPost,
+		Path:          "/v1/messages",
+		StatusCode:    statusClientClosedRequest,
+		Error:         "client canceled",
+		ErrorCategory: errorCategoryClientCanceled,
+	})
+
+	app.endpointOutcomesMu.Lock()
+	_, recorded := app.endpointOutcomes["ep"]
+	app.endpointOutcomesMu.Unlock()
+	if recorded {
+		t.Fatal("expected a client cancellation not to count as an endpoint outcome")
+	}
+
+	result := app.GetLogs(map[string]interface{}{})
+	entries, _ := result["logs"].([]map[string]interface{})
+	if len(entries) != 1 || entries[0]["request_id"] != "req_canceled" || entries[0]["error"] != "client canceled" {
+		t.Fatalf("expected the cancelled request to be logged, got %v", entries)
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"testing"
+
+	"claude-code-codex-companion/internal/config"
+	"claude-code-codex-companion/internal/endpoint"
+	"claude-code-codex-companion/internal/health"
+)
+
+func TestApplyCapabilityReport(t *testing.T) {
+	supported, unsupported := true, false
+	report := &health.CapabilityReport{
+		AuthHeader:     "x-api-key",
+		MaxTokensField: "max_tokens",
+		Results: []health.CapabilityResult{
+			{Capability: health.CapabilityStreaming, Supported: &supported},
+			{Capability: health.CapabilityTools, Supported: &unsupported},
+			{Capability: health.CapabilityVision},
+			{Capability: health.CapabilityResponses, Supported: &unsupported},
+		},
+	}
+
+	app := &App{}
+	cfg := config.EndpointConfig{Name: "probed", URLOpenAI: "https://api.example.com"}
+	ep := endpoint.NewEndpoint(cfg)
+	app.applyCapabilityReport(ep, cfg, report)
+
+	snapshot := ep.GetLearningSnapshot()
+	if snapshot.DetectedAuthHeader != "x-api-key" || snapshot.MaxTokensField != "max_tokens" {
+		t.Fatalf("unexpected auth/max tokens learning: %+v", snapshot)
+	}
+	if snapshot.StreamingSupport == nil || !*snapshot.StreamingSupport || snapshot.VisionSupport != nil {
+		t.Fatalf("unexpected streaming/vision learning: %+v", snapshot)
+	}
+	if !ep.IsParamUnsupported("tools") || !ep.IsParamUnsupported("tool_choice") {
+		t.Fatalf("expected tools to be learned as unsupported, got %v", snapshot.UnsupportedParams)
+	}
+	if snapshot.NativeCodexFormat == nil || *snapshot.NativeCodexFormat {
+		t.Fatalf("expected /responses to be learned as unsupported")
+	}
+
+	// 显式配置的 supports_responses 不被探测结果覆盖
+	cfg.SupportsResponses = &supported
+	explicit := endpoint.NewEndpoint(cfg)
+	app.applyCapabilityReport(explicit, cfg, report)
+	if native := explicit.GetLearningSnapshot().NativeCodexFormat; native == nil || !*native {
+		t.Fatalf("expected explicit supports_responses to win over the probe")
+	}
+}
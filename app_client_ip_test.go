@@ -0,0 +1,45 @@
+package main
+
+import "testing"
+
+func TestClientIPForwardingResolve(t *testing.T) {
+	forwarding := clientIPForwarding{
+		enabled: true,
+		trusted: parseTrustedProxies([]string{"10.0.0.0/8", "127.0.0.1", "not-an-ip"}),
+	}
+	if len(forwarding.trusted) != 2 {
+		t.Fatalf("expected invalid entries to be ignored, got %d networks", len(forwarding.trusted))
+	}
+
+	cases := []struct {
+		name, remoteAddr, existing string
+		xff, realIP                string
+	}{
+		{"untrusted peer discards spoofed header", "203.0.113.7:5000", "1.2.3.4", "203.0.113.7", "203.0.113.7"},
+		{"no existing header", "127.0.0.1:5000", "", "127.0.0.1", "127.0.0.1"},
+		{"trusted peer appends", "127.0.0.1:5000", "198.51.100.2", "198.51.100.2, 127.0.0.1", "198.51.100.2"},
+		{"skips trusted hops", "127.0.0.1:5000", "198.51.100.2, 10.1.2.3", "198.51.100.2, 10.1.2.3, 127.0.0.1", "198.51.100.2"},
+		{"stops at unparseable hop", "127.0.0.1:5000", "garbage, 10.1.2.3", "garbage, 10.1.2.3, 127.0.0.1", "10.1.2.3"},
+		{"invalid remote", "pipe", "", "", ""},
+	}
+	for _, c := range cases {
+		xff, realIP := forwarding.resolve(c.remoteAddr, c.existing)
+		if xff != c.xff || realIP != c.realIP {
+			t.Errorf("%s: got (%q, %q), want (%q, %q)", c.name, xff, realIP, c.xff, c.realIP)
+		}
+	}
+}
+
+func TestGetClientIPForwardingDefaultsOff(t *testing.T) {
+	app := &App{config: map[string]interface{}{"server": map[string]interface{}{}}}
+	if app.getClientIPForwarding().enabled {
+		t.Fatalf("forward_client_ip must default to off")
+	}
+
+	app.config["server"].(map[string]interface{})["forward_client_ip"] = true
+	app.config["server"].(map[string]interface{})["trusted_proxies"] = []interface{}{"::1"}
+	forwarding := app.getClientIPForwarding()
+	if !forwarding.enabled || len(forwarding.trusted) != 1 {
+		t.Fatalf("unexpected forwarding config: %+v", forwarding)
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiffConfig(t *testing.T) {
+	app := &App{config: map[string]interface{}{
+		"server": map[string]interface{}{
+			"port":                   8080,
+			"claude_code_auth_token": "sk-secret-token-123",
+			"token_mappings":         []interface{}{map[string]interface{}{"token": "abc"}},
+		},
+		"logging": map[string]interface{}{"level": "info"},
+	}}
+
+	result := app.DiffConfig(map[string]interface{}{
+		"server": map[string]interface{}{
+			"port":                   float64(9090),
+			"claude_code_auth_token": "",
+			"token_mappings":         []interface{}{},
+		},
+		"retry": map[string]interface{}{"max_attempts": float64(3)},
+	})
+	if result["success"] != true || result["has_changes"] != true {
+		t.Fatalf("unexpected result: %v", result)
+	}
+
+	byPath := map[string]map[string]interface{}{}
+	for _, change := range result["changes"].([]map[string]interface{}) {
+		byPath[change["path"].(string)] = change
+	}
+	if c := byPath["server.port"]; c == nil || c["type"] != "changed" || c["new"] != float64(9090) {
+		t.Errorf("expected server.port change, got %v", c)
+	}
+	if c := byPath["logging"]; c == nil || c["type"] != "removed" {
+		t.Errorf("expected logging removed, got %v", c)
+	}
+	if c := byPath["retry"]; c == nil || c["type"] != "added" {
+		t.Errorf("expected retry added, got %v", c)
+	}
+	if c := byPath["server.claude_code_auth_token"]; c == nil || c["old"] != "sk-s***********-123" {
+		t.Errorf("expected masked token change, got %v", c)
+	}
+
+	warnings := strings.Join(result["warnings"].([]string), "\n")
+	for _, want := range []string{"claude_code_auth_token", "token_mappings", "9090"} {
+		if !strings.Contains(warnings, want) {
+			t.Errorf("expected warning mentioning %q, got %q", want, warnings)
+		}
+	}
+
+	// 传入的配置不应写回缓存
+	if app.config["server"].(map[string]interface{})["port"] != 8080 {
+		t.Errorf("DiffConfig must not modify current config")
+	}
+}
+
+func TestDiffEndpointLists(t *testing.T) {
+	oldEndpoints := []interface{}{
+		map[string]interface{}{"name": "a", "enabled": true, "priority": float64(1)},
+		map[string]interface{}{"name": "b", "enabled": true},
+	}
+	newEndpoints := []interface{}{
+		map[string]interface{}{"name": "a", "enabled": false, "priority": float64(1)},
+		map[string]interface{}{"name": "c", "enabled": false},
+	}
+
+	diff := diffEndpointLists(oldEndpoints, newEndpoints)
+	if added := diff["added"].([]string); len(added) != 1 || added[0] != "c" {
+		t.Errorf("unexpected added: %v", added)
+	}
+	if removed := diff["removed"].([]string); len(removed) != 1 || removed[0] != "b" {
+		t.Errorf("unexpected removed: %v", removed)
+	}
+	changed := diff["changed"].([]map[string]interface{})
+	if len(changed) != 1 || changed[0]["name"] != "a" || strings.Join(changed[0]["fields"].([]string), ",") != "enabled" {
+		t.Errorf("unexpected changed: %v", changed)
+	}
+
+	warnings := configDiffWarnings(nil, nil, newEndpoints, true)
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "所有端点") {
+		t.Errorf("expected all-disabled warning, got %v", warnings)
+	}
+}
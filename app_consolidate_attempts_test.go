@@ -0,0 +1,54 @@
+package main
+
+import (
+	"testing"
+
+	"claude-code-codex-companion/internal/logger"
+)
+
+func TestConsolidateAttempt(t *testing.T) {
+	app := &App{}
+	app.config = map[string]interface{}{
+		"logging": map[string]interface{}{"consolidate_attempts": true},
+	}
+	if !app.isConsolidateAttemptsEnabled() {
+		t.Fatal("expected logging.consolidate_attempts to be enabled")
+	}
+
+	failed := &logger.RequestLog{RequestID: "req-1", Endpoint: "primary", AttemptNumber: 1, StatusCode: 502, ResponseBody: `{"error":"down"}`}
+	if got := app.consolidateAttempt(failed); got != nil {
+		t.Fatalf("expected failed attempt to be buffered, got %+v", got)
+	}
+	timeout := &logger.RequestLog{RequestID: "req-1", Endpoint: "secondary", AttemptNumber: 2, Error: "timeout"}
+	if got := app.consolidateAttempt(timeout); got != nil {
+		t.Fatalf("expected errored attempt to be buffered, got %+v", got)
+	}
+
+	success := &logger.RequestLog{RequestID: "req-1", Endpoint: "backup", AttemptNumber: 3, StatusCode: 200}
+	got := app.consolidateAttempt(success)
+	if got != success {
+		t.Fatal("expected the successful attempt to be returned for writing")
+	}
+	if len(got.Attempts) != 3 {
+		t.Fatalf("expected 3 attempts in consolidated entry, got %+v", got.Attempts)
+	}
+	if got.Attempts[0].ResponseBody != `{"error":"down"}` || got.Attempts[1].Error != "timeout" || got.Attempts[2].Endpoint != "backup" {
+		t.Fatalf("unexpected attempts: %+v", got.Attempts)
+	}
+	if len(app.consolidatedLogs) != 0 {
+		t.Fatalf("expected buffer to be cleared, got %d pending", len(app.consolidatedLogs))
+	}
+
+	// 首次即成功的请求不附带 attempts
+	single := &logger.RequestLog{RequestID: "req-2", Endpoint: "primary", AttemptNumber: 1, StatusCode: 200}
+	if got := app.consolidateAttempt(single); got != single || len(got.Attempts) != 0 {
+		t.Fatalf("expected single success to be written unchanged, got %+v", got)
+	}
+
+	// 全部失败时由 fallback 日志汇总，fallback 本身不计为一次尝试
+	app.consolidateAttempt(&logger.RequestLog{RequestID: "req-3", Endpoint: "primary", AttemptNumber: 1, StatusCode: 500})
+	fallback := &logger.RequestLog{RequestID: "req-3", Endpoint: "fallback", AttemptNumber: 2, StatusCode: 500}
+	if got := app.consolidateAttempt(fallback); got != fallback || len(got.Attempts) != 1 {
+		t.Fatalf("expected fallback entry to carry the failed attempt, got %+v", got)
+	}
+}
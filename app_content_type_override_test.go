@@ -0,0 +1,70 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"claude-code-codex-companion/internal/logger"
+)
+
+func TestContentTypeCorrectionIsLogged(t *testing.T) {
+	requestLogger, err := logger.NewLogger(logger.LogConfig{Level: "info", LogRequestTypes: "all", LogDirectory: t.TempDir()})
+	if err != nil {
+		t.Fatalf("create logger: %v", err)
+	}
+	defer requestLogger.Close()
+	app := &App{requestLogger: requestLogger}
+	defer app.closeRequestLogWriter()
+
+	cases := []struct {
+		name         string
+		upstreamType string
+		body         string
+		wantType     string
+		wantOverride string
+	}{
+		{"plain-json", "text/plain; charset=utf-8", `{"id":"msg_1","type":"message","content":[]}`, "application/json", "plain->json"},
+		{"json-sse", "application/json", "event: message_start\ndata: {\"type\":\"message_start\"}\n\n", "text/event-stream; charset=utf-8", "json->sse"},
+		{"correct", "application/json", `{"id":"msg_2"}`, "application/json", ""},
+	}
+
+	for _, c := range cases {
+		resp := &http.Response{StatusCode: http.StatusOK, Header: http.Header{"Content-Type": []string{c.upstreamType}}}
+		rec := httptest.NewRecorder()
+
+		// 与桌面代理写回响应的顺序一致：复制上游响应头 -> 修正 Content-Type -> 应用端点响应头覆盖
+		for key, values := range resp.Header {
+			for _, value := range values {
+				rec.Header().Add(key, value)
+			}
+		}
+		_, override := applyContentTypeCorrection(rec.Header(), []byte(c.body), resp)
+		applyResponseHeaderOverrides(rec.Header(), map[string]string{"X-Extra": "1"})
+
+		if got := rec.Header().Get("Content-Type"); got != c.wantType {
+			t.Errorf("%s: expected Content-Type %q, got %q", c.name, c.wantType, got)
+		}
+		if values := rec.Header().Values("Content-Type"); len(values) != 1 {
+			t.Errorf("%s: expected a single Content-Type header, got %v", c.name, values)
+		}
+		if override != c.wantOverride {
+			t.Errorf("%s: expected override %q, got %q", c.name, c.wantOverride, override)
+		}
+
+		app.logProxyRequest(&logger.RequestLog{RequestID: c.name, Endpoint: "ep", Method: http.MethodPost, Path: "/v1/messages", StatusCode: http.StatusOK, ContentTypeOverride: override})
+	}
+
+	result := app.GetLogs(map[string]interface{}{})
+	entries, _ := result["logs"].([]map[string]interface{})
+	overrides := map[string]interface{}{}
+	for _, entry := range entries {
+		overrides[entry["request_id"].(string)] = entry["content_type_override"]
+	}
+	if overrides["plain-json"] != "plain->json" || overrides["json-sse"] != "json->sse" {
+		t.Fatalf("expected content_type_override in request logs, got %v", overrides)
+	}
+	if value, exists := overrides["correct"]; !exists || value != nil {
+		t.Fatalf("expected no override for a correctly labelled response, got %v (exists=%v)", value, exists)
+	}
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRequestConversionStages(t *testing.T) {
+	stages := requestConversionStages("/v1/messages", "https://api.example.com/base/v1/chat/completions", false, "claude-3-5-sonnet", "gpt-4o", true)
+	got := strings.Join(stages, conversionStageSeparator)
+	want := "path:/v1/messages->/base/v1/chat/completions|model:claude-3-5-sonnet->gpt-4o"
+	if got != want {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+
+	stages = requestConversionStages("/v1/complete", "https://api.example.com/v1/chat/completions", true, "", "", false)
+	if got := strings.Join(stages, conversionStageSeparator); got != "path:/v1/complete->/v1/chat/completions|request:anthropic_complete->openai" {
+		t.Fatalf("unexpected legacy completion stages: %q", got)
+	}
+
+	if stages := requestConversionStages("/v1/messages", "https://api.example.com/prefix/v1/messages", false, "m", "m", true); len(stages) != 0 {
+		t.Fatalf("expected no stages for a passthrough request, got %v", stages)
+	}
+}
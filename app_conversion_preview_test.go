@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestConvertRequestPreview_AnthropicToChat(t *testing.T) {
+	app := &App{}
+	body := `{"model":"claude-3","max_tokens":100,"system":"be brief","messages":[{"role":"user","content":"hi"}],"top_k":5}`
+
+	result := app.ConvertRequestPreview(body, "anthropic", "openai", "openai")
+	if result["success"] != true {
+		t.Fatalf("expected success, got %v", result)
+	}
+	if result["conversion_path"] != "anthropic->openai_chat" {
+		t.Fatalf("unexpected path: %v", result["conversion_path"])
+	}
+
+	var converted map[string]interface{}
+	if err := json.Unmarshal([]byte(result["converted_body"].(string)), &converted); err != nil {
+		t.Fatalf("converted body is not JSON: %v", err)
+	}
+	messages, _ := converted["messages"].([]interface{})
+	if len(messages) != 2 {
+		t.Fatalf("expected system + user messages, got %v", converted["messages"])
+	}
+
+	warnings := result["warnings"].([]string)
+	found := false
+	for _, w := range warnings {
+		if strings.Contains(w, "top_k") {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("expected a warning about dropped top_k, got %v", warnings)
+	}
+}
+
+func TestConvertRequestPreview_ResponsesToChat(t *testing.T) {
+	app := &App{}
+	body := `{"model":"gpt-5","instructions":"sys","input":[{"role":"user","content":[{"type":"input_text","text":"hello"}]}]}`
+
+	result := app.ConvertRequestPreview(body, "codex", "openai_chat", "")
+	if result["success"] != true {
+		t.Fatalf("expected success, got %v", result)
+	}
+	if !strings.Contains(result["converted_body"].(string), `"messages"`) {
+		t.Fatalf("expected chat messages in output, got %s", result["converted_body"])
+	}
+}
+
+func TestConvertRequestPreview_Errors(t *testing.T) {
+	app := &App{}
+
+	if result := app.ConvertRequestPreview(`{"model":"x"}`, "anthropic", "unknown", ""); result["success"] != false {
+		t.Fatalf("expected unknown format to fail, got %v", result)
+	}
+	if result := app.ConvertRequestPreview(`not json`, "anthropic", "openai_chat", ""); result["success"] != false {
+		t.Fatalf("expected invalid JSON to fail, got %v", result)
+	}
+	if result := app.ConvertRequestPreview(`{"model":"x"}`, "openai_chat", "anthropic", ""); result["success"] != false {
+		t.Fatalf("expected unsupported direction to fail, got %v", result)
+	}
+
+	same := app.ConvertRequestPreview(`{"model":"x"}`, "chat", "openai_chat", "")
+	if same["success"] != true || len(same["warnings"].([]string)) != 1 {
+		t.Fatalf("expected passthrough with a warning, got %v", same)
+	}
+}
+
+func TestConvertRequestPreview_UsesEndpointUserFieldMode(t *testing.T) {
+	db := newEndpointTestDB(t)
+	app := &App{db: db}
+	if _, err := db.Exec(`INSERT INTO endpoints (id, name, url_openai, endpoint_type, auth_type, auth_value, enabled, priority, user_field_mode, created_at)
+		VALUES ('1', 'openai-strip', 'https://openai.example.com', 'openai', 'api_key', 'k', 1, 1, 'strip', '2024-01-01')`); err != nil {
+		t.Fatalf("insert endpoint: %v", err)
+	}
+
+	body := `{"model":"claude-3","max_tokens":100,"metadata":{"user_id":"user-1"},"messages":[{"role":"user","content":"hi"}]}`
+	converted := func(endpointType string) map[string]interface{} {
+		result := app.ConvertRequestPreview(body, "anthropic", "openai_chat", endpointType)
+		if result["success"] != true {
+			t.Fatalf("expected success, got %v", result)
+		}
+		var payload map[string]interface{}
+		if err := json.Unmarshal([]byte(result["converted_body"].(string)), &payload); err != nil {
+			t.Fatalf("converted body is not JSON: %v", err)
+		}
+		return payload
+	}
+
+	if user := converted("openai")["user"]; user != "user-1" {
+		t.Fatalf("expected metadata.user_id to map to user by default, got %v", user)
+	}
+	if user, exists := converted("openai-strip")["user"]; exists {
+		t.Fatalf("expected the endpoint's strip mode to drop user, got %v", user)
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"claude-code-codex-companion/internal/logger"
+)
+
+func TestDeadLetterRecording(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	if err := ensureDeadLetterSchema(db); err != nil {
+		t.Fatalf("ensure schema: %v", err)
+	}
+
+	app := &App{db: db}
+	app.trackDeadLetterAttempt(&logger.RequestLog{RequestID: "req_1", Endpoint: "a", AttemptNumber: 1, StatusCode: 503, Error: "overloaded"})
+	app.trackDeadLetterAttempt(&logger.RequestLog{RequestID: "req_1", Endpoint: "b", AttemptNumber: 2, StatusCode: 200})
+	app.trackDeadLetterAttempt(&logger.RequestLog{RequestID: "req_1", Endpoint: "c", AttemptNumber: 3, Error: "connection refused"})
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/messages?beta=true", strings.NewReader(`{}`))
+	body := `{"model":"claude-3","messages":[]}`
+	app.recordDeadLetter("req_1", req, body, map[string]string{"Authorization": "Bearer sk-***"}, "claude_code", "anthropic", http.StatusBadGateway, "connection refused")
+
+	if remaining := app.takeDeadLetterAttempts("req_1"); len(remaining) != 0 {
+		t.Fatalf("expected attempts to be drained after recording, got %v", remaining)
+	}
+
+	result := app.GetDeadLetters(10)
+	if result["success"] != true {
+		t.Fatalf("GetDeadLetters failed: %v", result)
+	}
+	items, _ := result["data"].([]map[string]interface{})
+	if len(items) != 1 {
+		t.Fatalf("expected one dead letter, got %d", len(items))
+	}
+	item := items[0]
+	if item["request_body"] != body || item["raw_query"] != "beta=true" || item["final_status"] != http.StatusBadGateway {
+		t.Fatalf("unexpected dead letter: %v", item)
+	}
+	attempts, _ := item["attempts"].([]deadLetterAttempt)
+	if len(attempts) != 2 || attempts[0].Endpoint != "a" || attempts[1].Error != "connection refused" {
+		t.Fatalf("expected only failed attempts to be kept, got %v", attempts)
+	}
+}
+
+func TestDeadLetterHonoursBodyPolicy(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	if err := ensureDeadLetterSchema(db); err != nil {
+		t.Fatalf("ensure schema: %v", err)
+	}
+
+	app := &App{db: db, config: map[string]interface{}{
+		"logging": map[string]interface{}{
+			"per_client": map[string]interface{}{
+				"codex": map[string]interface{}{"body": "none"},
+			},
+		},
+	}}
+	req := httptest.NewRequest(http.MethodPost, "/responses", strings.NewReader(`{}`))
+	app.recordDeadLetter("req_codex", req, `{"input":"private"}`, nil, "codex", "openai", http.StatusBadGateway, "boom")
+	app.recordDeadLetter("req_claude", req, `{"messages":[]}`, nil, "claude_code", "anthropic", http.StatusBadGateway, "boom")
+
+	items, _ := app.GetDeadLetters(10)["data"].([]map[string]interface{})
+	if len(items) != 2 {
+		t.Fatalf("expected two dead letters, got %d", len(items))
+	}
+	bodies := map[string]interface{}{}
+	for _, item := range items {
+		bodies[item["request_id"].(string)] = item["request_body"]
+	}
+	if bodies["req_codex"] != "" || bodies["req_claude"] != `{"messages":[]}` {
+		t.Fatalf("expected per-client none policy to drop the codex body only, got %v", bodies)
+	}
+
+	// 未抽中的请求同样不保存请求体
+	app.config = map[string]interface{}{"logging": map[string]interface{}{"body_sample_rate": float64(0)}}
+	app.recordDeadLetter("req_sampled", req, `{"messages":[]}`, nil, "claude_code", "anthropic", http.StatusBadGateway, "boom")
+	items, _ = app.GetDeadLetters(1)["data"].([]map[string]interface{})
+	if len(items) != 1 || items[0]["request_body"] != "" {
+		t.Fatalf("expected unsampled dead letter without body, got %v", items)
+	}
+	if result := app.ReplayDeadLetter(items[0]["id"].(int64)); result["success"] != false {
+		t.Fatalf("expected replay without a stored body to fail, got %v", result)
+	}
+}
+
+func TestReplayDeadLetterMissing(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	if err := ensureDeadLetterSchema(db); err != nil {
+		t.Fatalf("ensure schema: %v", err)
+	}
+
+	app := &App{db: db}
+	if result := app.ReplayDeadLetter(42); result["success"] != false {
+		t.Fatalf("expected replay of a missing dead letter to fail, got %v", result)
+	}
+}
+
+func TestReplayResponseRecorder(t *testing.T) {
+	rec := newReplayResponseRecorder()
+	rec.Header().Set("Content-Type", "application/json")
+	rec.WriteHeader(http.StatusBadGateway)
+	rec.WriteHeader(http.StatusOK)
+	if _, err := rec.Write([]byte(`{"error":"upstream"}`)); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if rec.Code != http.StatusBadGateway {
+		t.Fatalf("expected first status to stick, got %d", rec.Code)
+	}
+	if rec.Body.String() != `{"error":"upstream"}` || rec.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("unexpected recorded response %q %v", rec.Body.String(), rec.Header())
+	}
+
+	// 未显式写状态码时与 net/http 一致默认为 200
+	implicit := newReplayResponseRecorder()
+	implicit.Write([]byte("ok"))
+	if implicit.Code != http.StatusOK {
+		t.Fatalf("expected implicit 200, got %d", implicit.Code)
+	}
+}
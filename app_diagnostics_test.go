@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"claude-code-codex-companion/internal/config"
+)
+
+func TestSanitizeDiagnosticValue(t *testing.T) {
+	secret := "sk-1234567890abcdef"
+	raw := map[string]interface{}{
+		"server": map[string]interface{}{
+			"port": float64(8080),
+			"token_mappings": []interface{}{
+				map[string]interface{}{"token": secret, "mapped_token": secret, "endpoint": "main"},
+			},
+		},
+		"endpoints": []map[string]interface{}{
+			{"name": "main", "auth_value": secret, "auth_type": "api_key"},
+		},
+	}
+
+	sanitized := sanitizeDiagnosticValue("", raw).(map[string]interface{})
+	server := sanitized["server"].(map[string]interface{})
+	mapping := server["token_mappings"].([]interface{})[0].(map[string]interface{})
+	if mapping["token"] == secret || mapping["mapped_token"] == secret {
+		t.Fatalf("expected token mappings to be masked, got %v", mapping)
+	}
+	if mapping["endpoint"] != "main" || server["port"] != float64(8080) {
+		t.Fatalf("expected non-secret values to survive, got %v", server)
+	}
+	endpoint := sanitized["endpoints"].([]interface{})[0].(map[string]interface{})
+	if endpoint["auth_value"] == secret || endpoint["auth_type"] != "api_key" {
+		t.Fatalf("unexpected endpoint sanitisation: %v", endpoint)
+	}
+
+	// 原始配置不应被修改
+	if raw["server"].(map[string]interface{})["token_mappings"].([]interface{})[0].(map[string]interface{})["token"] != secret {
+		t.Fatal("sanitising must not mutate the source config")
+	}
+}
+
+func TestSanitizeDiagnosticLog(t *testing.T) {
+	entry := map[string]interface{}{
+		"status_code":   500,
+		"request_body":  `{"messages":[{"role":"user","content":"private"}]}`,
+		"response_body": "",
+		"request_headers": map[string]string{
+			"Authorization":  "Bearer sk-1234567890abcdef",
+			"X-Goog-Api-Key": "AIzaSyExampleExample",
+			"Content-Type":   "application/json",
+		},
+	}
+
+	sanitized := sanitizeDiagnosticLog(entry)
+	if body := sanitized["request_body"].(string); strings.Contains(body, "private") || !strings.HasPrefix(body, "[masked ") {
+		t.Fatalf("expected request body to be masked, got %q", body)
+	}
+	headers := sanitized["request_headers"].(map[string]string)
+	if strings.Contains(headers["Authorization"], "1234567890") || headers["X-Goog-Api-Key"] == "AIzaSyExampleExample" {
+		t.Fatalf("expected secret headers to be masked, got %v", headers)
+	}
+	if headers["Content-Type"] != "application/json" || sanitized["status_code"] != 500 {
+		t.Fatalf("expected non-secret fields to survive, got %v", sanitized)
+	}
+}
+
+func TestSanitizeDiagnosticValueMasksOAuthConfig(t *testing.T) {
+	endpoints := []interface{}{
+		map[string]interface{}{
+			"name":      "oauth",
+			"auth_type": "oauth",
+			"oauth_config": &config.OAuthConfig{
+				AccessToken:  "access-1234567890abcdef",
+				RefreshToken: "refresh-1234567890abcdef",
+				TokenURL:     "https://auth.example.com/token",
+				AutoRefresh:  true,
+			},
+		},
+	}
+
+	sanitized := sanitizeDiagnosticValue("", endpoints)
+	encoded, err := json.Marshal(sanitized)
+	if err != nil {
+		t.Fatalf("marshal sanitized endpoints: %v", err)
+	}
+	if strings.Contains(string(encoded), "access-1234567890abcdef") || strings.Contains(string(encoded), "refresh-1234567890abcdef") {
+		t.Fatalf("expected oauth tokens to be masked, got %s", encoded)
+	}
+
+	oauth := sanitized.([]interface{})[0].(map[string]interface{})["oauth_config"].(map[string]interface{})
+	if oauth["auto_refresh"] != true {
+		t.Fatalf("expected non-secret oauth fields to survive, got %v", oauth)
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"testing"
+
+	"claude-code-codex-companion/internal/config"
+)
+
+func TestEffectiveAuth(t *testing.T) {
+	auth := effectiveAuth(config.EndpointConfig{AuthType: "api_key", AuthValue: "sk-ant-1234567890"}, "")
+	if auth["header"] != "x-api-key" || auth["scheme"] != "api_key" || auth["has_credential"] != true {
+		t.Fatalf("unexpected api_key auth view: %v", auth)
+	}
+	if auth["credential"] == "sk-ant-1234567890" {
+		t.Fatal("expected credential to be masked")
+	}
+
+	auth = effectiveAuth(config.EndpointConfig{AuthType: "auto"}, "Authorization")
+	if auth["header"] != "Authorization" || auth["scheme"] != "bearer" || auth["has_credential"] != false {
+		t.Fatalf("unexpected auto auth view: %v", auth)
+	}
+	if auth["detected_header"] != "Authorization" {
+		t.Fatalf("expected detected header to be reported, got %v", auth["detected_header"])
+	}
+
+	auth = effectiveAuth(config.EndpointConfig{AuthType: "oauth", OAuthConfig: &config.OAuthConfig{
+		AccessToken: "oauth-access-1234567890", ExpiresAt: 1700000000000, AutoRefresh: true,
+	}}, "")
+	if auth["header"] != "Authorization" || auth["scheme"] != "oauth" || auth["has_credential"] != true {
+		t.Fatalf("unexpected oauth auth view: %v", auth)
+	}
+	if auth["credential"] == "oauth-access-1234567890" || auth["oauth_auto_refresh"] != true {
+		t.Fatalf("expected masked oauth token with refresh settings, got %v", auth)
+	}
+
+	auth = effectiveAuth(config.EndpointConfig{AuthType: "oauth", AuthValue: "Token raw-1234567890"}, "")
+	if auth["scheme"] != "raw" || auth["has_credential"] != true {
+		t.Fatalf("expected oauth without oauth_config to fall back to auth_value, got %v", auth)
+	}
+}
+
+func TestEffectiveRoutingAndOverrides(t *testing.T) {
+	app := &App{}
+	cfg := &config.EndpointConfig{Name: "anthropic", URLAnthropic: "https://api.anthropic.com"}
+
+	routing := app.effectiveRouting(cfg)
+	messages, _ := routing["/v1/messages"].(map[string]interface{})
+	if messages["target_url"] != "https://api.anthropic.com/v1/messages" || messages["format_conversion"] != false {
+		t.Fatalf("unexpected /v1/messages routing: %v", messages)
+	}
+	if batches, _ := routing["/v1/messages/batches"].(map[string]interface{}); batches["target_url"] == nil {
+		t.Fatalf("expected batch path to route to the Anthropic URL: %v", batches)
+	}
+
+	overrides := decodeEncodedParameterOverrides(encodeParameterOverrides(map[string]interface{}{
+		"temperature": 0.3,
+		"top_p":       nil,
+	}))
+	if overrides["temperature"] != 0.3 {
+		t.Fatalf("expected temperature override to decode, got %#v", overrides["temperature"])
+	}
+	if value, exists := overrides["top_p"]; !exists || value != nil {
+		t.Fatalf("expected top_p removal to decode as nil, got %v", value)
+	}
+}
+
+func TestDefaultUserAgent(t *testing.T) {
+	app := &App{config: map[string]interface{}{
+		"server": map[string]interface{}{"user_agent": "cccc-proxy/1.0"},
+	}}
+	if got := app.getDefaultUserAgent(); got != "cccc-proxy/1.0" {
+		t.Fatalf("expected configured default, got %q", got)
+	}
+
+	app.config = map[string]interface{}{
+		"server": map[string]interface{}{"user_agent": "bad\nvalue"},
+	}
+	if got := app.getDefaultUserAgent(); got != "" {
+		t.Fatalf("expected invalid default to be ignored, got %q", got)
+	}
+}
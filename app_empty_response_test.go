@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"claude-code-codex-companion/internal/conversion"
+)
+
+func TestGetEmptyResponseSettings(t *testing.T) {
+	app := &App{}
+	if action, placeholder := app.getEmptyResponseSettings(); action != emptyResponseActionPatch || placeholder != defaultEmptyResponsePlaceholder {
+		t.Fatalf("unexpected defaults: %q %q", action, placeholder)
+	}
+
+	app.config = map[string]interface{}{
+		"server": map[string]interface{}{
+			"empty_response_action":      " Retry ",
+			"empty_response_placeholder": "(no output)",
+		},
+	}
+	if action, placeholder := app.getEmptyResponseSettings(); action != emptyResponseActionRetry || placeholder != "(no output)" {
+		t.Fatalf("unexpected settings: %q %q", action, placeholder)
+	}
+
+	app.config["server"].(map[string]interface{})["empty_response_action"] = "drop"
+	if action, _ := app.getEmptyResponseSettings(); action != emptyResponseActionPatch {
+		t.Fatalf("expected unknown action to fall back to patch, got %q", action)
+	}
+}
+
+func TestIsEmptyAnthropicResponse(t *testing.T) {
+	cases := map[string]bool{
+		`{"type":"message","content":[]}`:                                                                true,
+		`{"type":"message","content":[{"type":"text","text":""}]}`:                                       true,
+		`{"type":"message","content":[{"type":"text"}]}`:                                                 true,
+		`{"type":"message","content":[{"type":"text","text":"hi"}]}`:                                     false,
+		`{"type":"message","content":[{"type":"text","text":""},{"type":"tool_use","name":"get_time"}]}`: false,
+		`{"type":"error","error":{"message":"boom"}}`:                                                    false,
+	}
+	for body, expected := range cases {
+		if got := isEmptyAnthropicResponse([]byte(body)); got != expected {
+			t.Errorf("%s: expected %v, got %v", body, expected, got)
+		}
+	}
+}
+
+func TestPatchEmptyAnthropicText(t *testing.T) {
+	body := []byte(`{"type":"message","content":[{"type":"text","text":""},{"type":"tool_use","name":"get_time"}]}`)
+	patched, fixed := patchEmptyAnthropicText(body, "(no output)")
+	if !fixed {
+		t.Fatal("expected the empty text block to be patched")
+	}
+
+	var resp struct {
+		Content []map[string]interface{} `json:"content"`
+	}
+	if err := json.Unmarshal(patched, &resp); err != nil {
+		t.Fatalf("patched body is not valid JSON: %v", err)
+	}
+	if resp.Content[0]["text"] != "(no output)" || resp.Content[1]["type"] != "tool_use" {
+		t.Fatalf("unexpected patched content: %v", resp.Content)
+	}
+
+	if _, fixed := patchEmptyAnthropicText([]byte(`{"type":"message","content":[{"type":"text","text":"hi"}]}`), "x"); fixed {
+		t.Fatal("non-empty responses must not be patched")
+	}
+}
+
+// TestResponseFixupsProduceCompactJSON 响应修补路径重序列化时输出紧凑 JSON，不保留上游缩进
+func TestResponseFixupsProduceCompactJSON(t *testing.T) {
+	pretty := []byte("{\n  \"type\": \"message\",\n  \"content\": [\n    {\"type\": \"thinking\", \"thinking\": \"hmm\"},\n    {\"type\": \"text\", \"text\": \"\"}\n  ]\n}")
+
+	patched, fixed := patchEmptyAnthropicText(pretty, "(no output)")
+	if !fixed {
+		t.Fatal("expected the empty text block to be patched")
+	}
+	stripped, changed := conversion.StripReasoningFromResponse(patched)
+	if !changed {
+		t.Fatal("expected the thinking block to be stripped")
+	}
+
+	for name, body := range map[string][]byte{"patched": patched, "stripped": stripped} {
+		var compacted bytes.Buffer
+		if err := json.Compact(&compacted, body); err != nil {
+			t.Fatalf("%s body is not valid JSON: %v", name, err)
+		}
+		if !bytes.Equal(compacted.Bytes(), body) {
+			t.Fatalf("expected %s body to be compact, got:\n%s", name, body)
+		}
+	}
+}
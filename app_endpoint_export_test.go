@@ -0,0 +1,163 @@
+package main
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+)
+
+func sampleExportEndpoints() []interface{} {
+	return []interface{}{
+		map[string]interface{}{
+			"id":            "endpoint_1",
+			"name":          "primary",
+			"url_anthropic": "https://api.example.com",
+			"auth_type":     "api_key",
+			"auth_value":    "sk-live-1234567890",
+			"hmac_secret":   "env:PRIMARY_HMAC",
+			"tags":          []string{"fast"},
+			"notes":         "shared",
+			"status":        "unhealthy",
+			"last_error":    "boom",
+			"oauth_config":  map[string]interface{}{"access_token": "at", "refresh_token": "rt", "token_url": "https://t"},
+		},
+	}
+}
+
+func TestBuildEndpointExportWithoutSecrets(t *testing.T) {
+	exported := buildEndpointExport(sampleExportEndpoints(), false)
+	if len(exported) != 1 {
+		t.Fatalf("expected one endpoint, got %d", len(exported))
+	}
+	ep := exported[0]
+	for _, key := range []string{"id", "status", "last_error", "auth_value", "oauth_config"} {
+		if _, exists := ep[key]; exists {
+			t.Errorf("expected %s to be excluded, got %v", key, ep[key])
+		}
+	}
+	if ep["auth_value_masked"] != maskToken("sk-live-1234567890") {
+		t.Errorf("expected masked auth value for reference, got %v", ep["auth_value_masked"])
+	}
+	if ep["hmac_secret"] != "env:PRIMARY_HMAC" {
+		t.Errorf("expected environment reference to be kept, got %v", ep["hmac_secret"])
+	}
+	if ep["notes"] != "shared" || ep["url_anthropic"] != "https://api.example.com" {
+		t.Errorf("expected definition fields to be kept, got %v", ep)
+	}
+}
+
+func TestBuildEndpointExportWithSecrets(t *testing.T) {
+	ep := buildEndpointExport(sampleExportEndpoints(), true)[0]
+	if ep["auth_value"] != "sk-live-1234567890" || ep["oauth_config"] == nil {
+		t.Fatalf("expected secrets to be exported, got %v", ep)
+	}
+	if _, exists := ep["auth_value_masked"]; exists {
+		t.Fatal("did not expect masked reference when secrets are included")
+	}
+}
+
+func TestParseEndpointImport(t *testing.T) {
+	definitions, err := parseEndpointImport(`{"kind":"endpoints","endpoints":[{"id":"x","name":"primary","status":"healthy","auth_value_masked":"sk-l****7890","url_openai":"https://o"}]}`)
+	if err != nil {
+		t.Fatalf("parseEndpointImport: %v", err)
+	}
+	if len(definitions) != 1 {
+		t.Fatalf("expected one definition, got %d", len(definitions))
+	}
+	for _, key := range []string{"id", "status", "auth_value_masked"} {
+		if _, exists := definitions[0][key]; exists {
+			t.Errorf("expected %s to be dropped on import", key)
+		}
+	}
+
+	if definitions, err := parseEndpointImport(`[{"name":"a","url_openai":"https://o"}]`); err != nil || len(definitions) != 1 {
+		t.Fatalf("expected bare array to be accepted, got %v (%v)", definitions, err)
+	}
+
+	for _, data := range []string{"", "not json", `{"logs":[]}`, `[{"url_openai":"https://o"}]`} {
+		if _, err := parseEndpointImport(data); err == nil {
+			t.Errorf("expected %q to be rejected", data)
+		}
+	}
+}
+
+func TestImportEndpointsRejectsUnknownMode(t *testing.T) {
+	app := &App{}
+	result := app.ImportEndpoints(`[]`, "overwrite")
+	if result["success"] != false || !strings.Contains(result["message"].(string), "overwrite") {
+		t.Fatalf("expected unknown mode to be rejected, got %v", result)
+	}
+}
+
+func newEndpointImportTestApp(t *testing.T) (*App, *sql.DB) {
+	t.Helper()
+	db := newEndpointTestDB(t)
+	app := &App{db: db, config: map[string]interface{}{
+		"server": map[string]interface{}{"warm_up_on_enable": false},
+	}}
+	if _, err := db.Exec(`INSERT INTO endpoints (id, name, url_anthropic, auth_type, auth_value, enabled, priority)
+		VALUES ('existing', 'existing', 'https://a.example.com', 'api_key', 'sk-old', 1, 1)`); err != nil {
+		t.Fatalf("insert endpoint: %v", err)
+	}
+	return app, db
+}
+
+func endpointNames(t *testing.T, db *sql.DB) []string {
+	t.Helper()
+	rows, err := db.Query("SELECT name FROM endpoints ORDER BY name")
+	if err != nil {
+		t.Fatalf("query endpoints: %v", err)
+	}
+	defer rows.Close()
+	var names []string
+	for rows.Next() {
+		var name string
+		rows.Scan(&name)
+		names = append(names, name)
+	}
+	return names
+}
+
+func TestImportEndpointsReplaceIsAtomic(t *testing.T) {
+	app, db := newEndpointImportTestApp(t)
+
+	// 第二个定义无效：replace 不应删除任何现有端点
+	result := app.ImportEndpoints(`[{"name":"new","url_openai":"https://o","auth_type":"auth_token","auth_value":"sk-new"},{"name":"bad","url_openai":"https://o","hmac_algo":"md5"}]`, "replace")
+	if result["success"] != false {
+		t.Fatalf("expected invalid import to fail, got %v", result)
+	}
+	if names := endpointNames(t, db); len(names) != 1 || names[0] != "existing" {
+		t.Fatalf("expected existing endpoints to be kept, got %v", names)
+	}
+
+	result = app.ImportEndpoints(`[{"name":"new","url_openai":"https://o","auth_type":"auth_token","auth_value":"sk-new"}]`, "replace")
+	if result["success"] != true || result["deleted"] != 1 || result["created"] != 1 {
+		t.Fatalf("expected replace to succeed, got %v", result)
+	}
+	if names := endpointNames(t, db); len(names) != 1 || names[0] != "new" {
+		t.Fatalf("expected only the imported endpoint, got %v", names)
+	}
+}
+
+func TestImportEndpointsRejectsMissingCredentials(t *testing.T) {
+	app, db := newEndpointImportTestApp(t)
+
+	// 未包含密钥的导出：新建端点缺少 auth_value / hmac_secret
+	for _, mode := range []string{"replace", "append"} {
+		result := app.ImportEndpoints(`{"kind":"endpoints","endpoints":[{"name":"stripped","url_anthropic":"https://a","auth_type":"api_key","auth_value_masked":"sk-l****7890"}]}`, mode)
+		if result["success"] != false || !strings.Contains(strings.Join(result["failures"].([]string), ";"), "auth_value") {
+			t.Fatalf("%s: expected missing credential to be rejected, got %v", mode, result)
+		}
+	}
+	result := app.ImportEndpoints(`[{"name":"signed","url_anthropic":"https://a","auth_type":"auto","hmac_header":"X-Signature"}]`, "append")
+	if result["success"] != false || !strings.Contains(strings.Join(result["failures"].([]string), ";"), "hmac_secret") {
+		t.Fatalf("expected missing hmac_secret to be rejected, got %v", result)
+	}
+	if names := endpointNames(t, db); len(names) != 1 || names[0] != "existing" {
+		t.Fatalf("expected no endpoints to be created, got %v", names)
+	}
+
+	if missing := missingEndpointCredential(map[string]interface{}{"auth_type": "auto"}); missing != "" {
+		t.Fatalf("expected auto auth without a credential to be accepted, got %q", missing)
+	}
+}
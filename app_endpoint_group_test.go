@@ -0,0 +1,83 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"claude-code-codex-companion/internal/logger"
+)
+
+func TestMatchesEndpointGroup(t *testing.T) {
+	cases := []struct {
+		provider, region, wantProvider, wantRegion string
+		want                                       bool
+	}{
+		{"OpenRouter", "us", "", "", true},
+		{"OpenRouter", "us", "openrouter", "", true},
+		{"OpenRouter", "us", "openrouter", "US", true},
+		{"OpenRouter", "us", "openrouter", "eu", false},
+		{"", "", "openrouter", "", false},
+	}
+	for _, tc := range cases {
+		if got := matchesEndpointGroup(tc.provider, tc.region, tc.wantProvider, tc.wantRegion); got != tc.want {
+			t.Errorf("matchesEndpointGroup(%q, %q, %q, %q) = %v, want %v", tc.provider, tc.region, tc.wantProvider, tc.wantRegion, got, tc.want)
+		}
+	}
+}
+
+func TestRequestTrendsFilteredByProvider(t *testing.T) {
+	db := newEndpointTestDB(t)
+	app := &App{db: db}
+	if _, err := db.Exec(`INSERT INTO endpoints (id, name, enabled, provider, region) VALUES
+		('1', 'or-us', 1, 'openrouter', 'us'), ('2', 'or-eu', 1, 'openrouter', 'eu'), ('3', 'direct', 1, '', '')`); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	if names, err := queryEndpointNamesInGroup(db, "", ""); err != nil || names != nil {
+		t.Fatalf("expected no filter for empty group, got %v (%v)", names, err)
+	}
+	if names, err := queryEndpointNamesInGroup(db, "OpenRouter", "eu"); err != nil || len(names) != 1 || names[0] != "or-eu" {
+		t.Fatalf("expected only or-eu, got %v (%v)", names, err)
+	}
+
+	requestLogger, err := logger.NewLogger(logger.LogConfig{Level: "info", LogRequestTypes: "all", LogDirectory: t.TempDir()})
+	if err != nil {
+		t.Fatalf("create logger: %v", err)
+	}
+	defer requestLogger.Close()
+	app.requestLogger = requestLogger
+
+	now := time.Now()
+	for i, entry := range []struct {
+		endpoint string
+		status   int
+	}{
+		{"or-us", 200},
+		{"or-us", 502},
+		{"or-eu", 200},
+		{"direct", 200},
+		{"direct", 500},
+	} {
+		requestLogger.LogRequest(&logger.RequestLog{
+			Timestamp:  now.Add(-time.Duration(i+1) * time.Minute),
+			RequestID:  "req",
+			Endpoint:   entry.endpoint,
+			StatusCode: entry.status,
+		})
+	}
+
+	all := app.GetRequestTrends("1h", "", "")
+	if all["totalRequests"] != 5 || all["totalFailures"] != 2 {
+		t.Fatalf("unexpected unfiltered trends: %v", all)
+	}
+
+	openrouter := app.GetRequestTrends("1h", "openrouter", "")
+	if openrouter["totalRequests"] != 3 || openrouter["totalSuccesses"] != 2 || openrouter["totalFailures"] != 1 {
+		t.Fatalf("unexpected provider trends: %v", openrouter)
+	}
+
+	none := app.GetRequestTrends("1h", "anthropic", "")
+	if none["success"] != true || none["totalRequests"] != 0 {
+		t.Fatalf("expected empty trends for unknown provider: %v", none)
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestSummarizeEndpointError(t *testing.T) {
+	cases := []struct {
+		status int
+		errMsg string
+		want   string
+	}{
+		{401, "upstream returned 401", "401 Unauthorized"},
+		{429, "", "429 Too Many Requests"},
+		{502, `Post "http://127.0.0.1:1/v1/messages": dial tcp 127.0.0.1:1: connect: connection refused`, "connection refused"},
+		{0, "health check response missing required fields (expected content or choices)", "health check response missing required fields (expected content or choices)"},
+		{0, "", ""},
+	}
+	for _, tc := range cases {
+		if got := summarizeEndpointError(tc.status, tc.errMsg); got != tc.want {
+			t.Errorf("summarizeEndpointError(%d, %q) = %q, want %q", tc.status, tc.errMsg, got, tc.want)
+		}
+	}
+}
+
+func TestRecordEndpointLastError(t *testing.T) {
+	db := newEndpointTestDB(t)
+	if _, err := db.Exec(`INSERT INTO endpoints (id, name, enabled, status) VALUES ('1', 'primary', 1, 'healthy')`); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	app := &App{db: db}
+	lastError := func() (string, string) {
+		var message, at string
+		if err := db.QueryRow(`SELECT last_error, last_error_at FROM endpoints WHERE name = 'primary'`).Scan(&message, &at); err != nil {
+			t.Fatalf("select: %v", err)
+		}
+		return message, at
+	}
+
+	app.recordEndpointLastError(db, "primary", summarizeEndpointError(401, "upstream returned 401"))
+	if message, at := lastError(); message != "401 Unauthorized" || at == "" {
+		t.Fatalf("expected 401 to be recorded, got %q at %q", message, at)
+	}
+
+	app.recordEndpointLastError(db, "primary", summarizeEndpointError(502, "dial tcp 127.0.0.1:1: connect: connection refused"))
+	if message, _ := lastError(); message != "connection refused" {
+		t.Fatalf("expected the newest failure to replace the previous one, got %q", message)
+	}
+
+	// 空摘要不覆盖已记录的错误
+	app.recordEndpointLastError(db, "primary", "")
+	if message, _ := lastError(); message != "connection refused" {
+		t.Fatalf("expected last error to be kept, got %q", message)
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"testing"
+
+	"claude-code-codex-companion/internal/config"
+)
+
+func TestGetEndpointLearningReportsLearnedParams(t *testing.T) {
+	db := newEndpointTestDB(t)
+	if _, err := db.Exec(`INSERT INTO endpoints (id, name) VALUES ('ep-1', 'primary')`); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	app := &App{db: db}
+	learning := app.GetEndpointLearning("ep-1")
+	if learning["success"] != true || learning["source"] != "probe" {
+		t.Fatalf("unexpected learning result: %v", learning)
+	}
+	if params, _ := learning["unsupported_params"].([]string); len(params) != 0 {
+		t.Fatalf("expected nothing learned before probing, got %v", params)
+	}
+
+	app.runtimeEndpoint("ep-1", config.EndpointConfig{Name: "primary"}).LearnUnsupportedParam("tool_choice")
+	learning = app.GetEndpointLearning("ep-1")
+	if params, _ := learning["unsupported_params"].([]string); len(params) != 1 || params[0] != "tool_choice" {
+		t.Fatalf("expected probe result to be reported, got %v", learning["unsupported_params"])
+	}
+
+	// 代理请求的上游参数错误同样记录到 unsupported_params
+	app.learnUnsupportedParams(config.EndpointConfig{Name: "primary"},
+		[]byte(`{"error":{"message":"unsupported parameter top_k"}}`), []byte(`{"model":"m","top_k":5}`))
+	learning = app.GetEndpointLearning("ep-1")
+	if params, _ := learning["unsupported_params"].([]string); len(params) != 2 || params[1] != "top_k" {
+		t.Fatalf("expected proxy error to be learned, got %v", learning["unsupported_params"])
+	}
+
+	if missing := app.GetEndpointLearning("nope"); missing["success"] != false {
+		t.Fatalf("expected unknown endpoint to fail, got %v", missing)
+	}
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"database/sql"
+	"testing"
+)
+
+func TestEnsureEndpointSchemaAddsNotes(t *testing.T) {
+	db := newEndpointTestDB(t)
+
+	app := &App{}
+	// 再次执行应保持幂等
+	if err := app.ensureEndpointSchema(db); err != nil {
+		t.Fatalf("ensure schema twice: %v", err)
+	}
+
+	if _, err := db.Exec(`INSERT INTO endpoints (id, name) VALUES ('ep-1', 'legacy')`); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	var notes sql.NullString
+	if err := db.QueryRow(`SELECT notes FROM endpoints WHERE id = 'ep-1'`).Scan(&notes); err != nil {
+		t.Fatalf("select notes: %v", err)
+	}
+	if notes.String != "" {
+		t.Fatalf("expected empty default notes, got %q", notes.String)
+	}
+}
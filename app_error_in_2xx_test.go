@@ -0,0 +1,48 @@
+package main
+
+import "testing"
+
+func TestDetectErrorBodyIn2xx(t *testing.T) {
+	cases := []struct {
+		name    string
+		body    string
+		format  string
+		want    bool
+		message string
+	}{
+		{"openai error", `{"error":{"message":"quota exceeded","type":"insufficient_quota"}}`, "openai", true, "quota exceeded"},
+		{"anthropic error", `{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`, "anthropic", true, "Overloaded"},
+		{"string error", `{"error":"bad gateway"}`, "", true, "bad gateway"},
+		{"chat success", `{"id":"x","choices":[{"message":{"content":"hi"}}]}`, "openai", false, ""},
+		{"anthropic success", `{"type":"message","content":[]}`, "anthropic", false, ""},
+		{"responses object", `{"object":"response","status":"failed","error":{"message":"x"}}`, "responses", false, ""},
+		{"null error", `{"error":null,"choices":[]}`, "openai", false, ""},
+		{"not json", `data: {}`, "openai", false, ""},
+	}
+	for _, tc := range cases {
+		message, isError := detectErrorBodyIn2xx([]byte(tc.body), tc.format)
+		if isError != tc.want || message != tc.message {
+			t.Errorf("%s: got (%q, %v), want (%q, %v)", tc.name, message, isError, tc.message, tc.want)
+		}
+	}
+}
+
+func TestUpstreamResponseFormat(t *testing.T) {
+	cases := map[string]string{
+		"https://api.anthropic.com/v1/messages":                                "anthropic",
+		"https://api.openai.com/v1/chat/completions":                           "openai",
+		"https://api.openai.com/v1/responses?stream=false":                     "responses",
+		"https://example.com/v1beta/models/gemini-pro:generateContent?key=abc": "gemini",
+		"https://example.com/v1/models":                                        "",
+	}
+	for target, want := range cases {
+		if got := upstreamResponseFormat(target); got != want {
+			t.Errorf("upstreamResponseFormat(%q) = %q, want %q", target, got, want)
+		}
+	}
+
+	app := &App{config: map[string]interface{}{"server": map[string]interface{}{"detect_error_in_2xx": false}}}
+	if app.isErrorIn2xxDetectionEnabled() || !(&App{}).isErrorIn2xxDetectionEnabled() {
+		t.Error("unexpected detect_error_in_2xx setting")
+	}
+}
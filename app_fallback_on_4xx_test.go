@@ -0,0 +1,58 @@
+package main
+
+import (
+	"database/sql"
+	"testing"
+
+	"claude-code-codex-companion/internal/config"
+)
+
+func TestShouldFallbackOn4xx(t *testing.T) {
+	app := &App{}
+	endpoint := &config.EndpointConfig{Name: "a"}
+	if !app.shouldFallbackOn4xx(endpoint) {
+		t.Fatal("expected 4xx fallback enabled by default")
+	}
+
+	app.config = map[string]interface{}{
+		"server": map[string]interface{}{"fallback_on_4xx": false},
+	}
+	if app.shouldFallbackOn4xx(endpoint) {
+		t.Error("expected global setting to disable 4xx fallback")
+	}
+
+	enabled := true
+	endpoint.FallbackOn4xx = &enabled
+	if !app.shouldFallbackOn4xx(endpoint) {
+		t.Error("expected endpoint override to take precedence over global setting")
+	}
+
+	app.config = nil
+	disabled := false
+	endpoint.FallbackOn4xx = &disabled
+	if app.shouldFallbackOn4xx(endpoint) {
+		t.Error("expected endpoint override to disable 4xx fallback")
+	}
+}
+
+func TestExtractOptionalBool(t *testing.T) {
+	cases := []struct {
+		raw  interface{}
+		want interface{}
+	}{
+		{nil, nil},
+		{"", nil},
+		{"inherit", nil},
+		{true, true},
+		{"false", false},
+		{float64(1), true},
+	}
+	for _, tc := range cases {
+		if got := extractOptionalBool(tc.raw); got != tc.want {
+			t.Errorf("extractOptionalBool(%v) = %v, want %v", tc.raw, got, tc.want)
+		}
+	}
+	if nullableBool(sql.NullBool{}) != nil || nullableBool(sql.NullBool{Bool: true, Valid: true}) != true {
+		t.Error("unexpected nullableBool result")
+	}
+}
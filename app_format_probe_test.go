@@ -0,0 +1,33 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"claude-code-codex-companion/internal/health"
+)
+
+func TestSummarizeFormatProbes(t *testing.T) {
+	probes := []health.FormatProbeResult{
+		{Format: "anthropic", Result: &health.HealthCheckResult{StatusCode: http.StatusOK, Duration: 100 * time.Millisecond}},
+		{Format: "openai", Result: &health.HealthCheckResult{StatusCode: http.StatusBadRequest, Duration: 50 * time.Millisecond}, Err: errors.New("bad request")},
+		{Format: "responses", Result: &health.HealthCheckResult{StatusCode: http.StatusOK, Duration: 300 * time.Millisecond}},
+	}
+	result, slowest := summarizeFormatProbes(probes)
+	if result.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected the failed probe to be reported, got status %d", result.StatusCode)
+	}
+	if slowest != 300*time.Millisecond {
+		t.Fatalf("expected the slowest probe latency, got %v", slowest)
+	}
+
+	result, slowest = summarizeFormatProbes(probes[:1])
+	if result.StatusCode != http.StatusOK || slowest != 100*time.Millisecond {
+		t.Fatalf("unexpected single probe summary: %d %v", result.StatusCode, slowest)
+	}
+	if result, slowest = summarizeFormatProbes(nil); result == nil || slowest != 0 {
+		t.Fatalf("expected an empty result without probes, got %v %v", result, slowest)
+	}
+}
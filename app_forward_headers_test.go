@@ -0,0 +1,85 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestHeaderForwardFilterDefaults(t *testing.T) {
+	app := &App{}
+
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	header.Set("Anthropic-Version", "2023-06-01")
+	header.Set("X-Stainless-Lang", "js")
+	header.Set("X-Stainless-Runtime-Version", "v20")
+	header.Set("Anthropic-Dangerous-Direct-Browser-Access", "true")
+
+	filtered := filterForwardHeaders(header, app.getHeaderForwardFilter())
+
+	if filtered.Get("Anthropic-Version") == "" {
+		t.Error("expected anthropic-version to be forwarded")
+	}
+	if filtered.Get("X-Stainless-Lang") != "" || filtered.Get("X-Stainless-Runtime-Version") != "" {
+		t.Error("expected x-stainless-* headers to be stripped by default")
+	}
+	if filtered.Get("Anthropic-Dangerous-Direct-Browser-Access") != "" {
+		t.Error("expected anthropic-dangerous-direct-browser-access to be stripped by default")
+	}
+}
+
+func TestHeaderForwardFilterConfigured(t *testing.T) {
+	app := &App{
+		config: map[string]interface{}{
+			"server": map[string]interface{}{
+				"forward_header_allowlist": []interface{}{"anthropic-*", "x-stainless-*", "user-agent"},
+				"forward_header_blocklist": []interface{}{"anthropic-beta"},
+			},
+		},
+	}
+
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	header.Set("Anthropic-Version", "2023-06-01")
+	header.Set("Anthropic-Beta", "tools-2024-04-04")
+	header.Set("X-Stainless-Lang", "js")
+	header.Set("X-Custom", "value")
+
+	filtered := filterForwardHeaders(header, app.getHeaderForwardFilter())
+
+	if filtered.Get("Content-Type") == "" {
+		t.Error("content-type must always be forwarded")
+	}
+	if filtered.Get("Anthropic-Version") == "" {
+		t.Error("expected allowlisted anthropic-version to be forwarded")
+	}
+	if filtered.Get("X-Stainless-Lang") == "" {
+		t.Error("explicit blocklist should replace the default one")
+	}
+	if filtered.Get("Anthropic-Beta") != "" {
+		t.Error("expected blocklisted anthropic-beta to be stripped")
+	}
+	if filtered.Get("X-Custom") != "" {
+		t.Error("expected headers outside the allowlist to be stripped")
+	}
+}
+
+func TestRequestFormatOverrideHeader(t *testing.T) {
+	app := &App{}
+
+	req, _ := http.NewRequest(http.MethodPost, "http://localhost/v1/chat/completions", nil)
+	req.Header.Set("X-CCCC-Request-Format", "Anthropic")
+
+	detection := app.detectRequestFormat(req, []byte(`{"model":"gpt-4o","messages":[]}`))
+	if normalizeRequestFormat(detection.Format) != "anthropic" {
+		t.Fatalf("expected header to force anthropic format, got %s", detection.Format)
+	}
+	if detection.DetectedBy != "header" || detection.Confidence != 1.0 {
+		t.Errorf("unexpected detection metadata: %+v", detection)
+	}
+
+	filtered := filterForwardHeaders(req.Header, app.getHeaderForwardFilter())
+	if filtered.Get("X-CCCC-Request-Format") != "" {
+		t.Error("expected proxy control headers to be stripped before forwarding")
+	}
+}
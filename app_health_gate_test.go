@@ -0,0 +1,75 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMinHealthyEndpointsGate(t *testing.T) {
+	db := newEndpointTestDB(t)
+	if _, err := db.Exec(`INSERT INTO endpoints (id, enabled, status) VALUES ('a', 1, 'healthy'), ('b', 1, 'unhealthy'), ('c', 0, 'healthy')`); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	app := &App{db: db}
+	if open, _, required, _ := app.healthGateStatus(); !open || required != 0 {
+		t.Fatalf("expected gate open by default, got open=%v required=%d", open, required)
+	}
+
+	app.config = map[string]interface{}{
+		"server": map[string]interface{}{"min_healthy_endpoints": float64(2)},
+	}
+	if open, _, _, reason := app.healthGateStatus(); open || !strings.Contains(reason, "still running") {
+		t.Fatalf("expected gate closed before the startup sweep, got open=%v reason=%q", open, reason)
+	}
+
+	app.healthGateSweepDone = true
+	req := httptest.NewRequest(http.MethodPost, "/v1/messages", strings.NewReader(`{}`))
+	rec := httptest.NewRecorder()
+	app.handleProxyRequest(rec, req)
+	if rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), "1 of the required 2") {
+		t.Fatalf("expected 503 with explanation, got %d %s", rec.Code, rec.Body.String())
+	}
+
+	status := app.GetServerStatus()
+	if status["health_gate_open"] != false || status["endpoints_healthy"] != 1 || status["min_healthy_endpoints"] != 2 {
+		t.Fatalf("unexpected server status: %v", status)
+	}
+
+	if _, err := db.Exec(`UPDATE endpoints SET status = 'healthy' WHERE id = 'b'`); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if open, _, _, _ := app.healthGateStatus(); !open {
+		t.Fatal("expected gate to open once enough endpoints are healthy")
+	}
+	// 闸门打开后保持打开，且不再查询端点计数
+	db.Exec(`UPDATE endpoints SET status = 'unhealthy'`)
+	if open, _, _, _ := app.healthGateStatus(); !open {
+		t.Fatal("expected gate to stay open after it has been satisfied")
+	}
+	db.Exec(`UPDATE endpoints SET status = 'healthy'`)
+	if open, healthy, _, _ := app.healthGateStatus(); !open || healthy != 0 {
+		t.Fatalf("expected an open gate to skip the healthy endpoint query, got open=%v healthy=%d", open, healthy)
+	}
+}
+
+func TestHealthSweepRunsOnce(t *testing.T) {
+	// 未启用闸门时不检测，也不会标记检测完成
+	app := &App{}
+	app.runStartupHealthSweep()
+	if app.healthGateSweepDone || app.healthGateSweepRunning {
+		t.Fatal("expected no sweep while the gate is disabled")
+	}
+
+	// 保存配置启用闸门时若已有一轮检测在运行，不再重复启动
+	app.config = map[string]interface{}{
+		"server": map[string]interface{}{"min_healthy_endpoints": float64(1)},
+	}
+	app.healthGateSweepRunning = true
+	app.runStartupHealthSweep()
+	if app.healthGateSweepDone || !app.healthGateSweepRunning {
+		t.Fatal("expected a concurrent sweep to return without touching the gate")
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"testing"
+)
+
+func TestHealthHistoryCapAndFlapping(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	if err := ensureHealthHistorySchema(db); err != nil {
+		t.Fatalf("ensure schema: %v", err)
+	}
+
+	total := healthHistoryLimitPerEndpoint + 5
+	for i := 0; i < total; i++ {
+		status := "healthy"
+		if i%2 == 1 {
+			status = "unhealthy"
+		}
+		if err := recordHealthHistory(db, "ep-1", "flappy", status, i, "", fmt.Sprintf("t%03d", i)); err != nil {
+			t.Fatalf("record: %v", err)
+		}
+	}
+	if err := recordHealthHistory(db, "ep-2", "stable", "healthy", 10, "", "t000"); err != nil {
+		t.Fatalf("record: %v", err)
+	}
+
+	entries, err := queryHealthHistory(db, "ep-1", 1000)
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	if len(entries) != healthHistoryLimitPerEndpoint {
+		t.Fatalf("expected history capped at %d, got %d", healthHistoryLimitPerEndpoint, len(entries))
+	}
+	if entries[0].Timestamp != fmt.Sprintf("t%03d", total-1) {
+		t.Fatalf("expected newest entry first, got %s", entries[0].Timestamp)
+	}
+
+	app := &App{db: db}
+	result := app.GetEndpointHealthHistory("ep-1", 10)
+	if result["success"] != true || result["count"] != 10 {
+		t.Fatalf("unexpected result: %v", result)
+	}
+	if result["transitions"] != 9 {
+		t.Fatalf("expected flapping endpoint to report 9 transitions, got %v", result["transitions"])
+	}
+
+	stable := app.GetEndpointHealthHistory("ep-2", 10)
+	if stable["transitions"] != 0 || stable["uptime_percent"] != 100.0 {
+		t.Fatalf("unexpected stable endpoint summary: %v", stable)
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import "testing"
+
+func TestComputeHMACSignature(t *testing.T) {
+	body := []byte("The quick brown fox jumps over the lazy dog")
+
+	cases := []struct {
+		algo string
+		want string
+	}{
+		{"", "f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8"},
+		{"sha256", "f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8"},
+		{"SHA1", "de7c9b85b8b78aa6bc8a7a36f70a90701c9db4d9"},
+	}
+	for _, tc := range cases {
+		got, err := computeHMACSignature(body, "key", tc.algo)
+		if err != nil {
+			t.Fatalf("algo %q: unexpected error: %v", tc.algo, err)
+		}
+		if got != tc.want {
+			t.Errorf("algo %q: expected %s, got %s", tc.algo, tc.want, got)
+		}
+	}
+
+	if _, err := computeHMACSignature(body, "key", "md5"); err == nil {
+		t.Error("expected error for unsupported algorithm")
+	}
+	if isValidHMACAlgo("md5") || !isValidHMACAlgo("sha512") || !isValidHMACAlgo("") {
+		t.Error("unexpected isValidHMACAlgo result")
+	}
+}
+
+func TestResolveSecretValue(t *testing.T) {
+	t.Setenv("CCCC_TEST_HMAC_SECRET", "from-env")
+
+	cases := map[string]string{
+		"plain-secret":                "plain-secret",
+		"env:CCCC_TEST_HMAC_SECRET":   "from-env",
+		"${CCCC_TEST_HMAC_SECRET}":    "from-env",
+		"env:CCCC_TEST_HMAC_MISSING":  "",
+		"  env:CCCC_TEST_HMAC_SECRET": "from-env",
+	}
+	for input, want := range cases {
+		if got := resolveSecretValue(input); got != want {
+			t.Errorf("resolveSecretValue(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
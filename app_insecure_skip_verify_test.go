@@ -0,0 +1,45 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"claude-code-codex-companion/internal/config"
+)
+
+func TestUpstreamClientInsecureSkipVerify(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	app := &App{}
+	strict := config.EndpointConfig{Name: "self-signed", URLOpenAI: server.URL}
+	client, err := app.getUpstreamClient(strict)
+	if err != nil {
+		t.Fatalf("getUpstreamClient failed: %v", err)
+	}
+	if resp, err := client.Get(server.URL); err == nil {
+		resp.Body.Close()
+		t.Fatal("expected TLS verification to reject the self-signed certificate by default")
+	}
+
+	insecure := strict
+	insecure.InsecureSkipVerify = true
+	insecureClient, err := app.getUpstreamClient(insecure)
+	if err != nil {
+		t.Fatalf("getUpstreamClient failed: %v", err)
+	}
+	if insecureClient == client {
+		t.Fatal("expected a new client after insecure_skip_verify changed")
+	}
+	resp, err := insecureClient.Get(server.URL)
+	if err != nil {
+		t.Fatalf("expected insecure_skip_verify to accept the self-signed certificate: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("unexpected status %d", resp.StatusCode)
+	}
+}
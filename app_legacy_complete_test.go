@@ -0,0 +1,37 @@
+package main
+
+import (
+	"testing"
+
+	"claude-code-codex-companion/internal/config"
+)
+
+func TestLegacyCompleteRouting(t *testing.T) {
+	app := &App{}
+	anthropic := config.EndpointConfig{Name: "anthropic", URLAnthropic: "https://api.anthropic.example"}
+	openAI := config.EndpointConfig{Name: "openai", URLOpenAI: "https://api.openai.example/base"}
+
+	target, err := app.buildTargetURL(&anthropic, "/v1/complete", "")
+	if err != nil || target != "https://api.anthropic.example/v1/complete" {
+		t.Fatalf("expected passthrough for Anthropic endpoint, got %q (%v)", target, err)
+	}
+	target, err = app.buildTargetURL(&openAI, "/v1/complete", "")
+	if err != nil || target != "https://api.openai.example/base/v1/chat/completions" {
+		t.Fatalf("expected chat completions path for OpenAI-only endpoint, got %q (%v)", target, err)
+	}
+
+	endpoints := []config.EndpointConfig{anthropic, openAI}
+	if got := filterLegacyCompleteEndpoints(endpoints, false); len(got) != 2 {
+		t.Errorf("expected both endpoints for non-streaming requests, got %d", len(got))
+	}
+	if got := filterLegacyCompleteEndpoints(endpoints, true); len(got) != 1 || got[0].Name != "anthropic" {
+		t.Errorf("expected only the Anthropic endpoint for streaming requests, got %v", got)
+	}
+	if got := filterLegacyCompleteEndpoints([]config.EndpointConfig{openAI}, true); len(got) != 0 {
+		t.Errorf("expected no compatible endpoint for streaming via OpenAI-only endpoint, got %v", got)
+	}
+
+	if !streamRequested([]byte(`{"prompt":"x","stream":true}`)) || streamRequested([]byte(`{"prompt":"x"}`)) {
+		t.Error("unexpected stream flag detection")
+	}
+}
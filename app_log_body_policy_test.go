@@ -0,0 +1,47 @@
+package main
+
+import (
+	"testing"
+
+	"claude-code-codex-companion/internal/logger"
+)
+
+func TestLogBodyPolicy(t *testing.T) {
+	app := &App{}
+	if policy := app.getLogBodyPolicy("codex"); policy.request != logBodyTruncated || policy.response != logBodyTruncated {
+		t.Fatalf("expected truncated defaults, got %+v", policy)
+	}
+
+	app.config = map[string]interface{}{
+		"logging": map[string]interface{}{
+			"log_response_body": "none",
+			"per_client": map[string]interface{}{
+				"codex":       map[string]interface{}{"body": "full"},
+				"claude_code": map[string]interface{}{"body": "none", "response_body": "truncated"},
+				"gemini":      map[string]interface{}{"body": "bogus"},
+			},
+		},
+	}
+
+	cases := map[string]logBodyPolicy{
+		"codex":       {request: logBodyFull, response: logBodyFull},
+		"claude_code": {request: logBodyNone, response: logBodyTruncated},
+		"gemini":      {request: logBodyTruncated, response: logBodyNone},
+		"unknown":     {request: logBodyTruncated, response: logBodyNone},
+	}
+	for clientType, want := range cases {
+		if got := app.getLogBodyPolicy(clientType); got != want {
+			t.Errorf("client %s: expected %+v, got %+v", clientType, want, got)
+		}
+	}
+
+	if logBodyLimit(logBodyFull) != 0 || logBodyLimit(logBodyTruncated) != healthLogPreviewLimit {
+		t.Error("unexpected body limits")
+	}
+
+	entry := &logger.RequestLog{RequestBody: "{}", OriginalRequestBody: "{}", ResponseBody: "ok", FinalResponseBody: "ok"}
+	stripLoggedRequestBodies(entry)
+	if entry.RequestBody != "" || entry.OriginalRequestBody != "" || entry.ResponseBody != "ok" || entry.FinalResponseBody != "ok" {
+		t.Fatalf("expected only request bodies stripped, got %+v", entry)
+	}
+}
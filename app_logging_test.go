@@ -2,8 +2,15 @@ package main
 
 import (
 	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"claude-code-codex-companion/internal/config"
 	"claude-code-codex-companion/internal/endpoint"
@@ -78,3 +85,78 @@ func TestLogEndpointTestResult(t *testing.T) {
 		t.Fatalf("expected exactly one log entry, got %d", count)
 	}
 }
+
+func TestExportLogsFlushesQueuedLogs(t *testing.T) {
+	requestLogger, err := logger.NewLogger(logger.LogConfig{Level: "info", LogRequestTypes: "all", LogDirectory: t.TempDir()})
+	if err != nil {
+		t.Fatalf("create logger: %v", err)
+	}
+	defer requestLogger.Close()
+
+	writer := logger.NewAsyncRequestWriter(requestLogger, 10)
+	defer writer.Close()
+	app := &App{requestLogger: requestLogger, requestLogWriter: writer}
+
+	writer.Enqueue(&logger.RequestLog{Timestamp: time.Now(), RequestID: "queued", Endpoint: "main", StatusCode: 200})
+	result := app.ExportLogs(map[string]interface{}{"format": "jsonl"})
+	if result["success"] != true || result["count"] != 1 {
+		t.Fatalf("expected the queued log to be exported, got %v", result)
+	}
+}
+
+func TestCappedExportBuffer(t *testing.T) {
+	buffer := &cappedExportBuffer{limit: 300}
+	logs := make([]*logger.RequestLog, 10)
+	for i := range logs {
+		logs[i] = &logger.RequestLog{Timestamp: time.Now(), RequestID: "req", Endpoint: "main", StatusCode: 200}
+	}
+	stream := func(fn func(*logger.RequestLog) error) error {
+		for _, log := range logs {
+			if err := fn(log); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if _, err := writeLogExport(buffer, "jsonl", stream); !errors.Is(err, errLogExportTooLarge) {
+		t.Fatalf("expected the in-memory export to be capped, got %v", err)
+	}
+	if buffer.Len() > 300 {
+		t.Fatalf("expected the buffer to stay within the limit, got %d bytes", buffer.Len())
+	}
+
+	buffer = &cappedExportBuffer{limit: 1 << 20}
+	if count, err := writeLogExport(buffer, "csv", stream); err != nil || count != len(logs) {
+		t.Fatalf("expected a small export to succeed, got %d %v", count, err)
+	}
+}
+
+func TestAddLogConcurrentWithProxyRequests(t *testing.T) {
+	// 并发代理请求与请求路径上的 addLog、内存日志读取同时进行，需在 -race 下无数据竞争且不丢日志
+	app := &App{configError: "unexpected end of JSON input"}
+
+	const workers = 8
+	const perWorker = 50
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for j := 0; j < perWorker; j++ {
+				rec := httptest.NewRecorder()
+				app.handleProxyRequest(rec, httptest.NewRequest(http.MethodPost, "/v1/messages", strings.NewReader(`{}`)))
+				app.addLog("info", fmt.Sprintf("worker %d request %d", worker, j))
+				app.GetRequestTrends("1h", "", "")
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	app.logsMu.Lock()
+	count := len(app.logs)
+	app.logsMu.Unlock()
+	if count != workers*perWorker {
+		t.Fatalf("expected %d log entries, got %d", workers*perWorker, count)
+	}
+}
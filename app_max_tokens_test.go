@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"claude-code-codex-companion/internal/config"
+)
+
+func TestApplyMaxTokensPolicy(t *testing.T) {
+	body := []byte(`{"model":"claude","messages":[]}`)
+	updated, note := applyMaxTokensPolicy(body, "max_tokens", 4096, 0)
+	if note == "" {
+		t.Fatal("expected a note when the default is injected")
+	}
+	var payload map[string]interface{}
+	json.Unmarshal(updated, &payload)
+	if payload["max_tokens"] != float64(4096) {
+		t.Fatalf("expected injected max_tokens=4096, got %v", payload["max_tokens"])
+	}
+
+	// 默认值超过上限时注入上限
+	updated, _ = applyMaxTokensPolicy(body, "max_output_tokens", 64000, 8192)
+	payload = nil
+	json.Unmarshal(updated, &payload)
+	if payload["max_output_tokens"] != float64(8192) || payload["max_tokens"] != nil {
+		t.Fatalf("expected max_output_tokens capped to 8192, got %v", payload)
+	}
+
+	// 客户端已携带其他字段名时不注入，只截断
+	updated, note = applyMaxTokensPolicy([]byte(`{"max_completion_tokens":200000}`), "max_tokens", 4096, 32000)
+	payload = nil
+	json.Unmarshal(updated, &payload)
+	if payload["max_completion_tokens"] != float64(32000) || payload["max_tokens"] != nil || note == "" {
+		t.Fatalf("expected max_completion_tokens capped without injection, got %v (%q)", payload, note)
+	}
+
+	// 未超过上限时请求体保持原样
+	original := []byte(`{"max_tokens":1024}`)
+	if updated, note := applyMaxTokensPolicy(original, "max_tokens", 4096, 32000); string(updated) != string(original) || note != "" {
+		t.Fatalf("expected body to be unchanged, got %s (%q)", updated, note)
+	}
+}
+
+func TestMaxTokensLimitsAndField(t *testing.T) {
+	app := &App{config: map[string]interface{}{
+		"server": map[string]interface{}{"default_max_tokens": float64(8192), "max_tokens_ceiling": "64000"},
+	}}
+
+	if defaultMax, ceiling := app.getMaxTokensLimits(&config.EndpointConfig{}); defaultMax != 8192 || ceiling != 64000 {
+		t.Fatalf("expected global limits, got %d/%d", defaultMax, ceiling)
+	}
+	endpoint := &config.EndpointConfig{DefaultMaxTokens: 2048}
+	if defaultMax, ceiling := app.getMaxTokensLimits(endpoint); defaultMax != 2048 || ceiling != 64000 {
+		t.Fatalf("expected endpoint default with global ceiling, got %d/%d", defaultMax, ceiling)
+	}
+
+	if field := resolveMaxTokensField(&config.EndpointConfig{}, "/responses"); field != "max_output_tokens" {
+		t.Fatalf("expected max_output_tokens for /responses, got %s", field)
+	}
+	if field := resolveMaxTokensField(&config.EndpointConfig{MaxTokensFieldName: "max_completion_tokens"}, "/v1/messages"); field != "max_completion_tokens" {
+		t.Fatalf("expected the endpoint field name to win, got %s", field)
+	}
+	if isValidMaxTokensFieldName("max_length") {
+		t.Fatal("expected max_length to be rejected")
+	}
+}
@@ -0,0 +1,17 @@
+package main
+
+import "testing"
+
+func TestIsModelOverrideHeaderEnabled(t *testing.T) {
+	app := &App{}
+	if app.isModelOverrideHeaderEnabled() {
+		t.Fatal("expected model override header disabled by default")
+	}
+
+	app.config = map[string]interface{}{
+		"server": map[string]interface{}{"allow_model_override_header": true},
+	}
+	if !app.isModelOverrideHeaderEnabled() {
+		t.Error("expected server.allow_model_override_header to enable the header")
+	}
+}
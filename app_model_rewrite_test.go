@@ -0,0 +1,17 @@
+package main
+
+import "testing"
+
+func TestPreserveOriginalModelFlag(t *testing.T) {
+	app := &App{}
+	if !app.isPreserveOriginalModelEnabled() {
+		t.Fatal("expected preserve_original_model to default to true")
+	}
+
+	app.config = map[string]interface{}{
+		"server": map[string]interface{}{"preserve_original_model": false},
+	}
+	if app.isPreserveOriginalModelEnabled() {
+		t.Fatal("expected preserve_original_model=false to opt out")
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"claude-code-codex-companion/internal/config"
+)
+
+func TestParseUpstreamModelList(t *testing.T) {
+	cases := map[string][]string{
+		`{"object":"list","data":[{"id":"gpt-4o"},{"id":"gpt-4o"},{"id":"o3"}]}`: {"gpt-4o", "o3"},
+		`{"data":[{"id":"claude-sonnet-4","type":"model"}],"has_more":false}`:    {"claude-sonnet-4"},
+		`{"models":[{"name":"models/gemini-2.5-pro"}]}`:                          {"gemini-2.5-pro"},
+	}
+	for body, expected := range cases {
+		models, err := parseUpstreamModelList([]byte(body))
+		if err != nil {
+			t.Fatalf("parse %s: %v", body, err)
+		}
+		if len(models) != len(expected) {
+			t.Fatalf("parse %s: expected %v, got %v", body, expected, models)
+		}
+		for i := range expected {
+			if models[i] != expected[i] {
+				t.Fatalf("parse %s: expected %v, got %v", body, expected, models)
+			}
+		}
+	}
+	if _, err := parseUpstreamModelList([]byte(`{"error":"nope"}`)); err == nil {
+		t.Fatal("expected an error for a response without a model list")
+	}
+}
+
+func TestAggregateModelsUsesUpstreamWithConfigFallback(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/models" || r.Header.Get("Authorization") != "Bearer live-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"object":"list","data":[{"id":"live-model"},{"id":"shared-model"}]}`))
+	}))
+	defer upstream.Close()
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer broken.Close()
+
+	db := newEndpointTestDB(t)
+	app := &App{db: db}
+	if _, err := db.Exec(`INSERT INTO endpoints (id, name, url_openai, auth_type, auth_value, enabled, priority,
+		model_rewrite_enabled, model_rewrite_rules) VALUES
+		('1', 'live', ?, 'auth_token', 'live-key', 1, 10, 0, ''),
+		('2', 'flaky', ?, 'auth_token', 'other', 1, 5, 1,
+			'[{"source_pattern":"gpt-*","target_model":"fallback-model"},{"source_pattern":"*","target_model":"shared-model"}]')`, upstream.URL, broken.URL); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	// 未配置 models_refresh_minutes：只使用配置推断的模型
+	data, err := app.aggregateModels()
+	if err != nil {
+		t.Fatalf("aggregateModels: %v", err)
+	}
+	if ids := modelIDs(data); len(ids) != 2 || ids[0] != "fallback-model" || ids[1] != "shared-model" {
+		t.Fatalf("unexpected config-derived models: %v", ids)
+	}
+
+	app.config = map[string]interface{}{"server": map[string]interface{}{"models_refresh_minutes": float64(5)}}
+	app.refreshEndpointModels()
+
+	recorder := httptest.NewRecorder()
+	app.handleModelsList(recorder, httptest.NewRequest(http.MethodGet, "/v1/models", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("unexpected status %d: %s", recorder.Code, recorder.Body.String())
+	}
+	var resp struct {
+		Object string                   `json:"object"`
+		Data   []map[string]interface{} `json:"data"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	ids := modelIDs(resp.Data)
+	expected := []string{"live-model", "shared-model", "fallback-model"}
+	if resp.Object != "list" || len(ids) != len(expected) {
+		t.Fatalf("unexpected aggregated models: %v", ids)
+	}
+	for i := range expected {
+		if ids[i] != expected[i] {
+			t.Fatalf("expected %v, got %v", expected, ids)
+		}
+	}
+	if resp.Data[1]["owned_by"] != "live" {
+		t.Fatalf("expected shared-model to be owned by the higher-priority endpoint, got %v", resp.Data[1]["owned_by"])
+	}
+}
+
+func TestFetchEndpointModelsUsesEndpointAuth(t *testing.T) {
+	var got http.Header
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		w.Write([]byte(`{"data":[{"id":"m"}]}`))
+	}))
+	defer upstream.Close()
+
+	app := &App{}
+	cases := []struct {
+		endpoint     config.EndpointConfig
+		header, want string
+	}{
+		{config.EndpointConfig{Name: "key", URLOpenAI: upstream.URL, AuthType: "api_key", AuthValue: "sk-key"}, "x-api-key", "sk-key"},
+		{config.EndpointConfig{Name: "oauth", URLOpenAI: upstream.URL, AuthType: "oauth", AuthValue: "stale",
+			OAuthConfig: &config.OAuthConfig{AccessToken: "access"}}, "Authorization", "Bearer access"},
+		{config.EndpointConfig{Name: "raw", URLOpenAI: upstream.URL, AuthType: "oauth", AuthValue: "Token raw"}, "Authorization", "Token raw"},
+	}
+	for _, tc := range cases {
+		if _, err := app.fetchEndpointModels(tc.endpoint); err != nil {
+			t.Fatalf("%s: fetch models: %v", tc.endpoint.Name, err)
+		}
+		if got.Get(tc.header) != tc.want {
+			t.Fatalf("%s: expected %s=%q, got %v", tc.endpoint.Name, tc.header, tc.want, got)
+		}
+	}
+}
+
+func modelIDs(data []map[string]interface{}) []string {
+	ids := make([]string, 0, len(data))
+	for _, item := range data {
+		id, _ := item["id"].(string)
+		ids = append(ids, id)
+	}
+	return ids
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGetNetworkRetryCount(t *testing.T) {
+	app := &App{}
+	if got := app.getNetworkRetryCount(); got != defaultNetworkRetryCount {
+		t.Fatalf("expected default retry count %d, got %d", defaultNetworkRetryCount, got)
+	}
+
+	cases := map[interface{}]int{
+		float64(3): 3,
+		"2":        2,
+		-1:         0,
+		100:        maxNetworkRetryCount,
+	}
+	for raw, expected := range cases {
+		app.config = map[string]interface{}{
+			"server": map[string]interface{}{"network_retry_count": raw},
+		}
+		if got := app.getNetworkRetryCount(); got != expected {
+			t.Errorf("network_retry_count=%v: expected %d, got %d", raw, expected, got)
+		}
+	}
+}
+
+func TestNetworkRetryDelayJitter(t *testing.T) {
+	for retry := 0; retry < 3; retry++ {
+		base := networkRetryBaseDelay << uint(retry)
+		for i := 0; i < 20; i++ {
+			delay := networkRetryDelay(retry)
+			if delay < base/2 || delay >= base/2+base {
+				t.Fatalf("retry %d: delay %v outside jitter window", retry, delay)
+			}
+		}
+	}
+}
+
+func TestDoWithNetworkRetryRetriesDroppedConnectionOnSameEndpoint(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&hits, 1) == 1 {
+			// 第一次连接直接断开，模拟连接重置
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err != nil {
+				t.Errorf("hijack: %v", err)
+				return
+			}
+			conn.Close()
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	body := []byte(`{"model":"m"}`)
+	req, err := http.NewRequest(http.MethodPost, server.URL, bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("new request: %v", err)
+	}
+	// 禁用连接复用，确保重试走新连接
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+
+	var retries []int
+	resp, _, err := doWithNetworkRetry(client, req, body, 0, 1, func(retry int, delay time.Duration, err error) {
+		retries = append(retries, retry)
+	})
+	if err != nil {
+		t.Fatalf("expected the retry on the same endpoint to succeed, got %v", err)
+	}
+	defer resp.Body.Close()
+
+	got, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || string(got) != string(body) {
+		t.Fatalf("expected the request body to be resent, got %d %q", resp.StatusCode, got)
+	}
+	if atomic.LoadInt32(&hits) != 2 || len(retries) != 1 || retries[0] != 0 {
+		t.Fatalf("expected one in-endpoint retry, got hits=%d retries=%v", hits, retries)
+	}
+
+	// 重试次数用尽时返回网络错误，由调用方计为一次端点失败
+	atomic.StoreInt32(&hits, 0)
+	req, _ = http.NewRequest(http.MethodPost, server.URL, bytes.NewReader(body))
+	if _, _, err := doWithNetworkRetry(client, req, body, 0, 0, nil); err == nil {
+		t.Fatal("expected an error without retries")
+	}
+	if atomic.LoadInt32(&hits) != 1 {
+		t.Fatalf("expected a single send without retries, got %d", hits)
+	}
+}
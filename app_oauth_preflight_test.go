@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"claude-code-codex-companion/internal/config"
+)
+
+func TestNeedsOAuthPreflight(t *testing.T) {
+	expiring := &config.OAuthConfig{
+		AccessToken:  "old",
+		RefreshToken: "refresh",
+		TokenURL:     "https://auth.example.com/token",
+		ExpiresAt:    time.Now().Add(2 * time.Minute).UnixMilli(),
+		AutoRefresh:  true,
+	}
+	if !needsOAuthPreflight(config.EndpointConfig{AuthType: "oauth", OAuthConfig: expiring}) {
+		t.Error("expected token expiring within the refresh window to need preflight")
+	}
+
+	fresh := *expiring
+	fresh.ExpiresAt = time.Now().Add(time.Hour).UnixMilli()
+	if needsOAuthPreflight(config.EndpointConfig{AuthType: "oauth", OAuthConfig: &fresh}) {
+		t.Error("expected fresh token to be left alone")
+	}
+
+	manual := *expiring
+	manual.AutoRefresh = false
+	if needsOAuthPreflight(config.EndpointConfig{AuthType: "oauth", OAuthConfig: &manual}) {
+		t.Error("expected auto_refresh=false to skip preflight")
+	}
+	if needsOAuthPreflight(config.EndpointConfig{AuthType: "api_key", OAuthConfig: expiring}) {
+		t.Error("expected non-oauth endpoints to be skipped")
+	}
+}
+
+func TestRefreshExpiringOAuthTokensPersists(t *testing.T) {
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token":"new-access","refresh_token":"new-refresh","expires_in":3600,"token_type":"Bearer"}`))
+	}))
+	defer tokenServer.Close()
+
+	db := newEndpointTestDB(t)
+	app := &App{db: db}
+
+	oauthConfig, err := serialiseOAuthConfig(map[string]interface{}{
+		"access_token":  "old-access",
+		"refresh_token": "old-refresh",
+		"token_url":     tokenServer.URL,
+		"expires_at":    time.Now().Add(time.Minute).UnixMilli(),
+		"auto_refresh":  true,
+	}, "test")
+	if err != nil {
+		t.Fatalf("serialise oauth config: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO endpoints (id, name, url_anthropic, endpoint_type, auth_type, auth_value, enabled, priority, oauth_config)
+		VALUES ('1', 'oauth-ep', 'https://api.example.com', 'anthropic', 'oauth', '', 1, 1, ?)`, oauthConfig); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	app.refreshExpiringOAuthTokens()
+
+	var stored string
+	if err := db.QueryRow(`SELECT oauth_config FROM endpoints WHERE name = 'oauth-ep'`).Scan(&stored); err != nil {
+		t.Fatalf("select: %v", err)
+	}
+	var refreshed config.OAuthConfig
+	if err := json.Unmarshal([]byte(stored), &refreshed); err != nil {
+		t.Fatalf("invalid stored oauth_config: %v", err)
+	}
+	if refreshed.AccessToken != "new-access" || refreshed.RefreshToken != "new-refresh" {
+		t.Fatalf("expected refreshed tokens to be persisted, got %+v", refreshed)
+	}
+	if time.Until(time.UnixMilli(refreshed.ExpiresAt)) < 50*time.Minute {
+		t.Fatalf("expected new expiry about an hour out, got %v", time.UnixMilli(refreshed.ExpiresAt))
+	}
+}
+
+func TestSerialiseOAuthConfigRejectsInvalid(t *testing.T) {
+	if encoded, err := serialiseOAuthConfig(nil, "test"); err != nil || encoded != "" {
+		t.Fatalf("expected nil to clear oauth_config, got %q, %v", encoded, err)
+	}
+	if _, err := serialiseOAuthConfig(map[string]interface{}{"access_token": "a"}, "test"); err == nil {
+		t.Fatal("expected missing refresh_token/token_url to be rejected")
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"claude-code-codex-companion/internal/config"
+)
+
+func TestApplyOrganizationHeaders(t *testing.T) {
+	header := http.Header{}
+	header.Set("OpenAI-Organization", "org-client")
+	header.Set("OpenAI-Project", "proj-client")
+
+	applyOrganizationHeaders(header, config.EndpointConfig{OpenAIOrganization: " org-endpoint "})
+	if header.Get("OpenAI-Organization") != "org-endpoint" {
+		t.Fatalf("expected endpoint organization to override the client value, got %q", header.Get("OpenAI-Organization"))
+	}
+	if header.Get("OpenAI-Project") != "proj-client" {
+		t.Fatalf("expected client project to pass through, got %q", header.Get("OpenAI-Project"))
+	}
+}
+
+func TestOrganizationHeadersForwardedAndMaskedInLogs(t *testing.T) {
+	app := &App{}
+	original := http.Header{}
+	original.Set("Content-Type", "application/json")
+	original.Set("Anthropic-Organization-Id", "0b9c4a7e-1111-2222-3333-444455556666")
+	original.Set("OpenAI-Project", "proj_client_abcdef")
+
+	filtered := filterForwardHeaders(original, app.getHeaderForwardFilter())
+	if filtered.Get("Anthropic-Organization-Id") == "" || filtered.Get("OpenAI-Project") == "" {
+		t.Fatalf("expected organization headers to be forwarded by default, got %v", filtered)
+	}
+
+	endpoint := config.EndpointConfig{OpenAIOrganization: "org-1234567890abcdef"}
+	headers := buildFinalRequestHeaders(filtered, &endpoint, "")
+	if got := headers["Openai-Organization"]; got != maskToken("org-1234567890abcdef") {
+		t.Fatalf("expected injected organization to be logged masked, got %q (%v)", got, headers)
+	}
+	if got := headers["Openai-Project"]; got != maskToken("proj_client_abcdef") {
+		t.Fatalf("expected client project to be logged masked, got %q", got)
+	}
+	if got := headers["Anthropic-Organization-Id"]; got == "0b9c4a7e-1111-2222-3333-444455556666" {
+		t.Fatal("expected anthropic-organization-id to be masked in logs")
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"testing"
+)
+
+func TestParameterOverridesRoundTrip(t *testing.T) {
+	raw := map[string]interface{}{
+		"frequency_penalty": nil,
+		"temperature":       0.2,
+		"stream":            false,
+		"user":              "alice",
+	}
+
+	serialised, err := serialiseParameterOverrides(raw, "{}")
+	if err != nil {
+		t.Fatalf("serialise failed: %v", err)
+	}
+
+	decoded := decodeParameterOverrides(sql.NullString{String: serialised, Valid: true})
+	if value, exists := decoded["frequency_penalty"]; !exists || value != nil {
+		t.Fatalf("expected null override to survive round trip, got %v (exists=%v)", value, exists)
+	}
+	if decoded["temperature"] != 0.2 || decoded["stream"] != false || decoded["user"] != "alice" {
+		t.Fatalf("typed values lost in round trip: %v", decoded)
+	}
+}
+
+func TestApplyParameterOverrides(t *testing.T) {
+	overrides := encodeParameterOverrides(map[string]interface{}{
+		"frequency_penalty": nil,
+		"temperature":       0.2,
+		"stream":            false,
+		"user":              "alice",
+	})
+
+	body := []byte(`{"model":"gpt-4o","frequency_penalty":0.5,"stream":true}`)
+	updated, modified := applyParameterOverrides(body, overrides)
+	if !modified {
+		t.Fatal("expected body to be modified")
+	}
+
+	var payload map[string]interface{}
+	if err := json.Unmarshal(updated, &payload); err != nil {
+		t.Fatalf("invalid body: %v", err)
+	}
+	if _, exists := payload["frequency_penalty"]; exists {
+		t.Error("expected frequency_penalty to be removed")
+	}
+	if payload["temperature"] != 0.2 {
+		t.Errorf("expected numeric temperature, got %#v", payload["temperature"])
+	}
+	if payload["stream"] != false {
+		t.Errorf("expected boolean stream, got %#v", payload["stream"])
+	}
+	if payload["user"] != "alice" {
+		t.Errorf("expected non-JSON string user to stay a string, got %#v", payload["user"])
+	}
+}
+
+func TestLegacyStringParameterOverrides(t *testing.T) {
+	// 前端提交的 Record<string,string>：空串删除字段，其余字符串按JSON解析，失败时作为字符串
+	serialised, err := serialiseParameterOverrides(map[string]interface{}{
+		"temperature":       "0.5",
+		"stream":            "false",
+		"frequency_penalty": "",
+		"user":              " alice ",
+		"metadata":          `{"team":"core"}`,
+	}, "{}")
+	if err != nil {
+		t.Fatalf("serialise failed: %v", err)
+	}
+
+	decoded := decodeParameterOverrides(sql.NullString{String: serialised, Valid: true})
+	if decoded["temperature"] != "0.5" || decoded["frequency_penalty"] != "" {
+		t.Fatalf("expected string overrides to be stored as strings, got %v", decoded)
+	}
+
+	body := []byte(`{"model":"gpt-4o","temperature":1,"frequency_penalty":0.5,"stream":true}`)
+	updated, modified := applyParameterOverrides(body, encodeParameterOverrides(decoded))
+	if !modified {
+		t.Fatal("expected body to be modified")
+	}
+
+	var payload map[string]interface{}
+	if err := json.Unmarshal(updated, &payload); err != nil {
+		t.Fatalf("invalid body: %v", err)
+	}
+	if payload["temperature"] != 0.5 || payload["stream"] != false {
+		t.Errorf("expected JSON-looking strings to be parsed, got temperature=%#v stream=%#v", payload["temperature"], payload["stream"])
+	}
+	if _, exists := payload["frequency_penalty"]; exists {
+		t.Error("expected empty string to remove frequency_penalty")
+	}
+	if payload["user"] != "alice" {
+		t.Errorf("expected plain string to be trimmed and kept, got %#v", payload["user"])
+	}
+	if metadata, ok := payload["metadata"].(map[string]interface{}); !ok || metadata["team"] != "core" {
+		t.Errorf("expected JSON object string to be parsed, got %#v", payload["metadata"])
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"claude-code-codex-companion/internal/config"
+)
+
+func TestPingHostReportsUntrustedTLS(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	result := pingHost(server.URL, config.EndpointConfig{Name: "tls"}, 2*time.Second)
+	if result["dns_resolved"] != true || result["reachable"] != true {
+		t.Fatalf("expected host to resolve and be reachable, got %v", result)
+	}
+	if result["tls_valid"] != false {
+		t.Fatalf("expected the self-signed certificate to be reported invalid, got %v", result)
+	}
+}
+
+func TestPingHostUsesEndpointProxy(t *testing.T) {
+	upstream := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer upstream.Close()
+	upstreamAddr := upstream.Listener.Addr().String()
+
+	// CONNECT 代理：无论请求哪个主机都转发到测试服务器
+	var connects int32
+	proxyServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		atomic.AddInt32(&connects, 1)
+		target, err := net.Dial("tcp", upstreamAddr)
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			target.Close()
+			return
+		}
+		conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+		go func() {
+			io.Copy(target, conn)
+			target.Close()
+		}()
+		io.Copy(conn, target)
+		conn.Close()
+	}))
+	defer proxyServer.Close()
+	proxyURL, _ := url.Parse(proxyServer.URL)
+
+	// .invalid 主机在本地无法解析，只有经代理才能连通
+	endpoint := config.EndpointConfig{
+		Name:  "proxied",
+		Proxy: &config.ProxyConfig{Type: "http", Address: proxyURL.Host},
+	}
+	result := pingHost("https://upstream.invalid", endpoint, 2*time.Second)
+	if result["reachable"] != true || result["tls_valid"] != false {
+		t.Fatalf("expected the host to be reached through the proxy, got %v", result)
+	}
+	if result["dns_resolved"] != false || result["via_proxy"] != "http://"+proxyURL.Host {
+		t.Fatalf("expected local DNS to be skipped when a proxy is set, got %v", result)
+	}
+	if atomic.LoadInt32(&connects) == 0 {
+		t.Fatal("expected the request to go through the proxy")
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestQueryEndpointQuickStats(t *testing.T) {
+	db := newEndpointTestDB(t)
+
+	app := &App{db: db}
+	if stats := app.GetQuickStats(); stats["fastest_endpoint"] != "" || stats["unhealthy_endpoints"] != 0 || stats["success_rate"] != nil {
+		t.Fatalf("unexpected stats for an empty table: %v", stats)
+	}
+
+	if _, err := db.Exec(`INSERT INTO endpoints (id, name, enabled, status, response_time, auto_disabled) VALUES
+		('1', 'slow', 1, 'healthy', 900, 0),
+		('2', 'fast', 1, 'healthy', 120, 0),
+		('3', 'untested', 1, 'healthy', 0, 0),
+		('4', 'disabled-fast', 0, 'healthy', 10, 0),
+		('5', 'broken', 1, 'unhealthy', 50, 0),
+		('6', 'auto-disabled', 0, 'unhealthy', 0, 1),
+		('7', 'manually-disabled', 0, 'unhealthy', 0, 0)`); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	stats := app.GetQuickStats()
+	if stats["success"] != true {
+		t.Fatalf("GetQuickStats failed: %v", stats)
+	}
+	if stats["fastest_endpoint"] != "fast" || stats["fastest_response_ms"] != int64(120) {
+		t.Fatalf("unexpected fastest endpoint: %v (%v ms)", stats["fastest_endpoint"], stats["fastest_response_ms"])
+	}
+	if stats["unhealthy_endpoints"] != 2 {
+		t.Fatalf("expected 2 unhealthy endpoints, got %v", stats["unhealthy_endpoints"])
+	}
+}
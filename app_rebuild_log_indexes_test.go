@@ -0,0 +1,56 @@
+package main
+
+import (
+	"database/sql"
+	"testing"
+
+	"claude-code-codex-companion/internal/logger"
+)
+
+func TestRebuildLogIndexes(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(`CREATE TABLE request_logs (
+		id INTEGER PRIMARY KEY,
+		timestamp DATETIME,
+		request_id TEXT,
+		endpoint TEXT,
+		model TEXT,
+		error TEXT,
+		status_code INTEGER,
+		client_type TEXT,
+		request_format TEXT,
+		format_converted BOOLEAN
+	)`); err != nil {
+		t.Fatalf("create table: %v", err)
+	}
+	// 模拟旧库：只有部分索引
+	if _, err := db.Exec("CREATE INDEX idx_request_logs_pagination_opt ON request_logs(timestamp)"); err != nil {
+		t.Fatalf("create index: %v", err)
+	}
+
+	definitions := logger.RequestLogIndexes()
+	result := (&App{}).rebuildRequestLogIndexes(db)
+	if result["success"] != true {
+		t.Fatalf("rebuildRequestLogIndexes failed: %v", result)
+	}
+	if indexes := result["indexes"].([]map[string]interface{}); len(indexes) != len(definitions) {
+		t.Fatalf("expected %d index results, got %v", len(definitions), indexes)
+	}
+
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'index' AND tbl_name = 'request_logs' AND name LIKE 'idx_%'").Scan(&count); err != nil {
+		t.Fatalf("count indexes: %v", err)
+	}
+	if count != len(definitions) {
+		t.Errorf("expected %d indexes after rebuild, got %d", len(definitions), count)
+	}
+
+	if result := (&App{}).RebuildLogIndexes(); result["success"] != false {
+		t.Errorf("expected failure without database, got %v", result)
+	}
+}
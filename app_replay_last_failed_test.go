@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"claude-code-codex-companion/internal/logger"
+)
+
+func TestFindLastFailedRequest(t *testing.T) {
+	requestLogger, err := logger.NewLogger(logger.LogConfig{Level: "info", LogRequestTypes: "all", LogDirectory: t.TempDir()})
+	if err != nil {
+		t.Fatalf("create logger: %v", err)
+	}
+	defer requestLogger.Close()
+
+	if log, err := findLastFailedRequest(requestLogger); err != nil || log != nil {
+		t.Fatalf("expected no candidate in an empty log, got %v (%v)", log, err)
+	}
+
+	now := time.Now()
+	body := `{"model":"claude-sonnet-4","messages":[]}`
+	for _, entry := range []struct {
+		requestID string
+		age       time.Duration
+		status    int
+		body      string
+		size      int
+	}{
+		{"req-old-failure", 5 * time.Minute, 502, body, len(body)},
+		{"req-recovered", 3 * time.Minute, 500, body, len(body)},
+		{"req-recovered", 3*time.Minute - time.Second, 200, body, len(body)},
+		{"req-truncated", 2 * time.Minute, 500, body[:10], len(body)},
+		{"req-unsampled", time.Minute, 500, "", len(body)},
+	} {
+		requestLogger.LogRequest(&logger.RequestLog{
+			Timestamp:           now.Add(-entry.age),
+			RequestID:           entry.requestID,
+			Endpoint:            "primary",
+			Method:              "POST",
+			Path:                "/v1/messages",
+			StatusCode:          entry.status,
+			RequestBodySize:     entry.size,
+			OriginalRequestBody: entry.body,
+		})
+	}
+
+	log, err := findLastFailedRequest(requestLogger)
+	if err != nil {
+		t.Fatalf("findLastFailedRequest: %v", err)
+	}
+	if log == nil || log.RequestID != "req-old-failure" {
+		t.Fatalf("expected req-old-failure to be the replay candidate, got %+v", log)
+	}
+}
+
+func TestReplayLastFailedWithoutCandidates(t *testing.T) {
+	app := &App{}
+	if result := app.ReplayLastFailed(""); result["success"] != false {
+		t.Fatalf("expected failure without a request logger, got %v", result)
+	}
+
+	requestLogger, err := logger.NewLogger(logger.LogConfig{Level: "info", LogRequestTypes: "all", LogDirectory: t.TempDir()})
+	if err != nil {
+		t.Fatalf("create logger: %v", err)
+	}
+	defer requestLogger.Close()
+	app.requestLogger = requestLogger
+
+	if result := app.ReplayLastFailed(""); result["success"] != false || result["request_id"] != nil {
+		t.Fatalf("expected no replay when nothing failed, got %v", result)
+	}
+}
+
+func TestReplayEndpointFromContext(t *testing.T) {
+	if name := replayEndpointFromContext(context.Background()); name != "" {
+		t.Fatalf("expected no pinned endpoint, got %q", name)
+	}
+	ctx := context.WithValue(context.Background(), replayEndpointKey{}, "primary")
+	if name := replayEndpointFromContext(ctx); name != "primary" {
+		t.Fatalf("expected pinned endpoint primary, got %q", name)
+	}
+}
+
+func TestQueryPinnedEndpointMatchesNameLoosely(t *testing.T) {
+	db := newEndpointTestDB(t)
+	app := &App{db: db}
+	if _, err := db.Exec(`INSERT INTO endpoints (id, name, url_anthropic, enabled, priority) VALUES
+		('1', 'Anthropic-Primary', 'https://a.example.com', 0, 1), ('2', 'anthropic-backup', 'https://b.example.com', 1, 2)`); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	// 日志中记录的名称大小写不同，且固定端点不受启用状态限制
+	endpoints, err := app.queryPinnedEndpoint("anthropic-primary")
+	if err != nil || len(endpoints) != 1 || endpoints[0].Name != "Anthropic-Primary" {
+		t.Fatalf("expected case-insensitive match, got %+v (%v)", endpoints, err)
+	}
+	if endpoints, err = app.queryPinnedEndpoint("anthropic-back"); err != nil || len(endpoints) != 1 || endpoints[0].Name != "anthropic-backup" {
+		t.Fatalf("expected unique prefix match, got %+v (%v)", endpoints, err)
+	}
+	if _, err := app.queryPinnedEndpoint("anthropic"); err == nil || !strings.Contains(err.Error(), "ambiguous") {
+		t.Fatalf("expected ambiguous match to list candidates, got %v", err)
+	}
+	if _, err := app.queryPinnedEndpoint("missing"); err == nil {
+		t.Fatal("expected unknown endpoint to fail")
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReserveRequestBudgetSlidingWindow(t *testing.T) {
+	app := &App{}
+	start := time.Now()
+
+	for i := 0; i < 3; i++ {
+		if !app.reserveRequestBudget("paid", 3, start.Add(time.Duration(i)*10*time.Second)) {
+			t.Fatalf("request %d should fit in the budget", i)
+		}
+	}
+	if app.reserveRequestBudget("paid", 3, start.Add(30*time.Second)) {
+		t.Fatalf("fourth request within the minute should be rejected")
+	}
+
+	remaining, resetIn := app.requestBudgetStatus("paid", 3, start.Add(30*time.Second))
+	if remaining != 0 || resetIn != 30*time.Second {
+		t.Fatalf("unexpected budget status: remaining=%d resetIn=%v", remaining, resetIn)
+	}
+
+	// 第一次请求滑出窗口后恢复一个额度
+	later := start.Add(61 * time.Second)
+	if remaining, _ := app.requestBudgetStatus("paid", 3, later); remaining != 1 {
+		t.Fatalf("expected one request to roll over, got remaining=%d", remaining)
+	}
+	if !app.reserveRequestBudget("paid", 3, later) {
+		t.Fatalf("request should be allowed after the window rolls over")
+	}
+}
+
+func TestReserveRequestBudgetUnlimited(t *testing.T) {
+	app := &App{}
+	for i := 0; i < 100; i++ {
+		if !app.reserveRequestBudget("free", 0, time.Now()) {
+			t.Fatalf("limit 0 must not restrict requests")
+		}
+	}
+	if len(app.requestBudgets) != 0 {
+		t.Fatalf("unlimited endpoints should not be tracked")
+	}
+	if remaining, _ := app.requestBudgetStatus("fresh", 5, time.Now()); remaining != 5 {
+		t.Fatalf("untracked endpoint should report its full budget, got %d", remaining)
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"claude-code-codex-companion/internal/logger"
+)
+
+func TestWriteRequestLogIsFlushedOnCleanup(t *testing.T) {
+	requestLogger, err := logger.NewLogger(logger.LogConfig{Level: "info", LogRequestTypes: "all", LogDirectory: t.TempDir()})
+	if err != nil {
+		t.Fatalf("create logger: %v", err)
+	}
+	defer requestLogger.Close()
+
+	app := &App{requestLogger: requestLogger}
+	for i := 0; i < 20; i++ {
+		app.writeRequestLog(&logger.RequestLog{RequestID: fmt.Sprintf("req_%d", i), Endpoint: "ep", StatusCode: 200})
+	}
+	app.closeRequestLogWriter()
+
+	_, total, err := requestLogger.GetLogs(1, 0, false)
+	if err != nil {
+		t.Fatalf("GetLogs: %v", err)
+	}
+	if total != 20 {
+		t.Fatalf("expected all queued logs to be written on shutdown, got %d", total)
+	}
+	if dropped := app.droppedRequestLogs(); dropped != 0 {
+		t.Fatalf("expected no dropped logs, got %d", dropped)
+	}
+}
+
+func TestGetLogsSeesQueuedRequestLogs(t *testing.T) {
+	requestLogger, err := logger.NewLogger(logger.LogConfig{Level: "info", LogRequestTypes: "all", LogDirectory: t.TempDir()})
+	if err != nil {
+		t.Fatalf("create logger: %v", err)
+	}
+	defer requestLogger.Close()
+
+	app := &App{requestLogger: requestLogger}
+	defer app.closeRequestLogWriter()
+	for i := 0; i < 5; i++ {
+		app.writeRequestLog(&logger.RequestLog{RequestID: fmt.Sprintf("req_%d", i), Endpoint: "ep", StatusCode: 500})
+	}
+
+	// 读取前先等待写入队列落库，不应漏掉仍在队列中的日志
+	result := app.GetLogs(map[string]interface{}{})
+	if result["success"] != true || result["total"] != 5 {
+		t.Fatalf("expected queued logs to be visible, got success=%v total=%v", result["success"], result["total"])
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRequestQueueFIFOHandoff(t *testing.T) {
+	queue := newRequestQueue(1, 2)
+	if err := queue.acquire(context.Background(), time.Second); err != nil {
+		t.Fatalf("first acquire failed: %v", err)
+	}
+
+	order := make(chan int, 2)
+	for i := 1; i <= 2; i++ {
+		go func(id int) {
+			if err := queue.acquire(context.Background(), time.Second); err != nil {
+				t.Errorf("waiter %d failed: %v", id, err)
+				return
+			}
+			order <- id
+			queue.release()
+		}(i)
+		// 保证等待者按顺序入队
+		for {
+			queue.mu.Lock()
+			queued := queue.waiters.Len()
+			queue.mu.Unlock()
+			if queued == i {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	if err := queue.acquire(context.Background(), time.Second); !errors.Is(err, errRequestQueueFull) {
+		t.Fatalf("expected full queue error, got %v", err)
+	}
+
+	queue.release()
+	if first, second := <-order, <-order; first != 1 || second != 2 {
+		t.Fatalf("expected FIFO order 1,2 got %d,%d", first, second)
+	}
+}
+
+func TestRequestQueueTimeout(t *testing.T) {
+	queue := newRequestQueue(1, 4)
+	if err := queue.acquire(context.Background(), time.Second); err != nil {
+		t.Fatalf("first acquire failed: %v", err)
+	}
+	if err := queue.acquire(context.Background(), 10*time.Millisecond); !errors.Is(err, errRequestQueueTimeout) {
+		t.Fatalf("expected timeout error, got %v", err)
+	}
+	if queue.waiters.Len() != 0 {
+		t.Fatalf("expected timed out waiter to be removed, got %d", queue.waiters.Len())
+	}
+
+	queue.release()
+	if err := queue.acquire(context.Background(), 10*time.Millisecond); err != nil {
+		t.Fatalf("expected slot to be free after release, got %v", err)
+	}
+}
+
+func TestAcquireRequestQueueSlotDisabledByDefault(t *testing.T) {
+	app := &App{}
+	release, err := app.acquireRequestQueueSlot(context.Background(), "ep")
+	if err != nil {
+		t.Fatalf("expected no error when queue disabled, got %v", err)
+	}
+	release()
+	if len(app.requestQueues) != 0 {
+		t.Fatal("expected no queue to be created when disabled")
+	}
+
+	app.config = map[string]interface{}{
+		"server": map[string]interface{}{
+			"request_queue_concurrency": float64(1),
+			"request_queue_depth":       float64(0),
+			"request_queue_max_wait_ms": float64(50),
+		},
+	}
+	release, err = app.acquireRequestQueueSlot(context.Background(), "ep")
+	if err != nil {
+		t.Fatalf("first acquire failed: %v", err)
+	}
+	if _, err := app.acquireRequestQueueSlot(context.Background(), "ep"); !errors.Is(err, errRequestQueueFull) {
+		t.Fatalf("expected full error with zero depth, got %v", err)
+	}
+	release()
+	release()
+	if _, err := app.acquireRequestQueueSlot(context.Background(), "ep"); err != nil {
+		t.Fatalf("expected slot after release, got %v", err)
+	}
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"testing"
+
+	"claude-code-codex-companion/internal/config"
+)
+
+func TestPreferTaggedEndpoints(t *testing.T) {
+	endpoints := []config.EndpointConfig{
+		{Name: "primary"},
+		{Name: "interactive", Tags: []string{"interactive"}},
+		{Name: "ci-a", Tags: []string{"CI"}},
+		{Name: "ci-b", Tags: []string{"batch", "ci"}},
+	}
+
+	ordered := preferTaggedEndpoints(endpoints, []string{"ci"})
+	names := make([]string, 0, len(ordered))
+	for _, ep := range ordered {
+		names = append(names, ep.Name)
+	}
+	expected := []string{"ci-a", "ci-b", "primary", "interactive"}
+	for i := range expected {
+		if names[i] != expected[i] {
+			t.Fatalf("expected order %v, got %v", expected, names)
+		}
+	}
+}
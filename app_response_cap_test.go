@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestReadResponseBodyCapped(t *testing.T) {
+	data, err := readResponseBodyCapped(strings.NewReader("0123456789"), 0)
+	if err != nil || string(data) != "0123456789" {
+		t.Fatalf("expected unlimited read, got %q (err=%v)", data, err)
+	}
+
+	data, err = readResponseBodyCapped(strings.NewReader("0123456789"), 10)
+	if err != nil || len(data) != 10 {
+		t.Fatalf("expected body at the limit to pass, got %d bytes (err=%v)", len(data), err)
+	}
+
+	data, err = readResponseBodyCapped(strings.NewReader("0123456789"), 4)
+	if !errors.Is(err, errResponseBodyTooLarge) || string(data) != "0123" {
+		t.Fatalf("expected capped body, got %q (err=%v)", data, err)
+	}
+}
+
+func TestDecompressGzipCapped(t *testing.T) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write(bytes.Repeat([]byte("a"), 1024))
+	gz.Close()
+
+	if _, err := decompressGzipCapped(buf.Bytes(), 100); !errors.Is(err, errResponseBodyTooLarge) {
+		t.Fatalf("expected decompressed size to be capped, got %v", err)
+	}
+	if data, err := decompressGzipCapped(buf.Bytes(), 0); err != nil || len(data) != 1024 {
+		t.Fatalf("expected full decompression, got %d bytes (err=%v)", len(data), err)
+	}
+}
+
+func TestTrimToLastSSEEvent(t *testing.T) {
+	body := []byte("data: {\"a\":1}\n\ndata: {\"b\":")
+	if got := string(trimToLastSSEEvent(body)); got != "data: {\"a\":1}\n\n" {
+		t.Fatalf("unexpected trimmed stream: %q", got)
+	}
+	if got := trimToLastSSEEvent([]byte("data: partial")); len(got) != 0 {
+		t.Fatalf("expected empty stream without a complete event, got %q", got)
+	}
+}
+
+func TestGetMaxResponseBodyBytes(t *testing.T) {
+	app := &App{}
+	if got := app.getMaxResponseBodyBytes(); got != 0 {
+		t.Fatalf("expected unlimited by default, got %d", got)
+	}
+	app.config = map[string]interface{}{
+		"server": map[string]interface{}{"max_response_body_bytes": float64(1 << 20)},
+	}
+	if got := app.getMaxResponseBodyBytes(); got != 1<<20 {
+		t.Fatalf("expected configured limit, got %d", got)
+	}
+}
+
+func TestDecodeGzipBodyTruncated(t *testing.T) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write([]byte(`{"id":"msg_1","type":"message","content":[{"type":"text","text":"` + strings.Repeat("hello ", 200) + `"}]}`))
+	gz.Close()
+	full := buf.Bytes()
+
+	if data, err := decodeGzipBody(full, 0); err != nil || !bytes.HasPrefix(data, []byte(`{"id":"msg_1"`)) {
+		t.Fatalf("expected complete gzip body to decode, got %q (err=%v)", data, err)
+	}
+
+	truncated := full[:len(full)/2]
+	data, err := decodeGzipBody(truncated, 0)
+	if !errors.Is(err, errGzipDecompressFailed) {
+		t.Fatalf("expected truncated gzip body to report a decompression failure, got %v", err)
+	}
+	if !bytes.Equal(data, truncated) {
+		t.Fatal("expected raw bytes to be returned untouched on failure")
+	}
+
+	plain := []byte(`{"ok":true}`)
+	if data, err := decodeGzipBody(plain, 0); err != nil || !bytes.Equal(data, plain) {
+		t.Fatalf("expected non-gzip body to pass through, got %q (err=%v)", data, err)
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestParseHeaderOverrides(t *testing.T) {
+	overrides, err := parseHeaderOverrides(`{"x-served-by":"cccc","anthropic-ratelimit-requests-remaining":null," X-Debug ":" "}`)
+	if err != nil {
+		t.Fatalf("parseHeaderOverrides failed: %v", err)
+	}
+	expected := map[string]string{
+		"X-Served-By":                            "cccc",
+		"Anthropic-Ratelimit-Requests-Remaining": "",
+		"X-Debug":                                "",
+	}
+	if len(overrides) != len(expected) {
+		t.Fatalf("unexpected overrides: %v", overrides)
+	}
+	for name, value := range expected {
+		if got, ok := overrides[name]; !ok || got != value {
+			t.Fatalf("expected %s=%q, got %v", name, value, overrides)
+		}
+	}
+
+	for _, invalid := range []interface{}{
+		map[string]interface{}{"X-Count": float64(1)},
+		map[string]interface{}{"Bad Name": "x"},
+		map[string]interface{}{"X-Injected": "a\r\nSet-Cookie: b"},
+		`not json`,
+	} {
+		if _, err := parseHeaderOverrides(invalid); err == nil {
+			t.Errorf("expected %v to be rejected", invalid)
+		}
+	}
+}
+
+func TestApplyResponseHeaderOverrides(t *testing.T) {
+	header := http.Header{}
+	header.Set("X-Ratelimit-Remaining-Requests", "42")
+	header.Set("Openai-Processing-Ms", "120")
+	header.Set("Content-Length", "10")
+
+	applyResponseHeaderOverrides(header, map[string]string{
+		"X-Served-By":          "cccc",
+		"Openai-Processing-Ms": "",
+		"Content-Length":       "999",
+	})
+
+	if header.Get("X-Served-By") != "cccc" {
+		t.Fatalf("expected X-Served-By to be set, got %v", header)
+	}
+	if _, exists := header["Openai-Processing-Ms"]; exists {
+		t.Fatalf("expected an empty override to delete the header, got %v", header)
+	}
+	if header.Get("X-Ratelimit-Remaining-Requests") != "42" {
+		t.Fatalf("expected unrelated headers to be kept, got %v", header)
+	}
+	if header.Get("Content-Length") != "10" {
+		t.Fatalf("expected Content-Length to be protected, got %q", header.Get("Content-Length"))
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWriteProxyErrorForCodexClients(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/responses", nil)
+
+	rec := httptest.NewRecorder()
+	writeProxyError(rec, req, []byte(`{"model":"gpt-5"}`), http.StatusBadGateway, "proxy_forward_failed", "boom")
+	var payload struct {
+		Error struct {
+			Type    string `json:"type"`
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("invalid JSON error: %v", err)
+	}
+	if rec.Code != http.StatusBadGateway || payload.Error.Type != "server_error" || payload.Error.Code != "proxy_forward_failed" || payload.Error.Message != "boom" {
+		t.Fatalf("unexpected non-streaming error: %d %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	writeProxyError(rec, req, []byte(`{"model":"gpt-5","stream":true}`), http.StatusServiceUnavailable, "no_available_endpoints", "No available endpoints")
+	body := rec.Body.String()
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("expected SSE response, got %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if !strings.HasPrefix(body, "event: response.failed\n") || !strings.Contains(body, `"status":"failed"`) || !strings.Contains(body, `"message":"No available endpoints"`) {
+		t.Fatalf("unexpected streaming error: %s", body)
+	}
+
+	rec = httptest.NewRecorder()
+	writeProxyError(rec, httptest.NewRequest(http.MethodPost, "/v1/messages", nil), nil, http.StatusBadGateway, "proxy_forward_failed", "boom")
+	if !strings.Contains(rec.Body.String(), `"success":false`) {
+		t.Fatalf("expected generic error shape for non-Responses requests, got %s", rec.Body.String())
+	}
+
+	// Chat Completions 请求同样被检测为 codex 客户端，但不应收到 response.failed 事件
+	rec = httptest.NewRecorder()
+	writeProxyError(rec, httptest.NewRequest(http.MethodPost, "/chat/completions", nil), []byte(`{"model":"gpt-5","stream":true}`), http.StatusBadGateway, "proxy_forward_failed", "boom")
+	if rec.Code != http.StatusBadGateway || strings.Contains(rec.Body.String(), "response.failed") || !strings.Contains(rec.Body.String(), `"success":false`) {
+		t.Fatalf("expected generic error for chat completions, got %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestUpstreamErrorMessage(t *testing.T) {
+	cases := map[string]string{
+		`{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`: "Overloaded",
+		`{"success":false,"error":{"code":"response_too_large","message":"too big"}}`: "too big",
+		`{"error":"rate limited"}`:  "rate limited",
+		`{"message":"bad gateway"}`: "bad gateway",
+		"  upstream exploded\n":     "upstream exploded",
+	}
+	for body, want := range cases {
+		if got := upstreamErrorMessage([]byte(body)); got != want {
+			t.Fatalf("upstreamErrorMessage(%q) = %q, want %q", body, got, want)
+		}
+	}
+
+	if got := errorBodyContentType([]byte(`{"error":"x"}`)); got != "application/json" {
+		t.Fatalf("expected JSON content type, got %q", got)
+	}
+	if got := errorBodyContentType([]byte("oops")); !strings.HasPrefix(got, "text/plain") {
+		t.Fatalf("expected plain text content type, got %q", got)
+	}
+}
@@ -0,0 +1,31 @@
+package main
+
+import "testing"
+
+func TestGetRetryStatusPolicy(t *testing.T) {
+	app := &App{}
+	if policy := app.getRetryStatusPolicy(); policy.IsTerminalStatus(400) {
+		t.Fatal("expected all error statuses to be retriable without config")
+	}
+
+	app.config = map[string]interface{}{
+		"retry": map[string]interface{}{
+			"retriable_statuses": []interface{}{float64(429), float64(500), "502", float64(503), float64(504)},
+			"terminal_statuses":  []interface{}{float64(400)},
+		},
+	}
+	policy := app.getRetryStatusPolicy()
+	if !policy.IsTerminalStatus(400) || policy.IsTerminalStatus(502) || !policy.IsTerminalStatus(404) {
+		t.Fatalf("unexpected policy: %+v", policy)
+	}
+
+	app.config = map[string]interface{}{
+		"retry": map[string]interface{}{
+			"retriable_statuses": []interface{}{float64(429)},
+			"terminal_statuses":  []interface{}{float64(429)},
+		},
+	}
+	if policy := app.getRetryStatusPolicy(); policy.IsTerminalStatus(429) || policy.IsTerminalStatus(400) {
+		t.Fatalf("expected invalid config to fall back to the default policy, got %+v", policy)
+	}
+}
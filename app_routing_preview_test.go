@@ -0,0 +1,138 @@
+package main
+
+import (
+	"testing"
+
+	"claude-code-codex-companion/internal/config"
+)
+
+func TestPreviewRoutingOrder(t *testing.T) {
+	db := newEndpointTestDB(t)
+	app := &App{db: db}
+	rows := []struct {
+		name, anthropic, openai string
+		enabled                 bool
+		priority                int
+		tags                    string
+	}{
+		{"openai-only", "", "https://openai.example.com", true, 10, ""},
+		{"anthropic", "https://anthropic.example.com", "", true, 5, ""},
+		{"tagged", "https://tagged.example.com", "", true, 1, `["fast"]`},
+		{"disabled", "https://disabled.example.com", "", false, 20, ""},
+	}
+	for i, r := range rows {
+		if _, err := db.Exec(`INSERT INTO endpoints (id, name, url_anthropic, url_openai, endpoint_type, auth_type, auth_value, enabled, priority, tags, created_at)
+			VALUES (?, ?, ?, ?, 'anthropic', 'api_key', 'k', ?, ?, ?, '2024-01-01')`,
+			i, r.name, r.anthropic, r.openai, r.enabled, r.priority, r.tags); err != nil {
+			t.Fatalf("insert %s: %v", r.name, err)
+		}
+	}
+
+	result := app.PreviewRoutingOrder("claude_code", "claude-sonnet", []string{" fast "})
+	if result["success"] != true {
+		t.Fatalf("expected success, got %v", result)
+	}
+	attempts := result["data"].([]map[string]interface{})
+	var names []string
+	for _, a := range attempts {
+		names = append(names, a["name"].(string))
+	}
+	// openai-only 对 claude_code 回退到 OpenAI URL；tagged 因标签匹配被提前
+	want := []string{"tagged", "openai-only", "anthropic"}
+	if len(names) != len(want) {
+		t.Fatalf("unexpected order %v, want %v", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Fatalf("unexpected order %v, want %v", names, want)
+		}
+	}
+	if attempts[1]["upstream_url"] != "https://openai.example.com" {
+		t.Fatalf("unexpected upstream url %v", attempts[1]["upstream_url"])
+	}
+
+	skipped := result["skipped"].([]map[string]interface{})
+	if len(skipped) != 1 || skipped[0]["name"] != "disabled" {
+		t.Fatalf("expected disabled endpoint to be skipped, got %v", skipped)
+	}
+}
+
+func TestPreviewUpstreamURL(t *testing.T) {
+	both := config.EndpointConfig{URLAnthropic: "https://a.example.com", URLOpenAI: "https://o.example.com"}
+	if got := previewUpstreamURL(both, "claude_code"); got != "https://a.example.com" {
+		t.Fatalf("claude_code should prefer anthropic url, got %q", got)
+	}
+	if got := previewUpstreamURL(both, "codex"); got != "https://o.example.com" {
+		t.Fatalf("codex should prefer openai url, got %q", got)
+	}
+	if got := previewUpstreamURL(config.EndpointConfig{}, "codex"); got != "" {
+		t.Fatalf("expected empty url, got %q", got)
+	}
+}
+
+func TestPreviewRoutingOrderForRequestAppliesRulesAndFilters(t *testing.T) {
+	db := newEndpointTestDB(t)
+	app := &App{db: db, config: map[string]interface{}{
+		"server": map[string]interface{}{
+			"routing_rules": []interface{}{
+				map[string]interface{}{"name": "agentic", "min_tools": 1, "tag": "tools", "model": "claude-opus"},
+			},
+		},
+	}}
+	rows := []struct {
+		name, anthropic, openai string
+		priority                int
+		tags                    string
+		audio                   bool
+	}{
+		{"openai-audio", "", "https://openai.example.com", 10, "", true},
+		{"anthropic", "https://anthropic.example.com", "", 5, "", false},
+		{"tools", "https://tools.example.com", "", 1, `["tools"]`, false},
+	}
+	for i, r := range rows {
+		if _, err := db.Exec(`INSERT INTO endpoints (id, name, url_anthropic, url_openai, endpoint_type, auth_type, auth_value, enabled, priority, tags, supports_audio, created_at)
+			VALUES (?, ?, ?, ?, 'anthropic', 'api_key', 'k', 1, ?, ?, ?, '2024-01-01')`,
+			i, r.name, r.anthropic, r.openai, r.priority, r.tags, r.audio); err != nil {
+			t.Fatalf("insert %s: %v", r.name, err)
+		}
+	}
+	names := func(result map[string]interface{}) []string {
+		if result["success"] != true {
+			t.Fatalf("expected success, got %v", result)
+		}
+		var out []string
+		for _, a := range result["data"].([]map[string]interface{}) {
+			out = append(out, a["name"].(string))
+		}
+		return out
+	}
+
+	// 命中路由规则：规则标签把 tools 端点提前，规则模型先于别名归一化生效
+	result := app.PreviewRoutingOrderForRequest("claude_code", "/v1/messages",
+		`{"model":"claude-sonnet","tools":[{"name":"a"}],"messages":[{"role":"user","content":"hi"}]}`, nil)
+	if got := names(result); len(got) != 3 || got[0] != "tools" {
+		t.Fatalf("expected rule tag to prefer tools endpoint, got %v", got)
+	}
+	if result["routing_rule"] != "agentic" || result["canonical_model"] != "claude-opus" {
+		t.Fatalf("expected rule name and model override, got rule=%v model=%v", result["routing_rule"], result["canonical_model"])
+	}
+
+	// 批处理请求只能走 Anthropic URL
+	result = app.PreviewRoutingOrderForRequest("claude_code", "/v1/messages/batches", `{"requests":[]}`, nil)
+	if got := names(result); len(got) != 2 || got[0] != "anthropic" || got[1] != "tools" {
+		t.Fatalf("expected batch preview to drop openai-only endpoint, got %v", got)
+	}
+	if skipped := result["skipped"].([]map[string]interface{}); len(skipped) != 1 || skipped[0]["name"] != "openai-audio" {
+		t.Fatalf("expected openai-audio skipped for batch, got %v", skipped)
+	}
+
+	// 音频请求只能走开启 supports_audio 的 OpenAI 端点
+	result = app.PreviewRoutingOrderForRequest("codex", "/v1/chat/completions",
+		`{"model":"gpt-4o-audio","modalities":["text","audio"],"messages":[{"role":"user","content":"hi"}]}`, nil)
+	if got := names(result); len(got) != 1 || got[0] != "openai-audio" {
+		t.Fatalf("expected only audio endpoint, got %v", got)
+	}
+	if skipped := result["skipped"].([]map[string]interface{}); len(skipped) != 2 {
+		t.Fatalf("expected two endpoints skipped for audio, got %v", skipped)
+	}
+}
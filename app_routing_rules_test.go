@@ -0,0 +1,43 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"claude-code-codex-companion/internal/utils"
+)
+
+func TestGetRoutingRulesFromConfig(t *testing.T) {
+	var server map[string]interface{}
+	if err := json.Unmarshal([]byte(`{"routing_rules":[
+		{"name":"tool-heavy","min_tools":6,"model":"big-model"},
+		{"min_messages":40,"tag":"strong"},
+		{"name":"no-action","min_tools":1},
+		{"name":"no-condition","model":"x"},
+		"bogus"
+	]}`), &server); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	app := &App{config: map[string]interface{}{"server": server}}
+
+	rules := app.getRoutingRules()
+	if len(rules) != 2 {
+		t.Fatalf("expected 2 valid rules, got %+v", rules)
+	}
+	if rules[0].Name != "tool-heavy" || rules[0].MinTools != 6 || rules[0].Model != "big-model" {
+		t.Fatalf("unexpected first rule: %+v", rules[0])
+	}
+	if rules[1].Name != "#2" || rules[1].MinMessages != 40 || rules[1].Tag != "strong" {
+		t.Fatalf("expected unnamed rule to be named by position, got %+v", rules[1])
+	}
+
+	body := []byte(`{"model":"small","messages":[{"role":"user","content":"go"}],"tools":[{},{},{},{},{},{}]}`)
+	rule, ok := utils.MatchRoutingRule(rules, utils.MeasureRequest(body))
+	if !ok || rule.Model != "big-model" {
+		t.Fatalf("expected tool-heavy request to match, got %+v (%v)", rule, ok)
+	}
+
+	if rules := (&App{config: map[string]interface{}{}}).getRoutingRules(); rules != nil {
+		t.Fatalf("expected no rules without configuration, got %+v", rules)
+	}
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestProxyPausedInSafeMode(t *testing.T) {
+	app := &App{configError: "invalid character '}' looking for beginning of object key string"}
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/messages", strings.NewReader(`{}`))
+	rec := httptest.NewRecorder()
+	app.handleProxyRequest(rec, req)
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 in safe mode, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "config_safe_mode") || !strings.Contains(rec.Body.String(), "looking for beginning") {
+		t.Fatalf("expected config error in response body, got %s", rec.Body.String())
+	}
+
+	status := app.GetServerStatus()
+	if status["safe_mode"] != true || status["config_error"] == nil {
+		t.Fatalf("expected safe mode to be surfaced in server status, got %v", status)
+	}
+}
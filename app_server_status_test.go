@@ -0,0 +1,39 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestServerStatusReportsEndpointCountsAndRequestCounters(t *testing.T) {
+	db := newEndpointTestDB(t)
+	if _, err := db.Exec(`INSERT INTO endpoints (id, enabled, status) VALUES ('a', 1, 'healthy'), ('b', 1, 'healthy'), ('c', 1, 'unhealthy'), ('d', 0, 'healthy')`); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	// 安全模式下代理直接返回 503，用于产生一次失败请求
+	app := &App{db: db, configError: "unexpected end of JSON input"}
+	rec := httptest.NewRecorder()
+	app.handleProxyRequest(rec, httptest.NewRequest(http.MethodPost, "/v1/messages", strings.NewReader(`{}`)))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected safe-mode 503, got %d", rec.Code)
+	}
+	app.requestCounters.record(http.StatusOK)
+
+	status := app.GetServerStatus()
+	if status["endpoints_total"] != 4 || status["endpoints_healthy"] != 2 {
+		t.Fatalf("expected endpoint counts from the database, got total=%v healthy=%v", status["endpoints_total"], status["endpoints_healthy"])
+	}
+	if status["requests_total"] != int64(2) || status["requests_failed"] != int64(1) ||
+		status["requests_since_restart"] != int64(2) || status["requests_failed_since_restart"] != int64(1) {
+		t.Fatalf("unexpected request counters: %v", status)
+	}
+
+	app.requestCounters.reset()
+	status = app.GetServerStatus()
+	if status["requests_total"] != int64(2) || status["requests_since_restart"] != int64(0) || status["requests_failed_since_restart"] != int64(0) {
+		t.Fatalf("expected reset to clear only since-restart counters, got %v", status)
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServerTimingsHeader(t *testing.T) {
+	start := time.Now()
+	timings := &serverTimings{
+		start:                   start,
+		selection:               2 * time.Millisecond,
+		requestConversion:       500 * time.Microsecond,
+		upstream:                120 * time.Millisecond,
+		responseConversionStart: start.Add(130 * time.Millisecond),
+	}
+
+	got := timings.header(start.Add(131500 * time.Microsecond))
+	want := `select;desc="endpoint selection";dur=2.0, convert;desc="conversion";dur=2.0, upstream;desc="upstream TTFB";dur=120.0, total;desc="total";dur=131.5`
+	if got != want {
+		t.Fatalf("unexpected Server-Timing header:\n got %s\nwant %s", got, want)
+	}
+
+	timings.markUpstream(80 * time.Millisecond)
+	if !timings.responseConversionStart.IsZero() {
+		t.Fatal("expected a new upstream attempt to reset the response conversion start")
+	}
+
+	var disabled *serverTimings
+	disabled.markSelection()
+	header := http.Header{}
+	disabled.apply(header, time.Now())
+	if header.Get("Server-Timing") != "" {
+		t.Fatal("expected no header when server timing is disabled")
+	}
+}
+
+func TestServerTimingHeaderGatedByConfig(t *testing.T) {
+	for _, enabled := range []bool{false, true} {
+		// 安全模式下代理直接返回 503，足以验证响应头在写出前附加
+		app := &App{
+			config:      map[string]interface{}{"server": map[string]interface{}{"server_timing": enabled}},
+			configError: "unexpected end of JSON input",
+		}
+		rec := httptest.NewRecorder()
+		app.handleProxyRequest(rec, httptest.NewRequest(http.MethodPost, "/v1/messages", strings.NewReader(`{}`)))
+
+		header := rec.Header().Get("Server-Timing")
+		if enabled && !strings.Contains(header, `total;desc="total";dur=`) {
+			t.Fatalf("expected Server-Timing header when enabled, got %q", header)
+		}
+		if !enabled && header != "" {
+			t.Fatalf("expected no Server-Timing header when disabled, got %q", header)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFilterSSEEvents(t *testing.T) {
+	stream := "event: ping\ndata: {\"type\":\"ping\"}\n\n" +
+		"event: message_start\ndata: {\"type\":\"message_start\"}\n\n" +
+		"event: x_vendor_usage\ndata: {\"type\":\"x_vendor_usage\"}\n\n" +
+		"data: {\"type\":\"x_trace\"}\n\n" +
+		"event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n"
+
+	filtered, dropped := filterSSEEvents([]byte(stream), []string{"ping", "x_*"})
+	if dropped != 3 {
+		t.Fatalf("expected 3 dropped events, got %d: %s", dropped, filtered)
+	}
+	out := string(filtered)
+	if strings.Contains(out, "ping") || strings.Contains(out, "x_vendor_usage") || strings.Contains(out, "x_trace") {
+		t.Fatalf("noisy events were not removed: %s", out)
+	}
+	if !strings.Contains(out, "message_start") || !strings.HasSuffix(out, "{\"type\":\"message_stop\"}\n\n") {
+		t.Fatalf("required events were not preserved: %q", out)
+	}
+}
+
+func TestFilterSSEEvents_KeepsRequiredAndUntypedEvents(t *testing.T) {
+	stream := "event: message_stop\ndata: {\"type\":\"message_stop\"}\n\ndata: [DONE]\n\n"
+
+	filtered, dropped := filterSSEEvents([]byte(stream), []string{"message_*", "error"})
+	if dropped != 0 || string(filtered) != stream {
+		t.Fatalf("required and untyped events must never be dropped, got %d: %q", dropped, filtered)
+	}
+}
+
+func TestFilterSSEEvents_KeepsResponsesProtocolEvents(t *testing.T) {
+	stream := "event: response.in_progress\ndata: {}\n\n" +
+		"event: response.output_item.added\ndata: {}\n\n" +
+		"event: response.content_part.added\ndata: {}\n\n" +
+		"event: response.function_call_arguments.delta\ndata: {}\n\n" +
+		"event: response.content_part.done\ndata: {}\n\n" +
+		"event: response.output_item.done\ndata: {}\n\n" +
+		"event: response.reasoning_summary_text.delta\ndata: {}\n\n"
+
+	filtered, dropped := filterSSEEvents([]byte(stream), []string{"response.*"})
+	if dropped != 1 || strings.Contains(string(filtered), "reasoning_summary") || !strings.Contains(string(filtered), "response.function_call_arguments.delta") {
+		t.Fatalf("expected only the optional event to be dropped, got %d: %q", dropped, filtered)
+	}
+}
+
+func TestSerialiseSSEEventFilter(t *testing.T) {
+	serialised, err := serialiseSSEEventFilter([]interface{}{"ping", " x_* "})
+	if err != nil || serialised != `["ping","x_*"]` {
+		t.Fatalf("unexpected result %q, %v", serialised, err)
+	}
+	if _, err := serialiseSSEEventFilter([]interface{}{"*"}); err == nil {
+		t.Fatalf("expected a catch-all pattern to be rejected")
+	}
+	if _, err := serialiseSSEEventFilter([]interface{}{"bad event"}); err == nil {
+		t.Fatalf("expected whitespace in a pattern to be rejected")
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"claude-code-codex-companion/internal/logger"
+)
+
+func TestGetStatsWindow(t *testing.T) {
+	app := &App{config: map[string]interface{}{}}
+	if window, duration := app.getStatsWindow(); window != "24h" || duration != 24*time.Hour {
+		t.Fatalf("expected default 24h window, got %s (%v)", window, duration)
+	}
+
+	app.config["server"] = map[string]interface{}{"stats_window": "7d"}
+	if window, duration := app.getStatsWindow(); window != "7d" || duration != 7*24*time.Hour {
+		t.Fatalf("expected 7d window, got %s (%v)", window, duration)
+	}
+
+	app.config["server"] = map[string]interface{}{"stats_window": "5m"}
+	if window, _ := app.getStatsWindow(); window != "24h" {
+		t.Fatalf("expected unsupported window to fall back to 24h, got %s", window)
+	}
+}
+
+func TestGetStatsFromLogs(t *testing.T) {
+	db := newEndpointTestDB(t)
+	if _, err := db.Exec(`INSERT INTO endpoints (id, name, enabled, status) VALUES
+		('1', 'a', 1, 'healthy'), ('2', 'b', 1, 'unhealthy'), ('3', 'c', 0, 'healthy')`); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	requestLogger, err := logger.NewLogger(logger.LogConfig{Level: "info", LogRequestTypes: "all", LogDirectory: t.TempDir()})
+	if err != nil {
+		t.Fatalf("create logger: %v", err)
+	}
+	defer requestLogger.Close()
+
+	now := time.Now()
+	for _, entry := range []struct {
+		requestID string
+		status    int
+		duration  int64
+	}{
+		{"req-1", 200, 100},
+		{"req-2", 502, 300},
+		{"req-2", 200, 200},
+		{"req-3", 500, 400},
+	} {
+		requestLogger.LogRequest(&logger.RequestLog{
+			Timestamp:  now.Add(-time.Minute),
+			RequestID:  entry.requestID,
+			Endpoint:   "a",
+			StatusCode: entry.status,
+			DurationMs: entry.duration,
+		})
+	}
+
+	app := &App{
+		db:            db,
+		requestLogger: requestLogger,
+		config:        map[string]interface{}{"server": map[string]interface{}{"stats_window": "1h"}},
+	}
+	stats := app.GetStats()
+	if stats["success"] != true {
+		t.Fatalf("GetStats failed: %v", stats)
+	}
+	if stats["timeRange"] != "1h" || stats["totalRequests"] != int64(3) || stats["successRequests"] != int64(2) || stats["errorsCount"] != int64(1) {
+		t.Fatalf("unexpected request totals: %v", stats)
+	}
+	if stats["avgResponseTime"] != int64(250) || stats["p95ResponseTime"] != int64(400) {
+		t.Fatalf("unexpected response times: avg=%v p95=%v", stats["avgResponseTime"], stats["p95ResponseTime"])
+	}
+	if stats["totalEndpoints"] != 3 || stats["activeEndpoints"] != 1 {
+		t.Fatalf("unexpected endpoint counts: total=%v active=%v", stats["totalEndpoints"], stats["activeEndpoints"])
+	}
+}
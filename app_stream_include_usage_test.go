@@ -0,0 +1,40 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestApplyStreamIncludeUsage(t *testing.T) {
+	body := []byte(`{"model":"gpt-4o","stream":true,"messages":[]}`)
+	updated, modified := applyStreamIncludeUsage(body, true)
+	if !modified {
+		t.Fatalf("expected include_usage to be injected")
+	}
+	var payload map[string]interface{}
+	if err := json.Unmarshal(updated, &payload); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if options, _ := payload["stream_options"].(map[string]interface{}); options["include_usage"] != true {
+		t.Fatalf("expected stream_options.include_usage=true, got %s", updated)
+	}
+
+	// 客户端显式指定时保持不变
+	explicit := []byte(`{"stream":true,"stream_options":{"include_usage":false}}`)
+	if _, modified := applyStreamIncludeUsage(explicit, true); modified {
+		t.Error("expected explicit include_usage to be preserved")
+	}
+	// 非流式请求不处理
+	if _, modified := applyStreamIncludeUsage([]byte(`{"stream":false}`), true); modified {
+		t.Error("expected non-streaming request to be untouched")
+	}
+	// 端点关闭时移除 stream_options
+	stripped, modified := applyStreamIncludeUsage(updated, false)
+	payload = nil
+	if !modified || json.Unmarshal(stripped, &payload) != nil {
+		t.Fatalf("expected stream_options to be removed, got %s", stripped)
+	}
+	if _, exists := payload["stream_options"]; exists {
+		t.Errorf("expected stream_options removed, got %s", stripped)
+	}
+}
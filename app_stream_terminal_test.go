@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"claude-code-codex-companion/internal/conversion"
+)
+
+func TestEnsureSSETerminalEvent(t *testing.T) {
+	truncatedAnthropic := []byte("event: message_start\ndata: {\"type\":\"message_start\"}\n\nevent: content_block_delta\ndata: {\"type\":\"content_block_delta\"}\n")
+	completed, appended := ensureSSETerminalEvent(truncatedAnthropic, "anthropic", "/v1/messages")
+	if !appended {
+		t.Fatal("expected an error event for a truncated Anthropic stream")
+	}
+	if !strings.HasSuffix(string(completed), "\n\n") || !strings.Contains(string(completed), "\n\nevent: error\ndata: ") {
+		t.Fatalf("unexpected completed stream: %q", completed)
+	}
+
+	finished := []byte("event: message_stop\ndata: {\"type\": \"message_stop\"}\n\n")
+	if _, appended := ensureSSETerminalEvent(finished, "anthropic", "/v1/messages"); appended {
+		t.Error("did not expect a terminal event for a completed Anthropic stream")
+	}
+
+	truncatedChat := []byte("data: {\"choices\":[{\"delta\":{\"content\":\"hi\"},\"finish_reason\":null}]}\n\n")
+	completed, appended = ensureSSETerminalEvent(truncatedChat, "openai", "/v1/chat/completions")
+	if !appended || !strings.HasSuffix(string(completed), "data: [DONE]\n\n") {
+		t.Fatalf("expected error chunk and [DONE] for truncated chat stream, got %q", completed)
+	}
+
+	truncatedResponses := []byte("event: response.output_text.delta\ndata: {\"type\":\"response.output_text.delta\"}\n\n")
+	completed, appended = ensureSSETerminalEvent(truncatedResponses, "openai", "/v1/responses")
+	if !appended || !strings.Contains(string(completed), "event: response.failed") {
+		t.Fatalf("expected response.failed for truncated responses stream, got %q", completed)
+	}
+
+	if _, appended := ensureSSETerminalEvent(truncatedChat, "unknown", "/v1/other"); appended {
+		t.Error("did not expect unknown formats to be modified")
+	}
+}
+
+func TestEnsureSSETerminalEventIgnoresMarkersInDeltaText(t *testing.T) {
+	// 增量文本中出现终止事件名或 [DONE] 不应被当作流已结束
+	anthropic := []byte("event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"delta\":{\"type\":\"text_delta\",\"text\":\"send event: message_stop then \\\"type\\\":\\\"message_stop\\\"\"}}\n\n")
+	if _, appended := ensureSSETerminalEvent(anthropic, "anthropic", "/v1/messages"); !appended {
+		t.Error("expected an error event when message_stop only appears in delta text")
+	}
+
+	responses := []byte("event: response.output_text.delta\ndata: {\"type\":\"response.output_text.delta\",\"delta\":\"wait for response.completed\"}\n\n")
+	if _, appended := ensureSSETerminalEvent(responses, "openai", "/v1/responses"); !appended {
+		t.Error("expected response.failed when response.completed only appears in delta text")
+	}
+
+	chat := []byte("data: {\"choices\":[{\"delta\":{\"content\":\"the stream ends with [DONE] and \\\"finish_reason\\\":\\\"stop\\\"\"},\"finish_reason\":null}]}\n\n")
+	if _, appended := ensureSSETerminalEvent(chat, "openai", "/v1/chat/completions"); !appended {
+		t.Error("expected error chunk when [DONE] only appears in delta text")
+	}
+
+	finishedChat := []byte("data: {\"choices\":[{\"delta\":{},\"finish_reason\": \"stop\"}]}\n\n")
+	if _, appended := ensureSSETerminalEvent(finishedChat, "openai", "/v1/chat/completions"); appended {
+		t.Error("did not expect a terminal event after finish_reason")
+	}
+
+	completedResponses := []byte("data: {\"type\":\"response.completed\",\"response\":{}}\n\n")
+	if _, appended := ensureSSETerminalEvent(completedResponses, "openai", "/v1/responses"); appended {
+		t.Error("did not expect a terminal event after a response.completed data line")
+	}
+}
+
+func TestConvertedStreamTruncationDetectedBeforeConversion(t *testing.T) {
+	// 上游 OpenAI 流缺少 finish_reason 与 [DONE]，转换器仍会补齐 message_stop
+	upstream := []byte("data: {\"id\":\"c1\",\"model\":\"gpt-4o\",\"choices\":[{\"index\":0,\"delta\":{\"content\":\"hi\"},\"finish_reason\":null}]}\n\n")
+	if sseStreamTerminated(upstream, "openai", "/chat/completions") {
+		t.Fatal("expected the raw upstream stream to be reported as truncated")
+	}
+
+	var converted bytes.Buffer
+	if err := conversion.StreamOpenAISSEToAnthropic(bytes.NewReader(upstream), &converted); err != nil {
+		t.Fatalf("convert stream: %v", err)
+	}
+	if !sseStreamTerminated(converted.Bytes(), "anthropic", "/v1/messages") {
+		t.Fatal("expected the converter to synthesise message_stop")
+	}
+
+	completed := string(appendSSETerminalError(converted.Bytes(), "anthropic", "/v1/messages", true))
+	if strings.Contains(completed, "message_stop") || strings.Contains(completed, "event: message_delta") {
+		t.Fatalf("expected synthesised completion events to be dropped, got %q", completed)
+	}
+	if !strings.Contains(completed, "event: content_block_delta") || !strings.HasSuffix(completed, "\n\n") || !strings.Contains(completed, "event: error\ndata: ") {
+		t.Fatalf("expected content followed by an error event, got %q", completed)
+	}
+}
+
+func TestExtractServiceTier(t *testing.T) {
+	if tier := extractServiceTier([]byte(`{"id":"x","service_tier":"priority"}`)); tier != "priority" {
+		t.Errorf("expected JSON service_tier, got %q", tier)
+	}
+	if tier := extractServiceTier([]byte(`{"type":"message","usage":{"input_tokens":1,"service_tier":"standard"}}`)); tier != "standard" {
+		t.Errorf("expected usage.service_tier, got %q", tier)
+	}
+	sse := "data: {\"id\":\"c1\",\"service_tier\":\"default\",\"choices\":[]}\n\ndata: [DONE]\n\n"
+	if tier := extractServiceTier([]byte(sse)); tier != "default" {
+		t.Errorf("expected SSE service_tier, got %q", tier)
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"testing"
+
+	"claude-code-codex-companion/internal/config"
+)
+
+func TestEndpointOutcomeWindow(t *testing.T) {
+	window := &endpointOutcomeWindow{}
+	for i := 0; i < 6; i++ {
+		window.record(i%2 == 0, 4)
+	}
+	// 最近4次: i=2..5 -> true,false,true,false
+	if rate, samples := window.successRate(); samples != 4 || rate != 0.5 {
+		t.Fatalf("expected 0.5 over 4 samples, got %v over %d", rate, samples)
+	}
+
+	// 缩小窗口时保留最近的结果
+	window.record(true, 2)
+	if rate, samples := window.successRate(); samples != 2 || rate != 0.5 {
+		t.Fatalf("expected 0.5 over 2 samples after resize, got %v over %d", rate, samples)
+	}
+}
+
+func TestEndpointSelectionWeight(t *testing.T) {
+	app := &App{config: map[string]interface{}{
+		"server": map[string]interface{}{"success_rate_window": float64(10)},
+	}}
+
+	// 样本不足时权重为 1
+	app.recordEndpointOutcome("flaky", 500)
+	if weight := app.endpointSelectionWeight("flaky"); weight != 1 {
+		t.Fatalf("expected neutral weight with insufficient history, got %v", weight)
+	}
+
+	for i := 0; i < 9; i++ {
+		app.recordEndpointOutcome("flaky", 502)
+		app.recordEndpointOutcome("healthy", 200)
+	}
+	if weight := app.endpointSelectionWeight("flaky"); weight != successRateMinWeight {
+		t.Fatalf("expected weight floor for failing endpoint, got %v", weight)
+	}
+	if weight := app.endpointSelectionWeight("healthy"); weight != 1 {
+		t.Fatalf("expected full weight for healthy endpoint, got %v", weight)
+	}
+
+	endpoints := []config.EndpointConfig{
+		{Name: "primary", Priority: 10},
+		{Name: "flaky", Priority: 5},
+		{Name: "healthy", Priority: 5},
+		{Name: "last", Priority: 1},
+	}
+	healthyFirst := 0
+	for i := 0; i < 200; i++ {
+		ordered := app.orderEndpointsBySuccessRate(endpoints)
+		if ordered[0].Name != "primary" || ordered[3].Name != "last" {
+			t.Fatalf("priority order must be preserved, got %v", ordered)
+		}
+		if ordered[1].Name == "healthy" {
+			healthyFirst++
+		}
+	}
+	if healthyFirst < 150 {
+		t.Fatalf("expected healthy endpoint to win most tie-breaks, won %d/200", healthyFirst)
+	}
+}
+
+func TestOrderEndpointsBySuccessRateDisabledByDefault(t *testing.T) {
+	app := &App{}
+	endpoints := []config.EndpointConfig{{Name: "a", Priority: 1}, {Name: "b", Priority: 1}}
+	app.recordEndpointOutcome("a", 500)
+	if len(app.endpointOutcomes) != 0 {
+		t.Fatal("expected no outcomes to be tracked when the window is disabled")
+	}
+	if ordered := app.orderEndpointsBySuccessRate(endpoints); ordered[0].Name != "a" || ordered[1].Name != "b" {
+		t.Fatalf("expected original order when disabled, got %v", ordered)
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"claude-code-codex-companion/internal/config"
+)
+
+func TestResolveSystemPromptTemplate(t *testing.T) {
+	resolved, unknown := resolveSystemPromptTemplate(
+		"Today is {{date}}, client={{ client_type }}, model={{model}}, keep {{unknown}}",
+		map[string]string{"date": "2025-01-02", "client_type": "codex", "model": "gpt-5"},
+	)
+
+	expected := "Today is 2025-01-02, client=codex, model=gpt-5, keep {{unknown}}"
+	if resolved != expected {
+		t.Fatalf("unexpected resolved prompt: %q", resolved)
+	}
+	if len(unknown) != 1 || unknown[0] != "unknown" {
+		t.Fatalf("expected unknown token to be reported, got %v", unknown)
+	}
+}
+
+func TestApplyExtraSystemPrompt(t *testing.T) {
+	app := &App{}
+	endpoint := &config.EndpointConfig{Name: "test", ExtraSystemPrompt: "Model {{model}} via {{client_type}}"}
+
+	anthropicBody := []byte(`{"model":"claude-3","system":"original","messages":[]}`)
+	var anthropic map[string]interface{}
+	if err := json.Unmarshal(app.applyExtraSystemPrompt(anthropicBody, endpoint, "/v1/messages", "claude_code"), &anthropic); err != nil {
+		t.Fatalf("invalid anthropic body: %v", err)
+	}
+	if system, _ := anthropic["system"].(string); !strings.HasPrefix(system, "Model claude-3 via claude_code") || !strings.HasSuffix(system, "original") {
+		t.Fatalf("unexpected anthropic system: %q", system)
+	}
+
+	openaiBody := []byte(`{"model":"gpt-4o","messages":[{"role":"user","content":"hi"}]}`)
+	var openai map[string]interface{}
+	if err := json.Unmarshal(app.applyExtraSystemPrompt(openaiBody, endpoint, "/v1/chat/completions", "codex"), &openai); err != nil {
+		t.Fatalf("invalid openai body: %v", err)
+	}
+	messages, _ := openai["messages"].([]interface{})
+	if len(messages) != 2 {
+		t.Fatalf("expected system message to be prepended, got %d messages", len(messages))
+	}
+	first, _ := messages[0].(map[string]interface{})
+	if first["role"] != "system" || first["content"] != "Model gpt-4o via codex" {
+		t.Fatalf("unexpected system message: %v", first)
+	}
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"database/sql"
+	"testing"
+
+	_ "modernc.org/sqlite"
+)
+
+// legacyEndpointsTableSQL 迁移前的端点表（基础列），其余列统一由 ensureEndpointSchema 补齐
+const legacyEndpointsTableSQL = `CREATE TABLE endpoints (id TEXT PRIMARY KEY, name TEXT, url_anthropic TEXT, url_openai TEXT,
+	endpoint_type TEXT, auth_type TEXT, auth_value TEXT, enabled BOOLEAN, priority INTEGER)`
+
+// newEndpointTestDB 打开内存数据库并按生产迁移建立端点表，避免各测试手写的表结构与实际列不一致
+func newEndpointTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(legacyEndpointsTableSQL); err != nil {
+		t.Fatalf("create endpoints table: %v", err)
+	}
+	if err := (&App{}).ensureEndpointSchema(db); err != nil {
+		t.Fatalf("ensure endpoint schema: %v", err)
+	}
+	return db
+}
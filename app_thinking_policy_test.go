@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"claude-code-codex-companion/internal/config"
+)
+
+func TestApplyThinkingPolicyDisable(t *testing.T) {
+	endpoint := &config.EndpointConfig{DisableThinking: true, ForceThinking: true}
+	body := []byte(`{"model":"cheap","thinking":{"type":"enabled","budget_tokens":4096},"reasoning_effort":"high","messages":[]}`)
+
+	updated, enabled, budget := applyThinkingPolicy(body, endpoint, "/v1/messages")
+	if enabled || budget != 0 {
+		t.Fatalf("expected thinking to be disabled, got enabled=%v budget=%d", enabled, budget)
+	}
+
+	var payload map[string]interface{}
+	if err := json.Unmarshal(updated, &payload); err != nil {
+		t.Fatalf("invalid body: %v", err)
+	}
+	if _, exists := payload["thinking"]; exists {
+		t.Error("expected thinking to be stripped")
+	}
+	if _, exists := payload["reasoning_effort"]; exists {
+		t.Error("expected reasoning_effort to be stripped")
+	}
+}
+
+func TestApplyThinkingPolicyForce(t *testing.T) {
+	endpoint := &config.EndpointConfig{ForceThinking: true}
+
+	updated, enabled, budget := applyThinkingPolicy([]byte(`{"model":"premium","max_tokens":1024,"messages":[]}`), endpoint, "/v1/messages")
+	if !enabled || budget != defaultForcedThinkingBudget {
+		t.Fatalf("expected forced thinking budget, got enabled=%v budget=%d", enabled, budget)
+	}
+	var payload map[string]interface{}
+	if err := json.Unmarshal(updated, &payload); err != nil {
+		t.Fatalf("invalid body: %v", err)
+	}
+	if maxTokens, _ := payload["max_tokens"].(float64); int(maxTokens) <= defaultForcedThinkingBudget {
+		t.Errorf("expected max_tokens to exceed the thinking budget, got %v", payload["max_tokens"])
+	}
+
+	// 已携带的思考配置保持不变
+	existing := []byte(`{"model":"premium","reasoning_effort":"high","messages":[]}`)
+	updated, enabled, budget = applyThinkingPolicy(existing, endpoint, "/v1/chat/completions")
+	if string(updated) != string(existing) {
+		t.Errorf("expected existing reasoning_effort to be kept, got %s", updated)
+	}
+	if !enabled || budget != 16384 {
+		t.Errorf("expected high reasoning to be reported, got enabled=%v budget=%d", enabled, budget)
+	}
+
+	updated, enabled, _ = applyThinkingPolicy([]byte(`{"model":"premium","input":"hi"}`), endpoint, "/v1/responses")
+	if !enabled {
+		t.Fatalf("expected reasoning to be injected for responses, got %s", updated)
+	}
+}
+
+func TestApplyThinkingPolicyForceNormalizesSamplingParams(t *testing.T) {
+	endpoint := &config.EndpointConfig{ForceThinking: true}
+
+	body := []byte(`{"model":"premium","temperature":0.2,"top_k":40,"top_p":0.5,"messages":[]}`)
+	updated, enabled, _ := applyThinkingPolicy(body, endpoint, "/v1/messages")
+	if !enabled {
+		t.Fatalf("expected forced thinking, got %s", updated)
+	}
+	var payload map[string]interface{}
+	if err := json.Unmarshal(updated, &payload); err != nil {
+		t.Fatalf("invalid body: %v", err)
+	}
+	for _, field := range []string{"temperature", "top_k", "top_p"} {
+		if _, exists := payload[field]; exists {
+			t.Errorf("expected %s to be removed when forcing thinking, got %v", field, payload[field])
+		}
+	}
+	// 未携带 max_tokens 时也要补齐到预算之上
+	if maxTokens, _ := payload["max_tokens"].(float64); int(maxTokens) <= defaultForcedThinkingBudget {
+		t.Errorf("expected max_tokens to be set above the thinking budget, got %v", payload["max_tokens"])
+	}
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"testing"
+
+	"claude-code-codex-companion/internal/config"
+	"claude-code-codex-companion/internal/conversion"
+)
+
+func TestResolveThinkingStreamModePrefersEndpointOverGlobal(t *testing.T) {
+	app := &App{config: map[string]interface{}{}}
+	if got := app.resolveThinkingStreamMode(&config.EndpointConfig{}); got != conversion.ThinkingStreamModePassthrough {
+		t.Fatalf("expected passthrough by default, got %q", got)
+	}
+
+	app.config["server"] = map[string]interface{}{"thinking_stream_mode": "Text"}
+	if got := app.resolveThinkingStreamMode(&config.EndpointConfig{}); got != conversion.ThinkingStreamModeText {
+		t.Fatalf("expected global text mode, got %q", got)
+	}
+	if got := app.resolveThinkingStreamMode(&config.EndpointConfig{ThinkingStreamMode: "drop"}); got != conversion.ThinkingStreamModeDrop {
+		t.Fatalf("expected endpoint drop mode to override global, got %q", got)
+	}
+	if got := app.resolveThinkingStreamMode(&config.EndpointConfig{ThinkingStreamMode: "passthrough"}); got != conversion.ThinkingStreamModePassthrough {
+		t.Fatalf("expected endpoint passthrough to override global, got %q", got)
+	}
+
+	app.config["server"] = map[string]interface{}{"thinking_stream_mode": "bogus"}
+	if got := app.resolveThinkingStreamMode(nil); got != conversion.ThinkingStreamModePassthrough {
+		t.Fatalf("expected invalid global value to fall back to passthrough, got %q", got)
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+)
+
+func TestTruncatedResponseReason(t *testing.T) {
+	if reason := truncatedResponseReason([]byte(`{"id":"msg_1"}`), -1, nil); reason != "" {
+		t.Errorf("expected complete body not to be truncated, got %q", reason)
+	}
+	if reason := truncatedResponseReason([]byte(`{"id":"msg_1","content":[`), -1, nil); reason == "" {
+		t.Error("expected unterminated JSON to be detected as truncated")
+	}
+	if reason := truncatedResponseReason([]byte(`{"id":`), 64, nil); reason == "" {
+		t.Error("expected Content-Length mismatch to be detected as truncated")
+	}
+	if reason := truncatedResponseReason([]byte(`{"id":`), -1, io.ErrUnexpectedEOF); reason == "" {
+		t.Error("expected unexpected EOF to be detected as truncated")
+	}
+	if reason := truncatedResponseReason([]byte(`<html>502</html>`), -1, nil); reason != "" {
+		t.Errorf("expected malformed non-JSON body not to count as truncation, got %q", reason)
+	}
+	if reason := truncatedResponseReason(nil, -1, errResponseBodyTooLarge); reason != "" {
+		t.Errorf("expected other read errors not to count as truncation, got %q", reason)
+	}
+}
+
+func TestReadErrorCategory(t *testing.T) {
+	if got := readErrorCategory(fmt.Errorf("%w: cut", errResponseTruncated)); got != errorCategoryTruncated {
+		t.Errorf("expected truncated category, got %q", got)
+	}
+	if got := readErrorCategory(errors.New("boom")); got != "" {
+		t.Errorf("expected empty category, got %q", got)
+	}
+}
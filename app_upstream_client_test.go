@@ -0,0 +1,41 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"claude-code-codex-companion/internal/config"
+)
+
+func TestUpstreamClientReuse(t *testing.T) {
+	app := &App{
+		config: map[string]interface{}{
+			"server": map[string]interface{}{"upstream_max_idle_conns_per_host": float64(32)},
+		},
+	}
+	ep := config.EndpointConfig{Name: "pooled", URLOpenAI: "https://api.example.com"}
+
+	first, err := app.getUpstreamClient(ep)
+	if err != nil {
+		t.Fatalf("getUpstreamClient failed: %v", err)
+	}
+	second, _ := app.getUpstreamClient(ep)
+	if first != second {
+		t.Fatal("expected the same client to be reused for an unchanged endpoint")
+	}
+	if transport, ok := first.Transport.(*http.Transport); !ok || transport.MaxIdleConnsPerHost != 32 {
+		t.Fatalf("expected tuned MaxIdleConnsPerHost, got %#v", first.Transport)
+	}
+
+	ep.Proxy = &config.ProxyConfig{Type: "http", Address: "127.0.0.1:8080"}
+	proxied, _ := app.getUpstreamClient(ep)
+	if proxied == first {
+		t.Fatal("expected a new client after the proxy config changed")
+	}
+
+	app.invalidateUpstreamClients()
+	rebuilt, _ := app.getUpstreamClient(ep)
+	if rebuilt == proxied {
+		t.Fatal("expected a new client after invalidation")
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"claude-code-codex-companion/internal/config"
+)
+
+func TestValidateEndpointConfigs(t *testing.T) {
+	endpoints := []config.EndpointConfig{
+		{Name: "plain"},
+		{Name: "good", ModelRewrite: &config.ModelRewriteConfig{Enabled: true, Rules: []config.ModelRewriteRule{{SourcePattern: "claude-*", TargetModel: "gpt-4o"}}}},
+		{Name: "bad-regex", ModelRewrite: &config.ModelRewriteConfig{Enabled: true, Rules: []config.ModelRewriteRule{{SourcePattern: "claude-(", TargetModel: "gpt-4o", MatchType: "regex"}}}},
+		{Name: "no-target", ModelRewrite: &config.ModelRewriteConfig{Enabled: true, Rules: []config.ModelRewriteRule{{SourcePattern: "gpt-*"}}}},
+	}
+
+	problems := validateEndpointConfigs(endpoints)
+	if len(problems) != 2 {
+		t.Fatalf("expected 2 problems, got %v", problems)
+	}
+	if problems[0]["endpoint"] != "bad-regex" || !strings.Contains(problems[0]["message"].(string), "invalid regex") {
+		t.Errorf("unexpected first problem: %v", problems[0])
+	}
+	if problems[1]["endpoint"] != "no-target" || !strings.Contains(problems[1]["message"].(string), "target_model is required") {
+		t.Errorf("unexpected second problem: %v", problems[1])
+	}
+}
+
+func TestValidateEndpointConfigsAllowedModels(t *testing.T) {
+	rewrite := &config.ModelRewriteConfig{Enabled: true, Rules: []config.ModelRewriteRule{
+		{SourcePattern: "claude-*", TargetModel: "gpt-4o"},
+		{SourcePattern: "haiku-*", TargetModel: "gpt-4o-mini"},
+	}}
+	endpoints := []config.EndpointConfig{
+		{Name: "unrestricted", ModelRewrite: rewrite},
+		{Name: "glob", ModelRewrite: rewrite, AllowedModels: []string{"gpt-4o*"}},
+		{Name: "denied", ModelRewrite: rewrite, AllowedModels: []string{"gpt-4o"}},
+		{Name: "rewrite-disabled", ModelRewrite: &config.ModelRewriteConfig{Rules: rewrite.Rules}, AllowedModels: []string{"o3"}},
+	}
+
+	problems := validateEndpointConfigs(endpoints)
+	if len(problems) != 1 {
+		t.Fatalf("expected 1 problem, got %v", problems)
+	}
+	if problems[0]["endpoint"] != "denied" || problems[0]["severity"] != "warning" || !strings.Contains(problems[0]["message"].(string), "gpt-4o-mini") {
+		t.Errorf("unexpected problem: %v", problems[0])
+	}
+}
+
+func TestEndpointAllowsModel(t *testing.T) {
+	if !endpointAllowsModel(nil, "gpt-4o") {
+		t.Error("endpoints without allowed_models should accept any model")
+	}
+	if !endpointAllowsModel([]string{"gpt-4o"}, "") {
+		t.Error("requests without a model should not be restricted")
+	}
+	if !endpointAllowsModel([]string{"claude-*", "gpt-4o"}, "claude-sonnet-4") {
+		t.Error("expected glob pattern to allow model")
+	}
+	if endpointAllowsModel([]string{"gpt-4o"}, "gpt-4o-mini") {
+		t.Error("expected exact entry to reject other models")
+	}
+}
+
+func TestValidateConfigReadsAllowedModels(t *testing.T) {
+	db := newEndpointTestDB(t)
+	app := &App{db: db}
+	if _, err := db.Exec(`INSERT INTO endpoints (id, name, url_openai, endpoint_type, auth_type, auth_value, enabled, priority, created_at,
+		model_rewrite_enabled, model_rewrite_rules, allowed_models)
+		VALUES ('1', 'restricted', 'https://o.example.com', 'openai', 'api_key', 'k', 1, 1, '2024-01-01',
+		1, '[{"source_pattern":"claude-*","target_model":"gpt-4.1"}]', '["gpt-4o"]')`); err != nil {
+		t.Fatalf("insert endpoint: %v", err)
+	}
+
+	endpoints, err := app.queryEndpointConfigs("", false)
+	if err != nil || len(endpoints) != 1 || len(endpoints[0].AllowedModels) != 1 || endpoints[0].AllowedModels[0] != "gpt-4o" {
+		t.Fatalf("expected allowed_models to load, got %v (%v)", endpoints, err)
+	}
+
+	result := app.ValidateConfig()
+	if result["success"] != true || result["valid"] != false {
+		t.Fatalf("expected config problems, got %v", result)
+	}
+	problems := result["problems"].([]map[string]interface{})
+	if len(problems) != 1 || !strings.Contains(problems[0]["message"].(string), "gpt-4.1") {
+		t.Fatalf("expected rewrite target warning, got %v", problems)
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWarmUpOnEnableSetting(t *testing.T) {
+	app := &App{}
+	if !app.isWarmUpOnEnableEnabledNoLock() {
+		t.Fatal("expected warm-up to be enabled by default")
+	}
+
+	app.config = map[string]interface{}{
+		"server": map[string]interface{}{"warm_up_on_enable": false},
+	}
+	if app.isWarmUpOnEnableEnabledNoLock() {
+		t.Fatal("expected server.warm_up_on_enable=false to disable warm-up")
+	}
+}
+
+func TestCreateAndEnableEndpointTriggerWarmUp(t *testing.T) {
+	db := newEndpointTestDB(t)
+
+	warmed := make(chan string, 4)
+	app := &App{db: db, warmUpFunc: func(id string) { warmed <- id }}
+	expectWarmUp := func(want string) {
+		t.Helper()
+		select {
+		case id := <-warmed:
+			if id != want {
+				t.Fatalf("expected warm-up for %s, got %s", want, id)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("expected warm-up for %s", want)
+		}
+	}
+
+	created := app.CreateEndpoint(map[string]interface{}{
+		"name": "new", "url_anthropic": "https://a.example.com", "auth_type": "api_key", "auth_value": "sk-test",
+	})
+	if created["success"] != true {
+		t.Fatalf("create endpoint: %v", created)
+	}
+	id := created["id"].(string)
+	expectWarmUp(id)
+
+	// 普通编辑不预热；禁用后重新启用时预热
+	for _, update := range []map[string]interface{}{{"priority": 3}, {"enabled": false}} {
+		if result := app.UpdateEndpoint(id, update); result["success"] != true {
+			t.Fatalf("update endpoint %v: %v", update, result)
+		}
+	}
+	if result := app.UpdateEndpoint(id, map[string]interface{}{"enabled": true}); result["success"] != true {
+		t.Fatalf("enable endpoint: %v", result)
+	}
+	expectWarmUp(id)
+	select {
+	case extra := <-warmed:
+		t.Fatalf("expected exactly one warm-up per transition, got another for %s", extra)
+	default:
+	}
+}
@@ -81,6 +81,12 @@ class WailsAPI implements DesktopAPI {
     return window.go!.main.App.RestartServer()
   }
 
+  async ResetRequestCounters(): Promise<OperationResult> {
+    await ensureWailsAPIReady()
+    checkWailsAPI()
+    return window.go!.main.App.ResetRequestCounters()
+  }
+
   // 配置管理
   async GetConfigPath(): Promise<string> {
     await ensureWailsAPIReady()
@@ -171,10 +177,10 @@ class WailsAPI implements DesktopAPI {
   }
 
   // 监控和统计 - 通过Go API
-  async GetRequestTrends(timeRange: string): Promise<any> {
+  async GetRequestTrends(timeRange: string, provider = '', region = ''): Promise<any> {
     await ensureWailsAPIReady()
     checkWailsAPI()
-    return window.go!.main.App.GetRequestTrends(timeRange)
+    return window.go!.main.App.GetRequestTrends(timeRange, provider, region)
   }
 
   async GetSystemInfo(): Promise<any> {
@@ -183,10 +189,10 @@ class WailsAPI implements DesktopAPI {
     return window.go!.main.App.GetSystemInfo()
   }
 
-  async GetEndpointStats(): Promise<any> {
+  async GetEndpointStats(provider = '', region = ''): Promise<any> {
     await ensureWailsAPIReady()
     checkWailsAPI()
-    return window.go!.main.App.GetEndpointStats()
+    return window.go!.main.App.GetEndpointStats(provider, region)
   }
 
   // 进程绑定 - 通过Go API
@@ -54,6 +54,7 @@ interface SystemStats {
   errorsCount: number
   successRate: number
   avgResponseTime: number
+  p95ResponseTime: number
   requestsPerSecond: number
   systemUptime: number
   activeEndpoints: number
@@ -478,7 +479,7 @@ export default function Dashboard() {
               {stats?.avgResponseTime !== undefined ? `${stats.avgResponseTime}ms` : '--'}
             </div>
             <p className="text-xs text-muted-foreground">
-              {stats ? '平均响应时间' : '暂无数据'}
+              {stats ? `P95 ${stats.p95ResponseTime ?? 0}ms` : '暂无数据'}
             </p>
           </CardContent>
         </Card>
@@ -25,6 +25,12 @@ export interface Endpoint {
   model_rewrite?: ModelRewrite
   parameter_overrides?: Record<string, string>
   target_model?: string
+  provider?: string // 服务商，仅用于分组展示与统计过滤
+  region?: string // 地区，仅用于分组展示与统计过滤
+  openai_organization?: string // 转发时注入的 OpenAI-Organization 请求头
+  openai_project?: string // 转发时注入的 OpenAI-Project 请求头
+  supports_audio?: boolean // 是否支持音频请求（modalities 含 audio 或 input_audio 内容块）
+  thinking_stream_mode?: "" | "passthrough" | "text" | "drop" // 流式响应中 thinking 内容的处理方式，为空时使用全局 server.thinking_stream_mode
   // 学习信息（运行时学习，部分持久化）
   openai_preference?: "auto" | "responses" | "chat_completions" // OpenAI格式偏好（持久化）
   supports_responses?: boolean // 是否支持 /responses API（持久化）
@@ -73,6 +79,11 @@ export interface ServerStatus {
   port: string | number
   endpoints_total?: number
   endpoints_healthy?: number
+  requests_total?: number // 自进程启动累计的代理请求数
+  requests_failed?: number
+  requests_since_restart?: number // 上次重启/重置计数以来的代理请求数
+  requests_failed_since_restart?: number
+  counters_reset_at?: string
   mode?: string
   architecture?: string
   http_server?: string
@@ -83,15 +94,22 @@ export interface ServerStatus {
 
 // 统计信息
 export interface SystemStats {
-  uptime: string
-  requests_total: number
-  requests_successful: number
-  requests_failed: number
-  endpoints_total: number
-  endpoints_healthy: number
+  success: boolean
+  message?: string
+  timeRange: string // 统计窗口（server.stats_window），与 GetRequestTrends 取值一致
+  totalRequests: number
+  successRequests: number
+  errorsCount: number
+  successRate: number
+  avgResponseTime: number
+  p95ResponseTime: number
+  requestsPerSecond: number
+  systemUptime: number // 秒
+  activeEndpoints: number
+  totalEndpoints: number
   running: boolean
-  last_updated: string
-  architecture: string
+  droppedLogs?: number // 写入队列已满时丢弃的请求日志条数
+  lastUpdated: string
 }
 
 // 请求趋势数据点
@@ -293,6 +311,7 @@ export interface DesktopAPI {
   // 服务器管理
   GetServerStatus(): Promise<ServerStatus>
   RestartServer(): Promise<string>
+  ResetRequestCounters(): Promise<OperationResult>
 
   // 端点管理
   GetEndpoints(): Promise<Endpoint[]>
@@ -307,8 +326,8 @@ export interface DesktopAPI {
 
   // 统计信息
   GetStats(): Promise<any>
-  GetRequestTrends(timeRange: string): Promise<any>
-  GetEndpointStats(): Promise<any>
+  GetRequestTrends(timeRange: string, provider: string, region: string): Promise<any>
+  GetEndpointStats(provider: string, region: string): Promise<any>
   GetSystemInfo(): Promise<any>
 
   // 配置管理
@@ -338,6 +357,7 @@ declare global {
           // 服务器管理
           GetServerStatus(): Promise<ServerStatus>
           RestartServer(): Promise<string>
+          ResetRequestCounters(): Promise<any>
 
           // 端点管理
           GetEndpoints(): Promise<any[]>
@@ -352,8 +372,8 @@ declare global {
 
           // 统计信息
           GetStats(): Promise<any>
-          GetRequestTrends(timeRange: string): Promise<any>
-          GetEndpointStats(): Promise<any>
+          GetRequestTrends(timeRange: string, provider: string, region: string): Promise<any>
+          GetEndpointStats(provider: string, region: string): Promise<any>
           GetSystemInfo(): Promise<any>
 
           // 配置管理
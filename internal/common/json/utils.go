@@ -135,7 +135,8 @@ func IsValidJSON(data []byte) bool {
 	return ValidateJSON(data) == nil
 }
 
-// PrettyPrint 美化打印JSON
+// PrettyPrint 美化打印JSON，仅用于配置文件、导出等面向人阅读的输出；
+// 代理响应体的重序列化一律使用 SafeMarshal 保持紧凑
 func PrettyPrint(v interface{}) (string, error) {
 	data, err := json.MarshalIndent(v, "", "  ")
 	if err != nil {
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"strings"
 	"time"
 )
 
@@ -108,6 +109,9 @@ type DefaultValues struct {
 	// （已移除）ToolCalling 全局默认：采用零配置 + 端点级自动学习/开关
 }
 
+// DefaultAnthropicVersion 未配置时发送给 Anthropic 端点的 anthropic-version
+const DefaultAnthropicVersion = "2023-06-01"
+
 // Default 全局默认值实例
 var Default = DefaultValues{
 	Server: struct {
@@ -162,7 +166,7 @@ var Default = DefaultValues{
 			"Accept-Language": "*",
 			"Anthropic-Beta":  "fine-grained-tool-streaming-2025-05-14",
 			"Anthropic-Dangerous-Direct-Browser-Access": "true",
-			"Anthropic-Version":                         "2023-06-01",
+			"Anthropic-Version":                         DefaultAnthropicVersion,
 			"Connection":                                "keep-alive",
 			"Content-Type":                              "application/json",
 			"Sec-Fetch-Mode":                            "cors",
@@ -322,3 +326,24 @@ func GetBoolWithDefault(configValue bool, hasValue bool, defaultValue bool) bool
 	}
 	return configValue
 }
+
+// ResolveAnthropicVersion 决定实际发送的 anthropic-version：
+// 端点显式覆盖优先，其次保留客户端发送的值，最后使用 DefaultAnthropicVersion
+func ResolveAnthropicVersion(endpointOverride, clientValue string) string {
+	if override := strings.TrimSpace(endpointOverride); override != "" {
+		return override
+	}
+	if client := strings.TrimSpace(clientValue); client != "" {
+		return client
+	}
+	return DefaultAnthropicVersion
+}
+
+// ResolveUserAgent 决定转发时覆盖的 User-Agent：端点配置优先，其次全局默认值；
+// 返回空字符串表示不覆盖，保留客户端发送的 User-Agent
+func ResolveUserAgent(endpointOverride, globalDefault string) string {
+	if override := strings.TrimSpace(endpointOverride); override != "" {
+		return override
+	}
+	return strings.TrimSpace(globalDefault)
+}
@@ -0,0 +1,46 @@
+package config
+
+import "testing"
+
+func TestResolveAnthropicVersion(t *testing.T) {
+	cases := []struct {
+		override, client, expected string
+	}{
+		{"", "", DefaultAnthropicVersion},
+		{"", "2024-01-01", "2024-01-01"},
+		{"2023-01-01", "2024-01-01", "2023-01-01"},
+		{"  ", " ", DefaultAnthropicVersion},
+	}
+	for _, c := range cases {
+		if got := ResolveAnthropicVersion(c.override, c.client); got != c.expected {
+			t.Errorf("override=%q client=%q: expected %q, got %q", c.override, c.client, c.expected, got)
+		}
+	}
+}
+
+func TestResolveUserAgent(t *testing.T) {
+	if got := ResolveUserAgent("", ""); got != "" {
+		t.Errorf("expected no override, got %q", got)
+	}
+	if got := ResolveUserAgent("", "cccc/1.0"); got != "cccc/1.0" {
+		t.Errorf("expected global default, got %q", got)
+	}
+	if got := ResolveUserAgent("MyApp/2.0", "cccc/1.0"); got != "MyApp/2.0" {
+		t.Errorf("expected endpoint override, got %q", got)
+	}
+}
+
+func TestValidateUserAgent(t *testing.T) {
+	valid := []string{"", "MyApp/1.0 (+https://example.com)"}
+	for _, ua := range valid {
+		if err := ValidateUserAgent(ua); err != nil {
+			t.Errorf("expected %q to be valid, got %v", ua, err)
+		}
+	}
+	invalid := []string{"MyApp\r\nX-Injected: 1", " padded ", "tab\there", "ünicode", string(make([]byte, MaxUserAgentLength+1))}
+	for _, ua := range invalid {
+		if err := ValidateUserAgent(ua); err == nil {
+			t.Errorf("expected %q to be rejected", ua)
+		}
+	}
+}
@@ -10,20 +10,56 @@ type EndpointConfig struct {
 	AuthValue          string              `yaml:"auth_value" json:"auth_value"`
 	Enabled            bool                `yaml:"enabled" json:"enabled"`
 	Priority           int                 `yaml:"priority" json:"priority"`
-	Tags               []string            `yaml:"tags" json:"tags"`                                                       // 支持的tag列表
-	ModelRewrite       *ModelRewriteConfig `yaml:"model_rewrite,omitempty" json:"model_rewrite,omitempty"`                 // 模型重写配置
-	Proxy              *ProxyConfig        `yaml:"proxy,omitempty" json:"proxy,omitempty"`                                 // 代理配置
-	OAuthConfig        *OAuthConfig        `yaml:"oauth_config,omitempty" json:"oauth_config,omitempty"`                   // OAuth配置
-	HeaderOverrides    map[string]string   `yaml:"header_overrides,omitempty" json:"header_overrides,omitempty"`           // HTTP Header覆盖配置
-	ParameterOverrides map[string]string   `yaml:"parameter_overrides,omitempty" json:"parameter_overrides,omitempty"`     // Request Parameters覆盖配置
-	MaxTokensFieldName string              `yaml:"max_tokens_field_name,omitempty" json:"max_tokens_field_name,omitempty"` // max_tokens 参数名转换选项
-	RateLimitReset     *int64              `yaml:"rate_limit_reset,omitempty" json:"rate_limit_reset,omitempty"`           // Anthropic-Ratelimit-Unified-Reset
-	RateLimitStatus    *string             `yaml:"rate_limit_status,omitempty" json:"rate_limit_status,omitempty"`         // Anthropic-Ratelimit-Unified-Status
-	EnhancedProtection bool                `yaml:"enhanced_protection,omitempty" json:"enhanced_protection,omitempty"`     // 官方帐号增强保护：allowed_warning时即禁用端点
-	SSEConfig          *SSEConfig          `yaml:"sse_config,omitempty" json:"sse_config,omitempty"`                       // SSE行为配置
-	OpenAIPreference   string              `yaml:"openai_preference,omitempty" json:"openai_preference,omitempty"`         // OpenAI格式偏好："responses"|"chat_completions"|"auto"
-	CountTokensEnabled *bool               `yaml:"count_tokens_enabled,omitempty" json:"count_tokens_enabled,omitempty"`   // 是否允许使用 /count_tokens 接口
-	SupportsResponses  *bool               `yaml:"supports_responses,omitempty" json:"supports_responses,omitempty"`       // 显式声明是否原生支持 /responses 接口
+	Tags               []string            `yaml:"tags" json:"tags"`                                                                   // 支持的tag列表
+	ModelRewrite       *ModelRewriteConfig `yaml:"model_rewrite,omitempty" json:"model_rewrite,omitempty"`                             // 模型重写配置
+	Proxy              *ProxyConfig        `yaml:"proxy,omitempty" json:"proxy,omitempty"`                                             // 代理配置
+	OAuthConfig        *OAuthConfig        `yaml:"oauth_config,omitempty" json:"oauth_config,omitempty"`                               // OAuth配置
+	HeaderOverrides    map[string]string   `yaml:"header_overrides,omitempty" json:"header_overrides,omitempty"`                       // HTTP Header覆盖配置
+	ParameterOverrides map[string]string   `yaml:"parameter_overrides,omitempty" json:"parameter_overrides,omitempty"`                 // Request Parameters覆盖配置
+	MaxTokensFieldName string              `yaml:"max_tokens_field_name,omitempty" json:"max_tokens_field_name,omitempty"`             // max_tokens 参数名转换选项
+	RateLimitReset     *int64              `yaml:"rate_limit_reset,omitempty" json:"rate_limit_reset,omitempty"`                       // Anthropic-Ratelimit-Unified-Reset
+	RateLimitStatus    *string             `yaml:"rate_limit_status,omitempty" json:"rate_limit_status,omitempty"`                     // Anthropic-Ratelimit-Unified-Status
+	EnhancedProtection bool                `yaml:"enhanced_protection,omitempty" json:"enhanced_protection,omitempty"`                 // 官方帐号增强保护：allowed_warning时即禁用端点
+	SSEConfig          *SSEConfig          `yaml:"sse_config,omitempty" json:"sse_config,omitempty"`                                   // SSE行为配置
+	OpenAIPreference   string              `yaml:"openai_preference,omitempty" json:"openai_preference,omitempty"`                     // OpenAI格式偏好："responses"|"chat_completions"|"auto"
+	CountTokensEnabled *bool               `yaml:"count_tokens_enabled,omitempty" json:"count_tokens_enabled,omitempty"`               // 是否允许使用 /count_tokens 接口
+	SupportsResponses  *bool               `yaml:"supports_responses,omitempty" json:"supports_responses,omitempty"`                   // 显式声明是否原生支持 /responses 接口
+	ExtraSystemPrompt  string              `yaml:"extra_system_prompt,omitempty" json:"extra_system_prompt,omitempty"`                 // 额外注入的系统提示词，支持 {{date}}/{{client_type}}/{{model}} 模板变量
+	ForceThinking      bool                `yaml:"force_thinking,omitempty" json:"force_thinking,omitempty"`                           // 请求未携带 thinking/reasoning 参数时注入默认预算
+	DisableThinking    bool                `yaml:"disable_thinking,omitempty" json:"disable_thinking,omitempty"`                       // 移除请求中的 thinking/reasoning 参数
+	UserFieldMode      string              `yaml:"user_field_mode,omitempty" json:"user_field_mode,omitempty"`                         // OpenAI user 字段处理：passthrough|strip|hash|truncate（默认）
+	AnthropicVersion   string              `yaml:"anthropic_version,omitempty" json:"anthropic_version,omitempty"`                     // 覆盖 anthropic-version 请求头（为空时保留客户端值或使用默认版本）
+	UserAgent          string              `yaml:"user_agent,omitempty" json:"user_agent,omitempty"`                                   // 覆盖转发请求的 User-Agent（为空时使用全局默认值或保留客户端值）
+	StripReasoning     bool                `yaml:"strip_reasoning_in_response,omitempty" json:"strip_reasoning_in_response,omitempty"` // 返回客户端前移除响应中的 thinking/reasoning 内容
+	InsecureSkipVerify bool                `yaml:"insecure_skip_verify,omitempty" json:"insecure_skip_verify,omitempty"`               // 跳过 TLS 证书校验（仅用于自签名证书的内网端点）
+	DefaultMaxTokens   int                 `yaml:"default_max_tokens,omitempty" json:"default_max_tokens,omitempty"`                   // 请求未携带 max_tokens 时注入的默认值（0 表示使用全局 server.default_max_tokens）
+	MaxTokensCeiling   int                 `yaml:"max_tokens_ceiling,omitempty" json:"max_tokens_ceiling,omitempty"`                   // 客户端 max_tokens 的上限，超过时截断（0 表示使用全局 server.max_tokens_ceiling）
+
+	// 返回客户端前的响应头覆盖：空值删除该头部，非空值设置（Content-Length 等由代理计算的头部除外）
+	ResponseHeaderOverrides map[string]string `yaml:"response_header_overrides,omitempty" json:"response_header_overrides,omitempty"`
+	// 流式代理时丢弃的 SSE 事件类型（如 ping、x_*），协议必需的事件不受影响
+	SSEEventFilter []string `yaml:"sse_event_filter,omitempty" json:"sse_event_filter,omitempty"`
+	// 每分钟最多转发的请求数（滑动窗口），用尽时跳过该端点；0 表示不限制
+	MaxRequestsPerMinute int `yaml:"max_requests_per_minute,omitempty" json:"max_requests_per_minute,omitempty"`
+	// 请求签名：对最终请求体计算 HMAC 写入 HMACHeader；HMACSecret 支持 env:NAME / ${NAME} 引用环境变量
+	HMACHeader string `yaml:"hmac_header,omitempty" json:"hmac_header,omitempty"`
+	HMACSecret string `yaml:"hmac_secret,omitempty" json:"hmac_secret,omitempty"`
+	HMACAlgo   string `yaml:"hmac_algo,omitempty" json:"hmac_algo,omitempty"` // sha256（默认）|sha512|sha1
+	// 流式 Chat Completions 请求是否注入 stream_options.include_usage（nil 表示默认开启；不支持该字段的上游可关闭）
+	StreamIncludeUsage *bool `yaml:"stream_include_usage,omitempty" json:"stream_include_usage,omitempty"`
+	// 上游返回 4xx 时是否尝试下一端点（nil 表示沿用全局 server.fallback_on_4xx）
+	FallbackOn4xx *bool `yaml:"fallback_on_4xx,omitempty" json:"fallback_on_4xx,omitempty"`
+	// 最终请求体的 JSONPath 改写（按顺序执行 set/delete），参数覆盖的嵌套路径版本
+	BodyTransforms []BodyTransform `yaml:"body_transforms,omitempty" json:"body_transforms,omitempty"`
+	// 转发时注入的 OpenAI-Organization / OpenAI-Project 请求头（组织/项目受限的 API Key 需要），为空时保留客户端值
+	OpenAIOrganization string `yaml:"openai_organization,omitempty" json:"openai_organization,omitempty"`
+	OpenAIProject      string `yaml:"openai_project,omitempty" json:"openai_project,omitempty"`
+	// 是否支持音频输入/输出（modalities 含 audio 或 input_audio 内容块）；未开启的端点在路由时跳过音频请求
+	SupportsAudio bool `yaml:"supports_audio,omitempty" json:"supports_audio,omitempty"`
+	// 流式响应中 thinking 内容的处理方式：passthrough|text|drop，为空时使用 server.thinking_stream_mode
+	ThinkingStreamMode string `yaml:"thinking_stream_mode,omitempty" json:"thinking_stream_mode,omitempty"`
+	// 端点允许的模型（支持 glob 通配）；非空时最终请求模型不在列表中的请求跳过该端点
+	AllowedModels []string `yaml:"allowed_models,omitempty" json:"allowed_models,omitempty"`
 
 	// 新增：智能转换标记（方案A核心字段）
 	NativeFormat bool   `yaml:"native_format,omitempty" json:"native_format,omitempty"` // 是否原生支持客户端格式（true=无需转换）
@@ -82,6 +118,13 @@ type OAuthConfig struct {
 	AutoRefresh  bool     `yaml:"auto_refresh" json:"auto_refresh"`               // 是否自动刷新
 }
 
+// BodyTransform 端点请求体改写操作：op 为 set（写入 value）或 delete，path 为 JSONPath（如 $.messages[0].name）
+type BodyTransform struct {
+	Op    string      `yaml:"op" json:"op"`
+	Path  string      `yaml:"path" json:"path"`
+	Value interface{} `yaml:"value,omitempty" json:"value,omitempty"`
+}
+
 // 新增：模型重写配置结构
 type ModelRewriteConfig struct {
 	Enabled     bool               `yaml:"enabled" json:"enabled"`                               // 是否启用模型重写
@@ -91,8 +134,9 @@ type ModelRewriteConfig struct {
 
 // 新增：模型重写规则
 type ModelRewriteRule struct {
-	SourcePattern string `yaml:"source_pattern" json:"source_pattern"` // 源模型通配符模式
-	TargetModel   string `yaml:"target_model" json:"target_model"`     // 目标模型名称
+	SourcePattern string `yaml:"source_pattern" json:"source_pattern"`             // 源模型通配符模式
+	TargetModel   string `yaml:"target_model" json:"target_model"`                 // 目标模型名称
+	MatchType     string `yaml:"match_type,omitempty" json:"match_type,omitempty"` // 匹配方式：glob（默认）| regex | exact
 }
 
 type LoggingConfig struct {
@@ -101,7 +145,8 @@ type LoggingConfig struct {
 	LogRequestBody  string   `yaml:"log_request_body"`
 	LogResponseBody string   `yaml:"log_response_body"`
 	LogDirectory    string   `yaml:"log_directory"`
-	ExcludePaths    []string `yaml:"exclude_paths,omitempty"` // 新增：不记录日志的路径列表
+	ExcludePaths    []string `yaml:"exclude_paths,omitempty"`    // 新增：不记录日志的路径列表
+	BodySampleRate  *float64 `yaml:"body_sample_rate,omitempty"` // 记录完整请求/响应体的请求比例（0.0-1.0），未设置时全部记录
 }
 
 type ValidationConfig struct {
@@ -209,7 +254,32 @@ type ConversionConfig struct {
 
 // RetryConfig 重试策略配置
 type RetryConfig struct {
-	UpstreamErrors []UpstreamErrorRule `yaml:"upstream_errors" json:"upstream_errors"`
+	UpstreamErrors    []UpstreamErrorRule `yaml:"upstream_errors" json:"upstream_errors"`
+	RetriableStatuses []int               `yaml:"retriable_statuses,omitempty" json:"retriable_statuses,omitempty"` // 允许切换端点重试的状态码；为空表示所有 4xx/5xx 都可重试
+	TerminalStatuses  []int               `yaml:"terminal_statuses,omitempty" json:"terminal_statuses,omitempty"`   // 直接返回给客户端、不再尝试其他端点的状态码
+}
+
+// IsTerminalStatus 判断上游错误状态码是否应直接返回给客户端而不再尝试其他端点：
+// 命中 terminal_statuses 为终止；配置了 retriable_statuses 时未列出的错误状态码也视为终止；
+// 两者均未配置时所有 4xx/5xx 都可重试（保持原有行为）
+func (c RetryConfig) IsTerminalStatus(statusCode int) bool {
+	if statusCode < 400 {
+		return false
+	}
+	for _, code := range c.TerminalStatuses {
+		if code == statusCode {
+			return true
+		}
+	}
+	if len(c.RetriableStatuses) == 0 {
+		return false
+	}
+	for _, code := range c.RetriableStatuses {
+		if code == statusCode {
+			return false
+		}
+	}
+	return true
 }
 
 // UpstreamErrorRule 定义上游错误的匹配与处理方式
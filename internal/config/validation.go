@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"time"
 )
@@ -87,6 +88,10 @@ func validateConfig(config *Config) error {
 		return fmt.Errorf("invalid log_response_body '%s', must be one of: none, truncated, full", config.Logging.LogResponseBody)
 	}
 
+	if rate := config.Logging.BodySampleRate; rate != nil && (*rate < 0 || *rate > 1) {
+		return fmt.Errorf("invalid body_sample_rate %v, must be between 0.0 and 1.0", *rate)
+	}
+
 	// 验证Tagging配置
 	if err := validateTaggingConfig(&config.Tagging); err != nil {
 		return fmt.Errorf("tagging configuration error: %v", err)
@@ -255,6 +260,26 @@ func validateRetryConfig(cfg *RetryConfig) error {
 		}
 	}
 
+	return ValidateRetryStatuses(cfg.RetriableStatuses, cfg.TerminalStatuses)
+}
+
+// ValidateRetryStatuses 校验 retriable_statuses/terminal_statuses：必须是 400-599 的状态码且不能同时出现在两个列表中
+func ValidateRetryStatuses(retriable, terminal []int) error {
+	seen := make(map[int]string)
+	for _, group := range []struct {
+		name  string
+		codes []int
+	}{{"retriable_statuses", retriable}, {"terminal_statuses", terminal}} {
+		for _, code := range group.codes {
+			if code < 400 || code > 599 {
+				return fmt.Errorf("%s: invalid status code %d (must be 400-599)", group.name, code)
+			}
+			if previous, exists := seen[code]; exists && previous != group.name {
+				return fmt.Errorf("status code %d cannot be both retriable and terminal", code)
+			}
+			seen[code] = group.name
+		}
+	}
 	return nil
 }
 
@@ -361,9 +386,20 @@ func validateModelRewriteConfig(config *ModelRewriteConfig, context string) erro
 		}
 		seenPatterns[rule.SourcePattern] = true
 
-		// 验证通配符模式语法（尝试用一个测试字符串匹配）
-		if _, err := filepath.Match(rule.SourcePattern, "test-model"); err != nil {
-			return fmt.Errorf("%s: rule[%d] invalid source_pattern '%s': %v", context, i, rule.SourcePattern, err)
+		// 验证模式语法（与 modelrewrite.MatchModelPattern 一致）：regex 按完整匹配编译正则，
+		// glob 尝试用一个测试字符串匹配，exact 按字面比较无需校验
+		switch strings.ToLower(strings.TrimSpace(rule.MatchType)) {
+		case "regex", "regexp":
+			if _, err := regexp.Compile("^(?:" + rule.SourcePattern + ")$"); err != nil {
+				return fmt.Errorf("%s: rule[%d] invalid regex source_pattern '%s': %v", context, i, rule.SourcePattern, err)
+			}
+		case "exact":
+		case "", "glob":
+			if _, err := filepath.Match(rule.SourcePattern, "test-model"); err != nil {
+				return fmt.Errorf("%s: rule[%d] invalid source_pattern '%s': %v", context, i, rule.SourcePattern, err)
+			}
+		default:
+			return fmt.Errorf("%s: rule[%d] unsupported match_type '%s'", context, i, rule.MatchType)
 		}
 	}
 
@@ -485,6 +521,10 @@ func validateEndpoint(endpoint EndpointConfig, index int) error {
 		return fmt.Errorf("endpoint %d: auth_value cannot be empty for non-oauth authentication", index)
 	}
 
+	if err := ValidateUserAgent(endpoint.UserAgent); err != nil {
+		return fmt.Errorf("endpoint %d: %v", index, err)
+	}
+
 	if endpoint.OpenAIPreference != "" {
 		switch endpoint.OpenAIPreference {
 		case "auto", "responses", "chat_completions":
@@ -497,6 +537,31 @@ func validateEndpoint(endpoint EndpointConfig, index int) error {
 	return nil
 }
 
+// MaxUserAgentLength User-Agent 覆盖值的最大长度
+const MaxUserAgentLength = 512
+
+// ValidateUserAgent 校验 User-Agent 覆盖值：允许为空，否则必须是不含控制字符的单行可打印文本
+func ValidateUserAgent(userAgent string) error {
+	if userAgent == "" {
+		return nil
+	}
+	if len(userAgent) > MaxUserAgentLength {
+		return fmt.Errorf("user_agent is too long (%d bytes, max %d)", len(userAgent), MaxUserAgentLength)
+	}
+	if strings.TrimSpace(userAgent) != userAgent {
+		return fmt.Errorf("user_agent must not have leading or trailing whitespace")
+	}
+	for _, r := range userAgent {
+		if r < 0x20 || r == 0x7f {
+			return fmt.Errorf("user_agent must be a single line without control characters")
+		}
+		if r > 0x7e {
+			return fmt.Errorf("user_agent must contain only printable ASCII characters")
+		}
+	}
+	return nil
+}
+
 // validateConversionConfig 验证转换配置
 func validateConversionConfig(config *ConversionConfig) error {
 	if config == nil {
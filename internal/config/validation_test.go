@@ -0,0 +1,63 @@
+package config
+
+import "testing"
+
+func TestRetryConfigIsTerminalStatus(t *testing.T) {
+	// 默认：所有错误状态码都可重试（保持原有行为）
+	var defaults RetryConfig
+	for _, code := range []int{400, 401, 429, 500, 503} {
+		if defaults.IsTerminalStatus(code) {
+			t.Errorf("expected %d to be retriable by default", code)
+		}
+	}
+
+	cfg := RetryConfig{
+		RetriableStatuses: []int{429, 500, 502, 503, 504},
+		TerminalStatuses:  []int{400},
+	}
+	cases := map[int]bool{200: false, 400: true, 401: true, 429: false, 503: false, 501: true}
+	for code, expected := range cases {
+		if got := cfg.IsTerminalStatus(code); got != expected {
+			t.Errorf("status %d: expected terminal=%v, got %v", code, expected, got)
+		}
+	}
+
+	terminalOnly := RetryConfig{TerminalStatuses: []int{400, 422}}
+	if !terminalOnly.IsTerminalStatus(422) || terminalOnly.IsTerminalStatus(500) {
+		t.Error("expected only the listed statuses to be terminal when no retriable list is set")
+	}
+}
+
+func TestValidateRetryStatuses(t *testing.T) {
+	if err := ValidateRetryStatuses([]int{429, 503}, []int{400}); err != nil {
+		t.Fatalf("expected valid statuses, got %v", err)
+	}
+	if err := ValidateRetryStatuses([]int{429}, []int{429}); err == nil {
+		t.Fatal("expected overlapping statuses to be rejected")
+	}
+	if err := ValidateRetryStatuses([]int{200}, nil); err == nil {
+		t.Fatal("expected non-error status to be rejected")
+	}
+}
+
+func TestValidateModelRewriteMatchTypes(t *testing.T) {
+	valid := &ModelRewriteConfig{Enabled: true, Rules: []ModelRewriteRule{
+		{SourcePattern: "claude-*", TargetModel: "a"},
+		{SourcePattern: `claude-3[.-]5-sonnet(-\d{8})?`, TargetModel: "b", MatchType: "regexp"},
+		{SourcePattern: "gpt-[4", TargetModel: "c", MatchType: "Exact"}, // 精确匹配不按通配符语法校验
+	}}
+	if err := ValidateModelRewriteConfig(valid, "test"); err != nil {
+		t.Fatalf("expected valid rules, got %v", err)
+	}
+
+	for _, rule := range []ModelRewriteRule{
+		{SourcePattern: "gpt-[4", TargetModel: "x"},
+		{SourcePattern: "claude-(", TargetModel: "x", MatchType: "regex"},
+		{SourcePattern: "claude-*", TargetModel: "x", MatchType: "fuzzy"},
+	} {
+		cfg := &ModelRewriteConfig{Enabled: true, Rules: []ModelRewriteRule{rule}}
+		if err := ValidateModelRewriteConfig(cfg, "test"); err == nil {
+			t.Errorf("expected rule %+v to be rejected", rule)
+		}
+	}
+}
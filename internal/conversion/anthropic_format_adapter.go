@@ -44,11 +44,25 @@ func (a *AnthropicFormatAdapter) ParseRequestJSON(payload []byte) (*InternalRequ
 		MaxReasoningTokens: nil,
 	}
 
+	if userID, ok := req.Metadata["user_id"].(string); ok && userID != "" {
+		internal.User = userID
+	}
+
 	if req.DisableParallelToolUse != nil {
 		val := !*req.DisableParallelToolUse
 		internal.ParallelToolCalls = &val
 	}
 
+	if req.ServiceTier != "" {
+		if tier, ok := AnthropicServiceTierToOpenAI(req.ServiceTier); ok {
+			internal.ServiceTier = tier
+		} else if a.logger != nil {
+			a.logger.Info("Dropping unsupported Anthropic service_tier", map[string]interface{}{
+				"service_tier": req.ServiceTier,
+			})
+		}
+	}
+
 	if len(req.Tools) > 0 {
 		for _, tool := range req.Tools {
 			internal.Tools = append(internal.Tools, InternalTool{
@@ -124,6 +138,16 @@ func (a *AnthropicFormatAdapter) BuildRequestJSON(req *InternalRequest) ([]byte,
 		out.Stream = ptrBool(true)
 	}
 
+	// OpenAI user 对应 Anthropic metadata.user_id
+	if req.User != "" {
+		if _, exists := out.Metadata["user_id"]; !exists {
+			if out.Metadata == nil {
+				out.Metadata = map[string]interface{}{}
+			}
+			out.Metadata["user_id"] = req.User
+		}
+	}
+
 	if req.ParallelToolCalls != nil && !*req.ParallelToolCalls {
 		disable := true
 		out.DisableParallelToolUse = &disable
@@ -152,14 +176,50 @@ func (a *AnthropicFormatAdapter) BuildRequestJSON(req *InternalRequest) ([]byte,
 
 	ApplyInternalThinkingToAnthropic(req, &out, newDefaultThinkingMapper(a.logger))
 
+	if req.ServiceTier != "" {
+		if tier, ok := OpenAIServiceTierToAnthropic(req.ServiceTier); ok {
+			out.ServiceTier = tier
+		} else if a.logger != nil {
+			a.logger.Info("Dropping service_tier (no Anthropic equivalent)", map[string]interface{}{
+				"service_tier": req.ServiceTier,
+			})
+		}
+	}
+
+	// Anthropic 不支持 predicted outputs，直接丢弃以免上游返回 400
+	if req.Prediction != nil && a.logger != nil {
+		a.logger.Info("Dropping prediction field (not supported by Anthropic)", map[string]interface{}{
+			"prediction_type": req.Prediction["type"],
+		})
+	}
+
+	// Anthropic has no equivalent of seed; drop it instead of forwarding an unknown field
+	if req.Seed != nil && a.logger != nil {
+		a.logger.Debug("Dropping seed field (not supported by Anthropic)", map[string]interface{}{
+			"seed": *req.Seed,
+		})
+	}
+
 	// Build system prompt + conversational messages
 	var messages []AnthropicMessage
+	lastWasTool := false
 	for _, msg := range req.Messages {
-		if msg.Role == "system" {
+		// OpenAI 允许多条 system/developer 消息穿插出现，Anthropic 只有顶层 system：按出现顺序全部合并
+		if msg.Role == "system" || msg.Role == "developer" {
 			out.System = appendSystemInstruction(out.System, msg)
 			continue
 		}
-		messages = append(messages, internalMessageToAnthropic(msg))
+		anthMsg := internalMessageToAnthropic(msg)
+		if msg.Role == "tool" {
+			// Anthropic 没有 tool 角色：tool_result 放入 user 消息，连续的工具结果合并到同一条消息
+			anthMsg.Role = "user"
+			if n := len(messages); n > 0 && lastWasTool {
+				messages[n-1].Content = append(messages[n-1].GetContentBlocks(), anthMsg.GetContentBlocks()...)
+				continue
+			}
+		}
+		lastWasTool = msg.Role == "tool"
+		messages = append(messages, anthMsg)
 	}
 	out.Messages = messages
 
@@ -432,6 +492,9 @@ func internalMessageToAnthropic(msg InternalMessage) AnthropicMessage {
 		}
 	}
 	for _, call := range msg.ToolCalls {
+		if hasToolUseBlock(blocks, call.ID) {
+			continue
+		}
 		blocks = append(blocks, AnthropicContentBlock{
 			Type: "tool_use",
 			ID:   call.ID,
@@ -446,8 +509,39 @@ func internalMessageToAnthropic(msg InternalMessage) AnthropicMessage {
 	}
 	return AnthropicMessage{
 		Role:    msg.Role,
-		Content: blocks,
+		Content: dropEmptyTextBlocks(blocks),
+	}
+}
+
+// hasToolUseBlock 避免同一工具调用同时来自 Contents 与 ToolCalls 时重复输出
+func hasToolUseBlock(blocks []AnthropicContentBlock, id string) bool {
+	if id == "" {
+		return false
+	}
+	for _, block := range blocks {
+		if block.Type == "tool_use" && block.ID == id {
+			return true
+		}
 	}
+	return false
+}
+
+// dropEmptyTextBlocks 移除与其他内容并存的空文本块（Anthropic 拒绝空 text 块）
+func dropEmptyTextBlocks(blocks []AnthropicContentBlock) []AnthropicContentBlock {
+	if len(blocks) < 2 {
+		return blocks
+	}
+	filtered := make([]AnthropicContentBlock, 0, len(blocks))
+	for _, block := range blocks {
+		if block.Type == "text" && block.Text == "" {
+			continue
+		}
+		filtered = append(filtered, block)
+	}
+	if len(filtered) == 0 {
+		return blocks[:1]
+	}
+	return filtered
 }
 
 func extractToolResultText(content interface{}) string {
@@ -478,13 +572,16 @@ func extractToolResultText(content interface{}) string {
 }
 
 func appendSystemInstruction(existing interface{}, msg InternalMessage) interface{} {
-	if len(msg.Contents) == 0 {
-		return existing
+	var parts []string
+	for _, content := range msg.Contents {
+		if content.Text != "" {
+			parts = append(parts, content.Text)
+		}
 	}
-	text := msg.Contents[0].Text
-	if text == "" {
+	if len(parts) == 0 {
 		return existing
 	}
+	text := strings.Join(parts, "\n")
 	if existing == nil {
 		return text
 	}
@@ -20,6 +20,7 @@ type AnthropicRequest struct {
 	StopSequences []string    `json:"stop_sequences,omitempty"`
 	Thinking    *AnthropicThinking `json:"thinking,omitempty"` // 将被忽略，OpenAI不支持
 	DisableParallelToolUse *bool `json:"disable_parallel_tool_use,omitempty"`
+	ServiceTier   string      `json:"service_tier,omitempty"` // "auto" | "standard_only"
 }
 
 // AnthropicThinking 思考模式配置
@@ -0,0 +1,193 @@
+package conversion
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	jsonutils "claude-code-codex-companion/internal/common/json"
+)
+
+// ConvertGeminiResponseToOpenAI converts a Gemini generateContent response
+// (candidates[].content.parts) into an OpenAI Chat Completions response.
+func ConvertGeminiResponseToOpenAI(body []byte) ([]byte, error) {
+	resp, err := parseGeminiResponse(body)
+	if err != nil {
+		return nil, err
+	}
+
+	jsonFixer := NewPythonJSONFixer(nil)
+	toolCounter := 0
+	out := OpenAIResponse{
+		ID:      geminiResponseID(resp),
+		Model:   resp.ModelVersion,
+		Choices: make([]OpenAIChoice, 0, len(resp.Candidates)),
+	}
+
+	for i, candidate := range resp.Candidates {
+		message := OpenAIMessage{Role: "assistant"}
+		text := ""
+		for _, part := range candidate.Content.Parts {
+			switch {
+			case part.Thought:
+				// 思考内容没有对应的 OpenAI 字段，直接丢弃
+			case part.FunctionCall != nil:
+				argsBytes, _ := jsonutils.SafeMarshal(part.FunctionCall.Args)
+				message.ToolCalls = append(message.ToolCalls, OpenAIToolCall{
+					ID:   generateToolCallID(part.FunctionCall.Name, toolCounter),
+					Type: "function",
+					Function: OpenAIToolCallDetail{
+						Name:      part.FunctionCall.Name,
+						Arguments: formatToolArguments(string(argsBytes), jsonFixer),
+					},
+				})
+				toolCounter++
+			case part.Text != "":
+				text += part.Text
+			}
+		}
+		if text != "" || len(message.ToolCalls) == 0 {
+			message.Content = text
+		}
+
+		index := candidate.Index
+		if index == 0 {
+			index = i
+		}
+		out.Choices = append(out.Choices, OpenAIChoice{
+			Index:        index,
+			FinishReason: geminiFinishReasonToOpenAI(candidate.FinishReason, len(message.ToolCalls) > 0),
+			Message:      message,
+		})
+	}
+
+	if resp.UsageMetadata != nil {
+		out.Usage = &OpenAIUsage{
+			PromptTokens:     resp.UsageMetadata.PromptTokenCount,
+			CompletionTokens: resp.UsageMetadata.CandidatesTokenCount,
+			TotalTokens:      resp.UsageMetadata.TotalTokenCount,
+		}
+	}
+
+	payload, err := jsonutils.SafeMarshal(out)
+	if err != nil {
+		return nil, err
+	}
+	return withChatCompletionObject(payload)
+}
+
+// ConvertGeminiResponseToAnthropic converts a Gemini generateContent response
+// into an Anthropic message response. Only the first candidate is used because
+// Anthropic messages carry a single completion.
+func ConvertGeminiResponseToAnthropic(body []byte) ([]byte, error) {
+	resp, err := parseGeminiResponse(body)
+	if err != nil {
+		return nil, err
+	}
+
+	jsonFixer := NewPythonJSONFixer(nil)
+	var contentBlocks []AnthropicContentBlock
+	finishReason := ""
+	if len(resp.Candidates) > 0 {
+		candidate := resp.Candidates[0]
+		for i, part := range candidate.Content.Parts {
+			switch {
+			case part.Thought:
+				// Anthropic thinking 块需要签名，无法从 Gemini 思考内容构造，直接丢弃
+			case part.FunctionCall != nil:
+				argsBytes, _ := jsonutils.SafeMarshal(part.FunctionCall.Args)
+				contentBlocks = append(contentBlocks, AnthropicContentBlock{
+					Type:  "tool_use",
+					ID:    generateToolCallID(part.FunctionCall.Name, i),
+					Name:  part.FunctionCall.Name,
+					Input: []byte(formatToolArguments(string(argsBytes), jsonFixer)),
+				})
+			case part.Text != "":
+				// 相邻文本片段合并为一个 text 块
+				if n := len(contentBlocks); n > 0 && contentBlocks[n-1].Type == "text" {
+					contentBlocks[n-1].Text += part.Text
+				} else {
+					contentBlocks = append(contentBlocks, AnthropicContentBlock{Type: "text", Text: part.Text})
+				}
+			}
+		}
+		finishReason = candidate.FinishReason
+	}
+
+	hasToolCalls := false
+	for _, block := range contentBlocks {
+		if block.Type == "tool_use" {
+			hasToolCalls = true
+			break
+		}
+	}
+
+	// Ensure we always return at least one content block to satisfy Anthropic schema.
+	if len(contentBlocks) == 0 {
+		contentBlocks = []AnthropicContentBlock{
+			{Type: "text", Text: ""},
+		}
+	}
+
+	out := AnthropicResponse{
+		ID:         geminiResponseID(resp),
+		Type:       "message",
+		Role:       "assistant",
+		Model:      resp.ModelVersion,
+		Content:    contentBlocks,
+		StopReason: normalizeOpenAIFinishReason(geminiFinishReasonToOpenAI(finishReason, hasToolCalls)),
+	}
+
+	if resp.UsageMetadata != nil {
+		out.Usage = &AnthropicUsage{
+			InputTokens:  resp.UsageMetadata.PromptTokenCount,
+			OutputTokens: resp.UsageMetadata.CandidatesTokenCount,
+		}
+	}
+
+	return jsonutils.SafeMarshal(out)
+}
+
+// parseGeminiResponse 解析 Gemini 非流式响应，拒绝没有候选结果的响应体
+func parseGeminiResponse(body []byte) (*GeminiResponse, error) {
+	if len(body) == 0 {
+		return nil, errors.New("empty response body")
+	}
+
+	var resp GeminiResponse
+	if err := jsonutils.SafeUnmarshal(body, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Candidates) == 0 && resp.UsageMetadata == nil {
+		return nil, errors.New("gemini response has no candidates")
+	}
+	return &resp, nil
+}
+
+// geminiResponseID 使用 Gemini 的 responseId，缺失时生成一个
+func geminiResponseID(resp *GeminiResponse) string {
+	if resp.ResponseID != "" {
+		return resp.ResponseID
+	}
+	return fmt.Sprintf("chatcmpl-%d", time.Now().UnixNano())
+}
+
+// geminiFinishReasonToOpenAI 映射 Gemini finishReason；Gemini 调用函数时仍返回 STOP，需按是否有工具调用修正
+func geminiFinishReasonToOpenAI(reason string, hasToolCalls bool) string {
+	mapped := mapGeminiFinishReason(reason)
+	if hasToolCalls && mapped == "stop" {
+		return "tool_calls"
+	}
+	return mapped
+}
+
+// withChatCompletionObject 补充 object/created 字段，使结果与 OpenAI 非流式响应一致
+func withChatCompletionObject(payload []byte) ([]byte, error) {
+	var data map[string]interface{}
+	if err := jsonutils.SafeUnmarshal(payload, &data); err != nil {
+		return nil, err
+	}
+	data["object"] = "chat.completion"
+	data["created"] = time.Now().Unix()
+	return jsonutils.SafeMarshal(data)
+}
@@ -0,0 +1,134 @@
+package conversion
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const geminiTextResponse = `{
+  "candidates": [{"content": {"role": "model", "parts": [{"text": "Hello, "}, {"text": "world"}]}, "finishReason": "STOP", "index": 0}],
+  "usageMetadata": {"promptTokenCount": 7, "candidatesTokenCount": 3, "totalTokenCount": 10},
+  "modelVersion": "gemini-2.5-pro",
+  "responseId": "resp-123"
+}`
+
+const geminiToolResponse = `{
+  "candidates": [{"content": {"role": "model", "parts": [
+    {"text": "Checking the weather.", "thought": true},
+    {"functionCall": {"name": "get_weather", "args": {"city": "Paris"}}}
+  ]}, "finishReason": "STOP"}],
+  "usageMetadata": {"promptTokenCount": 12, "candidatesTokenCount": 5, "totalTokenCount": 17},
+  "modelVersion": "gemini-2.5-flash"
+}`
+
+func TestConvertGeminiResponseToOpenAI(t *testing.T) {
+	out, err := ConvertGeminiResponseToOpenAI([]byte(geminiTextResponse))
+	if err != nil {
+		t.Fatalf("text conversion failed: %v", err)
+	}
+	var resp OpenAIResponse
+	if err := json.Unmarshal(out, &resp); err != nil {
+		t.Fatalf("invalid OpenAI response: %v", err)
+	}
+	if resp.ID != "resp-123" || resp.Model != "gemini-2.5-pro" || len(resp.Choices) != 1 {
+		t.Fatalf("unexpected response envelope: %s", out)
+	}
+	if resp.Choices[0].Message.Content != "Hello, world" || resp.Choices[0].FinishReason != "stop" {
+		t.Fatalf("unexpected choice: %+v", resp.Choices[0])
+	}
+	if resp.Usage == nil || resp.Usage.PromptTokens != 7 || resp.Usage.CompletionTokens != 3 || resp.Usage.TotalTokens != 10 {
+		t.Fatalf("unexpected usage: %+v", resp.Usage)
+	}
+	if !strings.Contains(string(out), `"object":"chat.completion"`) {
+		t.Fatalf("expected chat.completion object, got %s", out)
+	}
+
+	out, err = ConvertGeminiResponseToOpenAI([]byte(geminiToolResponse))
+	if err != nil {
+		t.Fatalf("tool conversion failed: %v", err)
+	}
+	resp = OpenAIResponse{}
+	if err := json.Unmarshal(out, &resp); err != nil {
+		t.Fatalf("invalid OpenAI response: %v", err)
+	}
+	choice := resp.Choices[0]
+	if choice.FinishReason != "tool_calls" || len(choice.Message.ToolCalls) != 1 {
+		t.Fatalf("expected a single tool call with tool_calls finish reason, got %+v", choice)
+	}
+	call := choice.Message.ToolCalls[0]
+	if call.Function.Name != "get_weather" || call.Function.Arguments != `{"city":"Paris"}` || call.ID == "" {
+		t.Fatalf("unexpected tool call: %+v", call)
+	}
+	if choice.Message.Content != nil {
+		t.Fatalf("expected thought text to be dropped, got content %v", choice.Message.Content)
+	}
+}
+
+func TestConvertGeminiResponseToAnthropic(t *testing.T) {
+	out, err := ConvertGeminiResponseToAnthropic([]byte(geminiTextResponse))
+	if err != nil {
+		t.Fatalf("text conversion failed: %v", err)
+	}
+	var resp AnthropicResponse
+	if err := json.Unmarshal(out, &resp); err != nil {
+		t.Fatalf("invalid Anthropic response: %v", err)
+	}
+	if resp.Type != "message" || resp.Model != "gemini-2.5-pro" || resp.StopReason != "end_turn" {
+		t.Fatalf("unexpected response envelope: %s", out)
+	}
+	if len(resp.Content) != 1 || resp.Content[0].Type != "text" || resp.Content[0].Text != "Hello, world" {
+		t.Fatalf("expected merged text block, got %+v", resp.Content)
+	}
+	if resp.Usage == nil || resp.Usage.InputTokens != 7 || resp.Usage.OutputTokens != 3 {
+		t.Fatalf("unexpected usage: %+v", resp.Usage)
+	}
+
+	out, err = ConvertGeminiResponseToAnthropic([]byte(geminiToolResponse))
+	if err != nil {
+		t.Fatalf("tool conversion failed: %v", err)
+	}
+	resp = AnthropicResponse{}
+	if err := json.Unmarshal(out, &resp); err != nil {
+		t.Fatalf("invalid Anthropic response: %v", err)
+	}
+	if resp.StopReason != "tool_use" || len(resp.Content) != 1 {
+		t.Fatalf("expected a single tool_use block, got %s", out)
+	}
+	block := resp.Content[0]
+	if block.Type != "tool_use" || block.Name != "get_weather" || string(block.Input) != `{"city":"Paris"}` {
+		t.Fatalf("unexpected tool_use block: %+v", block)
+	}
+}
+
+func TestConvertGeminiResponseRejectsEmpty(t *testing.T) {
+	if _, err := ConvertGeminiResponseToOpenAI(nil); err == nil {
+		t.Fatal("expected error for empty body")
+	}
+	if _, err := ConvertGeminiResponseToAnthropic([]byte(`{"candidates":[]}`)); err == nil {
+		t.Fatal("expected error for response without candidates")
+	}
+}
+
+func TestStreamGeminiToolCallFinishReason(t *testing.T) {
+	geminiSSE := `data: {"candidates":[{"content":{"parts":[{"functionCall":{"name":"get_weather","args":{"city":"Paris"}}}]},"finishReason":"STOP"}],"modelVersion":"gemini-2.5-flash"}
+
+data: [DONE]
+`
+	var openai bytes.Buffer
+	if err := StreamGeminiSSEToOpenAI(strings.NewReader(geminiSSE), &openai); err != nil {
+		t.Fatalf("StreamGeminiSSEToOpenAI failed: %v", err)
+	}
+	if !strings.Contains(openai.String(), `"finish_reason":"tool_calls"`) || !strings.Contains(openai.String(), `"model":"gemini-2.5-flash"`) {
+		t.Fatalf("expected tool_calls finish reason and model version, got %s", openai.String())
+	}
+
+	var anthropic bytes.Buffer
+	if err := StreamGeminiSSEToAnthropic(strings.NewReader(geminiSSE), &anthropic); err != nil {
+		t.Fatalf("StreamGeminiSSEToAnthropic failed: %v", err)
+	}
+	if !strings.Contains(anthropic.String(), `"stop_reason":"tool_use"`) {
+		t.Fatalf("expected tool_use stop reason, got %s", anthropic.String())
+	}
+}
@@ -81,6 +81,8 @@ type GeminiThinkingConfig struct {
 type GeminiResponse struct {
 	Candidates    []GeminiCandidate     `json:"candidates"`
 	UsageMetadata *GeminiUsageMetadata  `json:"usageMetadata,omitempty"`
+	ModelVersion  string                `json:"modelVersion,omitempty"`
+	ResponseID    string                `json:"responseId,omitempty"`
 }
 
 // GeminiCandidate 候选响应
@@ -24,10 +24,15 @@ type InternalRequest struct {
 	FrequencyPenalty    *float64                `json:"frequency_penalty,omitempty"`
 	LogitBias           map[string]float64      `json:"logit_bias,omitempty"`
 	N                   *int                    `json:"n,omitempty"`
+	Seed                *int                    `json:"seed,omitempty"`
 	ResponseFormat      *InternalResponseFormat `json:"response_format,omitempty"`
 	ReasoningEffort     *string                 `json:"reasoning_effort,omitempty"`
 	MaxReasoningTokens  *int                    `json:"max_reasoning_tokens,omitempty"`
 	Thinking            *InternalThinking       `json:"thinking,omitempty"`
+	ServiceTier         string                  `json:"service_tier,omitempty"` // OpenAI 取值
+	Prediction          map[string]interface{}  `json:"prediction,omitempty"`   // OpenAI predicted outputs，仅 Chat Completions 支持
+	Store               *bool                   `json:"store,omitempty"`        // 是否在服务端持久化结果
+	Include             []string                `json:"include,omitempty"`      // Responses API 额外返回的输出项
 }
 
 // InternalMessage represents a role based message comprised of structured
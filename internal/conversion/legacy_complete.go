@@ -0,0 +1,141 @@
+package conversion
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	jsonutils "claude-code-codex-companion/internal/common/json"
+)
+
+const (
+	legacyHumanPrompt     = "\n\nHuman:"
+	legacyAssistantPrompt = "\n\nAssistant:"
+)
+
+// ConvertLegacyCompleteRequestToChat converts an Anthropic legacy /v1/complete
+// request ("\n\nHuman: ...\n\nAssistant:" prompt) into an OpenAI Chat Completions
+// request. Streaming is always disabled because the legacy SSE format is not
+// produced from OpenAI chunks.
+func ConvertLegacyCompleteRequestToChat(body []byte) ([]byte, error) {
+	if len(body) == 0 {
+		return nil, errors.New("empty request body")
+	}
+
+	var req map[string]interface{}
+	if err := jsonutils.SafeUnmarshal(body, &req); err != nil {
+		return nil, err
+	}
+	prompt, _ := req["prompt"].(string)
+	if strings.TrimSpace(prompt) == "" {
+		return nil, errors.New("legacy completion request has no prompt")
+	}
+
+	chat := map[string]interface{}{
+		"messages": legacyPromptToMessages(prompt),
+		"stream":   false,
+	}
+	if model, ok := req["model"]; ok {
+		chat["model"] = model
+	}
+	if maxTokens, ok := req["max_tokens_to_sample"]; ok {
+		chat["max_tokens"] = maxTokens
+	}
+	if stops, ok := req["stop_sequences"].([]interface{}); ok && len(stops) > 0 {
+		chat["stop"] = stops
+	}
+	for _, key := range []string{"temperature", "top_p"} {
+		if value, ok := req[key]; ok {
+			chat[key] = value
+		}
+	}
+	if metadata, ok := req["metadata"].(map[string]interface{}); ok {
+		if userID, ok := metadata["user_id"].(string); ok && userID != "" {
+			chat["user"] = userID
+		}
+	}
+
+	return jsonutils.SafeMarshal(chat)
+}
+
+// legacyPromptToMessages 按 Human/Assistant 轮次拆分 prompt；首个轮次之前的文本作为 system，结尾的空 Assistant 轮次丢弃
+func legacyPromptToMessages(prompt string) []map[string]interface{} {
+	messages := make([]map[string]interface{}, 0, 4)
+	appendMessage := func(role, content string) {
+		content = strings.TrimSpace(content)
+		if content == "" {
+			return
+		}
+		messages = append(messages, map[string]interface{}{"role": role, "content": content})
+	}
+
+	role := "system"
+	rest := prompt
+	for {
+		humanIdx := strings.Index(rest, legacyHumanPrompt)
+		assistantIdx := strings.Index(rest, legacyAssistantPrompt)
+		next, nextRole, markerLen := -1, "", 0
+		switch {
+		case humanIdx >= 0 && (assistantIdx < 0 || humanIdx < assistantIdx):
+			next, nextRole, markerLen = humanIdx, "user", len(legacyHumanPrompt)
+		case assistantIdx >= 0:
+			next, nextRole, markerLen = assistantIdx, "assistant", len(legacyAssistantPrompt)
+		}
+		if next < 0 {
+			appendMessage(role, rest)
+			break
+		}
+		appendMessage(role, rest[:next])
+		role = nextRole
+		rest = rest[next+markerLen:]
+	}
+
+	if len(messages) == 0 || messages[0]["role"] == "system" && len(messages) == 1 {
+		// 没有轮次标记的 prompt 整体作为用户消息
+		return []map[string]interface{}{{"role": "user", "content": strings.TrimSpace(prompt)}}
+	}
+	return messages
+}
+
+// ConvertChatResponseToLegacyComplete converts an OpenAI Chat Completions response
+// into the Anthropic legacy completion response shape.
+func ConvertChatResponseToLegacyComplete(body []byte) ([]byte, error) {
+	if len(body) == 0 {
+		return nil, errors.New("empty response body")
+	}
+
+	var resp OpenAIResponse
+	if err := jsonutils.SafeUnmarshal(body, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Choices) == 0 {
+		return nil, errors.New("chat response has no choices")
+	}
+
+	choice := resp.Choices[0]
+	completion, _ := choice.Message.Content.(string)
+	id := resp.ID
+	if id == "" {
+		id = fmt.Sprintf("compl_%d", time.Now().UnixNano())
+	}
+
+	return jsonutils.SafeMarshal(map[string]interface{}{
+		"id":          id,
+		"type":        "completion",
+		"completion":  completion,
+		"stop_reason": LegacyCompleteStopReason(choice.FinishReason),
+		"model":       resp.Model,
+	})
+}
+
+// LegacyCompleteStopReason maps an OpenAI finish_reason to the legacy completion
+// stop_reason, which only distinguishes "stop_sequence" and "max_tokens".
+func LegacyCompleteStopReason(finishReason string) string {
+	switch finishReason {
+	case "length":
+		return "max_tokens"
+	default:
+		return "stop_sequence"
+	}
+}
@@ -0,0 +1,72 @@
+package conversion
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestConvertLegacyCompleteRequestToChat(t *testing.T) {
+	body := []byte(`{"model":"claude-2.1","prompt":"Be terse.\n\nHuman: hi\n\nAssistant: hello\n\nHuman: bye\n\nAssistant:","max_tokens_to_sample":64,"stop_sequences":["\n\nHuman:"],"stream":true}`)
+	converted, err := ConvertLegacyCompleteRequestToChat(body)
+	if err != nil {
+		t.Fatalf("conversion failed: %v", err)
+	}
+
+	var chat struct {
+		Model     string              `json:"model"`
+		MaxTokens int                 `json:"max_tokens"`
+		Stop      []string            `json:"stop"`
+		Stream    bool                `json:"stream"`
+		Messages  []map[string]string `json:"messages"`
+	}
+	if err := json.Unmarshal(converted, &chat); err != nil {
+		t.Fatalf("invalid chat request: %v", err)
+	}
+	if chat.Model != "claude-2.1" || chat.MaxTokens != 64 || chat.Stream {
+		t.Fatalf("unexpected request fields: %+v", chat)
+	}
+	if len(chat.Stop) != 1 || chat.Stop[0] != "\n\nHuman:" {
+		t.Errorf("expected stop_sequences to map to stop, got %v", chat.Stop)
+	}
+
+	expected := [][2]string{{"system", "Be terse."}, {"user", "hi"}, {"assistant", "hello"}, {"user", "bye"}}
+	if len(chat.Messages) != len(expected) {
+		t.Fatalf("expected %d messages, got %v", len(expected), chat.Messages)
+	}
+	for i, want := range expected {
+		if chat.Messages[i]["role"] != want[0] || chat.Messages[i]["content"] != want[1] {
+			t.Errorf("message %d: expected %v, got %v", i, want, chat.Messages[i])
+		}
+	}
+
+	if _, err := ConvertLegacyCompleteRequestToChat([]byte(`{"model":"claude-2.1"}`)); err == nil {
+		t.Error("expected an error for a request without prompt")
+	}
+}
+
+func TestConvertChatResponseToLegacyComplete(t *testing.T) {
+	cases := map[string]string{
+		"stop":       "stop_sequence",
+		"length":     "max_tokens",
+		"tool_calls": "stop_sequence",
+		"":           "stop_sequence",
+	}
+	for finishReason, want := range cases {
+		body := []byte(`{"id":"chatcmpl-1","model":"gpt-4o","choices":[{"index":0,"finish_reason":"` + finishReason + `","message":{"role":"assistant","content":"Hello!"}}]}`)
+		converted, err := ConvertChatResponseToLegacyComplete(body)
+		if err != nil {
+			t.Fatalf("finish_reason %q: conversion failed: %v", finishReason, err)
+		}
+
+		var legacy map[string]interface{}
+		if err := json.Unmarshal(converted, &legacy); err != nil {
+			t.Fatalf("invalid legacy response: %v", err)
+		}
+		if legacy["type"] != "completion" || legacy["completion"] != "Hello!" || legacy["model"] != "gpt-4o" {
+			t.Errorf("unexpected legacy response: %v", legacy)
+		}
+		if legacy["stop_reason"] != want {
+			t.Errorf("finish_reason %q: expected stop_reason %q, got %v", finishReason, want, legacy["stop_reason"])
+		}
+	}
+}
@@ -49,9 +49,16 @@ func (o *OpenAIChatFormatAdapter) ParseRequestJSON(payload []byte) (*InternalReq
 		FrequencyPenalty:    req.FrequencyPenalty,
 		LogitBias:           cloneLogitBias(req.LogitBias),
 		N:                   req.N,
+		Seed:                req.Seed,
 		ResponseFormat:      convertOpenAIResponseFormatToInternal(req.ResponseFormat),
 		ReasoningEffort:     req.ReasoningEffort,
 		MaxReasoningTokens:  req.MaxReasoningTokens,
+		ServiceTier:         req.ServiceTier,
+		Prediction:          cloneAnyMap(req.Prediction),
+		Store:               req.Store,
+	}
+	if req.Logprobs != nil && *req.Logprobs {
+		internal.Include = []string{ResponsesIncludeOutputLogprobs}
 	}
 
 	internal.Messages = openAIMessagesToInternal(req.Messages)
@@ -83,13 +90,30 @@ func (o *OpenAIChatFormatAdapter) BuildRequestJSON(req *InternalRequest) ([]byte
 		FrequencyPenalty:    req.FrequencyPenalty,
 		LogitBias:           cloneLogitBias(req.LogitBias),
 		N:                   req.N,
+		Seed:                req.Seed,
 		ResponseFormat:      convertInternalResponseFormatToOpenAI(req.ResponseFormat),
 		ReasoningEffort:     req.ReasoningEffort,
 		MaxReasoningTokens:  req.MaxReasoningTokens,
+		ServiceTier:         req.ServiceTier,
+		Prediction:          cloneAnyMap(req.Prediction),
+		Store:               req.Store,
+	}
+
+	for _, item := range req.Include {
+		if item == ResponsesIncludeOutputLogprobs {
+			out.Logprobs = ptrBool(true)
+		}
+	}
+	if dropped := UnsupportedChatIncludes(req.Include); len(dropped) > 0 && o.logger != nil {
+		o.logger.Info("Dropping include entries (no Chat Completions equivalent)", map[string]interface{}{
+			"include": dropped,
+		})
 	}
 
 	if req.Stream {
 		out.Stream = ptrBool(true)
+		// 流式 Chat Completions 默认不返回 usage，需要显式请求
+		out.StreamOptions = &OpenAIStreamOptions{IncludeUsage: true}
 	}
 
 	out.Messages = internalMessagesToOpenAI(req.Messages)
@@ -112,6 +136,12 @@ func (o *OpenAIChatFormatAdapter) ParseResponseJSON(payload []byte) (*InternalRe
 		Success: true,
 	}
 
+	if resp.SystemFingerprint != "" {
+		internal.Metadata = map[string]interface{}{
+			"system_fingerprint": resp.SystemFingerprint,
+		}
+	}
+
 	if resp.Usage != nil {
 		internal.TokenUsage = &TokenUsage{
 			PromptTokens:     resp.Usage.PromptTokens,
@@ -140,6 +170,9 @@ func (o *OpenAIChatFormatAdapter) BuildResponseJSON(resp *InternalResponse) ([]b
 		ID:    resp.ID,
 		Model: resp.Model,
 	}
+	if fingerprint, ok := resp.Metadata["system_fingerprint"].(string); ok {
+		out.SystemFingerprint = fingerprint
+	}
 
 	if resp.TokenUsage != nil {
 		out.Usage = &OpenAIUsage{
@@ -211,24 +244,28 @@ func openAIMessageToInternal(msg OpenAIMessage) InternalMessage {
 		ToolCallID: msg.ToolCallID,
 	}
 
+	// role:tool 的内容（字符串或文本分片）整体作为一个 tool_result
+	if msg.Role == "tool" {
+		text, isError := unmarkToolResultError(extractToolResultText(msg.Content))
+		internal.Contents = append(internal.Contents, InternalContent{
+			Type: "tool_result",
+			Text: text,
+			ToolResult: &InternalToolResult{
+				ToolUseID: msg.ToolCallID,
+				Content:   text,
+				IsError:   isError,
+			},
+		})
+		return internal
+	}
+
 	// content can be string or []OpenAIMessageContent
 	switch content := msg.Content.(type) {
 	case string:
-		if msg.Role == "tool" {
-			internal.Contents = append(internal.Contents, InternalContent{
-				Type: "tool_result",
-				Text: content,
-				ToolResult: &InternalToolResult{
-					ToolUseID: msg.ToolCallID,
-					Content:   content,
-				},
-			})
-		} else {
-			internal.Contents = append(internal.Contents, InternalContent{
-				Type: "text",
-				Text: content,
-			})
-		}
+		internal.Contents = append(internal.Contents, InternalContent{
+			Type: "text",
+			Text: content,
+		})
 	case []interface{}:
 		for _, raw := range content {
 			if part, ok := raw.(map[string]interface{}); ok {
@@ -299,11 +336,63 @@ func openAIMessageToInternal(msg OpenAIMessage) InternalMessage {
 func internalMessagesToOpenAI(messages []InternalMessage) []OpenAIMessage {
 	result := make([]OpenAIMessage, 0, len(messages))
 	for _, msg := range messages {
+		if msg.Role != "tool" {
+			// Anthropic 把 tool_result 放在 user 消息中，OpenAI 需要独立的 role:tool 消息，且必须紧跟在 assistant 之后
+			toolMessages, rest := splitToolResultMessages(msg)
+			result = append(result, toolMessages...)
+			if len(toolMessages) > 0 && len(rest.Contents) == 0 && len(rest.ToolCalls) == 0 {
+				continue
+			}
+			msg = rest
+		}
 		result = append(result, internalMessageToOpenAI(msg))
 	}
 	return result
 }
 
+// toolResultErrorMarker OpenAI 的 tool 消息没有 is_error 字段，转换时以内容前缀标记错误结果，反向转换时还原
+const toolResultErrorMarker = "[tool_error] "
+
+func markToolResultError(content string, isError bool) string {
+	if !isError {
+		return content
+	}
+	return toolResultErrorMarker + content
+}
+
+func unmarkToolResultError(content string) (string, bool) {
+	if strings.HasPrefix(content, toolResultErrorMarker) {
+		return strings.TrimPrefix(content, toolResultErrorMarker), true
+	}
+	return content, false
+}
+
+// splitToolResultMessages 拆出消息中的 tool_result 内容，返回对应的 role:tool 消息以及剩余内容
+func splitToolResultMessages(msg InternalMessage) ([]OpenAIMessage, InternalMessage) {
+	var toolMessages []OpenAIMessage
+	rest := msg
+	rest.Contents = make([]InternalContent, 0, len(msg.Contents))
+	for _, content := range msg.Contents {
+		if content.Type != "tool_result" || content.ToolResult == nil {
+			rest.Contents = append(rest.Contents, content)
+			continue
+		}
+		toolMessages = append(toolMessages, OpenAIMessage{
+			Role:       "tool",
+			ToolCallID: content.ToolResult.ToolUseID,
+			Content:    markToolResultError(toolResultContentText(content), content.ToolResult.IsError),
+		})
+	}
+	return toolMessages, rest
+}
+
+func toolResultContentText(content InternalContent) string {
+	if content.ToolResult != nil && content.ToolResult.Content != "" {
+		return content.ToolResult.Content
+	}
+	return content.Text
+}
+
 func internalMessageToOpenAI(msg InternalMessage) OpenAIMessage {
 	out := OpenAIMessage{
 		Role:       msg.Role,
@@ -326,7 +415,14 @@ func internalMessageToOpenAI(msg InternalMessage) OpenAIMessage {
 				},
 			})
 		case "tool_result":
-			textBuilder.WriteString(content.Text)
+			if content.ToolResult != nil {
+				textBuilder.WriteString(markToolResultError(toolResultContentText(content), content.ToolResult.IsError))
+				if out.ToolCallID == "" {
+					out.ToolCallID = content.ToolResult.ToolUseID
+				}
+			} else {
+				textBuilder.WriteString(content.Text)
+			}
 		default:
 			textBuilder.WriteString(content.Text)
 		}
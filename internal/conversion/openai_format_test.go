@@ -304,3 +304,119 @@ func TestEmptyCollections(t *testing.T) {
 		t.Error("Empty Stop should have 0 items")
 	}
 }
+
+// 测试 seed 在 OpenAI → OpenAI 中保留，在 Anthropic 目标中被丢弃
+func TestSeedConversion(t *testing.T) {
+	chatJSON := `{
+		"model": "gpt-4o",
+		"messages": [{"role": "user", "content": "test"}],
+		"seed": 42
+	}`
+
+	factory := NewAdapterFactory(nil)
+	chatAdapter := factory.OpenAIChatAdapter()
+	anthropicAdapter := factory.AnthropicAdapter()
+
+	internalReq, err := chatAdapter.ParseRequestJSON([]byte(chatJSON))
+	if err != nil {
+		t.Fatalf("ParseRequestJSON failed: %v", err)
+	}
+	if internalReq.Seed == nil || *internalReq.Seed != 42 {
+		t.Fatalf("Seed not mapped correctly")
+	}
+
+	chatBytes, err := chatAdapter.BuildRequestJSON(internalReq)
+	if err != nil {
+		t.Fatalf("Chat BuildRequestJSON failed: %v", err)
+	}
+	var chatReq OpenAIRequest
+	if err := json.Unmarshal(chatBytes, &chatReq); err != nil {
+		t.Fatalf("Failed to unmarshal chat request: %v", err)
+	}
+	if chatReq.Seed == nil || *chatReq.Seed != 42 {
+		t.Errorf("Seed should survive OpenAI → OpenAI, got %v", chatReq.Seed)
+	}
+
+	anthropicBytes, err := anthropicAdapter.BuildRequestJSON(internalReq)
+	if err != nil {
+		t.Fatalf("Anthropic BuildRequestJSON failed: %v", err)
+	}
+	var anthropicReq map[string]interface{}
+	if err := json.Unmarshal(anthropicBytes, &anthropicReq); err != nil {
+		t.Fatalf("Failed to unmarshal anthropic request: %v", err)
+	}
+	if _, exists := anthropicReq["seed"]; exists {
+		t.Error("Seed should be dropped for Anthropic targets")
+	}
+}
+
+// 测试 prediction（predicted outputs）在 OpenAI → OpenAI 中保留，在 Anthropic 目标中丢弃
+func TestPredictionConversion(t *testing.T) {
+	chatJSON := `{
+		"model": "gpt-4o",
+		"messages": [{"role": "user", "content": "rename foo to bar"}],
+		"prediction": {"type": "content", "content": "func bar() {}"}
+	}`
+
+	factory := NewAdapterFactory(nil)
+	chatAdapter := factory.OpenAIChatAdapter()
+	anthropicAdapter := factory.AnthropicAdapter()
+
+	internalReq, err := chatAdapter.ParseRequestJSON([]byte(chatJSON))
+	if err != nil {
+		t.Fatalf("ParseRequestJSON failed: %v", err)
+	}
+
+	chatBytes, err := chatAdapter.BuildRequestJSON(internalReq)
+	if err != nil {
+		t.Fatalf("Chat BuildRequestJSON failed: %v", err)
+	}
+	var chatReq OpenAIRequest
+	if err := json.Unmarshal(chatBytes, &chatReq); err != nil {
+		t.Fatalf("Failed to unmarshal chat request: %v", err)
+	}
+	if chatReq.Prediction["type"] != "content" || chatReq.Prediction["content"] != "func bar() {}" {
+		t.Errorf("Prediction should survive OpenAI → OpenAI, got %v", chatReq.Prediction)
+	}
+
+	anthropicBytes, err := anthropicAdapter.BuildRequestJSON(internalReq)
+	if err != nil {
+		t.Fatalf("Anthropic BuildRequestJSON failed: %v", err)
+	}
+	var anthropicReq map[string]interface{}
+	if err := json.Unmarshal(anthropicBytes, &anthropicReq); err != nil {
+		t.Fatalf("Failed to unmarshal anthropic request: %v", err)
+	}
+	if _, exists := anthropicReq["prediction"]; exists {
+		t.Error("Prediction should be dropped for Anthropic targets")
+	}
+}
+
+// 测试 system_fingerprint 在 OpenAI 响应中透传
+func TestSystemFingerprintPassthrough(t *testing.T) {
+	respJSON := `{
+		"id": "chatcmpl-1",
+		"model": "gpt-4o",
+		"system_fingerprint": "fp_abc123",
+		"choices": [{"index": 0, "message": {"role": "assistant", "content": "hi"}, "finish_reason": "stop"}]
+	}`
+
+	chatAdapter := NewAdapterFactory(nil).OpenAIChatAdapter()
+
+	internalResp, err := chatAdapter.ParseResponseJSON([]byte(respJSON))
+	if err != nil {
+		t.Fatalf("ParseResponseJSON failed: %v", err)
+	}
+
+	out, err := chatAdapter.BuildResponseJSON(internalResp)
+	if err != nil {
+		t.Fatalf("BuildResponseJSON failed: %v", err)
+	}
+	var resp OpenAIResponse
+	if err := json.Unmarshal(out, &resp); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if resp.SystemFingerprint != "fp_abc123" {
+		t.Errorf("Expected system_fingerprint fp_abc123, got %q", resp.SystemFingerprint)
+	}
+}
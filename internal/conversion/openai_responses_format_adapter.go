@@ -43,6 +43,9 @@ func (o *OpenAIResponsesFormatAdapter) ParseRequestJSON(payload []byte) (*Intern
 		N:                 req.N,
 		Stop:              append([]string(nil), req.Stop...),
 		ResponseFormat:    convertOpenAIResponseFormatToInternal(req.ResponseFormat),
+		ServiceTier:       req.ServiceTier,
+		Store:             req.Store,
+		Include:           append([]string(nil), req.Include...),
 	}
 
 	messages := req.Input
@@ -50,10 +53,23 @@ func (o *OpenAIResponsesFormatAdapter) ParseRequestJSON(payload []byte) (*Intern
 		messages = req.Messages
 	}
 	internal.Messages = responsesMessagesToInternal(messages)
+	// instructions 等价于置顶的 system 消息
+	if instructions := strings.TrimSpace(req.Instructions); instructions != "" {
+		internal.Messages = append([]InternalMessage{{
+			Role:     "system",
+			Contents: []InternalContent{{Type: "text", Text: req.Instructions}},
+		}}, internal.Messages...)
+	}
+	internal.Stream = req.Stream
 
 	internal.Tools = responsesToolsToInternal(req.Tools)
 	internal.ToolChoice = convertOpenAIToolChoice(req.ToolChoice)
-	if thinking := InternalThinkingFromOpenAI(req.ReasoningEffort, req.MaxReasoningTokens); thinking != nil {
+	reasoningEffort := req.ReasoningEffort
+	if reasoningEffort == nil && req.Reasoning != nil && req.Reasoning.Effort != "" {
+		effort := req.Reasoning.Effort
+		reasoningEffort = &effort
+	}
+	if thinking := InternalThinkingFromOpenAI(reasoningEffort, req.MaxReasoningTokens); thinking != nil {
 		internal.Thinking = thinking
 	}
 
@@ -79,6 +95,15 @@ func (o *OpenAIResponsesFormatAdapter) BuildRequestJSON(req *InternalRequest) ([
 		N:                 req.N,
 		Stop:              append([]string(nil), req.Stop...),
 		ResponseFormat:    convertInternalResponseFormatToOpenAI(req.ResponseFormat),
+		ServiceTier:       req.ServiceTier,
+		Store:             req.Store,
+		Include:           append([]string(nil), req.Include...),
+	}
+
+	if req.Prediction != nil && o.logger != nil {
+		o.logger.Info("Dropping prediction field (not supported by Responses API)", map[string]interface{}{
+			"prediction_type": req.Prediction["type"],
+		})
 	}
 
 	out.Input = internalMessagesToResponses(req.Messages)
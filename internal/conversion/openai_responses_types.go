@@ -4,8 +4,9 @@ package conversion
 
 type OpenAIResponsesRequest struct {
 	Model             string                   `json:"model"`
-	Input             []OpenAIResponsesMessage `json:"input,omitempty"`    // 输入消息（Responses API 命名）
-	Messages          []OpenAIResponsesMessage `json:"messages,omitempty"` // 🆕 兼容 messages 字段（双路径回退）
+	Instructions      string                   `json:"instructions,omitempty"` // 系统指令（转换为 Chat 时成为 system 消息）
+	Input             []OpenAIResponsesMessage `json:"input,omitempty"`        // 输入消息（Responses API 命名）
+	Messages          []OpenAIResponsesMessage `json:"messages,omitempty"`     // 🆕 兼容 messages 字段（双路径回退）
 	Tools             []OpenAIResponsesTool    `json:"tools,omitempty"`
 	ToolChoice        interface{}              `json:"tool_choice,omitempty"`
 	Temperature       *float64                 `json:"temperature,omitempty"`
@@ -25,6 +26,17 @@ type OpenAIResponsesRequest struct {
 	// 🆕 推理相关字段
 	ReasoningEffort    *string `json:"reasoning_effort,omitempty"`
 	MaxReasoningTokens *int    `json:"max_reasoning_tokens,omitempty"`
+	ServiceTier        string  `json:"service_tier,omitempty"`
+	// Responses API 的推理配置对象：{"effort": "..."}
+	Reasoning *OpenAIResponsesReasoning `json:"reasoning,omitempty"`
+	Stream    bool                      `json:"stream,omitempty"`
+	// 服务端持久化与额外输出控制
+	Store   *bool    `json:"store,omitempty"`
+	Include []string `json:"include,omitempty"`
+}
+
+type OpenAIResponsesReasoning struct {
+	Effort string `json:"effort,omitempty"`
 }
 
 type OpenAIResponsesMessage struct {
@@ -2,6 +2,7 @@ package conversion
 
 import (
 	"bytes"
+	"encoding/json"
 	"strings"
 	"testing"
 )
@@ -61,3 +62,189 @@ data: [DONE]
 		t.Errorf("expected stop_reason tool_use, got %s", output)
 	}
 }
+
+// fragmentedToolCallSSE 文本后跟随一个参数拆分到三个 chunk 的工具调用（name 与 id 只出现在首个分片）
+const fragmentedToolCallSSE = `data: {"id":"chatcmpl-789","model":"gpt-4","choices":[{"index":0,"delta":{"content":"Checking."}}]}
+data: {"id":"chatcmpl-789","model":"gpt-4","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_xyz","type":"function","function":{"name":"get_weather","arguments":"{\"locat"}}]}}]}
+data: {"id":"chatcmpl-789","model":"gpt-4","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"ion\":\"Par"}}]}}]}
+data: {"id":"chatcmpl-789","model":"gpt-4","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"is\",\"unit\":\"c\"}"}}]}}]}
+data: {"id":"chatcmpl-789","model":"gpt-4","choices":[{"index":0,"delta":{},"finish_reason":"tool_calls"}]}
+
+data: [DONE]
+`
+
+func TestStreamOpenAISSEToAnthropic_FragmentedToolArguments(t *testing.T) {
+	var writer bytes.Buffer
+	if err := StreamOpenAISSEToAnthropic(strings.NewReader(fragmentedToolCallSSE), &writer); err != nil {
+		t.Fatalf("StreamOpenAISSEToAnthropic failed: %v", err)
+	}
+
+	blockTypes := map[int]string{}
+	arguments := map[int]string{}
+	started := map[int]int{}
+	for _, line := range strings.Split(writer.String(), "\n") {
+		if !strings.HasPrefix(line, "data: ") {
+			continue
+		}
+		var evt struct {
+			Type         string `json:"type"`
+			Index        int    `json:"index"`
+			ContentBlock struct {
+				Type string `json:"type"`
+				ID   string `json:"id"`
+				Name string `json:"name"`
+			} `json:"content_block"`
+			Delta struct {
+				Type        string `json:"type"`
+				PartialJSON string `json:"partial_json"`
+			} `json:"delta"`
+		}
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &evt); err != nil {
+			t.Fatalf("invalid event payload %q: %v", line, err)
+		}
+		switch evt.Type {
+		case "content_block_start":
+			started[evt.Index]++
+			blockTypes[evt.Index] = evt.ContentBlock.Type
+			if evt.ContentBlock.Type == "tool_use" && (evt.ContentBlock.ID != "call_xyz" || evt.ContentBlock.Name != "get_weather") {
+				t.Errorf("unexpected tool_use block: %+v", evt.ContentBlock)
+			}
+		case "content_block_delta":
+			if evt.Delta.Type == "input_json_delta" {
+				arguments[evt.Index] += evt.Delta.PartialJSON
+			}
+		}
+	}
+
+	if blockTypes[0] != "text" || blockTypes[1] != "tool_use" || len(blockTypes) != 2 {
+		t.Fatalf("expected a text block at index 0 and a tool_use block at index 1, got %v", blockTypes)
+	}
+	for index, count := range started {
+		if count != 1 {
+			t.Errorf("block %d started %d times", index, count)
+		}
+	}
+
+	var input map[string]string
+	if err := json.Unmarshal([]byte(arguments[1]), &input); err != nil {
+		t.Fatalf("concatenated partial_json is not valid JSON: %q (%v)", arguments[1], err)
+	}
+	if input["location"] != "Paris" || input["unit"] != "c" {
+		t.Fatalf("unexpected tool input: %v", input)
+	}
+}
+
+func TestStreamChatCompletionsToResponses_FragmentedToolArguments(t *testing.T) {
+	var writer bytes.Buffer
+	if err := StreamChatCompletionsToResponses(strings.NewReader(fragmentedToolCallSSE), &writer); err != nil {
+		t.Fatalf("StreamChatCompletionsToResponses failed: %v", err)
+	}
+
+	output := writer.String()
+	if count := strings.Count(output, "event: response.function_call.started"); count != 1 {
+		t.Fatalf("expected a single function_call.started event, got %d:\n%s", count, output)
+	}
+
+	var completed struct {
+		ID        string `json:"id"`
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	}
+	for _, block := range strings.Split(output, "\n\n") {
+		if !strings.Contains(block, "event: response.function_call.completed") {
+			continue
+		}
+		payload := block[strings.Index(block, "data: ")+len("data: "):]
+		if err := json.Unmarshal([]byte(payload), &completed); err != nil {
+			t.Fatalf("invalid completed payload %q: %v", payload, err)
+		}
+	}
+	if completed.ID != "call_xyz" || completed.Name != "get_weather" {
+		t.Fatalf("unexpected completed tool call: %+v", completed)
+	}
+	if completed.Arguments != `{"location":"Paris","unit":"c"}` {
+		t.Fatalf("unexpected assembled arguments: %q", completed.Arguments)
+	}
+}
+
+// sseTestEvent SSE 流中的一个事件：事件名（无 event: 行时为空）与 data 内容
+type sseTestEvent struct {
+	name string
+	data string
+}
+
+// sseEvents 按顺序解析 SSE 流中的事件
+func sseEvents(t *testing.T, body string) []sseTestEvent {
+	t.Helper()
+	var events []sseTestEvent
+	for _, block := range strings.Split(strings.TrimSpace(body), "\n\n") {
+		var name, data string
+		for _, line := range strings.Split(block, "\n") {
+			switch {
+			case strings.HasPrefix(line, "event: "):
+				name = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				data = strings.TrimPrefix(line, "data: ")
+			}
+		}
+		events = append(events, sseTestEvent{name: name, data: data})
+	}
+	return events
+}
+
+func TestStreamBridgesEmitUsageBeforeTerminalEvent(t *testing.T) {
+	// 上游 Chat 流没有 usage chunk，只有 [DONE]
+	chatSSE := "data: {\"id\":\"chatcmpl-1\",\"model\":\"gpt-4\",\"choices\":[{\"index\":0,\"delta\":{\"content\":\"hi\"}}]}\n\n" +
+		"data: {\"id\":\"chatcmpl-1\",\"model\":\"gpt-4\",\"choices\":[{\"index\":0,\"delta\":{},\"finish_reason\":\"stop\"}]}\n\n" +
+		"data: [DONE]\n\n"
+
+	var anthropic bytes.Buffer
+	if err := StreamOpenAISSEToAnthropic(strings.NewReader(chatSSE), &anthropic); err != nil {
+		t.Fatalf("StreamOpenAISSEToAnthropic failed: %v", err)
+	}
+	events := sseEvents(t, anthropic.String())
+	if n := len(events); n < 2 || events[n-1].name != "message_stop" || events[n-2].name != "message_delta" {
+		t.Fatalf("expected message_delta right before message_stop, got %q", anthropic.String())
+	}
+	var delta struct {
+		Usage *AnthropicUsage `json:"usage"`
+	}
+	if err := json.Unmarshal([]byte(events[len(events)-2].data), &delta); err != nil || delta.Usage == nil {
+		t.Fatalf("expected usage in message_delta, got %s", events[len(events)-2].data)
+	}
+
+	anthropicSSE := "event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"id\":\"msg_1\",\"model\":\"claude\"}}\n\n" +
+		"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"hi\"}}\n\n" +
+		"event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n"
+	var chat bytes.Buffer
+	if err := StreamAnthropicSSEToOpenAI(strings.NewReader(anthropicSSE), &chat); err != nil {
+		t.Fatalf("StreamAnthropicSSEToOpenAI failed: %v", err)
+	}
+	events = sseEvents(t, chat.String())
+	if n := len(events); n < 2 || events[n-1].data != "[DONE]" {
+		t.Fatalf("expected stream to end with [DONE], got %q", chat.String())
+	}
+	var last struct {
+		Usage *OpenAIUsage `json:"usage"`
+	}
+	if err := json.Unmarshal([]byte(events[len(events)-2].data), &last); err != nil || last.Usage == nil {
+		t.Fatalf("expected usage chunk before [DONE], got %s", events[len(events)-2].data)
+	}
+
+	var responses bytes.Buffer
+	if err := StreamChatCompletionsToResponses(strings.NewReader(chatSSE), &responses); err != nil {
+		t.Fatalf("StreamChatCompletionsToResponses failed: %v", err)
+	}
+	events = sseEvents(t, responses.String())
+	if n := len(events); n < 2 || events[n-1].data != "[DONE]" || events[n-2].name != "response.completed" {
+		t.Fatalf("expected response.completed before [DONE], got %q", responses.String())
+	}
+	var completed struct {
+		Response struct {
+			Usage map[string]int `json:"usage"`
+		} `json:"response"`
+	}
+	if err := json.Unmarshal([]byte(events[len(events)-2].data), &completed); err != nil || completed.Response.Usage == nil {
+		t.Fatalf("expected response.usage in response.completed, got %s", events[len(events)-2].data)
+	}
+}
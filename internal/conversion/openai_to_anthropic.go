@@ -2,6 +2,7 @@ package conversion
 
 import (
 	"errors"
+	"strings"
 	
 	jsonutils "claude-code-codex-companion/internal/common/json"
 )
@@ -49,6 +50,9 @@ func ConvertChatResponseJSONToAnthropic(body []byte) ([]byte, error) {
 		contentBlocks = append(contentBlocks, anthMsg.GetContentBlocks()...)
 	}
 
+	// Surface refusals as text instead of letting them collapse into an empty response.
+	contentBlocks = appendRefusalBlocks(contentBlocks, resp.Choices)
+
 	// Ensure we always return at least one content block to satisfy Anthropic schema.
 	if len(contentBlocks) == 0 {
 		contentBlocks = []AnthropicContentBlock{
@@ -77,3 +81,28 @@ func ConvertChatResponseJSONToAnthropic(body []byte) ([]byte, error) {
 
 	return jsonutils.SafeMarshal(out)
 }
+
+// appendRefusalBlocks 将 choice.message.refusal 转为 Anthropic text 块；存在拒绝说明时丢弃空 text 块
+func appendRefusalBlocks(blocks []AnthropicContentBlock, choices []OpenAIChoice) []AnthropicContentBlock {
+	var refusals []string
+	for _, choice := range choices {
+		if refusal := strings.TrimSpace(choice.Message.Refusal); refusal != "" {
+			refusals = append(refusals, refusal)
+		}
+	}
+	if len(refusals) == 0 {
+		return blocks
+	}
+
+	kept := make([]AnthropicContentBlock, 0, len(blocks)+len(refusals))
+	for _, block := range blocks {
+		if block.Type == "text" && strings.TrimSpace(block.Text) == "" {
+			continue
+		}
+		kept = append(kept, block)
+	}
+	for _, refusal := range refusals {
+		kept = append(kept, AnthropicContentBlock{Type: "text", Text: refusal})
+	}
+	return kept
+}
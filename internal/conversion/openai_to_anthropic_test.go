@@ -1,6 +1,7 @@
 package conversion
 
 import (
+	"bytes"
 	"encoding/json"
 	"testing"
 )
@@ -73,3 +74,57 @@ func TestConvertChatResponseJSONToAnthropic_ToolCall(t *testing.T) {
 		t.Fatalf("expected tool_use block in content: %+v", anthropic.Content)
 	}
 }
+
+func TestConvertChatResponseJSONToAnthropic_Refusal(t *testing.T) {
+	input := `{"id":"chatcmpl-789","model":"gpt-4o","choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":null,"refusal":"I'm sorry, I can't help with that."}}]}`
+	output, err := ConvertChatResponseJSONToAnthropic([]byte(input))
+	if err != nil {
+		t.Fatalf("conversion failed: %v", err)
+	}
+
+	var anthropic AnthropicResponse
+	if err := json.Unmarshal(output, &anthropic); err != nil {
+		t.Fatalf("invalid anthropic JSON: %v", err)
+	}
+
+	if len(anthropic.Content) != 1 {
+		t.Fatalf("expected a single refusal block, got %+v", anthropic.Content)
+	}
+	if anthropic.Content[0].Type != "text" || anthropic.Content[0].Text != "I'm sorry, I can't help with that." {
+		t.Fatalf("expected refusal text to be surfaced, got %+v", anthropic.Content[0])
+	}
+}
+
+// TestConvertChatResponseJSONToAnthropic_CompactOutput 响应重序列化必须输出紧凑 JSON，即使上游响应与工具参数带缩进
+func TestConvertChatResponseJSONToAnthropic_CompactOutput(t *testing.T) {
+	input := `{
+		"id": "chatcmpl-789",
+		"model": "gpt-4",
+		"choices": [{
+			"index": 0,
+			"finish_reason": "tool_calls",
+			"message": {
+				"role": "assistant",
+				"content": "Looking it up",
+				"tool_calls": [{
+					"id": "tool_1",
+					"type": "function",
+					"function": {"name": "search", "arguments": "{\n  \"query\": \"weather\"\n}"}
+				}]
+			}
+		}],
+		"usage": {"prompt_tokens": 3, "completion_tokens": 5, "total_tokens": 8}
+	}`
+
+	output, err := ConvertChatResponseJSONToAnthropic([]byte(input))
+	if err != nil {
+		t.Fatalf("conversion failed: %v", err)
+	}
+	var compacted bytes.Buffer
+	if err := json.Compact(&compacted, output); err != nil {
+		t.Fatalf("invalid anthropic JSON: %v", err)
+	}
+	if !bytes.Equal(compacted.Bytes(), output) {
+		t.Fatalf("expected compact output, got:\n%s", output)
+	}
+}
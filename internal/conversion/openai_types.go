@@ -4,29 +4,41 @@ package conversion
 
 // OpenAIRequest OpenAI Chat Completions 请求（2025 以后官方推荐字段名）
 type OpenAIRequest struct {
-	Model               string          `json:"model"`
-	Messages            []OpenAIMessage `json:"messages"`
-	Tools               []OpenAITool    `json:"tools,omitempty"`       // functions
-	ToolChoice          interface{}     `json:"tool_choice,omitempty"` // "none"|"auto"|{"type":"function","function":{"name":...}}|"required"
-	Temperature         *float64        `json:"temperature,omitempty"`
-	TopP                *float64        `json:"top_p,omitempty"`
-	MaxCompletionTokens *int            `json:"max_completion_tokens,omitempty"` // OpenAI: 输出最大 token
-	MaxOutputTokens     *int            `json:"max_output_tokens,omitempty"`     // OpenAI: 新的输出最大 token 字段
-	MaxTokens           *int            `json:"max_tokens,omitempty"`            // 兼容保留：老字段，有些代理仍在用
-	Stream              *bool           `json:"stream,omitempty"`
-	Stop                []string        `json:"stop,omitempty"`
-	User                string          `json:"user,omitempty"`
-	ParallelToolCalls   *bool           `json:"parallel_tool_calls,omitempty"`
+	Model               string               `json:"model"`
+	Messages            []OpenAIMessage      `json:"messages"`
+	Tools               []OpenAITool         `json:"tools,omitempty"`       // functions
+	ToolChoice          interface{}          `json:"tool_choice,omitempty"` // "none"|"auto"|{"type":"function","function":{"name":...}}|"required"
+	Temperature         *float64             `json:"temperature,omitempty"`
+	TopP                *float64             `json:"top_p,omitempty"`
+	MaxCompletionTokens *int                 `json:"max_completion_tokens,omitempty"` // OpenAI: 输出最大 token
+	MaxOutputTokens     *int                 `json:"max_output_tokens,omitempty"`     // OpenAI: 新的输出最大 token 字段
+	MaxTokens           *int                 `json:"max_tokens,omitempty"`            // 兼容保留：老字段，有些代理仍在用
+	Stream              *bool                `json:"stream,omitempty"`
+	StreamOptions       *OpenAIStreamOptions `json:"stream_options,omitempty"` // 流式时 include_usage 才会返回 usage
+	Stop                []string             `json:"stop,omitempty"`
+	User                string               `json:"user,omitempty"`
+	ParallelToolCalls   *bool                `json:"parallel_tool_calls,omitempty"`
 	// 🆕 采样控制参数 (参考 chat2response)
 	PresencePenalty  *float64           `json:"presence_penalty,omitempty"`  // 存在惩罚 (-2.0 to 2.0)
 	FrequencyPenalty *float64           `json:"frequency_penalty,omitempty"` // 频率惩罚 (-2.0 to 2.0)
 	LogitBias        map[string]float64 `json:"logit_bias,omitempty"`        // token ID 到偏置值的映射
 	N                *int               `json:"n,omitempty"`                 // 生成多个候选响应
+	Seed             *int               `json:"seed,omitempty"`              // 可复现采样种子
 	// 🆕 输出格式控制
 	ResponseFormat *OpenAIResponseFormat `json:"response_format,omitempty"` // {"type":"json_object"|"text",...}
 	// 推理相关字段 (o1 模型)
 	ReasoningEffort    *string `json:"reasoning_effort,omitempty"`     // "low"|"medium"|"high" 推理强度
 	MaxReasoningTokens *int    `json:"max_reasoning_tokens,omitempty"` // 推理阶段的最大 token 数
+	ServiceTier        string  `json:"service_tier,omitempty"`         // "auto"|"default"|"flex"|"priority"|"scale"
+	// 预测输出 (predicted outputs)：{"type":"content","content":...}
+	Prediction map[string]interface{} `json:"prediction,omitempty"`
+	Store      *bool                  `json:"store,omitempty"`    // stored completions
+	Logprobs   *bool                  `json:"logprobs,omitempty"` // 对应 Responses include 中的 message.output_text.logprobs
+}
+
+// OpenAIStreamOptions OpenAI 流式选项
+type OpenAIStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
 }
 
 // OpenAIResponseFormat 定义输出格式约束
@@ -43,6 +55,8 @@ type OpenAIMessage struct {
 	ToolCallID string      `json:"tool_call_id,omitempty"`
 	// 仅 assistant 会用到
 	ToolCalls []OpenAIToolCall `json:"tool_calls,omitempty"`
+	// 模型拒绝回答时的说明（此时 content 通常为空）
+	Refusal string `json:"refusal,omitempty"`
 }
 
 // OpenAIMessageContent 复合内容：text / image_url
@@ -87,10 +101,11 @@ type OpenAIToolCallDetail struct {
 
 // OpenAIResponse OpenAI 响应（非流式）
 type OpenAIResponse struct {
-	ID      string         `json:"id"`
-	Model   string         `json:"model"`
-	Choices []OpenAIChoice `json:"choices"`
-	Usage   *OpenAIUsage   `json:"usage,omitempty"`
+	ID                string         `json:"id"`
+	Model             string         `json:"model"`
+	SystemFingerprint string         `json:"system_fingerprint,omitempty"` // 可复现性元数据，原样透传
+	Choices           []OpenAIChoice `json:"choices"`
+	Usage             *OpenAIUsage   `json:"usage,omitempty"`
 }
 
 // OpenAIChoice 选择结构
@@ -109,12 +124,13 @@ type OpenAIUsage struct {
 
 // OpenAIStreamChunk OpenAI 流式片段（SSE 的 delta 合并结果；这里假定你已收集完所有 chunk）
 type OpenAIStreamChunk struct {
-	ID      string               `json:"id"`
-	Object  string               `json:"object,omitempty"`
-	Created int64                `json:"created,omitempty"`
-	Model   string               `json:"model"`
-	Choices []OpenAIStreamChoice `json:"choices"`
-	Usage   *OpenAIUsage         `json:"usage,omitempty"` // 可能在最后一个chunk中包含
+	ID                string               `json:"id"`
+	Object            string               `json:"object,omitempty"`
+	Created           int64                `json:"created,omitempty"`
+	Model             string               `json:"model"`
+	SystemFingerprint string               `json:"system_fingerprint,omitempty"`
+	Choices           []OpenAIStreamChoice `json:"choices"`
+	Usage             *OpenAIUsage         `json:"usage,omitempty"` // 可能在最后一个chunk中包含
 }
 
 // OpenAIStreamChoice 流式选择
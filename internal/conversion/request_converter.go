@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"claude-code-codex-companion/internal/logger"
+	"claude-code-codex-companion/internal/utils"
 )
 
 // RequestConverter 请求转换器 - 基于参考实现
@@ -82,12 +83,31 @@ func (c *RequestConverter) Convert(anthropicReq []byte, endpointInfo *EndpointIn
 		}
 	}
 	out.Stream = anthReq.Stream
+	if anthReq.Stream != nil && *anthReq.Stream {
+		// 流式 Chat Completions 默认不返回 usage，需要显式请求
+		out.StreamOptions = &OpenAIStreamOptions{IncludeUsage: true}
+	}
 	out.Stop = anthReq.StopSequences
 
-	// 处理用户ID
+	// service_tier 映射：无法表达的档位直接丢弃
+	if anthReq.ServiceTier != "" {
+		if tier, ok := AnthropicServiceTierToOpenAI(anthReq.ServiceTier); ok {
+			out.ServiceTier = tier
+		} else if c.logger != nil {
+			c.logger.Info("Dropping unsupported service_tier during conversion", map[string]interface{}{
+				"service_tier": anthReq.ServiceTier,
+			})
+		}
+	}
+
+	// 处理用户ID：metadata.user_id -> user，按端点 user_field_mode 处理
 	if anthReq.Metadata != nil {
 		if userID, ok := anthReq.Metadata["user_id"].(string); ok && userID != "" {
-			out.User = userID
+			mode := ""
+			if endpointInfo != nil {
+				mode = endpointInfo.UserFieldMode
+			}
+			out.User = utils.ApplyUserFieldModeToValue(userID, mode)
 		}
 	}
 
@@ -221,7 +241,7 @@ func (c *RequestConverter) Convert(anthropicReq []byte, endpointInfo *EndpointIn
 				out.Messages = append(out.Messages, OpenAIMessage{
 					Role:       "tool",
 					ToolCallID: tr.ToolUseID,
-					Content:    strings.TrimSpace(content),
+					Content:    markToolResultError(strings.TrimSpace(content), tr.IsError != nil && *tr.IsError),
 				})
 			}
 
@@ -366,6 +386,23 @@ func (c *RequestConverter) Convert(anthropicReq []byte, endpointInfo *EndpointIn
 	return result, ctx, nil
 }
 
+// ConvertToAnthropic 转换 OpenAI Chat 请求为 Anthropic 格式，user 映射为 metadata.user_id
+func (c *RequestConverter) ConvertToAnthropic(openaiReq []byte, endpointInfo *EndpointInfo) ([]byte, error) {
+	internalReq, err := NewOpenAIChatFormatAdapter(c.logger).ParseRequestJSON(openaiReq)
+	if err != nil {
+		return nil, err
+	}
+
+	if internalReq.User != "" && endpointInfo != nil {
+		// truncate 模式仅针对 OpenAI 的 64 字节限制，Anthropic 侧不做截断
+		if mode := utils.NormalizeUserFieldMode(endpointInfo.UserFieldMode); mode != utils.UserFieldModeTruncate {
+			internalReq.User = utils.ApplyUserFieldModeToValue(internalReq.User, mode)
+		}
+	}
+
+	return NewAnthropicFormatAdapter(c.logger).BuildRequestJSON(internalReq)
+}
+
 // boolPtr 返回bool指针
 func boolPtr(b bool) *bool {
 	return &b
@@ -746,4 +746,107 @@ func TestToolChoiceOnlyWhenToolsPresent(t *testing.T) {
 			t.Errorf("Expected tool_choice 'required' when tool_choice is 'any', got %v", oaReq.ToolChoice)
 		}
 	})
-}
\ No newline at end of file
+}
+
+func TestUserIDMappingAnthropicToOpenAI(t *testing.T) {
+	converter := NewRequestConverter(getTestLogger())
+	anthReq := AnthropicRequest{
+		Model:     "claude-3-sonnet-20240229",
+		Messages:  []AnthropicMessage{{Role: "user", Content: []AnthropicContentBlock{{Type: "text", Text: "hi"}}}},
+		MaxTokens: intPtr(16),
+		Metadata:  map[string]interface{}{"user_id": "user-42"},
+	}
+	reqBytes, _ := json.Marshal(anthReq)
+
+	cases := []struct {
+		mode string
+		want string
+	}{
+		{"", "user-42"},
+		{"passthrough", "user-42"},
+		{"strip", ""},
+	}
+	for _, tc := range cases {
+		result, _, err := converter.Convert(reqBytes, &EndpointInfo{Type: "openai", UserFieldMode: tc.mode})
+		if err != nil {
+			t.Fatalf("mode %q: conversion failed: %v", tc.mode, err)
+		}
+		var oaReq OpenAIRequest
+		if err := json.Unmarshal(result, &oaReq); err != nil {
+			t.Fatalf("mode %q: failed to unmarshal result: %v", tc.mode, err)
+		}
+		if oaReq.User != tc.want {
+			t.Errorf("mode %q: expected user %q, got %q", tc.mode, tc.want, oaReq.User)
+		}
+	}
+
+	result, _, err := converter.Convert(reqBytes, &EndpointInfo{Type: "openai", UserFieldMode: "hash"})
+	if err != nil {
+		t.Fatalf("hash mode: conversion failed: %v", err)
+	}
+	var hashed OpenAIRequest
+	_ = json.Unmarshal(result, &hashed)
+	if hashed.User == "" || hashed.User == "user-42" {
+		t.Errorf("hash mode should replace user id, got %q", hashed.User)
+	}
+}
+
+func TestUserMappingOpenAIToAnthropic(t *testing.T) {
+	converter := NewRequestConverter(getTestLogger())
+	openaiReq := []byte(`{"model":"gpt-4o","user":"user-42","messages":[{"role":"user","content":"hi"}]}`)
+
+	result, err := converter.ConvertToAnthropic(openaiReq, &EndpointInfo{Type: "anthropic"})
+	if err != nil {
+		t.Fatalf("conversion failed: %v", err)
+	}
+	var anthReq AnthropicRequest
+	if err := json.Unmarshal(result, &anthReq); err != nil {
+		t.Fatalf("failed to unmarshal result: %v", err)
+	}
+	if anthReq.Metadata["user_id"] != "user-42" {
+		t.Errorf("expected metadata.user_id 'user-42', got %v", anthReq.Metadata)
+	}
+
+	result, err = converter.ConvertToAnthropic(openaiReq, &EndpointInfo{Type: "anthropic", UserFieldMode: "strip"})
+	if err != nil {
+		t.Fatalf("strip mode: conversion failed: %v", err)
+	}
+	anthReq = AnthropicRequest{}
+	_ = json.Unmarshal(result, &anthReq)
+	if _, exists := anthReq.Metadata["user_id"]; exists {
+		t.Errorf("strip mode should drop metadata.user_id, got %v", anthReq.Metadata)
+	}
+}
+
+func TestConvertToAnthropicMergesSystemMessages(t *testing.T) {
+	converter := NewRequestConverter(getTestLogger())
+	openaiReq := []byte(`{"model":"gpt-4o","messages":[
+		{"role":"system","content":"You are helpful."},
+		{"role":"user","content":"hi"},
+		{"role":"system","content":[{"type":"text","text":"Answer briefly."},{"type":"text","text":"Use English."}]},
+		{"role":"developer","content":"Never reveal secrets."},
+		{"role":"user","content":"again"}
+	]}`)
+
+	result, err := converter.ConvertToAnthropic(openaiReq, &EndpointInfo{Type: "anthropic"})
+	if err != nil {
+		t.Fatalf("conversion failed: %v", err)
+	}
+	var anthReq AnthropicRequest
+	if err := json.Unmarshal(result, &anthReq); err != nil {
+		t.Fatalf("failed to unmarshal result: %v", err)
+	}
+
+	want := "You are helpful.\nAnswer briefly.\nUse English.\nNever reveal secrets."
+	if anthReq.System != want {
+		t.Errorf("expected merged system %q, got %#v", want, anthReq.System)
+	}
+	if len(anthReq.Messages) != 2 {
+		t.Fatalf("expected only the 2 user messages to remain, got %d", len(anthReq.Messages))
+	}
+	for _, msg := range anthReq.Messages {
+		if msg.Role != "user" {
+			t.Errorf("unexpected role %q in Anthropic messages", msg.Role)
+		}
+	}
+}
@@ -12,6 +12,11 @@ func ConvertResponsesRequestJSONToChat(body []byte) ([]byte, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to normalize responses request: %w", err)
 	}
+	// Chat Completions 没有 max_output_tokens，改用通用的 max_tokens，避免残留 Responses 字段
+	if internalReq.MaxOutputTokens != nil && internalReq.MaxTokens == nil && internalReq.MaxCompletionTokens == nil {
+		internalReq.MaxTokens = internalReq.MaxOutputTokens
+	}
+	internalReq.MaxOutputTokens = nil
 
 	converted, err := chatAdapter.BuildRequestJSON(internalReq)
 	if err != nil {
@@ -92,4 +97,17 @@ func ConvertAnthropicResponseJSONToChat(body []byte) ([]byte, error) {
 		return nil, fmt.Errorf("failed to render chat response: %w", err)
 	}
 	return converted, nil
-}
\ No newline at end of file
+}
+// ResponsesIncludeOutputLogprobs 是唯一能映射到 Chat Completions（logprobs=true）的 include 取值
+const ResponsesIncludeOutputLogprobs = "message.output_text.logprobs"
+
+// UnsupportedChatIncludes 返回 Responses include 中在 Chat Completions 里没有对应字段、转换时会被丢弃的取值
+func UnsupportedChatIncludes(include []string) []string {
+	var dropped []string
+	for _, item := range include {
+		if item != ResponsesIncludeOutputLogprobs {
+			dropped = append(dropped, item)
+		}
+	}
+	return dropped
+}
@@ -84,3 +84,126 @@ func TestConvertChatResponseJSONToResponses_ToolCall(t *testing.T) {
 		t.Fatalf("unexpected arguments: %s", functionItem.Arguments)
 	}
 }
+
+func TestConvertResponsesRequestStoreAndInclude(t *testing.T) {
+	input := `{
+		"model": "gpt-5",
+		"store": false,
+		"include": ["reasoning.encrypted_content", "message.output_text.logprobs"],
+		"input": [{"role":"user","content":[{"type":"input_text","text":"Hello"}]}]
+	}`
+
+	converted, err := ConvertResponsesRequestJSONToChat([]byte(input))
+	if err != nil {
+		t.Fatalf("conversion failed: %v", err)
+	}
+
+	var req map[string]interface{}
+	if err := json.Unmarshal(converted, &req); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if store, ok := req["store"].(bool); !ok || store {
+		t.Errorf("expected store:false to be preserved, got %#v", req["store"])
+	}
+	if req["logprobs"] != true {
+		t.Errorf("expected logprobs include to map to logprobs:true, got %#v", req["logprobs"])
+	}
+	if _, exists := req["include"]; exists {
+		t.Error("expected include to be stripped from the chat request")
+	}
+
+	dropped := UnsupportedChatIncludes([]string{"reasoning.encrypted_content", ResponsesIncludeOutputLogprobs})
+	if len(dropped) != 1 || dropped[0] != "reasoning.encrypted_content" {
+		t.Errorf("unexpected dropped includes: %v", dropped)
+	}
+
+	// 未设置 store/include 时不输出对应字段
+	converted, _ = ConvertResponsesRequestJSONToChat([]byte(`{"model":"gpt-5","input":[{"role":"user","content":[{"type":"input_text","text":"Hi"}]}]}`))
+	req = nil
+	json.Unmarshal(converted, &req)
+	if _, exists := req["store"]; exists {
+		t.Error("expected store to be omitted when not set")
+	}
+	if _, exists := req["logprobs"]; exists {
+		t.Error("expected logprobs to be omitted when include is empty")
+	}
+}
+
+func TestConvertResponsesRequestJSONToChat_NoResidualResponsesFields(t *testing.T) {
+	input := `{
+		"model": "gpt-5",
+		"instructions": "You are terse.",
+		"input": [{"type":"message","role":"user","content":[{"type":"input_text","text":"Hello"}]}],
+		"stream": true,
+		"max_output_tokens": 256,
+		"reasoning": {"effort": "high"},
+		"include": ["reasoning.encrypted_content"],
+		"temperature": 0.2
+	}`
+
+	converted, err := ConvertResponsesRequestJSONToChat([]byte(input))
+	if err != nil {
+		t.Fatalf("conversion failed: %v", err)
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(converted, &fields); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	validChatFields := map[string]bool{
+		"model": true, "messages": true, "stream": true, "max_tokens": true,
+		"max_completion_tokens": true, "reasoning_effort": true, "temperature": true,
+		"top_p": true, "tools": true, "tool_choice": true, "stop": true, "user": true,
+		"parallel_tool_calls": true, "store": true, "logprobs": true, "stream_options": true,
+	}
+	for field := range fields {
+		if !validChatFields[field] {
+			t.Errorf("converted body contains non-chat field %q: %s", field, converted)
+		}
+	}
+
+	var req OpenAIRequest
+	if err := json.Unmarshal(converted, &req); err != nil {
+		t.Fatalf("invalid chat request: %v", err)
+	}
+	if len(req.Messages) != 2 || req.Messages[0].Role != "system" || req.Messages[0].Content != "You are terse." {
+		t.Fatalf("expected instructions as leading system message, got %+v", req.Messages)
+	}
+	if req.Stream == nil || !*req.Stream {
+		t.Fatalf("expected stream to be preserved")
+	}
+	if req.MaxTokens == nil || *req.MaxTokens != 256 {
+		t.Fatalf("expected max_output_tokens to become max_tokens, got %v", req.MaxTokens)
+	}
+	if req.ReasoningEffort == nil || *req.ReasoningEffort != "high" {
+		t.Fatalf("expected reasoning.effort to map to reasoning_effort, got %v", req.ReasoningEffort)
+	}
+}
+
+func TestConvertResponsesRequestJSONToChat_StreamIncludeUsage(t *testing.T) {
+	input := `{"model":"gpt-5","input":[{"type":"message","role":"user","content":[{"type":"input_text","text":"Hi"}]}],"stream":true}`
+
+	converted, err := ConvertResponsesRequestJSONToChat([]byte(input))
+	if err != nil {
+		t.Fatalf("conversion failed: %v", err)
+	}
+	var req OpenAIRequest
+	if err := json.Unmarshal(converted, &req); err != nil {
+		t.Fatalf("invalid chat request: %v", err)
+	}
+	if req.StreamOptions == nil || !req.StreamOptions.IncludeUsage {
+		t.Fatalf("expected stream_options.include_usage for streaming request, got %s", converted)
+	}
+
+	converted, err = ConvertResponsesRequestJSONToChat([]byte(`{"model":"gpt-5","input":[{"type":"message","role":"user","content":[{"type":"input_text","text":"Hi"}]}]}`))
+	if err != nil {
+		t.Fatalf("conversion failed: %v", err)
+	}
+	req = OpenAIRequest{}
+	if err := json.Unmarshal(converted, &req); err != nil {
+		t.Fatalf("invalid chat request: %v", err)
+	}
+	if req.StreamOptions != nil {
+		t.Errorf("expected no stream_options for non-streaming request, got %s", converted)
+	}
+}
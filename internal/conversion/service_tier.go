@@ -0,0 +1,30 @@
+package conversion
+
+import "strings"
+
+// 内部表示统一使用 OpenAI 的 service_tier 取值（auto / default / flex / priority / scale）。
+// Anthropic 仅支持 auto 与 standard_only，其余档位跨协议时无法表达。
+
+// AnthropicServiceTierToOpenAI 将 Anthropic 的 service_tier 映射为 OpenAI 取值
+func AnthropicServiceTierToOpenAI(tier string) (string, bool) {
+	switch strings.ToLower(strings.TrimSpace(tier)) {
+	case "auto":
+		return "auto", true
+	case "standard_only":
+		return "default", true
+	default:
+		return "", false
+	}
+}
+
+// OpenAIServiceTierToAnthropic 将 OpenAI 的 service_tier 映射为 Anthropic 取值
+func OpenAIServiceTierToAnthropic(tier string) (string, bool) {
+	switch strings.ToLower(strings.TrimSpace(tier)) {
+	case "auto":
+		return "auto", true
+	case "default":
+		return "standard_only", true
+	default:
+		return "", false
+	}
+}
@@ -0,0 +1,43 @@
+package conversion
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestServiceTierAcrossFamilies(t *testing.T) {
+	anthropic := NewAnthropicFormatAdapter(nil)
+	chat := NewOpenAIChatFormatAdapter(nil)
+
+	internal, err := anthropic.ParseRequestJSON([]byte(`{"model":"claude","max_tokens":10,"service_tier":"standard_only","messages":[{"role":"user","content":"hi"}]}`))
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if internal.ServiceTier != "default" {
+		t.Fatalf("expected standard_only to map to default, got %q", internal.ServiceTier)
+	}
+
+	out, err := chat.BuildRequestJSON(internal)
+	if err != nil {
+		t.Fatalf("build failed: %v", err)
+	}
+	var payload map[string]interface{}
+	json.Unmarshal(out, &payload)
+	if payload["service_tier"] != "default" {
+		t.Errorf("expected service_tier to reach OpenAI request, got %v", payload["service_tier"])
+	}
+
+	internal, err = chat.ParseRequestJSON([]byte(`{"model":"gpt-4o","service_tier":"flex","messages":[{"role":"user","content":"hi"}]}`))
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	out, err = anthropic.BuildRequestJSON(internal)
+	if err != nil {
+		t.Fatalf("build failed: %v", err)
+	}
+	payload = nil
+	json.Unmarshal(out, &payload)
+	if _, exists := payload["service_tier"]; exists {
+		t.Errorf("expected unsupported flex tier to be dropped, got %v", payload["service_tier"])
+	}
+}
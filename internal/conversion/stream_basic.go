@@ -31,8 +31,6 @@ func StreamChatCompletionsToResponses(r io.Reader, w io.Writer) error {
 		usage      *OpenAIUsage
 	)
 
-	pythonFixer := NewPythonJSONFixer(nil)
-
 	for scanner.Scan() {
 		line := scanner.Text()
 		rawLines = append(rawLines, line)
@@ -55,18 +53,8 @@ func StreamChatCompletionsToResponses(r io.Reader, w io.Writer) error {
 			return writeFallbackSSE(w, rawLines)
 		}
 
-		// 规范化工具调用参数中的 Python 风格 JSON
-		if pythonFixer != nil {
-			for ci := range chunk.Choices {
-				for ti := range chunk.Choices[ci].Delta.ToolCalls {
-					args := chunk.Choices[ci].Delta.ToolCalls[ti].Function.Arguments
-					if fixed, changed := pythonFixer.FixPythonStyleJSON(args); changed {
-						chunk.Choices[ci].Delta.ToolCalls[ti].Function.Arguments = fixed
-					}
-				}
-			}
-		}
-
+		// 工具调用参数分片在 buildResponsesSSEFromChunks 中按 index 拼接完整后再规范化 Python 风格 JSON，
+		// 对单个分片做修复可能破坏参数
 		if responseID == "" && chunk.ID != "" {
 			responseID = chunk.ID
 		}
@@ -320,9 +308,11 @@ func StreamAnthropicSSEToOpenAI(r io.Reader, w io.Writer) error {
 					},
 				},
 			}
-			if usage != nil {
-				chunk["usage"] = usage
+			// 终止前的最后一个 chunk 总携带 usage（上游未提供时为 0），保证下游 token 统计可用
+			if usage == nil {
+				usage = &OpenAIUsage{}
 			}
+			chunk["usage"] = usage
 			if err := writeOpenAISSEChunk(w, chunk); err != nil {
 				return err
 			}
@@ -372,6 +362,8 @@ func StreamOpenAISSEToAnthropic(r io.Reader, w io.Writer) error {
 	startEmitted := false
 	textStarted := false
 	textIndex := 0
+	// nextBlockIndex 分配 Anthropic content block 序号；OpenAI 的 tool_call.index 只用于归并同一工具调用的分片
+	nextBlockIndex := 0
 	jsonFixer := NewPythonJSONFixer(nil)
 	toolStates := make(map[int]*anthropicToolCallState)
 	finishReason := ""
@@ -397,6 +389,8 @@ func StreamOpenAISSEToAnthropic(r io.Reader, w io.Writer) error {
 		}
 		if !textStarted {
 			textStarted = true
+			textIndex = nextBlockIndex
+			nextBlockIndex++
 			if err := writeEvent("content_block_start", map[string]interface{}{
 				"type":  "content_block_start",
 				"index": textIndex,
@@ -475,57 +469,24 @@ func StreamOpenAISSEToAnthropic(r io.Reader, w io.Writer) error {
 			if choice.FinishReason != "" {
 				finishReason = normalizeOpenAIFinishReason(choice.FinishReason)
 			}
-			if choice.Delta.ToolCalls != nil {
-				for _, toolCall := range choice.Delta.ToolCalls {
-					idx := toolCall.Index
-					if idx < 0 {
-						idx = 0
-					}
-					state, exists := toolStates[idx]
-					if !exists {
-						state = &anthropicToolCallState{
-							ID:    toolCall.ID,
-							Name:  toolCall.Function.Name,
-							Index: idx,
-						}
-						if state.ID == "" {
-							state.ID = generateToolCallID(state.Name, idx)
-						}
-						if state.Name == "" {
-							state.Name = toolCall.Function.Name
-						}
-						toolStates[idx] = state
-						if err := writeEvent("content_block_start", map[string]interface{}{
-							"type":  "content_block_start",
-							"index": idx,
-							"content_block": map[string]interface{}{
-								"type":  "tool_use",
-								"id":    state.ID,
-								"name":  state.Name,
-								"input": map[string]interface{}{},
-							},
-						}); err != nil {
-							return err
-						}
-					}
-					if toolCall.Function.Name != "" && state.Name == "" {
-						state.Name = toolCall.Function.Name
-					}
-					if toolCall.Function.Arguments != "" {
-						state.Arguments.WriteString(toolCall.Function.Arguments)
-						args := formatToolArguments(state.Arguments.String(), jsonFixer)
-						if err := writeEvent("content_block_delta", map[string]interface{}{
-							"type":  "content_block_delta",
-							"index": idx,
-							"delta": map[string]interface{}{
-								"type":         "input_json_delta",
-								"partial_json": args,
-							},
-						}); err != nil {
-							return err
-						}
-					}
+			// 工具调用参数会被拆分到多个 chunk，按 tool_call.index 累积完整参数，流结束时再输出完整的 tool_use 块
+			for _, toolCall := range choice.Delta.ToolCalls {
+				idx := toolCall.Index
+				if idx < 0 {
+					idx = 0
+				}
+				state, exists := toolStates[idx]
+				if !exists {
+					state = &anthropicToolCallState{Index: idx}
+					toolStates[idx] = state
+				}
+				if state.ID == "" && toolCall.ID != "" {
+					state.ID = toolCall.ID
 				}
+				if state.Name == "" && toolCall.Function.Name != "" {
+					state.Name = toolCall.Function.Name
+				}
+				state.Arguments.WriteString(toolCall.Function.Arguments)
 			}
 
 			if choice.Delta.Content != nil {
@@ -583,31 +544,28 @@ func StreamOpenAISSEToAnthropic(r io.Reader, w io.Writer) error {
 			}
 			sort.Ints(indices)
 			for _, idx := range indices {
-				if err := writeEvent("content_block_stop", map[string]interface{}{
-					"type":  "content_block_stop",
-					"index": idx,
-				}); err != nil {
+				if err := writeAnthropicToolUseBlock(writeEvent, nextBlockIndex, toolStates[idx], jsonFixer); err != nil {
 					return err
 				}
+				nextBlockIndex++
 			}
 		}
 
-		if finishReason != "" || usage != nil {
-			payload := map[string]interface{}{
-				"type": "message_delta",
-				"delta": map[string]interface{}{
-					"stop_reason": finishReason,
-				},
-			}
-			if usage != nil {
-				payload["usage"] = map[string]interface{}{
-					"input_tokens":  usage.InputTokens,
-					"output_tokens": usage.OutputTokens,
-				}
-			}
-			if err := writeEvent("message_delta", payload); err != nil {
-				return err
-			}
+		// message_stop 之前总输出携带 usage 的 message_delta（上游未提供时为 0），保证下游 token 统计可用
+		if usage == nil {
+			usage = &AnthropicUsage{}
+		}
+		if err := writeEvent("message_delta", map[string]interface{}{
+			"type": "message_delta",
+			"delta": map[string]interface{}{
+				"stop_reason": finishReason,
+			},
+			"usage": map[string]interface{}{
+				"input_tokens":  usage.InputTokens,
+				"output_tokens": usage.OutputTokens,
+			},
+		}); err != nil {
+			return err
 		}
 
 		if err := writeEvent("message_stop", map[string]interface{}{
@@ -620,6 +578,39 @@ func StreamOpenAISSEToAnthropic(r io.Reader, w io.Writer) error {
 	return nil
 }
 
+// writeAnthropicToolUseBlock 输出一个完整的 tool_use 块：start、携带完整参数的单个 input_json_delta、stop
+func writeAnthropicToolUseBlock(writeEvent func(string, map[string]interface{}) error, blockIndex int, state *anthropicToolCallState, fixer *PythonJSONFixer) error {
+	if state.ID == "" {
+		state.ID = generateToolCallID(state.Name, state.Index)
+	}
+	if err := writeEvent("content_block_start", map[string]interface{}{
+		"type":  "content_block_start",
+		"index": blockIndex,
+		"content_block": map[string]interface{}{
+			"type":  "tool_use",
+			"id":    state.ID,
+			"name":  state.Name,
+			"input": map[string]interface{}{},
+		},
+	}); err != nil {
+		return err
+	}
+	if err := writeEvent("content_block_delta", map[string]interface{}{
+		"type":  "content_block_delta",
+		"index": blockIndex,
+		"delta": map[string]interface{}{
+			"type":         "input_json_delta",
+			"partial_json": formatToolArguments(state.Arguments.String(), fixer),
+		},
+	}); err != nil {
+		return err
+	}
+	return writeEvent("content_block_stop", map[string]interface{}{
+		"type":  "content_block_stop",
+		"index": blockIndex,
+	})
+}
+
 // StreamGeminiSSEToOpenAI 将 Gemini SSE 转换为 OpenAI SSE。
 func StreamGeminiSSEToOpenAI(r io.Reader, w io.Writer) error {
 	scanner := bufio.NewScanner(r)
@@ -654,6 +645,8 @@ func StreamGeminiSSEToOpenAI(r io.Reader, w io.Writer) error {
 
 		if chunk.Model != "" {
 			model = chunk.Model
+		} else if chunk.ModelVersion != "" {
+			model = chunk.ModelVersion
 		}
 		if chunk.UsageMetadata != nil {
 			usage = &OpenAIUsage{
@@ -729,7 +722,7 @@ func StreamGeminiSSEToOpenAI(r io.Reader, w io.Writer) error {
 						{
 							"index":         0,
 							"delta":         map[string]interface{}{},
-							"finish_reason": mapGeminiFinishReason(candidate.FinishReason),
+							"finish_reason": geminiFinishReasonToOpenAI(candidate.FinishReason, toolCounter > 0),
 						},
 					},
 				}
@@ -797,6 +790,8 @@ func StreamGeminiSSEToAnthropic(r io.Reader, w io.Writer) error {
 		}
 		if chunk.Model != "" {
 			model = chunk.Model
+		} else if chunk.ModelVersion != "" {
+			model = chunk.ModelVersion
 		}
 		if !startEmitted {
 			startEmitted = true
@@ -881,7 +876,7 @@ func StreamGeminiSSEToAnthropic(r io.Reader, w io.Writer) error {
 				if err := writeEvent("message_delta", map[string]interface{}{
 					"type": "message_delta",
 					"delta": map[string]interface{}{
-						"stop_reason": mapGeminiFinishReason(candidate.FinishReason),
+						"stop_reason": normalizeOpenAIFinishReason(geminiFinishReasonToOpenAI(candidate.FinishReason, nextIndex > 1)),
 					},
 				}); err != nil {
 					return err
@@ -986,8 +981,9 @@ func formatToolArguments(raw string, fixer *PythonJSONFixer) string {
 }
 
 type geminiStreamingChunk struct {
-	Model      string `json:"model"`
-	Candidates []struct {
+	Model        string `json:"model"`
+	ModelVersion string `json:"modelVersion"`
+	Candidates   []struct {
 		FinishReason string `json:"finishReason"`
 		Content      struct {
 			Parts []struct {
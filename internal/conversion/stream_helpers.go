@@ -61,12 +61,10 @@ func buildResponsesSSEFromChunks(chunks []OpenAIStreamChunk, responseID, model s
 	})
 
 	// 处理chunks，提取文本和工具调用
+	// 工具调用参数会被拆分到多个 chunk（id/name 也可能晚于首个分片到达），按 tool_call.index 累积，
+	// 文本输出完毕后再为每个工具调用输出完整的事件序列
 	textBuilder := strings.Builder{}
-	toolCalls := make(map[int]*struct {
-		id        string
-		name      string
-		arguments strings.Builder
-	})
+	toolCalls := make(map[int]*anthropicToolCallState)
 	toolOrder := []int{}
 	var finishReason string
 
@@ -89,59 +87,22 @@ func buildResponsesSSEFromChunks(chunks []OpenAIStreamChunk, responseID, model s
 				}
 			}
 			
-			// 处理工具调用
-			if choice.Delta.ToolCalls != nil {
-				for _, toolCall := range choice.Delta.ToolCalls {
-					idx := toolCall.Index
-					
-					if _, exists := toolCalls[idx]; !exists {
-						toolCalls[idx] = &struct {
-							id        string
-							name      string
-							arguments strings.Builder
-						}{}
-						toolOrder = append(toolOrder, idx)
-					}
-					
-					tc := toolCalls[idx]
-					
-					if toolCall.ID != "" {
-						if tc.id == "" {
-							tc.id = toolCall.ID
-							// 发送工具调用开始事件
-							writeSSEEvent(&builder, "response.function_call.started", map[string]interface{}{
-								"type":         "response.function_call.started",
-								"response_id":  responseID,
-								"id":           tc.id,
-								"output_index": 0,
-							})
-						}
-					}
-					
-					if toolCall.Function.Name != "" {
-						tc.name = toolCall.Function.Name
-						// 发送工具调用名称事件
-						writeSSEEvent(&builder, "response.function_call.started", map[string]interface{}{
-							"type":         "response.function_call.started",
-							"response_id":  responseID,
-							"id":           tc.id,
-							"name":         tc.name,
-							"output_index": 0,
-						})
-					}
-					
-					if toolCall.Function.Arguments != "" {
-						tc.arguments.WriteString(toolCall.Function.Arguments)
-						// 发送参数delta事件
-						writeSSEEvent(&builder, "response.function_call_arguments.delta", map[string]interface{}{
-							"type":         "response.function_call_arguments.delta",
-							"response_id":  responseID,
-							"id":           tc.id,
-							"delta":        toolCall.Function.Arguments,
-							"output_index": 0,
-						})
-					}
+			// 累积工具调用分片
+			for _, toolCall := range choice.Delta.ToolCalls {
+				idx := toolCall.Index
+				tc, exists := toolCalls[idx]
+				if !exists {
+					tc = &anthropicToolCallState{Index: idx}
+					toolCalls[idx] = tc
+					toolOrder = append(toolOrder, idx)
+				}
+				if tc.ID == "" && toolCall.ID != "" {
+					tc.ID = toolCall.ID
+				}
+				if tc.Name == "" && toolCall.Function.Name != "" {
+					tc.Name = toolCall.Function.Name
 				}
+				tc.Arguments.WriteString(toolCall.Function.Arguments)
 			}
 			
 			// 记录完成原因
@@ -151,37 +112,62 @@ func buildResponsesSSEFromChunks(chunks []OpenAIStreamChunk, responseID, model s
 		}
 	}
 
-	// 为所有工具调用发送完成事件
+	// 为每个工具调用输出 started / 完整参数 delta / completed 事件
+	jsonFixer := NewPythonJSONFixer(nil)
 	for _, idx := range toolOrder {
 		tc := toolCalls[idx]
-		if tc.id != "" {
-			writeSSEEvent(&builder, "response.function_call.completed", map[string]interface{}{
-				"type":         "response.function_call.completed",
-				"response_id":  responseID,
-				"id":           tc.id,
-				"output_index": 0,
-			})
+		if tc.ID == "" {
+			tc.ID = generateToolCallID(tc.Name, idx)
 		}
+		arguments := formatToolArguments(tc.Arguments.String(), jsonFixer)
+		writeSSEEvent(&builder, "response.function_call.started", map[string]interface{}{
+			"type":         "response.function_call.started",
+			"response_id":  responseID,
+			"id":           tc.ID,
+			"name":         tc.Name,
+			"output_index": 0,
+		})
+		writeSSEEvent(&builder, "response.function_call_arguments.delta", map[string]interface{}{
+			"type":         "response.function_call_arguments.delta",
+			"response_id":  responseID,
+			"id":           tc.ID,
+			"delta":        arguments,
+			"output_index": 0,
+		})
+		writeSSEEvent(&builder, "response.function_call.completed", map[string]interface{}{
+			"type":         "response.function_call.completed",
+			"response_id":  responseID,
+			"id":           tc.ID,
+			"name":         tc.Name,
+			"arguments":    arguments,
+			"output_index": 0,
+		})
 	}
 
-	// 发送response.completed事件
+	// 发送response.completed事件：总携带 usage（上游未提供时为 0），Codex 读取 response.usage 统计 token
+	if usage == nil {
+		usage = &OpenAIUsage{}
+	}
+	totalTokens := usage.TotalTokens
+	if totalTokens == 0 {
+		totalTokens = usage.PromptTokens + usage.CompletionTokens
+	}
+	responsesUsage := map[string]interface{}{
+		"input_tokens":  usage.PromptTokens,
+		"output_tokens": usage.CompletionTokens,
+		"total_tokens":  totalTokens,
+	}
 	completed := map[string]interface{}{
 		"type": "response.completed",
 		"response": map[string]interface{}{
 			"id":    responseID,
 			"model": model,
+			"usage": responsesUsage,
 		},
 		"finish_reason": finishReason,
+		"usage":         responsesUsage,
 	}
-	
-	if usage != nil {
-		completed["usage"] = map[string]interface{}{
-			"input_tokens":  usage.PromptTokens,
-			"output_tokens": usage.CompletionTokens,
-			"total_tokens":  usage.TotalTokens,
-		}
-	}
-	
+
 	writeSSEEvent(&builder, "response.completed", completed)
 
 	return []byte(builder.String()), nil
@@ -0,0 +1,224 @@
+package conversion
+
+import (
+	"bytes"
+	"strings"
+
+	jsonutils "claude-code-codex-companion/internal/common/json"
+)
+
+// chatReasoningFields 是各 OpenAI 兼容上游在 Chat Completions message/delta 中携带推理内容的字段
+var chatReasoningFields = []string{"reasoning_content", "reasoning", "reasoning_details"}
+
+// StripReasoningFromResponse removes reasoning/thinking content from a response
+// body: Anthropic thinking/redacted_thinking blocks, OpenAI Responses reasoning
+// output items and Chat Completions reasoning fields. Both JSON and SSE bodies
+// are supported; usage is left untouched. It reports whether anything was removed.
+func StripReasoningFromResponse(body []byte) ([]byte, bool) {
+	if len(body) == 0 {
+		return body, false
+	}
+
+	var data map[string]interface{}
+	if err := jsonutils.SafeUnmarshal(body, &data); err == nil {
+		if !stripReasoningFromObject(data) {
+			return body, false
+		}
+		newBody, err := jsonutils.SafeMarshal(data)
+		if err != nil {
+			return body, false
+		}
+		return newBody, true
+	}
+
+	return stripReasoningFromSSE(body)
+}
+
+// stripReasoningFromObject 处理非流式响应或流式事件中携带的完整响应对象
+func stripReasoningFromObject(data map[string]interface{}) bool {
+	changed := false
+
+	if content, ok := data["content"].([]interface{}); ok {
+		if filtered, removed := filterReasoningItems(content, isThinkingBlock); removed {
+			data["content"] = filtered
+			changed = true
+		}
+	}
+	if output, ok := data["output"].([]interface{}); ok {
+		if filtered, removed := filterReasoningItems(output, isReasoningItem); removed {
+			data["output"] = filtered
+			changed = true
+		}
+	}
+	if choices, ok := data["choices"].([]interface{}); ok {
+		for _, rawChoice := range choices {
+			choice, ok := rawChoice.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			for _, key := range []string{"message", "delta"} {
+				if message, ok := choice[key].(map[string]interface{}); ok {
+					for _, field := range chatReasoningFields {
+						if _, exists := message[field]; exists {
+							delete(message, field)
+							changed = true
+						}
+					}
+				}
+			}
+		}
+	}
+	return changed
+}
+
+// stripReasoningFromSSE 逐个事件处理 SSE 响应：丢弃推理块/推理输出项的全部事件，并重新编号后续块的 index/output_index
+func stripReasoningFromSSE(body []byte) ([]byte, bool) {
+	events := bytes.Split(body, []byte("\n\n"))
+	droppedBlocks := map[int]bool{}
+	droppedOutputs := map[int]bool{}
+	changed := false
+
+	kept := make([][]byte, 0, len(events))
+	for _, event := range events {
+		lines := strings.Split(string(event), "\n")
+		dataLine := -1
+		for i, line := range lines {
+			if strings.HasPrefix(line, "data:") {
+				dataLine = i
+				break
+			}
+		}
+		if dataLine < 0 {
+			kept = append(kept, event)
+			continue
+		}
+		payload := strings.TrimSpace(strings.TrimPrefix(lines[dataLine], "data:"))
+		var data map[string]interface{}
+		if payload == "[DONE]" || jsonutils.SafeUnmarshal([]byte(payload), &data) != nil {
+			kept = append(kept, event)
+			continue
+		}
+
+		drop, modified := stripReasoningFromEvent(data, droppedBlocks, droppedOutputs)
+		if drop {
+			changed = true
+			continue
+		}
+		if modified {
+			if newJSON, err := jsonutils.SafeMarshal(data); err == nil {
+				lines[dataLine] = "data: " + string(newJSON)
+				event = []byte(strings.Join(lines, "\n"))
+				changed = true
+			}
+		}
+		kept = append(kept, event)
+	}
+
+	if !changed {
+		return body, false
+	}
+	return bytes.Join(kept, []byte("\n\n")), true
+}
+
+// stripReasoningFromEvent 返回事件是否应丢弃，以及事件内容是否被修改
+func stripReasoningFromEvent(data map[string]interface{}, droppedBlocks, droppedOutputs map[int]bool) (bool, bool) {
+	eventType, _ := data["type"].(string)
+
+	switch eventType {
+	case "content_block_start":
+		index := jsonInt(data["index"])
+		if block, ok := data["content_block"].(map[string]interface{}); ok && isThinkingBlock(block) {
+			droppedBlocks[index] = true
+			return true, false
+		}
+		return false, reindex(data, "index", droppedBlocks)
+	case "content_block_delta", "content_block_stop":
+		if droppedBlocks[jsonInt(data["index"])] {
+			return true, false
+		}
+		return false, reindex(data, "index", droppedBlocks)
+	case "response.output_item.added", "response.output_item.done":
+		if item, ok := data["item"].(map[string]interface{}); ok && isReasoningItem(item) {
+			droppedOutputs[jsonInt(data["output_index"])] = true
+			return true, false
+		}
+	}
+
+	if strings.HasPrefix(eventType, "response.reasoning") {
+		return true, false
+	}
+	if _, ok := data["output_index"]; ok {
+		if droppedOutputs[jsonInt(data["output_index"])] {
+			return true, false
+		}
+		modified := reindex(data, "output_index", droppedOutputs)
+		if item, ok := data["item"].(map[string]interface{}); ok && stripReasoningFromObject(item) {
+			modified = true
+		}
+		return false, modified
+	}
+
+	modified := false
+	if response, ok := data["response"].(map[string]interface{}); ok && stripReasoningFromObject(response) {
+		modified = true
+	}
+	if message, ok := data["message"].(map[string]interface{}); ok && stripReasoningFromObject(message) {
+		modified = true
+	}
+	if stripReasoningFromObject(data) {
+		modified = true
+	}
+	return false, modified
+}
+
+// reindex 按已丢弃的较小序号数量下调 key 对应的序号，保持客户端看到的序号连续
+func reindex(data map[string]interface{}, key string, dropped map[int]bool) bool {
+	if len(dropped) == 0 {
+		return false
+	}
+	index := jsonInt(data[key])
+	shift := 0
+	for droppedIndex := range dropped {
+		if droppedIndex < index {
+			shift++
+		}
+	}
+	if shift == 0 {
+		return false
+	}
+	data[key] = index - shift
+	return true
+}
+
+func filterReasoningItems(items []interface{}, isReasoning func(map[string]interface{}) bool) ([]interface{}, bool) {
+	filtered := make([]interface{}, 0, len(items))
+	for _, raw := range items {
+		if item, ok := raw.(map[string]interface{}); ok && isReasoning(item) {
+			continue
+		}
+		filtered = append(filtered, raw)
+	}
+	return filtered, len(filtered) != len(items)
+}
+
+func isThinkingBlock(block map[string]interface{}) bool {
+	blockType, _ := block["type"].(string)
+	return blockType == "thinking" || blockType == "redacted_thinking"
+}
+
+func isReasoningItem(item map[string]interface{}) bool {
+	itemType, _ := item["type"].(string)
+	return itemType == "reasoning"
+}
+
+func jsonInt(value interface{}) int {
+	switch v := value.(type) {
+	case float64:
+		return int(v)
+	case int:
+		return v
+	case int64:
+		return int(v)
+	}
+	return 0
+}
@@ -0,0 +1,92 @@
+package conversion
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestStripReasoningFromJSONResponses(t *testing.T) {
+	anthropic := []byte(`{"type":"message","content":[{"type":"thinking","thinking":"hmm","signature":"sig"},{"type":"text","text":"Hi"}],"usage":{"input_tokens":3,"output_tokens":9}}`)
+	stripped, removed := StripReasoningFromResponse(anthropic)
+	if !removed {
+		t.Fatal("expected thinking block to be removed")
+	}
+	var msg struct {
+		Content []map[string]interface{} `json:"content"`
+		Usage   map[string]int           `json:"usage"`
+	}
+	json.Unmarshal(stripped, &msg)
+	if len(msg.Content) != 1 || msg.Content[0]["type"] != "text" || msg.Usage["output_tokens"] != 9 {
+		t.Fatalf("unexpected stripped message: %s", stripped)
+	}
+
+	responses := []byte(`{"object":"response","output":[{"type":"reasoning","summary":[]},{"type":"message","content":[{"type":"output_text","text":"Hi"}]}],"usage":{"output_tokens":5}}`)
+	stripped, removed = StripReasoningFromResponse(responses)
+	if !removed || strings.Contains(string(stripped), `"reasoning"`) || !strings.Contains(string(stripped), `"output_tokens":5`) {
+		t.Fatalf("unexpected stripped responses body: %s", stripped)
+	}
+
+	chat := []byte(`{"choices":[{"message":{"role":"assistant","content":"Hi","reasoning_content":"hmm"}}]}`)
+	stripped, removed = StripReasoningFromResponse(chat)
+	if !removed || strings.Contains(string(stripped), "reasoning_content") {
+		t.Fatalf("unexpected stripped chat body: %s", stripped)
+	}
+
+	plain := []byte(`{"type":"message","content":[{"type":"text","text":"Hi"}]}`)
+	if out, removed := StripReasoningFromResponse(plain); removed || string(out) != string(plain) {
+		t.Fatal("expected body without reasoning to be returned unchanged")
+	}
+}
+
+func TestStripReasoningFromAnthropicSSE(t *testing.T) {
+	stream := strings.Join([]string{
+		"event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"content\":[],\"usage\":{\"input_tokens\":3}}}",
+		"event: content_block_start\ndata: {\"type\":\"content_block_start\",\"index\":0,\"content_block\":{\"type\":\"thinking\",\"thinking\":\"\"}}",
+		"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"thinking_delta\",\"thinking\":\"hmm\"}}",
+		"event: content_block_stop\ndata: {\"type\":\"content_block_stop\",\"index\":0}",
+		"event: content_block_start\ndata: {\"type\":\"content_block_start\",\"index\":1,\"content_block\":{\"type\":\"text\",\"text\":\"\"}}",
+		"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":1,\"delta\":{\"type\":\"text_delta\",\"text\":\"Hi\"}}",
+		"event: content_block_stop\ndata: {\"type\":\"content_block_stop\",\"index\":1}",
+		"event: message_delta\ndata: {\"type\":\"message_delta\",\"delta\":{\"stop_reason\":\"end_turn\"},\"usage\":{\"output_tokens\":9}}",
+		"",
+	}, "\n\n")
+
+	stripped, removed := StripReasoningFromResponse([]byte(stream))
+	if !removed {
+		t.Fatal("expected thinking events to be removed")
+	}
+	out := string(stripped)
+	if strings.Contains(out, "thinking") {
+		t.Fatalf("expected no thinking events, got %s", out)
+	}
+	if strings.Contains(out, `"index":1`) || !strings.Contains(out, `"text":"Hi"`) {
+		t.Fatalf("expected text block to be renumbered to index 0, got %s", out)
+	}
+	if !strings.Contains(out, `"output_tokens":9`) {
+		t.Fatalf("expected usage to be kept, got %s", out)
+	}
+}
+
+func TestStripReasoningFromResponsesSSE(t *testing.T) {
+	stream := strings.Join([]string{
+		"event: response.output_item.added\ndata: {\"type\":\"response.output_item.added\",\"output_index\":0,\"item\":{\"type\":\"reasoning\"}}",
+		"event: response.reasoning_summary_text.delta\ndata: {\"type\":\"response.reasoning_summary_text.delta\",\"output_index\":0,\"delta\":\"hmm\"}",
+		"event: response.output_item.done\ndata: {\"type\":\"response.output_item.done\",\"output_index\":0,\"item\":{\"type\":\"reasoning\"}}",
+		"event: response.output_text.delta\ndata: {\"type\":\"response.output_text.delta\",\"output_index\":1,\"delta\":\"Hi\"}",
+		"event: response.completed\ndata: {\"type\":\"response.completed\",\"response\":{\"output\":[{\"type\":\"reasoning\"},{\"type\":\"message\"}],\"usage\":{\"output_tokens\":5}}}",
+		"",
+	}, "\n\n")
+
+	stripped, removed := StripReasoningFromResponse([]byte(stream))
+	if !removed {
+		t.Fatal("expected reasoning events to be removed")
+	}
+	out := string(stripped)
+	if strings.Contains(out, "reasoning") {
+		t.Fatalf("expected no reasoning content, got %s", out)
+	}
+	if !strings.Contains(out, `"output_index":0`) || !strings.Contains(out, `"output_tokens":5`) {
+		t.Fatalf("expected renumbered output and intact usage, got %s", out)
+	}
+}
@@ -0,0 +1,175 @@
+package conversion
+
+import (
+	"bytes"
+	"strings"
+
+	jsonutils "claude-code-codex-companion/internal/common/json"
+)
+
+// 流式响应中 thinking 内容的处理方式（thinking_stream_mode）
+const (
+	ThinkingStreamModePassthrough = "passthrough" // 原样透传（默认）
+	ThinkingStreamModeText        = "text"        // thinking 块/推理增量改写为普通文本
+	ThinkingStreamModeDrop        = "drop"        // 丢弃 thinking 块/推理增量
+)
+
+// NormalizeThinkingStreamMode 规范化取值，空值或未知值返回空字符串（表示未配置）
+func NormalizeThinkingStreamMode(mode string) string {
+	switch normalized := strings.ToLower(strings.TrimSpace(mode)); normalized {
+	case ThinkingStreamModePassthrough, ThinkingStreamModeText, ThinkingStreamModeDrop:
+		return normalized
+	}
+	return ""
+}
+
+// IsValidThinkingStreamMode 判断是否为受支持的模式（空值视为未配置，也合法）
+func IsValidThinkingStreamMode(mode string) bool {
+	return strings.TrimSpace(mode) == "" || NormalizeThinkingStreamMode(mode) != ""
+}
+
+// ApplyThinkingStreamMode 按模式处理 SSE 响应中的 thinking 内容，返回新响应体及是否发生修改。
+// text：Anthropic thinking 块改为 text 块（thinking_delta -> text_delta，丢弃 signature_delta 与
+// redacted_thinking 块），Chat Completions 的 reasoning_content 并入 content；Responses 推理事件保持不变。
+// drop：与 strip_reasoning_in_response 相同，移除全部推理内容。正文内容始终保留。
+func ApplyThinkingStreamMode(body []byte, mode string) ([]byte, bool) {
+	switch NormalizeThinkingStreamMode(mode) {
+	case ThinkingStreamModeDrop:
+		return StripReasoningFromResponse(body)
+	case ThinkingStreamModeText:
+		return relabelThinkingInSSE(body)
+	}
+	return body, false
+}
+
+// relabelThinkingInSSE 逐个事件将 thinking 内容改写为普通文本
+func relabelThinkingInSSE(body []byte) ([]byte, bool) {
+	if len(body) == 0 {
+		return body, false
+	}
+
+	events := bytes.Split(body, []byte("\n\n"))
+	droppedBlocks := map[int]bool{}
+	changed := false
+
+	kept := make([][]byte, 0, len(events))
+	for _, event := range events {
+		lines := strings.Split(string(event), "\n")
+		dataLine := -1
+		for i, line := range lines {
+			if strings.HasPrefix(line, "data:") {
+				dataLine = i
+				break
+			}
+		}
+		if dataLine < 0 {
+			kept = append(kept, event)
+			continue
+		}
+		payload := strings.TrimSpace(strings.TrimPrefix(lines[dataLine], "data:"))
+		var data map[string]interface{}
+		if payload == "[DONE]" || jsonutils.SafeUnmarshal([]byte(payload), &data) != nil {
+			kept = append(kept, event)
+			continue
+		}
+
+		drop, modified := relabelThinkingInEvent(data, droppedBlocks)
+		if drop {
+			changed = true
+			continue
+		}
+		if modified {
+			if newJSON, err := jsonutils.SafeMarshal(data); err == nil {
+				lines[dataLine] = "data: " + string(newJSON)
+				event = []byte(strings.Join(lines, "\n"))
+				changed = true
+			}
+		}
+		kept = append(kept, event)
+	}
+
+	if !changed {
+		return body, false
+	}
+	return bytes.Join(kept, []byte("\n\n")), true
+}
+
+// relabelThinkingInEvent 返回事件是否应丢弃，以及事件内容是否被修改
+func relabelThinkingInEvent(data map[string]interface{}, droppedBlocks map[int]bool) (bool, bool) {
+	eventType, _ := data["type"].(string)
+
+	switch eventType {
+	case "content_block_start":
+		index := jsonInt(data["index"])
+		block, _ := data["content_block"].(map[string]interface{})
+		if blockType, _ := block["type"].(string); blockType == "redacted_thinking" {
+			droppedBlocks[index] = true
+			return true, false
+		} else if blockType == "thinking" {
+			text, _ := block["thinking"].(string)
+			data["content_block"] = map[string]interface{}{"type": "text", "text": text}
+			reindex(data, "index", droppedBlocks)
+			return false, true
+		}
+		return false, reindex(data, "index", droppedBlocks)
+	case "content_block_delta":
+		if droppedBlocks[jsonInt(data["index"])] {
+			return true, false
+		}
+		modified := reindex(data, "index", droppedBlocks)
+		delta, _ := data["delta"].(map[string]interface{})
+		switch deltaType, _ := delta["type"].(string); deltaType {
+		case "signature_delta":
+			return true, false
+		case "thinking_delta":
+			text, _ := delta["thinking"].(string)
+			data["delta"] = map[string]interface{}{"type": "text_delta", "text": text}
+			return false, true
+		}
+		return false, modified
+	case "content_block_stop":
+		if droppedBlocks[jsonInt(data["index"])] {
+			return true, false
+		}
+		return false, reindex(data, "index", droppedBlocks)
+	}
+
+	modified := false
+	if choices, ok := data["choices"].([]interface{}); ok {
+		for _, rawChoice := range choices {
+			choice, ok := rawChoice.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if delta, ok := choice["delta"].(map[string]interface{}); ok && mergeChatReasoningIntoContent(delta) {
+				modified = true
+			}
+		}
+	}
+	return false, modified
+}
+
+// mergeChatReasoningIntoContent 将 Chat Completions 增量中的推理文本并入 content，并移除推理字段
+func mergeChatReasoningIntoContent(delta map[string]interface{}) bool {
+	reasoning := ""
+	found := false
+	for _, field := range chatReasoningFields {
+		value, exists := delta[field]
+		if !exists {
+			continue
+		}
+		if text, ok := value.(string); ok && reasoning == "" {
+			reasoning = text
+		}
+		delete(delta, field)
+		found = true
+	}
+	if !found {
+		return false
+	}
+	if reasoning != "" {
+		content, _ := delta["content"].(string)
+		delta["content"] = reasoning + content
+	}
+	return true
+}
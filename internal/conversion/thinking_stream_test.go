@@ -0,0 +1,84 @@
+package conversion
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestApplyThinkingStreamModeTextRelabelsAnthropicThinking(t *testing.T) {
+	stream := strings.Join([]string{
+		"event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"content\":[],\"usage\":{\"input_tokens\":3}}}",
+		"event: content_block_start\ndata: {\"type\":\"content_block_start\",\"index\":0,\"content_block\":{\"type\":\"redacted_thinking\",\"data\":\"xyz\"}}",
+		"event: content_block_stop\ndata: {\"type\":\"content_block_stop\",\"index\":0}",
+		"event: content_block_start\ndata: {\"type\":\"content_block_start\",\"index\":1,\"content_block\":{\"type\":\"thinking\",\"thinking\":\"\"}}",
+		"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":1,\"delta\":{\"type\":\"thinking_delta\",\"thinking\":\"hmm\"}}",
+		"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":1,\"delta\":{\"type\":\"signature_delta\",\"signature\":\"sig\"}}",
+		"event: content_block_stop\ndata: {\"type\":\"content_block_stop\",\"index\":1}",
+		"event: content_block_start\ndata: {\"type\":\"content_block_start\",\"index\":2,\"content_block\":{\"type\":\"text\",\"text\":\"\"}}",
+		"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":2,\"delta\":{\"type\":\"text_delta\",\"text\":\"Hi\"}}",
+		"event: content_block_stop\ndata: {\"type\":\"content_block_stop\",\"index\":2}",
+		"event: message_delta\ndata: {\"type\":\"message_delta\",\"delta\":{\"stop_reason\":\"end_turn\"},\"usage\":{\"output_tokens\":9}}",
+		"",
+	}, "\n\n")
+
+	out, changed := ApplyThinkingStreamMode([]byte(stream), ThinkingStreamModeText)
+	if !changed {
+		t.Fatal("expected thinking events to be relabeled")
+	}
+	got := string(out)
+	if strings.Contains(got, "thinking") || strings.Contains(got, "signature") {
+		t.Fatalf("expected no thinking or signature events, got %s", got)
+	}
+	if !strings.Contains(got, `{"delta":{"text":"hmm","type":"text_delta"},"index":0,"type":"content_block_delta"}`) {
+		t.Fatalf("expected thinking delta to become a text delta at index 0, got %s", got)
+	}
+	if !strings.Contains(got, `{"delta":{"text":"Hi","type":"text_delta"},"index":1,"type":"content_block_delta"}`) {
+		t.Fatalf("expected the final answer to be kept and renumbered to index 1, got %s", got)
+	}
+	if !strings.Contains(got, `"output_tokens":9`) {
+		t.Fatalf("expected usage to be kept, got %s", got)
+	}
+}
+
+func TestApplyThinkingStreamModeTextMergesChatReasoning(t *testing.T) {
+	stream := strings.Join([]string{
+		`data: {"choices":[{"index":0,"delta":{"role":"assistant","reasoning_content":"hmm"}}]}`,
+		`data: {"choices":[{"index":0,"delta":{"content":"Hi"}}]}`,
+		`data: [DONE]`,
+		"",
+	}, "\n\n")
+
+	out, changed := ApplyThinkingStreamMode([]byte(stream), "TEXT")
+	if !changed {
+		t.Fatal("expected reasoning_content to be merged")
+	}
+	got := string(out)
+	if strings.Contains(got, "reasoning_content") || !strings.Contains(got, `"content":"hmm"`) || !strings.Contains(got, `"content":"Hi"`) {
+		t.Fatalf("unexpected relabeled chat stream: %s", got)
+	}
+}
+
+func TestApplyThinkingStreamModeDropAndPassthrough(t *testing.T) {
+	stream := strings.Join([]string{
+		"event: content_block_start\ndata: {\"type\":\"content_block_start\",\"index\":0,\"content_block\":{\"type\":\"thinking\",\"thinking\":\"\"}}",
+		"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"thinking_delta\",\"thinking\":\"hmm\"}}",
+		"event: content_block_stop\ndata: {\"type\":\"content_block_stop\",\"index\":0}",
+		"event: content_block_start\ndata: {\"type\":\"content_block_start\",\"index\":1,\"content_block\":{\"type\":\"text\",\"text\":\"\"}}",
+		"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":1,\"delta\":{\"type\":\"text_delta\",\"text\":\"Hi\"}}",
+		"",
+	}, "\n\n")
+
+	out, changed := ApplyThinkingStreamMode([]byte(stream), ThinkingStreamModeDrop)
+	if !changed || strings.Contains(string(out), "hmm") || !strings.Contains(string(out), `"text":"Hi"`) {
+		t.Fatalf("expected thinking to be dropped and answer kept, got %s", out)
+	}
+
+	for _, mode := range []string{"", ThinkingStreamModePassthrough, "unknown"} {
+		if out, changed := ApplyThinkingStreamMode([]byte(stream), mode); changed || string(out) != stream {
+			t.Fatalf("expected mode %q to leave the stream untouched", mode)
+		}
+	}
+	if IsValidThinkingStreamMode("relabel") || !IsValidThinkingStreamMode("") || !IsValidThinkingStreamMode(" Drop ") {
+		t.Fatal("unexpected thinking_stream_mode validation result")
+	}
+}
@@ -265,3 +265,134 @@ func TestMixedUserContent(t *testing.T) {
 	t.Logf("✅ Mixed user content (tool_result + text) parsed successfully")
 }
 
+// TestMultiTurnToolRoundTrip 模拟完整的 tool_call → tool_result → 最终回答 多轮对话在两种格式间的转换
+func TestMultiTurnToolRoundTrip(t *testing.T) {
+	anthropicJSON := `{
+		"model": "claude-sonnet-4-20250514",
+		"max_tokens": 1024,
+		"tools": [{"name": "get_weather", "description": "Get weather", "input_schema": {"type": "object"}}],
+		"messages": [
+			{"role": "user", "content": "What's the weather in Paris and Tokyo?"},
+			{"role": "assistant", "content": [
+				{"type": "text", "text": "Let me check."},
+				{"type": "tool_use", "id": "toolu_paris", "name": "get_weather", "input": {"city": "Paris"}},
+				{"type": "tool_use", "id": "toolu_tokyo", "name": "get_weather", "input": {"city": "Tokyo"}}
+			]},
+			{"role": "user", "content": [
+				{"type": "tool_result", "tool_use_id": "toolu_paris", "content": [{"type": "text", "text": "Sunny, 25°C"}]},
+				{"type": "tool_result", "tool_use_id": "toolu_tokyo", "content": "service unavailable", "is_error": true}
+			]},
+			{"role": "assistant", "content": "Paris is sunny; Tokyo's weather is unavailable."},
+			{"role": "user", "content": "Thanks!"}
+		]
+	}`
+
+	// Anthropic -> OpenAI（RequestConverter）
+	converter := NewRequestConverter(getTestLoggerForToolResult())
+	openaiBytes, _, err := converter.Convert([]byte(anthropicJSON), &EndpointInfo{Type: "openai"})
+	if err != nil {
+		t.Fatalf("Failed to convert request: %v", err)
+	}
+	var openaiReq OpenAIRequest
+	if err := json.Unmarshal(openaiBytes, &openaiReq); err != nil {
+		t.Fatalf("Failed to unmarshal result: %v", err)
+	}
+	assertOpenAIToolExchange(t, "RequestConverter", openaiReq.Messages)
+
+	// Anthropic -> OpenAI（适配器链路）
+	anthropicAdapter := NewAnthropicFormatAdapter(nil)
+	chatAdapter := NewOpenAIChatFormatAdapter(nil)
+	internalReq, err := anthropicAdapter.ParseRequestJSON([]byte(anthropicJSON))
+	if err != nil {
+		t.Fatalf("Failed to parse Anthropic request: %v", err)
+	}
+	adapterBytes, err := chatAdapter.BuildRequestJSON(internalReq)
+	if err != nil {
+		t.Fatalf("Failed to build OpenAI request: %v", err)
+	}
+	var adapterReq OpenAIRequest
+	if err := json.Unmarshal(adapterBytes, &adapterReq); err != nil {
+		t.Fatalf("Failed to unmarshal adapter result: %v", err)
+	}
+	assertOpenAIToolExchange(t, "adapter", adapterReq.Messages)
+
+	// OpenAI -> Anthropic：tool 消息应合并回同一条 user 消息并还原 is_error
+	internalBack, err := chatAdapter.ParseRequestJSON(openaiBytes)
+	if err != nil {
+		t.Fatalf("Failed to parse OpenAI request: %v", err)
+	}
+	anthropicBytes, err := anthropicAdapter.BuildRequestJSON(internalBack)
+	if err != nil {
+		t.Fatalf("Failed to build Anthropic request: %v", err)
+	}
+	var anthReq AnthropicRequest
+	if err := json.Unmarshal(anthropicBytes, &anthReq); err != nil {
+		t.Fatalf("Failed to unmarshal Anthropic result: %v", err)
+	}
+
+	if len(anthReq.Messages) != 5 {
+		t.Fatalf("Expected 5 Anthropic messages, got %d", len(anthReq.Messages))
+	}
+
+	assistantBlocks := anthReq.Messages[1].GetContentBlocks()
+	toolUses := 0
+	for _, block := range assistantBlocks {
+		if block.Type == "tool_use" {
+			toolUses++
+		}
+	}
+	if toolUses != 2 {
+		t.Errorf("Expected 2 tool_use blocks, got %d", toolUses)
+	}
+
+	results := anthReq.Messages[2]
+	if results.Role != "user" {
+		t.Errorf("Expected tool results in a user message, got role %q", results.Role)
+	}
+	resultBlocks := results.GetContentBlocks()
+	if len(resultBlocks) != 2 {
+		t.Fatalf("Expected 2 tool_result blocks, got %d", len(resultBlocks))
+	}
+	if resultBlocks[0].ToolUseID != "toolu_paris" || resultBlocks[0].IsError != nil {
+		t.Errorf("Unexpected first tool_result: id=%s is_error=%v", resultBlocks[0].ToolUseID, resultBlocks[0].IsError)
+	}
+	if extractToolResultText(resultBlocks[0].Content) != "Sunny, 25°C" {
+		t.Errorf("Unexpected first tool_result content: %v", resultBlocks[0].Content)
+	}
+	if resultBlocks[1].ToolUseID != "toolu_tokyo" || resultBlocks[1].IsError == nil || !*resultBlocks[1].IsError {
+		t.Errorf("Expected second tool_result to keep is_error")
+	}
+	if extractToolResultText(resultBlocks[1].Content) != "service unavailable" {
+		t.Errorf("Unexpected second tool_result content: %v", resultBlocks[1].Content)
+	}
+	if anthReq.Messages[3].Role != "assistant" || anthReq.Messages[4].Role != "user" {
+		t.Errorf("Unexpected trailing roles: %s, %s", anthReq.Messages[3].Role, anthReq.Messages[4].Role)
+	}
+}
+
+func assertOpenAIToolExchange(t *testing.T, path string, messages []OpenAIMessage) {
+	t.Helper()
+
+	expectedRoles := []string{"user", "assistant", "tool", "tool", "assistant", "user"}
+	if len(messages) != len(expectedRoles) {
+		t.Fatalf("%s: expected %d messages, got %d", path, len(expectedRoles), len(messages))
+	}
+	for i, role := range expectedRoles {
+		if messages[i].Role != role {
+			t.Errorf("%s: message %d expected role %q, got %q", path, i, role, messages[i].Role)
+		}
+	}
+
+	if len(messages[1].ToolCalls) != 2 {
+		t.Fatalf("%s: expected 2 tool calls, got %d", path, len(messages[1].ToolCalls))
+	}
+	if messages[2].ToolCallID != messages[1].ToolCalls[0].ID || messages[3].ToolCallID != messages[1].ToolCalls[1].ID {
+		t.Errorf("%s: tool_call_id does not match tool call ids", path)
+	}
+	if messages[2].Content != "Sunny, 25°C" {
+		t.Errorf("%s: unexpected tool content %v", path, messages[2].Content)
+	}
+	if content, _ := messages[3].Content.(string); content != toolResultErrorMarker+"service unavailable" {
+		t.Errorf("%s: expected error marker on failed tool result, got %v", path, messages[3].Content)
+	}
+}
@@ -14,6 +14,7 @@ type RequestAdapter interface {
 type EndpointInfo struct {
 	Type               string
 	MaxTokensFieldName string
+	UserFieldMode      string // 端点 user_field_mode，作用于 user / metadata.user_id 映射
 }
 
 // Converter describes the high level request/response conversion helpers used
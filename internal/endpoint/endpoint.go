@@ -3,11 +3,13 @@ package endpoint
 import (
 	"fmt"
 	"net/http"
+	"regexp"
 	"strings"
 	"sync"
 	"time"
 
 	"claude-code-codex-companion/internal/common/httpclient"
+	jsonutils "claude-code-codex-companion/internal/common/json"
 	commonutils "claude-code-codex-companion/internal/common/utils"
 	"claude-code-codex-companion/internal/config"
 	"claude-code-codex-companion/internal/interfaces"
@@ -59,12 +61,16 @@ type Endpoint struct {
 	HeaderOverrides    map[string]string          `json:"header_overrides,omitempty"`      // 新增：HTTP Header覆盖配置
 	ParameterOverrides map[string]string          `json:"parameter_overrides,omitempty"`   // 新增：Request Parameters覆盖配置
 	MaxTokensFieldName string                     `json:"max_tokens_field_name,omitempty"` // max_tokens 参数名转换选项
+	UserFieldMode      string                     `json:"user_field_mode,omitempty"`       // OpenAI user 字段处理：passthrough|strip|hash|truncate
 	RateLimitReset     *int64                     `json:"rate_limit_reset,omitempty"`      // Anthropic-Ratelimit-Unified-Reset
 	RateLimitStatus    *string                    `json:"rate_limit_status,omitempty"`     // Anthropic-Ratelimit-Unified-Status
 	EnhancedProtection bool                       `json:"enhanced_protection,omitempty"`   // 官方帐号增强保护：allowed_warning时即禁用端点
 	SSEConfig          *config.SSEConfig          `json:"sse_config,omitempty"`            // SSE行为配置
 	OpenAIPreference   string                     `json:"openai_preference,omitempty"`     // OpenAI格式偏好："responses"|"chat_completions"|"auto"
 	SupportsResponses  *bool                      `json:"supports_responses,omitempty"`    // 显式声明 /responses 支持情况
+	AnthropicVersion   string                     `json:"anthropic_version,omitempty"`     // 覆盖 anthropic-version 请求头
+	UserAgent          string                     `json:"user_agent,omitempty"`            // 覆盖转发请求的 User-Agent
+	InsecureSkipVerify bool                       `json:"insecure_skip_verify,omitempty"`  // 跳过 TLS 证书校验（自签名证书的内网端点）
 	// 是否允许使用 /count_tokens 接口
 	CountTokensEnabled bool `json:"count_tokens_enabled"`
 	// 记录 count_tokens 支持情况（nil 表示未知）
@@ -107,6 +113,13 @@ type Endpoint struct {
 	// 新增：保护 DetectedAuthHeader 的互斥锁
 	AuthHeaderMutex sync.RWMutex
 
+	// 能力探测结果（运行时学习，不持久化）：nil 表示未探测
+	StreamingSupport *bool `json:"-"`
+	VisionSupport    *bool `json:"-"`
+	// 探测到的 max_tokens 参数名（"max_tokens" 或 "max_completion_tokens"），空字符串表示未探测
+	LearnedMaxTokensField string `json:"-"`
+	capabilityMutex       sync.RWMutex
+
 	// 新增：动态排序器引用（用于状态变化时触发排序更新）
 	dynamicSorter *utils.DynamicEndpointSorter `json:"-"`
 
@@ -204,12 +217,16 @@ func NewEndpoint(cfg config.EndpointConfig) *Endpoint {
 		HeaderOverrides:    cfg.HeaderOverrides,
 		ParameterOverrides: cfg.ParameterOverrides,
 		MaxTokensFieldName: cfg.MaxTokensFieldName,
+		UserFieldMode:      cfg.UserFieldMode,
 		RateLimitReset:     cfg.RateLimitReset,
 		RateLimitStatus:    cfg.RateLimitStatus,
 		EnhancedProtection: cfg.EnhancedProtection,
 		SSEConfig:          cfg.SSEConfig,
 		OpenAIPreference:   openAIPreference,
 		SupportsResponses:  cfg.SupportsResponses,
+		AnthropicVersion:   cfg.AnthropicVersion,
+		UserAgent:          cfg.UserAgent,
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
 		CountTokensEnabled: countTokensEnabled,
 		NativeCodexFormat:  nativeCodexFormat,
 		Status:             StatusActive,
@@ -634,6 +651,7 @@ func generateID(name string) string {
 func (e *Endpoint) CreateProxyClient(timeoutConfig config.ProxyTimeoutConfig) (*http.Client, error) {
 	e.mutex.RLock()
 	proxyConfig := e.Proxy
+	insecureSkipVerify := e.InsecureSkipVerify
 	e.mutex.RUnlock()
 
 	factory := httpclient.NewFactory()
@@ -645,7 +663,8 @@ func (e *Endpoint) CreateProxyClient(timeoutConfig config.ProxyTimeoutConfig) (*
 			IdleConnection: commonutils.ParseDuration(timeoutConfig.IdleConnection, 90*time.Second),
 			OverallRequest: commonutils.ParseDuration(timeoutConfig.OverallRequest, 0),
 		},
-		ProxyConfig: proxyConfig,
+		ProxyConfig:        proxyConfig,
+		InsecureSkipVerify: insecureSkipVerify,
 	}
 
 	return factory.CreateClient(clientConfig)
@@ -655,6 +674,7 @@ func (e *Endpoint) CreateProxyClient(timeoutConfig config.ProxyTimeoutConfig) (*
 func (e *Endpoint) CreateHealthClient(timeoutConfig config.HealthCheckTimeoutConfig) (*http.Client, error) {
 	e.mutex.RLock()
 	proxyConfig := e.Proxy
+	insecureSkipVerify := e.InsecureSkipVerify
 	e.mutex.RUnlock()
 
 	factory := httpclient.NewFactory()
@@ -666,7 +686,8 @@ func (e *Endpoint) CreateHealthClient(timeoutConfig config.HealthCheckTimeoutCon
 			IdleConnection: commonutils.ParseDuration(timeoutConfig.IdleConnection, 60*time.Second),
 			OverallRequest: commonutils.ParseDuration(timeoutConfig.OverallRequest, 30*time.Second),
 		},
-		ProxyConfig: proxyConfig,
+		ProxyConfig:        proxyConfig,
+		InsecureSkipVerify: insecureSkipVerify,
 	}
 
 	return factory.CreateClient(clientConfig)
@@ -982,6 +1003,70 @@ func (e *Endpoint) LearnUnsupportedParam(param string) {
 	e.LearnedUnsupportedParams = append(e.LearnedUnsupportedParams, param)
 }
 
+// unsupportedParamNamePattern 匹配错误消息中的参数名，如 "parameter 'xxx' is not supported"
+var unsupportedParamNamePattern = regexp.MustCompile(`parameter["']?\s*([a-zA-Z_][a-zA-Z0-9_]*)`)
+
+// LearnUnsupportedParamsFromError 从上游错误响应中学习请求体里不被支持的参数，返回本次识别出的参数名
+func (e *Endpoint) LearnUnsupportedParamsFromError(errorBody, requestBody []byte) []string {
+	if len(errorBody) == 0 {
+		return nil
+	}
+
+	// 解析错误消息
+	var errorData map[string]interface{}
+	if err := jsonutils.SafeUnmarshal(errorBody, &errorData); err != nil {
+		return nil // 无法解析为JSON,忽略
+	}
+	errorMsg := ""
+	if msg, ok := errorData["message"].(string); ok {
+		errorMsg = msg
+	} else if errField, ok := errorData["error"].(map[string]interface{}); ok {
+		if msg, ok := errField["message"].(string); ok {
+			errorMsg = msg
+		}
+	} else if errField, ok := errorData["error"].(string); ok {
+		errorMsg = errField
+	}
+	if errorMsg == "" {
+		return nil
+	}
+
+	// 只学习请求中实际存在的参数
+	var requestData map[string]interface{}
+	if err := jsonutils.SafeUnmarshal(requestBody, &requestData); err != nil {
+		return nil
+	}
+
+	var learned []string
+	learn := func(param string) {
+		if _, exists := requestData[param]; exists {
+			e.LearnUnsupportedParam(param)
+			learned = append(learned, param)
+		}
+	}
+
+	errorMsgLower := strings.ToLower(errorMsg)
+	// 工具调用相关的错误：学习全部工具参数
+	for _, keyword := range []string{"tool", "function", "function_call", "tool_choice"} {
+		if strings.Contains(errorMsgLower, keyword) {
+			for _, param := range []string{"tools", "tool_choice", "functions", "function_call"} {
+				learn(param)
+			}
+			break
+		}
+	}
+	// 通用的不支持参数错误：从错误消息中提取参数名
+	for _, keyword := range []string{"unsupported", "not supported", "invalid parameter", "unexpected parameter"} {
+		if strings.Contains(errorMsgLower, keyword) {
+			if matches := unsupportedParamNamePattern.FindStringSubmatch(errorMsg); len(matches) > 1 {
+				learn(matches[1])
+			}
+			break
+		}
+	}
+	return learned
+}
+
 // IsParamUnsupported 检查参数是否已被学习为不支持
 func (e *Endpoint) IsParamUnsupported(param string) bool {
 	e.learnedParamsMutex.RLock()
@@ -1005,6 +1090,112 @@ func (e *Endpoint) GetLearnedUnsupportedParams() []string {
 	return result
 }
 
+// LearningSnapshot 端点运行时学习到的能力信息
+type LearningSnapshot struct {
+	UnsupportedParams  []string `json:"unsupported_params"`
+	NativeCodexFormat  *bool    `json:"native_codex_format"`  // nil = 未探测
+	DetectedAuthHeader string   `json:"detected_auth_header"` // 空字符串 = 未检测
+	CountTokensSupport *bool    `json:"count_tokens_support"` // nil = 未知
+	StreamingSupport   *bool    `json:"streaming_support"`    // nil = 未探测
+	VisionSupport      *bool    `json:"vision_support"`       // nil = 未探测
+	MaxTokensField     string   `json:"max_tokens_field"`     // 空字符串 = 未探测
+}
+
+// MarkCapabilities 记录能力探测得到的流式、图片输入支持情况与 max_tokens 参数名；nil/空值表示未能判断，保留原结果
+func (e *Endpoint) MarkCapabilities(streaming, vision *bool, maxTokensField string) {
+	e.capabilityMutex.Lock()
+	defer e.capabilityMutex.Unlock()
+
+	if streaming != nil {
+		value := *streaming
+		e.StreamingSupport = &value
+	}
+	if vision != nil {
+		value := *vision
+		e.VisionSupport = &value
+	}
+	if maxTokensField != "" {
+		e.LearnedMaxTokensField = maxTokensField
+	}
+}
+
+// SetNativeCodexSupport 以显式探测结果覆盖 /responses 支持状态（与 UpdateNativeCodexSupport 不同，已有判断也会被更新）
+func (e *Endpoint) SetNativeCodexSupport(supported bool) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.NativeCodexFormat = &supported
+}
+
+// GetLearningSnapshot 获取端点运行时学习结果的副本
+func (e *Endpoint) GetLearningSnapshot() LearningSnapshot {
+	snapshot := LearningSnapshot{
+		UnsupportedParams: e.GetLearnedUnsupportedParams(),
+	}
+
+	e.mutex.RLock()
+	if e.NativeCodexFormat != nil {
+		value := *e.NativeCodexFormat
+		snapshot.NativeCodexFormat = &value
+	}
+	e.mutex.RUnlock()
+
+	e.AuthHeaderMutex.RLock()
+	snapshot.DetectedAuthHeader = e.DetectedAuthHeader
+	e.AuthHeaderMutex.RUnlock()
+
+	e.countTokensMutex.RLock()
+	if e.CountTokensSupport != nil {
+		value := *e.CountTokensSupport
+		snapshot.CountTokensSupport = &value
+	}
+	e.countTokensMutex.RUnlock()
+
+	e.capabilityMutex.RLock()
+	if e.StreamingSupport != nil {
+		value := *e.StreamingSupport
+		snapshot.StreamingSupport = &value
+	}
+	if e.VisionSupport != nil {
+		value := *e.VisionSupport
+		snapshot.VisionSupport = &value
+	}
+	snapshot.MaxTokensField = e.LearnedMaxTokensField
+	e.capabilityMutex.RUnlock()
+
+	return snapshot
+}
+
+// ResetLearning 清除运行时学习结果（显式配置的 supports_responses 保留）
+func (e *Endpoint) ResetLearning() {
+	e.learnedParamsMutex.Lock()
+	e.LearnedUnsupportedParams = nil
+	e.learnedParamsMutex.Unlock()
+
+	e.mutex.Lock()
+	if e.SupportsResponses != nil {
+		value := *e.SupportsResponses
+		e.NativeCodexFormat = &value
+	} else {
+		e.NativeCodexFormat = nil
+	}
+	e.mutex.Unlock()
+
+	e.AuthHeaderMutex.Lock()
+	e.DetectedAuthHeader = ""
+	e.AuthHeaderMutex.Unlock()
+
+	e.countTokensMutex.Lock()
+	e.CountTokensSupport = nil
+	e.countTokensMutex.Unlock()
+
+	e.capabilityMutex.Lock()
+	e.StreamingSupport = nil
+	e.VisionSupport = nil
+	e.LearnedMaxTokensField = ""
+	e.capabilityMutex.Unlock()
+}
+
 // GetURL 获取主URL用于日志记录等场景 (优先Anthropic URL)
 // GetURL 返回端点的基础URL（用于日志记录和显示）
 // 优先返回 URLAnthropic,因为它通常是主URL
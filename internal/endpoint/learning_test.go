@@ -0,0 +1,81 @@
+package endpoint
+
+import (
+	"testing"
+
+	"claude-code-codex-companion/internal/config"
+)
+
+// TestResetLearning 测试运行时学习结果的读取与重置
+func TestResetLearning(t *testing.T) {
+	supportsResponses := true
+	ep := NewEndpoint(config.EndpointConfig{
+		Name:              "learning",
+		URLOpenAI:         "https://api.example.com",
+		SupportsResponses: &supportsResponses,
+	})
+
+	ep.LearnUnsupportedParam("tool_choice")
+	ep.MarkCountTokensSupport(false)
+	ep.DetectedAuthHeader = "x-api-key"
+	streaming := true
+	ep.MarkCapabilities(&streaming, nil, "max_completion_tokens")
+
+	snapshot := ep.GetLearningSnapshot()
+	if len(snapshot.UnsupportedParams) != 1 || snapshot.UnsupportedParams[0] != "tool_choice" {
+		t.Errorf("unexpected unsupported params: %v", snapshot.UnsupportedParams)
+	}
+	if snapshot.CountTokensSupport == nil || *snapshot.CountTokensSupport {
+		t.Errorf("expected count_tokens support to be learned as false")
+	}
+	if snapshot.DetectedAuthHeader != "x-api-key" {
+		t.Errorf("unexpected detected auth header: %q", snapshot.DetectedAuthHeader)
+	}
+	if snapshot.StreamingSupport == nil || !*snapshot.StreamingSupport || snapshot.VisionSupport != nil {
+		t.Errorf("unexpected probed capabilities: streaming=%v vision=%v", snapshot.StreamingSupport, snapshot.VisionSupport)
+	}
+	if snapshot.MaxTokensField != "max_completion_tokens" {
+		t.Errorf("unexpected max tokens field: %q", snapshot.MaxTokensField)
+	}
+
+	ep.ResetLearning()
+
+	snapshot = ep.GetLearningSnapshot()
+	if len(snapshot.UnsupportedParams) != 0 {
+		t.Errorf("expected unsupported params to be cleared, got %v", snapshot.UnsupportedParams)
+	}
+	if snapshot.CountTokensSupport != nil {
+		t.Errorf("expected count_tokens support to be unknown after reset")
+	}
+	if snapshot.DetectedAuthHeader != "" {
+		t.Errorf("expected detected auth header to be cleared")
+	}
+	if snapshot.StreamingSupport != nil || snapshot.MaxTokensField != "" {
+		t.Errorf("expected probed capabilities to be cleared")
+	}
+	if snapshot.NativeCodexFormat == nil || !*snapshot.NativeCodexFormat {
+		t.Errorf("expected explicit supports_responses to survive reset")
+	}
+}
+
+// TestLearnUnsupportedParamsFromError 测试从上游参数错误中学习不支持的参数
+func TestLearnUnsupportedParamsFromError(t *testing.T) {
+	ep := NewEndpoint(config.EndpointConfig{Name: "learning", URLOpenAI: "https://api.example.com"})
+	request := []byte(`{"model":"m","top_k":5,"tools":[{"name":"a"}],"messages":[]}`)
+
+	learned := ep.LearnUnsupportedParamsFromError([]byte(`{"error":{"message":"unsupported parameter top_k"}}`), request)
+	if len(learned) != 1 || learned[0] != "top_k" {
+		t.Errorf("expected top_k to be learned, got %v", learned)
+	}
+	learned = ep.LearnUnsupportedParamsFromError([]byte(`{"message":"tool calling is not available for this model"}`), request)
+	if len(learned) != 1 || learned[0] != "tools" {
+		t.Errorf("expected only the tool params present in the request, got %v", learned)
+	}
+	if ep.LearnUnsupportedParamsFromError([]byte(`not json`), request) != nil {
+		t.Error("expected non-JSON errors to be ignored")
+	}
+
+	if params := ep.GetLearnedUnsupportedParams(); len(params) != 2 {
+		t.Errorf("expected top_k and tools to be learned, got %v", params)
+	}
+}
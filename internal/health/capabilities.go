@@ -0,0 +1,344 @@
+package health
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"claude-code-codex-companion/internal/config"
+	"claude-code-codex-companion/internal/endpoint"
+)
+
+// 能力矩阵中的能力名称
+const (
+	CapabilityAuth           = "auth"
+	CapabilityMaxTokensField = "max_tokens_field"
+	CapabilityStreaming      = "streaming"
+	CapabilityTools          = "tools"
+	CapabilityVision         = "vision"
+	CapabilityResponses      = "responses"
+)
+
+// probeMaxTokens 探测请求使用的输出上限，尽量减少消耗
+const probeMaxTokens = 16
+
+// probeResponseLimit 探测响应最多读取的字节数
+const probeResponseLimit = 64 * 1024
+
+// probePixelPNG 1x1 PNG，用于图片输入探测
+const probePixelPNG = "iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAQAAAC1HAwCAAAAC0lEQVR42mNkYAAAAAYAAjCB0C8AAAAASUVORK5CYII="
+
+// CapabilityResult 单项能力的探测结果
+type CapabilityResult struct {
+	Capability string `json:"capability"`
+	Supported  *bool  `json:"supported"` // nil = 无法判断（网络错误、限流、服务端错误或认证未通过）
+	StatusCode int    `json:"status_code"`
+	Detail     string `json:"detail,omitempty"`
+}
+
+// CapabilityReport 端点能力探测结果
+type CapabilityReport struct {
+	Format         string             `json:"format"` // 探测使用的请求格式："anthropic" | "openai"
+	Model          string             `json:"model"`
+	AuthHeader     string             `json:"auth_header"`      // 可用的认证头："x-api-key" | "Authorization"，空字符串表示未找到
+	MaxTokensField string             `json:"max_tokens_field"` // 可用的 max_tokens 参数名，空字符串表示未能判断
+	Results        []CapabilityResult `json:"results"`
+}
+
+// Result 返回指定能力的探测结果，未探测时返回 nil
+func (r *CapabilityReport) Result(capability string) *CapabilityResult {
+	for i := range r.Results {
+		if r.Results[i].Capability == capability {
+			return &r.Results[i]
+		}
+	}
+	return nil
+}
+
+// capabilityProber 持有单次能力探测的上下文
+type capabilityProber struct {
+	client *http.Client
+	ep     *endpoint.Endpoint
+	format string
+	model  string
+}
+
+// ProbeCapabilities 发送少量针对性的小请求，探测端点是否支持流式、工具调用、/responses、图片输入，
+// 以及可用的 max_tokens 参数名和认证头。探测按端点的主格式进行（优先 Anthropic URL）
+func (c *Checker) ProbeCapabilities(ep *endpoint.Endpoint) (*CapabilityReport, error) {
+	format := ""
+	switch {
+	case ep.HasURLForFormat("anthropic"):
+		format = "anthropic"
+	case ep.HasURLForFormat("openai"):
+		format = "openai"
+	default:
+		return nil, fmt.Errorf("endpoint %s has no anthropic or openai url to probe", ep.Name)
+	}
+
+	client, err := ep.CreateHealthClient(c.healthTimeouts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create health client for endpoint: %v", err)
+	}
+
+	p := &capabilityProber{
+		client: client,
+		ep:     ep,
+		format: format,
+		model:  c.selectHealthCheckModel(ep, c.defaultModel),
+	}
+	report := &CapabilityReport{Format: format, Model: p.model}
+
+	// 认证：先尝试按 auth_type 推断的认证头，401/403 时换另一种
+	authResult, basicStatus, basicBody := p.probeAuth(report)
+	report.Results = append(report.Results, authResult)
+	if report.AuthHeader == "" {
+		for _, capability := range []string{CapabilityMaxTokensField, CapabilityStreaming, CapabilityTools, CapabilityVision, CapabilityResponses} {
+			report.Results = append(report.Results, CapabilityResult{Capability: capability, Detail: "skipped: no working auth header"})
+		}
+		return report, nil
+	}
+
+	report.Results = append(report.Results, p.probeMaxTokensField(report, basicStatus, basicBody))
+	maxTokensField := report.MaxTokensField
+	if maxTokensField == "" {
+		maxTokensField = "max_tokens"
+	}
+
+	streamPayload := p.chatPayload(maxTokensField, "Say hi")
+	streamPayload["stream"] = true
+	report.Results = append(report.Results, p.probeChat(report.AuthHeader, CapabilityStreaming, streamPayload, func(body []byte) bool {
+		return bytes.Contains(body, []byte("data:"))
+	}))
+
+	toolsPayload := p.chatPayload(maxTokensField, "What time is it?")
+	toolsPayload["tools"] = p.toolDefinitions()
+	report.Results = append(report.Results, p.probeChat(report.AuthHeader, CapabilityTools, toolsPayload, nil))
+
+	report.Results = append(report.Results, p.probeChat(report.AuthHeader, CapabilityVision, p.chatPayload(maxTokensField, p.imageContent()), nil))
+
+	report.Results = append(report.Results, p.probeResponses(report.AuthHeader))
+	return report, nil
+}
+
+// probeAuth 用最基础的请求探测可用认证头，同时返回该请求的状态码与响应体供 max_tokens 探测复用
+func (p *capabilityProber) probeAuth(report *CapabilityReport) (CapabilityResult, int, []byte) {
+	candidates := []string{"Authorization", "x-api-key"}
+	if strings.EqualFold(p.ep.AuthType, "api_key") {
+		candidates = []string{"x-api-key", "Authorization"}
+	}
+
+	result := CapabilityResult{Capability: CapabilityAuth}
+	for _, header := range candidates {
+		status, body, err := p.send(p.chatURL(), p.chatPayload("max_tokens", "Say hi"), header)
+		result.StatusCode = status
+		if err != nil {
+			result.Detail = err.Error()
+			return result, status, body
+		}
+		if status == http.StatusUnauthorized || status == http.StatusForbidden {
+			result.Detail = fmt.Sprintf("%s rejected with status %d", header, status)
+			continue
+		}
+		report.AuthHeader = header
+		result.Supported = boolPtr(true)
+		result.Detail = header
+		return result, status, body
+	}
+
+	result.Supported = boolPtr(false)
+	return result, result.StatusCode, nil
+}
+
+// probeMaxTokensField Anthropic 固定为 max_tokens；OpenAI 兼容端点在 max_tokens 被拒绝且提示 max_completion_tokens 时改用后者
+func (p *capabilityProber) probeMaxTokensField(report *CapabilityReport, basicStatus int, basicBody []byte) CapabilityResult {
+	result := CapabilityResult{Capability: CapabilityMaxTokensField, StatusCode: basicStatus}
+	if isSuccessStatus(basicStatus) {
+		report.MaxTokensField = "max_tokens"
+		result.Supported = boolPtr(true)
+		result.Detail = report.MaxTokensField
+		return result
+	}
+	if p.format != "openai" || basicStatus != http.StatusBadRequest || !bytes.Contains(basicBody, []byte("max_completion_tokens")) {
+		result.Detail = fmt.Sprintf("undetermined: basic request returned status %d", basicStatus)
+		return result
+	}
+
+	status, _, err := p.send(p.chatURL(), p.chatPayload("max_completion_tokens", "Say hi"), report.AuthHeader)
+	result.StatusCode = status
+	if err != nil {
+		result.Detail = err.Error()
+		return result
+	}
+	if !isSuccessStatus(status) {
+		result.Detail = fmt.Sprintf("undetermined: max_completion_tokens returned status %d", status)
+		return result
+	}
+	report.MaxTokensField = "max_completion_tokens"
+	result.Supported = boolPtr(true)
+	result.Detail = report.MaxTokensField
+	return result
+}
+
+// probeChat 发送一次对话请求；accept 非空时还需响应体满足条件才算支持
+func (p *capabilityProber) probeChat(authHeader, capability string, payload map[string]interface{}, accept func([]byte) bool) CapabilityResult {
+	status, body, err := p.send(p.chatURL(), payload, authHeader)
+	result := CapabilityResult{Capability: capability, StatusCode: status}
+	if err != nil {
+		result.Detail = err.Error()
+		return result
+	}
+	result.Supported = classifyProbeStatus(status)
+	if result.Supported != nil && *result.Supported && accept != nil && !accept(body) {
+		result.Supported = boolPtr(false)
+		result.Detail = "request accepted but response did not match the expected shape"
+	}
+	return result
+}
+
+// probeResponses 探测 OpenAI /responses 接口，没有 OpenAI URL 的端点视为不支持
+func (p *capabilityProber) probeResponses(authHeader string) CapabilityResult {
+	result := CapabilityResult{Capability: CapabilityResponses}
+	if !p.ep.HasURLForFormat("openai") {
+		result.Supported = boolPtr(false)
+		result.Detail = "endpoint has no openai url"
+		return result
+	}
+
+	payload := map[string]interface{}{
+		"model":             p.model,
+		"input":             "Say hi",
+		"max_output_tokens": probeMaxTokens,
+	}
+	status, _, err := p.send(p.ep.GetFullURLWithFormat("/responses", "openai"), payload, authHeader)
+	result.StatusCode = status
+	if err != nil {
+		result.Detail = err.Error()
+		return result
+	}
+	result.Supported = classifyProbeStatus(status)
+	return result
+}
+
+func (p *capabilityProber) chatURL() string {
+	if p.format == "openai" {
+		return p.ep.GetFullURLWithFormat("/chat/completions", "openai")
+	}
+	return p.ep.GetFullURLWithFormat("/v1/messages", "anthropic")
+}
+
+func (p *capabilityProber) chatPayload(maxTokensField string, content interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"model":        p.model,
+		maxTokensField: probeMaxTokens,
+		"messages": []map[string]interface{}{{
+			"role":    "user",
+			"content": content,
+		}},
+	}
+}
+
+func (p *capabilityProber) toolDefinitions() []map[string]interface{} {
+	schema := map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+	if p.format == "openai" {
+		return []map[string]interface{}{{
+			"type": "function",
+			"function": map[string]interface{}{
+				"name":        "get_time",
+				"description": "Returns the current time",
+				"parameters":  schema,
+			},
+		}}
+	}
+	return []map[string]interface{}{{
+		"name":         "get_time",
+		"description":  "Returns the current time",
+		"input_schema": schema,
+	}}
+}
+
+func (p *capabilityProber) imageContent() []map[string]interface{} {
+	image := map[string]interface{}{
+		"type": "image",
+		"source": map[string]interface{}{
+			"type":       "base64",
+			"media_type": "image/png",
+			"data":       probePixelPNG,
+		},
+	}
+	if p.format == "openai" {
+		image = map[string]interface{}{
+			"type":      "image_url",
+			"image_url": map[string]interface{}{"url": "data:image/png;base64," + probePixelPNG},
+		}
+	}
+	return []map[string]interface{}{image, {"type": "text", "text": "What is in this image?"}}
+}
+
+// send 发送探测请求，返回状态码与（截断后的）响应体
+func (p *capabilityProber) send(targetURL string, payload map[string]interface{}, authHeader string) (int, []byte, error) {
+	requestBody, err := json.Marshal(payload)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to marshal probe request: %v", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, targetURL, bytes.NewReader(requestBody))
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to create probe request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	if p.format == "anthropic" {
+		req.Header.Set("anthropic-version", config.ResolveAnthropicVersion(p.ep.AnthropicVersion, ""))
+	}
+
+	if authHeader == "x-api-key" {
+		req.Header.Set("x-api-key", p.ep.AuthValue)
+	} else if strings.EqualFold(p.ep.AuthType, "api_key") {
+		req.Header.Set("Authorization", "Bearer "+p.ep.AuthValue)
+	} else {
+		value, err := p.ep.GetAuthHeader()
+		if err != nil {
+			return 0, nil, fmt.Errorf("failed to get auth header: %v", err)
+		}
+		req.Header.Set("Authorization", value)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return 0, nil, fmt.Errorf("probe request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, probeResponseLimit))
+	if err != nil {
+		return resp.StatusCode, body, fmt.Errorf("failed to read probe response: %v", err)
+	}
+	return resp.StatusCode, body, nil
+}
+
+// classifyProbeStatus 2xx 视为支持，其余 4xx 视为不支持；认证、超时、限流与服务端错误无法判断
+func classifyProbeStatus(status int) *bool {
+	switch {
+	case isSuccessStatus(status):
+		return boolPtr(true)
+	case status == http.StatusUnauthorized, status == http.StatusForbidden,
+		status == http.StatusRequestTimeout, status == http.StatusTooManyRequests:
+		return nil
+	case status >= 400 && status < 500:
+		return boolPtr(false)
+	default:
+		return nil
+	}
+}
+
+func isSuccessStatus(status int) bool {
+	return status >= 200 && status < 300
+}
+
+func boolPtr(value bool) *bool {
+	return &value
+}
@@ -0,0 +1,109 @@
+package health
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"claude-code-codex-companion/internal/config"
+	"claude-code-codex-companion/internal/endpoint"
+)
+
+// TestProbeCapabilitiesOpenAI 模拟只接受 Bearer、要求 max_completion_tokens、不支持工具与 /responses 的 OpenAI 兼容端点
+func TestProbeCapabilitiesOpenAI(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if strings.HasSuffix(r.URL.Path, "/responses") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		var req map[string]interface{}
+		json.Unmarshal(body, &req)
+		switch {
+		case req["max_tokens"] != nil:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":{"message":"Unsupported parameter: 'max_tokens'. Use 'max_completion_tokens' instead."}}`))
+		case req["tools"] != nil:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":{"message":"tools are not supported"}}`))
+		case req["stream"] == true:
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Write([]byte("data: {\"choices\":[{\"delta\":{\"content\":\"hi\"}}]}\n\ndata: [DONE]\n\n"))
+		default:
+			w.Write([]byte(`{"choices":[{"message":{"content":"hi"}}]}`))
+		}
+	}))
+	defer server.Close()
+
+	ep := endpoint.NewEndpoint(config.EndpointConfig{
+		Name:      "probe",
+		URLOpenAI: server.URL,
+		AuthType:  "api_key",
+		AuthValue: "secret",
+	})
+	checker := NewChecker(config.HealthCheckTimeoutConfig{}, nil, "gpt-test")
+
+	report, err := checker.ProbeCapabilities(ep)
+	if err != nil {
+		t.Fatalf("ProbeCapabilities failed: %v", err)
+	}
+	if report.Format != "openai" || report.Model != "gpt-test" {
+		t.Fatalf("unexpected format/model: %s/%s", report.Format, report.Model)
+	}
+	if report.AuthHeader != "Authorization" {
+		t.Errorf("expected Authorization to be detected after x-api-key was rejected, got %q", report.AuthHeader)
+	}
+	if report.MaxTokensField != "max_completion_tokens" {
+		t.Errorf("expected max_completion_tokens, got %q", report.MaxTokensField)
+	}
+
+	expected := map[string]bool{
+		CapabilityAuth:      true,
+		CapabilityStreaming: true,
+		CapabilityTools:     false,
+		CapabilityVision:    true,
+		CapabilityResponses: false,
+	}
+	for capability, supported := range expected {
+		result := report.Result(capability)
+		if result == nil || result.Supported == nil || *result.Supported != supported {
+			t.Errorf("%s: expected supported=%v, got %+v", capability, supported, result)
+		}
+	}
+}
+
+// TestProbeCapabilitiesAuthFailure 所有认证头都被拒绝时其余能力标记为无法判断
+func TestProbeCapabilitiesAuthFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	ep := endpoint.NewEndpoint(config.EndpointConfig{
+		Name:         "denied",
+		URLAnthropic: server.URL,
+		AuthType:     "api_key",
+		AuthValue:    "wrong",
+	})
+	report, err := NewChecker(config.HealthCheckTimeoutConfig{}, nil, "claude-test").ProbeCapabilities(ep)
+	if err != nil {
+		t.Fatalf("ProbeCapabilities failed: %v", err)
+	}
+	if report.AuthHeader != "" {
+		t.Fatalf("expected no working auth header, got %q", report.AuthHeader)
+	}
+	if auth := report.Result(CapabilityAuth); auth == nil || auth.Supported == nil || *auth.Supported {
+		t.Fatalf("expected auth to be reported unsupported, got %+v", auth)
+	}
+	if tools := report.Result(CapabilityTools); tools == nil || tools.Supported != nil {
+		t.Fatalf("expected tools to be undetermined, got %+v", tools)
+	}
+}
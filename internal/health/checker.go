@@ -7,6 +7,7 @@ import (
 	"io"
 	"net/http"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"claude-code-codex-companion/internal/config"
@@ -20,6 +21,7 @@ type Checker struct {
 	healthTimeouts config.HealthCheckTimeoutConfig
 	modelRewriter  *modelrewrite.Rewriter
 	defaultModel   string
+	probeFormats   []string // 需要探测的请求格式（"anthropic"|"openai"），为空表示按端点类型单格式检查
 }
 
 type HealthCheckResult struct {
@@ -34,6 +36,13 @@ type HealthCheckResult struct {
 	Model           string
 }
 
+// FormatProbeResult 单个请求格式的探测结果
+type FormatProbeResult struct {
+	Format string
+	Result *HealthCheckResult
+	Err    error
+}
+
 func NewChecker(healthTimeouts config.HealthCheckTimeoutConfig, modelRewriter *modelrewrite.Rewriter, defaultModel string) *Checker {
 	return &Checker{
 		extractor:      NewRequestExtractor(),
@@ -47,7 +56,60 @@ func (c *Checker) GetExtractor() *RequestExtractor {
 	return c.extractor
 }
 
+// SetProbeFormats 设置多格式探测时需要通过的请求格式
+func (c *Checker) SetProbeFormats(formats []string) {
+	normalized := make([]string, 0, len(formats))
+	for _, format := range formats {
+		format = strings.ToLower(strings.TrimSpace(format))
+		if format == "anthropic" || format == "openai" {
+			normalized = append(normalized, format)
+		}
+	}
+	c.probeFormats = normalized
+}
+
+// ProbeFormatsFor 返回端点需要探测的请求格式：仅保留端点有URL的已配置格式；未配置格式时返回空，沿用单格式检查
+func (c *Checker) ProbeFormatsFor(ep *endpoint.Endpoint) []string {
+	formats := make([]string, 0, len(c.probeFormats))
+	for _, format := range c.probeFormats {
+		if ep.HasURLForFormat(format) {
+			formats = append(formats, format)
+		}
+	}
+	return formats
+}
+
+// CheckEndpointFormats 分别以配置的 Anthropic / OpenAI 格式探测端点，所有需要的格式均通过才视为健康；
+// 未配置 probe 格式（或端点没有对应URL）时按端点类型做一次单格式检查
+func (c *Checker) CheckEndpointFormats(ep *endpoint.Endpoint) ([]FormatProbeResult, error) {
+	formats := c.ProbeFormatsFor(ep)
+	if len(formats) == 0 {
+		result, err := c.CheckEndpointWithDetails(ep)
+		return []FormatProbeResult{{Format: "", Result: result, Err: err}}, err
+	}
+
+	probes := make([]FormatProbeResult, 0, len(formats))
+	var failures []string
+	for _, format := range formats {
+		result, err := c.checkWithFormat(ep, format)
+		probes = append(probes, FormatProbeResult{Format: format, Result: result, Err: err})
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", format, err))
+		}
+	}
+
+	if len(failures) > 0 {
+		return probes, fmt.Errorf("format probe failed (%s)", strings.Join(failures, "; "))
+	}
+	return probes, nil
+}
+
 func (c *Checker) CheckEndpointWithDetails(ep *endpoint.Endpoint) (*HealthCheckResult, error) {
+	return c.checkWithFormat(ep, "")
+}
+
+// checkWithFormat 执行一次健康检查请求；format 为空时沿用端点类型自动判断是否转换为 OpenAI 格式
+func (c *Checker) checkWithFormat(ep *endpoint.Endpoint, format string) (*HealthCheckResult, error) {
 	requestInfo := c.extractor.GetRequestInfo()
 
 	// 实现模型选择优先级链：测试模型 -> 重写模型1 -> 重写模型2 -> ... -> 默认模型
@@ -79,7 +141,7 @@ func (c *Checker) CheckEndpointWithDetails(ep *endpoint.Endpoint) (*HealthCheckR
 		return result, fmt.Errorf("failed to marshal health check request: %v", err)
 	}
 
-	targetURL := ep.GetFullURL("/v1/messages")
+	targetURL := ep.GetFullURLWithFormat("/v1/messages", format)
 	result.URL = targetURL
 
 	tempReq, err := http.NewRequest(http.MethodPost, targetURL, bytes.NewReader(requestBody))
@@ -111,11 +173,15 @@ func (c *Checker) CheckEndpointWithDetails(ep *endpoint.Endpoint) (*HealthCheckR
 	}
 
 	shouldConvert := ep.EndpointType == "openai" && ep.URLOpenAI != "" && ep.URLAnthropic == ""
+	if format != "" {
+		shouldConvert = format == "openai"
+	}
 	if shouldConvert {
 		reqConverter := conversion.NewRequestConverter(nil)
 		endpointInfo := &conversion.EndpointInfo{
-			Type:               ep.EndpointType,
+			Type:               "openai",
 			MaxTokensFieldName: ep.MaxTokensFieldName,
+			UserFieldMode:      ep.UserFieldMode,
 		}
 
 		convertedBody, _, err := reqConverter.Convert(finalRequestBody, endpointInfo)
@@ -124,7 +190,7 @@ func (c *Checker) CheckEndpointWithDetails(ep *endpoint.Endpoint) (*HealthCheckR
 		}
 		finalRequestBody = convertedBody
 		result.RequestBody = finalRequestBody
-		targetURL = ep.GetFullURL("/chat/completions")
+		targetURL = ep.GetFullURLWithFormat("/chat/completions", "openai")
 		result.URL = targetURL
 
 		// Update result.Model after format conversion as well
@@ -0,0 +1,121 @@
+package health
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"claude-code-codex-companion/internal/config"
+	"claude-code-codex-companion/internal/endpoint"
+	"claude-code-codex-companion/internal/logger"
+	"claude-code-codex-companion/internal/modelrewrite"
+)
+
+// newTestChecker 创建使用临时日志目录的健康检查器
+func newTestChecker(t *testing.T) *Checker {
+	t.Helper()
+	log, err := logger.NewLogger(logger.LogConfig{Level: "error", LogRequestTypes: "failed", LogDirectory: t.TempDir()})
+	if err != nil {
+		t.Fatalf("create logger: %v", err)
+	}
+	return NewChecker(config.HealthCheckTimeoutConfig{}, modelrewrite.NewRewriter(*log), "claude-test")
+}
+
+// newFormatServers 启动分别代表 Anthropic 与 OpenAI URL 的测试服务器，OpenAI 侧始终返回 500
+func newFormatServers(t *testing.T) (anthropic, openai *httptest.Server, openaiHits *int32) {
+	t.Helper()
+	var hits int32
+	anthropic = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"content":[{"type":"text","text":"hi"}]}`))
+	}))
+	openai = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error":{"message":"boom"}}`))
+	}))
+	t.Cleanup(anthropic.Close)
+	t.Cleanup(openai.Close)
+	return anthropic, openai, &hits
+}
+
+// TestCheckEndpointFormatsDefaultsToSingleFormat 未配置 probe 格式时只做一次单格式检查
+func TestCheckEndpointFormatsDefaultsToSingleFormat(t *testing.T) {
+	anthropic, openai, openaiHits := newFormatServers(t)
+	ep := endpoint.NewEndpoint(config.EndpointConfig{
+		Name:         "dual",
+		URLAnthropic: anthropic.URL,
+		URLOpenAI:    openai.URL,
+		AuthType:     "api_key",
+		AuthValue:    "secret",
+	})
+
+	probes, err := newTestChecker(t).CheckEndpointFormats(ep)
+	if err != nil {
+		t.Fatalf("expected the single-format check to pass, got %v", err)
+	}
+	if len(probes) != 1 || probes[0].Format != "" || probes[0].Result.StatusCode != http.StatusOK {
+		t.Fatalf("expected one unnamed probe, got %+v", probes)
+	}
+	if atomic.LoadInt32(openaiHits) != 0 {
+		t.Fatalf("expected the OpenAI URL not to be probed by default, got %d hits", atomic.LoadInt32(openaiHits))
+	}
+}
+
+// TestCheckEndpointFormatsAggregatesFailures 配置多个格式时全部通过才健康，失败信息按格式汇总
+func TestCheckEndpointFormatsAggregatesFailures(t *testing.T) {
+	anthropic, openai, openaiHits := newFormatServers(t)
+	ep := endpoint.NewEndpoint(config.EndpointConfig{
+		Name:         "dual",
+		URLAnthropic: anthropic.URL,
+		URLOpenAI:    openai.URL,
+		AuthType:     "api_key",
+		AuthValue:    "secret",
+	})
+
+	checker := newTestChecker(t)
+	checker.SetProbeFormats([]string{"anthropic", "openai"})
+	probes, err := checker.CheckEndpointFormats(ep)
+	if err == nil {
+		t.Fatal("expected the failing OpenAI probe to fail the check")
+	}
+	if len(probes) != 2 || probes[0].Format != "anthropic" || probes[0].Err != nil ||
+		probes[1].Format != "openai" || probes[1].Err == nil {
+		t.Fatalf("unexpected probe results: %+v", probes)
+	}
+	if !strings.HasPrefix(err.Error(), "format probe failed (openai: ") {
+		t.Fatalf("expected the error to name the failing format, got %q", err)
+	}
+	if atomic.LoadInt32(openaiHits) != 1 {
+		t.Fatalf("expected exactly one OpenAI probe, got %d", atomic.LoadInt32(openaiHits))
+	}
+
+	// 只配置 Anthropic 格式时不再探测 OpenAI URL
+	checker.SetProbeFormats([]string{"anthropic"})
+	if _, err := checker.CheckEndpointFormats(ep); err != nil {
+		t.Fatalf("expected the Anthropic-only check to pass, got %v", err)
+	}
+}
+
+// TestSetProbeFormats 格式名规范化，未知格式被忽略；端点缺少对应URL的格式不参与探测
+func TestSetProbeFormats(t *testing.T) {
+	checker := newTestChecker(t)
+	checker.SetProbeFormats([]string{" OpenAI ", "gemini", "anthropic", ""})
+
+	openaiOnly := endpoint.NewEndpoint(config.EndpointConfig{Name: "openai", URLOpenAI: "https://api.example.com"})
+	if formats := checker.ProbeFormatsFor(openaiOnly); len(formats) != 1 || formats[0] != "openai" {
+		t.Fatalf("expected only the openai format, got %v", formats)
+	}
+
+	dual := endpoint.NewEndpoint(config.EndpointConfig{Name: "dual", URLAnthropic: "https://a.example.com", URLOpenAI: "https://o.example.com"})
+	if formats := checker.ProbeFormatsFor(dual); len(formats) != 2 || formats[0] != "openai" || formats[1] != "anthropic" {
+		t.Fatalf("expected configured order to be kept, got %v", formats)
+	}
+
+	checker.SetProbeFormats(nil)
+	if formats := checker.ProbeFormatsFor(dual); len(formats) != 0 {
+		t.Fatalf("expected no formats when unconfigured, got %v", formats)
+	}
+}
@@ -0,0 +1,253 @@
+// Package jsonpath 实现用于请求体改写的 JSONPath 子集：
+// $.a.b、a.b、a[0]、a[-1]（倒数）、a[*] / a.*（通配）以及 ['key.with.dot'] 形式的键名。
+package jsonpath
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// segment 路径中的一级：对象键、数组下标或通配符
+type segment struct {
+	key      string
+	index    int
+	isIndex  bool
+	wildcard bool
+}
+
+// Path 编译后的路径表达式
+type Path struct {
+	expr     string
+	segments []segment
+}
+
+// String 返回原始表达式
+func (p *Path) String() string {
+	return p.expr
+}
+
+// Compile 解析路径表达式，语法错误或空路径时返回错误
+func Compile(expr string) (*Path, error) {
+	raw := strings.TrimSpace(expr)
+	rest := raw
+	if strings.HasPrefix(rest, "$") {
+		rest = rest[1:]
+	} else if rest != "" && rest[0] != '.' && rest[0] != '[' {
+		// 允许省略开头的 $.
+		rest = "." + rest
+	}
+
+	var segments []segment
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			name := rest[:end]
+			if name == "" {
+				return nil, fmt.Errorf("invalid path %q: empty key", expr)
+			}
+			if name == "*" {
+				segments = append(segments, segment{wildcard: true})
+			} else {
+				segments = append(segments, segment{key: name})
+			}
+			rest = rest[end:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid path %q: missing ]", expr)
+			}
+			inner := strings.TrimSpace(rest[1:end])
+			rest = rest[end+1:]
+			switch {
+			case inner == "*":
+				segments = append(segments, segment{wildcard: true})
+			case len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0]:
+				segments = append(segments, segment{key: inner[1 : len(inner)-1]})
+			default:
+				index, err := strconv.Atoi(inner)
+				if err != nil {
+					return nil, fmt.Errorf("invalid path %q: bad index %q", expr, inner)
+				}
+				segments = append(segments, segment{index: index, isIndex: true})
+			}
+		default:
+			return nil, fmt.Errorf("invalid path %q: unexpected %q", expr, rest[0])
+		}
+	}
+
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("invalid path %q: path must select a field", expr)
+	}
+	return &Path{expr: raw, segments: segments}, nil
+}
+
+// Set 将所有匹配位置设置为 value，缺失的中间对象会被创建（数组不会自动扩展）
+// 返回新的根节点与设置的位置数
+func (p *Path) Set(root interface{}, value interface{}) (interface{}, int) {
+	return set(root, p.segments, value)
+}
+
+// Delete 删除所有匹配的对象键或数组元素，返回新的根节点与删除的数量
+func (p *Path) Delete(root interface{}) (interface{}, int) {
+	return del(root, p.segments)
+}
+
+func set(node interface{}, segments []segment, value interface{}) (interface{}, int) {
+	seg := segments[0]
+	last := len(segments) == 1
+
+	switch n := node.(type) {
+	case map[string]interface{}:
+		if seg.isIndex {
+			return node, 0
+		}
+		if seg.wildcard {
+			count := 0
+			for key, child := range n {
+				if last {
+					n[key] = deepCopy(value)
+					count++
+					continue
+				}
+				updated, c := set(child, segments[1:], value)
+				n[key] = updated
+				count += c
+			}
+			return n, count
+		}
+		if last {
+			n[seg.key] = deepCopy(value)
+			return n, 1
+		}
+		child, exists := n[seg.key]
+		if !exists || child == nil {
+			next := segments[1]
+			if next.isIndex || next.wildcard {
+				return n, 0
+			}
+			child = map[string]interface{}{}
+		}
+		updated, count := set(child, segments[1:], value)
+		if count > 0 {
+			n[seg.key] = updated
+		}
+		return n, count
+	case []interface{}:
+		if !seg.isIndex && !seg.wildcard {
+			return node, 0
+		}
+		count := 0
+		for _, i := range matchIndexes(len(n), seg) {
+			if last {
+				n[i] = deepCopy(value)
+				count++
+				continue
+			}
+			updated, c := set(n[i], segments[1:], value)
+			n[i] = updated
+			count += c
+		}
+		return n, count
+	}
+	return node, 0
+}
+
+func del(node interface{}, segments []segment) (interface{}, int) {
+	seg := segments[0]
+	last := len(segments) == 1
+
+	switch n := node.(type) {
+	case map[string]interface{}:
+		if seg.isIndex {
+			return node, 0
+		}
+		count := 0
+		for key, child := range n {
+			if !seg.wildcard && key != seg.key {
+				continue
+			}
+			if last {
+				delete(n, key)
+				count++
+				continue
+			}
+			updated, c := del(child, segments[1:])
+			n[key] = updated
+			count += c
+		}
+		return n, count
+	case []interface{}:
+		if !seg.isIndex && !seg.wildcard {
+			return node, 0
+		}
+		indexes := matchIndexes(len(n), seg)
+		if !last {
+			count := 0
+			for _, i := range indexes {
+				updated, c := del(n[i], segments[1:])
+				n[i] = updated
+				count += c
+			}
+			return n, count
+		}
+		if len(indexes) == 0 {
+			return n, 0
+		}
+		remove := make(map[int]bool, len(indexes))
+		for _, i := range indexes {
+			remove[i] = true
+		}
+		kept := make([]interface{}, 0, len(n)-len(remove))
+		for i, item := range n {
+			if !remove[i] {
+				kept = append(kept, item)
+			}
+		}
+		return kept, len(remove)
+	}
+	return node, 0
+}
+
+// matchIndexes 返回数组中与片段匹配的下标，负数下标从末尾计数，越界时为空
+func matchIndexes(length int, seg segment) []int {
+	if seg.wildcard {
+		indexes := make([]int, length)
+		for i := range indexes {
+			indexes[i] = i
+		}
+		return indexes
+	}
+	index := seg.index
+	if index < 0 {
+		index += length
+	}
+	if index < 0 || index >= length {
+		return nil
+	}
+	return []int{index}
+}
+
+// deepCopy 复制 JSON 值，避免同一个值被写入多个位置后相互影响
+func deepCopy(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for key, child := range v {
+			out[key] = deepCopy(child)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, child := range v {
+			out[i] = deepCopy(child)
+		}
+		return out
+	}
+	return value
+}
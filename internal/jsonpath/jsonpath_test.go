@@ -0,0 +1,91 @@
+package jsonpath
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func decode(t *testing.T, raw string) interface{} {
+	t.Helper()
+	var doc interface{}
+	if err := json.Unmarshal([]byte(raw), &doc); err != nil {
+		t.Fatalf("invalid fixture: %v", err)
+	}
+	return doc
+}
+
+func encode(t *testing.T, doc interface{}) string {
+	t.Helper()
+	data, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	return string(data)
+}
+
+func TestCompile(t *testing.T) {
+	valid := []string{"$.messages[0].name", "messages[-1].content", "$.tools[*].function.strict", "$['odd.key'].x", "metadata.*"}
+	for _, expr := range valid {
+		if _, err := Compile(expr); err != nil {
+			t.Errorf("Compile(%q) failed: %v", expr, err)
+		}
+	}
+	invalid := []string{"", "$", "$.", "a..b", "a[0", "a[x]", "$x"}
+	for _, expr := range invalid {
+		if _, err := Compile(expr); err == nil {
+			t.Errorf("Compile(%q) should fail", expr)
+		}
+	}
+}
+
+func TestSet(t *testing.T) {
+	doc := decode(t, `{"messages":[{"role":"user","name":"a"},{"role":"user"}]}`)
+
+	path, _ := Compile("$.messages[*].role")
+	doc, count := path.Set(doc, "system")
+	if count != 2 {
+		t.Fatalf("expected 2 values set, got %d", count)
+	}
+
+	path, _ = Compile("$.extra.nested.flag")
+	doc, count = path.Set(doc, true)
+	if count != 1 {
+		t.Fatalf("expected missing objects to be created, got %d", count)
+	}
+
+	path, _ = Compile("$.messages[5].role")
+	if _, count = path.Set(doc, "x"); count != 0 {
+		t.Fatalf("expected out-of-range index to be skipped, got %d", count)
+	}
+
+	want := `{"extra":{"nested":{"flag":true}},"messages":[{"name":"a","role":"system"},{"role":"system"}]}`
+	if got := encode(t, doc); got != want {
+		t.Fatalf("unexpected document:\n got %s\nwant %s", got, want)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	doc := decode(t, `{"messages":[{"role":"user","name":"a"},{"role":"assistant","name":"b"},{"role":"user"}],"stream":true}`)
+
+	path, _ := Compile("messages[0].name")
+	doc, count := path.Delete(doc)
+	if count != 1 {
+		t.Fatalf("expected 1 deletion, got %d", count)
+	}
+
+	path, _ = Compile("$.messages[-2]")
+	doc, count = path.Delete(doc)
+	if count != 1 {
+		t.Fatalf("expected negative index deletion, got %d", count)
+	}
+
+	path, _ = Compile("$.missing.field")
+	if _, count = path.Delete(doc); count != 0 {
+		t.Fatalf("expected no deletion for missing path, got %d", count)
+	}
+
+	want := `{"messages":[{"role":"user"},{"role":"user"}],"stream":true}`
+	if got := encode(t, doc); got != want {
+		t.Fatalf("unexpected document:\n got %s\nwant %s", got, want)
+	}
+}
@@ -0,0 +1,217 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	AccessLogFormatJSON     = "json"
+	AccessLogFormatCombined = "combined"
+
+	defaultAccessLogMaxSizeMB  = 100
+	defaultAccessLogMaxBackups = 3
+)
+
+// AccessLogConfig 访问日志配置：destination 为 stdout、stderr 或文件路径（文件按大小轮转）
+type AccessLogConfig struct {
+	Format      string
+	Destination string
+	MaxSizeMB   int
+	MaxBackups  int
+}
+
+// AccessLogEntry 一次代理请求的访问日志字段
+type AccessLogEntry struct {
+	Time       time.Time
+	RemoteAddr string
+	Method     string
+	Path       string
+	Proto      string
+	Status     int
+	Bytes      int64
+	Duration   time.Duration
+	Endpoint   string
+	Model      string
+	UserAgent  string
+}
+
+// AccessLogger 将每个请求写成一行纯文本（combined）或 JSON，独立于数据库日志
+type AccessLogger struct {
+	mu     sync.Mutex
+	format string
+	out    io.Writer
+	closer io.Closer
+}
+
+// NewAccessLogger 按配置创建访问日志记录器
+func NewAccessLogger(cfg AccessLogConfig) (*AccessLogger, error) {
+	format := strings.ToLower(strings.TrimSpace(cfg.Format))
+	switch format {
+	case "":
+		format = AccessLogFormatCombined
+	case AccessLogFormatCombined, AccessLogFormatJSON:
+	default:
+		return nil, fmt.Errorf("unsupported access log format %q (expected combined or json)", cfg.Format)
+	}
+
+	l := &AccessLogger{format: format}
+	switch destination := strings.TrimSpace(cfg.Destination); strings.ToLower(destination) {
+	case "", "stdout":
+		l.out = os.Stdout
+	case "stderr":
+		l.out = os.Stderr
+	default:
+		file, err := newRotatingFile(destination, cfg.MaxSizeMB, cfg.MaxBackups)
+		if err != nil {
+			return nil, err
+		}
+		l.out = file
+		l.closer = file
+	}
+	return l, nil
+}
+
+// Log 写入一行访问日志，写入失败时静默丢弃，不影响代理请求
+func (l *AccessLogger) Log(entry AccessLogEntry) {
+	if l == nil {
+		return
+	}
+	line := FormatAccessLogLine(l.format, entry)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	io.WriteString(l.out, line+"\n")
+}
+
+// Close 关闭文件输出
+func (l *AccessLogger) Close() error {
+	if l == nil || l.closer == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.closer.Close()
+}
+
+// FormatAccessLogLine 生成单行访问日志；combined 格式在 Apache combined 基础上追加耗时、端点与模型
+func FormatAccessLogLine(format string, entry AccessLogEntry) string {
+	if format == AccessLogFormatJSON {
+		payload, err := json.Marshal(map[string]interface{}{
+			"time":        entry.Time.Format(time.RFC3339Nano),
+			"remote_addr": entry.RemoteAddr,
+			"method":      entry.Method,
+			"path":        entry.Path,
+			"proto":       entry.Proto,
+			"status":      entry.Status,
+			"bytes":       entry.Bytes,
+			"duration_ms": entry.Duration.Milliseconds(),
+			"endpoint":    entry.Endpoint,
+			"model":       entry.Model,
+			"user_agent":  entry.UserAgent,
+		})
+		if err == nil {
+			return string(payload)
+		}
+	}
+
+	return fmt.Sprintf(`%s - - [%s] "%s %s %s" %d %d "-" %q duration_ms=%d endpoint=%s model=%s`,
+		dashIfEmpty(entry.RemoteAddr),
+		entry.Time.Format("02/Jan/2006:15:04:05 -0700"),
+		entry.Method, entry.Path, dashIfEmpty(entry.Proto),
+		entry.Status, entry.Bytes,
+		dashIfEmpty(entry.UserAgent),
+		entry.Duration.Milliseconds(),
+		dashIfEmpty(entry.Endpoint), dashIfEmpty(entry.Model),
+	)
+}
+
+func dashIfEmpty(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+// rotatingFile 超过大小上限时将 path 轮转为 path.1 ... path.N
+type rotatingFile struct {
+	path       string
+	maxBytes   int64
+	maxBackups int
+	file       *os.File
+	size       int64
+}
+
+func newRotatingFile(path string, maxSizeMB, maxBackups int) (*rotatingFile, error) {
+	if maxSizeMB <= 0 {
+		maxSizeMB = defaultAccessLogMaxSizeMB
+	}
+	if maxBackups <= 0 {
+		maxBackups = defaultAccessLogMaxBackups
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("create access log directory: %w", err)
+	}
+	r := &rotatingFile{path: path, maxBytes: int64(maxSizeMB) * 1024 * 1024, maxBackups: maxBackups}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *rotatingFile) open() error {
+	file, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("open access log: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("stat access log: %w", err)
+	}
+	r.file = file
+	r.size = info.Size()
+	return nil
+}
+
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	if r.file == nil {
+		return 0, os.ErrClosed
+	}
+	if r.size > 0 && r.size+int64(len(p)) > r.maxBytes {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+func (r *rotatingFile) rotate() error {
+	r.file.Close()
+	r.file = nil
+	os.Remove(fmt.Sprintf("%s.%d", r.path, r.maxBackups))
+	for i := r.maxBackups - 1; i >= 1; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", r.path, i), fmt.Sprintf("%s.%d", r.path, i+1))
+	}
+	if err := os.Rename(r.path, r.path+".1"); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("rotate access log: %w", err)
+	}
+	return r.open()
+}
+
+func (r *rotatingFile) Close() error {
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
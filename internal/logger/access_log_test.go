@@ -0,0 +1,67 @@
+package logger
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatAccessLogLine(t *testing.T) {
+	entry := AccessLogEntry{
+		Time:       time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+		RemoteAddr: "127.0.0.1:5000",
+		Method:     "POST",
+		Path:       "/v1/messages",
+		Proto:      "HTTP/1.1",
+		Status:     200,
+		Bytes:      42,
+		Duration:   1500 * time.Millisecond,
+		Endpoint:   "primary",
+		Model:      "claude-sonnet-4",
+		UserAgent:  "claude-cli/1.0",
+	}
+
+	combined := FormatAccessLogLine(AccessLogFormatCombined, entry)
+	want := `127.0.0.1:5000 - - [02/Jan/2025:03:04:05 +0000] "POST /v1/messages HTTP/1.1" 200 42 "-" "claude-cli/1.0" duration_ms=1500 endpoint=primary model=claude-sonnet-4`
+	if combined != want {
+		t.Fatalf("unexpected combined line:\n got: %s\nwant: %s", combined, want)
+	}
+
+	var payload map[string]interface{}
+	if err := json.Unmarshal([]byte(FormatAccessLogLine(AccessLogFormatJSON, entry)), &payload); err != nil {
+		t.Fatalf("invalid JSON line: %v", err)
+	}
+	if payload["status"] != float64(200) || payload["duration_ms"] != float64(1500) || payload["endpoint"] != "primary" {
+		t.Fatalf("unexpected JSON line: %v", payload)
+	}
+
+	if _, err := NewAccessLogger(AccessLogConfig{Format: "xml"}); err == nil {
+		t.Fatal("expected unsupported format to be rejected")
+	}
+}
+
+func TestAccessLogFileRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+	accessLogger, err := NewAccessLogger(AccessLogConfig{Format: AccessLogFormatJSON, Destination: path, MaxSizeMB: 1, MaxBackups: 2})
+	if err != nil {
+		t.Fatalf("create access logger: %v", err)
+	}
+	defer accessLogger.Close()
+
+	// 每行约 1KB，写满 1MB 后应轮转
+	entry := AccessLogEntry{Time: time.Now(), Method: "POST", Path: "/v1/messages", Status: 200, UserAgent: strings.Repeat("a", 1000)}
+	for i := 0; i < 1200; i++ {
+		accessLogger.Log(entry)
+	}
+
+	if _, err := os.Stat(path + ".1"); err != nil {
+		t.Fatalf("expected rotated backup file: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.Size() > 1024*1024 {
+		t.Fatalf("expected active file under the size limit, got %v (%v)", info, err)
+	}
+}
@@ -0,0 +1,178 @@
+package logger
+
+import (
+	"sync"
+)
+
+const (
+	// DefaultAsyncLogBufferSize 异步写入队列的默认容量
+	DefaultAsyncLogBufferSize = 1000
+	// asyncLogBatchSize 后台协程每批写入的最大日志条数
+	asyncLogBatchSize = 100
+)
+
+// AsyncRequestWriter 异步批量写入请求日志：请求处理路径只入队，后台协程按批写入 request_logs。
+// 队列满时丢弃最旧的仅含元数据（无请求/响应体）的日志，没有时丢弃新日志，并计数，不阻塞请求处理。
+// 读取方在后台写入完成前可能看不到仍在队列中的日志，需要最新数据时先调用 Flush。
+type AsyncRequestWriter struct {
+	logger   *Logger
+	capacity int
+
+	mu      sync.Mutex
+	queue   []*RequestLog
+	dropped int64
+	closed  bool
+
+	notify  chan struct{}
+	flushCh chan chan struct{}
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// NewAsyncRequestWriter 创建异步写入器并启动后台写入协程；capacity <= 0 时使用默认容量
+func NewAsyncRequestWriter(l *Logger, capacity int) *AsyncRequestWriter {
+	if capacity <= 0 {
+		capacity = DefaultAsyncLogBufferSize
+	}
+	w := &AsyncRequestWriter{
+		logger:   l,
+		capacity: capacity,
+		notify:   make(chan struct{}, 1),
+		flushCh:  make(chan chan struct{}),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// Enqueue 将日志放入写入队列，立即返回；写入器关闭后退化为同步写入，避免丢失关闭期间的日志
+func (w *AsyncRequestWriter) Enqueue(log *RequestLog) {
+	if w == nil || log == nil {
+		return
+	}
+
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		w.logger.LogRequest(log)
+		return
+	}
+	if len(w.queue) >= w.capacity && !w.dropForLocked() {
+		w.mu.Unlock()
+		return
+	}
+	w.queue = append(w.queue, log)
+	w.mu.Unlock()
+
+	select {
+	case w.notify <- struct{}{}:
+	default:
+	}
+}
+
+// dropForLocked 队列已满时腾出位置：丢弃最旧的仅元数据日志；队列中没有仅元数据日志时丢弃新日志（返回 false），
+// 已入队的带请求/响应体的日志不会被挤掉
+func (w *AsyncRequestWriter) dropForLocked() bool {
+	w.dropped++
+	for i, queued := range w.queue {
+		if isMetadataOnly(queued) {
+			w.queue = append(w.queue[:i], w.queue[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// isMetadataOnly 日志不含任何请求/响应体（只有状态、耗时、模型等元数据）
+func isMetadataOnly(log *RequestLog) bool {
+	return log.RequestBody == "" && log.ResponseBody == "" &&
+		log.OriginalRequestBody == "" && log.OriginalResponseBody == "" &&
+		log.FinalRequestBody == "" && log.FinalResponseBody == ""
+}
+
+// Dropped 返回因队列已满被丢弃的日志条数
+func (w *AsyncRequestWriter) Dropped() int64 {
+	if w == nil {
+		return 0
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.dropped
+}
+
+// Pending 返回尚未写入的日志条数
+func (w *AsyncRequestWriter) Pending() int {
+	if w == nil {
+		return 0
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.queue)
+}
+
+// Flush 阻塞直到调用前已入队的日志全部写入
+func (w *AsyncRequestWriter) Flush() {
+	if w == nil {
+		return
+	}
+	reply := make(chan struct{})
+	select {
+	case w.flushCh <- reply:
+		<-reply
+	case <-w.done:
+	}
+}
+
+// Close 写完队列中剩余的日志后停止后台协程；重复调用安全
+func (w *AsyncRequestWriter) Close() {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	alreadyClosed := w.closed
+	w.closed = true
+	w.mu.Unlock()
+
+	if !alreadyClosed {
+		close(w.stop)
+	}
+	<-w.done
+}
+
+func (w *AsyncRequestWriter) run() {
+	defer close(w.done)
+	for {
+		select {
+		case <-w.notify:
+			w.drain()
+		case reply := <-w.flushCh:
+			w.drain()
+			close(reply)
+		case <-w.stop:
+			w.drain()
+			return
+		}
+	}
+}
+
+// drain 按批写出队列中的全部日志；写入期间新入队的日志会在下一批写出
+func (w *AsyncRequestWriter) drain() {
+	for {
+		w.mu.Lock()
+		n := len(w.queue)
+		if n == 0 {
+			w.mu.Unlock()
+			return
+		}
+		if n > asyncLogBatchSize {
+			n = asyncLogBatchSize
+		}
+		batch := make([]*RequestLog, n)
+		copy(batch, w.queue[:n])
+		w.queue = append(w.queue[:0:0], w.queue[n:]...)
+		w.mu.Unlock()
+
+		w.logger.LogRequests(batch)
+	}
+}
@@ -0,0 +1,110 @@
+package logger
+
+import (
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+// blockingStorage 第一批写入阻塞到 release 关闭，便于在写入期间填满队列
+type blockingStorage struct {
+	StorageInterface
+	started chan struct{}
+	release chan struct{}
+
+	once  sync.Once
+	mu    sync.Mutex
+	saved []string
+}
+
+func (s *blockingStorage) SaveLog(log *RequestLog) {
+	s.SaveLogs([]*RequestLog{log})
+}
+
+func (s *blockingStorage) SaveLogs(logs []*RequestLog) {
+	s.once.Do(func() {
+		close(s.started)
+		<-s.release
+	})
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, log := range logs {
+		s.saved = append(s.saved, log.RequestID)
+	}
+}
+
+func TestAsyncRequestWriterDropsOldestMetadataOnlyWhenFull(t *testing.T) {
+	console := logrus.New()
+	console.SetOutput(io.Discard)
+	storage := &blockingStorage{started: make(chan struct{}), release: make(chan struct{})}
+	writer := NewAsyncRequestWriter(&Logger{logger: console, storage: storage}, 2)
+
+	withBody := func(id string) *RequestLog {
+		return &RequestLog{RequestID: id, StatusCode: 200, ResponseBody: "{}"}
+	}
+	metadataOnly := func(id string) *RequestLog {
+		return &RequestLog{RequestID: id, StatusCode: 200}
+	}
+
+	writer.Enqueue(withBody("in-flight"))
+	<-storage.started
+
+	writer.Enqueue(withBody("body-1"))
+	writer.Enqueue(metadataOnly("meta-1"))
+	writer.Enqueue(withBody("body-2"))     // 队列已满：丢弃 meta-1
+	writer.Enqueue(metadataOnly("meta-2")) // 队列中只剩带体日志：丢弃新日志本身
+	writer.Enqueue(withBody("body-3"))     // 已入队的带体日志不被挤掉：同样丢弃新日志
+
+	if got := writer.Dropped(); got != 3 {
+		t.Fatalf("expected 3 dropped logs, got %d", got)
+	}
+	if got := writer.Pending(); got != 2 {
+		t.Fatalf("expected queue to stay bounded at 2, got %d", got)
+	}
+
+	close(storage.release)
+	writer.Close()
+
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+	want := []string{"in-flight", "body-1", "body-2"}
+	if len(storage.saved) != len(want) {
+		t.Fatalf("expected %v to be written, got %v", want, storage.saved)
+	}
+	for i := range want {
+		if storage.saved[i] != want[i] {
+			t.Fatalf("expected %v to be written, got %v", want, storage.saved)
+		}
+	}
+}
+
+func TestAsyncRequestWriterFlushesBatchesToStorage(t *testing.T) {
+	l, err := NewLogger(LogConfig{Level: "error", LogRequestTypes: "failed", LogDirectory: t.TempDir()})
+	if err != nil {
+		t.Fatalf("create logger: %v", err)
+	}
+	defer l.Close()
+
+	writer := NewAsyncRequestWriter(l, 0)
+	const total = 250
+	for i := 0; i < total; i++ {
+		writer.Enqueue(generateTestLog(i))
+	}
+	writer.Flush()
+
+	_, count, err := l.GetLogs(1, 0, false)
+	if err != nil {
+		t.Fatalf("GetLogs: %v", err)
+	}
+	if count != total {
+		t.Fatalf("expected %d logs after flush, got %d", total, count)
+	}
+
+	writer.Close()
+	writer.Enqueue(generateTestLog(total)) // 关闭后同步写入，不丢日志
+	if _, count, _ = l.GetLogs(1, 0, false); count != total+1 {
+		t.Fatalf("expected log enqueued after close to be written synchronously, got %d", count)
+	}
+}
@@ -0,0 +1,279 @@
+package logger
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// TestStreamLogsFilter 测试导出过滤条件
+func TestStreamLogsFilter(t *testing.T) {
+	storage, err := NewGORMStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer storage.Close()
+
+	now := time.Now()
+	entries := []struct {
+		age        time.Duration
+		status     int
+		clientType string
+		endpoint   string
+	}{
+		{48 * time.Hour, 200, "codex", "ep-a"},
+		{2 * time.Hour, 200, "codex", "ep-a"},
+		{time.Hour, 502, "codex", "ep-a"},
+		{time.Hour, 500, "claude-code", "ep-b"},
+	}
+	for i, entry := range entries {
+		log := generateTestLog(i)
+		log.Timestamp = now.Add(-entry.age)
+		log.StatusCode = entry.status
+		log.ClientType = entry.clientType
+		log.Endpoint = entry.endpoint
+		storage.SaveLog(log)
+	}
+
+	var matched []*RequestLog
+	err = storage.StreamLogs(LogExportFilter{
+		Since:       now.Add(-24 * time.Hour),
+		StatusRange: "5xx",
+		ClientType:  "codex",
+		Endpoint:    "ep-a",
+	}, func(log *RequestLog) error {
+		matched = append(matched, log)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("StreamLogs failed: %v", err)
+	}
+	if len(matched) != 1 || matched[0].StatusCode != 502 {
+		t.Fatalf("expected a single 502 log, got %d", len(matched))
+	}
+
+	count := 0
+	if err := storage.StreamLogs(LogExportFilter{Since: now.Add(-24 * time.Hour)}, func(*RequestLog) error {
+		count++
+		return nil
+	}); err != nil {
+		t.Fatalf("StreamLogs failed: %v", err)
+	}
+	if count != 3 {
+		t.Fatalf("expected 3 logs within time range, got %d", count)
+	}
+}
+
+// TestCountRequestsSince 测试请求计数：多次端点尝试按 request_id 去重，任一尝试成功即计为成功
+func TestCountRequestsSince(t *testing.T) {
+	storage, err := NewGORMStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer storage.Close()
+
+	now := time.Now()
+	entries := []struct {
+		requestID string
+		age       time.Duration
+		status    int
+	}{
+		{"req-old", 2 * time.Hour, 200},
+		{"req-retried", 10 * time.Minute, 502},
+		{"req-retried", 9 * time.Minute, 200},
+		{"req-failed", 5 * time.Minute, 500},
+		{"req-ok", time.Minute, 200},
+	}
+	for i, entry := range entries {
+		log := generateTestLog(i)
+		log.RequestID = entry.requestID
+		log.Timestamp = now.Add(-entry.age)
+		log.StatusCode = entry.status
+		log.Error = ""
+		storage.SaveLog(log)
+	}
+
+	counts, err := storage.CountRequestsSince(now.Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("CountRequestsSince failed: %v", err)
+	}
+	if counts.Total != 3 || counts.Succeeded != 2 {
+		t.Fatalf("expected 3 requests with 2 succeeded, got %+v", counts)
+	}
+}
+
+// TestDurationStatsSince 测试耗时均值与 P95：窗口外与耗时为 0 的记录不计入
+func TestDurationStatsSince(t *testing.T) {
+	storage, err := NewGORMStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer storage.Close()
+
+	now := time.Now()
+	if stats, err := storage.DurationStatsSince(now.Add(-time.Hour)); err != nil || stats.Count != 0 || stats.P95Ms != 0 {
+		t.Fatalf("expected empty stats, got %+v (%v)", stats, err)
+	}
+
+	for i := 1; i <= 20; i++ {
+		log := generateTestLog(i)
+		log.Timestamp = now.Add(-time.Duration(i) * time.Minute)
+		log.DurationMs = int64(i * 100)
+		storage.SaveLog(log)
+	}
+	old := generateTestLog(100)
+	old.Timestamp = now.Add(-2 * time.Hour)
+	old.DurationMs = 99999
+	storage.SaveLog(old)
+	unmeasured := generateTestLog(101)
+	unmeasured.DurationMs = 0
+	storage.SaveLog(unmeasured)
+
+	stats, err := storage.DurationStatsSince(now.Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("DurationStatsSince failed: %v", err)
+	}
+	if stats.Count != 20 || stats.AvgMs != 1050 || stats.P95Ms != 1900 {
+		t.Fatalf("expected 20 durations with avg 1050ms and p95 1900ms, got %+v", stats)
+	}
+}
+
+func TestSearchLogBodies(t *testing.T) {
+	storage, err := NewGORMStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer storage.Close()
+
+	now := time.Now()
+	bodies := []struct {
+		request, response string
+		status            int
+	}{
+		{`{"messages":[{"content":"refactor the parser"}]}`, `{"content":"ok"}`, 200},
+		{`{"messages":[{"content":"hello"}]}`, `{"content":"the Parser is done"}`, 500},
+		{`{"messages":[{"content":"100% done_now"}]}`, `{}`, 200},
+		{`{"messages":[{"content":"unrelated"}]}`, `{}`, 200},
+	}
+	for i, entry := range bodies {
+		log := generateTestLog(i)
+		log.RequestID = fmt.Sprintf("req-%d", i)
+		log.Timestamp = now.Add(time.Duration(i) * time.Second)
+		log.OriginalRequestBody = entry.request
+		log.FinalResponseBody = entry.response
+		log.StatusCode = entry.status
+		log.Error = ""
+		storage.SaveLog(log)
+	}
+
+	logs, total, err := storage.SearchLogBodies("parser", 1, 0, false)
+	if err != nil {
+		t.Fatalf("SearchLogBodies failed: %v", err)
+	}
+	if total != 2 || len(logs) != 1 || logs[0].RequestID != "req-1" {
+		t.Fatalf("expected 2 matches with the newest first on page 1, got total=%d logs=%v", total, logs)
+	}
+	if logs, _, _ := storage.SearchLogBodies("parser", 1, 1, false); len(logs) != 1 || logs[0].RequestID != "req-0" {
+		t.Fatalf("expected req-0 on page 2, got %v", logs)
+	}
+
+	if _, total, _ := storage.SearchLogBodies("parser", 10, 0, true); total != 1 {
+		t.Fatalf("expected failedOnly to narrow the total to 1, got %d", total)
+	}
+
+	// LIKE 通配符按字面匹配
+	if _, total, _ := storage.SearchLogBodies("0% done_", 10, 0, false); total != 1 {
+		t.Fatalf("expected literal wildcard match, got %d", total)
+	}
+	if _, total, _ := storage.SearchLogBodies("%", 10, 0, false); total != 1 {
+		t.Fatalf("expected %% to match only the body containing it, got %d", total)
+	}
+}
+
+func TestAuthMethodUsedRoundTrip(t *testing.T) {
+	storage, err := NewGORMStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer storage.Close()
+
+	log := generateTestLog(0)
+	log.RequestID = "req-auth"
+	log.AuthMethodUsed = "api_key"
+	storage.SaveLog(log)
+
+	logs, err := storage.GetAllLogsByRequestID("req-auth")
+	if err != nil {
+		t.Fatalf("GetAllLogsByRequestID failed: %v", err)
+	}
+	if len(logs) != 1 || logs[0].AuthMethodUsed != "api_key" {
+		t.Fatalf("expected auth_method_used to round-trip, got %+v", logs)
+	}
+}
+
+func TestAttemptsRoundTrip(t *testing.T) {
+	storage, err := NewGORMStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer storage.Close()
+
+	log := generateTestLog(0)
+	log.RequestID = "req-attempts"
+	log.Attempts = []RequestAttempt{
+		{Endpoint: "primary", AttemptNumber: 1, StatusCode: 502, Error: "bad gateway", ResponseBody: `{"error":"upstream down"}`},
+		{Endpoint: "backup", AttemptNumber: 2, StatusCode: 200},
+	}
+	storage.SaveLog(log)
+
+	logs, err := storage.GetAllLogsByRequestID("req-attempts")
+	if err != nil {
+		t.Fatalf("GetAllLogsByRequestID failed: %v", err)
+	}
+	if len(logs) != 1 || len(logs[0].Attempts) != 2 {
+		t.Fatalf("expected attempts to round-trip, got %+v", logs)
+	}
+	if logs[0].Attempts[0].ResponseBody != `{"error":"upstream down"}` || logs[0].Attempts[1].Endpoint != "backup" {
+		t.Fatalf("unexpected attempts after round-trip: %+v", logs[0].Attempts)
+	}
+}
+
+func TestQueryLogsFiltersBeforePagination(t *testing.T) {
+	storage, err := NewGORMStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	defer storage.Close()
+
+	now := time.Now()
+	for i := 0; i < 30; i++ {
+		log := generateTestLog(i)
+		log.Timestamp = now.Add(time.Duration(i) * time.Second)
+		log.ClientType = "codex"
+		log.IsStreaming = i%3 == 0
+		if i%10 == 0 {
+			log.ClientType = "claude_code"
+			log.Model = "claude-special"
+		}
+		storage.SaveLog(log)
+	}
+
+	// 匹配的记录分散在多页中：总数与分页都应基于过滤后的结果
+	logs, total, err := storage.QueryLogs(LogQueryFilter{ClientType: "claude_code"}, 2, 0)
+	if err != nil {
+		t.Fatalf("QueryLogs failed: %v", err)
+	}
+	if total != 3 || len(logs) != 2 || logs[0].RequestID != "test-req-20" {
+		t.Fatalf("expected 3 matches with the newest first, got total=%d logs=%v", total, logs)
+	}
+	if logs, _, _ := storage.QueryLogs(LogQueryFilter{ClientType: "claude_code"}, 2, 2); len(logs) != 1 || logs[0].RequestID != "test-req-0" {
+		t.Fatalf("expected the last match on page 2, got %v", logs)
+	}
+
+	if _, total, _ := storage.QueryLogs(LogQueryFilter{Search: "SPECIAL", StreamingOnly: true}, 20, 0); total != 1 {
+		t.Fatalf("expected search and streaming filters to combine to 1 match, got %d", total)
+	}
+	if _, total, _ := storage.QueryLogs(LogQueryFilter{StatusRange: "5xx"}, 20, 0); total != 0 {
+		t.Fatalf("expected no 5xx logs, got %d", total)
+	}
+}
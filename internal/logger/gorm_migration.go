@@ -6,44 +6,55 @@ import (
 	"strings"
 )
 
-// createOptimizedIndexes 创建基于现有查询模式的优化索引
-func createOptimizedIndexes(db *gorm.DB) error {
-	// 基于现有查询模式分析的索引优化策略
-	// 这些是对现有索引的补充优化，不会破坏现有结构
-	indexes := []string{
-		// 复合索引优化（基于 GetLogs 方法的查询模式）
-		"CREATE INDEX IF NOT EXISTS idx_request_logs_timestamp_status_opt ON request_logs(timestamp DESC, status_code)",
+// RequestLogIndex request_logs 表的一条优化索引定义
+type RequestLogIndex struct {
+	Name string
+	SQL  string
+}
 
-		// 支持分页查询的覆盖索引
-		"CREATE INDEX IF NOT EXISTS idx_request_logs_pagination_opt ON request_logs(timestamp DESC, id)",
+// requestLogIndexes 基于现有查询模式分析的索引优化策略
+// 这些是对现有索引的补充优化，不会破坏现有结构
+var requestLogIndexes = []RequestLogIndex{
+	// 复合索引优化（基于 GetLogs 方法的查询模式）
+	{"idx_request_logs_timestamp_status_opt", "CREATE INDEX IF NOT EXISTS idx_request_logs_timestamp_status_opt ON request_logs(timestamp DESC, status_code)"},
 
-		// 端点特定查询优化
-		"CREATE INDEX IF NOT EXISTS idx_request_logs_endpoint_time_opt ON request_logs(endpoint, timestamp DESC)",
+	// 支持分页查询的覆盖索引
+	{"idx_request_logs_pagination_opt", "CREATE INDEX IF NOT EXISTS idx_request_logs_pagination_opt ON request_logs(timestamp DESC, id)"},
 
-		// 请求ID查询优化（GetAllLogsByRequestID方法）
-		"CREATE INDEX IF NOT EXISTS idx_request_logs_request_id_time ON request_logs(request_id, timestamp ASC)",
+	// 端点特定查询优化
+	{"idx_request_logs_endpoint_time_opt", "CREATE INDEX IF NOT EXISTS idx_request_logs_endpoint_time_opt ON request_logs(endpoint, timestamp DESC)"},
 
-		// 基于模型的查询优化
-		"CREATE INDEX IF NOT EXISTS idx_request_logs_model_time ON request_logs(model, timestamp DESC)",
+	// 请求ID查询优化（GetAllLogsByRequestID方法）
+	{"idx_request_logs_request_id_time", "CREATE INDEX IF NOT EXISTS idx_request_logs_request_id_time ON request_logs(request_id, timestamp ASC)"},
 
-		// 失败日志查询优化
-		"CREATE INDEX IF NOT EXISTS idx_request_logs_status_code_time ON request_logs(status_code, timestamp DESC)",
+	// 基于模型的查询优化
+	{"idx_request_logs_model_time", "CREATE INDEX IF NOT EXISTS idx_request_logs_model_time ON request_logs(model, timestamp DESC)"},
 
-		// 错误字段索引
-		"CREATE INDEX IF NOT EXISTS idx_request_logs_error_time ON request_logs(timestamp DESC) WHERE error != ''",
+	// 失败日志查询优化
+	{"idx_request_logs_status_code_time", "CREATE INDEX IF NOT EXISTS idx_request_logs_status_code_time ON request_logs(status_code, timestamp DESC)"},
 
-		// 新增：客户端类型和格式转换查询优化
-		"CREATE INDEX IF NOT EXISTS idx_client_type ON request_logs(client_type)",
-		"CREATE INDEX IF NOT EXISTS idx_request_format ON request_logs(request_format)",
-		"CREATE INDEX IF NOT EXISTS idx_format_converted ON request_logs(format_converted)",
+	// 错误字段索引
+	{"idx_request_logs_error_time", "CREATE INDEX IF NOT EXISTS idx_request_logs_error_time ON request_logs(timestamp DESC) WHERE error != ''"},
 
-		// 新增：组合索引优化客户端分析查询
-		"CREATE INDEX IF NOT EXISTS idx_request_logs_client_time ON request_logs(client_type, timestamp DESC)",
-		"CREATE INDEX IF NOT EXISTS idx_request_logs_format_time ON request_logs(request_format, format_converted, timestamp DESC)",
-	}
+	// 新增：客户端类型和格式转换查询优化
+	{"idx_client_type", "CREATE INDEX IF NOT EXISTS idx_client_type ON request_logs(client_type)"},
+	{"idx_request_format", "CREATE INDEX IF NOT EXISTS idx_request_format ON request_logs(request_format)"},
+	{"idx_format_converted", "CREATE INDEX IF NOT EXISTS idx_format_converted ON request_logs(format_converted)"},
+
+	// 新增：组合索引优化客户端分析查询
+	{"idx_request_logs_client_time", "CREATE INDEX IF NOT EXISTS idx_request_logs_client_time ON request_logs(client_type, timestamp DESC)"},
+	{"idx_request_logs_format_time", "CREATE INDEX IF NOT EXISTS idx_request_logs_format_time ON request_logs(request_format, format_converted, timestamp DESC)"},
+}
+
+// RequestLogIndexes 返回日志库 request_logs 的优化索引定义（迁移与索引重建共用）
+func RequestLogIndexes() []RequestLogIndex {
+	return append([]RequestLogIndex(nil), requestLogIndexes...)
+}
 
-	for _, sql := range indexes {
-		if err := db.Exec(sql).Error; err != nil {
+// createOptimizedIndexes 创建基于现有查询模式的优化索引
+func createOptimizedIndexes(db *gorm.DB) error {
+	for _, index := range requestLogIndexes {
+		if err := db.Exec(index.SQL).Error; err != nil {
 			// 忽略已存在的索引错误，但记录其他错误
 			if !strings.Contains(err.Error(), "already exists") && !strings.Contains(err.Error(), "duplicate") {
 				return fmt.Errorf("failed to create index: %v", err)
@@ -94,6 +105,9 @@ func validateTableCompatibility(db *gorm.DB) error {
 		"was_streaming":                 "was_streaming BOOLEAN DEFAULT 0",
 		"conversion_path":               "conversion_path VARCHAR(100) DEFAULT ''",
 		"supports_responses_flag":       "supports_responses_flag VARCHAR(20) DEFAULT ''",
+		"service_tier":                  "service_tier VARCHAR(50) DEFAULT ''",
+		"auth_method_used":              "auth_method_used VARCHAR(20) DEFAULT ''",
+		"attempts":                      "attempts TEXT DEFAULT '[]'",
 	}
 
 	for column, definition := range optionalColumns {
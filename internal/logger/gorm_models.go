@@ -36,6 +36,9 @@ type GormRequestLog struct {
 	Tags                string `gorm:"column:tags;type:text;default:'[]'"` // JSON array
 	ContentTypeOverride string `gorm:"column:content_type_override;size:100;default:''"`
 	SessionID           string `gorm:"column:session_id;size:100;default:''"`
+	ServiceTier         string `gorm:"column:service_tier;size:50;default:''"`
+	AuthMethodUsed      string `gorm:"column:auth_method_used;size:20;default:''"`
+	Attempts            string `gorm:"column:attempts;type:text;default:'[]'"` // JSON array
 
 	// 模型重写字段
 	OriginalModel       string `gorm:"column:original_model;size:100;default:''"`
@@ -109,6 +112,8 @@ func ConvertToGormRequestLog(log *RequestLog) *GormRequestLog {
 		Error:                      log.Error,
 		ContentTypeOverride:        log.ContentTypeOverride,
 		SessionID:                  log.SessionID,
+		ServiceTier:                log.ServiceTier,
+		AuthMethodUsed:             log.AuthMethodUsed,
 		RequestBodyHash:            log.RequestBodyHash,
 		ResponseBodyHash:           log.ResponseBodyHash,
 		RequestBodyTruncated:       log.RequestBodyTruncated,
@@ -142,6 +147,7 @@ func ConvertToGormRequestLog(log *RequestLog) *GormRequestLog {
 	gormLog.RequestHeaders = marshalToJSON(log.RequestHeaders)
 	gormLog.ResponseHeaders = marshalToJSON(log.ResponseHeaders)
 	gormLog.Tags = marshalTagsToJSON(log.Tags)
+	gormLog.Attempts = marshalAttemptsToJSON(log.Attempts)
 	gormLog.OriginalRequestHeaders = marshalToJSON(log.OriginalRequestHeaders)
 	gormLog.OriginalResponseHeaders = marshalToJSON(log.OriginalResponseHeaders)
 	gormLog.FinalRequestHeaders = marshalToJSON(log.FinalRequestHeaders)
@@ -177,6 +183,8 @@ func ConvertFromGormRequestLog(gormLog *GormRequestLog) *RequestLog {
 		Error:                      gormLog.Error,
 		ContentTypeOverride:        gormLog.ContentTypeOverride,
 		SessionID:                  gormLog.SessionID,
+		ServiceTier:                gormLog.ServiceTier,
+		AuthMethodUsed:             gormLog.AuthMethodUsed,
 		OriginalModel:              gormLog.OriginalModel,
 		RewrittenModel:             gormLog.RewrittenModel,
 		ModelRewriteApplied:        gormLog.ModelRewriteApplied,
@@ -204,6 +212,7 @@ func ConvertFromGormRequestLog(gormLog *GormRequestLog) *RequestLog {
 	log.RequestHeaders = unmarshalFromJSON(gormLog.RequestHeaders)
 	log.ResponseHeaders = unmarshalFromJSON(gormLog.ResponseHeaders)
 	log.Tags = unmarshalTagsFromJSON(gormLog.Tags)
+	log.Attempts = unmarshalAttemptsFromJSON(gormLog.Attempts)
 	log.OriginalRequestHeaders = unmarshalFromJSON(gormLog.OriginalRequestHeaders)
 	log.OriginalResponseHeaders = unmarshalFromJSON(gormLog.OriginalResponseHeaders)
 	log.FinalRequestHeaders = unmarshalFromJSON(gormLog.FinalRequestHeaders)
@@ -256,3 +265,25 @@ func unmarshalTagsFromJSON(jsonStr string) []string {
 	}
 	return tags
 }
+
+func marshalAttemptsToJSON(attempts []RequestAttempt) string {
+	if len(attempts) == 0 {
+		return "[]"
+	}
+	data, err := json.Marshal(attempts)
+	if err != nil {
+		return "[]"
+	}
+	return string(data)
+}
+
+func unmarshalAttemptsFromJSON(jsonStr string) []RequestAttempt {
+	if jsonStr == "" || jsonStr == "[]" || jsonStr == "null" {
+		return nil
+	}
+	var attempts []RequestAttempt
+	if err := json.Unmarshal([]byte(jsonStr), &attempts); err != nil {
+		return nil
+	}
+	return attempts
+}
@@ -2,15 +2,17 @@ package logger
 
 import (
 	"fmt"
-	"gorm.io/driver/sqlite"
-	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
-	_ "modernc.org/sqlite"
+	"math"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
 
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	_ "modernc.org/sqlite"
+
 	appconfig "claude-code-codex-companion/internal/config"
 )
 
@@ -149,6 +151,46 @@ func (g *GORMStorage) SaveLog(log *RequestLog) {
 	}
 }
 
+// saveLogsInsertBatchSize 单条 INSERT 携带的日志行数，避免超过 SQLite 参数个数上限
+const saveLogsInsertBatchSize = 50
+
+// SaveLogs 批量保存日志条目（异步写入器使用）；批量写入失败时逐条回退，避免整批丢失
+func (g *GORMStorage) SaveLogs(logs []*RequestLog) {
+	if len(logs) == 0 {
+		return
+	}
+	gormLogs := make([]*GormRequestLog, 0, len(logs))
+	for _, log := range logs {
+		gormLogs = append(gormLogs, ConvertToGormRequestLog(log))
+	}
+
+	maxRetries := appconfig.Default.Database.MaxRetries * 2
+	baseDelay := 5 * time.Millisecond
+	for attempt := 0; attempt < maxRetries; attempt++ {
+		err := g.db.CreateInBatches(gormLogs, saveLogsInsertBatchSize).Error
+		if err == nil {
+			return
+		}
+		errStr := err.Error()
+		isBusyError := strings.Contains(errStr, "database is locked") ||
+			strings.Contains(errStr, "SQLITE_BUSY") ||
+			strings.Contains(errStr, "database table is locked")
+		if !isBusyError || attempt == maxRetries-1 {
+			fmt.Printf("Failed to batch save %d logs, falling back to single inserts: %v\n", len(logs), err)
+			break
+		}
+		delay := baseDelay * time.Duration(1<<uint(attempt))
+		if delay > 500*time.Millisecond {
+			delay = 500 * time.Millisecond
+		}
+		time.Sleep(delay)
+	}
+
+	for _, log := range logs {
+		g.SaveLog(log)
+	}
+}
+
 // GetLogs 获取日志列表，支持分页和过滤
 func (g *GORMStorage) GetLogs(limit, offset int, failedOnly bool) ([]*RequestLog, int, error) {
 	var gormLogs []GormRequestLog
@@ -185,6 +227,219 @@ func (g *GORMStorage) GetLogs(limit, offset int, failedOnly bool) ([]*RequestLog
 	return logs, int(total), nil
 }
 
+// LogQueryFilter 日志列表过滤条件（零值字段表示不限制），全部在数据库层应用，分页总数即过滤后的总数
+type LogQueryFilter struct {
+	Search        string // 匹配 request_id / endpoint / model / path
+	SearchBodies  bool   // Search 改为匹配原始请求体与最终响应体（LIKE 全表扫描，较慢）
+	FailedOnly    bool
+	StatusRange   string // 2xx / 4xx / 5xx / error
+	ClientType    string
+	StreamingOnly bool
+	RewrittenOnly bool // 只返回发生模型重写的请求
+	ThinkingOnly  bool
+}
+
+// QueryLogs 按过滤条件查询日志，返回分页结果与匹配总数。
+// 正文搜索只检索落库后的内容：未被 body_sample_rate 抽中的请求不保存请求/响应体，脱敏后的头部也不参与匹配
+func (g *GORMStorage) QueryLogs(filter LogQueryFilter, limit, offset int) ([]*RequestLog, int, error) {
+	var gormLogs []GormRequestLog
+	var total int64
+
+	query := g.db.Model(&GormRequestLog{})
+	if filter.Search != "" {
+		pattern := "%" + escapeLikePattern(filter.Search) + "%"
+		if filter.SearchBodies {
+			query = query.Where(`original_request_body LIKE ? ESCAPE '\' OR final_response_body LIKE ? ESCAPE '\'`, pattern, pattern)
+		} else {
+			query = query.Where(`request_id LIKE ? ESCAPE '\' OR endpoint LIKE ? ESCAPE '\' OR model LIKE ? ESCAPE '\' OR path LIKE ? ESCAPE '\'`,
+				pattern, pattern, pattern, pattern)
+		}
+	}
+	if filter.FailedOnly {
+		query = query.Where("status_code >= ? OR error != ?", 400, "")
+	}
+	switch filter.StatusRange {
+	case "2xx":
+		query = query.Where("status_code >= 200 AND status_code < 300")
+	case "4xx":
+		query = query.Where("status_code >= 400 AND status_code < 500")
+	case "5xx":
+		query = query.Where("status_code >= 500")
+	case "error":
+		query = query.Where("status_code >= 400 OR error != ?", "")
+	}
+	if filter.ClientType != "" && filter.ClientType != "all" {
+		query = query.Where("client_type = ?", filter.ClientType)
+	}
+	if filter.StreamingOnly {
+		query = query.Where("is_streaming = ?", true)
+	}
+	if filter.RewrittenOnly {
+		query = query.Where("model_rewrite_applied = ?", true)
+	}
+	if filter.ThinkingOnly {
+		query = query.Where("thinking_enabled = ?", true)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to get total count: %v", err)
+	}
+	if err := query.Order("timestamp DESC").Limit(limit).Offset(offset).Find(&gormLogs).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to query logs: %v", err)
+	}
+
+	logs := make([]*RequestLog, len(gormLogs))
+	for i, gormLog := range gormLogs {
+		logs[i] = ConvertFromGormRequestLog(&gormLog)
+	}
+	return logs, int(total), nil
+}
+
+// SearchLogBodies 在已存储的原始请求体与最终响应体中做子串匹配，返回分页结果与匹配总数
+func (g *GORMStorage) SearchLogBodies(term string, limit, offset int, failedOnly bool) ([]*RequestLog, int, error) {
+	return g.QueryLogs(LogQueryFilter{Search: term, SearchBodies: true, FailedOnly: failedOnly}, limit, offset)
+}
+
+// escapeLikePattern 转义 LIKE 通配符，使搜索词按字面匹配
+func escapeLikePattern(term string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(term)
+}
+
+// LogExportFilter 日志导出过滤条件（零值字段表示不限制）
+type LogExportFilter struct {
+	Since       time.Time
+	Until       time.Time
+	StatusRange string // 2xx / 4xx / 5xx / error
+	ClientType  string
+	Endpoint    string
+}
+
+// StreamLogs 按过滤条件逐行读取日志并回调，避免一次性加载全部记录
+func (g *GORMStorage) StreamLogs(filter LogExportFilter, fn func(*RequestLog) error) error {
+	query := g.db.Model(&GormRequestLog{})
+
+	if !filter.Since.IsZero() {
+		query = query.Where("timestamp >= ?", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		query = query.Where("timestamp <= ?", filter.Until)
+	}
+	switch filter.StatusRange {
+	case "2xx":
+		query = query.Where("status_code >= 200 AND status_code < 300")
+	case "4xx":
+		query = query.Where("status_code >= 400 AND status_code < 500")
+	case "5xx":
+		query = query.Where("status_code >= 500")
+	case "error":
+		query = query.Where("status_code >= 400 OR error != ?", "")
+	}
+	if filter.ClientType != "" && filter.ClientType != "all" {
+		query = query.Where("client_type = ?", filter.ClientType)
+	}
+	if filter.Endpoint != "" {
+		query = query.Where("endpoint = ?", filter.Endpoint)
+	}
+
+	rows, err := query.Order("timestamp ASC").Rows()
+	if err != nil {
+		return fmt.Errorf("failed to query logs: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var gormLog GormRequestLog
+		if err := g.db.ScanRows(rows, &gormLog); err != nil {
+			return fmt.Errorf("failed to scan log row: %v", err)
+		}
+		if err := fn(ConvertFromGormRequestLog(&gormLog)); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// RequestCounts 时间窗口内的请求计数（同一 request_id 的多次端点尝试只计一次，任一尝试成功即视为成功）
+type RequestCounts struct {
+	Total     int64 `json:"total"`
+	Succeeded int64 `json:"succeeded"`
+}
+
+// CountRequestsSince 用一条聚合查询统计 since 之后的请求数与成功数，供需要频繁轮询的概览使用
+func (g *GORMStorage) CountRequestsSince(since time.Time) (RequestCounts, error) {
+	var counts RequestCounts
+	err := g.db.Model(&GormRequestLog{}).
+		Select(`COUNT(DISTINCT request_id) AS total,
+			COUNT(DISTINCT CASE WHEN status_code >= 200 AND status_code < 400 AND (error IS NULL OR error = '') THEN request_id END) AS succeeded`).
+		Where("timestamp >= ?", since).
+		Scan(&counts).Error
+	if err != nil {
+		return RequestCounts{}, fmt.Errorf("failed to count requests: %v", err)
+	}
+	return counts, nil
+}
+
+// RequestOutcome 单条端点尝试的结果摘要，供趋势统计按时间分桶
+type RequestOutcome struct {
+	Timestamp time.Time `json:"timestamp"`
+	Endpoint  string    `json:"endpoint"`
+	Succeeded bool      `json:"succeeded"`
+}
+
+// RequestOutcomesSince 只读取 since 之后各次端点尝试的时间、端点与成功与否；
+// endpoints 非 nil 时仅返回这些端点的记录（空切片表示没有匹配的端点）
+func (g *GORMStorage) RequestOutcomesSince(since time.Time, endpoints []string) ([]RequestOutcome, error) {
+	if endpoints != nil && len(endpoints) == 0 {
+		return []RequestOutcome{}, nil
+	}
+
+	query := g.db.Model(&GormRequestLog{}).
+		Select(`timestamp, endpoint,
+			CASE WHEN status_code >= 200 AND status_code < 400 AND (error IS NULL OR error = '') THEN 1 ELSE 0 END AS succeeded`).
+		Where("timestamp >= ?", since)
+	if endpoints != nil {
+		query = query.Where("endpoint IN ?", endpoints)
+	}
+
+	var outcomes []RequestOutcome
+	if err := query.Order("timestamp ASC").Scan(&outcomes).Error; err != nil {
+		return nil, fmt.Errorf("failed to query request outcomes: %v", err)
+	}
+	return outcomes, nil
+}
+
+// DurationStats 时间窗口内已记录耗时的统计（毫秒）
+type DurationStats struct {
+	Count int64   `json:"count"`
+	AvgMs float64 `json:"avg_ms"`
+	P95Ms int64   `json:"p95_ms"`
+}
+
+// DurationStatsSince 统计 since 之后记录的耗时均值与 P95（最近秩法），耗时为 0 的记录不计入
+func (g *GORMStorage) DurationStatsSince(since time.Time) (DurationStats, error) {
+	var stats DurationStats
+	base := g.db.Model(&GormRequestLog{}).Where("timestamp >= ? AND duration_ms > 0", since)
+	if err := base.Session(&gorm.Session{}).
+		Select("COUNT(*) AS count, COALESCE(AVG(duration_ms), 0) AS avg_ms").
+		Scan(&stats).Error; err != nil {
+		return DurationStats{}, fmt.Errorf("failed to aggregate durations: %v", err)
+	}
+	if stats.Count == 0 {
+		return stats, nil
+	}
+
+	rank := int(math.Ceil(float64(stats.Count) * 0.95))
+	if err := base.Session(&gorm.Session{}).
+		Select("duration_ms").
+		Order("duration_ms ASC").
+		Offset(rank - 1).
+		Limit(1).
+		Scan(&stats.P95Ms).Error; err != nil {
+		return DurationStats{}, fmt.Errorf("failed to compute p95 duration: %v", err)
+	}
+	return stats, nil
+}
+
 // GetAllLogsByRequestID 获取指定request_id的所有日志条目
 func (g *GORMStorage) GetAllLogsByRequestID(requestID string) ([]*RequestLog, error) {
 	var gormLogs []GormRequestLog
@@ -16,6 +16,16 @@ import (
 
 const logBodyPreviewLimit = 2048
 
+// RequestAttempt 合并日志中单个端点的尝试结果
+type RequestAttempt struct {
+	Endpoint      string `json:"endpoint"`
+	AttemptNumber int    `json:"attempt_number"`
+	StatusCode    int    `json:"status_code"`
+	DurationMs    int64  `json:"duration_ms"`
+	Error         string `json:"error,omitempty"`
+	ResponseBody  string `json:"response_body,omitempty"`
+}
+
 type RequestLog struct {
 	Timestamp             time.Time         `json:"timestamp"`
 	RequestID             string            `json:"request_id"`
@@ -47,6 +57,9 @@ type RequestLog struct {
 	Tags                  []string          `json:"tags,omitempty"`
 	ContentTypeOverride   string            `json:"content_type_override,omitempty"`
 	SessionID             string            `json:"session_id,omitempty"`
+	ServiceTier           string            `json:"service_tier,omitempty"`     // 上游响应中实际生效的 service_tier
+	AuthMethodUsed        string            `json:"auth_method_used,omitempty"` // 转发时实际使用的上游认证方式：api_key / authorization / oauth
+	Attempts              []RequestAttempt  `json:"attempts,omitempty"`         // logging.consolidate_attempts 开启时合并记录的各端点尝试结果
 	// Thinking mode fields
 	ThinkingEnabled      bool `json:"thinking_enabled"`       // 是否启用了 thinking 模式
 	ThinkingBudgetTokens int  `json:"thinking_budget_tokens"` // thinking 模式的 budget tokens
@@ -118,6 +131,7 @@ type RequestLog struct {
 // StorageInterface 定义存储接口
 type StorageInterface interface {
 	SaveLog(log *RequestLog)
+	SaveLogs(logs []*RequestLog)
 	GetLogs(limit, offset int, failedOnly bool) ([]*RequestLog, int, error)
 	GetAllLogsByRequestID(requestID string) ([]*RequestLog, error)
 	CleanupLogsByDays(days int) (int64, error)
@@ -239,7 +253,29 @@ func (l *Logger) LogRequest(log *RequestLog) {
 	
 	// 总是记录到存储，方便Web界面查看
 	l.storage.SaveLog(log)
+	l.logToConsole(log)
+}
+
+// LogRequests 批量写入请求日志（供 AsyncRequestWriter 使用），排除路径与控制台输出规则与 LogRequest 一致
+func (l *Logger) LogRequests(logs []*RequestLog) {
+	kept := make([]*RequestLog, 0, len(logs))
+	for _, log := range logs {
+		if log != nil && !l.shouldExcludePath(log.Path) {
+			kept = append(kept, log)
+		}
+	}
+	if len(kept) == 0 {
+		return
+	}
 
+	l.storage.SaveLogs(kept)
+	for _, log := range kept {
+		l.logToConsole(log)
+	}
+}
+
+// logToConsole 根据配置决定是否将请求摘要输出到控制台
+func (l *Logger) logToConsole(log *RequestLog) {
 	// 根据配置决定是否输出到控制台
 	shouldLog := l.shouldLogRequest(log.StatusCode)
 
@@ -343,6 +379,60 @@ func (l *Logger) GetLogs(limit, offset int, failedOnly bool) ([]*RequestLog, int
 	return l.storage.GetLogs(limit, offset, failedOnly)
 }
 
+// StreamLogs 按过滤条件流式读取日志（仅GORM存储支持）
+func (l *Logger) StreamLogs(filter LogExportFilter, fn func(*RequestLog) error) error {
+	storage, ok := l.storage.(*GORMStorage)
+	if !ok {
+		return fmt.Errorf("storage does not support streaming export")
+	}
+	return storage.StreamLogs(filter, fn)
+}
+
+// QueryLogs 按过滤条件分页查询日志（仅GORM存储支持）
+func (l *Logger) QueryLogs(filter LogQueryFilter, limit, offset int) ([]*RequestLog, int, error) {
+	storage, ok := l.storage.(*GORMStorage)
+	if !ok {
+		return nil, 0, fmt.Errorf("storage does not support filtered queries")
+	}
+	return storage.QueryLogs(filter, limit, offset)
+}
+
+// SearchLogBodies 按请求/响应体内容搜索日志（仅GORM存储支持）
+func (l *Logger) SearchLogBodies(term string, limit, offset int, failedOnly bool) ([]*RequestLog, int, error) {
+	storage, ok := l.storage.(*GORMStorage)
+	if !ok {
+		return nil, 0, fmt.Errorf("storage does not support body search")
+	}
+	return storage.SearchLogBodies(term, limit, offset, failedOnly)
+}
+
+// CountRequestsSince 统计 since 之后的请求数与成功数（仅GORM存储支持）
+func (l *Logger) CountRequestsSince(since time.Time) (RequestCounts, error) {
+	storage, ok := l.storage.(*GORMStorage)
+	if !ok {
+		return RequestCounts{}, fmt.Errorf("storage does not support request counts")
+	}
+	return storage.CountRequestsSince(since)
+}
+
+// RequestOutcomesSince 返回 since 之后各次端点尝试的结果摘要（仅GORM存储支持）
+func (l *Logger) RequestOutcomesSince(since time.Time, endpoints []string) ([]RequestOutcome, error) {
+	storage, ok := l.storage.(*GORMStorage)
+	if !ok {
+		return nil, fmt.Errorf("storage does not support request outcomes")
+	}
+	return storage.RequestOutcomesSince(since, endpoints)
+}
+
+// DurationStatsSince 统计 since 之后记录的耗时均值与 P95（仅GORM存储支持）
+func (l *Logger) DurationStatsSince(since time.Time) (DurationStats, error) {
+	storage, ok := l.storage.(*GORMStorage)
+	if !ok {
+		return DurationStats{}, fmt.Errorf("storage does not support duration stats")
+	}
+	return storage.DurationStatsSince(since)
+}
+
 func (l *Logger) GetAllLogsByRequestID(requestID string) ([]*RequestLog, error) {
 	if l.storage == nil {
 		return []*RequestLog{}, nil
@@ -0,0 +1,98 @@
+package modelrewrite
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+
+	jsonutils "claude-code-codex-companion/internal/common/json"
+)
+
+// NormalizeModelName 根据别名表将模型名归一化为规范名称
+// 匹配顺序：精确匹配 > 忽略大小写匹配 > 通配符匹配（按模式字典序）
+func NormalizeModelName(model string, aliases map[string]string) (string, bool) {
+	if model == "" || len(aliases) == 0 {
+		return model, false
+	}
+
+	if canonical, ok := aliases[model]; ok && canonical != "" {
+		return canonical, canonical != model
+	}
+
+	patterns := make([]string, 0, len(aliases))
+	for alias, canonical := range aliases {
+		if canonical == "" {
+			continue
+		}
+		if strings.EqualFold(alias, model) {
+			return canonical, canonical != model
+		}
+		if strings.ContainsAny(alias, "*?[") {
+			patterns = append(patterns, alias)
+		}
+	}
+
+	sort.Strings(patterns)
+	for _, pattern := range patterns {
+		if matched, err := filepath.Match(pattern, model); err == nil && matched {
+			canonical := aliases[pattern]
+			return canonical, canonical != model
+		}
+	}
+
+	return model, false
+}
+
+// CanonicalizeRequestModel 在模型重写之前归一化请求体中的 model 字段
+// 返回新的请求体、原始模型名、规范模型名以及是否发生了改变
+func CanonicalizeRequestModel(body []byte, aliases map[string]string) ([]byte, string, string, bool) {
+	if len(body) == 0 || len(aliases) == 0 {
+		return body, "", "", false
+	}
+
+	var requestData map[string]interface{}
+	if err := jsonutils.SafeUnmarshal(body, &requestData); err != nil {
+		return body, "", "", false
+	}
+	rawModel, ok := requestData["model"].(string)
+	if !ok || rawModel == "" {
+		return body, "", "", false
+	}
+
+	canonical, changed := NormalizeModelName(rawModel, aliases)
+	if !changed {
+		return body, rawModel, rawModel, false
+	}
+
+	requestData["model"] = canonical
+	newBody, err := jsonutils.SafeMarshal(requestData)
+	if err != nil {
+		return body, rawModel, rawModel, false
+	}
+	return newBody, rawModel, canonical, true
+}
+
+// OverrideRequestModel 用指定模型替换请求体中的 model 字段（X-CCCC-Model 请求头）
+// 返回新的请求体、替换前的模型名以及是否发生了改变
+func OverrideRequestModel(body []byte, model string) ([]byte, string, bool) {
+	model = strings.TrimSpace(model)
+	if len(body) == 0 || model == "" {
+		return body, "", false
+	}
+
+	var requestData map[string]interface{}
+	if err := jsonutils.SafeUnmarshal(body, &requestData); err != nil {
+		return body, "", false
+	}
+	previous, _ := requestData["model"].(string)
+	if previous == model {
+		return body, previous, false
+	}
+
+	requestData["model"] = model
+	newBody, err := jsonutils.SafeMarshal(requestData)
+	if err != nil {
+		return body, previous, false
+	}
+	return newBody, previous, true
+}
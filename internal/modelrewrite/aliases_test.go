@@ -0,0 +1,65 @@
+package modelrewrite
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestNormalizeModelName(t *testing.T) {
+	aliases := map[string]string{
+		"claude-3-5-sonnet-latest": "claude-3-5-sonnet",
+		"claude-3.5-sonnet":        "claude-3-5-sonnet",
+		"claude-3-5-sonnet-2024*":  "claude-3-5-sonnet",
+	}
+
+	cases := map[string]string{
+		"claude-3-5-sonnet-latest":   "claude-3-5-sonnet",
+		"Claude-3.5-Sonnet":          "claude-3-5-sonnet",
+		"claude-3-5-sonnet-20241022": "claude-3-5-sonnet",
+		"gpt-4o":                     "gpt-4o",
+	}
+	for input, expected := range cases {
+		if got, _ := NormalizeModelName(input, aliases); got != expected {
+			t.Errorf("NormalizeModelName(%q) = %q, expected %q", input, got, expected)
+		}
+	}
+}
+
+func TestCanonicalizeRequestModel(t *testing.T) {
+	aliases := map[string]string{"claude-3.5-sonnet": "claude-3-5-sonnet"}
+
+	body, raw, canonical, changed := CanonicalizeRequestModel([]byte(`{"model":"claude-3.5-sonnet","max_tokens":10}`), aliases)
+	if !changed || raw != "claude-3.5-sonnet" || canonical != "claude-3-5-sonnet" {
+		t.Fatalf("unexpected result: raw=%q canonical=%q changed=%v", raw, canonical, changed)
+	}
+
+	var payload map[string]interface{}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		t.Fatalf("invalid body: %v", err)
+	}
+	if payload["model"] != "claude-3-5-sonnet" {
+		t.Errorf("expected canonical model in body, got %v", payload["model"])
+	}
+}
+
+func TestOverrideRequestModel(t *testing.T) {
+	body, previous, changed := OverrideRequestModel([]byte(`{"model":"claude-3-5-sonnet","max_tokens":10}`), " gpt-4o ")
+	if !changed || previous != "claude-3-5-sonnet" {
+		t.Fatalf("unexpected result: previous=%q changed=%v", previous, changed)
+	}
+
+	var payload map[string]interface{}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		t.Fatalf("invalid body: %v", err)
+	}
+	if payload["model"] != "gpt-4o" || payload["max_tokens"] != float64(10) {
+		t.Errorf("unexpected body after override: %v", payload)
+	}
+
+	if _, _, changed := OverrideRequestModel([]byte(`{"model":"gpt-4o"}`), "gpt-4o"); changed {
+		t.Error("expected no change when the model already matches")
+	}
+	if _, _, changed := OverrideRequestModel([]byte(`not json`), "gpt-4o"); changed {
+		t.Error("expected invalid JSON to be left untouched")
+	}
+}
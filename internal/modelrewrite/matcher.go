@@ -0,0 +1,85 @@
+package modelrewrite
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// 规则匹配类型
+const (
+	MatchTypeGlob  = "glob"  // 默认：通配符（filepath.Match 语义）
+	MatchTypeRegex = "regex" // 正则表达式（完整匹配）
+	MatchTypeExact = "exact" // 精确匹配
+)
+
+// maxCompiledPatterns 正则缓存的最大条目数；规则测试等场景会传入大量临时模式，超过上限时清空重建
+const maxCompiledPatterns = 256
+
+var (
+	compiledPatternsMu sync.Mutex
+	compiledPatterns   = map[string]*regexp.Regexp{} // 已编译的正则，避免每个请求重复编译
+)
+
+// NormalizeMatchType 规范化匹配类型：空值为 glob，regexp 是 regex 的别名；
+// 未知值返回错误（与 config.ValidateModelRewriteConfig 的校验一致）
+func NormalizeMatchType(matchType string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(matchType)) {
+	case "", MatchTypeGlob:
+		return MatchTypeGlob, nil
+	case MatchTypeRegex, "regexp":
+		return MatchTypeRegex, nil
+	case MatchTypeExact:
+		return MatchTypeExact, nil
+	default:
+		return "", fmt.Errorf("unsupported match_type %q", matchType)
+	}
+}
+
+// MatchModelPattern 判断模型名是否匹配规则的源模式，重写器与规则测试共用此逻辑
+func MatchModelPattern(pattern, matchType, model string) (bool, error) {
+	normalized, err := NormalizeMatchType(matchType)
+	if err != nil {
+		return false, err
+	}
+	switch normalized {
+	case MatchTypeExact:
+		return pattern == model, nil
+	case MatchTypeRegex:
+		re, err := compileModelPattern(pattern)
+		if err != nil {
+			return false, err
+		}
+		return re.MatchString(model), nil
+	default:
+		matched, err := filepath.Match(pattern, model)
+		if err != nil {
+			return false, fmt.Errorf("invalid glob pattern %q: %v", pattern, err)
+		}
+		return matched, nil
+	}
+}
+
+func compileModelPattern(pattern string) (*regexp.Regexp, error) {
+	compiledPatternsMu.Lock()
+	cached, ok := compiledPatterns[pattern]
+	compiledPatternsMu.Unlock()
+	if ok {
+		return cached, nil
+	}
+
+	re, err := regexp.Compile("^(?:" + pattern + ")$")
+	if err != nil {
+		return nil, fmt.Errorf("invalid regex pattern %q: %v", pattern, err)
+	}
+
+	compiledPatternsMu.Lock()
+	if len(compiledPatterns) >= maxCompiledPatterns {
+		compiledPatterns = map[string]*regexp.Regexp{}
+	}
+	compiledPatterns[pattern] = re
+	compiledPatternsMu.Unlock()
+	return re, nil
+}
@@ -0,0 +1,66 @@
+package modelrewrite
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestMatchModelPattern(t *testing.T) {
+	cases := []struct {
+		pattern, matchType, model string
+		expected                  bool
+	}{
+		{"claude-*", "", "claude-3-5-sonnet", true},
+		{"claude-*", MatchTypeGlob, "gpt-4o", false},
+		{`claude-3[.-]5-sonnet(-\d{8})?`, MatchTypeRegex, "claude-3.5-sonnet", true},
+		{`claude-3[.-]5-sonnet(-\d{8})?`, MatchTypeRegex, "claude-3-5-sonnet-20241022", true},
+		{`sonnet`, MatchTypeRegex, "claude-3-5-sonnet", false}, // 正则需要完整匹配
+		{"gpt-4o", MatchTypeExact, "gpt-4o", true},
+		{"gpt-4*", MatchTypeExact, "gpt-4o", false},
+	}
+	for _, c := range cases {
+		matched, err := MatchModelPattern(c.pattern, c.matchType, c.model)
+		if err != nil {
+			t.Fatalf("MatchModelPattern(%q, %q) error: %v", c.pattern, c.matchType, err)
+		}
+		if matched != c.expected {
+			t.Errorf("MatchModelPattern(%q, %q, %q) = %v, expected %v", c.pattern, c.matchType, c.model, matched, c.expected)
+		}
+	}
+
+	if _, err := MatchModelPattern("claude-(", MatchTypeRegex, "claude"); err == nil {
+		t.Error("expected invalid regex to return an error")
+	}
+}
+
+func TestNormalizeMatchType(t *testing.T) {
+	cases := map[string]string{"": MatchTypeGlob, " GLOB ": MatchTypeGlob, "regexp": MatchTypeRegex, "Regex": MatchTypeRegex, "exact": MatchTypeExact}
+	for raw, expected := range cases {
+		if got, err := NormalizeMatchType(raw); err != nil || got != expected {
+			t.Errorf("NormalizeMatchType(%q) = %q, %v; expected %q", raw, got, err, expected)
+		}
+	}
+
+	// 未知类型与配置校验一致地报错，不再静默回退为通配符
+	if _, err := NormalizeMatchType("fuzzy"); err == nil {
+		t.Error("expected unknown match type to be rejected")
+	}
+	if _, err := MatchModelPattern("claude-*", "fuzzy", "claude-3"); err == nil {
+		t.Error("expected MatchModelPattern to reject unknown match types")
+	}
+}
+
+func TestCompiledPatternCacheIsBounded(t *testing.T) {
+	for i := 0; i < maxCompiledPatterns*2; i++ {
+		if _, err := MatchModelPattern(fmt.Sprintf("model-%d", i), MatchTypeRegex, "model-0"); err != nil {
+			t.Fatalf("MatchModelPattern error: %v", err)
+		}
+	}
+
+	compiledPatternsMu.Lock()
+	size := len(compiledPatterns)
+	compiledPatternsMu.Unlock()
+	if size > maxCompiledPatterns {
+		t.Fatalf("expected at most %d cached patterns, got %d", maxCompiledPatterns, size)
+	}
+}
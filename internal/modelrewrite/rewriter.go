@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"path/filepath"
 	"strings"
 
 	"claude-code-codex-companion/internal/config"
@@ -266,6 +265,90 @@ func (r *Rewriter) replaceModelInObject(obj interface{}, rewrittenModel, origina
 	return replaced
 }
 
+// ForceResponseModel 将响应中的 model 字段强制设置为客户端原始模型名。
+// 与 RewriteResponse 不同，它不依赖上游返回的模型名：字段缺失时补齐，值不同时覆盖。
+// 仅处理顶层对象及 message_start 的 message、Responses 事件的 response，避免误改工具参数中的 model。
+func (r *Rewriter) ForceResponseModel(responseBody []byte, originalModel string) []byte {
+	if originalModel == "" || len(responseBody) == 0 {
+		return responseBody
+	}
+
+	var responseData map[string]interface{}
+	if err := jsonutils.SafeUnmarshal(responseBody, &responseData); err == nil {
+		if !forceModelInEvent(responseData, originalModel) {
+			return responseBody
+		}
+		newBody, err := jsonutils.SafeMarshal(responseData)
+		if err != nil {
+			return responseBody
+		}
+		return newBody
+	}
+
+	if !r.isSSEResponse(responseBody) {
+		return responseBody
+	}
+
+	lines := strings.Split(string(responseBody), "\n")
+	changed := false
+	for i, line := range lines {
+		if !strings.HasPrefix(line, "data: ") || line == "data: [DONE]" {
+			continue
+		}
+		var eventData map[string]interface{}
+		if err := jsonutils.SafeUnmarshal([]byte(strings.TrimPrefix(line, "data: ")), &eventData); err != nil {
+			continue
+		}
+		if !forceModelInEvent(eventData, originalModel) {
+			continue
+		}
+		if newJSON, err := jsonutils.SafeMarshal(eventData); err == nil {
+			lines[i] = "data: " + string(newJSON)
+			changed = true
+		}
+	}
+	if !changed {
+		return responseBody
+	}
+	return []byte(strings.Join(lines, "\n"))
+}
+
+// forceModelInEvent 在承载模型名的对象上设置 model，返回是否有修改
+func forceModelInEvent(event map[string]interface{}, originalModel string) bool {
+	if _, isError := event["error"]; isError || event["type"] == "error" {
+		return false
+	}
+
+	changed := false
+	setModel := func(obj map[string]interface{}) {
+		if current, _ := obj["model"].(string); current != originalModel {
+			obj["model"] = originalModel
+			changed = true
+		}
+	}
+
+	eventType, _ := event["type"].(string)
+	objectType, _ := event["object"].(string)
+	switch {
+	case eventType == "message_start":
+		if message, ok := event["message"].(map[string]interface{}); ok {
+			setModel(message)
+		}
+	case strings.HasPrefix(eventType, "response."):
+		if response, ok := event["response"].(map[string]interface{}); ok {
+			setModel(response)
+		}
+	case eventType == "message", objectType == "chat.completion", objectType == "chat.completion.chunk", objectType == "response":
+		setModel(event)
+	default:
+		// 未知结构：仅覆盖已存在的顶层 model 字段
+		if _, exists := event["model"]; exists {
+			setModel(event)
+		}
+	}
+	return changed
+}
+
 // rewriteTextResponse 处理纯文本响应（简单字符串替换）
 func (r *Rewriter) rewriteTextResponse(responseBody []byte, originalModel, rewrittenModel string) ([]byte, error) {
 	bodyStr := string(responseBody)
@@ -286,7 +369,7 @@ func (r *Rewriter) rewriteTextResponse(responseBody []byte, originalModel, rewri
 // applyRewriteRules 应用重写规则
 func (r *Rewriter) applyRewriteRules(originalModel string, rules []config.ModelRewriteRule, isHealthCheck bool) string {
 	for _, rule := range rules {
-		if matched, err := filepath.Match(rule.SourcePattern, originalModel); err == nil && matched {
+		if matched, err := MatchModelPattern(rule.SourcePattern, rule.MatchType, originalModel); err == nil && matched {
 			if !isHealthCheck {
 				r.logger.Debug("Model rewrite rule matched", map[string]interface{}{
 					"original": originalModel,
@@ -303,7 +386,7 @@ func (r *Rewriter) applyRewriteRules(originalModel string, rules []config.ModelR
 // TestRewriteRule 测试重写规则（用于WebUI测试功能）
 func (r *Rewriter) TestRewriteRule(testModel string, rules []config.ModelRewriteRule) (string, string, bool) {
 	for _, rule := range rules {
-		if matched, err := filepath.Match(rule.SourcePattern, testModel); err == nil && matched {
+		if matched, err := MatchModelPattern(rule.SourcePattern, rule.MatchType, testModel); err == nil && matched {
 			return rule.TargetModel, rule.SourcePattern, true
 		}
 	}
@@ -119,4 +119,33 @@ func TestNoRewriteNeeded(t *testing.T) {
 	if string(result) != response {
 		t.Errorf("Response should remain unchanged when no model field present")
 	}
-}
\ No newline at end of file
+}
+
+func TestForceResponseModel(t *testing.T) {
+	rewriter := &Rewriter{}
+
+	// 上游省略 model 字段时也应补齐为客户端原始模型名
+	jsonResponse := `{"id":"chatcmpl-1","object":"chat.completion","choices":[]}`
+	result := string(rewriter.ForceResponseModel([]byte(jsonResponse), "claude-3-haiku-20240307"))
+	if !strings.Contains(result, `"model":"claude-3-haiku-20240307"`) {
+		t.Errorf("Expected model to be injected into JSON response, got %s", result)
+	}
+
+	// 上游返回了其他模型名时也应覆盖，但不修改工具参数中的 model
+	sseResponse := "event: message_start\n" +
+		`data: {"type":"message_start","message":{"id":"msg_1","model":"upstream-alias","role":"assistant"}}` + "\n\n" +
+		`data: {"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":"{\"model\":\"x\"}"}}` + "\n\n" +
+		"data: [DONE]\n"
+	result = string(rewriter.ForceResponseModel([]byte(sseResponse), "claude-3-haiku-20240307"))
+	if !strings.Contains(result, `"model":"claude-3-haiku-20240307"`) || strings.Contains(result, "upstream-alias") {
+		t.Errorf("Expected message_start model to be forced, got %s", result)
+	}
+	if !strings.Contains(result, `\"model\":\"x\"`) {
+		t.Errorf("Expected tool input to stay untouched, got %s", result)
+	}
+
+	errorResponse := `{"type":"error","error":{"type":"overloaded_error","message":"busy"}}`
+	if got := string(rewriter.ForceResponseModel([]byte(errorResponse), "claude-3-haiku-20240307")); got != errorResponse {
+		t.Errorf("Expected error response to stay untouched, got %s", got)
+	}
+}
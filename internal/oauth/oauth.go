@@ -201,6 +201,20 @@ func truncateToken(token string) string {
 	return token
 }
 
+// RefreshTokenWithCallback 刷新 OAuth token，成功后调用 onRefreshed 持久化新配置（回调失败时返回错误，但仍返回新配置）
+func RefreshTokenWithCallback(oauthConfig *config.OAuthConfig, httpClient *http.Client, onRefreshed func(*config.OAuthConfig) error) (*config.OAuthConfig, error) {
+	newConfig, err := RefreshToken(oauthConfig, httpClient)
+	if err != nil {
+		return nil, err
+	}
+	if onRefreshed != nil {
+		if err := onRefreshed(newConfig); err != nil {
+			return newConfig, fmt.Errorf("failed to persist refreshed token: %v", err)
+		}
+	}
+	return newConfig, nil
+}
+
 // IsTokenExpired 检查 token 是否已过期
 func IsTokenExpired(oauthConfig *config.OAuthConfig) bool {
 	if oauthConfig == nil {
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	jsonutils "claude-code-codex-companion/internal/common/json"
+	"claude-code-codex-companion/internal/config"
 	"claude-code-codex-companion/internal/conversion"
 	"claude-code-codex-companion/internal/endpoint"
 	"claude-code-codex-companion/internal/utils"
@@ -373,14 +374,17 @@ func (s *Server) executeRequest(c *gin.Context, ep *endpoint.Endpoint, ctx *Requ
 	}
 	req.Header.Set("Authorization", authHeader)
 
+	// 端点配置了 user_agent 时覆盖客户端的 User-Agent
+	if userAgent := config.ResolveUserAgent(ep.UserAgent, ""); userAgent != "" {
+		req.Header.Set("User-Agent", userAgent)
+	}
+
 	// 根据格式补全必需的头信息
 	if ctx.EndpointRequestFormat == "anthropic" {
 		if req.Header.Get("Content-Type") == "" {
 			req.Header.Set("Content-Type", "application/json")
 		}
-		if req.Header.Get("anthropic-version") == "" {
-			req.Header.Set("anthropic-version", "2023-06-01")
-		}
+		req.Header.Set("anthropic-version", config.ResolveAnthropicVersion(ep.AnthropicVersion, req.Header.Get("anthropic-version")))
 		if ep.AuthValue != "" {
 			req.Header.Set("x-api-key", ep.AuthValue)
 		}
@@ -435,6 +439,7 @@ func (s *Server) handleResponse(c *gin.Context, resp *http.Response, ep *endpoin
 		// 记录错误信息到上下文
 		ctx.LastStatusCode = resp.StatusCode
 		ctx.LastResponseBody = string(decompressedBody)
+		c.Set("last_status_code", resp.StatusCode)
 
 		// 使用错误模式匹配器分析错误
 		retryDecision := s.errorPatternMatcher.MakeRetryDecision(
@@ -597,12 +602,13 @@ func (s *Server) handleResponse(c *gin.Context, resp *http.Response, ep *endpoin
 }
 
 // convertRequestBody 转换请求体格式
-func (s *Server) convertRequestBody(ctx *RequestContext) ([]byte, error) {
+func (s *Server) convertRequestBody(ctx *RequestContext, ep *endpoint.Endpoint) ([]byte, error) {
 	if ctx.ClientRequestFormat == "anthropic" && ctx.EndpointRequestFormat == "openai" {
 		// Anthropic -> OpenAI 转换
 		endpointInfo := &conversion.EndpointInfo{
 			Type:               "openai",
 			MaxTokensFieldName: "max_tokens",
+			UserFieldMode:      ep.UserFieldMode,
 		}
 
 		converter := conversion.NewRequestConverter(s.logger)
@@ -620,19 +626,9 @@ func (s *Server) convertRequestBody(ctx *RequestContext) ([]byte, error) {
 	}
 
 	if ctx.ClientRequestFormat == "openai" && ctx.EndpointRequestFormat == "anthropic" {
-		// OpenAI -> Anthropic 转换
-		factory := conversion.NewAdapterFactory(nil)
-		chatAdapter := factory.OpenAIChatAdapter()
-		anthropicAdapter := factory.AnthropicAdapter()
-
-		// 解析 OpenAI 请求
-		internalReq, err := chatAdapter.ParseRequestJSON(ctx.RequestBody)
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse OpenAI request: %w", err)
-		}
-
-		// 转换为 Anthropic 格式
-		convertedBody, err := anthropicAdapter.BuildRequestJSON(internalReq)
+		// OpenAI -> Anthropic 转换（user 映射为 metadata.user_id）
+		converter := conversion.NewRequestConverter(s.logger)
+		convertedBody, err := converter.ConvertToAnthropic(ctx.RequestBody, &conversion.EndpointInfo{Type: "anthropic", UserFieldMode: ep.UserFieldMode})
 		if err != nil {
 			return nil, fmt.Errorf("failed to convert OpenAI request to Anthropic format: %w", err)
 		}
@@ -711,7 +707,7 @@ func (s *Server) proxyToEndpoint(c *gin.Context, ep *endpoint.Endpoint, path str
 			"original_body":   string(ctx.RequestBody),
 		})
 
-		convertedBody, err := s.convertRequestBody(ctx)
+		convertedBody, err := s.convertRequestBody(ctx, ep)
 		if err != nil {
 			s.logger.Error("Request body conversion failed", err)
 			elapsed := time.Since(ctx.EndpointStartTime)
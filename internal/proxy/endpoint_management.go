@@ -207,6 +207,11 @@ func (s *Server) determineRetryBehaviorFromError(err error, statusCode int, curr
 		return RetryBehaviorReturnError
 	}
 
+	// retry.terminal_statuses / retry.retriable_statuses：终止状态码直接返回，不再尝试其他端点
+	if s.config != nil && s.config.Retry.IsTerminalStatus(statusCode) {
+		return RetryBehaviorReturnError
+	}
+
 	if ue, ok := err.(*upstreamError); ok {
 		switch ue.action {
 		case "retry_endpoint":
@@ -3,7 +3,6 @@ package proxy
 import (
 	"fmt"
 	"net/http"
-	"regexp"
 	"strings"
 
 	jsonutils "claude-code-codex-companion/internal/common/json"
@@ -146,96 +145,14 @@ func (s *Server) autoRemoveUnsupportedParams(requestBody []byte, ep *endpoint.En
 
 // learnUnsupportedParamsFromError 从错误响应中学习不支持的参数
 func (s *Server) learnUnsupportedParamsFromError(errorBody []byte, ep *endpoint.Endpoint, originalReqBody []byte) {
-	if ep == nil || len(errorBody) == 0 {
+	if ep == nil {
 		return
 	}
-
-	// 解析错误消息
-	var errorData map[string]interface{}
-	if err := jsonutils.SafeUnmarshal(errorBody, &errorData); err != nil {
-		return // 无法解析为JSON,忽略
-	}
-
-	// 尝试从错误消息中提取参数名
-	errorMsg := ""
-	if msg, ok := errorData["message"].(string); ok {
-		errorMsg = msg
-	} else if err, ok := errorData["error"].(map[string]interface{}); ok {
-		if msg, ok := err["message"].(string); ok {
-			errorMsg = msg
-		}
-	} else if err, ok := errorData["error"].(string); ok {
-		errorMsg = err
-	}
-
-	if errorMsg == "" {
-		return
-	}
-
-	// 解析请求体以检查哪些参数存在
-	var requestData map[string]interface{}
-	if err := jsonutils.SafeUnmarshal(originalReqBody, &requestData); err != nil {
-		return
-	}
-
-	// 常见的不支持参数关键词模式
-	unsupportedPatterns := []struct {
-		keywords []string
-		params   []string
-	}{
-		{
-			keywords: []string{"tool", "function", "function_call", "tool_choice"},
-			params:   []string{"tools", "tool_choice", "functions", "function_call"},
-		},
-		{
-			keywords: []string{"unsupported", "not supported", "invalid parameter", "unexpected parameter"},
-			params:   []string{}, // 将从错误消息中动态提取
-		},
-	}
-
-	errorMsgLower := strings.ToLower(errorMsg)
-
-	// 检查每个模式
-	for _, pattern := range unsupportedPatterns {
-		matched := false
-		for _, keyword := range pattern.keywords {
-			if strings.Contains(errorMsgLower, keyword) {
-				matched = true
-				break
-			}
-		}
-
-		if matched {
-			// 如果模式匹配，学习对应的参数
-			if len(pattern.params) > 0 {
-				for _, param := range pattern.params {
-					if _, exists := requestData[param]; exists {
-						ep.LearnUnsupportedParam(param)
-						s.logger.Info("Learned unsupported parameter from API error", map[string]interface{}{
-							"endpoint":  ep.Name,
-							"parameter": param,
-							"error_msg": errorMsg,
-						})
-					}
-				}
-			} else {
-				// 尝试从错误消息中提取参数名
-				// 匹配类似 "parameter 'xxx' is not supported" 或 "unsupported parameter: xxx"
-				paramNameRegex := regexp.MustCompile(`parameter["']?\s*([a-zA-Z_][a-zA-Z0-9_]*)`)
-				matches := paramNameRegex.FindStringSubmatch(errorMsg)
-				if len(matches) > 1 {
-					paramName := matches[1]
-					if _, exists := requestData[paramName]; exists {
-						ep.LearnUnsupportedParam(paramName)
-						s.logger.Info("Learned unsupported parameter from API error (regex)", map[string]interface{}{
-							"endpoint":  ep.Name,
-							"parameter": paramName,
-							"error_msg": errorMsg,
-						})
-					}
-				}
-			}
-		}
+	for _, param := range ep.LearnUnsupportedParamsFromError(errorBody, originalReqBody) {
+		s.logger.Info("Learned unsupported parameter from API error", map[string]interface{}{
+			"endpoint":  ep.Name,
+			"parameter": param,
+		})
 	}
 }
 
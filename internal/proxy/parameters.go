@@ -1,8 +1,6 @@
 package proxy
 
 import (
-	"crypto/md5"
-	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -18,7 +16,6 @@ import (
 // 目标：
 // - 包含 applyParameterOverrides 函数。
 // - 包含所有与特定模型或端点相关的参数 "hacks"，如：
-//   - applyOpenAIUserLengthHack
 //   - applyGPT5ModelHack
 // - 集中管理所有对请求体的修改逻辑（除格式转换外）。
 
@@ -77,63 +74,6 @@ func (s *Server) applyParameterOverrides(requestBody []byte, parameterOverrides
 	return modifiedBody, nil
 }
 
-// applyOpenAIUserLengthHack 应用 OpenAI user 参数长度限制 hack
-func (s *Server) applyOpenAIUserLengthHack(requestBody []byte) ([]byte, error) {
-	// 解析JSON请求体
-	var requestData map[string]interface{}
-	if err := json.Unmarshal(requestBody, &requestData); err != nil {
-		// 如果解析失败，记录日志但不返回错误，使用原始请求体
-		s.logger.Debug("Failed to parse request body as JSON for OpenAI user hack, using original body", map[string]interface{}{
-			"error": err.Error(),
-		})
-		return nil, nil
-	}
-
-	// 检查是否存在 user 参数
-	userValue, exists := requestData["user"]
-	if !exists {
-		return nil, nil // 没有 user 参数，无需处理
-	}
-
-	// 转换为字符串
-	userStr, ok := userValue.(string)
-	if !ok {
-		return nil, nil // user 参数不是字符串，无需处理
-	}
-
-	// 检查长度（以字节为单位）
-	if len(userStr) <= 64 {
-		return nil, nil // 长度在限制内，无需处理
-	}
-
-	// 生成 hash
-	hasher := md5.New()
-	hasher.Write([]byte(userStr))
-	hashBytes := hasher.Sum(nil)
-	hashStr := hex.EncodeToString(hashBytes)
-
-	// 添加前缀标识
-	hashedUser := "hashed-" + hashStr
-
-	// 更新请求数据
-	requestData["user"] = hashedUser
-
-	s.logger.Info("OpenAI user parameter hashed due to length limit", map[string]interface{}{
-		"original_length": len(userStr),
-		"hashed_length":   len(hashedUser),
-		"original_user":   userStr[:min(32, len(userStr))] + "...", // 只记录前32个字符用于调试
-	})
-
-	// 重新序列化为JSON
-	modifiedBody, err := json.Marshal(requestData)
-	if err != nil {
-		s.logger.Error("Failed to marshal request body after user hash", err)
-		return nil, err
-	}
-
-	return modifiedBody, nil
-}
-
 // applyGPT5ModelHack 应用 GPT-5 模型特殊处理 hack
 // 如果模型名包含 "gpt5" 且端点是 OpenAI 类型，则：
 // 1. 如果 temperature 不是 1 则将其改为 1
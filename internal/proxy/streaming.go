@@ -74,6 +74,9 @@ func (s *Server) handleStreamingResponse(
 
 	originalCapture := newLimitedBuffer(responseCaptureLimit)
 	reader = io.TeeReader(reader, originalCapture)
+	// 完整性检查跟踪上游原始流：转换器在 EOF 时总会补发终止事件，检查转换后的输出发现不了上游截断
+	completionTracker := newSSECompletionTracker(reader)
+	reader = completionTracker
 
 	isCodexClient := formatDetection != nil && formatDetection.ClientType == utils.ClientCodex
 	if isCodexClient {
@@ -144,6 +147,24 @@ func (s *Server) handleStreamingResponse(
 	finalSample := captureWriter.Captured()
 	originalSample := originalCapture.Bytes()
 
+	// 内联完整性检查：边转发边检测终止标记，不受采样上限影响，超长流被截断同样能发现
+	if completionTracker.BytesRead() > 0 && !completionTracker.Completed() {
+		err := validator.NewFormatError("incomplete SSE stream: upstream closed the stream without a terminal event", nil)
+		if s.config.Blacklist.SSEValidationSafe {
+			s.logger.Info(fmt.Sprintf("Streaming response from endpoint %s ended without a terminal event (sse_validation_safe, not counted as failure)", ep.Name))
+		} else {
+			s.logger.Info(fmt.Sprintf("Streaming response validation failed for endpoint %s: %v", ep.Name, err))
+			duration := time.Since(endpointStartTime)
+			if conversionStages != nil {
+				setConversionContext(c, *conversionStages)
+			}
+			s.logSimpleRequest(requestID, ep.GetURLForFormat(endpointRequestFormat), c.Request.Method, path, requestBody, finalRequestBody, c, req, resp, finalSample, duration, err, true, tags, "", originalModel, rewrittenModel, attemptNumber, ep.GetURLForFormat(endpointRequestFormat))
+			c.Set("last_error", err)
+			c.Set("last_status_code", resp.StatusCode)
+			return false, true, duration, 0
+		}
+	}
+
 	if len(finalSample) >= responseCaptureLimit {
 		s.logger.Debug("Streaming response capture truncated", map[string]interface{}{
 			"endpoint":   ep.Name,
@@ -134,6 +134,19 @@ func (s *Server) convertCodexToOpenAI(requestBody []byte, endpointName string) (
 		})
 		return nil, nil
 	}
+
+	// include 中没有 Chat Completions 对应项的取值会被丢弃，记录下来便于排查行为差异
+	var fields struct {
+		Include []string `json:"include"`
+	}
+	if json.Unmarshal(requestBody, &fields) == nil {
+		if dropped := conversion.UnsupportedChatIncludes(fields.Include); len(dropped) > 0 {
+			s.logger.Info("Dropped Responses include entries during Codex->OpenAI conversion", map[string]interface{}{
+				"include":  dropped,
+				"endpoint": endpointName,
+			})
+		}
+	}
 	return result, nil
 }
 
@@ -158,6 +171,16 @@ func (s *Server) convertResponseJSONToSSE(jsonBody []byte) []byte {
 		"response_preview": string(jsonBody[:min(200, len(jsonBody))]),
 	})
 
+	sseBody, hasContent := buildResponsesSSEFromJSON(respData)
+	if !hasContent {
+		s.logger.Debug("No content found in Response JSON for SSE conversion")
+	}
+	return sseBody
+}
+
+// buildResponsesSSEFromJSON 根据 Responses（或兼容的 Chat Completion）JSON 构造 SSE 事件流，
+// 返回的事件流总以携带 usage 的 response.completed 结束，保证下游 token 统计可用
+func buildResponsesSSEFromJSON(respData map[string]interface{}) ([]byte, bool) {
 	// 构造 SSE 事件流
 	var buf bytes.Buffer
 
@@ -219,21 +242,60 @@ func (s *Server) convertResponseJSONToSSE(jsonBody []byte) []byte {
 			buf.WriteString("event: response.output_text.delta\n")
 			buf.WriteString(fmt.Sprintf("data: %s\n\n", string(deltaJSON)))
 		}
-	} else {
-		s.logger.Debug("No content found in Response JSON for SSE conversion")
 	}
 
-	// 3. response.completed 事件
+	// 3. response.completed 事件（终止事件，必须携带 usage）
 	completedEvent := map[string]interface{}{
 		"type":     "response.completed",
-		"response": respData,
+		"response": withResponsesUsage(respData),
 	}
 	if completedJSON, err := json.Marshal(completedEvent); err == nil {
 		buf.WriteString("event: response.completed\n")
 		buf.WriteString(fmt.Sprintf("data: %s\n\n", string(completedJSON)))
 	}
 
-	return buf.Bytes()
+	return buf.Bytes(), contentText != ""
+}
+
+// withResponsesUsage 返回带 Responses 格式 usage（input/output/total_tokens）的响应副本：
+// Chat Completion 的 prompt/completion_tokens 会被转换，缺失时补零
+func withResponsesUsage(respData map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(respData)+1)
+	for k, v := range respData {
+		result[k] = v
+	}
+
+	usage, _ := respData["usage"].(map[string]interface{})
+	if _, ok := usage["input_tokens"]; ok {
+		return result
+	}
+
+	inputTokens := jsonNumber(usage["prompt_tokens"])
+	outputTokens := jsonNumber(usage["completion_tokens"])
+	totalTokens := jsonNumber(usage["total_tokens"])
+	if totalTokens == 0 {
+		totalTokens = inputTokens + outputTokens
+	}
+	result["usage"] = map[string]interface{}{
+		"input_tokens":  inputTokens,
+		"output_tokens": outputTokens,
+		"total_tokens":  totalTokens,
+	}
+	return result
+}
+
+// jsonNumber 将 JSON 解码得到的数值转换为 int，非数值返回 0
+func jsonNumber(value interface{}) int {
+	switch v := value.(type) {
+	case float64:
+		return int(v)
+	case int:
+		return v
+	case json.Number:
+		n, _ := v.Int64()
+		return int(n)
+	}
+	return 0
 }
 
 // 动态更新端点的Codex支持状态
@@ -0,0 +1,75 @@
+package proxy
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// lastSSEEvent 返回 SSE 流中最后一个事件的名称与数据
+func lastSSEEvent(t *testing.T, body []byte) (string, map[string]interface{}) {
+	t.Helper()
+	events := strings.Split(strings.TrimSpace(string(body)), "\n\n")
+	last := events[len(events)-1]
+	var name string
+	var data map[string]interface{}
+	for _, line := range strings.Split(last, "\n") {
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &data); err != nil {
+				t.Fatalf("invalid event data: %v", err)
+			}
+		}
+	}
+	return name, data
+}
+
+func TestBuildResponsesSSEFromJSONEndsWithUsage(t *testing.T) {
+	cases := map[string]struct {
+		body           string
+		expectedInput  float64
+		expectedOutput float64
+	}{
+		"responses usage": {
+			body:          `{"id":"resp_1","output":[{"content":[{"type":"output_text","text":"hi"}]}],"usage":{"input_tokens":12,"output_tokens":3,"total_tokens":15}}`,
+			expectedInput: 12, expectedOutput: 3,
+		},
+		"chat completion usage": {
+			body:          `{"id":"chatcmpl-1","choices":[{"message":{"role":"assistant","content":"hi"}}],"usage":{"prompt_tokens":7,"completion_tokens":2}}`,
+			expectedInput: 7, expectedOutput: 2,
+		},
+		"missing usage": {
+			body:          `{"id":"resp_2","output":[{"content":[{"type":"output_text","text":"hi"}]}]}`,
+			expectedInput: 0, expectedOutput: 0,
+		},
+	}
+
+	for name, c := range cases {
+		var respData map[string]interface{}
+		if err := json.Unmarshal([]byte(c.body), &respData); err != nil {
+			t.Fatalf("%s: invalid fixture: %v", name, err)
+		}
+		sse, hasContent := buildResponsesSSEFromJSON(respData)
+		if !hasContent {
+			t.Errorf("%s: expected content to be found", name)
+		}
+
+		event, data := lastSSEEvent(t, sse)
+		if event != "response.completed" {
+			t.Fatalf("%s: expected stream to end with response.completed, got %q", name, event)
+		}
+		response, _ := data["response"].(map[string]interface{})
+		usage, ok := response["usage"].(map[string]interface{})
+		if !ok {
+			t.Fatalf("%s: expected usage on the terminal event, got %v", name, response)
+		}
+		if usage["input_tokens"] != c.expectedInput || usage["output_tokens"] != c.expectedOutput {
+			t.Errorf("%s: unexpected usage %v", name, usage)
+		}
+		if sourceUsage, _ := respData["usage"].(map[string]interface{}); name == "missing usage" && sourceUsage != nil {
+			t.Errorf("%s: source response must not be mutated", name)
+		}
+	}
+}
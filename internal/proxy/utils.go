@@ -1,11 +1,13 @@
 package proxy
 
 import (
+	"bytes"
 	"encoding/json"
 	"io"
 	"strings"
 
 	"claude-code-codex-companion/internal/endpoint"
+	"claude-code-codex-companion/internal/utils"
 	"github.com/gin-gonic/gin"
 )
 
@@ -42,6 +44,76 @@ func (t *teeCaptureWriter) Captured() []byte {
 	return t.capture.Bytes()
 }
 
+// maxTrackedSSELine 完整性检查保留的最长单行；超长行（通常是大段增量）不可能是终止事件，直接跳过
+const maxTrackedSSELine = 1 << 20
+
+// sseCompletionTracker wraps the upstream SSE reader and watches for a
+// terminal event while the stream is consumed. Only the current partial line
+// is retained, so arbitrarily long streams are never buffered. It must wrap
+// the raw upstream stream: converters always emit their own terminal event at
+// EOF, so checking the converted output would hide a truncated upstream.
+type sseCompletionTracker struct {
+	underlying io.Reader
+	line       []byte
+	overflow   bool
+	completed  bool
+	bytesRead  int64
+}
+
+func newSSECompletionTracker(underlying io.Reader) *sseCompletionTracker {
+	return &sseCompletionTracker{underlying: underlying}
+}
+
+func (t *sseCompletionTracker) Read(p []byte) (int, error) {
+	n, err := t.underlying.Read(p)
+	t.bytesRead += int64(n)
+	if !t.completed {
+		t.scan(p[:n])
+		if err == io.EOF && len(t.line) > 0 && !t.overflow {
+			// 最后一行没有换行符
+			t.completed = utils.AnySSETerminal.LineTerminates(string(t.line))
+		}
+	}
+	return n, err
+}
+
+func (t *sseCompletionTracker) scan(chunk []byte) {
+	for len(chunk) > 0 && !t.completed {
+		idx := bytes.IndexByte(chunk, '\n')
+		part := chunk
+		if idx >= 0 {
+			part = chunk[:idx]
+		}
+		if !t.overflow {
+			if len(t.line)+len(part) > maxTrackedSSELine {
+				t.overflow = true
+				t.line = t.line[:0]
+			} else {
+				t.line = append(t.line, part...)
+			}
+		}
+		if idx < 0 {
+			return
+		}
+		if !t.overflow && utils.AnySSETerminal.LineTerminates(string(t.line)) {
+			t.completed = true
+		}
+		t.line = t.line[:0]
+		t.overflow = false
+		chunk = chunk[idx+1:]
+	}
+}
+
+// Completed reports whether a terminal event has been read.
+func (t *sseCompletionTracker) Completed() bool {
+	return t.completed
+}
+
+// BytesRead returns the number of upstream bytes consumed.
+func (t *sseCompletionTracker) BytesRead() int64 {
+	return t.bytesRead
+}
+
 // limitedBuffer is a buffer that only stores the first N bytes written to it.
 type limitedBuffer struct {
 	buf   []byte
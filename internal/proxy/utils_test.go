@@ -0,0 +1,108 @@
+package proxy
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"claude-code-codex-companion/internal/conversion"
+)
+
+func TestSSECompletionTrackerDetectsTerminalMarker(t *testing.T) {
+	cases := map[string]struct {
+		chunks    []string
+		completed bool
+	}{
+		"anthropic message_stop": {
+			chunks:    []string{"event: message_start\ndata: {}\n\n", "event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n"},
+			completed: true,
+		},
+		"openai done split across reads": {
+			chunks:    []string{"data: {\"choices\":[]}\n\ndata: [DO", "NE]\n\n"},
+			completed: true,
+		},
+		"finish_reason without done": {
+			chunks:    []string{"data: {\"choices\":[{\"delta\":{},\"finish_reason\": \"stop\"}]}\n\n"},
+			completed: true,
+		},
+		"done without trailing newline": {
+			chunks:    []string{"data: {\"choices\":[]}\n\n", "data: [DONE]"},
+			completed: true,
+		},
+		"truncated stream": {
+			chunks:    []string{"event: message_start\ndata: {}\n\n", "event: content_block_delta\ndata: {\"delta\":{\"text\":\"hi\"}}\n\n"},
+			completed: false,
+		},
+		"markers inside delta text": {
+			chunks:    []string{"data: {\"choices\":[{\"delta\":{\"content\":\"[DONE] message_stop \\\"finish_reason\\\":\\\"stop\\\"\"},\"finish_reason\":null}]}\n\n"},
+			completed: false,
+		},
+	}
+
+	for name, c := range cases {
+		readers := make([]io.Reader, 0, len(c.chunks))
+		expected := ""
+		for _, chunk := range c.chunks {
+			readers = append(readers, strings.NewReader(chunk))
+			expected += chunk
+		}
+		tracker := newSSECompletionTracker(io.MultiReader(readers...))
+		out, err := io.ReadAll(tracker)
+		if err != nil {
+			t.Fatalf("%s: read failed: %v", name, err)
+		}
+		if tracker.Completed() != c.completed {
+			t.Errorf("%s: completed = %v, want %v", name, tracker.Completed(), c.completed)
+		}
+		if string(out) != expected || tracker.BytesRead() != int64(len(expected)) {
+			t.Errorf("%s: reads must pass through unchanged", name)
+		}
+	}
+
+	oneByte := newSSECompletionTracker(iotest.OneByteReader(strings.NewReader("data: [DONE]\n\n")))
+	if _, err := io.ReadAll(oneByte); err != nil || !oneByte.Completed() {
+		t.Errorf("expected [DONE] split one byte at a time to be detected")
+	}
+}
+
+func TestSSECompletionTrackerDetectsTruncatedConvertedStream(t *testing.T) {
+	// 上游 Chat 流在 finish_reason 之前断开；转换器仍会在 EOF 补发 message_stop，
+	// 因此必须跟踪上游原始流才能发现截断
+	upstream := "data: {\"id\":\"c1\",\"model\":\"gpt-4o\",\"choices\":[{\"index\":0,\"delta\":{\"role\":\"assistant\",\"content\":\"hel\"},\"finish_reason\":null}]}\n\n"
+	tracker := newSSECompletionTracker(strings.NewReader(upstream))
+	var converted bytes.Buffer
+	if err := conversion.StreamOpenAISSEToAnthropic(tracker, &converted); err != nil {
+		t.Fatalf("convert stream: %v", err)
+	}
+	if !strings.Contains(converted.String(), "message_stop") {
+		t.Fatalf("expected converter to emit message_stop, got %q", converted.String())
+	}
+	if tracker.Completed() {
+		t.Fatal("truncated upstream stream must not be reported as completed")
+	}
+
+	complete := upstream + "data: {\"id\":\"c1\",\"choices\":[{\"index\":0,\"delta\":{},\"finish_reason\":\"stop\"}]}\n\ndata: [DONE]\n\n"
+	tracker = newSSECompletionTracker(strings.NewReader(complete))
+	if err := conversion.StreamOpenAISSEToAnthropic(tracker, io.Discard); err != nil {
+		t.Fatalf("convert stream: %v", err)
+	}
+	if !tracker.Completed() {
+		t.Fatal("expected completed upstream stream to be detected")
+	}
+}
+
+func TestSSECompletionTrackerKeepsBoundedLine(t *testing.T) {
+	long := "data: {\"delta\":\"" + strings.Repeat("x", maxTrackedSSELine) + "\"}\n\ndata: [DONE]\n\n"
+	tracker := newSSECompletionTracker(strings.NewReader(long))
+	if _, err := io.Copy(io.Discard, tracker); err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	if cap(tracker.line) > 2*maxTrackedSSELine {
+		t.Fatalf("tracker retained %d bytes, expected a bounded line buffer", cap(tracker.line))
+	}
+	if !tracker.Completed() {
+		t.Fatal("expected terminal event after an oversized line to be detected")
+	}
+}
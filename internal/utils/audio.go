@@ -0,0 +1,79 @@
+package utils
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// audioContentTypes 表示音频输入的内容块类型（Chat Completions 与 Responses API）
+var audioContentTypes = map[string]bool{
+	"input_audio": true,
+	"audio":       true,
+}
+
+// RequestNeedsAudio 判断 OpenAI 格式请求是否需要音频能力：modalities 包含 audio、携带 audio 输出参数，
+// 或 messages / input 中存在音频内容块。这类请求无法转换为 Anthropic 格式，只能透传给支持音频的端点
+func RequestNeedsAudio(body []byte) bool {
+	var payload struct {
+		Modalities []string          `json:"modalities"`
+		Audio      json.RawMessage   `json:"audio"`
+		Messages   []json.RawMessage `json:"messages"`
+		Input      json.RawMessage   `json:"input"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return false
+	}
+
+	for _, modality := range payload.Modalities {
+		if strings.EqualFold(strings.TrimSpace(modality), "audio") {
+			return true
+		}
+	}
+	if len(payload.Audio) > 0 && string(payload.Audio) != "null" {
+		return true
+	}
+
+	for _, message := range payload.Messages {
+		if messageHasAudio(message) {
+			return true
+		}
+	}
+
+	// Responses API：input 可以是字符串或消息/内容块数组
+	var inputItems []json.RawMessage
+	if json.Unmarshal(payload.Input, &inputItems) == nil {
+		for _, item := range inputItems {
+			if contentPartIsAudio(item) || messageHasAudio(item) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// messageHasAudio 检查消息的 content 数组中是否有音频内容块
+func messageHasAudio(raw json.RawMessage) bool {
+	var message struct {
+		Content json.RawMessage `json:"content"`
+	}
+	if json.Unmarshal(raw, &message) != nil {
+		return false
+	}
+	var parts []json.RawMessage
+	if json.Unmarshal(message.Content, &parts) != nil {
+		return false
+	}
+	for _, part := range parts {
+		if contentPartIsAudio(part) {
+			return true
+		}
+	}
+	return false
+}
+
+func contentPartIsAudio(raw json.RawMessage) bool {
+	var part struct {
+		Type string `json:"type"`
+	}
+	return json.Unmarshal(raw, &part) == nil && audioContentTypes[part.Type]
+}
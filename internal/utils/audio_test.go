@@ -0,0 +1,26 @@
+package utils
+
+import "testing"
+
+func TestRequestNeedsAudio(t *testing.T) {
+	cases := []struct {
+		name string
+		body string
+		want bool
+	}{
+		{"text only", `{"model":"gpt-4o","messages":[{"role":"user","content":"hi"}]}`, false},
+		{"vision", `{"messages":[{"role":"user","content":[{"type":"image_url","image_url":{"url":"data:"}}]}]}`, false},
+		{"audio modality", `{"model":"gpt-4o-audio-preview","modalities":["text","audio"],"messages":[]}`, true},
+		{"audio output params", `{"audio":{"voice":"alloy","format":"wav"},"messages":[]}`, true},
+		{"null audio", `{"audio":null,"messages":[]}`, false},
+		{"input_audio part", `{"messages":[{"role":"user","content":[{"type":"text","text":"?"},{"type":"input_audio","input_audio":{"data":"...","format":"mp3"}}]}]}`, true},
+		{"responses input", `{"input":[{"role":"user","content":[{"type":"input_audio","input_audio":{"data":"..."}}]}]}`, true},
+		{"responses string input", `{"input":"hello"}`, false},
+		{"invalid json", `{"modalities":`, false},
+	}
+	for _, tc := range cases {
+		if got := RequestNeedsAudio([]byte(tc.body)); got != tc.want {
+			t.Errorf("%s: RequestNeedsAudio = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
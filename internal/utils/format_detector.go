@@ -12,9 +12,13 @@ type RequestFormat string
 const (
 	FormatAnthropic RequestFormat = "anthropic"
 	FormatOpenAI    RequestFormat = "openai"
+	FormatGemini    RequestFormat = "gemini"
 	FormatUnknown   RequestFormat = "unknown"
 )
 
+// RequestFormatOverrideHeader 强制指定请求格式的请求头，自动检测误判时作为兜底
+const RequestFormatOverrideHeader = "X-CCCC-Request-Format"
+
 // ClientType represents the detected client type
 type ClientType string
 
@@ -200,8 +204,31 @@ func InitializeLRUCache(maxSize int) {
 	lruCache = NewLRUCache(maxSize)
 }
 
+// IsAnthropicBatchPath reports whether path targets the Anthropic Message Batches API
+// (/v1/messages/batches and its sub-resources such as /{id}/results or /{id}/cancel)
+func IsAnthropicBatchPath(path string) bool {
+	return strings.Contains(path, "/messages/batches")
+}
+
+// IsAnthropicCompletePath reports whether path targets the Anthropic legacy
+// Text Completions API (/v1/complete)
+func IsAnthropicCompletePath(path string) bool {
+	return strings.HasSuffix(strings.TrimSuffix(path, "/"), "/v1/complete")
+}
+
 // DetectRequestFormat automatically detects the API format from request path and body
 func DetectRequestFormat(path string, requestBody []byte) *FormatDetectionResult {
+	// 0. Batch API bodies are {"requests": [...]} and would otherwise match the OpenAI "/batches" path;
+	//    legacy /v1/complete bodies carry a bare prompt that body detection cannot classify.
+	if IsAnthropicBatchPath(path) || IsAnthropicCompletePath(path) {
+		return &FormatDetectionResult{
+			Format:     FormatAnthropic,
+			ClientType: ClientClaudeCode,
+			Confidence: 0.95,
+			DetectedBy: "path",
+		}
+	}
+
 	// 1. Body-based detection first, as it's more reliable than path.
 	if len(requestBody) > 0 {
 		var reqData map[string]interface{}
@@ -275,6 +302,31 @@ func DetectRequestFormat(path string, requestBody []byte) *FormatDetectionResult
 	}
 }
 
+// DetectRequestFormatFromHeader 解析 X-CCCC-Request-Format 请求头的值，
+// 值为空或不受支持时返回 nil（调用方应回退到自动检测）
+func DetectRequestFormatFromHeader(value string) *FormatDetectionResult {
+	result := &FormatDetectionResult{
+		Confidence: 1.0,
+		DetectedBy: "header",
+	}
+
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "anthropic":
+		result.Format = FormatAnthropic
+		result.ClientType = ClientClaudeCode
+	case "openai":
+		result.Format = FormatOpenAI
+		result.ClientType = ClientCodex
+	case "gemini":
+		result.Format = FormatGemini
+		result.ClientType = ClientGemini
+	default:
+		return nil
+	}
+
+	return result
+}
+
 // detectFromBody detects format from request body structure
 func detectFromBody(reqData map[string]interface{}) *FormatDetectionResult {
 	result := &FormatDetectionResult{
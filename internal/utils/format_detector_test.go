@@ -0,0 +1,28 @@
+package utils
+
+import "testing"
+
+func TestDetectRequestFormatBatchPath(t *testing.T) {
+	body := []byte(`{"requests":[{"custom_id":"a","params":{"model":"claude-3","max_tokens":16,"messages":[{"role":"user","content":"hi"}]}}]}`)
+	for _, path := range []string{"/v1/messages/batches", "/v1/messages/batches/msgbatch_1/results"} {
+		result := DetectRequestFormat(path, body)
+		if result.Format != FormatAnthropic {
+			t.Errorf("%s: expected anthropic format, got %s", path, result.Format)
+		}
+	}
+
+	if result := DetectRequestFormat("/v1/batches", nil); result.Format != FormatOpenAI {
+		t.Errorf("expected OpenAI batches path to stay openai, got %s", result.Format)
+	}
+}
+
+func TestDetectRequestFormatLegacyCompletePath(t *testing.T) {
+	body := []byte(`{"model":"claude-2.1","prompt":"\n\nHuman: hi\n\nAssistant:","max_tokens_to_sample":16}`)
+	result := DetectRequestFormat("/v1/complete", body)
+	if result.Format != FormatAnthropic {
+		t.Errorf("expected anthropic format for /v1/complete, got %s", result.Format)
+	}
+	if IsAnthropicCompletePath("/v1/completions") {
+		t.Error("expected OpenAI /v1/completions not to be treated as legacy complete")
+	}
+}
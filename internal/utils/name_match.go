@@ -0,0 +1,51 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MatchEndpointName 按名称模糊查找端点，匹配优先级依次为：
+// 精确匹配 > 忽略大小写精确匹配 > 唯一前缀匹配 > 唯一子串匹配（前缀与子串均忽略大小写）。
+// 同一级别命中多个候选时返回错误并列出候选名称，便于 CLI 工具提示用户。
+func MatchEndpointName(names []string, query string) (string, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return "", fmt.Errorf("endpoint name is empty")
+	}
+
+	for _, name := range names {
+		if name == query {
+			return name, nil
+		}
+	}
+
+	lowerQuery := strings.ToLower(query)
+	matchers := []struct {
+		kind  string
+		match func(string) bool
+	}{
+		{"case-insensitive", func(name string) bool { return name == lowerQuery }},
+		{"prefix", func(name string) bool { return strings.HasPrefix(name, lowerQuery) }},
+		{"substring", func(name string) bool { return strings.Contains(name, lowerQuery) }},
+	}
+
+	for _, matcher := range matchers {
+		var candidates []string
+		for _, name := range names {
+			if matcher.match(strings.ToLower(name)) {
+				candidates = append(candidates, name)
+			}
+		}
+		switch len(candidates) {
+		case 0:
+			continue
+		case 1:
+			return candidates[0], nil
+		default:
+			return "", fmt.Errorf("endpoint name %q is ambiguous (%s match): %s", query, matcher.kind, strings.Join(candidates, ", "))
+		}
+	}
+
+	return "", fmt.Errorf("endpoint %q not found", query)
+}
@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMatchEndpointName(t *testing.T) {
+	names := []string{"anthropic-official-primary", "anthropic-official-backup", "OpenRouter", "kimi"}
+
+	cases := map[string]string{
+		"kimi":                    "kimi",
+		"openrouter":              "OpenRouter",
+		"open":                    "OpenRouter",
+		"backup":                  "anthropic-official-backup",
+		"ANTHROPIC-OFFICIAL-PRIM": "anthropic-official-primary",
+	}
+	for query, expected := range cases {
+		got, err := MatchEndpointName(names, query)
+		if err != nil || got != expected {
+			t.Errorf("query %q: expected %q, got %q (err=%v)", query, expected, got, err)
+		}
+	}
+
+	_, err := MatchEndpointName(names, "anthropic")
+	if err == nil || !strings.Contains(err.Error(), "anthropic-official-primary") || !strings.Contains(err.Error(), "anthropic-official-backup") {
+		t.Fatalf("expected ambiguity error listing candidates, got %v", err)
+	}
+
+	if _, err := MatchEndpointName(names, "missing"); err == nil {
+		t.Fatal("expected not found error")
+	}
+}
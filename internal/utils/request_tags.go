@@ -0,0 +1,47 @@
+package utils
+
+import "strings"
+
+// RequestTagsHeader 调用方附加请求标签的请求头，值为逗号分隔的标签列表
+const RequestTagsHeader = "X-CCCC-Tags"
+
+// ModelOverrideHeader 调用方临时指定模型的请求头（需开启 server.allow_model_override_header）
+const ModelOverrideHeader = "X-CCCC-Model"
+
+// ParseRequestTags 解析 X-CCCC-Tags 请求头，去除空白与重复项（大小写不敏感），保留首次出现的顺序
+func ParseRequestTags(header string) []string {
+	if strings.TrimSpace(header) == "" {
+		return nil
+	}
+	return MergeTags(strings.Split(header, ","))
+}
+
+// MergeTags 合并多组标签，去除空白与重复项（大小写不敏感），保留首次出现的顺序
+func MergeTags(groups ...[]string) []string {
+	merged := []string{}
+	seen := make(map[string]bool)
+	for _, group := range groups {
+		for _, tag := range group {
+			tag = strings.TrimSpace(tag)
+			key := strings.ToLower(tag)
+			if tag == "" || seen[key] {
+				continue
+			}
+			seen[key] = true
+			merged = append(merged, tag)
+		}
+	}
+	return merged
+}
+
+// HasCommonTag 判断两组标签是否存在交集（大小写不敏感）
+func HasCommonTag(a, b []string) bool {
+	for _, left := range a {
+		for _, right := range b {
+			if strings.EqualFold(strings.TrimSpace(left), strings.TrimSpace(right)) {
+				return true
+			}
+		}
+	}
+	return false
+}
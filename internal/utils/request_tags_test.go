@@ -0,0 +1,30 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseRequestTags(t *testing.T) {
+	tags := ParseRequestTags(" ci, nightly ,,CI,batch ")
+	expected := []string{"ci", "nightly", "batch"}
+	if !reflect.DeepEqual(tags, expected) {
+		t.Fatalf("expected %v, got %v", expected, tags)
+	}
+	if tags := ParseRequestTags("  "); tags != nil {
+		t.Fatalf("expected no tags for blank header, got %v", tags)
+	}
+}
+
+func TestMergeAndMatchTags(t *testing.T) {
+	merged := MergeTags([]string{"ci"}, []string{"openai", "CI"})
+	if !reflect.DeepEqual(merged, []string{"ci", "openai"}) {
+		t.Fatalf("unexpected merged tags: %v", merged)
+	}
+	if !HasCommonTag([]string{"Nightly"}, []string{"ci", "nightly"}) {
+		t.Fatal("expected case-insensitive tag match")
+	}
+	if HasCommonTag([]string{"ci"}, nil) {
+		t.Fatal("expected no match against empty tags")
+	}
+}
@@ -0,0 +1,93 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// RequestCharacteristics 路由规则使用的请求特征
+type RequestCharacteristics struct {
+	Tools        int // tools 数组长度
+	Messages     int // messages 数组长度（Responses API 为 input 条目数）
+	ApproxTokens int // 按 EstimateTokenCount 粗略估算的 token 数
+}
+
+// MeasureRequest 提取请求体的工具数、消息数与估算 token 数；非 JSON 请求体只估算 token 数
+func MeasureRequest(body []byte) RequestCharacteristics {
+	characteristics := RequestCharacteristics{ApproxTokens: EstimateTokenCount(body)}
+
+	var payload struct {
+		Tools    []json.RawMessage `json:"tools"`
+		Messages []json.RawMessage `json:"messages"`
+		Input    json.RawMessage   `json:"input"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return characteristics
+	}
+	characteristics.Tools = len(payload.Tools)
+	characteristics.Messages = len(payload.Messages)
+	if characteristics.Messages == 0 && len(payload.Input) > 0 {
+		var items []json.RawMessage
+		if err := json.Unmarshal(payload.Input, &items); err == nil {
+			characteristics.Messages = len(items)
+		} else if string(payload.Input) != "null" {
+			characteristics.Messages = 1
+		}
+	}
+	return characteristics
+}
+
+// RoutingRule server.routing_rules 中的一条规则：所有已设置（大于 0）的条件同时满足时命中，
+// 命中后把请求模型改写为 Model，和/或优先路由到带 Tag 标签的端点
+type RoutingRule struct {
+	Name        string `json:"name"`
+	MinTools    int    `json:"min_tools"`
+	MinMessages int    `json:"min_messages"`
+	MinTokens   int    `json:"min_tokens"`
+	Model       string `json:"model"`
+	Tag         string `json:"tag"`
+}
+
+// HasCondition 规则至少设置了一个条件
+func (r RoutingRule) HasCondition() bool {
+	return r.MinTools > 0 || r.MinMessages > 0 || r.MinTokens > 0
+}
+
+// HasAction 规则至少设置了模型改写或路由标签
+func (r RoutingRule) HasAction() bool {
+	return strings.TrimSpace(r.Model) != "" || strings.TrimSpace(r.Tag) != ""
+}
+
+// Matches 判断请求特征是否满足规则的全部条件；未设置任何条件的规则不命中
+func (r RoutingRule) Matches(c RequestCharacteristics) bool {
+	if !r.HasCondition() {
+		return false
+	}
+	return c.Tools >= r.MinTools && c.Messages >= r.MinMessages && c.ApproxTokens >= r.MinTokens
+}
+
+// Conditions 返回规则条件的可读描述，用于日志
+func (r RoutingRule) Conditions() string {
+	var parts []string
+	if r.MinTools > 0 {
+		parts = append(parts, fmt.Sprintf("tools>=%d", r.MinTools))
+	}
+	if r.MinMessages > 0 {
+		parts = append(parts, fmt.Sprintf("messages>=%d", r.MinMessages))
+	}
+	if r.MinTokens > 0 {
+		parts = append(parts, fmt.Sprintf("tokens>=%d", r.MinTokens))
+	}
+	return strings.Join(parts, " && ")
+}
+
+// MatchRoutingRule 按配置顺序返回第一条命中的规则
+func MatchRoutingRule(rules []RoutingRule, c RequestCharacteristics) (RoutingRule, bool) {
+	for _, rule := range rules {
+		if rule.Matches(c) {
+			return rule, true
+		}
+	}
+	return RoutingRule{}, false
+}
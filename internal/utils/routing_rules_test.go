@@ -0,0 +1,44 @@
+package utils
+
+import "testing"
+
+func TestMeasureRequest(t *testing.T) {
+	chat := MeasureRequest([]byte(`{"model":"m","messages":[{"role":"user","content":"hi"},{"role":"assistant","content":"yo"}],"tools":[{"name":"a"},{"name":"b"},{"name":"c"}]}`))
+	if chat.Tools != 3 || chat.Messages != 2 || chat.ApproxTokens == 0 {
+		t.Fatalf("unexpected chat characteristics: %+v", chat)
+	}
+
+	responses := MeasureRequest([]byte(`{"model":"m","input":[{"role":"user","content":"hi"}]}`))
+	if responses.Messages != 1 || responses.Tools != 0 {
+		t.Fatalf("unexpected responses characteristics: %+v", responses)
+	}
+	if plain := MeasureRequest([]byte(`{"model":"m","input":"hello"}`)); plain.Messages != 1 {
+		t.Fatalf("expected string input to count as one message, got %+v", plain)
+	}
+	if invalid := MeasureRequest([]byte(`not json`)); invalid.Tools != 0 || invalid.ApproxTokens == 0 {
+		t.Fatalf("expected only a token estimate for non-JSON bodies, got %+v", invalid)
+	}
+}
+
+func TestMatchRoutingRule(t *testing.T) {
+	rules := []RoutingRule{
+		{Name: "no-condition", Model: "ignored"},
+		{Name: "long-agentic", MinTools: 6, MinMessages: 20, Tag: "strong"},
+		{Name: "tool-heavy", MinTools: 6, Model: "big-model"},
+	}
+
+	if _, ok := MatchRoutingRule(rules, RequestCharacteristics{Tools: 5, Messages: 50}); ok {
+		t.Fatal("expected no rule to match 5 tools")
+	}
+	rule, ok := MatchRoutingRule(rules, RequestCharacteristics{Tools: 6, Messages: 3})
+	if !ok || rule.Name != "tool-heavy" {
+		t.Fatalf("expected tool-heavy to match, got %+v (%v)", rule, ok)
+	}
+	rule, ok = MatchRoutingRule(rules, RequestCharacteristics{Tools: 8, Messages: 25})
+	if !ok || rule.Name != "long-agentic" {
+		t.Fatalf("expected the first matching rule to win, got %+v (%v)", rule, ok)
+	}
+	if got := rule.Conditions(); got != "tools>=6 && messages>=20" {
+		t.Fatalf("unexpected condition description %q", got)
+	}
+}
@@ -0,0 +1,86 @@
+package utils
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// SSETerminalSpec 描述一种 SSE 流的终止条件。只检查 event: 行的事件名和 data: 行的内容，
+// 增量文本中恰好出现终止事件名或 [DONE] 不会被误判为流已结束
+type SSETerminalSpec struct {
+	EventTypes   map[string]bool // event: 行的事件名或 data JSON 的 type 字段
+	Done         bool            // data: [DONE]
+	FinishReason bool            // choices[].finish_reason 或 candidates[].finishReason 非空
+}
+
+// AnySSETerminal 不区分格式的终止条件，用于上游格式不确定时的完整性检查
+var AnySSETerminal = SSETerminalSpec{
+	EventTypes: map[string]bool{
+		"message_stop":        true,
+		"response.completed":  true,
+		"response.done":       true,
+		"response.failed":     true,
+		"response.incomplete": true,
+		"error":               true,
+	},
+	Done:         true,
+	FinishReason: true,
+}
+
+// LineTerminates 判断单行 SSE（不含换行符）是否为终止事件
+func (s SSETerminalSpec) LineTerminates(line string) bool {
+	line = strings.TrimSpace(line)
+	if event, ok := strings.CutPrefix(line, "event:"); ok {
+		return s.EventTypes[strings.TrimSpace(event)]
+	}
+	data, ok := strings.CutPrefix(line, "data:")
+	if !ok {
+		return false
+	}
+	data = strings.TrimSpace(data)
+	if data == "[DONE]" {
+		return s.Done
+	}
+	if !strings.HasPrefix(data, "{") {
+		return false
+	}
+
+	var payload struct {
+		Type    string `json:"type"`
+		Choices []struct {
+			FinishReason *string `json:"finish_reason"`
+		} `json:"choices"`
+		Candidates []struct {
+			FinishReason string `json:"finishReason"`
+		} `json:"candidates"`
+	}
+	if json.Unmarshal([]byte(data), &payload) != nil {
+		return false
+	}
+	if s.EventTypes[payload.Type] {
+		return true
+	}
+	if s.FinishReason {
+		for _, choice := range payload.Choices {
+			if choice.FinishReason != nil && *choice.FinishReason != "" {
+				return true
+			}
+		}
+		for _, candidate := range payload.Candidates {
+			if candidate.FinishReason != "" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// SSEStreamTerminated 逐行检查完整的 SSE 响应体是否包含终止事件
+func SSEStreamTerminated(body []byte, spec SSETerminalSpec) bool {
+	for _, line := range strings.Split(string(body), "\n") {
+		if spec.LineTerminates(line) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,30 @@
+package utils
+
+import "testing"
+
+func TestSSETerminalSpecLineTerminates(t *testing.T) {
+	anthropic := SSETerminalSpec{EventTypes: map[string]bool{"message_stop": true, "error": true}}
+	cases := []struct {
+		name string
+		spec SSETerminalSpec
+		line string
+		want bool
+	}{
+		{"event line", anthropic, "event: message_stop", true},
+		{"event line without space", anthropic, "event:error", true},
+		{"data type", anthropic, `data: {"type":"message_stop"}`, true},
+		{"marker in delta", anthropic, `data: {"type":"content_block_delta","delta":{"text":"event: message_stop"}}`, false},
+		{"done not accepted", anthropic, "data: [DONE]", false},
+		{"done", AnySSETerminal, "data: [DONE]", true},
+		{"done in text", AnySSETerminal, `data: {"choices":[{"delta":{"content":"[DONE]"},"finish_reason":null}]}`, false},
+		{"chat finish_reason", AnySSETerminal, `data: {"choices":[{"delta":{},"finish_reason":"stop"}]}`, true},
+		{"gemini finishReason", AnySSETerminal, `data: {"candidates":[{"finishReason":"STOP"}]}`, true},
+		{"responses completed", AnySSETerminal, `data: {"type":"response.completed","response":{}}`, true},
+		{"comment line", AnySSETerminal, ": message_stop", false},
+	}
+	for _, c := range cases {
+		if got := c.spec.LineTerminates(c.line); got != c.want {
+			t.Errorf("%s: LineTerminates(%q) = %v, want %v", c.name, c.line, got, c.want)
+		}
+	}
+}
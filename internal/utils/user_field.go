@@ -0,0 +1,103 @@
+package utils
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+)
+
+// OpenAI user 字段处理模式（按端点配置）
+const (
+	UserFieldModePassthrough = "passthrough" // 原样转发
+	UserFieldModeStrip       = "strip"       // 移除 user 字段
+	UserFieldModeHash        = "hash"        // 始终替换为哈希值
+	UserFieldModeTruncate    = "truncate"    // 默认：超过长度限制时替换为哈希（兼容旧行为）
+)
+
+// OpenAIUserMaxLength OpenAI 对 user 字段的长度限制（字节）
+const OpenAIUserMaxLength = 64
+
+// NormalizeUserFieldMode 规范化模式取值，未知或空值回退为 truncate
+func NormalizeUserFieldMode(mode string) string {
+	switch strings.ToLower(strings.TrimSpace(mode)) {
+	case UserFieldModePassthrough:
+		return UserFieldModePassthrough
+	case UserFieldModeStrip:
+		return UserFieldModeStrip
+	case UserFieldModeHash:
+		return UserFieldModeHash
+	default:
+		return UserFieldModeTruncate
+	}
+}
+
+// IsValidUserFieldMode 判断是否为受支持的模式
+func IsValidUserFieldMode(mode string) bool {
+	switch strings.ToLower(strings.TrimSpace(mode)) {
+	case UserFieldModePassthrough, UserFieldModeStrip, UserFieldModeHash, UserFieldModeTruncate:
+		return true
+	}
+	return false
+}
+
+// ApplyUserFieldMode 按模式处理请求体中的 user 字段，返回新请求体及是否发生修改
+func ApplyUserFieldMode(body []byte, mode string) ([]byte, bool, error) {
+	mode = NormalizeUserFieldMode(mode)
+	if mode == UserFieldModePassthrough || len(body) == 0 {
+		return body, false, nil
+	}
+
+	var requestData map[string]interface{}
+	if err := json.Unmarshal(body, &requestData); err != nil {
+		return body, false, nil
+	}
+
+	userValue, exists := requestData["user"]
+	if !exists {
+		return body, false, nil
+	}
+	userStr, isString := userValue.(string)
+
+	if mode == UserFieldModeStrip {
+		delete(requestData, "user")
+	} else {
+		if !isString {
+			return body, false, nil
+		}
+		transformed := ApplyUserFieldModeToValue(userStr, mode)
+		if transformed == userStr {
+			return body, false, nil
+		}
+		requestData["user"] = transformed
+	}
+
+	modified, err := json.Marshal(requestData)
+	if err != nil {
+		return body, false, err
+	}
+	return modified, true, nil
+}
+
+// ApplyUserFieldModeToValue 按模式处理单个用户标识（用于跨格式映射 user / metadata.user_id），strip 模式返回空串
+func ApplyUserFieldModeToValue(user, mode string) string {
+	switch NormalizeUserFieldMode(mode) {
+	case UserFieldModePassthrough:
+		return user
+	case UserFieldModeStrip:
+		return ""
+	case UserFieldModeHash:
+		if user == "" {
+			return user
+		}
+		sum := sha256.Sum256([]byte(user))
+		return "hashed-" + hex.EncodeToString(sum[:16])
+	default:
+		if len(user) <= OpenAIUserMaxLength {
+			return user
+		}
+		sum := md5.Sum([]byte(user))
+		return "hashed-" + hex.EncodeToString(sum[:])
+	}
+}
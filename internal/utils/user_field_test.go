@@ -0,0 +1,44 @@
+package utils
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestApplyUserFieldMode(t *testing.T) {
+	longUser := strings.Repeat("u", 80)
+	body := []byte(`{"model":"gpt-4o","user":"` + longUser + `"}`)
+
+	decodeUser := func(t *testing.T, payload []byte) (string, bool) {
+		var data map[string]interface{}
+		if err := json.Unmarshal(payload, &data); err != nil {
+			t.Fatalf("invalid body: %v", err)
+		}
+		user, exists := data["user"].(string)
+		return user, exists
+	}
+
+	if out, modified, _ := ApplyUserFieldMode(body, UserFieldModePassthrough); modified || string(out) != string(body) {
+		t.Error("passthrough should not modify the body")
+	}
+
+	out, modified, _ := ApplyUserFieldMode(body, UserFieldModeStrip)
+	if _, exists := decodeUser(t, out); !modified || exists {
+		t.Error("strip should remove the user field")
+	}
+
+	out, _, _ = ApplyUserFieldMode(body, "")
+	if user, _ := decodeUser(t, out); !strings.HasPrefix(user, "hashed-") || len(user) > OpenAIUserMaxLength {
+		t.Errorf("default truncate mode should hash over-long users, got %q", user)
+	}
+
+	short := []byte(`{"user":"alice"}`)
+	if _, modified, _ := ApplyUserFieldMode(short, UserFieldModeTruncate); modified {
+		t.Error("truncate should keep short users untouched")
+	}
+	out, modified, _ = ApplyUserFieldMode(short, UserFieldModeHash)
+	if user, _ := decodeUser(t, out); !modified || user == "alice" || !strings.HasPrefix(user, "hashed-") {
+		t.Errorf("hash mode should always hash, got %q", user)
+	}
+}
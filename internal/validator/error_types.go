@@ -17,6 +17,9 @@ const (
 
 	// ClientValidationError 客户端验证错误（端点拒绝非官方客户端）- 端点正常，但拒绝测试请求
 	ClientValidationError
+
+	// TruncatedError 响应体被截断（连接中途断开）- 网络抖动，可重试，不应触发端点黑名单
+	TruncatedError
 )
 
 // ValidationError 增强的验证错误，包含错误类型信息
@@ -43,9 +46,14 @@ func (e *ValidationError) IsClientValidationError() bool {
 	return e.Type == ClientValidationError
 }
 
+// IsTruncatedError 检查错误是否为响应截断错误
+func (e *ValidationError) IsTruncatedError() bool {
+	return e.Type == TruncatedError
+}
+
 // IsSafeError 检查错误是否为"安全"错误（不应触发端点拉黑）
 func (e *ValidationError) IsSafeError() bool {
-	return e.Type == BusinessError || e.Type == ClientValidationError
+	return e.Type == BusinessError || e.Type == ClientValidationError || e.Type == TruncatedError
 }
 
 // NewNetworkError 创建网络错误
@@ -84,6 +92,15 @@ func NewClientValidationError(message string, cause error) *ValidationError {
 	}
 }
 
+// NewTruncatedError 创建响应截断错误
+func NewTruncatedError(message string, cause error) *ValidationError {
+	return &ValidationError{
+		Type:    TruncatedError,
+		Message: message,
+		Cause:   cause,
+	}
+}
+
 // IsBusinessError 辅助函数，检查error是否为业务错误
 func IsBusinessError(err error) bool {
 	if verr, ok := err.(*ValidationError); ok {
@@ -100,6 +117,14 @@ func IsClientValidationError(err error) bool {
 	return false
 }
 
+// IsTruncatedError 辅助函数，检查error是否为响应截断错误
+func IsTruncatedError(err error) bool {
+	if verr, ok := err.(*ValidationError); ok {
+		return verr.IsTruncatedError()
+	}
+	return false
+}
+
 // IsSafeError 辅助函数，检查error是否为安全错误（不应触发拉黑）
 func IsSafeError(err error) bool {
 	if verr, ok := err.(*ValidationError); ok {
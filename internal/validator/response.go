@@ -42,6 +42,11 @@ func (v *ResponseValidator) ValidateResponseWithPath(body []byte, isStreaming bo
 		return fmt.Errorf("count_tokens response missing input_tokens field")
 	}
 
+	// Message Batches 接口返回 message_batch 对象或 JSONL 结果，不是 message
+	if isMessageBatchesEndpoint(path) {
+		return v.ValidateMessageBatchResponse(body, path)
+	}
+
 	if isStreaming {
 		// 首先进行基本的SSE chunk验证
 		if err := v.ValidateSSEChunk(body, endpointType); err != nil {
@@ -58,6 +63,63 @@ func isCountTokensEndpoint(path string) bool {
 	return strings.Contains(path, "/count_tokens")
 }
 
+// isMessageBatchesEndpoint 检查是否为 Anthropic Message Batches 接口
+func isMessageBatchesEndpoint(path string) bool {
+	return strings.Contains(path, "/messages/batches")
+}
+
+// ValidateMessageBatchResponse 验证 Message Batches 接口响应：
+// /results 为逐行的 JSONL（每行需包含 custom_id），其余为 message_batch 对象、批次列表或删除结果
+func (v *ResponseValidator) ValidateMessageBatchResponse(body []byte, path string) error {
+	if len(body) == 0 {
+		return NewBusinessError("endpoint returned empty response body", nil)
+	}
+
+	if strings.HasSuffix(strings.TrimSuffix(path, "/"), "/results") {
+		for i, line := range bytes.Split(body, []byte("\n")) {
+			line = bytes.TrimSpace(line)
+			if len(line) == 0 {
+				continue
+			}
+			var result map[string]interface{}
+			if err := json.Unmarshal(line, &result); err != nil {
+				return NewFormatError(fmt.Sprintf("invalid JSONL batch result at line %d: %v", i+1, err), err)
+			}
+			if _, ok := result["custom_id"]; !ok {
+				return NewFormatError(fmt.Sprintf("batch result at line %d missing custom_id", i+1), nil)
+			}
+		}
+		return nil
+	}
+
+	var response map[string]interface{}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return NewBusinessError(fmt.Sprintf("invalid JSON response: %v", err), err)
+	}
+	if errorField, hasError := response["error"]; hasError && errorField != nil {
+		errorMsg := "API returned error response"
+		if errorMap, ok := errorField.(map[string]interface{}); ok {
+			if msg, ok := errorMap["message"].(string); ok {
+				errorMsg = msg
+			}
+		}
+		return NewBusinessError(errorMsg, nil)
+	}
+
+	switch response["type"] {
+	case "message_batch", "message_batch_deleted":
+		if _, ok := response["id"]; !ok {
+			return NewFormatError("message batch response missing required field: id", nil)
+		}
+		return nil
+	}
+	// 列表接口返回 {"data": [...], "has_more": ...}
+	if _, ok := response["data"].([]interface{}); ok {
+		return nil
+	}
+	return NewFormatError(fmt.Sprintf("invalid message batch response type: %v", response["type"]), nil)
+}
+
 func (v *ResponseValidator) ValidateStandardResponse(body []byte, endpointType string) error {
 	// 首先检查空响应体
 	if len(body) == 0 {
@@ -66,6 +128,10 @@ func (v *ResponseValidator) ValidateStandardResponse(body []byte, endpointType s
 
 	var response map[string]interface{}
 	if err := json.Unmarshal(body, &response); err != nil {
+		// 以 { / [ 开头却没有闭合：连接中途断开导致的截断，与真正的格式错误区分
+		if IsTruncatedJSON(body, -1) {
+			return NewTruncatedError(fmt.Sprintf("truncated JSON response (%d bytes)", len(body)), err)
+		}
 		// JSON解析失败，可能是HTML错误页面或其他格式
 		bodyPreview := string(body)
 		if len(bodyPreview) > 200 {
@@ -137,6 +203,23 @@ func (v *ResponseValidator) ValidateStandardResponse(body []byte, endpointType s
 	return nil
 }
 
+// IsTruncatedJSON 判断无效的 JSON 响应体是否由截断导致：实际长度小于 Content-Length（contentLength < 0 表示未知），
+// 或以 { / [ 开头但没有以 } / ] 结尾。有效 JSON、空响应体以及 HTML 等非 JSON 内容均返回 false
+func IsTruncatedJSON(body []byte, contentLength int64) bool {
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) == 0 || json.Valid(trimmed) {
+		return false
+	}
+	if contentLength > 0 && int64(len(body)) < contentLength {
+		return true
+	}
+	first, last := trimmed[0], trimmed[len(trimmed)-1]
+	if first != '{' && first != '[' {
+		return false
+	}
+	return last != '}' && last != ']'
+}
+
 func (v *ResponseValidator) ValidateSSEChunk(chunk []byte, endpointType string) error {
 	lines := bytes.Split(chunk, []byte("\n"))
 
@@ -217,3 +217,62 @@ func containsAt(s, substr string) bool {
 	}
 	return false
 }
+
+func TestValidateMessageBatchResponse(t *testing.T) {
+	validator := NewResponseValidator()
+
+	batch := []byte(`{"id":"msgbatch_1","type":"message_batch","processing_status":"in_progress"}`)
+	if err := validator.ValidateResponseWithPath(batch, false, "anthropic", "/v1/messages/batches", ""); err != nil {
+		t.Errorf("expected message_batch response to pass, got %v", err)
+	}
+
+	list := []byte(`{"data":[{"id":"msgbatch_1","type":"message_batch"}],"has_more":false}`)
+	if err := validator.ValidateResponseWithPath(list, false, "anthropic", "/v1/messages/batches", ""); err != nil {
+		t.Errorf("expected batch list response to pass, got %v", err)
+	}
+
+	results := []byte("{\"custom_id\":\"a\",\"result\":{\"type\":\"succeeded\"}}\n{\"custom_id\":\"b\",\"result\":{\"type\":\"errored\"}}\n")
+	if err := validator.ValidateResponseWithPath(results, false, "anthropic", "/v1/messages/batches/msgbatch_1/results", ""); err != nil {
+		t.Errorf("expected JSONL results to pass, got %v", err)
+	}
+
+	if err := validator.ValidateResponseWithPath([]byte(`{"id":"msg_1","type":"message"}`), false, "anthropic", "/v1/messages/batches", ""); err == nil {
+		t.Error("expected a plain message to be rejected on the batch path")
+	}
+}
+
+func TestIsTruncatedJSON(t *testing.T) {
+	cases := []struct {
+		name          string
+		body          string
+		contentLength int64
+		want          bool
+	}{
+		{"valid object", `{"id":"msg_1"}`, -1, false},
+		{"cut off object", `{"id":"msg_1","content":[{"type":"te`, -1, true},
+		{"cut off array", `[{"id":1},{"id"`, -1, true},
+		{"short of content-length", `{"id":"msg_1"}x`, 100, true},
+		{"malformed but closed", `{"id": msg_1}`, -1, false},
+		{"html error page", `<html>Bad Gateway</html>`, -1, false},
+		{"empty", ``, -1, false},
+	}
+	for _, tc := range cases {
+		if got := IsTruncatedJSON([]byte(tc.body), tc.contentLength); got != tc.want {
+			t.Errorf("%s: IsTruncatedJSON = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestValidateStandardResponseTruncated(t *testing.T) {
+	validator := NewResponseValidator()
+
+	err := validator.ValidateStandardResponse([]byte(`{"id":"msg_1","type":"message","content":[`), "anthropic")
+	if !IsTruncatedError(err) || !IsSafeError(err) {
+		t.Fatalf("expected truncated (safe) error, got %v", err)
+	}
+
+	err = validator.ValidateStandardResponse([]byte(`<html>oops</html>`), "anthropic")
+	if IsTruncatedError(err) || !IsBusinessError(err) {
+		t.Fatalf("expected malformed body to stay a business error, got %v", err)
+	}
+}